@@ -29,10 +29,13 @@ package actions
 // swagger:meta
 
 import (
+	"net/http"
+
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/buffalo-pop/v3/pop/popmw"
 	i18n "github.com/gobuffalo/mw-i18n/v2"
 	paramlogger "github.com/gobuffalo/mw-paramlogger"
+	"github.com/gobuffalo/pop/v6"
 	"github.com/rs/cors"
 
 	"github.com/silinternational/wecarry-api/domain"
@@ -76,6 +79,12 @@ func App() *buffalo.App {
 		// Setup and use translations. This should be the first middleware if all other middleware use i18n
 		app.Use(translations())
 
+		// Records the duration and response status of each request
+		app.Use(recordRequestMetrics)
+
+		// Records a trace span for each request
+		app.Use(traceRequest)
+
 		registerCustomErrorHandler(app)
 
 		// Initialize remote logger middleware
@@ -84,15 +93,46 @@ func App() *buffalo.App {
 		// Log request parameters (filters apply).
 		app.Use(paramlogger.ParameterLogger)
 
+		// Limits the rate of requests from each client IP address
+		initRateLimits()
+		app.Use(limitRequestsByIP)
+
 		//  Added for authorization
 		app.Use(setCurrentUser)
-		app.Middleware.Skip(setCurrentUser, statusHandler, serviceHandler)
+		app.Middleware.Skip(setCurrentUser, statusHandler, livenessHandler, readinessHandler, metricsHandler, serviceHandler,
+			emailInbound)
+
+		// Limits the rate of requests from each user, and of each rate-limited route
+		app.Use(limitRequestsByUser)
+
+		// Limits personal access tokens to the routes allowed by their scopes
+		app.Use(enforceTokenScope)
+
+		// Delivers the events recorded in the outbox by a request after its transaction commits
+		app.Use(dispatchOutboxEvents)
 
 		// Wraps each request in a transaction.
 		app.Use(popmw.Transaction(models.DB))
+		app.Middleware.Skip(popmw.Transaction(models.DB), livenessHandler, readinessHandler)
+
+		// Includes the queries of the transaction in the request's trace
+		app.Use(traceTransaction)
 
 		app.GET("/site/status", statusHandler)
 		app.Middleware.Skip(buffalo.RequestLogger, statusHandler)
+		app.Middleware.Skip(limitRequestsByIP, statusHandler)
+		app.Middleware.Skip(limitRequestsByUser, statusHandler)
+
+		// Kubernetes liveness and readiness probes
+		app.GET("/site/live", livenessHandler)
+		app.GET("/site/ready", readinessHandler)
+		app.Middleware.Skip(buffalo.RequestLogger, livenessHandler, readinessHandler)
+		app.Middleware.Skip(limitRequestsByIP, livenessHandler, readinessHandler)
+		app.Middleware.Skip(limitRequestsByUser, livenessHandler, readinessHandler)
+
+		// Prometheus metrics, authenticated by the service integration token
+		app.GET("/metrics", metricsHandler)
+		app.Middleware.Skip(buffalo.RequestLogger, metricsHandler)
 
 		eventsGroup := app.Group("/events")
 		eventsGroup.GET("/", meetingsList)
@@ -104,9 +144,14 @@ func App() *buffalo.App {
 		eventsGroup.DELETE("/{event_id}/invite/", meetingsInviteDelete)
 
 		app.POST("/messages/", messagesCreate)
+		app.PUT("/messages/{message_id}", messagesUpdate)
+		app.DELETE("/messages/{message_id}", messagesRemove)
+		app.POST("/messages/{message_id}/report", messagesReport)
 
 		threadsGroup := app.Group("/threads")
 		threadsGroup.GET("/", threadsMine)
+		threadsGroup.GET("/{thread_id}", threadsGet)
+		threadsGroup.GET("/{thread_id}/messages", threadsMessages)
 		threadsGroup.PUT("/{thread_id}/read", threadsMarkAsRead)
 
 		requestsGroup := app.Group("/requests")
@@ -119,19 +164,28 @@ func App() *buffalo.App {
 		requestsGroup.POST("/{request_id}/potentialprovider", requestsAddMeAsPotentialProvider)
 		requestsGroup.DELETE("/{request_id}/potentialprovider/{user_id}", requestsRejectPotentialProvider)
 		requestsGroup.DELETE("/{request_id}/potentialprovider", requestsRemoveMeAsPotentialProvider)
+		requestsGroup.POST("/{request_id}/report", requestsReport)
+		requestsGroup.POST("/{request_id}/duplicate", requestsDuplicate)
+		requestsGroup.PUT("/{request_id}/renew", requestsRenew)
 
 		watchesGroup := app.Group("/watches")
 		watchesGroup.GET("/", watchesMine)
 		watchesGroup.POST("/", watchesCreate)
+		watchesGroup.PUT("/{watch_id}", watchesUpdate)
+		watchesGroup.GET("/{watch_id}/requests", watchesRequests)
 		watchesGroup.DELETE("/{watch_id}", watchesRemove)
 
 		app.POST("/upload/", uploadHandler)
 
 		app.POST("/service", serviceHandler)
 
+		// Replies to thread message notifications, authenticated by the inbound email secret
+		app.POST("/email/inbound", emailInbound)
+		app.Middleware.Skip(limitRequestsByIP, emailInbound)
+
 		auth := app.Group("/auth")
 		auth.Middleware.Skip(setCurrentUser, authInvite, authRequest, authSelect, authCallback,
-			authDestroy, serviceHandler)
+			authDestroy, authSAMLMetadata, authSAMLSingleLogout, serviceHandler)
 
 		auth.POST("/invite", authInvite)
 
@@ -143,13 +197,67 @@ func App() *buffalo.App {
 
 		auth.GET("/logout", authDestroy)
 
+		auth.GET("/saml/{org_id}/metadata", authSAMLMetadata)
+		auth.GET("/saml/{org_id}/slo", authSAMLSingleLogout)  // for HTTP-Redirect binding
+		auth.POST("/saml/{org_id}/slo", authSAMLSingleLogout) // for HTTP-POST binding
+
 		users := app.Group("/users")
 		users.GET("/me", usersMe)
 		users.PUT("/me", usersMeUpdate)
+		users.DELETE("/me", usersMeDelete)
+		users.GET("/me/export", usersMeExport)
+		users.GET("/me/preferences", usersMePreferences)
+		users.PUT("/me/preferences", usersMePreferencesUpdate)
+		users.GET("/me/sessions", usersMeSessions)
+		users.DELETE("/me/sessions", usersMeSessionsRemove)
+		users.DELETE("/me/sessions/{session_id}", usersMeSessionRemove)
+		users.GET("/me/tokens", usersMeTokens)
+		users.POST("/me/tokens", usersMeTokensCreate)
+		users.DELETE("/me/tokens/{token_id}", usersMeTokenRemove)
+		users.GET("/me/blocks", usersMeBlocks)
+		users.PUT("/me/blocks/{user_id}", usersMeBlock)
+		users.DELETE("/me/blocks/{user_id}", usersMeUnblock)
+		users.GET("/me/templates", usersMeTemplates)
+		users.POST("/me/templates", usersMeTemplatesCreate)
+		users.PUT("/me/templates/{template_id}", usersMeTemplateUpdate)
+		users.DELETE("/me/templates/{template_id}", usersMeTemplateRemove)
+		users.POST("/me/templates/{template_id}/request", usersMeTemplateRequest)
+		users.GET("/{user_id}", usersGet)
+		users.POST("/{user_id}/report", usersReport)
 
-		listeners.RegisterListener()
+		moderation := app.Group("/moderation")
+		moderation.GET("/reports", moderationReports)
+		moderation.PUT("/reports/{report_id}", moderationReportsResolve)
+
+		organizations := app.Group("/organizations")
+		organizations.DELETE("/{org_id}/users/{user_id}/sessions", organizationsUserSessionsRemove)
+		organizations.POST("/{org_id}/scim-token", organizationsSCIMTokenCreate)
+		organizations.DELETE("/{org_id}/scim-token", organizationsSCIMTokenRemove)
+
+		// SCIM 2.0 provisioning, authenticated by each organization's SCIM bearer token
+		scim := app.Group("/scim/v2/{org_id}")
+		scim.Use(scimAuthenticate)
+		scim.Middleware.Skip(setCurrentUser, scimServiceProviderConfig, scimResourceTypes, scimUsersList,
+			scimUsersGet, scimUsersCreate, scimUsersReplace, scimUsersPatch, scimUsersDelete, scimGroupsList,
+			scimGroupsGet, scimGroupsPatch, scimGroupsReplace)
+		scim.GET("/ServiceProviderConfig", scimServiceProviderConfig)
+		scim.GET("/ResourceTypes", scimResourceTypes)
+		scim.GET("/Users", scimUsersList)
+		scim.POST("/Users", scimUsersCreate)
+		scim.GET("/Users/{user_id}", scimUsersGet)
+		scim.PUT("/Users/{user_id}", scimUsersReplace)
+		scim.PATCH("/Users/{user_id}", scimUsersPatch)
+		scim.DELETE("/Users/{user_id}", scimUsersDelete)
+		scim.GET("/Groups", scimGroupsList)
+		scim.GET("/Groups/{group_id}", scimGroupsGet)
+		scim.PATCH("/Groups/{group_id}", scimGroupsPatch)
+		scim.PUT("/Groups/{group_id}", scimGroupsReplace)
 
 		job.Init(&app.Worker)
+
+		listeners.RegisterListener()
+
+		registerMetrics()
 	}
 
 	return app
@@ -166,3 +274,21 @@ func translations() buffalo.MiddlewareFunc {
 	}
 	return domain.T.Middleware()
 }
+
+// dispatchOutboxEvents is middleware that starts delivery of the events written to the outbox during a request, as
+// soon as the request's transaction has committed. It must be used before popmw.Transaction.
+func dispatchOutboxEvents(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		err := next(c)
+
+		committed := err == nil
+		if res, ok := c.Response().(*buffalo.Response); ok && res.Status >= http.StatusBadRequest {
+			committed = false
+		}
+
+		tx, _ := c.Value(domain.ContextKeyTx).(*pop.Connection)
+		models.FinishOutboxTransaction(tx, committed)
+
+		return err
+	}
+}
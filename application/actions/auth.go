@@ -637,6 +637,9 @@ func authDestroy(c buffalo.Context) error {
 		return logErrorAndRedirect(c, api.ErrorFindingOrgForAccessToken, err.Error())
 	}
 
+	// the SAML provider needs the user's NameID for single logout
+	c.Set(domain.ContextKeyAuthID, uat.UserOrganization.AuthID)
+
 	authUser, err := uat.GetUser(tx)
 	if err != nil {
 		return logErrorAndRedirect(c, api.ErrorAuthProvidersLogout, err.Error())
@@ -649,6 +652,9 @@ func authDestroy(c buffalo.Context) error {
 		return logErrorAndRedirect(c, api.ErrorLoadingAuthProvider, err.Error())
 	}
 
+	// the session is cleared first, so the provider can keep what it needs to complete the logout
+	c.Session().Clear()
+
 	authResp := authPro.Logout(c)
 	if authResp.Error != nil {
 		return logErrorAndRedirect(c, api.ErrorAuthProvidersLogout, authResp.Error.Error())
@@ -662,7 +668,6 @@ func authDestroy(c buffalo.Context) error {
 		if err != nil {
 			return logErrorAndRedirect(c, api.ErrorDeletingAccessToken, err.Error())
 		}
-		c.Session().Clear()
 		redirectURL = authResp.RedirectURL
 	}
 
@@ -676,27 +681,26 @@ func setCurrentUser(next buffalo.Handler) buffalo.Handler {
 			return c.Error(http.StatusUnauthorized, errors.New("no Bearer token provided"))
 		}
 
-		var userAccessToken models.UserAccessToken
-		err := userAccessToken.FindByBearerToken(models.DB, bearerToken)
-		if err != nil {
-			if domain.IsOtherThanNoRows(err) {
-				log.WithContext(c).Error(err)
-			}
-			return c.Error(http.StatusUnauthorized, errors.New("invalid bearer token"))
+		var ipAddress string
+		if ip, err := getClientIPAddress(c); err == nil {
+			ipAddress = ip.String()
 		}
 
-		isExpired, err := userAccessToken.DeleteIfExpired(models.DB)
-		if err != nil {
-			log.WithContext(c).Error(err)
+		var user models.User
+		var err error
+		if strings.HasPrefix(bearerToken, models.PersonalAccessTokenPrefix) {
+			user, err = authenticatePersonalAccessToken(c, bearerToken, ipAddress)
+		} else {
+			user, err = authenticateUserAccessToken(c, bearerToken, ipAddress)
 		}
-
-		if isExpired {
-			return c.Error(http.StatusUnauthorized, errors.New("expired bearer token"))
-		}
-
-		user, err := userAccessToken.GetUser(models.DB)
 		if err != nil {
-			return c.Error(http.StatusInternalServerError, fmt.Errorf("error finding user by access token, %s", err.Error()))
+			return err
+		}
+		if user.IsSuspended() {
+			appErr := api.NewAppError(fmt.Errorf("user %s is suspended", user.UUID), api.ErrorUserSuspended,
+				api.CategoryForbidden)
+			appErr.HttpStatus = http.StatusForbidden
+			return reportError(c, appErr)
 		}
 		c.Set(domain.ContextKeyCurrentUser, user)
 
@@ -711,6 +715,66 @@ func setCurrentUser(next buffalo.Handler) buffalo.Handler {
 	}
 }
 
+// authenticateUserAccessToken validates a session token, records its use, and returns its user
+func authenticateUserAccessToken(c buffalo.Context, bearerToken, ipAddress string) (models.User, error) {
+	var userAccessToken models.UserAccessToken
+	err := userAccessToken.FindByBearerToken(models.DB, bearerToken)
+	if err != nil {
+		if domain.IsOtherThanNoRows(err) {
+			log.WithContext(c).Error(err)
+		}
+		return models.User{}, c.Error(http.StatusUnauthorized, errors.New("invalid bearer token"))
+	}
+
+	isExpired, err := userAccessToken.DeleteIfExpired(models.DB)
+	if err != nil {
+		log.WithContext(c).Error(err)
+	}
+
+	if isExpired {
+		return models.User{}, c.Error(http.StatusUnauthorized, errors.New("expired bearer token"))
+	}
+
+	if err := userAccessToken.Touch(models.DB, ipAddress); err != nil {
+		log.WithContext(c).Error(err)
+	}
+	c.Set(domain.ContextKeyCurrentAccessToken, userAccessToken)
+
+	user, err := userAccessToken.GetUser(models.DB)
+	if err != nil {
+		return models.User{}, c.Error(http.StatusInternalServerError,
+			fmt.Errorf("error finding user by access token, %s", err.Error()))
+	}
+	return user, nil
+}
+
+// authenticatePersonalAccessToken validates a personal access token, records its use, and returns its owner
+func authenticatePersonalAccessToken(c buffalo.Context, bearerToken, ipAddress string) (models.User, error) {
+	var pat models.PersonalAccessToken
+	if err := pat.FindByBearerToken(models.DB, bearerToken); err != nil {
+		if domain.IsOtherThanNoRows(err) {
+			log.WithContext(c).Error(err)
+		}
+		return models.User{}, c.Error(http.StatusUnauthorized, errors.New("invalid personal access token"))
+	}
+
+	if pat.IsExpired() {
+		return models.User{}, c.Error(http.StatusUnauthorized, errors.New("expired personal access token"))
+	}
+
+	if err := pat.Touch(models.DB, ipAddress); err != nil {
+		log.WithContext(c).Error(err)
+	}
+	c.Set(domain.ContextKeyCurrentPersonalAccessToken, pat)
+
+	user, err := pat.GetUser(models.DB)
+	if err != nil {
+		return models.User{}, c.Error(http.StatusInternalServerError,
+			fmt.Errorf("error finding user by personal access token, %s", err.Error()))
+	}
+	return user, nil
+}
+
 // getLoginSuccessRedirectURL generates the URL for redirection after a successful login
 func getLoginSuccessRedirectURL(authUser AuthUser, returnTo string) string {
 	uiURL := domain.Env.UIURL
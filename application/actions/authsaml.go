@@ -0,0 +1,142 @@
+package actions
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	saml2 "github.com/russellhaering/gosaml2"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/auth/saml"
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/log"
+	"github.com/silinternational/wecarry-api/models"
+)
+
+const samlMetadataContentType = "application/samlmetadata+xml"
+
+// authSAMLMetadata renders the signed SAML SP metadata of an Organization, for configuring its IdP
+func authSAMLMetadata(c buffalo.Context) error {
+	org, providers, err := findSAMLOrganization(c)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	if len(providers) == 0 {
+		err := errors.New("organization " + org.UUID.String() + " does not use SAML")
+		return reportError(c, api.NewAppError(err, api.ErrorSAMLNotConfigured, api.CategoryNotFound))
+	}
+
+	metadata, err := providers[0].Metadata()
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorSAMLMetadata, api.CategoryInternal))
+	}
+
+	return c.Render(http.StatusOK, r.Func(samlMetadataContentType, func(w io.Writer, _ render.Data) error {
+		_, err := w.Write(metadata)
+		return err
+	}))
+}
+
+// authSAMLSingleLogout is an Organization's SAML single logout service. It handles LogoutRequests from the IdP by
+// ending the sessions the user started through the Organization, and LogoutResponses to LogoutRequests sent by
+// authDestroy.
+func authSAMLSingleLogout(c buffalo.Context) error {
+	if c.Param("SAMLResponse") != "" {
+		return authSAMLLogoutResponse(c)
+	}
+
+	if c.Param("SAMLRequest") == "" {
+		return logErrorAndRedirect(c, api.ErrorSAMLLogoutRequest, "SAMLRequest is required for single logout")
+	}
+
+	org, providers, err := findSAMLOrganization(c)
+	if err != nil {
+		return logErrorAndRedirect(c, api.ErrorSAMLLogoutRequest, err.Error())
+	}
+
+	var provider *saml.Provider
+	var logoutRequest *saml2.LogoutRequest
+	err = errors.New("organization " + org.UUID.String() + " does not use SAML")
+	for _, p := range providers {
+		if logoutRequest, err = p.ValidateLogoutRequest(c.Request()); err == nil {
+			provider = p
+			break
+		}
+	}
+	if provider == nil {
+		return logErrorAndRedirect(c, api.ErrorSAMLLogoutRequest, err.Error())
+	}
+
+	tx := models.Tx(c)
+	var userOrgs models.UserOrganizations
+	if err := userOrgs.FindByAuthID(tx, logoutRequest.NameID.Value, org.ID); err != nil {
+		return logErrorAndRedirect(c, api.ErrorFindingUserOrgs, err.Error())
+	}
+
+	for _, userOrg := range userOrgs {
+		var tokens models.UserAccessTokens
+		if err := tokens.DeleteByUserOrganization(tx, userOrg); err != nil {
+			return logErrorAndRedirect(c, api.ErrorDeletingAccessToken, err.Error())
+		}
+		log.WithContext(c).Infof("ended sessions of user id %d in organization %s by saml single logout",
+			userOrg.UserID, org.UUID)
+	}
+
+	redirectURL, err := provider.LogoutResponseURL(logoutRequest, c.Param("RelayState"))
+	if err != nil {
+		return logErrorAndRedirect(c, api.ErrorSAMLLogoutRequest, err.Error())
+	}
+
+	c.Session().Clear()
+	return c.Redirect(http.StatusFound, redirectURL)
+}
+
+// authSAMLLogoutResponse completes a logout started by authDestroy, once the IdP's LogoutResponse to the
+// LogoutRequest kept in the session is validated
+func authSAMLLogoutResponse(c buffalo.Context) error {
+	requestID, _ := c.Session().Get(saml.LogoutRequestIDSessionKey).(string)
+
+	_, providers, err := findSAMLOrganization(c)
+	if err != nil {
+		return logErrorAndRedirect(c, api.ErrorSAMLLogoutResponse, err.Error())
+	}
+
+	err = errors.New("organization does not use SAML")
+	for _, p := range providers {
+		if err = p.ValidateLogoutResponse(c.Request(), requestID); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return logErrorAndRedirect(c, api.ErrorSAMLLogoutResponse, err.Error())
+	}
+
+	// authDestroy already deleted the access token before sending the LogoutRequest
+	c.Session().Clear()
+	return c.Redirect(http.StatusFound, domain.MarketingSiteURL)
+}
+
+// findSAMLOrganization finds the Organization identified by the `org_id` param and its SAML providers
+func findSAMLOrganization(c buffalo.Context) (models.Organization, []*saml.Provider, error) {
+	orgID, err := getUUIDFromParam(c, "org_id")
+	if err != nil {
+		return models.Organization{}, nil, err
+	}
+
+	tx := models.Tx(c)
+	var org models.Organization
+	if err := org.FindByUUID(tx, orgID.String()); err != nil {
+		return org, nil, api.NewAppError(err, api.ErrorOrganizationNotFound, api.CategoryNotFound)
+	}
+
+	providers, err := org.GetSAMLProviders(tx)
+	if err != nil {
+		return org, nil, api.NewAppError(err, api.ErrorLoadingAuthProvider, api.CategoryInternal)
+	}
+
+	return org, providers, nil
+}
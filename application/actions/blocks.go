@@ -0,0 +1,111 @@
+package actions
+
+import (
+	"net/http"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/models"
+)
+
+// swagger:operation GET /users/me/blocks Users UsersMeBlocks
+//
+// Lists the users blocked by the authenticated User, most recently blocked first.
+//
+// ---
+// responses:
+//   '200':
+//     description: blocked users
+//     schema:
+//       "$ref": "#/definitions/Users"
+func usersMeBlocks(c buffalo.Context) error {
+	user := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	blocked, err := user.GetBlockedUsers(tx)
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorUserBlocksLoadFailure, api.CategoryInternal))
+	}
+
+	output, err := models.ConvertUsers(c, blocked)
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorFailedToConvertToAPIType, api.CategoryInternal))
+	}
+
+	return c.Render(http.StatusOK, r.JSON(output))
+}
+
+// swagger:operation PUT /users/me/blocks/{user_id} Users UsersMeBlock
+//
+// Blocks a user. A blocked user cannot see the authenticated User's requests, offer on them, or send messages to
+// the authenticated User. Blocking a user who is already blocked has no effect.
+//
+// ---
+// parameters:
+//   - name: user_id
+//     in: path
+//     required: true
+//     description: ID of the user to block
+// responses:
+//   '204':
+//     description: OK but no content in response
+func usersMeBlock(c buffalo.Context) error {
+	user := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	blocked, err := findUserFromParam(c, tx)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	if err := user.Block(tx, blocked); err != nil {
+		return reportError(c, err)
+	}
+
+	return c.Render(http.StatusNoContent, nil)
+}
+
+// swagger:operation DELETE /users/me/blocks/{user_id} Users UsersMeUnblock
+//
+// Unblocks a user blocked by the authenticated User.
+//
+// ---
+// parameters:
+//   - name: user_id
+//     in: path
+//     required: true
+//     description: ID of the user to unblock
+// responses:
+//   '204':
+//     description: OK but no content in response
+func usersMeUnblock(c buffalo.Context) error {
+	user := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	blocked, err := findUserFromParam(c, tx)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	if err := user.Unblock(tx, blocked); err != nil {
+		return reportError(c, err)
+	}
+
+	return c.Render(http.StatusNoContent, nil)
+}
+
+// findUserFromParam finds the User identified by the `user_id` param
+func findUserFromParam(c buffalo.Context, tx *pop.Connection) (models.User, error) {
+	id, err := getUUIDFromParam(c, "user_id")
+	if err != nil {
+		return models.User{}, err
+	}
+
+	var user models.User
+	if err := user.FindByUUID(tx, id.String()); err != nil {
+		return models.User{}, api.NewAppError(err, api.ErrorUserNotFound, api.CategoryNotFound)
+	}
+	return user, nil
+}
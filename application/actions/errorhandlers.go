@@ -16,9 +16,11 @@ import (
 var httpErrorCodes = map[int]api.ErrorKey{
 	http.StatusBadRequest:          api.ErrorBadRequest,
 	http.StatusUnauthorized:        api.ErrorNotAuthenticated,
+	http.StatusForbidden:           api.ErrorNotAuthorized,
 	http.StatusNotFound:            api.ErrorRouteNotFound,
 	http.StatusMethodNotAllowed:    api.ErrorMethodNotAllowed,
 	http.StatusUnprocessableEntity: api.ErrorUnprocessableEntity,
+	http.StatusTooManyRequests:     api.ErrorTooManyRequests,
 	http.StatusInternalServerError: api.ErrorInternalServerError,
 }
 
@@ -0,0 +1,123 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+
+	"github.com/silinternational/wecarry-api/aws"
+	"github.com/silinternational/wecarry-api/cache"
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/job"
+	"github.com/silinternational/wecarry-api/log"
+	"github.com/silinternational/wecarry-api/models"
+	"github.com/silinternational/wecarry-api/notifications"
+)
+
+const (
+	healthStatusOK    = "ok"
+	healthStatusError = "error"
+
+	// healthCheckTimeout limits each dependency check, so that a dependency that hangs fails the probe rather than
+	// holding it up
+	healthCheckTimeout = 3 * time.Second
+)
+
+// dependencyCheck verifies that a dependency of the API is usable
+type dependencyCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// readinessChecks are the dependencies that must be usable for a pod to receive traffic
+var readinessChecks = []dependencyCheck{
+	{name: "database", check: func(ctx context.Context) error {
+		return models.DB.WithContext(ctx).RawQuery("SELECT 1").Exec()
+	}},
+	{name: "migrations", check: func(ctx context.Context) error {
+		return models.CheckMigrations(models.DB.WithContext(ctx))
+	}},
+	{name: "redis", check: cache.Ping},
+	{name: "storage", check: aws.CheckBucket},
+	{name: "worker", check: job.Ping},
+	{name: "email", check: func(context.Context) error {
+		return notifications.CheckEmailConfig()
+	}},
+}
+
+// healthReport is the response of the liveness and readiness endpoints
+type healthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]healthCheck `json:"checks,omitempty"`
+}
+
+// healthCheck is the outcome of the check of one dependency. The error is only included for callers that provide the
+// service integration token, since it may reveal details of the infrastructure.
+type healthCheck struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// livenessHandler responds as long as the API is able to serve requests. It does not check any dependency, since
+// restarting the API would not fix a broken dependency.
+func livenessHandler(c buffalo.Context) error {
+	return c.Render(http.StatusOK, r.JSON(healthReport{Status: healthStatusOK}))
+}
+
+// readinessHandler checks each dependency of the API, and responds with a report of the outcome and latency of each
+// check. The response status is 503 if any of the checks failed.
+func readinessHandler(c buffalo.Context) error {
+	showErrors := domain.Env.ServiceIntegrationToken != "" &&
+		domain.Env.ServiceIntegrationToken == domain.GetBearerTokenFromRequest(c.Request())
+
+	report := runHealthChecks(c, readinessChecks, showErrors)
+
+	status := http.StatusOK
+	if report.Status != healthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	return c.Render(status, r.JSON(report))
+}
+
+// runHealthChecks runs the checks concurrently, each limited to healthCheckTimeout
+func runHealthChecks(ctx context.Context, checks []dependencyCheck, showErrors bool) healthReport {
+	results := make([]healthCheck, len(checks))
+
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := checks[i].check(checkCtx)
+			results[i].LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+
+			results[i].Status = healthStatusOK
+			if err != nil {
+				log.Errorf("%s health check failed, %s", checks[i].name, err)
+				results[i].Status = healthStatusError
+				if showErrors {
+					results[i].Error = err.Error()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	report := healthReport{Status: healthStatusOK, Checks: map[string]healthCheck{}}
+	for i, result := range results {
+		report.Checks[checks[i].name] = result
+		if result.Status != healthStatusOK {
+			report.Status = healthStatusError
+		}
+	}
+	return report
+}
@@ -0,0 +1,46 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+func (as *ActionSuite) Test_livenessHandler() {
+	responseBody := makeCall(as, http.MethodGet, "/site/live", "", nil)
+
+	var report healthReport
+	as.NoError(json.Unmarshal(responseBody, &report), "response body parsing error")
+	as.Equal(healthStatusOK, report.Status)
+	as.Empty(report.Checks)
+}
+
+func (as *ActionSuite) Test_runHealthChecks() {
+	good := dependencyCheck{name: "good", check: func(context.Context) error { return nil }}
+	bad := dependencyCheck{name: "bad", check: func(context.Context) error { return errors.New("unreachable") }}
+	slow := dependencyCheck{name: "slow", check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	report := runHealthChecks(context.Background(), []dependencyCheck{good}, true)
+	as.Equal(healthStatusOK, report.Status)
+	as.Equal(healthStatusOK, report.Checks["good"].Status)
+
+	report = runHealthChecks(context.Background(), []dependencyCheck{good, bad}, false)
+	as.Equal(healthStatusError, report.Status)
+	as.Equal(healthStatusOK, report.Checks["good"].Status)
+	as.Equal(healthStatusError, report.Checks["bad"].Status)
+	as.Empty(report.Checks["bad"].Error, "error should not be shown")
+
+	report = runHealthChecks(context.Background(), []dependencyCheck{bad}, true)
+	as.Equal("unreachable", report.Checks["bad"].Error)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	report = runHealthChecks(ctx, []dependencyCheck{slow}, true)
+	as.Equal(healthStatusError, report.Checks["slow"].Status)
+	as.Equal(context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+}
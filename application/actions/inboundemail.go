@@ -0,0 +1,138 @@
+package actions
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/log"
+	"github.com/silinternational/wecarry-api/models"
+	"github.com/silinternational/wecarry-api/notifications"
+)
+
+// maxInboundEmailSize limits the size of a posted inbound email
+const maxInboundEmailSize = 4 * domain.MaxFileSize
+
+// swagger:operation POST /email/inbound Messages InboundEmail
+//
+// Receives email sent to the reply-to addresses of thread message notifications, and adds each reply to its thread
+// as a message from the participant the reply-to address was given to. Accepts the SendGrid Inbound Parse webhook
+// (parsed or raw), Amazon SES notifications delivered by Amazon SNS, and raw MIME (message/rfc822) messages.
+// Requires HTTP Basic authentication with the configured inbound email secret as the password.
+//
+// ---
+// responses:
+//   '204':
+//     description: the email was received
+func emailInbound(c buffalo.Context) error {
+	if domain.Env.EmailInboundSecret == "" || domain.Env.EmailReplyDomain == "" {
+		return c.Error(http.StatusNotFound, errors.New("inbound email is not configured"))
+	}
+
+	_, password, _ := c.Request().BasicAuth()
+	if subtle.ConstantTimeCompare([]byte(password), []byte(domain.Env.EmailInboundSecret)) != 1 {
+		return c.Error(http.StatusUnauthorized, errors.New("incorrect inbound email secret provided"))
+	}
+
+	email, err := parseInboundEmail(c)
+	if err != nil {
+		return c.Error(http.StatusBadRequest, err)
+	}
+	if email == nil {
+		return c.Render(http.StatusNoContent, nil)
+	}
+
+	content := notifications.ExtractReply(email.Text)
+	if content == "" {
+		log.Warningf("inbound email from %s has no reply content", email.From)
+		return c.Render(http.StatusNoContent, nil)
+	}
+
+	tx := models.Tx(c)
+	for _, recipient := range email.Recipients {
+		if !strings.HasSuffix(strings.ToLower(recipient), "@"+strings.ToLower(domain.Env.EmailReplyDomain)) {
+			continue
+		}
+
+		var message models.Message
+		if err := message.CreateFromEmailReply(tx, recipient, content); err != nil {
+			// Not an error for the mail service, which would only retry it
+			log.Warningf("failed to create message from email reply to %s, %s", recipient, err)
+			continue
+		}
+		log.Infof("created message %s from email reply", message.UUID)
+	}
+
+	return c.Render(http.StatusNoContent, nil)
+}
+
+// parseInboundEmail parses the posted email according to its format. If the request is an Amazon SNS subscription
+// confirmation, the subscription is confirmed and no email is returned.
+func parseInboundEmail(c buffalo.Context) (*notifications.InboundEmail, error) {
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, maxInboundEmailSize)
+
+	if req.Header.Get("x-amz-sns-message-type") != "" {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error reading request body, %s", err)
+		}
+
+		email, subscribeURL, err := notifications.ParseSESInboundEmail(body)
+		if err != nil {
+			return nil, err
+		}
+		if subscribeURL != "" {
+			return nil, confirmSNSSubscription(subscribeURL)
+		}
+		return &email, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch mediaType {
+	case "multipart/form-data", "application/x-www-form-urlencoded":
+		if err := req.ParseMultipartForm(maxInboundEmailSize); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			return nil, fmt.Errorf("error parsing inbound email form, %s", err)
+		}
+		email, err := notifications.ParseSendGridInboundEmail(req.Form)
+		return &email, err
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading request body, %s", err)
+	}
+	email, err := notifications.ParseRawInboundEmail(body)
+	return &email, err
+}
+
+// confirmSNSSubscription confirms the subscription of the inbound email endpoint to an Amazon SNS topic
+func confirmSNSSubscription(subscribeURL string) error {
+	u, err := url.Parse(subscribeURL)
+	if err != nil || u.Scheme != "https" || !strings.HasSuffix(u.Hostname(), ".amazonaws.com") {
+		return fmt.Errorf("invalid SNS SubscribeURL '%s'", subscribeURL)
+	}
+
+	client := http.Client{Timeout: 10 * time.Second}
+	res, err := client.Get(u.String())
+	if err != nil {
+		return fmt.Errorf("failed to confirm SNS subscription, %s", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to confirm SNS subscription, status %d", res.StatusCode)
+	}
+
+	log.Infof("confirmed SNS subscription for inbound email")
+	return nil
+}
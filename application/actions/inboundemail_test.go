@@ -0,0 +1,108 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gobuffalo/httptest"
+
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/models"
+)
+
+func (as *ActionSuite) Test_emailInbound() {
+	f := createFixturesForThreads(as)
+
+	replyDomain, secret := domain.Env.EmailReplyDomain, domain.Env.EmailInboundSecret
+	defer func() {
+		domain.Env.EmailReplyDomain, domain.Env.EmailInboundSecret = replyDomain, secret
+	}()
+	domain.Env.EmailReplyDomain = "reply.example.com"
+	domain.Env.EmailInboundSecret = "inbound-secret"
+
+	var tp models.ThreadParticipant
+	as.NoError(tp.FindByThreadIDAndUserID(as.DB, f.Threads[0].ID, f.Users[0].ID))
+	replyTo, err := tp.GetReplyToAddress(as.DB)
+	as.NoError(err)
+
+	rawEmail := func(to, text string) string {
+		return fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: Re: new message\r\n\r\n%s\r\n\r\n"+
+			"On Mon, Oct 19, 2026 at 9:00 AM WeCarry <no_reply@example.com> wrote:\r\n> Message from %s\r\n",
+			f.Users[0].Email, to, text, f.Users[1].Nickname)
+	}
+
+	tests := []struct {
+		name        string
+		secret      string
+		contentType string
+		body        string
+		wantCode    int
+		wantContent string
+	}{
+		{
+			name:        "wrong secret",
+			secret:      "wrong",
+			contentType: "message/rfc822",
+			body:        rawEmail(replyTo, "wrong secret"),
+			wantCode:    http.StatusUnauthorized,
+		},
+		{
+			name:        "unknown reply-to address",
+			secret:      domain.Env.EmailInboundSecret,
+			contentType: "message/rfc822",
+			body:        rawEmail("reply+0123456789abcdef0123456789abcdef@reply.example.com", "unknown"),
+			wantCode:    http.StatusNoContent,
+		},
+		{
+			name:        "raw",
+			secret:      domain.Env.EmailInboundSecret,
+			contentType: "message/rfc822",
+			body:        rawEmail(replyTo, "Reply by raw email"),
+			wantCode:    http.StatusNoContent,
+			wantContent: "Reply by raw email",
+		},
+		{
+			name:        "sendgrid",
+			secret:      domain.Env.EmailInboundSecret,
+			contentType: "application/x-www-form-urlencoded",
+			body: url.Values{
+				"to":   {replyTo},
+				"from": {f.Users[0].Email},
+				"text": {"Reply by SendGrid\n\n-- \n" + f.Users[0].Nickname},
+			}.Encode(),
+			wantCode:    http.StatusNoContent,
+			wantContent: "Reply by SendGrid",
+		},
+	}
+	for _, tt := range tests {
+		as.T().Run(tt.name, func(t *testing.T) {
+			res := postInboundEmail(as, tt.secret, tt.contentType, strings.NewReader(tt.body))
+			as.Equal(tt.wantCode, res.Code, "incorrect status code, body: %s", res.Body.String())
+
+			var messages models.Messages
+			as.NoError(as.DB.Where("thread_id = ?", f.Threads[0].ID).Order("id desc").All(&messages))
+			if tt.wantContent == "" {
+				as.Equal(len(f.Messages), len(messages), "no message should have been created")
+				return
+			}
+
+			as.Equal(tt.wantContent, messages[0].Content, "incorrect message content")
+			as.Equal(f.Users[0].ID, messages[0].SentByID, "message was not created for the participant")
+			f.Messages = messages
+		})
+	}
+}
+
+func postInboundEmail(as *ActionSuite, secret, contentType string, body io.Reader) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/email/inbound", body)
+	req.SetBasicAuth("wecarry", secret)
+	req.Header.Set("content-type", contentType)
+
+	rr := httptest.NewRecorder()
+	as.App.ServeHTTP(rr, req)
+	return rr
+}
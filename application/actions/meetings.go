@@ -71,6 +71,10 @@ func meetingsCreate(c buffalo.Context) error {
 		return reportError(c, err)
 	}
 
+	if ok, err := limitMeetingInvites(c, len(models.SplitEmailList(input.Emails))); !ok {
+		return err
+	}
+
 	tx := models.Tx(c)
 
 	meeting, err := convertMeetingCreateInput(c, input)
@@ -53,6 +53,92 @@ func messagesCreate(c buffalo.Context) error {
 	return c.Render(200, render.JSON(output))
 }
 
+// swagger:operation PUT /messages/{message_id} Messages UpdateMessage
+//
+// Edit the content of one of the User's messages. A message can only be edited for a limited time after it was sent.
+//
+// ---
+// parameters:
+//   - name: message
+//     in: body
+//     description: message update input object
+//     required: true
+//     schema:
+//       "$ref": "#/definitions/MessageUpdateInput"
+// responses:
+//   '200':
+//     description: conversation/thread that contains the message
+//     schema:
+//       "$ref": "#/definitions/Thread"
+func messagesUpdate(c buffalo.Context) error {
+	var input api.MessageUpdateInput
+	if err := StrictBind(c, &input); err != nil {
+		return reportError(c, err)
+	}
+
+	id, err := getUUIDFromParam(c, "message_id")
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	user := models.CurrentUser(c)
+	tx := models.Tx(c)
+	var message models.Message
+
+	if err := message.UpdateFromInput(tx, user, id.String(), input); err != nil {
+		return reportError(c, err)
+	}
+
+	return renderMessageThread(c, message, user)
+}
+
+// swagger:operation DELETE /messages/{message_id} Messages RemoveMessage
+//
+// Delete one of the User's messages. The message remains in its thread, without its content or files.
+//
+// ---
+// responses:
+//   '200':
+//     description: conversation/thread that contains the message
+//     schema:
+//       "$ref": "#/definitions/Thread"
+func messagesRemove(c buffalo.Context) error {
+	id, err := getUUIDFromParam(c, "message_id")
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	user := models.CurrentUser(c)
+	tx := models.Tx(c)
+	var message models.Message
+
+	if err := message.DeleteForSender(tx, user, id.String()); err != nil {
+		return reportError(c, err)
+	}
+
+	return renderMessageThread(c, message, user)
+}
+
+// renderMessageThread renders the thread that contains the given message
+func renderMessageThread(c buffalo.Context, message models.Message, user models.User) error {
+	tx := models.Tx(c)
+
+	if err := tx.Load(&message, "Thread"); err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorThreadNotFound, api.CategoryInternal))
+	}
+
+	if err := message.Thread.LoadForAPI(tx, user); err != nil {
+		return reportError(c, err)
+	}
+
+	output, err := models.ConvertThread(c, message.Thread)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	return c.Render(200, render.JSON(output))
+}
+
 func convertMessagesToAPIType(ctx context.Context, messages models.Messages) (api.Messages, error) {
 	var output api.Messages
 	if err := api.ConvertToOtherType(messages, &output); err != nil {
@@ -2,8 +2,11 @@ package actions
 
 import (
 	"fmt"
+	"net/http"
+	"testing"
 
 	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/models"
 )
 
 func (as *ActionSuite) TestMessagesCreate() {
@@ -47,3 +50,73 @@ func (as *ActionSuite) TestMessagesCreate() {
 	}
 	as.verifyResponseData(wantContains, body, "In TestMessagesCreate")
 }
+
+func (as *ActionSuite) TestMessagesUpdate() {
+	f := createFixturesForMessagesCreate(as)
+	user0 := f.Users[0]
+	message1 := f.Messages[1]
+
+	tests := []struct {
+		name         string
+		user         models.User
+		messageID    string
+		wantStatus   int
+		wantContains []string
+	}{
+		{
+			name:         "not the sender",
+			user:         f.Users[1],
+			messageID:    message1.UUID.String(),
+			wantStatus:   http.StatusNotFound,
+			wantContains: []string{api.ErrorNotAuthorized.String()},
+		},
+		{
+			name:       "good",
+			user:       user0,
+			messageID:  message1.UUID.String(),
+			wantStatus: http.StatusOK,
+			wantContains: []string{
+				fmt.Sprintf(`"id":"%s"`, f.Threads[0].UUID),
+				`"content":"Edited reply"`,
+				`"is_edited":true`,
+			},
+		},
+	}
+	for _, tc := range tests {
+		as.T().Run(tc.name, func(t *testing.T) {
+			req := as.JSON("/messages/%s", tc.messageID)
+			req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", tc.user.Nickname)
+			req.Headers["content-type"] = "application/json"
+			res := req.Put(api.MessageUpdateInput{Content: "Edited reply"})
+
+			body := res.Body.String()
+			as.Equal(tc.wantStatus, res.Code, "incorrect status code returned, body: %s", body)
+			as.verifyResponseData(tc.wantContains, body, "In TestMessagesUpdate")
+		})
+	}
+}
+
+func (as *ActionSuite) TestMessagesRemove() {
+	f := createFixturesForMessagesCreate(as)
+	user0 := f.Users[0]
+	message1 := f.Messages[1]
+
+	req := as.JSON("/messages/%s", message1.UUID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", user0.Nickname)
+	res := req.Delete()
+
+	body := res.Body.String()
+	as.Equal(http.StatusOK, res.Code, "incorrect status code returned, body: %s", body)
+
+	wantContains := []string{
+		fmt.Sprintf(`"id":"%s"`, message1.UUID),
+		fmt.Sprintf(`"content":"%s"`, f.Messages[0].Content),
+		`"content":""`,
+		`"is_deleted":true`,
+	}
+	as.verifyResponseData(wantContains, body, "In TestMessagesRemove")
+
+	var message models.Message
+	as.NoError(message.FindByID(as.DB, message1.ID))
+	as.True(message.IsDeleted(), "message was not marked as deleted")
+}
@@ -0,0 +1,109 @@
+package actions
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/log"
+	"github.com/silinternational/wecarry-api/metrics"
+	"github.com/silinternational/wecarry-api/models"
+)
+
+// activeUserWindow is how recently a user must have used the API to be counted as active
+const activeUserWindow = 30 * 24 * time.Hour
+
+var (
+	requestsDesc = prometheus.NewDesc("wecarry_requests", "Number of requests not yet completed or removed, by status",
+		[]string{"status"}, nil)
+	activeUsersDesc = prometheus.NewDesc("wecarry_active_users",
+		"Number of users that have used the API in the last 30 days", nil, nil)
+)
+
+// businessCollector collects the business metrics from the database each time the metrics are scraped
+type businessCollector struct{}
+
+// Describe implements prometheus.Collector
+func (businessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- requestsDesc
+	ch <- activeUsersDesc
+}
+
+// Collect implements prometheus.Collector
+func (businessCollector) Collect(ch chan<- prometheus.Metric) {
+	counts, err := models.CountRequestsByStatus(models.DB)
+	if err != nil {
+		log.Error(err)
+	}
+	for status, n := range counts {
+		ch <- prometheus.MustNewConstMetric(requestsDesc, prometheus.GaugeValue, float64(n), status.String())
+	}
+
+	n, err := models.CountActiveUsers(models.DB, time.Now().Add(-activeUserWindow))
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(activeUsersDesc, prometheus.GaugeValue, float64(n))
+}
+
+// registerMetrics adds the metrics that are collected from the database
+func registerMetrics() {
+	if err := metrics.RegisterDBStats(models.DBStats); err != nil {
+		log.Errorf("error registering database metrics, %s", err)
+	}
+	if err := metrics.Register(businessCollector{}); err != nil {
+		log.Errorf("error registering business metrics, %s", err)
+	}
+}
+
+// recordRequestMetrics is middleware that records the duration and response status of each request
+func recordRequestMetrics(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		start := time.Now()
+		err := next(c)
+
+		route := "unknown"
+		if ri, ok := c.Value("current_route").(buffalo.RouteInfo); ok {
+			route = ri.Path
+		}
+
+		metrics.ObserveHTTPRequest(c.Request().Method, route, responseStatus(c, err), time.Since(start))
+		return err
+	}
+}
+
+// responseStatus determines the status of the response to a request. An error has not yet been rendered when the
+// middleware returns, so its status is taken from the error.
+func responseStatus(c buffalo.Context, err error) int {
+	if err != nil {
+		var httpErr buffalo.HTTPError
+		if errors.As(err, &httpErr) {
+			return httpErr.Status
+		}
+		return http.StatusInternalServerError
+	}
+	if res, ok := c.Response().(*buffalo.Response); ok && res.Status != 0 {
+		return res.Status
+	}
+	return http.StatusOK
+}
+
+// metricsHandler serves the metrics in the Prometheus text format. It requires the service integration token.
+func metricsHandler(c buffalo.Context) error {
+	if domain.Env.ServiceIntegrationToken == "" {
+		return c.Error(http.StatusInternalServerError, errors.New("no ServiceIntegrationToken configured"))
+	}
+
+	bearerToken := domain.GetBearerTokenFromRequest(c.Request())
+	if domain.Env.ServiceIntegrationToken != bearerToken {
+		return c.Error(http.StatusUnauthorized, errors.New("incorrect bearer token provided"))
+	}
+
+	metrics.Handler().ServeHTTP(c.Response(), c.Request())
+	return nil
+}
@@ -0,0 +1,55 @@
+package actions
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/internal/test"
+)
+
+func (as *ActionSuite) Test_metricsHandler() {
+	test.CreateRequestFixtures(as.DB, 1, false)
+
+	tests := []struct {
+		name         string
+		token        string
+		wantKey      api.ErrorKey
+		wantContains []string
+	}{
+		{
+			name:    "incorrect bearer token",
+			token:   "bad token",
+			wantKey: api.ErrorNotAuthenticated,
+		},
+		{
+			name:  "good",
+			token: domain.Env.ServiceIntegrationToken,
+			wantContains: []string{
+				`wecarry_http_request_duration_seconds_count{method="GET",route="/metrics`,
+				`wecarry_requests{status="OPEN"} 1`,
+				`wecarry_active_users `,
+				`wecarry_db_open_connections `,
+			},
+		},
+	}
+	for _, tt := range tests {
+		as.T().Run(tt.name, func(t *testing.T) {
+			responseBody := makeCall(as, http.MethodGet, "/metrics", tt.token, nil)
+
+			if tt.wantKey != "" {
+				var appError api.AppError
+				as.NoError(json.Unmarshal(responseBody, &appError), "response body parsing error")
+				as.Equal(http.StatusUnauthorized, appError.Code, "incorrect http error response code")
+				as.Equal(tt.wantKey, appError.Key, "incorrect http error response key")
+				return
+			}
+
+			for _, w := range tt.wantContains {
+				as.Contains(string(responseBody), w, "metrics are not correct")
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package actions
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/models"
+)
+
+// swagger:operation DELETE /organizations/{org_id}/users/{user_id}/sessions Organizations OrganizationsUserSessionsRemove
+//
+// Ends all sessions a member of the Organization started by logging in through the Organization. Only available
+// to admins of the Organization.
+//
+// ---
+// parameters:
+//   - name: org_id
+//     in: path
+//     required: true
+//     description: ID of the organization
+//   - name: user_id
+//     in: path
+//     required: true
+//     description: ID of the member
+// responses:
+//   '204':
+//     description: OK but no content in response
+func organizationsUserSessionsRemove(c buffalo.Context) error {
+	cUser := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	org, err := findOrganizationForAdmin(c, tx, cUser)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	userID, err := getUUIDFromParam(c, "user_id")
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	var member models.User
+	if err := member.FindByUUID(tx, userID.String()); err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorOrganizationMemberNotFound, api.CategoryNotFound))
+	}
+
+	userOrg, err := member.FindUserOrganization(tx, org)
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorOrganizationMemberNotFound, api.CategoryNotFound))
+	}
+
+	var tokens models.UserAccessTokens
+	if err := tokens.DeleteByUserOrganization(tx, userOrg); err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorSessionDeleteFailure, api.CategoryInternal))
+	}
+
+	return c.Render(http.StatusNoContent, nil)
+}
+
+// swagger:operation POST /organizations/{org_id}/scim-token Organizations OrganizationsSCIMTokenCreate
+//
+// Creates the bearer token for the Organization's SCIM endpoint, replacing any previous token. The token is only
+// provided in this response. Only available to admins of the Organization.
+//
+// ---
+// parameters:
+//   - name: org_id
+//     in: path
+//     required: true
+//     description: ID of the organization
+// responses:
+//   '200':
+//     description: the SCIM endpoint and bearer token
+//     schema:
+//       "$ref": "#/definitions/SCIMToken"
+func organizationsSCIMTokenCreate(c buffalo.Context) error {
+	cUser := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	org, err := findOrganizationForAdmin(c, tx, cUser)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	token, err := org.CreateSCIMToken(tx)
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorOrganizationSCIMToken, api.CategoryInternal))
+	}
+
+	return c.Render(http.StatusOK, r.JSON(api.SCIMToken{
+		BaseURL: scimBaseURL(org),
+		Token:   token,
+	}))
+}
+
+// swagger:operation DELETE /organizations/{org_id}/scim-token Organizations OrganizationsSCIMTokenRemove
+//
+// Revokes the bearer token for the Organization's SCIM endpoint, disabling provisioning. Only available to admins
+// of the Organization.
+//
+// ---
+// parameters:
+//   - name: org_id
+//     in: path
+//     required: true
+//     description: ID of the organization
+// responses:
+//   '204':
+//     description: OK but no content in response
+func organizationsSCIMTokenRemove(c buffalo.Context) error {
+	cUser := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	org, err := findOrganizationForAdmin(c, tx, cUser)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	if err := org.RemoveSCIMToken(tx); err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorOrganizationSCIMToken, api.CategoryInternal))
+	}
+
+	return c.Render(http.StatusNoContent, nil)
+}
+
+// findOrganizationForAdmin finds the Organization identified by the `org_id` param and verifies that the given
+// user is allowed to administer it
+func findOrganizationForAdmin(c buffalo.Context, tx *pop.Connection, user models.User) (models.Organization, error) {
+	orgID, err := getUUIDFromParam(c, "org_id")
+	if err != nil {
+		return models.Organization{}, err
+	}
+
+	var org models.Organization
+	if err := org.FindByUUID(tx, orgID.String()); err != nil {
+		return models.Organization{}, api.NewAppError(err, api.ErrorOrganizationNotFound, api.CategoryNotFound)
+	}
+
+	if !user.CanEditOrganization(tx, org.ID) {
+		err := errors.New("user is not an admin of the organization")
+		return models.Organization{}, api.NewAppError(err, api.ErrorNotAuthorized, api.CategoryForbidden)
+	}
+
+	return org, nil
+}
@@ -0,0 +1,177 @@
+package actions
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/log"
+	"github.com/silinternational/wecarry-api/models"
+	"github.com/silinternational/wecarry-api/ratelimit"
+)
+
+// rateLimitPolicy is a limit on the requests of each user or client IP address
+type rateLimitPolicy struct {
+	name  string
+	limit ratelimit.Limit
+}
+
+// rateLimitPolicies holds the rate limits, as configured by the RATE_LIMIT_* environment variables
+var rateLimitPolicies struct {
+	ip             *rateLimitPolicy
+	user           *rateLimitPolicy
+	routes         map[string]rateLimitPolicy
+	meetingInvites *rateLimitPolicy
+}
+
+// initRateLimits reads the rate limit policies from the environment. An invalid policy is logged and ignored.
+func initRateLimits() {
+	rateLimitPolicies.ip = newRateLimitPolicy("ip", domain.Env.RateLimitIP)
+	rateLimitPolicies.user = newRateLimitPolicy("user", domain.Env.RateLimitUser)
+	rateLimitPolicies.meetingInvites = newRateLimitPolicy("meeting_invites", domain.Env.RateLimitMeetingInvites)
+
+	rateLimitPolicies.routes = map[string]rateLimitPolicy{}
+	for _, entry := range strings.Split(domain.Env.RateLimitRoutes, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		route, limit, ok := strings.Cut(entry, "=")
+		method, path, ok2 := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok || !ok2 {
+			log.Errorf("invalid route rate limit '%s', must be of the form 'METHOD /path=requests/window'", entry)
+			continue
+		}
+		key := routeKey(method, path)
+		if p := newRateLimitPolicy("route:"+key, limit); p != nil {
+			rateLimitPolicies.routes[key] = *p
+		}
+	}
+}
+
+// newRateLimitPolicy parses the limit of a policy. If the limit is empty or invalid, there is no policy.
+func newRateLimitPolicy(name, limit string) *rateLimitPolicy {
+	if strings.TrimSpace(limit) == "" {
+		return nil
+	}
+	l, err := ratelimit.ParseLimit(limit)
+	if err != nil {
+		log.Errorf("invalid %s rate limit, %s", name, err)
+		return nil
+	}
+	return &rateLimitPolicy{name: name, limit: l}
+}
+
+// routeKey identifies a route in the route rate limits. Buffalo route paths always end with a slash.
+func routeKey(method, path string) string {
+	path = strings.TrimSpace(path)
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return strings.ToUpper(strings.TrimSpace(method)) + " " + path
+}
+
+// limitRequestsByIP is middleware that applies the client IP address rate limit. It comes before authentication, so
+// that requests with invalid credentials are also limited.
+func limitRequestsByIP(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		if isRateLimitExempt(c) || rateLimitPolicies.ip == nil {
+			return next(c)
+		}
+
+		if ok, err := applyRateLimit(c, *rateLimitPolicies.ip, clientIPSubject(c), 1); !ok {
+			return err
+		}
+		return next(c)
+	}
+}
+
+// limitRequestsByUser is middleware that applies the user rate limit and the limit of the route, if any. The route
+// limit is counted by user if the route requires authentication, otherwise by client IP address.
+func limitRequestsByUser(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		if isRateLimitExempt(c) {
+			return next(c)
+		}
+
+		subject := clientIPSubject(c)
+		if user, ok := c.Value(domain.ContextKeyCurrentUser).(models.User); ok && user.ID != 0 {
+			subject = "user:" + user.UUID.String()
+			if rateLimitPolicies.user != nil {
+				if ok, err := applyRateLimit(c, *rateLimitPolicies.user, subject, 1); !ok {
+					return err
+				}
+			}
+		}
+
+		if ri, ok := c.Value("current_route").(buffalo.RouteInfo); ok {
+			if policy, ok := rateLimitPolicies.routes[routeKey(ri.Method, ri.Path)]; ok {
+				if ok, err := applyRateLimit(c, policy, subject, 1); !ok {
+					return err
+				}
+			}
+		}
+
+		return next(c)
+	}
+}
+
+// limitMeetingInvites applies the meeting invite rate limit to the invitations to be sent by the current user. If
+// the limit is exceeded, it returns false and the error rendered by reportError.
+func limitMeetingInvites(c buffalo.Context, count int) (bool, error) {
+	if count == 0 || isRateLimitExempt(c) || rateLimitPolicies.meetingInvites == nil {
+		return true, nil
+	}
+	user := models.CurrentUser(c)
+	return applyRateLimit(c, *rateLimitPolicies.meetingInvites, "user:"+user.UUID.String(), count)
+}
+
+// applyRateLimit counts a request of the given cost against the policy, and sets the RateLimit headers of the
+// response. If the limit is exceeded, it returns false and the error rendered by reportError. If the limit cannot be
+// checked, the request is allowed.
+func applyRateLimit(c buffalo.Context, policy rateLimitPolicy, subject string, cost int) (bool, error) {
+	result, err := ratelimit.Allow(c, policy.name+":"+subject, policy.limit, cost)
+	if err != nil {
+		log.Errorf("rate limit not applied, %s", err)
+		return true, nil
+	}
+
+	reset := strconv.Itoa(int(math.Ceil(result.Reset.Seconds())))
+	header := c.Response().Header()
+	header.Set("RateLimit-Limit", strconv.Itoa(result.Limit.Requests))
+	header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	header.Set("RateLimit-Reset", reset)
+
+	if result.Allowed {
+		return true, nil
+	}
+
+	header.Set("Retry-After", reset)
+	appErr := api.NewAppError(fmt.Errorf("%s rate limit of %s exceeded by %s", policy.name, policy.limit, subject),
+		api.ErrorTooManyRequests, api.CategoryUser)
+	appErr.HttpStatus = http.StatusTooManyRequests
+	return false, reportError(c, appErr)
+}
+
+// isRateLimitExempt returns true if rate limiting is disabled or the request has the service integration token
+func isRateLimitExempt(c buffalo.Context) bool {
+	if !domain.Env.RateLimitEnabled {
+		return true
+	}
+	token := domain.Env.ServiceIntegrationToken
+	return token != "" && domain.GetBearerTokenFromRequest(c.Request()) == token
+}
+
+// clientIPSubject identifies the client IP address in a rate limit
+func clientIPSubject(c buffalo.Context) string {
+	ip, err := getClientIPAddress(c)
+	if err != nil {
+		return "ip:unknown"
+	}
+	return "ip:" + ip.String()
+}
@@ -0,0 +1,86 @@
+package actions
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/ratelimit"
+)
+
+func (as *ActionSuite) Test_initRateLimits() {
+	env := domain.Env
+	policies := rateLimitPolicies
+	defer func() {
+		domain.Env = env
+		rateLimitPolicies = policies
+	}()
+
+	domain.Env.RateLimitIP = "100/m"
+	domain.Env.RateLimitUser = ""
+	domain.Env.RateLimitMeetingInvites = "bad"
+	domain.Env.RateLimitRoutes = "POST /auth/login=10/m, post /requests/=20/h,/upload=5/m,"
+	initRateLimits()
+
+	as.Equal(ratelimit.Limit{Requests: 100, Window: time.Minute}, rateLimitPolicies.ip.limit)
+	as.Nil(rateLimitPolicies.user)
+	as.Nil(rateLimitPolicies.meetingInvites, "invalid limit should be ignored")
+	as.Len(rateLimitPolicies.routes, 2, "route without a method should be ignored")
+	as.Equal(ratelimit.Limit{Requests: 10, Window: time.Minute}, rateLimitPolicies.routes["POST /auth/login/"].limit)
+	as.Equal(ratelimit.Limit{Requests: 20, Window: time.Hour}, rateLimitPolicies.routes["POST /requests/"].limit)
+}
+
+func (as *ActionSuite) Test_limitRequestsByUser() {
+	env := domain.Env
+	policies := rateLimitPolicies
+	defer func() {
+		domain.Env = env
+		rateLimitPolicies = policies
+	}()
+
+	domain.Env.RateLimitEnabled = true
+	domain.Env.RateLimitIP = ""
+	domain.Env.RateLimitUser = ""
+	domain.Env.RateLimitRoutes = "POST /auth/invite=2/m"
+	initRateLimits()
+
+	// a client address of its own, so that earlier runs of the test are not counted
+	id := domain.GetUUID()
+	clientIP := net.IP(id[:4]).String()
+
+	call := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/auth/invite", strings.NewReader("{}"))
+		req.Header.Set("CF-Connecting-IP", clientIP)
+		req.Header.Set("content-type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		as.App.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for _, remaining := range []string{"1", "0"} {
+		rr := call("")
+		as.NotEqual(http.StatusTooManyRequests, rr.Code)
+		as.Equal("2", rr.Header().Get("RateLimit-Limit"))
+		as.Equal(remaining, rr.Header().Get("RateLimit-Remaining"))
+	}
+
+	rr := call("")
+	as.Equal(http.StatusTooManyRequests, rr.Code)
+	as.NotEmpty(rr.Header().Get("Retry-After"))
+
+	var appErr api.AppError
+	as.NoError(json.Unmarshal(rr.Body.Bytes(), &appErr))
+	as.Equal(api.ErrorTooManyRequests, appErr.Key)
+
+	rr = call(domain.Env.ServiceIntegrationToken)
+	as.NotEqual(http.StatusTooManyRequests, rr.Code, "service token should be exempt")
+	as.Empty(rr.Header().Get("RateLimit-Limit"))
+}
@@ -0,0 +1,221 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/models"
+)
+
+// swagger:operation POST /requests/{request_id}/report Requests RequestsReport
+//
+// Reports a request as abusive, fraudulent or otherwise inappropriate. The report is reviewed by the admins of the
+// request's organization. Reporting the same request again while the first report is open has no effect.
+//
+// ---
+// parameters:
+//   - name: request_id
+//     in: path
+//     required: true
+//     description: ID of the request to report
+//   - name: ReportInput
+//     in: body
+//     required: true
+//     schema:
+//       "$ref": "#/definitions/ReportInput"
+// responses:
+//   '200':
+//     description: the report
+//     schema:
+//       "$ref": "#/definitions/Report"
+func requestsReport(c buffalo.Context) error {
+	return createReport(c, "request_id", (*models.Report).CreateForRequest)
+}
+
+// swagger:operation POST /messages/{message_id}/report Messages MessagesReport
+//
+// Reports a message as abusive, fraudulent or otherwise inappropriate. Only the participants of the message's thread
+// may report it. The report is reviewed by the admins of the organization of the thread's request.
+//
+// ---
+// parameters:
+//   - name: message_id
+//     in: path
+//     required: true
+//     description: ID of the message to report
+//   - name: ReportInput
+//     in: body
+//     required: true
+//     schema:
+//       "$ref": "#/definitions/ReportInput"
+// responses:
+//   '200':
+//     description: the report
+//     schema:
+//       "$ref": "#/definitions/Report"
+func messagesReport(c buffalo.Context) error {
+	return createReport(c, "message_id", (*models.Report).CreateForMessage)
+}
+
+// swagger:operation POST /users/{user_id}/report Users UsersReport
+//
+// Reports a user as abusive, fraudulent or otherwise inappropriate. The report is reviewed by the admins of the
+// user's organizations.
+//
+// ---
+// parameters:
+//   - name: user_id
+//     in: path
+//     required: true
+//     description: ID of the user to report
+//   - name: ReportInput
+//     in: body
+//     required: true
+//     schema:
+//       "$ref": "#/definitions/ReportInput"
+// responses:
+//   '200':
+//     description: the report
+//     schema:
+//       "$ref": "#/definitions/Report"
+func usersReport(c buffalo.Context) error {
+	return createReport(c, "user_id", (*models.Report).CreateForUser)
+}
+
+type reportCreateFunc func(r *models.Report, tx *pop.Connection, reporter models.User, id string,
+	input api.ReportInput) error
+
+// createReport reports the item identified by the given path param, using the given Report creation method
+func createReport(c buffalo.Context, param string, create reportCreateFunc) error {
+	cUser := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	id, err := getUUIDFromParam(c, param)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	var input api.ReportInput
+	if err := StrictBind(c, &input); err != nil {
+		return reportError(c, err)
+	}
+
+	var report models.Report
+	if err := create(&report, tx, cUser, id.String(), input); err != nil {
+		return reportError(c, err)
+	}
+
+	output, err := models.ConvertReport(c, report)
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorFailedToConvertToAPIType, api.CategoryInternal))
+	}
+
+	return c.Render(http.StatusOK, r.JSON(output))
+}
+
+// swagger:operation GET /moderation/reports Moderation ModerationReports
+//
+// Lists the reports the authenticated User may moderate, oldest first. Super admins may moderate all reports.
+// Organization admins may moderate the reports of requests and messages of their organizations, and the reports of
+// members of their organizations.
+//
+// ---
+// parameters:
+//   - name: status
+//     in: query
+//     required: false
+//     description: status of the reports to list, `OPEN` (default), `DISMISSED`, or `ACTIONED`
+// responses:
+//   '200':
+//     description: reports
+//     schema:
+//       "$ref": "#/definitions/Reports"
+func moderationReports(c buffalo.Context) error {
+	cUser := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	if !cUser.IsModerator(tx) {
+		err := errors.New("user is not a moderator")
+		return reportError(c, api.NewAppError(err, api.ErrorNotAuthorized, api.CategoryForbidden))
+	}
+
+	status := models.ReportStatusOpen
+	if s := c.Param("status"); s != "" {
+		status = models.ReportStatus(s)
+		if !status.IsValid() {
+			err := fmt.Errorf("invalid report status '%s'", s)
+			return reportError(c, api.NewAppError(err, api.ErrorReportInputInvalid, api.CategoryUser))
+		}
+	}
+
+	var reports models.Reports
+	if err := reports.FindForModerator(tx, cUser, status); err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorReportsLoadFailure, api.CategoryInternal))
+	}
+
+	output, err := models.ConvertReports(c, reports)
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorFailedToConvertToAPIType, api.CategoryInternal))
+	}
+
+	return c.Render(http.StatusOK, r.JSON(output))
+}
+
+// swagger:operation PUT /moderation/reports/{report_id} Moderation ModerationReportsResolve
+//
+// Resolves an open report by taking a moderation action: `hide_request` removes the reported request (or the
+// request of the reported message), `suspend_user` suspends the reported user and ends all of their sessions, and
+// `dismiss` resolves the report without action. Hiding a request requires being an admin of its organization, and
+// suspending a user requires being an admin of one of the user's organizations. Super admins may take any action.
+//
+// ---
+// parameters:
+//   - name: report_id
+//     in: path
+//     required: true
+//     description: ID of the report
+//   - name: ReportActionInput
+//     in: body
+//     required: true
+//     schema:
+//       "$ref": "#/definitions/ReportActionInput"
+// responses:
+//   '200':
+//     description: the resolved report
+//     schema:
+//       "$ref": "#/definitions/Report"
+func moderationReportsResolve(c buffalo.Context) error {
+	cUser := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	id, err := getUUIDFromParam(c, "report_id")
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	var input api.ReportActionInput
+	if err := StrictBind(c, &input); err != nil {
+		return reportError(c, err)
+	}
+
+	var report models.Report
+	if appErr := report.FindByUUIDForModerator(tx, id.String(), cUser); appErr != nil {
+		return reportError(c, appErr)
+	}
+
+	if appErr := report.Resolve(tx, cUser, models.ReportAction(input.Action)); appErr != nil {
+		return reportError(c, appErr)
+	}
+
+	output, err := models.ConvertReport(c, report)
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorFailedToConvertToAPIType, api.CategoryInternal))
+	}
+
+	return c.Render(http.StatusOK, r.JSON(output))
+}
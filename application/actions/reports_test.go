@@ -0,0 +1,199 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/internal/test"
+	"github.com/silinternational/wecarry-api/models"
+)
+
+type reportFixtures struct {
+	models.Users
+	models.Requests
+}
+
+// createFixturesForReports creates 3 users in the same organization, of which users[2] is an admin of the
+// organization, and a request by users[0]
+func createFixturesForReports(as *ActionSuite) reportFixtures {
+	uf := test.CreateUserFixtures(as.DB, 3)
+
+	var userOrg models.UserOrganization
+	as.NoError(as.DB.Where("user_id = ?", uf.Users[2].ID).First(&userOrg))
+	userOrg.Role = models.UserOrganizationRoleAdmin
+	as.NoError(as.DB.Update(&userOrg))
+
+	requests := test.CreateRequestFixtures(as.DB, 1, false, uf.Users[0].ID)
+
+	return reportFixtures{Users: uf.Users, Requests: requests}
+}
+
+func (as *ActionSuite) TestUsersMeBlocks() {
+	f := createFixturesForReports(as)
+	blocker, blocked := f.Users[0], f.Users[1]
+	path := fmt.Sprintf("/users/me/blocks/%s", blocked.UUID)
+
+	req := as.JSON(path)
+	req.Headers["Authorization"] = "Bearer " + blocker.Nickname
+	res := req.Put(nil)
+	as.Equal(http.StatusNoContent, res.Code, "incorrect status code, body: %s", res.Body.String())
+
+	req = as.JSON("/users/me/blocks")
+	req.Headers["Authorization"] = "Bearer " + blocker.Nickname
+	res = req.Get()
+	body := res.Body.String()
+	as.Equal(http.StatusOK, res.Code, "incorrect status code, body: %s", body)
+	as.verifyResponseData([]string{fmt.Sprintf(`"id":"%s"`, blocked.UUID)}, body, "In TestUsersMeBlocks")
+
+	req = as.JSON("/requests/%s", f.Requests[0].UUID)
+	req.Headers["Authorization"] = "Bearer " + blocked.Nickname
+	res = req.Get()
+	as.Equal(http.StatusNotFound, res.Code, "blocked user should not see the request, body: %s", res.Body.String())
+
+	listRequests := func(user models.User) string {
+		req := as.JSON("/requests")
+		req.Headers["Authorization"] = "Bearer " + user.Nickname
+		res := req.Get()
+		as.Equal(http.StatusOK, res.Code, "incorrect status code, body: %s", res.Body.String())
+		return res.Body.String()
+	}
+	requestID := f.Requests[0].UUID.String()
+	as.Contains(listRequests(f.Users[2]), requestID, "other users should see the request in the list")
+	as.NotContains(listRequests(blocked), requestID, "blocked user should not see the request in the list")
+
+	req = as.JSON(path)
+	req.Headers["Authorization"] = "Bearer " + blocker.Nickname
+	res = req.Delete()
+	as.Equal(http.StatusNoContent, res.Code, "incorrect status code, body: %s", res.Body.String())
+
+	req = as.JSON("/requests/%s", f.Requests[0].UUID)
+	req.Headers["Authorization"] = "Bearer " + blocked.Nickname
+	res = req.Get()
+	as.Equal(http.StatusOK, res.Code, "unblocked user should see the request, body: %s", res.Body.String())
+	as.Contains(listRequests(blocked), requestID, "unblocked user should see the request in the list")
+
+	req = as.JSON("/users/me/blocks/%s", blocker.UUID)
+	req.Headers["Authorization"] = "Bearer " + blocker.Nickname
+	res = req.Put(nil)
+	as.Equal(http.StatusBadRequest, res.Code, "incorrect status code, body: %s", res.Body.String())
+	as.Contains(res.Body.String(), api.ErrorUserBlockSelf.String())
+}
+
+func (as *ActionSuite) TestRequestsReport() {
+	f := createFixturesForReports(as)
+
+	tests := []struct {
+		name         string
+		user         models.User
+		requestID    string
+		input        api.ReportInput
+		wantStatus   int
+		wantContains []string
+	}{
+		{
+			name:       "invalid reason",
+			user:       f.Users[1],
+			requestID:  f.Requests[0].UUID.String(),
+			input:      api.ReportInput{Reason: "BORING"},
+			wantStatus: http.StatusBadRequest,
+			wantContains: []string{
+				`"key":"` + api.ErrorReportInputInvalid.String(),
+			},
+		},
+		{
+			name:       "own request",
+			user:       f.Users[0],
+			requestID:  f.Requests[0].UUID.String(),
+			input:      api.ReportInput{Reason: models.ReportReasonSpam.String()},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "good",
+			user:       f.Users[1],
+			requestID:  f.Requests[0].UUID.String(),
+			input:      api.ReportInput{Reason: models.ReportReasonFraud.String()},
+			wantStatus: http.StatusOK,
+			wantContains: []string{
+				`"subject_type":"REQUEST"`,
+				`"reason":"FRAUD"`,
+				`"status":"OPEN"`,
+				fmt.Sprintf(`"request_id":"%s"`, f.Requests[0].UUID),
+				fmt.Sprintf(`"reported_user":{"id":"%s"`, f.Users[0].UUID),
+			},
+		},
+	}
+	for _, tt := range tests {
+		as.T().Run(tt.name, func(t *testing.T) {
+			req := as.JSON("/requests/%s/report", tt.requestID)
+			req.Headers["Authorization"] = "Bearer " + tt.user.Nickname
+			res := req.Post(tt.input)
+
+			body := res.Body.String()
+			as.Equal(tt.wantStatus, res.Code, "incorrect status code, body: %s", body)
+			as.verifyResponseData(tt.wantContains, body, "In TestRequestsReport")
+		})
+	}
+}
+
+func (as *ActionSuite) TestModerationReports() {
+	f := createFixturesForReports(as)
+
+	var report models.Report
+	as.NoError(report.CreateForUser(as.DB, f.Users[1], f.Users[0].UUID.String(),
+		api.ReportInput{Reason: models.ReportReasonAbuse.String()}))
+
+	req := as.JSON("/moderation/reports")
+	req.Headers["Authorization"] = "Bearer " + f.Users[1].Nickname
+	res := req.Get()
+	as.Equal(http.StatusNotFound, res.Code, "non-moderator should not list reports, body: %s", res.Body.String())
+
+	req = as.JSON("/moderation/reports")
+	req.Headers["Authorization"] = "Bearer " + f.Users[2].Nickname
+	res = req.Get()
+	body := res.Body.String()
+	as.Equal(http.StatusOK, res.Code, "incorrect status code, body: %s", body)
+	as.verifyResponseData([]string{fmt.Sprintf(`"id":"%s"`, report.UUID)}, body, "In TestModerationReports")
+
+	_, pat, err := f.Users[0].CreatePersonalAccessToken(as.DB, "suspended",
+		[]models.TokenScope{models.TokenScopeRequestsRead}, nulls.Time{})
+	as.NoError(err)
+
+	req = as.JSON("/moderation/reports/%s", report.UUID)
+	req.Headers["Authorization"] = "Bearer " + f.Users[2].Nickname
+	res = req.Put(api.ReportActionInput{Action: models.ReportActionSuspendUser.String()})
+	body = res.Body.String()
+	as.Equal(http.StatusOK, res.Code, "incorrect status code, body: %s", body)
+	as.verifyResponseData([]string{`"status":"ACTIONED"`, `"action":"suspend_user"`}, body,
+		"In TestModerationReports")
+
+	req = as.JSON("/moderation/reports/%s", report.UUID)
+	req.Headers["Authorization"] = "Bearer " + f.Users[2].Nickname
+	res = req.Put(api.ReportActionInput{Action: models.ReportActionDismiss.String()})
+	as.Equal(http.StatusBadRequest, res.Code, "resolved report should not be resolved again")
+
+	// the suspended user's sessions have ended, so a new session is needed to check that access is refused
+	token := models.UserAccessToken{
+		UserID:      f.Users[0].ID,
+		AccessToken: models.HashClientIdAccessToken("suspended"),
+		ClientID:    "suspended",
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	test.MustCreate(as.DB, &token)
+
+	req = as.JSON("/users/me")
+	req.Headers["Authorization"] = "Bearer suspended"
+	res = req.Get()
+	body = res.Body.String()
+	as.Equal(http.StatusForbidden, res.Code, "suspended user should not be authenticated, body: %s", body)
+	as.Contains(body, api.ErrorUserSuspended.String())
+
+	req = as.JSON("/requests/")
+	req.Headers["Authorization"] = "Bearer " + pat
+	res = req.Get()
+	as.Equal(http.StatusUnauthorized, res.Code, "personal access token of a suspended user should be revoked")
+}
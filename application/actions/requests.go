@@ -21,7 +21,7 @@ import (
 
 // swagger:operation GET /requests Requests ListRequests
 //
-// gets the list of requests for the current user
+// gets the list of requests for the current user, without the requests of users who blocked them
 //
 // ---
 // responses:
@@ -43,6 +43,25 @@ func requestsList(c buffalo.Context) error {
 		return reportError(c, api.NewAppError(err, api.ErrorGetRequests, api.CategoryInternal))
 	}
 
+	// the cached lists are shared by all users, so the requests of users who blocked this one are dropped here
+	blockers, err := cUser.GetBlockerUUIDs(tx)
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorGetRequests, api.CategoryInternal))
+	}
+	if len(blockers) > 0 {
+		isBlocker := map[uuid.UUID]bool{}
+		for _, id := range blockers {
+			isBlocker[id] = true
+		}
+		visible := make([]api.RequestAbridged, 0, len(requestsList))
+		for _, r := range requestsList {
+			if r.CreatedBy == nil || !isBlocker[r.CreatedBy.ID] {
+				visible = append(visible, r)
+			}
+		}
+		requestsList = visible
+	}
+
 	return c.Render(200, render.JSON(requestsList))
 }
 
@@ -316,6 +335,7 @@ func convertRequestUpdateInput(ctx context.Context, input api.RequestUpdateInput
 		request.MeetingID = nulls.Int{}
 	}
 
+	oldNeededBefore := request.NeededBefore
 	if input.NeededBefore.Valid {
 		if err := addNeededBeforeToRequest(tx, input.NeededBefore, &request); err != nil {
 			return request, err
@@ -324,6 +344,11 @@ func convertRequestUpdateInput(ctx context.Context, input api.RequestUpdateInput
 		request.NeededBefore = nulls.Time{}
 	}
 
+	// a new date deserves a new reminder
+	if !request.NeededBefore.Time.Equal(oldNeededBefore.Time) {
+		request.RemindedAt = nulls.Time{}
+	}
+
 	return request, nil
 }
 
@@ -562,3 +587,158 @@ func requestsUpdateStatus(c buffalo.Context) error {
 
 	return c.Render(200, render.JSON(output))
 }
+
+// swagger:operation POST /requests/{request_id}/duplicate Requests RequestsDuplicate
+//
+// create a new open request with the details of an existing one, in any status, made by the current user
+//
+// ---
+// parameters:
+//   - name: request_id
+//     in: path
+//     required: true
+//     description: ID of the request to duplicate
+//   - name: RequestDuplicateInput
+//     in: body
+//     required: true
+//     description: input object
+//     schema:
+//       "$ref": "#/definitions/RequestDuplicateInput"
+//
+// responses:
+//   '200':
+//     description: the new request
+//     schema:
+//       "$ref": "#/definitions/Request"
+func requestsDuplicate(c buffalo.Context) error {
+	var input api.RequestDuplicateInput
+	if err := StrictBind(c, &input); err != nil {
+		return reportError(c, err)
+	}
+
+	requestID, err := getUUIDFromParam(c, "request_id")
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	tx := models.Tx(c)
+	var request models.Request
+	if err := request.FindByUUID(tx, requestID.String()); err != nil {
+		appError := api.NewAppError(err, api.ErrorGetRequest, api.CategoryNotFound)
+		if domain.IsOtherThanNoRows(err) {
+			appError.Category = api.CategoryInternal
+		}
+		return reportError(c, appError)
+	}
+
+	cUser := models.CurrentUser(c)
+	if request.CreatedByID != cUser.ID {
+		err = errors.New("only the creator of a request may duplicate it")
+		return reportError(c, api.NewAppError(err, api.ErrorDuplicateRequestNotCreator, api.CategoryForbidden))
+	}
+
+	neededBefore, err := parseNeededBefore(input.NeededBefore)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	newRequest, err := request.Duplicate(tx, neededBefore)
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorDuplicateRequest, api.CategoryUser))
+	}
+
+	output, err := models.ConvertRequest(c, newRequest)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	return c.Render(200, render.JSON(output))
+}
+
+// swagger:operation PUT /requests/{request_id}/renew Requests RequestsRenew
+//
+// reopen an expired request made by the current user, with a new needed-before date
+//
+// ---
+// parameters:
+//   - name: request_id
+//     in: path
+//     required: true
+//     description: ID of the request to renew
+//   - name: RequestRenewInput
+//     in: body
+//     required: true
+//     description: input object
+//     schema:
+//       "$ref": "#/definitions/RequestRenewInput"
+//
+// responses:
+//   '200':
+//     description: the renewed request
+//     schema:
+//       "$ref": "#/definitions/Request"
+func requestsRenew(c buffalo.Context) error {
+	var input api.RequestRenewInput
+	if err := StrictBind(c, &input); err != nil {
+		return reportError(c, err)
+	}
+
+	requestID, err := getUUIDFromParam(c, "request_id")
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	tx := models.Tx(c)
+	var request models.Request
+	if err := request.FindByUUID(tx, requestID.String()); err != nil {
+		appError := api.NewAppError(err, api.ErrorGetRequest, api.CategoryNotFound)
+		if domain.IsOtherThanNoRows(err) {
+			appError.Category = api.CategoryInternal
+		}
+		return reportError(c, appError)
+	}
+
+	cUser := models.CurrentUser(c)
+	if request.CreatedByID != cUser.ID {
+		err = errors.New("only the creator of a request may renew it")
+		return reportError(c, api.NewAppError(err, api.ErrorRenewRequestNotCreator, api.CategoryForbidden))
+	}
+
+	if request.Status != models.RequestStatusExpired {
+		err = errors.New("only an expired request may be renewed, the status is " + request.Status.String())
+		return reportError(c, api.NewAppError(err, api.ErrorRenewRequestNotExpired, api.CategoryUser))
+	}
+
+	neededBefore, err := parseNeededBefore(input.NeededBefore)
+	if err != nil {
+		return reportError(c, err)
+	}
+	if !neededBefore.Valid {
+		err = errors.New("a needed_before date is required to renew a request")
+		return reportError(c, api.NewAppError(err, api.ErrorRenewRequest, api.CategoryUser))
+	}
+
+	if err := request.Renew(tx, neededBefore.Time); err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorRenewRequest, api.CategoryUser))
+	}
+
+	output, err := models.ConvertRequest(c, request)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	return c.Render(200, render.JSON(output))
+}
+
+// parseNeededBefore parses an optional needed-before date from API input
+func parseNeededBefore(neededBefore nulls.String) (nulls.Time, error) {
+	if !neededBefore.Valid {
+		return nulls.Time{}, nil
+	}
+	n, err := time.Parse(domain.DateFormat, neededBefore.String)
+	if err != nil {
+		err = errors.New("failed to parse NeededBefore, " + err.Error())
+		return nulls.Time{}, api.NewAppError(err, api.ErrorCreateRequestInvalidDate, api.CategoryUser)
+	}
+	return nulls.NewTime(n), nil
+}
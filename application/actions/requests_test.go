@@ -714,3 +714,178 @@ func (as *ActionSuite) Test_requestsUpdateStatus() {
 		as.verifyResponseData(wantData, body, fmt.Sprintf(`step "%s", `, step.name))
 	}
 }
+
+func (as *ActionSuite) Test_requestsDuplicate() {
+	uf := test.CreateUserFixtures(as.DB, 2)
+	creator := uf.Users[0]
+	requests := test.CreateRequestFixtures(as.DB, 1, false, creator.ID)
+
+	request := requests[0]
+	request.Status = models.RequestStatusCompleted
+	as.NoError(as.DB.Update(&request))
+
+	neededBefore := time.Now().Add(domain.DurationWeek).Format(domain.DateFormat)
+
+	steps := []struct {
+		name         string
+		user         models.User
+		requestID    string
+		neededBefore nulls.String
+		wantStatus   int
+		wantKey      api.ErrorKey
+	}{
+		{
+			name:       "request ID not found",
+			user:       creator,
+			requestID:  domain.GetUUID().String(),
+			wantStatus: http.StatusNotFound,
+			wantKey:    api.ErrorGetRequest,
+		},
+		{
+			name:       "non-creator can't duplicate",
+			user:       uf.Users[1],
+			requestID:  request.UUID.String(),
+			wantStatus: http.StatusNotFound,
+			wantKey:    api.ErrorDuplicateRequestNotCreator,
+		},
+		{
+			name:         "bad date",
+			user:         creator,
+			requestID:    request.UUID.String(),
+			neededBefore: nulls.NewString("next week"),
+			wantStatus:   http.StatusBadRequest,
+			wantKey:      api.ErrorCreateRequestInvalidDate,
+		},
+		{
+			name:         "date in the past",
+			user:         creator,
+			requestID:    request.UUID.String(),
+			neededBefore: nulls.NewString(time.Now().Add(-domain.DurationDay).Format(domain.DateFormat)),
+			wantStatus:   http.StatusBadRequest,
+			wantKey:      api.ErrorDuplicateRequest,
+		},
+		{
+			name:         "creator can duplicate a completed request",
+			user:         creator,
+			requestID:    request.UUID.String(),
+			neededBefore: nulls.NewString(neededBefore),
+			wantStatus:   http.StatusOK,
+		},
+	}
+
+	for _, step := range steps {
+		input := api.RequestDuplicateInput{NeededBefore: step.neededBefore}
+
+		req := as.JSON("/requests/" + step.requestID + "/duplicate")
+		req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", step.user.Nickname)
+		req.Headers["content-type"] = "application/json"
+		res := req.Post(&input)
+
+		body := res.Body.String()
+		as.Equal(step.wantStatus, res.Code, `step "%s", incorrect status code returned, body: %s`, step.name, body)
+
+		if step.wantStatus != http.StatusOK {
+			as.verifyResponseData([]string{string(step.wantKey)}, body, fmt.Sprintf(`step "%s", `, step.name))
+			continue
+		}
+		wantData := []string{
+			`"status":"OPEN"`,
+			`"title":"` + request.Title + `"`,
+			`"needed_before":"` + neededBefore + `"`,
+		}
+		as.verifyResponseData(wantData, body, fmt.Sprintf(`step "%s", `, step.name))
+		as.NotContains(body, `"id":"`+request.UUID.String()+`"`)
+	}
+}
+
+func (as *ActionSuite) Test_requestsRenew() {
+	uf := test.CreateUserFixtures(as.DB, 2)
+	creator := uf.Users[0]
+	requests := test.CreateRequestFixtures(as.DB, 2, false, creator.ID)
+
+	expired := requests[0]
+	expired.Status = models.RequestStatusExpired
+	expired.NeededBefore = nulls.NewTime(time.Now().Add(-2 * domain.DurationWeek))
+	as.NoError(as.DB.Update(&expired))
+
+	neededBefore := time.Now().Add(domain.DurationWeek).Format(domain.DateFormat)
+
+	steps := []struct {
+		name         string
+		user         models.User
+		requestID    string
+		neededBefore nulls.String
+		wantStatus   int
+		wantKey      api.ErrorKey
+	}{
+		{
+			name:         "request ID not found",
+			user:         creator,
+			requestID:    domain.GetUUID().String(),
+			neededBefore: nulls.NewString(neededBefore),
+			wantStatus:   http.StatusNotFound,
+			wantKey:      api.ErrorGetRequest,
+		},
+		{
+			name:         "non-creator can't renew",
+			user:         uf.Users[1],
+			requestID:    expired.UUID.String(),
+			neededBefore: nulls.NewString(neededBefore),
+			wantStatus:   http.StatusNotFound,
+			wantKey:      api.ErrorRenewRequestNotCreator,
+		},
+		{
+			name:         "open request",
+			user:         creator,
+			requestID:    requests[1].UUID.String(),
+			neededBefore: nulls.NewString(neededBefore),
+			wantStatus:   http.StatusBadRequest,
+			wantKey:      api.ErrorRenewRequestNotExpired,
+		},
+		{
+			name:       "no date",
+			user:       creator,
+			requestID:  expired.UUID.String(),
+			wantStatus: http.StatusBadRequest,
+			wantKey:    api.ErrorRenewRequest,
+		},
+		{
+			name:         "date today",
+			user:         creator,
+			requestID:    expired.UUID.String(),
+			neededBefore: nulls.NewString(time.Now().Format(domain.DateFormat)),
+			wantStatus:   http.StatusBadRequest,
+			wantKey:      api.ErrorRenewRequest,
+		},
+		{
+			name:         "creator can renew",
+			user:         creator,
+			requestID:    expired.UUID.String(),
+			neededBefore: nulls.NewString(neededBefore),
+			wantStatus:   http.StatusOK,
+		},
+	}
+
+	for _, step := range steps {
+		input := api.RequestRenewInput{NeededBefore: step.neededBefore}
+
+		req := as.JSON("/requests/" + step.requestID + "/renew")
+		req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", step.user.Nickname)
+		req.Headers["content-type"] = "application/json"
+		res := req.Put(&input)
+
+		body := res.Body.String()
+		as.Equal(step.wantStatus, res.Code, `step "%s", incorrect status code returned, body: %s`, step.name, body)
+
+		if step.wantStatus != http.StatusOK {
+			as.verifyResponseData([]string{string(step.wantKey)}, body, fmt.Sprintf(`step "%s", `, step.name))
+			continue
+		}
+		wantData := []string{
+			`"id":"` + expired.UUID.String() + `"`,
+			`"status":"OPEN"`,
+			`"needed_before":"` + neededBefore + `"`,
+		}
+		as.verifyResponseData(wantData, body, fmt.Sprintf(`step "%s", `, step.name))
+	}
+}
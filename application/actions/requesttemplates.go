@@ -0,0 +1,379 @@
+package actions
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/models"
+)
+
+// swagger:operation GET /users/me/templates Users UsersMeTemplates
+//
+// Lists the request templates of the authenticated User, most recently updated first
+//
+// ---
+// responses:
+//   '200':
+//     description: the user's request templates
+//     schema:
+//       "$ref": "#/definitions/RequestTemplates"
+func usersMeTemplates(c buffalo.Context) error {
+	cUser := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	var templates models.RequestTemplates
+	if err := templates.FindByOwner(tx, cUser); err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorRequestTemplatesLoadFailure, api.CategoryInternal))
+	}
+
+	output, err := convertRequestTemplates(tx, templates)
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorRequestTemplatesLoadFailure, api.CategoryInternal))
+	}
+
+	return c.Render(http.StatusOK, render.JSON(output))
+}
+
+// swagger:operation POST /users/me/templates Users UsersMeTemplatesCreate
+//
+// Creates a request template for the authenticated User. A template with `repeat_every_days` makes a new request
+// automatically at that interval, unless the previous one is still open.
+//
+// ---
+// parameters:
+//   - name: RequestTemplateInput
+//     in: body
+//     required: true
+//     description: input object
+//     schema:
+//       "$ref": "#/definitions/RequestTemplateInput"
+// responses:
+//   '200':
+//     description: the new request template
+//     schema:
+//       "$ref": "#/definitions/RequestTemplate"
+func usersMeTemplatesCreate(c buffalo.Context) error {
+	var input api.RequestTemplateInput
+	if err := StrictBind(c, &input); err != nil {
+		return reportError(c, err)
+	}
+
+	cUser := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	template := models.RequestTemplate{OwnerID: cUser.ID}
+	if err := convertRequestTemplateInput(tx, input, cUser, &template); err != nil {
+		return reportError(c, err)
+	}
+
+	if err := template.Create(tx); err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorRequestTemplateCreateFailure, api.CategoryUser))
+	}
+
+	return renderRequestTemplate(c, tx, template)
+}
+
+// swagger:operation PUT /users/me/templates/{template_id} Users UsersMeTemplateUpdate
+//
+// Replaces all of the properties of one of the authenticated User's request templates
+//
+// ---
+// parameters:
+//   - name: template_id
+//     in: path
+//     required: true
+//     description: ID of the request template
+//   - name: RequestTemplateInput
+//     in: body
+//     required: true
+//     description: input object
+//     schema:
+//       "$ref": "#/definitions/RequestTemplateInput"
+// responses:
+//   '200':
+//     description: the updated request template
+//     schema:
+//       "$ref": "#/definitions/RequestTemplate"
+func usersMeTemplateUpdate(c buffalo.Context) error {
+	var input api.RequestTemplateInput
+	if err := StrictBind(c, &input); err != nil {
+		return reportError(c, err)
+	}
+
+	cUser := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	template, err := findRequestTemplateFromParam(c, tx, cUser)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	if err := convertRequestTemplateInput(tx, input, cUser, &template); err != nil {
+		return reportError(c, err)
+	}
+
+	if err := template.Update(tx); err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorRequestTemplateUpdateFailure, api.CategoryUser))
+	}
+
+	return renderRequestTemplate(c, tx, template)
+}
+
+// swagger:operation DELETE /users/me/templates/{template_id} Users UsersMeTemplateRemove
+//
+// Removes one of the authenticated User's request templates. Requests made from the template are not affected.
+//
+// ---
+// parameters:
+//   - name: template_id
+//     in: path
+//     required: true
+//     description: ID of the request template
+// responses:
+//   '204':
+//     description: OK but no content in response
+func usersMeTemplateRemove(c buffalo.Context) error {
+	cUser := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	template, err := findRequestTemplateFromParam(c, tx, cUser)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	if err := template.Destroy(tx); err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorRequestTemplateDeleteFailure, api.CategoryInternal))
+	}
+
+	return c.Render(http.StatusNoContent, nil)
+}
+
+// swagger:operation POST /users/me/templates/{template_id}/request Users UsersMeTemplateRequest
+//
+// Makes a new request from one of the authenticated User's request templates
+//
+// ---
+// parameters:
+//   - name: template_id
+//     in: path
+//     required: true
+//     description: ID of the request template
+//   - name: RequestFromTemplateInput
+//     in: body
+//     required: true
+//     description: input object
+//     schema:
+//       "$ref": "#/definitions/RequestFromTemplateInput"
+// responses:
+//   '200':
+//     description: the new request
+//     schema:
+//       "$ref": "#/definitions/Request"
+func usersMeTemplateRequest(c buffalo.Context) error {
+	var input api.RequestFromTemplateInput
+	if err := StrictBind(c, &input); err != nil {
+		return reportError(c, err)
+	}
+
+	cUser := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	template, err := findRequestTemplateFromParam(c, tx, cUser)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	neededBefore, err := parseNeededBefore(input.NeededBefore)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	request, err := template.NewRequest(tx, neededBefore)
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorRequestTemplateRequestFailure, api.CategoryUser))
+	}
+
+	output, err := models.ConvertRequest(c, request)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	return c.Render(http.StatusOK, render.JSON(output))
+}
+
+func findRequestTemplateFromParam(c buffalo.Context, tx *pop.Connection, user models.User) (models.RequestTemplate, error) {
+	var template models.RequestTemplate
+
+	id, err := getUUIDFromParam(c, "template_id")
+	if err != nil {
+		return template, err
+	}
+
+	if appErr := template.FindByUUIDForOwner(tx, id.String(), user); appErr != nil {
+		return template, appErr
+	}
+	return template, nil
+}
+
+// convertRequestTemplateInput sets and validates all of the properties of a template from a `RequestTemplateInput`.
+// The template's existing location records are updated, or new ones created. The next recurring request is scheduled
+// for the `start_on` date if given, or else keeps its existing date, or else is made today.
+func convertRequestTemplateInput(tx *pop.Connection, input api.RequestTemplateInput, user models.User,
+	template *models.RequestTemplate,
+) error {
+	var org models.Organization
+	if err := org.FindByUUID(tx, input.OrganizationID.String()); err != nil {
+		err = errors.New("organization ID not found, " + err.Error())
+		appErr := api.NewAppError(err, api.ErrorCreateRequestOrgIDNotFound, api.CategoryUser)
+		if domain.IsOtherThanNoRows(err) {
+			appErr.Category = api.CategoryDatabase
+		}
+		return appErr
+	}
+	if !isOrganizationMember(tx, user, org) {
+		err := errors.New("user is not a member of the template's organization")
+		return api.NewAppError(err, api.ErrorCreateRequestOrgIDNotFound, api.CategoryUser)
+	}
+
+	template.OrganizationID = org.ID
+	template.Name = input.Name
+	template.Title = input.Title
+	template.Description = input.Description
+	template.Size = models.RequestSize(input.Size)
+	template.Kilograms = input.Kilograms
+	template.Visibility = models.RequestVisibility(input.Visibility)
+	template.NeededWithinDays = input.NeededWithinDays
+	template.RepeatEveryDays = input.RepeatEveryDays
+
+	if template.Name == "" {
+		template.Name = template.Title
+	}
+	if template.Visibility == "" {
+		template.Visibility = models.RequestVisibilitySame
+	}
+
+	switch {
+	case !input.RepeatEveryDays.Valid:
+		template.NextRequestOn = nulls.Time{}
+	case input.StartOn.Valid:
+		startOn, err := time.Parse(domain.DateFormat, input.StartOn.String)
+		if err != nil {
+			err = errors.New("failed to parse StartOn, " + err.Error())
+			return api.NewAppError(err, api.ErrorRequestTemplateInputInvalid, api.CategoryUser)
+		}
+		if startOn.Before(time.Now().UTC().Truncate(domain.DurationDay)) {
+			err = errors.New("the first recurring request cannot be in the past")
+			return api.NewAppError(err, api.ErrorRequestTemplateInputInvalid, api.CategoryUser)
+		}
+		template.NextRequestOn = nulls.NewTime(startOn)
+	case !template.NextRequestOn.Valid:
+		template.NextRequestOn = nulls.NewTime(time.Now().UTC().Truncate(domain.DurationDay))
+	}
+
+	var origin *models.Location
+	if input.Origin != nil {
+		o := models.ConvertLocationInput(*input.Origin)
+		origin = &o
+	}
+	if err := template.SetLocations(tx, models.ConvertLocationInput(input.Destination), origin); err != nil {
+		return api.NewAppError(err, api.ErrorLocationCreateFailure, api.CategoryUser)
+	}
+
+	if template.UUID.IsNil() {
+		template.UUID = domain.GetUUID()
+	}
+	vErrs, err := template.Validate(tx)
+	if err != nil {
+		return api.NewAppError(err, api.ErrorRequestTemplateInputInvalid, api.CategoryInternal)
+	}
+	if vErrs.HasAny() {
+		err = errors.New("invalid RequestTemplateInput, error: " + vErrs.Error())
+		return api.NewAppError(err, api.ErrorRequestTemplateInputInvalid, api.CategoryUser)
+	}
+
+	return nil
+}
+
+// isOrganizationMember returns true if the user is a member of the organization
+func isOrganizationMember(tx *pop.Connection, user models.User, org models.Organization) bool {
+	for _, id := range user.GetOrgIDs(tx) {
+		if id == org.ID {
+			return true
+		}
+	}
+	return false
+}
+
+func renderRequestTemplate(c buffalo.Context, tx *pop.Connection, template models.RequestTemplate) error {
+	if err := tx.Load(&template, "Destination", "Organization"); err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorRequestTemplatesLoadFailure, api.CategoryInternal))
+	}
+
+	output, err := convertRequestTemplate(tx, template)
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorRequestTemplatesLoadFailure, api.CategoryInternal))
+	}
+
+	return c.Render(http.StatusOK, render.JSON(output))
+}
+
+func convertRequestTemplates(tx *pop.Connection, templates models.RequestTemplates) (api.RequestTemplates, error) {
+	output := make(api.RequestTemplates, len(templates))
+	for i := range templates {
+		var err error
+		if output[i], err = convertRequestTemplate(tx, templates[i]); err != nil {
+			return nil, err
+		}
+	}
+	return output, nil
+}
+
+// convertRequestTemplate converts a template to its API type. The destination and organization must be loaded.
+func convertRequestTemplate(tx *pop.Connection, template models.RequestTemplate) (api.RequestTemplate, error) {
+	output := api.RequestTemplate{
+		ID:   template.UUID,
+		Name: template.Name,
+		Organization: api.Organization{
+			ID:   template.Organization.UUID,
+			Name: template.Organization.Name,
+		},
+		Visibility:       api.RequestVisibility(template.Visibility),
+		Title:            template.Title,
+		Description:      template.Description,
+		Destination:      models.ConvertLocation(template.Destination),
+		Size:             api.RequestSize(template.Size),
+		Kilograms:        template.Kilograms,
+		NeededWithinDays: template.NeededWithinDays,
+		RepeatEveryDays:  template.RepeatEveryDays,
+		NextRequestOn:    convertWatchDate(template.NextRequestOn),
+		CreatedAt:        template.CreatedAt,
+		UpdatedAt:        template.UpdatedAt,
+	}
+
+	if template.OriginID.Valid {
+		var origin models.Location
+		if err := tx.Find(&origin, template.OriginID.Int); err != nil {
+			return output, errors.New("error loading request template origin, " + err.Error())
+		}
+		o := models.ConvertLocation(origin)
+		output.Origin = &o
+	}
+
+	if template.LastRequestID.Valid {
+		var request models.Request
+		if err := request.FindByID(tx, template.LastRequestID.Int); err != nil {
+			return output, errors.New("error loading last request of request template, " + err.Error())
+		}
+		output.LastRequestID = nulls.NewUUID(request.UUID)
+	}
+
+	return output, nil
+}
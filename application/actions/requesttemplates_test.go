@@ -0,0 +1,266 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/internal/test"
+	"github.com/silinternational/wecarry-api/models"
+)
+
+type requestTemplateFixtures struct {
+	models.Organization
+	models.Users
+	models.RequestTemplates
+}
+
+func createFixturesForRequestTemplates(as *ActionSuite) requestTemplateFixtures {
+	// make 2 users, 1 that has templates, and another that will try to use those templates
+	uf := test.CreateUserFixtures(as.DB, 2)
+	locations := test.CreateLocationFixtures(as.DB, 2)
+
+	templates := make(models.RequestTemplates, 2)
+	for i := range templates {
+		templates[i] = models.RequestTemplate{
+			OwnerID:          uf.Users[0].ID,
+			OrganizationID:   uf.Organization.ID,
+			Title:            fmt.Sprintf("template %d", i),
+			Size:             models.RequestSizeSmall,
+			DestinationID:    locations[i].ID,
+			NeededWithinDays: nulls.NewInt(10),
+		}
+		as.NoError(templates[i].Create(as.DB))
+	}
+
+	return requestTemplateFixtures{
+		Organization:     uf.Organization,
+		Users:            uf.Users,
+		RequestTemplates: templates,
+	}
+}
+
+func (as *ActionSuite) Test_UsersMeTemplatesCreate() {
+	f := createFixturesForRequestTemplates(as)
+	owner := f.Users[0]
+
+	destination := api.Location{Description: "office", Country: "US", Latitude: 1.1, Longitude: 2.2}
+	tomorrow := time.Now().Add(domain.DurationDay).Format(domain.DateFormat)
+	yesterday := time.Now().Add(-domain.DurationDay).Format(domain.DateFormat)
+
+	tests := []struct {
+		name         string
+		input        api.RequestTemplateInput
+		wantStatus   int
+		wantContains []string
+	}{
+		{
+			name: "bad organization",
+			input: api.RequestTemplateInput{
+				OrganizationID: domain.GetUUID(),
+				Title:          "coffee",
+				Size:           api.RequestSizeSmall,
+				Destination:    destination,
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantContains: []string{api.ErrorCreateRequestOrgIDNotFound.String()},
+		},
+		{
+			name: "missing title",
+			input: api.RequestTemplateInput{
+				OrganizationID: f.Organization.UUID,
+				Size:           api.RequestSizeSmall,
+				Destination:    destination,
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantContains: []string{api.ErrorRequestTemplateInputInvalid.String()},
+		},
+		{
+			name: "start in the past",
+			input: api.RequestTemplateInput{
+				OrganizationID:  f.Organization.UUID,
+				Title:           "coffee",
+				Size:            api.RequestSizeSmall,
+				Destination:     destination,
+				RepeatEveryDays: nulls.NewInt(30),
+				StartOn:         nulls.NewString(yesterday),
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantContains: []string{api.ErrorRequestTemplateInputInvalid.String()},
+		},
+		{
+			name: "recurring",
+			input: api.RequestTemplateInput{
+				OrganizationID:   f.Organization.UUID,
+				Title:            "coffee",
+				Size:             api.RequestSizeSmall,
+				Destination:      destination,
+				Origin:           &api.Location{Description: "roaster", Country: "KE"},
+				NeededWithinDays: nulls.NewInt(7),
+				RepeatEveryDays:  nulls.NewInt(30),
+				StartOn:          nulls.NewString(tomorrow),
+			},
+			wantStatus: http.StatusOK,
+			wantContains: []string{
+				`"name":"coffee"`,
+				`"visibility":"SAME"`,
+				`"destination":{"description":"office"`,
+				`"origin":{"description":"roaster"`,
+				`"repeat_every_days":30`,
+				`"next_request_on":"` + tomorrow + `"`,
+			},
+		},
+	}
+	for _, tc := range tests {
+		as.T().Run(tc.name, func(t *testing.T) {
+			req := as.JSON("/users/me/templates")
+			req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", owner.Nickname)
+			req.Headers["content-type"] = "application/json"
+			res := req.Post(tc.input)
+
+			body := res.Body.String()
+			as.Equal(tc.wantStatus, res.Code, "incorrect status code returned, body: %s", body)
+			as.verifyResponseData(tc.wantContains, body, "")
+		})
+	}
+}
+
+func (as *ActionSuite) Test_UsersMeTemplates() {
+	f := createFixturesForRequestTemplates(as)
+
+	req := as.JSON("/users/me/templates")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", f.Users[0].Nickname)
+	res := req.Get()
+
+	body := res.Body.String()
+	as.Equal(http.StatusOK, res.Code, "incorrect status code returned, body: %s", body)
+
+	var templates api.RequestTemplates
+	as.NoError(json.Unmarshal([]byte(body), &templates))
+	as.Equal(2, len(templates))
+	as.Equal(f.Organization.UUID, templates[0].Organization.ID)
+	as.Nil(templates[0].Origin)
+
+	req = as.JSON("/users/me/templates")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", f.Users[1].Nickname)
+	res = req.Get()
+	as.Equal(http.StatusOK, res.Code)
+	as.Equal("[]\n", res.Body.String(), "expected an empty list in the response")
+}
+
+func (as *ActionSuite) Test_UsersMeTemplateUpdate() {
+	f := createFixturesForRequestTemplates(as)
+	template := f.RequestTemplates[0]
+
+	input := api.RequestTemplateInput{
+		Name:            "weekly",
+		OrganizationID:  f.Organization.UUID,
+		Title:           "new title",
+		Size:            api.RequestSizeMedium,
+		Destination:     api.Location{Description: "new place", Country: "FR"},
+		RepeatEveryDays: nulls.NewInt(7),
+	}
+
+	req := as.JSON("/users/me/templates/%s", template.UUID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", f.Users[1].Nickname)
+	res := req.Put(input)
+	as.Equal(http.StatusNotFound, res.Code, "a user should not be able to change another user's template")
+
+	req = as.JSON("/users/me/templates/%s", template.UUID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", f.Users[0].Nickname)
+	res = req.Put(input)
+	body := res.Body.String()
+	as.Equal(http.StatusOK, res.Code, "incorrect status code returned, body: %s", body)
+
+	var dbTemplate models.RequestTemplate
+	as.NoError(as.DB.Eager("Destination").Find(&dbTemplate, template.ID))
+	as.Equal("weekly", dbTemplate.Name)
+	as.Equal("new title", dbTemplate.Title)
+	as.Equal(models.RequestSizeMedium, dbTemplate.Size)
+	as.Equal(template.DestinationID, dbTemplate.DestinationID, "the destination record should be reused")
+	as.Equal("new place", dbTemplate.Destination.Description)
+	as.False(dbTemplate.NeededWithinDays.Valid)
+	as.True(dbTemplate.NextRequestOn.Valid, "a recurring template should have a next request date")
+}
+
+func (as *ActionSuite) Test_UsersMeTemplateRemove() {
+	f := createFixturesForRequestTemplates(as)
+	template := f.RequestTemplates[0]
+
+	req := as.JSON("/users/me/templates/%s", template.UUID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", f.Users[1].Nickname)
+	res := req.Delete()
+	as.Equal(http.StatusNotFound, res.Code)
+
+	req = as.JSON("/users/me/templates/%s", template.UUID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", f.Users[0].Nickname)
+	res = req.Delete()
+	as.Equal(http.StatusNoContent, res.Code, "incorrect status code returned, body: %s", res.Body.String())
+
+	var templates models.RequestTemplates
+	as.NoError(templates.FindByOwner(as.DB, f.Users[0]))
+	as.Equal(1, len(templates))
+	as.Equal(f.RequestTemplates[1].ID, templates[0].ID)
+}
+
+func (as *ActionSuite) Test_UsersMeTemplateRequest() {
+	f := createFixturesForRequestTemplates(as)
+	template := f.RequestTemplates[0]
+
+	neededBefore := time.Now().Add(domain.DurationWeek).Format(domain.DateFormat)
+	tests := []struct {
+		name         string
+		user         models.User
+		input        api.RequestFromTemplateInput
+		wantStatus   int
+		wantContains []string
+	}{
+		{
+			name:       "not the owner",
+			user:       f.Users[1],
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:         "needed today",
+			user:         f.Users[0],
+			input:        api.RequestFromTemplateInput{NeededBefore: nulls.NewString(time.Now().Format(domain.DateFormat))},
+			wantStatus:   http.StatusBadRequest,
+			wantContains: []string{api.ErrorRequestTemplateRequestFailure.String()},
+		},
+		{
+			name:       "template date",
+			user:       f.Users[0],
+			wantStatus: http.StatusOK,
+			wantContains: []string{
+				`"title":"template 0"`,
+				`"status":"OPEN"`,
+				`"needed_before":"` + time.Now().UTC().Add(10*domain.DurationDay).Format(domain.DateFormat) + `"`,
+			},
+		},
+		{
+			name:         "given date",
+			user:         f.Users[0],
+			input:        api.RequestFromTemplateInput{NeededBefore: nulls.NewString(neededBefore)},
+			wantStatus:   http.StatusOK,
+			wantContains: []string{`"needed_before":"` + neededBefore + `"`},
+		},
+	}
+	for _, tc := range tests {
+		as.T().Run(tc.name, func(t *testing.T) {
+			req := as.JSON("/users/me/templates/%s/request", template.UUID)
+			req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", tc.user.Nickname)
+			req.Headers["content-type"] = "application/json"
+			res := req.Post(tc.input)
+
+			body := res.Body.String()
+			as.Equal(tc.wantStatus, res.Code, "incorrect status code returned, body: %s", body)
+			as.verifyResponseData(tc.wantContains, body, "")
+		})
+	}
+}
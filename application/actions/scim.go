@@ -0,0 +1,837 @@
+package actions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/pop/v6"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/log"
+	"github.com/silinternational/wecarry-api/models"
+)
+
+const (
+	scimContentType     = "application/scim+json"
+	scimDefaultPageSize = 100
+	scimMaxPageSize     = 200
+)
+
+// scimFilterRegex matches the only form of SCIM filter supported, e.g. `userName eq "bjensen@example.com"`
+var scimFilterRegex = regexp.MustCompile(`(?i)^\s*([a-z.]+)\s+eq\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// scimMemberFilterRegex matches a SCIM PATCH path selecting a group member, e.g. `members[value eq "abc"]`
+var scimMemberFilterRegex = regexp.MustCompile(`(?i)^members\[\s*value\s+eq\s+"([^"]*)"\s*]$`)
+
+// scimAuthenticate verifies the bearer token of a request to an Organization's SCIM endpoint and puts the
+// Organization in the context
+func scimAuthenticate(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		tx := models.Tx(c)
+
+		var org models.Organization
+		orgID := c.Param("org_id")
+		if err := org.FindByUUID(tx, orgID); err != nil {
+			return scimError(c, http.StatusUnauthorized, "", fmt.Errorf("SCIM organization not found, %w", err))
+		}
+
+		if !org.IsValidSCIMToken(domain.GetBearerTokenFromRequest(c.Request())) {
+			return scimError(c, http.StatusUnauthorized, "", errors.New("invalid SCIM bearer token"))
+		}
+
+		c.Set(domain.ContextKeySCIMOrganization, org)
+		return next(c)
+	}
+}
+
+func scimOrganization(c buffalo.Context) models.Organization {
+	org, _ := c.Value(domain.ContextKeySCIMOrganization).(models.Organization)
+	return org
+}
+
+func scimBaseURL(org models.Organization) string {
+	return domain.Env.ApiBaseURL + "/scim/v2/" + org.UUID.String()
+}
+
+// scimRender renders a SCIM response body
+func scimRender(c buffalo.Context, status int, v interface{}) error {
+	return c.Render(status, r.Func(scimContentType, func(w io.Writer, _ render.Data) error {
+		return json.NewEncoder(w).Encode(v)
+	}))
+}
+
+// scimError logs an error and renders it as a SCIM error response
+func scimError(c buffalo.Context, status int, scimType string, err error) error {
+	address, _ := getClientIPAddress(c)
+	entry := log.WithContext(c).WithFields(map[string]interface{}{
+		domain.ExtrasStatus: status,
+		domain.ExtrasMethod: c.Request().Method,
+		domain.ExtrasURI:    c.Request().RequestURI,
+		domain.ExtrasIP:     address,
+	})
+	if status >= 500 {
+		entry.Error(err)
+	} else {
+		entry.Warning(err)
+	}
+
+	detail := http.StatusText(status)
+	if status < 500 {
+		detail = err.Error()
+	}
+
+	return scimRender(c, status, api.SCIMError{
+		Schemas:  []string{api.SCIMSchemaError},
+		Status:   strconv.Itoa(status),
+		SCIMType: scimType,
+		Detail:   detail,
+	})
+}
+
+// scimAppError renders an error returned by the models package as a SCIM error response
+func scimAppError(c buffalo.Context, err error) error {
+	var appErr *api.AppError
+	if !errors.As(err, &appErr) {
+		return scimError(c, http.StatusInternalServerError, "", err)
+	}
+
+	switch appErr.Key {
+	case api.ErrorSCIMUserConflict:
+		return scimError(c, http.StatusConflict, "uniqueness", appErr.Err)
+	case api.ErrorSCIMInvalidFilter:
+		return scimError(c, http.StatusBadRequest, "invalidFilter", appErr.Err)
+	case api.ErrorSCIMInvalidValue:
+		return scimError(c, http.StatusBadRequest, "invalidValue", appErr.Err)
+	}
+
+	switch appErr.Category {
+	case api.CategoryUser:
+		return scimError(c, http.StatusBadRequest, "", appErr.Err)
+	case api.CategoryNotFound, api.CategoryForbidden:
+		return scimError(c, http.StatusNotFound, "", appErr.Err)
+	}
+	return scimError(c, http.StatusInternalServerError, "", appErr.Err)
+}
+
+// scimBind decodes a SCIM request body. Unknown attributes are allowed since identity providers commonly send
+// extension schemas.
+func scimBind(c buffalo.Context, dest interface{}) error {
+	if err := json.NewDecoder(c.Request().Body).Decode(dest); err != nil {
+		return scimError(c, http.StatusBadRequest, "invalidSyntax", fmt.Errorf("invalid SCIM request body, %w", err))
+	}
+	return nil
+}
+
+// swagger:operation GET /scim/v2/{org_id}/ServiceProviderConfig SCIM SCIMServiceProviderConfig
+//
+// Describes the SCIM features supported for provisioning the members of an Organization. Requires the
+// Organization's SCIM bearer token.
+//
+// ---
+// parameters:
+//   - name: org_id
+//     in: path
+//     required: true
+//     description: ID of the organization
+// responses:
+//   '200':
+//     description: SCIM service provider configuration
+func scimServiceProviderConfig(c buffalo.Context) error {
+	supported := func(b bool) map[string]bool { return map[string]bool{"supported": b} }
+
+	return scimRender(c, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{api.SCIMSchemaServiceProviderConfig},
+		"patch":          supported(true),
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": scimMaxPageSize},
+		"changePassword": supported(false),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]interface{}{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Authentication using the Organization's SCIM bearer token",
+			"primary":     true,
+		}},
+	})
+}
+
+// swagger:operation GET /scim/v2/{org_id}/ResourceTypes SCIM SCIMResourceTypes
+//
+// Lists the SCIM resource types available for provisioning the members of an Organization. Requires the
+// Organization's SCIM bearer token.
+//
+// ---
+// parameters:
+//   - name: org_id
+//     in: path
+//     required: true
+//     description: ID of the organization
+// responses:
+//   '200':
+//     description: SCIM list of resource types
+func scimResourceTypes(c buffalo.Context) error {
+	resourceType := func(name, endpoint, schema string) map[string]interface{} {
+		return map[string]interface{}{
+			"schemas":  []string{api.SCIMSchemaResourceType},
+			"id":       name,
+			"name":     name,
+			"endpoint": endpoint,
+			"schema":   schema,
+		}
+	}
+
+	return scimRender(c, http.StatusOK, api.SCIMListResponse{
+		Schemas:      []string{api.SCIMSchemaListResponse},
+		TotalResults: 2,
+		StartIndex:   1,
+		ItemsPerPage: 2,
+		Resources: []interface{}{
+			resourceType("User", "/Users", api.SCIMSchemaUser),
+			resourceType("Group", "/Groups", api.SCIMSchemaGroup),
+		},
+	})
+}
+
+// swagger:operation GET /scim/v2/{org_id}/Users SCIM SCIMUsersList
+//
+// Lists the members of an Organization provisioned through SCIM. Only filters of the form
+// `userName eq "value"`, `externalId eq "value"` or `emails eq "value"` are supported. Requires the
+// Organization's SCIM bearer token.
+//
+// ---
+// parameters:
+//   - name: org_id
+//     in: path
+//     required: true
+//     description: ID of the organization
+//   - name: filter
+//     in: query
+//     required: false
+//     description: SCIM filter
+//   - name: startIndex
+//     in: query
+//     required: false
+//     description: 1-based index of the first result
+//   - name: count
+//     in: query
+//     required: false
+//     description: maximum number of results
+// responses:
+//   '200':
+//     description: SCIM list of users
+func scimUsersList(c buffalo.Context) error {
+	tx := models.Tx(c)
+	org := scimOrganization(c)
+
+	var filterAttr, filterValue string
+	if filter := c.Param("filter"); filter != "" {
+		m := scimFilterRegex.FindStringSubmatch(filter)
+		if m == nil {
+			return scimError(c, http.StatusBadRequest, "invalidFilter", fmt.Errorf("unsupported filter '%s'", filter))
+		}
+		filterAttr = m[1]
+		filterValue = strings.ReplaceAll(m[2], `\"`, `"`)
+	}
+
+	startIndex := scimIntParam(c, "startIndex", 1)
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	count := scimIntParam(c, "count", scimDefaultPageSize)
+	if count < 0 {
+		count = 0
+	}
+	if count > scimMaxPageSize {
+		count = scimMaxPageSize
+	}
+
+	var users models.SCIMUsers
+	total, err := users.FindByOrganization(tx, org, filterAttr, filterValue, startIndex, count)
+	if err != nil {
+		return scimAppError(c, err)
+	}
+
+	baseURL := scimBaseURL(org)
+	resources := make([]interface{}, len(users))
+	for i := range users {
+		resources[i] = models.ConvertSCIMUser(tx, users[i], baseURL)
+	}
+
+	return scimRender(c, http.StatusOK, api.SCIMListResponse{
+		Schemas:      []string{api.SCIMSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+func scimIntParam(c buffalo.Context, name string, def int) int {
+	n, err := strconv.Atoi(c.Param(name))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// swagger:operation GET /scim/v2/{org_id}/Users/{user_id} SCIM SCIMUsersGet
+//
+// Gets a member of an Organization provisioned through SCIM. Requires the Organization's SCIM bearer token.
+//
+// ---
+// parameters:
+//   - name: org_id
+//     in: path
+//     required: true
+//     description: ID of the organization
+//   - name: user_id
+//     in: path
+//     required: true
+//     description: ID of the user
+// responses:
+//   '200':
+//     description: SCIM user
+func scimUsersGet(c buffalo.Context) error {
+	tx := models.Tx(c)
+	org := scimOrganization(c)
+
+	var user models.SCIMUser
+	if err := user.FindByOrganizationAndUserUUID(tx, org, c.Param("user_id")); err != nil {
+		return scimAppError(c, err)
+	}
+
+	return scimRender(c, http.StatusOK, models.ConvertSCIMUser(tx, user, scimBaseURL(org)))
+}
+
+// swagger:operation POST /scim/v2/{org_id}/Users SCIM SCIMUsersCreate
+//
+// Provisions a member of an Organization. If a user with the same email address already exists, that user is
+// linked if already a member of the Organization or if the email address is on one of the Organization's domains.
+// Otherwise, the request is a conflict. The `externalId`, or `userName` if no `externalId` is given, must match the
+// user ID the Organization's auth provider gives at login. Requires the Organization's SCIM bearer token.
+//
+// ---
+// parameters:
+//   - name: org_id
+//     in: path
+//     required: true
+//     description: ID of the organization
+// responses:
+//   '201':
+//     description: SCIM user
+func scimUsersCreate(c buffalo.Context) error {
+	tx := models.Tx(c)
+	org := scimOrganization(c)
+
+	var input api.SCIMUser
+	if err := scimBind(c, &input); err != nil {
+		return err
+	}
+
+	var user models.SCIMUser
+	if err := user.Provision(tx, org, scimUserAttributes(input, models.SCIMUserAttributes{Active: true})); err != nil {
+		return scimAppError(c, err)
+	}
+
+	return scimRender(c, http.StatusCreated, models.ConvertSCIMUser(tx, user, scimBaseURL(org)))
+}
+
+// swagger:operation PUT /scim/v2/{org_id}/Users/{user_id} SCIM SCIMUsersReplace
+//
+// Replaces the attributes of a member of an Organization. Setting `active` to false removes the user from the
+// Organization and ends the sessions they started through the Organization. Requires the Organization's SCIM
+// bearer token.
+//
+// ---
+// parameters:
+//   - name: org_id
+//     in: path
+//     required: true
+//     description: ID of the organization
+//   - name: user_id
+//     in: path
+//     required: true
+//     description: ID of the user
+// responses:
+//   '200':
+//     description: SCIM user
+func scimUsersReplace(c buffalo.Context) error {
+	tx := models.Tx(c)
+	org := scimOrganization(c)
+
+	var user models.SCIMUser
+	if err := user.FindByOrganizationAndUserUUID(tx, org, c.Param("user_id")); err != nil {
+		return scimAppError(c, err)
+	}
+
+	var input api.SCIMUser
+	if err := scimBind(c, &input); err != nil {
+		return err
+	}
+
+	if err := user.Apply(tx, scimUserAttributes(input, models.SCIMUserAttributes{Active: true})); err != nil {
+		return scimAppError(c, err)
+	}
+
+	return scimRender(c, http.StatusOK, models.ConvertSCIMUser(tx, user, scimBaseURL(org)))
+}
+
+// swagger:operation PATCH /scim/v2/{org_id}/Users/{user_id} SCIM SCIMUsersPatch
+//
+// Modifies some of the attributes of a member of an Organization. Setting `active` to false removes the user from
+// the Organization and ends the sessions they started through the Organization. Requires the Organization's SCIM
+// bearer token.
+//
+// ---
+// parameters:
+//   - name: org_id
+//     in: path
+//     required: true
+//     description: ID of the organization
+//   - name: user_id
+//     in: path
+//     required: true
+//     description: ID of the user
+// responses:
+//   '200':
+//     description: SCIM user
+func scimUsersPatch(c buffalo.Context) error {
+	tx := models.Tx(c)
+	org := scimOrganization(c)
+
+	var user models.SCIMUser
+	if err := user.FindByOrganizationAndUserUUID(tx, org, c.Param("user_id")); err != nil {
+		return scimAppError(c, err)
+	}
+
+	var input api.SCIMPatchOp
+	if err := scimBind(c, &input); err != nil {
+		return err
+	}
+
+	attrs := user.Attributes()
+	for _, op := range input.Operations {
+		if err := applySCIMUserPatch(&attrs, op); err != nil {
+			return scimError(c, http.StatusBadRequest, "invalidValue", err)
+		}
+	}
+
+	if err := user.Apply(tx, attrs); err != nil {
+		return scimAppError(c, err)
+	}
+
+	return scimRender(c, http.StatusOK, models.ConvertSCIMUser(tx, user, scimBaseURL(org)))
+}
+
+// swagger:operation DELETE /scim/v2/{org_id}/Users/{user_id} SCIM SCIMUsersDelete
+//
+// Deprovisions a member of an Organization, removing the user from the Organization, ending the sessions they
+// started through the Organization and revoking their personal access tokens. The user's account is kept. Requires
+// the Organization's SCIM bearer token.
+//
+// ---
+// parameters:
+//   - name: org_id
+//     in: path
+//     required: true
+//     description: ID of the organization
+//   - name: user_id
+//     in: path
+//     required: true
+//     description: ID of the user
+// responses:
+//   '204':
+//     description: OK but no content in response
+func scimUsersDelete(c buffalo.Context) error {
+	tx := models.Tx(c)
+	org := scimOrganization(c)
+
+	var user models.SCIMUser
+	if err := user.FindByOrganizationAndUserUUID(tx, org, c.Param("user_id")); err != nil {
+		return scimAppError(c, err)
+	}
+
+	if err := user.Deprovision(tx); err != nil {
+		return scimAppError(c, err)
+	}
+
+	return c.Render(http.StatusNoContent, nil)
+}
+
+// scimUserAttributes overlays the attributes given in a SCIM User on `attrs`
+func scimUserAttributes(input api.SCIMUser, attrs models.SCIMUserAttributes) models.SCIMUserAttributes {
+	attrs.ExternalID = input.ExternalID
+	attrs.UserName = input.UserName
+	attrs.Email = input.PrimaryEmail()
+	attrs.FirstName = input.Name.GivenName
+	attrs.LastName = input.Name.FamilyName
+	attrs.Nickname = input.NickName
+	if input.Active != nil {
+		attrs.Active = *input.Active
+	}
+	return attrs
+}
+
+// applySCIMUserPatch applies one SCIM PATCH operation to the attributes of a user
+func applySCIMUserPatch(attrs *models.SCIMUserAttributes, op api.SCIMPatchOperation) error {
+	opName := strings.ToLower(op.Op)
+	if opName != "add" && opName != "replace" && opName != "remove" {
+		return fmt.Errorf("unsupported patch operation '%s'", op.Op)
+	}
+
+	if op.Path == "" {
+		if opName == "remove" {
+			return errors.New("remove operation requires a path")
+		}
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &values); err != nil {
+			return fmt.Errorf("patch value must be an object when no path is given, %w", err)
+		}
+		for path, value := range values {
+			if err := setSCIMUserAttribute(attrs, path, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if opName == "remove" {
+		return setSCIMUserAttribute(attrs, op.Path, json.RawMessage(`""`))
+	}
+	return setSCIMUserAttribute(attrs, op.Path, op.Value)
+}
+
+// setSCIMUserAttribute sets the user attribute identified by a SCIM path. Attributes not managed through SCIM are
+// ignored.
+func setSCIMUserAttribute(attrs *models.SCIMUserAttributes, path string, value json.RawMessage) error {
+	path = strings.ToLower(path)
+	if strings.HasPrefix(path, "emails[") {
+		path = "emails.value"
+	}
+
+	switch path {
+	case "active":
+		active, err := scimBool(value)
+		if err != nil {
+			return err
+		}
+		attrs.Active = active
+		return nil
+
+	case "name":
+		var name api.SCIMName
+		if err := json.Unmarshal(value, &name); err != nil {
+			return fmt.Errorf("invalid value for 'name', %w", err)
+		}
+		attrs.FirstName = name.GivenName
+		attrs.LastName = name.FamilyName
+		return nil
+
+	case "emails":
+		var emails []api.SCIMEmail
+		if err := json.Unmarshal(value, &emails); err != nil {
+			return fmt.Errorf("invalid value for 'emails', %w", err)
+		}
+		attrs.Email = api.SCIMUser{Emails: emails}.PrimaryEmail()
+		return nil
+	}
+
+	target := map[string]*string{
+		"externalid":      &attrs.ExternalID,
+		"username":        &attrs.UserName,
+		"emails.value":    &attrs.Email,
+		"name.givenname":  &attrs.FirstName,
+		"name.familyname": &attrs.LastName,
+	}[path]
+	if target == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(value, target); err != nil {
+		return fmt.Errorf("invalid value for '%s', %w", path, err)
+	}
+	return nil
+}
+
+// scimBool decodes a boolean value. Some identity providers send booleans as strings, e.g. "False".
+func scimBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return false, fmt.Errorf("invalid boolean value %s", value)
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("invalid boolean value %s", value)
+	}
+	return b, nil
+}
+
+// swagger:operation GET /scim/v2/{org_id}/Groups SCIM SCIMGroupsList
+//
+// Lists the SCIM Groups of an Organization. There is one group, `admins`, whose members are the admins of the
+// Organization. Requires the Organization's SCIM bearer token.
+//
+// ---
+// parameters:
+//   - name: org_id
+//     in: path
+//     required: true
+//     description: ID of the organization
+// responses:
+//   '200':
+//     description: SCIM list of groups
+func scimGroupsList(c buffalo.Context) error {
+	tx := models.Tx(c)
+	org := scimOrganization(c)
+
+	var resources []interface{}
+	if filter := c.Param("filter"); filter != "" {
+		m := scimFilterRegex.FindStringSubmatch(filter)
+		if m == nil || !strings.EqualFold(m[1], "displayName") {
+			return scimError(c, http.StatusBadRequest, "invalidFilter", fmt.Errorf("unsupported filter '%s'", filter))
+		}
+		if !strings.EqualFold(m[2], "Administrators") {
+			resources = []interface{}{}
+		}
+	}
+
+	if resources == nil {
+		var admins models.SCIMUsers
+		if err := admins.FindAdmins(tx, org); err != nil {
+			return scimError(c, http.StatusInternalServerError, "", err)
+		}
+		resources = []interface{}{models.ConvertSCIMAdminGroup(admins, scimBaseURL(org))}
+	}
+
+	return scimRender(c, http.StatusOK, api.SCIMListResponse{
+		Schemas:      []string{api.SCIMSchemaListResponse},
+		TotalResults: len(resources),
+		StartIndex:   1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// swagger:operation GET /scim/v2/{org_id}/Groups/{group_id} SCIM SCIMGroupsGet
+//
+// Gets a SCIM Group of an Organization. Requires the Organization's SCIM bearer token.
+//
+// ---
+// parameters:
+//   - name: org_id
+//     in: path
+//     required: true
+//     description: ID of the organization
+//   - name: group_id
+//     in: path
+//     required: true
+//     description: ID of the group
+// responses:
+//   '200':
+//     description: SCIM group
+func scimGroupsGet(c buffalo.Context) error {
+	tx := models.Tx(c)
+	org := scimOrganization(c)
+
+	if c.Param("group_id") != models.SCIMGroupAdmins {
+		return scimError(c, http.StatusNotFound, "", fmt.Errorf("SCIM group '%s' not found", c.Param("group_id")))
+	}
+
+	return renderSCIMAdminGroup(c, tx, org)
+}
+
+// swagger:operation PATCH /scim/v2/{org_id}/Groups/{group_id} SCIM SCIMGroupsPatch
+//
+// Adds or removes members of a SCIM Group of an Organization. Members must be active users provisioned through
+// SCIM. Requires the Organization's SCIM bearer token.
+//
+// ---
+// parameters:
+//   - name: org_id
+//     in: path
+//     required: true
+//     description: ID of the organization
+//   - name: group_id
+//     in: path
+//     required: true
+//     description: ID of the group
+// responses:
+//   '200':
+//     description: SCIM group
+func scimGroupsPatch(c buffalo.Context) error {
+	tx := models.Tx(c)
+	org := scimOrganization(c)
+
+	if c.Param("group_id") != models.SCIMGroupAdmins {
+		return scimError(c, http.StatusNotFound, "", fmt.Errorf("SCIM group '%s' not found", c.Param("group_id")))
+	}
+
+	var input api.SCIMPatchOp
+	if err := scimBind(c, &input); err != nil {
+		return err
+	}
+
+	for _, op := range input.Operations {
+		if err := applySCIMGroupPatch(tx, org, op); err != nil {
+			return scimAppError(c, err)
+		}
+	}
+
+	return renderSCIMAdminGroup(c, tx, org)
+}
+
+// swagger:operation PUT /scim/v2/{org_id}/Groups/{group_id} SCIM SCIMGroupsReplace
+//
+// Replaces the members of a SCIM Group of an Organization. Members must be active users provisioned through SCIM.
+// Requires the Organization's SCIM bearer token.
+//
+// ---
+// parameters:
+//   - name: org_id
+//     in: path
+//     required: true
+//     description: ID of the organization
+//   - name: group_id
+//     in: path
+//     required: true
+//     description: ID of the group
+// responses:
+//   '200':
+//     description: SCIM group
+func scimGroupsReplace(c buffalo.Context) error {
+	tx := models.Tx(c)
+	org := scimOrganization(c)
+
+	if c.Param("group_id") != models.SCIMGroupAdmins {
+		return scimError(c, http.StatusNotFound, "", fmt.Errorf("SCIM group '%s' not found", c.Param("group_id")))
+	}
+
+	var input api.SCIMGroup
+	if err := scimBind(c, &input); err != nil {
+		return err
+	}
+
+	if err := replaceSCIMAdmins(tx, org, input.Members); err != nil {
+		return scimAppError(c, err)
+	}
+
+	return renderSCIMAdminGroup(c, tx, org)
+}
+
+func renderSCIMAdminGroup(c buffalo.Context, tx *pop.Connection, org models.Organization) error {
+	var admins models.SCIMUsers
+	if err := admins.FindAdmins(tx, org); err != nil {
+		return scimError(c, http.StatusInternalServerError, "", err)
+	}
+
+	return scimRender(c, http.StatusOK, models.ConvertSCIMAdminGroup(admins, scimBaseURL(org)))
+}
+
+// applySCIMGroupPatch applies one SCIM PATCH operation to the members of the admins group
+func applySCIMGroupPatch(tx *pop.Connection, org models.Organization, op api.SCIMPatchOperation) error {
+	opName := strings.ToLower(op.Op)
+
+	if m := scimMemberFilterRegex.FindStringSubmatch(op.Path); m != nil {
+		if opName != "remove" {
+			err := fmt.Errorf("unsupported patch operation '%s' for path '%s'", op.Op, op.Path)
+			return api.NewAppError(err, api.ErrorSCIMInvalidValue, api.CategoryUser)
+		}
+		return setSCIMAdmin(tx, org, m[1], false)
+	}
+
+	path := strings.ToLower(op.Path)
+	if path != "members" && path != "" {
+		// displayName and other attributes are fixed
+		return nil
+	}
+
+	var members []api.SCIMMember
+	if len(op.Value) > 0 {
+		raw := op.Value
+		if path == "" {
+			var values map[string]json.RawMessage
+			if err := json.Unmarshal(op.Value, &values); err != nil {
+				return api.NewAppError(err, api.ErrorSCIMInvalidValue, api.CategoryUser)
+			}
+			raw = values["members"]
+		}
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &members); err != nil {
+				return api.NewAppError(err, api.ErrorSCIMInvalidValue, api.CategoryUser)
+			}
+		}
+	}
+
+	switch opName {
+	case "add":
+		for _, m := range members {
+			if err := setSCIMAdmin(tx, org, m.Value, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "remove":
+		if len(members) == 0 {
+			return replaceSCIMAdmins(tx, org, nil)
+		}
+		for _, m := range members {
+			if err := setSCIMAdmin(tx, org, m.Value, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "replace":
+		return replaceSCIMAdmins(tx, org, members)
+	}
+
+	err := fmt.Errorf("unsupported patch operation '%s'", op.Op)
+	return api.NewAppError(err, api.ErrorSCIMInvalidValue, api.CategoryUser)
+}
+
+func setSCIMAdmin(tx *pop.Connection, org models.Organization, userID string, isAdmin bool) error {
+	var user models.SCIMUser
+	if err := user.FindByOrganizationAndUserUUID(tx, org, userID); err != nil {
+		return err
+	}
+	return user.SetAdmin(tx, isAdmin)
+}
+
+// replaceSCIMAdmins makes the given members the only SCIM-provisioned admins of the Organization
+func replaceSCIMAdmins(tx *pop.Connection, org models.Organization, members []api.SCIMMember) error {
+	keep := map[string]bool{}
+	for _, m := range members {
+		keep[m.Value] = true
+		if err := setSCIMAdmin(tx, org, m.Value, true); err != nil {
+			return err
+		}
+	}
+
+	var admins models.SCIMUsers
+	if err := admins.FindAdmins(tx, org); err != nil {
+		return err
+	}
+	for i := range admins {
+		if keep[admins[i].User.UUID.String()] {
+			continue
+		}
+		if err := admins[i].SetAdmin(tx, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gobuffalo/httptest"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/internal/test"
+	"github.com/silinternational/wecarry-api/models"
+)
+
+func (as *ActionSuite) Test_applySCIMUserPatch() {
+	start := models.SCIMUserAttributes{
+		ExternalID: "ext",
+		UserName:   "user",
+		Email:      "user@example.com",
+		FirstName:  "First",
+		LastName:   "Last",
+		Active:     true,
+	}
+
+	tests := []struct {
+		name    string
+		op      api.SCIMPatchOperation
+		want    func(a *models.SCIMUserAttributes)
+		wantErr bool
+	}{
+		{
+			name: "replace active",
+			op:   api.SCIMPatchOperation{Op: "replace", Path: "active", Value: json.RawMessage(`false`)},
+			want: func(a *models.SCIMUserAttributes) { a.Active = false },
+		},
+		{
+			name: "replace active as string",
+			op:   api.SCIMPatchOperation{Op: "Replace", Path: "active", Value: json.RawMessage(`"False"`)},
+			want: func(a *models.SCIMUserAttributes) { a.Active = false },
+		},
+		{
+			name: "replace without path",
+			op: api.SCIMPatchOperation{Op: "replace",
+				Value: json.RawMessage(`{"active":false,"name.givenName":"New","userName":"renamed"}`)},
+			want: func(a *models.SCIMUserAttributes) {
+				a.Active = false
+				a.FirstName = "New"
+				a.UserName = "renamed"
+			},
+		},
+		{
+			name: "replace email by filter",
+			op: api.SCIMPatchOperation{Op: "replace", Path: `emails[type eq "work"].value`,
+				Value: json.RawMessage(`"new@example.com"`)},
+			want: func(a *models.SCIMUserAttributes) { a.Email = "new@example.com" },
+		},
+		{
+			name: "replace name",
+			op: api.SCIMPatchOperation{Op: "add", Path: "name",
+				Value: json.RawMessage(`{"givenName":"G","familyName":"F"}`)},
+			want: func(a *models.SCIMUserAttributes) {
+				a.FirstName = "G"
+				a.LastName = "F"
+			},
+		},
+		{
+			name: "remove externalId",
+			op:   api.SCIMPatchOperation{Op: "remove", Path: "externalId"},
+			want: func(a *models.SCIMUserAttributes) { a.ExternalID = "" },
+		},
+		{
+			name: "ignored attribute",
+			op:   api.SCIMPatchOperation{Op: "replace", Path: "title", Value: json.RawMessage(`"Boss"`)},
+			want: func(a *models.SCIMUserAttributes) {},
+		},
+		{
+			name:    "bad op",
+			op:      api.SCIMPatchOperation{Op: "move", Path: "active", Value: json.RawMessage(`false`)},
+			wantErr: true,
+		},
+		{
+			name:    "bad boolean",
+			op:      api.SCIMPatchOperation{Op: "replace", Path: "active", Value: json.RawMessage(`"maybe"`)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		as.T().Run(tt.name, func(t *testing.T) {
+			attrs := start
+			err := applySCIMUserPatch(&attrs, tt.op)
+			if tt.wantErr {
+				as.Error(err)
+				return
+			}
+			as.NoError(err)
+
+			want := start
+			tt.want(&want)
+			as.Equal(want, attrs)
+		})
+	}
+}
+
+func (as *ActionSuite) TestOrganizationsSCIMTokenCreate() {
+	f := createFixturesForSessions(as)
+
+	tests := []struct {
+		name       string
+		user       models.User
+		wantStatus int
+	}{
+		{name: "not an admin", user: f.Users[1], wantStatus: http.StatusNotFound},
+		{name: "admin", user: f.Users[0], wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		as.T().Run(tt.name, func(t *testing.T) {
+			req := as.JSON("/organizations/%s/scim-token", f.Organization.UUID.String())
+			req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", tt.user.Nickname)
+			req.Headers["content-type"] = "application/json"
+			res := req.Post(nil)
+
+			body := res.Body.String()
+			as.Equal(tt.wantStatus, res.Code, "incorrect status code returned, body: %s", body)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var token api.SCIMToken
+			as.NoError(json.Unmarshal([]byte(body), &token))
+			as.Contains(token.BaseURL, "/scim/v2/"+f.Organization.UUID.String())
+
+			var org models.Organization
+			as.NoError(org.FindByUUID(as.DB, f.Organization.UUID.String()))
+			as.True(org.IsValidSCIMToken(token.Token))
+		})
+	}
+}
+
+func (as *ActionSuite) TestSCIMUsers() {
+	uf := test.CreateUserFixtures(as.DB, 1)
+	org := uf.Organization
+	token, err := org.CreateSCIMToken(as.DB)
+	as.NoError(err)
+
+	scimRequest := func(bearer, path string) *httptest.JSON {
+		req := as.JSON("/scim/v2/%s%s", org.UUID.String(), path)
+		req.Headers["Authorization"] = "Bearer " + bearer
+		req.Headers["content-type"] = "application/scim+json"
+		return req
+	}
+
+	// bad token
+	res := scimRequest("wrong", "/Users").Get()
+	as.Equal(http.StatusUnauthorized, res.Code)
+	as.Contains(res.Body.String(), api.SCIMSchemaError)
+
+	// create
+	input := api.SCIMUser{
+		Schemas:    []string{api.SCIMSchemaUser},
+		ExternalID: "00u1",
+		UserName:   "new.member@example.com",
+		Name:       api.SCIMName{GivenName: "New", FamilyName: "Member"},
+		Emails:     []api.SCIMEmail{{Value: "new.member@example.com", Primary: true}},
+	}
+	res = scimRequest(token, "/Users").Post(input)
+	as.Equal(http.StatusCreated, res.Code, "body: %s", res.Body.String())
+
+	var created api.SCIMUser
+	as.NoError(json.Unmarshal(res.Body.Bytes(), &created))
+	as.Equal("new.member@example.com", created.UserName)
+	as.NotNil(created.Active)
+	as.True(*created.Active)
+
+	var user models.User
+	as.NoError(user.FindByUUID(as.DB, created.ID))
+	_, err = user.FindUserOrganization(as.DB, org)
+	as.NoError(err, "new user should be a member of the organization")
+
+	// conflict
+	res = scimRequest(token, "/Users").Post(input)
+	as.Equal(http.StatusConflict, res.Code, "body: %s", res.Body.String())
+
+	// a user of another organization can't be taken over
+	other := models.Organization{Name: "Other", AuthType: models.AuthTypeSaml, AuthConfig: "{}", UUID: domain.GetUUID()}
+	as.NoError(as.DB.Create(&other))
+	otherToken, err := other.CreateSCIMToken(as.DB)
+	as.NoError(err)
+	takeover := api.SCIMUser{
+		Schemas:  []string{api.SCIMSchemaUser},
+		UserName: uf.Users[0].Email,
+		Name:     api.SCIMName{GivenName: "Taken", FamilyName: "Over"},
+		Emails:   []api.SCIMEmail{{Value: uf.Users[0].Email, Primary: true}},
+	}
+	req := as.JSON("/scim/v2/%s/Users", other.UUID.String())
+	req.Headers["Authorization"] = "Bearer " + otherToken
+	req.Headers["content-type"] = "application/scim+json"
+	res = req.Post(takeover)
+	as.Equal(http.StatusConflict, res.Code, "body: %s", res.Body.String())
+
+	// filter
+	res = scimRequest(token, "/Users?filter="+url.QueryEscape(`userName eq "NEW.MEMBER@example.com"`)).Get()
+	as.Equal(http.StatusOK, res.Code, "body: %s", res.Body.String())
+	as.Contains(res.Body.String(), `"totalResults":1`)
+
+	// deactivate
+	patch := api.SCIMPatchOp{
+		Schemas:    []string{api.SCIMSchemaPatchOp},
+		Operations: []api.SCIMPatchOperation{{Op: "replace", Path: "active", Value: json.RawMessage(`false`)}},
+	}
+	res = scimRequest(token, "/Users/"+created.ID).Patch(patch)
+	as.Equal(http.StatusOK, res.Code, "body: %s", res.Body.String())
+	as.Contains(res.Body.String(), `"active":false`)
+	_, err = user.FindUserOrganization(as.DB, org)
+	as.Error(err, "deactivated user should not be a member of the organization")
+
+	// delete
+	res = scimRequest(token, "/Users/"+created.ID).Delete()
+	as.Equal(http.StatusNoContent, res.Code, "body: %s", res.Body.String())
+
+	res = scimRequest(token, "/Users/"+created.ID).Get()
+	as.Equal(http.StatusNotFound, res.Code, "body: %s", res.Body.String())
+}
+
+func (as *ActionSuite) TestSCIMGroups() {
+	uf := test.CreateUserFixtures(as.DB, 1)
+	org := uf.Organization
+	token, err := org.CreateSCIMToken(as.DB)
+	as.NoError(err)
+
+	var member models.SCIMUser
+	as.NoError(member.Provision(as.DB, org, models.SCIMUserAttributes{
+		UserName: "admin@example.com",
+		Email:    "admin@example.com",
+		Active:   true,
+	}))
+	memberID := member.User.UUID.String()
+
+	req := as.JSON("/scim/v2/%s/Groups/%s", org.UUID.String(), models.SCIMGroupAdmins)
+	req.Headers["Authorization"] = "Bearer " + token
+	req.Headers["content-type"] = "application/scim+json"
+
+	patch := api.SCIMPatchOp{
+		Schemas: []string{api.SCIMSchemaPatchOp},
+		Operations: []api.SCIMPatchOperation{{
+			Op:    "add",
+			Path:  "members",
+			Value: json.RawMessage(`[{"value":"` + memberID + `"}]`),
+		}},
+	}
+	res := req.Patch(patch)
+	as.Equal(http.StatusOK, res.Code, "body: %s", res.Body.String())
+	as.Contains(res.Body.String(), memberID)
+	as.True(member.IsAdmin(as.DB), "member should be an admin")
+
+	patch.Operations = []api.SCIMPatchOperation{{Op: "remove", Path: `members[value eq "` + memberID + `"]`}}
+	res = req.Patch(patch)
+	as.Equal(http.StatusOK, res.Code, "body: %s", res.Body.String())
+	as.NotContains(res.Body.String(), memberID)
+	as.False(member.IsAdmin(as.DB), "member should no longer be an admin")
+}
@@ -40,8 +40,18 @@ const (
 	// ServiceTaskTokenCleanup removes expired user access tokens
 	ServiceTaskTokenCleanup ServiceTaskName = job.TokenCleanup
 
-	// ServiceTaskOutdatedRequests sends emails to users who have requests with an outdated needed_before
+	// ServiceTaskOutdatedRequests sends reminders and notices to users who have requests with a near or outdated
+	// needed_before, and expires the requests that are past the grace period
 	ServiceTaskOutdatedRequests ServiceTaskName = job.OutdatedRequests
+
+	// ServiceTaskOutboxCleanup removes domain events that were delivered more than a week ago
+	ServiceTaskOutboxCleanup ServiceTaskName = job.OutboxCleanup
+
+	// ServiceTaskMarketingReconcile brings the marketing list in line with the users' opt-in, names and organizations
+	ServiceTaskMarketingReconcile ServiceTaskName = job.MarketingReconcile
+
+	// ServiceTaskRecurringRequests makes the requests of recurring request templates that are due
+	ServiceTaskRecurringRequests ServiceTaskName = job.RecurringRequests
 )
 
 var serviceTasks = map[ServiceTaskName]ServiceTask{
@@ -57,6 +67,15 @@ var serviceTasks = map[ServiceTaskName]ServiceTask{
 	ServiceTaskOutdatedRequests: {
 		Handler: outdatedRequestsHandler,
 	},
+	ServiceTaskOutboxCleanup: {
+		Handler: outboxCleanupHandler,
+	},
+	ServiceTaskMarketingReconcile: {
+		Handler: marketingReconcileHandler,
+	},
+	ServiceTaskRecurringRequests: {
+		Handler: recurringRequestsHandler,
+	},
 }
 
 func serviceHandler(c buffalo.Context) error {
@@ -117,3 +136,24 @@ func outdatedRequestsHandler(c buffalo.Context) error {
 	}
 	return nil
 }
+
+func outboxCleanupHandler(c buffalo.Context) error {
+	if err := job.Submit(job.OutboxCleanup, nil); err != nil {
+		return c.Error(http.StatusInternalServerError, fmt.Errorf("outbox cleanup job not started, %s", err))
+	}
+	return nil
+}
+
+func marketingReconcileHandler(c buffalo.Context) error {
+	if err := job.Submit(job.MarketingReconcile, nil); err != nil {
+		return c.Error(http.StatusInternalServerError, fmt.Errorf("marketing reconcile job not started, %s", err))
+	}
+	return nil
+}
+
+func recurringRequestsHandler(c buffalo.Context) error {
+	if err := job.Submit(job.RecurringRequests, nil); err != nil {
+		return c.Error(http.StatusInternalServerError, fmt.Errorf("recurring requests job not started, %s", err))
+	}
+	return nil
+}
@@ -70,6 +70,12 @@ func (as *ActionSuite) Test_serviceHandler() {
 			requestBody: postBody(job.OutdatedRequests),
 			wantTask:    ServiceTaskOutdatedRequests,
 		},
+		{
+			name:        "outbox cleanup",
+			token:       domain.Env.ServiceIntegrationToken,
+			requestBody: postBody(job.OutboxCleanup),
+			wantTask:    ServiceTaskOutboxCleanup,
+		},
 	}
 	for _, tt := range tests {
 		as.T().Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,95 @@
+package actions
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gobuffalo/buffalo"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/models"
+)
+
+// swagger:operation GET /users/me/sessions Users UsersMeSessions
+//
+// Lists the active sessions (access tokens) of the authenticated User.
+//
+// ---
+// responses:
+//   '200':
+//     description: active sessions of the authenticated user
+//     schema:
+//       "$ref": "#/definitions/Sessions"
+func usersMeSessions(c buffalo.Context) error {
+	user := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	var tokens models.UserAccessTokens
+	if err := tokens.FindByUser(tx, user); err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorSessionsLoadFailure, api.CategoryInternal))
+	}
+
+	output, err := models.ConvertSessions(tx, tokens, models.CurrentAccessToken(c))
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorFailedToConvertToAPIType, api.CategoryInternal))
+	}
+
+	return c.Render(http.StatusOK, r.JSON(output))
+}
+
+// swagger:operation DELETE /users/me/sessions Users UsersMeSessionsRemove
+//
+// Ends all sessions of the authenticated User other than the one used to make this request.
+//
+// ---
+// responses:
+//   '204':
+//     description: OK but no content in response
+func usersMeSessionsRemove(c buffalo.Context) error {
+	user := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	var tokens models.UserAccessTokens
+	if err := tokens.DeleteByUser(tx, user, models.CurrentAccessToken(c).ID); err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorSessionDeleteFailure, api.CategoryInternal))
+	}
+
+	return c.Render(http.StatusNoContent, nil)
+}
+
+// swagger:operation DELETE /users/me/sessions/{session_id} Users UsersMeSessionRemove
+//
+// Ends one of the authenticated User's sessions.
+//
+// ---
+// parameters:
+//   - name: session_id
+//     in: path
+//     required: true
+//     description: ID of the session to end
+// responses:
+//   '204':
+//     description: OK but no content in response
+func usersMeSessionRemove(c buffalo.Context) error {
+	user := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	id, err := getUUIDFromParam(c, "session_id")
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	var token models.UserAccessToken
+	if err := token.FindByUUID(tx, id.String()); err != nil || token.UserID != user.ID {
+		if err == nil {
+			err = errors.New("session belongs to a different user")
+		}
+		return reportError(c, api.NewAppError(err, api.ErrorSessionNotFound, api.CategoryNotFound))
+	}
+
+	if err := tx.Destroy(&token); err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorSessionDeleteFailure, api.CategoryInternal))
+	}
+
+	return c.Render(http.StatusNoContent, nil)
+}
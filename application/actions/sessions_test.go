@@ -0,0 +1,199 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/internal/test"
+	"github.com/silinternational/wecarry-api/models"
+)
+
+type sessionFixtures struct {
+	models.Organization
+	models.Users
+	models.UserAccessTokens
+}
+
+func createFixturesForSessions(as *ActionSuite) sessionFixtures {
+	uf := test.CreateUserFixtures(as.DB, 3)
+
+	userOrgs := make(models.UserOrganizations, len(uf.Users))
+	tokens := make(models.UserAccessTokens, len(uf.Users))
+	for i, user := range uf.Users {
+		as.NoError(as.DB.Where("user_id = ?", user.ID).First(&userOrgs[i]))
+		as.NoError(as.DB.Where("user_id = ?", user.ID).First(&tokens[i]))
+	}
+
+	// users[0] is an admin of the organization
+	userOrgs[0].Role = models.UserOrganizationRoleAdmin
+	as.NoError(as.DB.Update(&userOrgs[0]))
+
+	// give users[1] a second session
+	secondSession := models.UserAccessToken{
+		UserID:             uf.Users[1].ID,
+		UserOrganizationID: nulls.NewInt(userOrgs[1].ID),
+		AccessToken:        models.HashClientIdAccessToken("second_session"),
+		ClientID:           "second",
+		ExpiresAt:          time.Now().Add(time.Hour),
+	}
+	test.MustCreate(as.DB, &secondSession)
+
+	return sessionFixtures{
+		Organization:     uf.Organization,
+		Users:            uf.Users,
+		UserAccessTokens: append(tokens, secondSession),
+	}
+}
+
+func (as *ActionSuite) countSessions(user models.User) int {
+	n, err := as.DB.Where("user_id = ?", user.ID).Count(&models.UserAccessToken{})
+	as.NoError(err)
+	return n
+}
+
+func (as *ActionSuite) TestUsersMeSessions() {
+	f := createFixturesForSessions(as)
+	user := f.Users[1]
+
+	req := as.JSON("/users/me/sessions")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", user.Nickname)
+	res := req.Get()
+
+	body := res.Body.String()
+	as.Equal(http.StatusOK, res.Code, "incorrect status code returned, body: %s", body)
+
+	wantContains := []string{
+		fmt.Sprintf(`"id":"%s"`, f.UserAccessTokens[1].UUID),
+		fmt.Sprintf(`"id":"%s"`, f.UserAccessTokens[3].UUID),
+		`"client_id":"second"`,
+		`"is_current":true`,
+		`"is_current":false`,
+		fmt.Sprintf(`"organization":{"id":"%s"`, f.Organization.UUID),
+	}
+	as.verifyResponseData(wantContains, body, "")
+	as.NotContains(body, f.UserAccessTokens[0].UUID.String(), "another user's session was listed")
+}
+
+func (as *ActionSuite) TestUsersMeSessionRemove() {
+	f := createFixturesForSessions(as)
+
+	tests := []struct {
+		name       string
+		user       models.User
+		sessionID  string
+		wantStatus int
+	}{
+		{
+			name:       "bad id",
+			user:       f.Users[1],
+			sessionID:  "bad",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "another user's session",
+			user:       f.Users[0],
+			sessionID:  f.UserAccessTokens[3].UUID.String(),
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "not found",
+			user:       f.Users[1],
+			sessionID:  domain.GetUUID().String(),
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "good",
+			user:       f.Users[1],
+			sessionID:  f.UserAccessTokens[3].UUID.String(),
+			wantStatus: http.StatusNoContent,
+		},
+	}
+	for _, tt := range tests {
+		as.T().Run(tt.name, func(t *testing.T) {
+			req := as.JSON("/users/me/sessions/%s", tt.sessionID)
+			req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", tt.user.Nickname)
+			res := req.Delete()
+
+			body := res.Body.String()
+			as.Equal(tt.wantStatus, res.Code, "incorrect status code returned, body: %s", body)
+		})
+	}
+
+	as.Equal(1, as.countSessions(f.Users[1]), "wrong number of sessions remaining")
+}
+
+func (as *ActionSuite) TestUsersMeSessionsRemove() {
+	f := createFixturesForSessions(as)
+	user := f.Users[1]
+
+	req := as.JSON("/users/me/sessions")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", user.Nickname)
+	res := req.Delete()
+
+	body := res.Body.String()
+	as.Equal(http.StatusNoContent, res.Code, "incorrect status code returned, body: %s", body)
+
+	var remaining models.UserAccessTokens
+	as.NoError(as.DB.Where("user_id = ?", user.ID).All(&remaining))
+	as.Equal(1, len(remaining), "wrong number of sessions remaining")
+	as.Equal(f.UserAccessTokens[1].ID, remaining[0].ID, "the current session should not be removed")
+}
+
+func (as *ActionSuite) TestOrganizationsUserSessionsRemove() {
+	f := createFixturesForSessions(as)
+
+	tests := []struct {
+		name       string
+		user       models.User
+		orgID      string
+		memberID   string
+		wantStatus int
+	}{
+		{
+			name:       "not an admin",
+			user:       f.Users[2],
+			orgID:      f.Organization.UUID.String(),
+			memberID:   f.Users[1].UUID.String(),
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "org not found",
+			user:       f.Users[0],
+			orgID:      domain.GetUUID().String(),
+			memberID:   f.Users[1].UUID.String(),
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "member not found",
+			user:       f.Users[0],
+			orgID:      f.Organization.UUID.String(),
+			memberID:   domain.GetUUID().String(),
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "good",
+			user:       f.Users[0],
+			orgID:      f.Organization.UUID.String(),
+			memberID:   f.Users[1].UUID.String(),
+			wantStatus: http.StatusNoContent,
+		},
+	}
+	for _, tt := range tests {
+		as.T().Run(tt.name, func(t *testing.T) {
+			req := as.JSON("/organizations/%s/users/%s/sessions", tt.orgID, tt.memberID)
+			req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", tt.user.Nickname)
+			res := req.Delete()
+
+			body := res.Body.String()
+			as.Equal(tt.wantStatus, res.Code, "incorrect status code returned, body: %s", body)
+		})
+	}
+
+	as.Equal(0, as.countSessions(f.Users[1]), "member's sessions were not removed")
+	as.Equal(1, as.countSessions(f.Users[2]), "other member's session should not be removed")
+}
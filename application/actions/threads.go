@@ -2,23 +2,26 @@ package actions
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
 
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/buffalo/render"
 
 	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/domain"
 	"github.com/silinternational/wecarry-api/models"
 )
 
 // swagger:operation GET /threads Threads UsersThreads
 //
-// List the User's Conversations/Threads
+// List the User's Conversations/Threads. Each thread includes its latest message, but not the rest of its messages.
 //
 //
 // ---
 // responses:
 //   '200':
-//     description: A list of the user's threads/conversations with their messages
+//     description: A list of the user's threads/conversations
 //     schema:
 //       "$ref": "#/definitions/Threads"
 func threadsMine(c buffalo.Context) error {
@@ -38,6 +41,133 @@ func threadsMine(c buffalo.Context) error {
 	return c.Render(200, render.JSON(output))
 }
 
+// swagger:operation GET /threads/{thread_id} Threads GetThread
+//
+// Get one of the User's Conversations/Threads, with its most recent messages
+//
+// ---
+// parameters:
+//   - name: thread_id
+//     in: path
+//     required: true
+//     description: ID of the thread
+// responses:
+//   '200':
+//     description: A thread of messages
+//     schema:
+//       "$ref": "#/definitions/Thread"
+func threadsGet(c buffalo.Context) error {
+	cUser := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	id, err := getUUIDFromParam(c, "thread_id")
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	var thread models.Thread
+	if appErr := thread.FindByUUIDForParticipant(tx, id.String(), cUser); appErr != nil {
+		return reportError(c, appErr)
+	}
+
+	if err := thread.LoadForAPI(tx, cUser); err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorThreadsLoadFailure, api.CategoryInternal))
+	}
+
+	output, err := models.ConvertThread(c, thread)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	return c.Render(200, render.JSON(output))
+}
+
+// swagger:operation GET /threads/{thread_id}/messages Threads ThreadMessages
+//
+// Get a page of the messages of one of the User's Conversations/Threads, most recent first. To page back through
+// older messages, give the `next_before` value of the previous page as the `before` parameter.
+//
+// ---
+// parameters:
+//   - name: thread_id
+//     in: path
+//     required: true
+//     description: ID of the thread
+//   - name: before
+//     in: query
+//     required: false
+//     description: ID of a message in the thread. Only messages sent before this message are returned.
+//   - name: limit
+//     in: query
+//     required: false
+//     description: maximum number of messages to return, 50 by default and at most 200
+// responses:
+//   '200':
+//     description: A page of the thread's messages
+//     schema:
+//       "$ref": "#/definitions/ThreadMessages"
+func threadsMessages(c buffalo.Context) error {
+	cUser := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	id, err := getUUIDFromParam(c, "thread_id")
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	before := ""
+	if c.Param("before") != "" {
+		beforeID, err := getUUIDFromParam(c, "before")
+		if err != nil {
+			return reportError(c, err)
+		}
+		before = beforeID.String()
+	}
+
+	limit, err := getThreadMessagesLimit(c)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	var thread models.Thread
+	if appErr := thread.FindByUUIDForParticipant(tx, id.String(), cUser); appErr != nil {
+		return reportError(c, appErr)
+	}
+
+	hasMore, err := thread.LoadMessagesPage(tx, before, limit)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	messages, err := models.ConvertMessagesToAPIType(c, thread.Messages)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	output := api.ThreadMessages{Messages: messages}
+	if hasMore {
+		output.NextBefore = &messages[0].ID
+	}
+
+	return c.Render(200, render.JSON(output))
+}
+
+// getThreadMessagesLimit gets the `limit` query parameter, or the default page size if it is not given
+func getThreadMessagesLimit(c buffalo.Context) (int, error) {
+	param := c.Param("limit")
+	if param == "" {
+		return domain.ThreadMessagesPageSize, nil
+	}
+
+	limit, err := strconv.Atoi(param)
+	if err != nil || limit < 1 || limit > domain.ThreadMessagesMaxPageSize {
+		newExtra(c, "limit", param)
+		err = fmt.Errorf("limit must be between 1 and %d", domain.ThreadMessagesMaxPageSize)
+		return 0, api.NewAppError(err, api.ErrorThreadMessagesLimitInvalid, api.CategoryUser)
+	}
+	return limit, nil
+}
+
 // swagger:operation PUT /threads/{thread_id}/read Threads MarkAsRead
 //
 // Sets the last viewed time for the current user on the given thread
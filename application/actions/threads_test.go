@@ -2,20 +2,24 @@ package actions
 
 import (
 	"fmt"
+	"net/http"
 	"testing"
 	"time"
 
 	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/models"
 )
 
 func (as *ActionSuite) TestConversations() {
 	f := createFixturesForThreads(as)
 
 	tests := []struct {
-		name         string
-		token        string
-		wantContains []string
-		notWant      string
+		name           string
+		token          string
+		wantContains   []string
+		notWant        string
+		notWantContent string
 	}{
 		{
 			name:         "empty list",
@@ -29,14 +33,15 @@ func (as *ActionSuite) TestConversations() {
 				fmt.Sprintf(`"id":"%s"`, f.Threads[0].UUID),
 				fmt.Sprintf(`"nickname":"%s"`, f.Users[0].Nickname),
 				fmt.Sprintf(`"nickname":"%s"`, f.Users[1].Nickname),
+				`"messages":null`,
+				fmt.Sprintf(`"latest_message":{"id":"%s"`, f.Messages[1].UUID),
 				fmt.Sprintf(`"content":"Reply from %s"`, f.Users[0].Nickname),
-				fmt.Sprintf(`"content":"Message from %s"`, f.Users[1].Nickname),
 				fmt.Sprintf(`"sender":{"id":"%s"`, f.Users[0].UUID),
-				fmt.Sprintf(`"sender":{"id":"%s"`, f.Users[1].UUID),
 				fmt.Sprintf(`"request":{"id":"%s"`, f.Requests[0].UUID),
 				`"unread_message_count":1`,
 			},
-			notWant: `"participants":[{"id":"00000000-`,
+			notWant:        `"participants":[{"id":"00000000-`,
+			notWantContent: fmt.Sprintf(`"content":"Message from %s"`, f.Users[1].Nickname),
 		},
 	}
 
@@ -55,6 +60,143 @@ func (as *ActionSuite) TestConversations() {
 			if tt.notWant != "" {
 				as.NotContains(body, tt.notWant)
 			}
+			if tt.notWantContent != "" {
+				as.NotContains(body, tt.notWantContent, "list should only include the latest message")
+			}
+		})
+	}
+}
+
+func (as *ActionSuite) TestThreadsGet() {
+	f := createFixturesForThreads(as)
+
+	tests := []struct {
+		name         string
+		user         models.User
+		threadID     string
+		wantStatus   int
+		wantContains []string
+	}{
+		{
+			name:         "not a participant",
+			user:         f.Users[2],
+			threadID:     f.Threads[0].UUID.String(),
+			wantStatus:   http.StatusNotFound,
+			wantContains: []string{api.ErrorNotAuthorized.String()},
+		},
+		{
+			name:         "not found",
+			user:         f.Users[0],
+			threadID:     domain.GetUUID().String(),
+			wantStatus:   http.StatusNotFound,
+			wantContains: []string{api.ErrorThreadNotFound.String()},
+		},
+		{
+			name:       "good",
+			user:       f.Users[0],
+			threadID:   f.Threads[0].UUID.String(),
+			wantStatus: http.StatusOK,
+			wantContains: []string{
+				fmt.Sprintf(`"id":"%s"`, f.Threads[0].UUID),
+				fmt.Sprintf(`"content":"Message from %s"`, f.Users[1].Nickname),
+				fmt.Sprintf(`"content":"Reply from %s"`, f.Users[0].Nickname),
+				fmt.Sprintf(`"latest_message":{"id":"%s"`, f.Messages[1].UUID),
+				fmt.Sprintf(`"request":{"id":"%s"`, f.Requests[0].UUID),
+				`"unread_message_count":1`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		as.T().Run(tt.name, func(t *testing.T) {
+			req := as.JSON("/threads/%s", tt.threadID)
+			req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", tt.user.Nickname)
+			res := req.Get()
+
+			body := res.Body.String()
+			as.Equal(tt.wantStatus, res.Code, "incorrect status code returned, body: %s", body)
+			as.verifyResponseData(tt.wantContains, body, "In TestThreadsGet")
+		})
+	}
+}
+
+func (as *ActionSuite) TestThreadsMessages() {
+	f := createFixturesForThreads(as)
+
+	tests := []struct {
+		name         string
+		user         models.User
+		query        string
+		wantStatus   int
+		wantContains []string
+		notWant      string
+	}{
+		{
+			name:         "not a participant",
+			user:         f.Users[2],
+			wantStatus:   http.StatusNotFound,
+			wantContains: []string{api.ErrorNotAuthorized.String()},
+		},
+		{
+			name:         "bad limit",
+			user:         f.Users[0],
+			query:        "?limit=0",
+			wantStatus:   http.StatusBadRequest,
+			wantContains: []string{api.ErrorThreadMessagesLimitInvalid.String()},
+		},
+		{
+			name:         "bad before",
+			user:         f.Users[0],
+			query:        "?before=" + domain.GetUUID().String(),
+			wantStatus:   http.StatusBadRequest,
+			wantContains: []string{api.ErrorThreadMessagesBeforeInvalid.String()},
+		},
+		{
+			name:       "all",
+			user:       f.Users[0],
+			wantStatus: http.StatusOK,
+			wantContains: []string{
+				fmt.Sprintf(`{"messages":[{"id":"%s"`, f.Messages[0].UUID),
+				fmt.Sprintf(`"id":"%s"`, f.Messages[1].UUID),
+				`"next_before":null`,
+			},
+		},
+		{
+			name:       "latest page",
+			user:       f.Users[0],
+			query:      "?limit=1",
+			wantStatus: http.StatusOK,
+			wantContains: []string{
+				fmt.Sprintf(`{"messages":[{"id":"%s"`, f.Messages[1].UUID),
+				fmt.Sprintf(`"next_before":"%s"`, f.Messages[1].UUID),
+			},
+			notWant: f.Messages[0].UUID.String(),
+		},
+		{
+			name:       "previous page",
+			user:       f.Users[0],
+			query:      "?limit=1&before=" + f.Messages[1].UUID.String(),
+			wantStatus: http.StatusOK,
+			wantContains: []string{
+				fmt.Sprintf(`{"messages":[{"id":"%s"`, f.Messages[0].UUID),
+				`"next_before":null`,
+			},
+			notWant: f.Messages[1].UUID.String(),
+		},
+	}
+
+	for _, tt := range tests {
+		as.T().Run(tt.name, func(t *testing.T) {
+			req := as.JSON("/threads/%s/messages%s", f.Threads[0].UUID, tt.query)
+			req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", tt.user.Nickname)
+			res := req.Get()
+
+			body := res.Body.String()
+			as.Equal(tt.wantStatus, res.Code, "incorrect status code returned, body: %s", body)
+			as.verifyResponseData(tt.wantContains, body, "In TestThreadsMessages")
+			if tt.notWant != "" {
+				as.NotContains(body, tt.notWant)
+			}
 		})
 	}
 }
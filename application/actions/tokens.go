@@ -0,0 +1,181 @@
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/models"
+)
+
+// tokenScopeRule gives the scopes a personal access token needs in order to use the routes under a path prefix.
+// Safe (read-only) methods require `read`, all other methods require `write`.
+type tokenScopeRule struct {
+	prefix string
+	read   models.TokenScope
+	write  models.TokenScope
+}
+
+// tokenScopeRules lists the only routes available to personal access tokens. All other routes, including the
+// management of sessions and tokens, require a login session.
+var tokenScopeRules = []tokenScopeRule{
+	{prefix: "/events", read: models.TokenScopeEventsRead, write: models.TokenScopeEventsAdmin},
+	{prefix: "/messages", read: models.TokenScopeMessagesRead, write: models.TokenScopeMessagesWrite},
+	{prefix: "/requests", read: models.TokenScopeRequestsRead, write: models.TokenScopeRequestsWrite},
+	{prefix: "/threads", read: models.TokenScopeMessagesRead, write: models.TokenScopeMessagesWrite},
+	{prefix: "/upload", read: models.TokenScopeRequestsWrite, write: models.TokenScopeRequestsWrite},
+	{prefix: "/watches", read: models.TokenScopeWatchesRead, write: models.TokenScopeWatchesWrite},
+}
+
+// requiredTokenScope returns the scope needed to make the given request with a personal access token. The second
+// return value is false if the route is not available to personal access tokens.
+func requiredTokenScope(method, path string) (models.TokenScope, bool) {
+	for _, rule := range tokenScopeRules {
+		if path != rule.prefix && !strings.HasPrefix(path, rule.prefix+"/") {
+			continue
+		}
+		if method == http.MethodGet || method == http.MethodHead {
+			return rule.read, true
+		}
+		return rule.write, true
+	}
+	return "", false
+}
+
+// enforceTokenScope rejects requests authenticated by a personal access token that lacks the scope required by
+// the route. Requests authenticated by a login session are not affected.
+func enforceTokenScope(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		pat := models.CurrentPersonalAccessToken(c)
+		if pat.ID == 0 {
+			return next(c)
+		}
+
+		req := c.Request()
+		scope, ok := requiredTokenScope(req.Method, req.URL.Path)
+		if !ok {
+			return c.Error(http.StatusForbidden,
+				fmt.Errorf("route %s %s is not available to personal access tokens", req.Method, req.URL.Path))
+		}
+
+		if !pat.HasScope(scope) {
+			return c.Error(http.StatusForbidden,
+				fmt.Errorf("personal access token %s does not have scope %s", pat.UUID, scope))
+		}
+
+		return next(c)
+	}
+}
+
+// swagger:operation GET /users/me/tokens Users UsersMeTokens
+//
+// Lists the personal access tokens of the authenticated User.
+//
+// ---
+// responses:
+//   '200':
+//     description: personal access tokens of the authenticated user
+//     schema:
+//       "$ref": "#/definitions/PersonalAccessTokens"
+func usersMeTokens(c buffalo.Context) error {
+	user := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	var tokens models.PersonalAccessTokens
+	if err := tokens.FindByUser(tx, user); err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorPersonalAccessTokensLoadFailure, api.CategoryInternal))
+	}
+
+	return c.Render(http.StatusOK, r.JSON(models.ConvertPersonalAccessTokens(tokens)))
+}
+
+// swagger:operation POST /users/me/tokens Users UsersMeTokensCreate
+//
+// Creates a personal access token for the authenticated User. The secret token is only provided in this response.
+//
+// ---
+// parameters:
+//   - name: PersonalAccessTokenInput
+//     in: body
+//     required: true
+//     description: input object
+//     schema:
+//       "$ref": "#/definitions/PersonalAccessTokenInput"
+//
+// responses:
+//   '200':
+//     description: the new personal access token, including the secret token
+//     schema:
+//       "$ref": "#/definitions/PersonalAccessToken"
+func usersMeTokensCreate(c buffalo.Context) error {
+	user := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	var input api.PersonalAccessTokenInput
+	if err := StrictBind(c, &input); err != nil {
+		return reportError(c, err)
+	}
+
+	scopes := make([]models.TokenScope, len(input.Scopes))
+	for i := range input.Scopes {
+		scopes[i] = models.TokenScope(input.Scopes[i])
+		if !scopes[i].IsValid() {
+			err := fmt.Errorf("invalid token scope '%s'", input.Scopes[i])
+			return reportError(c, api.NewAppError(err, api.ErrorPersonalAccessTokenInvalidScope, api.CategoryUser))
+		}
+	}
+	if len(scopes) == 0 {
+		err := errors.New("at least one token scope is required")
+		return reportError(c, api.NewAppError(err, api.ErrorPersonalAccessTokenInvalidScope, api.CategoryUser))
+	}
+
+	var expiresAt nulls.Time
+	if input.ExpiresAt != nil {
+		expiresAt = nulls.NewTime(*input.ExpiresAt)
+	}
+
+	pat, token, err := user.CreatePersonalAccessToken(tx, input.Name, scopes, expiresAt)
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorPersonalAccessTokenCreate, api.CategoryUser))
+	}
+
+	output := models.ConvertPersonalAccessToken(pat)
+	output.Token = token
+
+	return c.Render(http.StatusOK, r.JSON(output))
+}
+
+// swagger:operation DELETE /users/me/tokens/{token_id} Users UsersMeTokenRemove
+//
+// Revokes one of the authenticated User's personal access tokens.
+//
+// ---
+// parameters:
+//   - name: token_id
+//     in: path
+//     required: true
+//     description: ID of the token to revoke
+// responses:
+//   '204':
+//     description: OK but no content in response
+func usersMeTokenRemove(c buffalo.Context) error {
+	user := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	id, err := getUUIDFromParam(c, "token_id")
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	var pat models.PersonalAccessToken
+	if _, appErr := pat.DeleteForOwner(tx, id.String(), user); appErr != nil {
+		return reportError(c, appErr)
+	}
+
+	return c.Render(http.StatusNoContent, nil)
+}
@@ -0,0 +1,140 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gobuffalo/nulls"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/internal/test"
+	"github.com/silinternational/wecarry-api/models"
+)
+
+func (as *ActionSuite) Test_requiredTokenScope() {
+	tests := []struct {
+		method    string
+		path      string
+		wantScope models.TokenScope
+		wantOK    bool
+	}{
+		{method: "GET", path: "/requests/", wantScope: models.TokenScopeRequestsRead, wantOK: true},
+		{method: "POST", path: "/requests", wantScope: models.TokenScopeRequestsWrite, wantOK: true},
+		{method: "PUT", path: "/requests/abc/status", wantScope: models.TokenScopeRequestsWrite, wantOK: true},
+		{method: "GET", path: "/events/abc", wantScope: models.TokenScopeEventsRead, wantOK: true},
+		{method: "DELETE", path: "/events/abc", wantScope: models.TokenScopeEventsAdmin, wantOK: true},
+		{method: "GET", path: "/requestsfoo", wantOK: false},
+		{method: "GET", path: "/users/me", wantOK: false},
+		{method: "POST", path: "/users/me/tokens", wantOK: false},
+	}
+	for _, tt := range tests {
+		as.T().Run(tt.method+" "+tt.path, func(t *testing.T) {
+			scope, ok := requiredTokenScope(tt.method, tt.path)
+			as.Equal(tt.wantOK, ok)
+			as.Equal(tt.wantScope, scope)
+		})
+	}
+}
+
+func (as *ActionSuite) TestUsersMeTokensCreate() {
+	uf := test.CreateUserFixtures(as.DB, 1)
+	user := uf.Users[0]
+
+	tests := []struct {
+		name       string
+		input      api.PersonalAccessTokenInput
+		wantStatus int
+	}{
+		{
+			name:       "invalid scope",
+			input:      api.PersonalAccessTokenInput{Name: "bad", Scopes: []string{"everything"}},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "no scopes",
+			input:      api.PersonalAccessTokenInput{Name: "none"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "good",
+			input:      api.PersonalAccessTokenInput{Name: "reports", Scopes: []string{"requests:read"}},
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		as.T().Run(tt.name, func(t *testing.T) {
+			req := as.JSON("/users/me/tokens")
+			req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", user.Nickname)
+			req.Headers["content-type"] = "application/json"
+			res := req.Post(tt.input)
+
+			body := res.Body.String()
+			as.Equal(tt.wantStatus, res.Code, "incorrect status code returned, body: %s", body)
+			if tt.wantStatus == http.StatusOK {
+				as.verifyResponseData([]string{
+					`"name":"reports"`,
+					`"scopes":["requests:read"]`,
+					`"token":"` + models.PersonalAccessTokenPrefix,
+				}, body, "")
+			}
+		})
+	}
+}
+
+func (as *ActionSuite) TestPersonalAccessTokenScopes() {
+	uf := test.CreateUserFixtures(as.DB, 1)
+	user := uf.Users[0]
+
+	_, token, err := user.CreatePersonalAccessToken(as.DB, "read only",
+		[]models.TokenScope{models.TokenScopeRequestsRead}, nulls.Time{})
+	as.NoError(err)
+
+	tests := []struct {
+		name       string
+		path       string
+		method     string
+		wantStatus int
+	}{
+		{name: "allowed", path: "/requests/", method: "GET", wantStatus: http.StatusOK},
+		{name: "missing scope", path: "/watches/", method: "GET", wantStatus: http.StatusForbidden},
+		{name: "route not available", path: "/users/me/tokens", method: "GET", wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		as.T().Run(tt.name, func(t *testing.T) {
+			req := as.JSON(tt.path)
+			req.Headers["Authorization"] = "Bearer " + token
+			res := req.Get()
+
+			body := res.Body.String()
+			as.Equal(tt.wantStatus, res.Code, "incorrect status code returned, body: %s", body)
+		})
+	}
+
+	var pat models.PersonalAccessToken
+	as.NoError(pat.FindByBearerToken(as.DB, token))
+	as.True(pat.LastUsedAt.Valid, "last use was not recorded")
+}
+
+func (as *ActionSuite) TestUsersMeTokenRemove() {
+	uf := test.CreateUserFixtures(as.DB, 2)
+	owner := uf.Users[0]
+
+	pat, token, err := owner.CreatePersonalAccessToken(as.DB, "t",
+		[]models.TokenScope{models.TokenScopeRequestsRead}, nulls.Time{})
+	as.NoError(err)
+
+	req := as.JSON("/users/me/tokens/%s", pat.UUID)
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", uf.Users[1].Nickname)
+	res := req.Delete()
+	as.Equal(http.StatusNotFound, res.Code, "should not be able to revoke another user's token")
+
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", owner.Nickname)
+	res = req.Delete()
+	as.Equal(http.StatusNoContent, res.Code, "incorrect status code returned, body: %s", res.Body.String())
+
+	req = as.JSON("/requests/")
+	req.Headers["Authorization"] = "Bearer " + token
+	res = req.Get()
+	as.Equal(http.StatusUnauthorized, res.Code, "revoked token should not be accepted")
+}
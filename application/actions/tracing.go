@@ -0,0 +1,69 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/pop/v6"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/tracing"
+)
+
+// tracedContext is a buffalo.Context that holds the span of the request
+type tracedContext struct {
+	buffalo.Context
+	ctx context.Context
+}
+
+// Value looks up the key in the span context first, which in turn falls back to the buffalo.Context
+func (t *tracedContext) Value(key interface{}) interface{} {
+	return t.ctx.Value(key)
+}
+
+// traceRequest is middleware that records a span for each request, as part of the caller's trace if the request has
+// a `traceparent` header
+func traceRequest(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		req := c.Request()
+
+		route := "unknown"
+		if ri, ok := c.Value("current_route").(buffalo.RouteInfo); ok {
+			route = ri.Path
+		}
+
+		ctx, span := tracing.Start(tracing.ExtractHTTP(c, req.Header), req.Method+" "+route,
+			semconv.HTTPMethod(req.Method),
+			semconv.HTTPRoute(route),
+			attribute.String("http.target", req.URL.Path),
+		)
+		defer span.End()
+
+		err := next(&tracedContext{Context: c, ctx: ctx})
+
+		status := responseStatus(c, err)
+		span.SetAttributes(semconv.HTTPStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		if err != nil {
+			span.RecordError(err)
+		}
+		return err
+	}
+}
+
+// traceTransaction is middleware that binds the request's transaction to the request's context, so that the
+// queries are part of the request's trace. It must be used after popmw.Transaction.
+func traceTransaction(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		if tx, ok := c.Value(domain.ContextKeyTx).(*pop.Connection); ok {
+			c.Set(domain.ContextKeyTx, tx.WithContext(c))
+		}
+		return next(c)
+	}
+}
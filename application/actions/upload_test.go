@@ -67,6 +67,7 @@ func Fixtures_Upload(as *ActionSuite, t *testing.T) UploadFixtures {
 	hash := models.HashClientIdAccessToken(clientID + accessToken)
 
 	userAccessToken := models.UserAccessToken{
+		UUID:               domain.GetUUID(),
 		UserID:             user.ID,
 		UserOrganizationID: nulls.NewInt(userOrg.ID),
 		AccessToken:        hash,
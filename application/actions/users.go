@@ -1,11 +1,16 @@
 package actions
 
 import (
+	"fmt"
 	"net/http"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/nulls"
 
 	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/domain"
 	"github.com/silinternational/wecarry-api/models"
 )
 
@@ -60,6 +65,21 @@ func usersMeUpdate(c buffalo.Context) error {
 		user.Nickname = *input.Nickname
 	}
 
+	if input.MarketingOptIn != nil {
+		user.MarketingOptIn = *input.MarketingOptIn
+	}
+
+	user.Bio = nulls.String{}
+	if input.Bio != nil && *input.Bio != "" {
+		if utf8.RuneCountInString(*input.Bio) > domain.MaxBioLength {
+			appErr := api.NewAppError(fmt.Errorf("bio is longer than %d characters", domain.MaxBioLength),
+				api.ErrorUserBioTooLong, api.CategoryUser)
+			appErr.Extras = map[string]interface{}{"MaxBioLength": domain.MaxBioLength}
+			return reportError(c, appErr)
+		}
+		user.Bio = nulls.NewString(*input.Bio)
+	}
+
 	tx := models.Tx(c)
 
 	var err error
@@ -72,6 +92,14 @@ func usersMeUpdate(c buffalo.Context) error {
 		return reportError(c, api.NewAppError(err, api.ErrorUserUpdatePhoto, api.CategoryInternal))
 	}
 
+	if input.Location == nil {
+		if err = user.RemoveLocation(tx); err != nil {
+			return reportError(c, api.NewAppError(err, api.ErrorLocationDeleteFailure, api.CategoryInternal))
+		}
+	} else if err = user.SetLocation(tx, models.ConvertLocationInput(*input.Location)); err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorLocationCreateFailure, api.CategoryUser))
+	}
+
 	if err = user.Save(tx); err != nil {
 		return reportError(c, err)
 	}
@@ -83,3 +111,196 @@ func usersMeUpdate(c buffalo.Context) error {
 
 	return c.Render(http.StatusOK, r.JSON(output))
 }
+
+// swagger:operation GET /users/{user_id} Users UsersGet
+//
+// Gets the public profile of a User. The profile is visible to members of the same organization or of a trusted
+// organization. The bio and home location are included only if the User allows it in their preferences.
+//
+// ---
+// parameters:
+//   - name: user_id
+//     in: path
+//     required: true
+//     description: ID of the user
+// responses:
+//   '200':
+//     description: public profile
+//     schema:
+//       "$ref": "#/definitions/UserProfile"
+func usersGet(c buffalo.Context) error {
+	cUser := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	user, err := findUserFromParam(c, tx)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	if !cUser.CanViewProfile(tx, user) {
+		err := fmt.Errorf("user %s may not view profile of user %s", cUser.UUID, user.UUID)
+		return reportError(c, api.NewAppError(err, api.ErrorUserNotFound, api.CategoryNotFound))
+	}
+
+	output, err := models.ConvertUserProfile(c, user)
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorUserProfileLoad, api.CategoryInternal))
+	}
+
+	return c.Render(http.StatusOK, r.JSON(output))
+}
+
+// swagger:operation GET /users/me/preferences Users UsersMePreferences
+//
+// Gets the preferences of the authenticated User.
+//
+// ---
+// responses:
+//   '200':
+//     description: preferences
+//     schema:
+//       "$ref": "#/definitions/UserPreferences"
+func usersMePreferences(c buffalo.Context) error {
+	user := models.CurrentUser(c)
+
+	prefs, err := user.GetPreferences(models.Tx(c))
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorUserPreferencesLoad, api.CategoryInternal))
+	}
+
+	return c.Render(http.StatusOK, r.JSON(models.ConvertUserPreferences(prefs)))
+}
+
+// swagger:operation PUT /users/me/preferences Users UsersMePreferencesUpdate
+//
+// Updates the preferences of the authenticated User. Omitted or empty preferences are reset to their defaults.
+//
+// ---
+// parameters:
+//   - name: UserPreferences
+//     in: body
+//     required: true
+//     description: input object
+//     schema:
+//       "$ref": "#/definitions/UserPreferences"
+//
+// responses:
+//   '200':
+//     description: preferences
+//     schema:
+//       "$ref": "#/definitions/UserPreferences"
+func usersMePreferencesUpdate(c buffalo.Context) error {
+	user := models.CurrentUser(c)
+
+	var input api.UserPreferences
+	if err := StrictBind(c, &input); err != nil {
+		return reportError(c, err)
+	}
+
+	prefs, err := user.UpdateStandardPreferences(models.Tx(c), models.ConvertUserPreferencesInput(input))
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	return c.Render(http.StatusOK, r.JSON(models.ConvertUserPreferences(prefs)))
+}
+
+// swagger:operation GET /users/me/export Users UsersMeExport
+//
+// Exports all of the data held about the authenticated User: profile, requests, offers, threads with all of their
+// messages, watches, request templates, events, and files. The response is a JSON file download.
+//
+// ---
+// responses:
+//   '200':
+//     description: data export
+//     schema:
+//       "$ref": "#/definitions/UserExport"
+func usersMeExport(c buffalo.Context) error {
+	user := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	data, err := user.GetData(tx)
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorUserExportFailure, api.CategoryInternal))
+	}
+
+	output, err := convertUserExport(c, user, data)
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorFailedToConvertToAPIType, api.CategoryInternal))
+	}
+
+	c.Response().Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="wecarry-export-%s.json"`, output.ExportedAt.Format("2006-01-02")))
+	return c.Render(http.StatusOK, r.JSON(output))
+}
+
+// swagger:operation DELETE /users/me Users UsersMeDelete
+//
+// Deletes the account of the authenticated User. All personal data is removed, the User's open requests are removed,
+// the User's messages are deleted, and all of the User's files are removed from storage. The User is signed out of
+// all sessions and unsubscribed from the mailing list. This cannot be undone.
+//
+// ---
+// responses:
+//   '204':
+//     description: OK but no content in response
+func usersMeDelete(c buffalo.Context) error {
+	user := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	if err := user.Anonymize(tx); err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorUserDeleteFailure, api.CategoryInternal))
+	}
+
+	return c.Render(http.StatusNoContent, nil)
+}
+
+func convertUserExport(c buffalo.Context, user models.User, data models.UserData) (api.UserExport, error) {
+	tx := models.Tx(c)
+
+	profile, err := models.ConvertUserPrivate(c, user)
+	if err != nil {
+		return api.UserExport{}, err
+	}
+
+	output := api.UserExport{
+		ExportedAt: time.Now().UTC(),
+		Profile:    profile,
+		FirstName:  user.FirstName,
+		LastName:   user.LastName,
+		Requests:   make(api.Requests, len(data.Requests)),
+		Threads:    make(api.Threads, len(data.Threads)),
+		Files:      models.ConvertFiles(data.Files),
+	}
+
+	for i := range data.Requests {
+		if output.Requests[i], err = models.ConvertRequest(c, data.Requests[i]); err != nil {
+			return api.UserExport{}, err
+		}
+	}
+
+	if output.Offers, err = models.ConvertRequestsAbridged(c, data.Offers); err != nil {
+		return api.UserExport{}, err
+	}
+
+	for i := range data.Threads {
+		if output.Threads[i], err = models.ConvertThread(c, data.Threads[i]); err != nil {
+			return api.UserExport{}, err
+		}
+	}
+
+	if output.Watches, err = convertWatches(tx, data.Watches, user); err != nil {
+		return api.UserExport{}, err
+	}
+
+	if output.Templates, err = convertRequestTemplates(tx, data.Templates); err != nil {
+		return api.UserExport{}, err
+	}
+
+	if output.Events, err = models.ConvertMeetings(c, data.Meetings, user); err != nil {
+		return api.UserExport{}, err
+	}
+
+	return output, nil
+}
@@ -2,9 +2,11 @@ package actions
 
 import (
 	"fmt"
+	"net/http"
 
 	"github.com/gobuffalo/nulls"
 	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/domain"
 	"github.com/silinternational/wecarry-api/internal/test"
 	"github.com/silinternational/wecarry-api/models"
 )
@@ -14,19 +16,33 @@ func (as *ActionSuite) Test_convertUserPrivate() {
 	user := uf.Users[0]
 	org := models.ConvertOrganization(uf.Organization)
 
+	l, err := user.GetLocation(as.DB)
+	as.NoError(err)
+	location := &api.Location{
+		Description: l.Description,
+		Country:     l.Country,
+		State:       l.State,
+		County:      l.County,
+		City:        l.City,
+		Borough:     l.Borough,
+		Latitude:    l.Latitude,
+		Longitude:   l.Longitude,
+	}
+
 	want := api.UserPrivate{
 		ID:            user.UUID,
 		Email:         user.Email,
 		Nickname:      user.Nickname,
 		AvatarURL:     user.AuthPhotoURL,
 		Organizations: []api.Organization{org},
+		Location:      location,
 	}
 	got, _ := models.ConvertUserPrivate(test.Ctx(), user)
 	as.Equal(want, got)
 
 	// with Photo
 	photo := test.CreateFileFixture(as.DB)
-	_, err := user.AttachPhoto(as.DB, photo.UUID.String())
+	_, err = user.AttachPhoto(as.DB, photo.UUID.String())
 	as.NoError(err)
 	want = api.UserPrivate{
 		ID:            user.UUID,
@@ -35,6 +51,7 @@ func (as *ActionSuite) Test_convertUserPrivate() {
 		PhotoID:       nulls.NewUUID(photo.UUID),
 		AvatarURL:     nulls.NewString(photo.URL),
 		Organizations: []api.Organization{org},
+		Location:      location,
 	}
 	got, _ = models.ConvertUserPrivate(test.Ctx(), user)
 	as.Equal(want, got)
@@ -122,3 +139,149 @@ func (as *ActionSuite) verifyUser(user models.User, apiUser api.User, msg string
 		as.Equal(*avatarURL, apiUser.AvatarURL.String, msg+", AvatarURL is not correct")
 	}
 }
+
+func (as *ActionSuite) TestUsersMeExport() {
+	uf := test.CreateUserFixtures(as.DB, 2)
+	user := uf.Users[0]
+	requests := test.CreateRequestFixtures(as.DB, 1, false, user.ID)
+	others := test.CreateRequestFixtures(as.DB, 1, false, uf.Users[1].ID)
+
+	req := as.JSON("/users/me/export")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", user.Nickname)
+	res := req.Get()
+
+	body := res.Body.String()
+	as.Equal(http.StatusOK, res.Code, "incorrect status code returned, body: %s", body)
+	as.Contains(res.Header().Get("Content-Disposition"), "attachment")
+
+	as.verifyResponseData([]string{
+		fmt.Sprintf(`"profile":{"id":"%s"`, user.UUID),
+		fmt.Sprintf(`"first_name":"%s"`, user.FirstName),
+		fmt.Sprintf(`"requests":[{"id":"%s"`, requests[0].UUID),
+		`"offers":[]`,
+		`"threads":[]`,
+		`"watches":[]`,
+		`"events":[]`,
+		`"files":[]`,
+	}, body, "In TestUsersMeExport")
+	as.NotContains(body, others[0].UUID.String(), "export should not include other users' requests")
+}
+
+func (as *ActionSuite) TestUsersMeDelete() {
+	uf := test.CreateUserFixtures(as.DB, 2)
+	user := uf.Users[0]
+	requests := test.CreateRequestFixtures(as.DB, 1, false, user.ID)
+
+	req := as.JSON("/users/me")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", user.Nickname)
+	res := req.Delete()
+	as.Equal(http.StatusNoContent, res.Code, "incorrect status code returned, body: %s", res.Body.String())
+
+	var request models.Request
+	as.NoError(request.FindByID(as.DB, requests[0].ID))
+	as.Equal(models.RequestStatusRemoved, request.Status, "open request was not removed")
+
+	req = as.JSON("/users/me")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", user.Nickname)
+	res = req.Get()
+	as.Equal(http.StatusUnauthorized, res.Code, "deleted user's session should have ended")
+
+	req = as.JSON("/users/me")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", uf.Users[1].Nickname)
+	res = req.Get()
+	as.Equal(http.StatusOK, res.Code, "other users should not be affected")
+}
+
+func (as *ActionSuite) TestUsersGet() {
+	uf := test.CreateUserFixtures(as.DB, 3)
+	viewer, member, outsider := uf.Users[0], uf.Users[1], uf.Users[2]
+
+	org2 := models.Organization{Name: "org2", AuthType: AuthTypeGoogle, AuthConfig: "{}"}
+	as.NoError(org2.Save(as.DB))
+	as.NoError(as.DB.RawQuery("UPDATE user_organizations SET organization_id = ? WHERE user_id = ?",
+		org2.ID, outsider.ID).Exec())
+
+	member.Bio = nulls.NewString("I travel a lot")
+	as.NoError(member.Save(as.DB))
+	_, err := member.UpdateStandardPreferences(as.DB, models.StandardPreferences{
+		LocationVisibility: domain.UserPreferenceVisibilityVisible,
+	})
+	as.NoError(err)
+	location, err := member.GetLocation(as.DB)
+	as.NoError(err)
+
+	requests := test.CreateRequestFixtures(as.DB, 1, false, viewer.ID)
+	requests[0].Status = models.RequestStatusCompleted
+	requests[0].ProviderID = nulls.NewInt(member.ID)
+	as.NoError(as.DB.Save(&requests[0]))
+
+	get := func(user models.User) (int, string) {
+		req := as.JSON("/users/%s", user.UUID)
+		req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", viewer.Nickname)
+		res := req.Get()
+		return res.Code, res.Body.String()
+	}
+
+	code, body := get(member)
+	as.Equal(http.StatusOK, code, "incorrect status code returned, body: %s", body)
+	as.verifyResponseData([]string{
+		fmt.Sprintf(`"id":"%s"`, member.UUID),
+		fmt.Sprintf(`"nickname":"%s"`, member.Nickname),
+		`"completed_deliveries":1`,
+		`"completed_requests":0`,
+		fmt.Sprintf(`"location":{"city":"%s","state":"%s","country":"%s"}`,
+			location.City, location.State, location.Country),
+		`"bio":"I travel a lot"`,
+	}, body, "In TestUsersGet, member")
+	as.NotContains(body, member.Email, "profile should not include the email address")
+
+	code, body = get(outsider)
+	as.Equal(http.StatusNotFound, code, "user in another organization should not be visible, body: %s", body)
+
+	trust := models.OrganizationTrust{PrimaryID: uf.Organization.ID, SecondaryID: org2.ID}
+	as.NoError(trust.CreateSymmetric(as.DB))
+
+	code, body = get(outsider)
+	as.Equal(http.StatusOK, code, "user in a trusted organization should be visible, body: %s", body)
+	as.verifyResponseData([]string{`"location":null`, `"bio":null`}, body, "In TestUsersGet, outsider")
+
+	_, err = member.UpdateStandardPreferences(as.DB, models.StandardPreferences{
+		BioVisibility: domain.UserPreferenceVisibilityHidden,
+	})
+	as.NoError(err)
+
+	code, body = get(member)
+	as.Equal(http.StatusOK, code, "incorrect status code returned, body: %s", body)
+	as.verifyResponseData([]string{`"location":null`, `"bio":null`}, body, "In TestUsersGet, hidden")
+}
+
+func (as *ActionSuite) TestUsersMePreferences() {
+	uf := test.CreateUserFixtures(as.DB, 1)
+	user := uf.Users[0]
+
+	req := as.JSON("/users/me/preferences")
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", user.Nickname)
+	req.Headers["content-type"] = "application/json"
+
+	res := req.Put(api.UserPreferences{
+		Language:      domain.UserPreferenceLanguageFrench,
+		BioVisibility: domain.UserPreferenceVisibilityHidden,
+	})
+	body := res.Body.String()
+	as.Equal(http.StatusOK, res.Code, "incorrect status code returned, body: %s", body)
+
+	res = req.Get()
+	body = res.Body.String()
+	as.Equal(http.StatusOK, res.Code, "incorrect status code returned, body: %s", body)
+	as.verifyResponseData([]string{
+		`"language":"fr"`,
+		`"weight_unit":""`,
+		`"bio_visibility":"hidden"`,
+		`"location_visibility":""`,
+	}, body, "In TestUsersMePreferences")
+
+	res = req.Put(api.UserPreferences{LocationVisibility: "everyone"})
+	body = res.Body.String()
+	as.Equal(http.StatusBadRequest, res.Code, "incorrect status code returned, body: %s", body)
+	as.Contains(body, api.ErrorUserPreferenceInvalid.String())
+}
@@ -2,6 +2,7 @@ package actions
 
 import (
 	"errors"
+	"time"
 
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/buffalo/render"
@@ -9,6 +10,7 @@ import (
 	"github.com/gobuffalo/pop/v6"
 
 	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/domain"
 	"github.com/silinternational/wecarry-api/models"
 )
 
@@ -43,26 +45,11 @@ func watchesCreate(c buffalo.Context) error {
 
 	newWatch, err := convertWatchInput(tx, input, cUser)
 	if err != nil {
-		err := errors.New("unable to find Meeting related to a new Watch, error: " + err.Error())
-		return reportError(c, api.NewAppError(err, api.ErrorWatchInputMeetingFailure, api.CategoryUser))
-	}
-
-	if input.Destination != nil {
-		location := models.ConvertLocationInput(*input.Destination)
-		if err = location.Create(tx); err != nil {
-			err := errors.New("unable to create the destination related to a new Watch, error: " + err.Error())
-			return reportError(c, api.NewAppError(err, api.ErrorLocationCreateFailure, api.CategoryInternal))
-		}
-		newWatch.DestinationID = nulls.NewInt(location.ID)
+		return reportError(c, err)
 	}
 
-	if input.Origin != nil {
-		location := models.ConvertLocationInput(*input.Origin)
-		if err = location.Create(tx); err != nil {
-			err := errors.New("unable to create the origin related to a new Watch, error: " + err.Error())
-			return reportError(c, api.NewAppError(err, api.ErrorLocationCreateFailure, api.CategoryInternal))
-		}
-		newWatch.OriginID = nulls.NewInt(location.ID)
+	if err = setWatchLocations(tx, input, &newWatch); err != nil {
+		return reportError(c, err)
 	}
 
 	if err = newWatch.Create(tx); err != nil {
@@ -75,6 +62,115 @@ func watchesCreate(c buffalo.Context) error {
 	return c.Render(200, render.JSON(output))
 }
 
+// swagger:operation PUT /watches/{watch_id} Watches UpdateWatch
+//
+// Replace all of the properties of one of the User's Watches
+//
+// ---
+// parameters:
+//   - name: watch
+//     in: body
+//     description: watch input object
+//     required: true
+//     schema:
+//       "$ref": "#/definitions/WatchInput"
+// responses:
+//   '200':
+//     description: the updated watch
+//     schema:
+//       "$ref": "#/definitions/Watch"
+func watchesUpdate(c buffalo.Context) error {
+	var input api.WatchInput
+	if err := StrictBind(c, &input); err != nil {
+		return reportError(c, err)
+	}
+
+	if input.IsEmpty() {
+		err := errors.New("empty WatchInput is not allowed")
+		return reportError(c, api.NewAppError(err, api.ErrorWatchInputEmpty, api.CategoryUser))
+	}
+
+	cUser := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	id, err := getUUIDFromParam(c, "watch_id")
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	var watch models.Watch
+	if appErr := watch.FindByUUIDForOwner(tx, id.String(), cUser); appErr != nil {
+		return reportError(c, appErr)
+	}
+
+	newWatch, err := convertWatchInput(tx, input, cUser)
+	if err != nil {
+		return reportError(c, err)
+	}
+	newWatch.ID = watch.ID
+	newWatch.UUID = watch.UUID
+	newWatch.CreatedAt = watch.CreatedAt
+	newWatch.DestinationID = watch.DestinationID
+	newWatch.OriginID = watch.OriginID
+
+	if err = setWatchLocations(tx, input, &newWatch); err != nil {
+		return reportError(c, err)
+	}
+
+	if err = newWatch.Update(tx); err != nil {
+		err := errors.New("unable to update the Watch, error: " + err.Error())
+		return reportError(c, api.NewAppError(err, api.ErrorWatchUpdateFailure, api.CategoryInternal))
+	}
+
+	if err = tx.Load(&newWatch, "Destination", "Origin", "Meeting"); err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorWatchesLoadFailure, api.CategoryInternal))
+	}
+
+	output, err := convertWatch(tx, newWatch, cUser)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	return c.Render(200, render.JSON(output))
+}
+
+// swagger:operation GET /watches/{watch_id}/requests Watches WatchRequests
+//
+// Run one of the User's Watches as a saved search, listing the open requests that match it now
+//
+// ---
+// responses:
+//   '200':
+//     description: A list of the matching requests, newest first
+//     schema:
+//       "$ref": "#/definitions/RequestsAbridged"
+func watchesRequests(c buffalo.Context) error {
+	cUser := models.CurrentUser(c)
+	tx := models.Tx(c)
+
+	id, err := getUUIDFromParam(c, "watch_id")
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	var watch models.Watch
+	if appErr := watch.FindByUUIDForOwner(tx, id.String(), cUser); appErr != nil {
+		return reportError(c, appErr)
+	}
+
+	requests, err := watch.FindMatchingRequests(tx)
+	if err != nil {
+		return reportError(c, api.NewAppError(err, api.ErrorWatchRequestsLoadFailure, api.CategoryInternal))
+	}
+
+	output, err := models.ConvertRequestsAbridged(c, requests)
+	if err != nil {
+		return reportError(c, err)
+	}
+
+	return c.Render(200, render.JSON(output))
+}
+
 // swagger:operation DELETE /watches/{watch_id} Watches RemoveWatch
 //
 // Remove one of the User's Watches
@@ -169,26 +265,44 @@ func convertWatch(tx *pop.Connection, watch models.Watch, user models.User) (api
 		output.Origin = nil
 	}
 
+	output.NeededBeforeStart = convertWatchDate(watch.NeededBeforeStart)
+	output.NeededBeforeEnd = convertWatchDate(watch.NeededBeforeEnd)
+
 	return output, nil
 }
 
-// convertWatchInput takes a `WatchInput` and either finds a record matching the UUID given in `input.ID` or
-// creates a new `models.Watch` with a new UUID. In either case, all properties that are not `nil` are set to the value
-// provided in `input`
+func convertWatchDate(date nulls.Time) nulls.String {
+	if !date.Valid {
+		return nulls.String{}
+	}
+	return nulls.NewString(date.Time.Format(domain.DateFormat))
+}
+
+// convertWatchInput creates a new `models.Watch` with a new UUID from a `WatchInput`. All properties that are not
+// `nil` are set to the value provided in `input`, except for the destination and origin, which are set by
+// `setWatchLocations`.
 func convertWatchInput(tx *pop.Connection, input api.WatchInput, user models.User) (models.Watch, error) {
 	watch := models.Watch{}
 
+	watch.UUID = domain.GetUUID()
 	watch.OwnerID = user.ID
 	watch.Name = input.Name
 
 	watch.SearchText = input.SearchText
 
-	if input.Size == nil {
-		watch.Size = nil
-	} else {
-		apiSize := *input.Size
-		s := models.GetRequestSizeFromAPISize(apiSize)
-		watch.Size = &s
+	watch.Size = convertWatchInputSize(input.Size)
+	watch.MinSize = convertWatchInputSize(input.MinSize)
+
+	watch.RadiusKm = input.RadiusKm
+	watch.PausedUntil = input.PausedUntil
+	watch.ExpiresAt = input.ExpiresAt
+
+	var err error
+	if watch.NeededBeforeStart, err = convertWatchInputDate(input.NeededBeforeStart); err != nil {
+		return watch, err
+	}
+	if watch.NeededBeforeEnd, err = convertWatchInputDate(input.NeededBeforeEnd); err != nil {
+		return watch, err
 	}
 
 	if !input.MeetingID.Valid {
@@ -196,10 +310,74 @@ func convertWatchInput(tx *pop.Connection, input api.WatchInput, user models.Use
 	} else {
 		var meeting models.Meeting
 		if err := meeting.FindByUUID(tx, input.MeetingID.UUID.String()); err != nil {
-			return watch, err
+			err = errors.New("unable to find Meeting related to a Watch, error: " + err.Error())
+			return watch, api.NewAppError(err, api.ErrorWatchInputMeetingFailure, api.CategoryUser)
 		}
 		watch.MeetingID = nulls.NewInt(meeting.ID)
 	}
 
+	vErrs, err := watch.Validate(tx)
+	if err != nil {
+		return watch, api.NewAppError(err, api.ErrorWatchInputInvalid, api.CategoryInternal)
+	}
+	if vErrs.HasAny() {
+		err = errors.New("invalid WatchInput, error: " + vErrs.Error())
+		return watch, api.NewAppError(err, api.ErrorWatchInputInvalid, api.CategoryUser)
+	}
+
 	return watch, nil
 }
+
+func convertWatchInputSize(size *api.RequestSize) *models.RequestSize {
+	if size == nil {
+		return nil
+	}
+	s := models.GetRequestSizeFromAPISize(*size)
+	return &s
+}
+
+func convertWatchInputDate(date nulls.String) (nulls.Time, error) {
+	if !date.Valid {
+		return nulls.Time{}, nil
+	}
+	t, err := time.Parse(domain.DateFormat, date.String)
+	if err != nil {
+		err = errors.New("failed to parse Watch date, " + err.Error())
+		return nulls.Time{}, api.NewAppError(err, api.ErrorWatchInputInvalid, api.CategoryUser)
+	}
+	return nulls.NewTime(t), nil
+}
+
+// setWatchLocations sets the destination and origin of a watch from a `WatchInput`, updating the watch's existing
+// location records or creating new ones. A destination or origin that is `nil` in `input` is removed from the watch.
+func setWatchLocations(tx *pop.Connection, input api.WatchInput, watch *models.Watch) error {
+	var err error
+	if watch.DestinationID, err = setWatchLocation(tx, input.Destination, watch.DestinationID); err != nil {
+		err = errors.New("unable to save the destination related to a Watch, error: " + err.Error())
+		return api.NewAppError(err, api.ErrorLocationCreateFailure, api.CategoryInternal)
+	}
+
+	if watch.OriginID, err = setWatchLocation(tx, input.Origin, watch.OriginID); err != nil {
+		err = errors.New("unable to save the origin related to a Watch, error: " + err.Error())
+		return api.NewAppError(err, api.ErrorLocationCreateFailure, api.CategoryInternal)
+	}
+
+	return nil
+}
+
+func setWatchLocation(tx *pop.Connection, input *api.Location, locationID nulls.Int) (nulls.Int, error) {
+	if input == nil {
+		return nulls.Int{}, nil
+	}
+
+	location := models.ConvertLocationInput(*input)
+	if locationID.Valid {
+		location.ID = locationID.Int
+		return locationID, location.Update(tx)
+	}
+
+	if err := location.Create(tx); err != nil {
+		return nulls.Int{}, err
+	}
+	return nulls.NewInt(location.ID), nil
+}
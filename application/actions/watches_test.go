@@ -71,6 +71,7 @@ func (as *ActionSuite) Test_WatchesCreate() {
 	}
 
 	xlarge := api.RequestSizeXlarge
+	small := api.RequestSizeSmall
 	testCases := []testCase{
 		{
 			name: "bad meeting id",
@@ -91,6 +92,27 @@ func (as *ActionSuite) Test_WatchesCreate() {
 			wantStatus:   http.StatusBadRequest,
 			wantContains: api.ErrorWatchInputEmpty.String(),
 		},
+		{
+			name: "min size larger than size",
+			watch: api.WatchInput{
+				Name:    "Bad Size Range",
+				Size:    &small,
+				MinSize: &xlarge,
+			},
+			user:         owner,
+			wantStatus:   http.StatusBadRequest,
+			wantContains: api.ErrorWatchInputInvalid.String(),
+		},
+		{
+			name: "bad needed before date",
+			watch: api.WatchInput{
+				Name:              "Bad Date",
+				NeededBeforeStart: nulls.NewString("tomorrow"),
+			},
+			user:         owner,
+			wantStatus:   http.StatusBadRequest,
+			wantContains: api.ErrorWatchInputInvalid.String(),
+		},
 		{
 			name: "just give the search text field",
 			watch: api.WatchInput{
@@ -307,3 +329,145 @@ func (as *ActionSuite) Test_WatchRemove() {
 		})
 	}
 }
+
+func (as *ActionSuite) Test_WatchesUpdate() {
+	f := createFixturesForWatches(as)
+	owner := f.Users[0]
+	notOwner := f.Users[1]
+	watch := f.Watches[0]
+
+	small := api.RequestSizeSmall
+	large := api.RequestSizeLarge
+	pausedUntil := time.Now().Add(domain.DurationWeek).Truncate(time.Second).UTC()
+	goodInput := api.WatchInput{
+		Name: "Updated",
+		Origin: &api.Location{
+			Description: "new watch origin",
+			Country:     "cd",
+			Latitude:    11.1,
+			Longitude:   22.2,
+		},
+		SearchText:        nulls.NewString("Updated"),
+		Size:              &large,
+		MinSize:           &small,
+		RadiusKm:          nulls.NewInt(25),
+		NeededBeforeStart: nulls.NewString("2030-01-01"),
+		NeededBeforeEnd:   nulls.NewString("2030-01-31"),
+		PausedUntil:       nulls.NewTime(pausedUntil),
+	}
+
+	tests := []struct {
+		name         string
+		watchID      string
+		input        api.WatchInput
+		user         models.User
+		wantStatus   int
+		wantContains string
+	}{
+		{
+			name:         "bad ID",
+			watchID:      "badid",
+			input:        goodInput,
+			user:         owner,
+			wantStatus:   http.StatusBadRequest,
+			wantContains: api.ErrorMustBeAValidUUID.String(),
+		},
+		{
+			name:         "not owner",
+			watchID:      watch.UUID.String(),
+			input:        goodInput,
+			user:         notOwner,
+			wantStatus:   http.StatusNotFound,
+			wantContains: api.ErrorNotAuthorized.String(),
+		},
+		{
+			name:         "empty input",
+			watchID:      watch.UUID.String(),
+			input:        api.WatchInput{Name: "Empty"},
+			user:         owner,
+			wantStatus:   http.StatusBadRequest,
+			wantContains: api.ErrorWatchInputEmpty.String(),
+		},
+		{
+			name:    "bad radius",
+			watchID: watch.UUID.String(),
+			input: api.WatchInput{
+				SearchText: nulls.NewString("Updated"),
+				RadiusKm:   nulls.NewInt(-1),
+			},
+			user:         owner,
+			wantStatus:   http.StatusBadRequest,
+			wantContains: api.ErrorWatchInputInvalid.String(),
+		},
+		{
+			name:         "good",
+			watchID:      watch.UUID.String(),
+			input:        goodInput,
+			user:         owner,
+			wantStatus:   http.StatusOK,
+			wantContains: `"name":"Updated"`,
+		},
+	}
+	for _, tt := range tests {
+		as.T().Run(tt.name, func(t *testing.T) {
+			req := as.JSON("/watches/%s", tt.watchID)
+			req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", tt.user.Nickname)
+			req.Headers["content-type"] = "application/json"
+			res := req.Put(tt.input)
+
+			body := res.Body.String()
+			as.Equal(tt.wantStatus, res.Code, "incorrect status code returned, body: %s", body)
+			as.Contains(body, tt.wantContains)
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			as.Contains(body, `"needed_before_start":"2030-01-01"`)
+			as.Contains(body, `"radius_km":25`)
+			as.NotContains(body, `"destination":`)
+
+			var dbWatch models.Watch
+			as.NoError(as.DB.Eager("Origin").Find(&dbWatch, watch.ID))
+			as.Equal(watch.UUID, dbWatch.UUID, "incorrect Watch UUID")
+			as.Equal(owner.ID, dbWatch.OwnerID, "incorrect Watch owner")
+			as.Equal("Updated", dbWatch.Name, "incorrect Watch name")
+			as.False(dbWatch.DestinationID.Valid, "Watch destination was not removed")
+			as.Equal("new watch origin", dbWatch.Origin.Description, "incorrect Watch origin")
+			as.Equal(models.RequestSizeLarge, *dbWatch.Size, "incorrect Watch size")
+			as.Equal(models.RequestSizeSmall, *dbWatch.MinSize, "incorrect Watch minimum size")
+			as.Equal(nulls.NewInt(25), dbWatch.RadiusKm, "incorrect Watch radius")
+			as.Equal("2030-01-31", dbWatch.NeededBeforeEnd.Time.Format(domain.DateFormat))
+			as.WithinDuration(pausedUntil, dbWatch.PausedUntil.Time, time.Second, "incorrect Watch paused until")
+			as.False(dbWatch.IsActive(time.Now()), "Watch should be paused")
+		})
+	}
+}
+
+func (as *ActionSuite) Test_WatchesRequests() {
+	f := createFixturesForWatches(as)
+	owner := f.Users[0]
+	notOwner := f.Users[1]
+
+	requests := test.CreateRequestFixtures(as.DB, 2, false, notOwner.ID)
+
+	watch := f.Watches[0]
+	watch.DestinationID = nulls.Int{}
+	watch.SearchText = nulls.NewString(requests[1].Title)
+	as.NoError(watch.Update(as.DB))
+
+	req := as.JSON("/watches/%s/requests", watch.UUID.String())
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", notOwner.Nickname)
+	res := req.Get()
+	as.Equal(http.StatusNotFound, res.Code, "incorrect status code returned, body: %s", res.Body.String())
+	as.Contains(res.Body.String(), api.ErrorNotAuthorized.String())
+
+	req = as.JSON("/watches/%s/requests", watch.UUID.String())
+	req.Headers["Authorization"] = fmt.Sprintf("Bearer %s", owner.Nickname)
+	res = req.Get()
+
+	body := res.Body.String()
+	as.Equal(http.StatusOK, res.Code, "incorrect status code returned, body: %s", body)
+	as.Contains(body, requests[1].UUID.String(), "matching request is missing")
+	as.NotContains(body, requests[0].UUID.String(), "request does not match the watch")
+}
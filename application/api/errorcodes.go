@@ -33,6 +33,7 @@ const (
 	ErrorMethodNotAllowed     = ErrorKey("ErrorMethodNotAllowed")
 	ErrorNotAuthenticated     = ErrorKey("ErrorNotAuthenticated")
 	ErrorRouteNotFound        = ErrorKey("ErrorRouteNotFound")
+	ErrorTooManyRequests      = ErrorKey("ErrorTooManyRequests")
 	ErrorUnexpectedHTTPStatus = ErrorKey("ErrorUnexpectedHTTPStatus")
 	ErrorUnprocessableEntity  = ErrorKey("ErrorUnprocessableEntity")
 
@@ -64,6 +65,10 @@ const (
 	ErrorMissingSessionInviteObjectUUID = ErrorKey("ErrorMissingSessionInviteObjectUUID")
 	ErrorMissingSessionSocialAuthType   = ErrorKey("ErrorMissingSessionSocialAuthType")
 	ErrorOrglessUserNotAllowed          = ErrorKey("ErrorOrglessUserNotAllowed")
+	ErrorSAMLLogoutRequest              = ErrorKey("ErrorSAMLLogoutRequest")
+	ErrorSAMLLogoutResponse             = ErrorKey("ErrorSAMLLogoutResponse")
+	ErrorSAMLMetadata                   = ErrorKey("ErrorSAMLMetadata")
+	ErrorSAMLNotConfigured              = ErrorKey("ErrorSAMLNotConfigured")
 	ErrorWithAuthUser                   = ErrorKey("ErrorWithAuthUser")
 
 	// File
@@ -100,9 +105,35 @@ const (
 
 	ErrorMessageBadRequestUUID        = ErrorKey("ErrorMessageBadRequestUUID")
 	ErrorMessageBadThreadUUID         = ErrorKey("ErrorMessageBadThreadUUID")
+	ErrorMessageDeleteFailure         = ErrorKey("ErrorMessageDeleteFailure")
+	ErrorMessageFileIDNotFound        = ErrorKey("ErrorMessageFileIDNotFound")
+	ErrorMessageNotEditable           = ErrorKey("ErrorMessageNotEditable")
+	ErrorMessageNotFound              = ErrorKey("ErrorMessageNotFound")
+	ErrorMessageReplyAddressNotFound  = ErrorKey("ErrorMessageReplyAddressNotFound")
 	ErrorMessageRequestNotVisible     = ErrorKey("ErrorMessageRequestNotVisible")
+	ErrorMessageSenderBlocked         = ErrorKey("ErrorMessageSenderBlocked")
 	ErrorMessageThreadNotVisible      = ErrorKey("ErrorMessageThreadNotVisible")
 	ErrorMessageThreadRequestMismatch = ErrorKey("ErrorMessageThreadRequestMismatch")
+	ErrorMessageUpdateFailure         = ErrorKey("ErrorMessageUpdateFailure")
+
+	// Personal Access Token
+
+	ErrorPersonalAccessTokenCreate        = ErrorKey("ErrorPersonalAccessTokenCreate")
+	ErrorPersonalAccessTokenDeleteFailure = ErrorKey("ErrorPersonalAccessTokenDeleteFailure")
+	ErrorPersonalAccessTokenInvalidScope  = ErrorKey("ErrorPersonalAccessTokenInvalidScope")
+	ErrorPersonalAccessTokenNotFound      = ErrorKey("ErrorPersonalAccessTokenNotFound")
+	ErrorPersonalAccessTokensLoadFailure  = ErrorKey("ErrorPersonalAccessTokensLoadFailure")
+
+	// Report
+
+	ErrorReportActionFailure   = ErrorKey("ErrorReportActionFailure")
+	ErrorReportActionInvalid   = ErrorKey("ErrorReportActionInvalid")
+	ErrorReportAlreadyResolved = ErrorKey("ErrorReportAlreadyResolved")
+	ErrorReportCreateFailure   = ErrorKey("ErrorReportCreateFailure")
+	ErrorReportInputInvalid    = ErrorKey("ErrorReportInputInvalid")
+	ErrorReportNotFound        = ErrorKey("ErrorReportNotFound")
+	ErrorReportSubjectNotFound = ErrorKey("ErrorReportSubjectNotFound")
+	ErrorReportsLoadFailure    = ErrorKey("ErrorReportsLoadFailure")
 
 	// Request
 
@@ -132,12 +163,49 @@ const (
 	ErrorUpdateRequestStatusBadStatus            = ErrorKey("ErrorUpdateRequestStatusBadStatus")
 	ErrorUpdateRequestStatusBadProvider          = ErrorKey("ErrorUpdateRequestStatusBadProvider")
 	ErrorUpdateRequestInvalidDate                = ErrorKey("ErrorUpdateRequestInvalidDate")
+	ErrorDuplicateRequest                        = ErrorKey("ErrorDuplicateRequest")
+	ErrorDuplicateRequestNotCreator              = ErrorKey("ErrorDuplicateRequestNotCreator")
+	ErrorRenewRequest                            = ErrorKey("ErrorRenewRequest")
+	ErrorRenewRequestNotCreator                  = ErrorKey("ErrorRenewRequestNotCreator")
+	ErrorRenewRequestNotExpired                  = ErrorKey("ErrorRenewRequestNotExpired")
+
+	// Request Template
+
+	ErrorRequestTemplateCreateFailure  = ErrorKey("ErrorRequestTemplateCreateFailure")
+	ErrorRequestTemplateDeleteFailure  = ErrorKey("ErrorRequestTemplateDeleteFailure")
+	ErrorRequestTemplateInputInvalid   = ErrorKey("ErrorRequestTemplateInputInvalid")
+	ErrorRequestTemplateNotFound       = ErrorKey("ErrorRequestTemplateNotFound")
+	ErrorRequestTemplateRequestFailure = ErrorKey("ErrorRequestTemplateRequestFailure")
+	ErrorRequestTemplatesLoadFailure   = ErrorKey("ErrorRequestTemplatesLoadFailure")
+	ErrorRequestTemplateUpdateFailure  = ErrorKey("ErrorRequestTemplateUpdateFailure")
+
+	// Organization
+
+	ErrorOrganizationNotFound       = ErrorKey("ErrorOrganizationNotFound")
+	ErrorOrganizationMemberNotFound = ErrorKey("ErrorOrganizationMemberNotFound")
+	ErrorOrganizationSCIMToken      = ErrorKey("ErrorOrganizationSCIMToken")
+
+	// SCIM
+
+	ErrorSCIMInvalidFilter    = ErrorKey("ErrorSCIMInvalidFilter")
+	ErrorSCIMInvalidValue     = ErrorKey("ErrorSCIMInvalidValue")
+	ErrorSCIMProvisionFailure = ErrorKey("ErrorSCIMProvisionFailure")
+	ErrorSCIMUserConflict     = ErrorKey("ErrorSCIMUserConflict")
+	ErrorSCIMUserNotFound     = ErrorKey("ErrorSCIMUserNotFound")
+
+	// Session
+
+	ErrorSessionDeleteFailure = ErrorKey("ErrorSessionDeleteFailure")
+	ErrorSessionNotFound      = ErrorKey("ErrorSessionNotFound")
+	ErrorSessionsLoadFailure  = ErrorKey("ErrorSessionsLoadFailure")
 
 	// Thread
 
-	ErrorThreadsLoadFailure    = ErrorKey("ErrorThreadsLoadFailure")
-	ErrorThreadNotFound        = ErrorKey("ErrorThreadNotFound")
-	ErrorThreadSetLastViewedAt = ErrorKey("ErrorThreadSetLastViewedAt")
+	ErrorThreadsLoadFailure          = ErrorKey("ErrorThreadsLoadFailure")
+	ErrorThreadMessagesBeforeInvalid = ErrorKey("ErrorThreadMessagesBeforeInvalid")
+	ErrorThreadMessagesLimitInvalid  = ErrorKey("ErrorThreadMessagesLimitInvalid")
+	ErrorThreadNotFound              = ErrorKey("ErrorThreadNotFound")
+	ErrorThreadSetLastViewedAt       = ErrorKey("ErrorThreadSetLastViewedAt")
 
 	// User
 
@@ -145,14 +213,28 @@ const (
 	ErrorUserUpdatePhoto       = ErrorKey("ErrorUserUpdatePhoto")
 	ErrorUserInvisibleNickname = ErrorKey("ErrorUserInvisibleNickname")
 	ErrorUserDuplicateNickname = ErrorKey("ErrorUserDuplicateNickname")
+	ErrorUserBioTooLong        = ErrorKey("ErrorUserBioTooLong")
+	ErrorUserBlockFailure      = ErrorKey("ErrorUserBlockFailure")
+	ErrorUserBlockSelf         = ErrorKey("ErrorUserBlockSelf")
+	ErrorUserBlocksLoadFailure = ErrorKey("ErrorUserBlocksLoadFailure")
+	ErrorUserDeleteFailure     = ErrorKey("ErrorUserDeleteFailure")
+	ErrorUserExportFailure     = ErrorKey("ErrorUserExportFailure")
+	ErrorUserNotFound          = ErrorKey("ErrorUserNotFound")
+	ErrorUserPreferenceInvalid = ErrorKey("ErrorUserPreferenceInvalid")
+	ErrorUserPreferencesLoad   = ErrorKey("ErrorUserPreferencesLoad")
+	ErrorUserProfileLoad       = ErrorKey("ErrorUserProfileLoad")
+	ErrorUserSuspended         = ErrorKey("ErrorUserSuspended")
 
 	// Watch
 
 	ErrorWatchCreateFailure       = ErrorKey("ErrorWatchCreateFailure")
 	ErrorWatchDeleteFailure       = ErrorKey("ErrorWatchDeleteFailure")
 	ErrorWatchInputEmpty          = ErrorKey("ErrorWatchInputEmpty")
+	ErrorWatchInputInvalid        = ErrorKey("ErrorWatchInputInvalid")
 	ErrorWatchInputMeetingFailure = ErrorKey("ErrorWatchInputMeetingFailure")
 	ErrorWatchesLoadFailure       = ErrorKey("ErrorWatchesLoadFailure")
 	ErrorWatchMissingID           = ErrorKey("ErrorWatchMissingID")
 	ErrorWatchNotFound            = ErrorKey("ErrorWatchNotFound")
+	ErrorWatchRequestsLoadFailure = ErrorKey("ErrorWatchRequestsLoadFailure")
+	ErrorWatchUpdateFailure       = ErrorKey("ErrorWatchUpdateFailure")
 )
@@ -3,6 +3,7 @@ package api
 import (
 	"time"
 
+	"github.com/gobuffalo/nulls"
 	"github.com/gofrs/uuid"
 )
 
@@ -26,7 +27,7 @@ type Message struct {
 	// example: 2020-10-02T15:00:00Z
 	CreatedAt time.Time `json:"created_at"`
 
-	// Text content of the message
+	// Text content of the message. Empty if the message was deleted.
 	//
 	Content string `json:"content"`
 
@@ -34,6 +35,27 @@ type Message struct {
 	//
 	// read-only: true
 	SentBy *User `json:"sender"`
+
+	// true if the content was changed after the message was sent
+	//
+	// read-only: true
+	IsEdited bool `json:"is_edited"`
+
+	// datetime when the content was last changed
+	//
+	// read-only: true
+	// example: 2020-10-02T15:05:00Z
+	EditedAt nulls.Time `json:"edited_at"`
+
+	// true if the message was deleted by its sender
+	//
+	// read-only: true
+	IsDeleted bool `json:"is_deleted"`
+
+	// files attached to the message, such as photos or receipts. Empty if the message was deleted.
+	//
+	// read-only: true
+	Files []File `json:"files"`
 }
 
 // MessageInput contains parameters to create a Message
@@ -47,4 +69,15 @@ type MessageInput struct {
 
 	// Message thread to which the new message should be attached. If not specified, a new thread is created.
 	ThreadID *string `json:"thread_id"`
+
+	// IDs of previously-uploaded files to attach to the message
+	FileIDs []uuid.UUID `json:"file_ids"`
+}
+
+// MessageUpdateInput contains parameters to edit a Message. A message can only be edited by its sender, for a limited
+// time after it was sent.
+// swagger:model
+type MessageUpdateInput struct {
+	// message content, limited to 4,096 characters
+	Content string `json:"content"`
 }
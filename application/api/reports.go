@@ -0,0 +1,83 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gofrs/uuid"
+)
+
+// swagger:model
+type Reports []Report
+
+// A Report of a request, message or user that may be abusive, fraudulent or otherwise inappropriate. Reports are
+// reviewed by moderators: the admins of the organizations involved and the super admins.
+// swagger:model
+type Report struct {
+	// unique identifier for the Report
+	// swagger:strfmt uuid4
+	// example: 63d5b060-1460-4348-bdf0-ad03c105a8d5
+	ID uuid.UUID `json:"id"`
+
+	// type of the reported item, one of `REQUEST`, `MESSAGE`, or `USER`
+	SubjectType string `json:"subject_type"`
+
+	// user who made the report
+	Reporter User `json:"reporter"`
+
+	// reported user, or the creator of the reported request or the sender of the reported message
+	ReportedUser User `json:"reported_user"`
+
+	// ID of the reported request, or of the request of the reported message
+	// swagger:strfmt uuid4
+	RequestID *uuid.UUID `json:"request_id"`
+
+	// title of the reported request, or of the request of the reported message
+	RequestTitle nulls.String `json:"request_title"`
+
+	// ID of the reported message
+	// swagger:strfmt uuid4
+	MessageID *uuid.UUID `json:"message_id"`
+
+	// content of the reported message
+	MessageContent nulls.String `json:"message_content"`
+
+	// reason for the report, one of `SPAM`, `ABUSE`, `FRAUD`, `INAPPROPRIATE`, or `OTHER`
+	Reason string `json:"reason"`
+
+	// optional explanation given by the reporter
+	Comment nulls.String `json:"comment"`
+
+	// status of the report, one of `OPEN`, `DISMISSED`, or `ACTIONED`
+	Status string `json:"status"`
+
+	// moderation action taken to resolve the report, one of `hide_request`, `suspend_user`, or `dismiss`
+	Action nulls.String `json:"action"`
+
+	// moderator who resolved the report
+	ResolvedBy *User `json:"resolved_by"`
+
+	// time the report was resolved
+	ResolvedAt nulls.Time `json:"resolved_at"`
+
+	// time the report was made
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReportInput contains the parameters to report a request, message or user
+// swagger:model
+type ReportInput struct {
+	// reason for the report, one of `SPAM`, `ABUSE`, `FRAUD`, `INAPPROPRIATE`, or `OTHER`
+	Reason string `json:"reason"`
+
+	// optional explanation, limited to 4,096 characters
+	Comment *string `json:"comment"`
+}
+
+// ReportActionInput contains the moderation action to take to resolve a Report
+// swagger:model
+type ReportActionInput struct {
+	// `hide_request` removes the reported request (or the request of the reported message), `suspend_user` suspends
+	// the reported user, and `dismiss` resolves the report without action
+	Action string `json:"action"`
+}
@@ -29,7 +29,7 @@ type Request struct {
 	// Whether request is editable by current user
 	IsEditable bool `json:"is_editable"`
 
-	// Request status: OPEN, ACCEPTED, DELIVERED, RECEIVED, COMPLETED, REMOVED
+	// Request status: OPEN, ACCEPTED, DELIVERED, RECEIVED, COMPLETED, REMOVED, EXPIRED
 	Status RequestStatus `json:"status"`
 
 	// Profile of the user that created this request
@@ -98,7 +98,7 @@ type RequestAbridged struct {
 	// example: 63d5b060-1460-4348-bdf0-ad03c105a8d5
 	ID uuid.UUID `json:"id"`
 
-	// Request status: OPEN, ACCEPTED, DELIVERED, RECEIVED, COMPLETED, REMOVED
+	// Request status: OPEN, ACCEPTED, DELIVERED, RECEIVED, COMPLETED, REMOVED, EXPIRED
 	Status RequestStatus `json:"status"`
 
 	// Profile of the user that created this request
@@ -230,3 +230,19 @@ type RequestUpdateStatusInput struct {
 	// User ID of the accepted provider. Required if `status` is ACCEPTED and ignored otherwise.
 	ProviderUserID *string `json:"provider_user_id"`
 }
+
+// RequestDuplicateInput includes the fields for duplicating a Request
+//
+// swagger:model
+type RequestDuplicateInput struct {
+	// Date (yyyy-mm-dd) before which the item will be needed. If omitted or `null`, the new request has no date.
+	NeededBefore nulls.String `json:"needed_before"`
+}
+
+// RequestRenewInput includes the fields for renewing an expired Request
+//
+// swagger:model
+type RequestRenewInput struct {
+	// New date (yyyy-mm-dd) before which the item will be needed. Required, and must be after today.
+	NeededBefore nulls.String `json:"needed_before"`
+}
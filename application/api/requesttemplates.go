@@ -0,0 +1,124 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gofrs/uuid"
+)
+
+// swagger:model
+type RequestTemplates []RequestTemplate
+
+// RequestTemplate is a saved set of request details, from which new requests can be made on demand or at a regular
+// interval
+//
+// swagger:model
+type RequestTemplate struct {
+	// unique identifier for the RequestTemplate
+	//
+	// swagger:strfmt uuid4
+	// unique: true
+	// example: 63d5b060-1460-4348-bdf0-ad03c105a8d5
+	ID uuid.UUID `json:"id"`
+
+	// Short description of the template, as named by its owner
+	Name string `json:"name"`
+
+	// Organization of the requests made from this template
+	Organization Organization `json:"organization"`
+
+	// Visibility restrictions for the requests made from this template
+	Visibility RequestVisibility `json:"visibility"`
+
+	// Short description of item, limited to 255 characters
+	Title string `json:"title"`
+
+	// Optional, longer description of the item, limited to 4,096 characters
+	Description nulls.String `json:"description"`
+
+	// Geographic location where item is needed
+	Destination Location `json:"destination"`
+
+	// Optional geographic location where the item can be picked up, purchased, or otherwise obtained
+	Origin *Location `json:"origin"`
+
+	// Broad category of the size of item
+	Size RequestSize `json:"size"`
+
+	// Optional weight of the item, measured in kilograms
+	Kilograms nulls.Float64 `json:"kilograms"`
+
+	// Number of days after a request is made from this template that the item will be needed. If `null`, the
+	// requests have no `needed_before` date unless one is given.
+	NeededWithinDays nulls.Int `json:"needed_within_days"`
+
+	// Number of days between requests made automatically from this template. If `null`, requests are only made on
+	// demand.
+	RepeatEveryDays nulls.Int `json:"repeat_every_days"`
+
+	// Date (yyyy-mm-dd) on which the next request will be made automatically
+	NextRequestOn nulls.String `json:"next_request_on"`
+
+	// ID of the most recent request made automatically from this template
+	// swagger:strfmt uuid4
+	LastRequestID nulls.UUID `json:"last_request_id"`
+
+	// Date and time this template was created
+	CreatedAt time.Time `json:"created_at"`
+
+	// Date and time this template was last updated
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RequestTemplateInput includes the fields for creating a RequestTemplate, or replacing all of the properties of an
+// existing one
+//
+// swagger:model
+type RequestTemplateInput struct {
+	// Short description of the template. If omitted, the title is used.
+	Name string `json:"name"`
+
+	// ID of associated Organization. Affects visibility of the requests, see also the `visibility` field.
+	OrganizationID uuid.UUID `json:"org_id"`
+
+	// Visibility restrictions for the requests, if omitted, the default is "SAME"
+	Visibility RequestVisibility `json:"visibility"`
+
+	// Short description, limited to 255 characters
+	Title string `json:"title"`
+
+	// Optional, longer description, limited to 4096 characters
+	Description nulls.String `json:"description"`
+
+	// Geographic location where item is needed
+	Destination Location `json:"destination"`
+
+	// Optional geographic location where the item can be picked up, purchased, or otherwise obtained
+	Origin *Location `json:"origin"`
+
+	// Broad category of the size of item.
+	Size RequestSize `json:"size"`
+
+	// Optional weight of the item, measured in kilograms
+	Kilograms nulls.Float64 `json:"kilograms"`
+
+	// Number of days, at least 2, after a request is made that the item will be needed
+	NeededWithinDays nulls.Int `json:"needed_within_days"`
+
+	// Number of days between requests made automatically. If omitted or `null`, requests are only made on demand.
+	RepeatEveryDays nulls.Int `json:"repeat_every_days"`
+
+	// Date (yyyy-mm-dd) of the first request made automatically. If omitted or `null`, the first request is made
+	// today.
+	StartOn nulls.String `json:"start_on"`
+}
+
+// RequestFromTemplateInput includes the fields for making a Request from a RequestTemplate
+//
+// swagger:model
+type RequestFromTemplateInput struct {
+	// Date (yyyy-mm-dd) before which the item will be needed. If omitted or `null`, the date is set by the
+	// template's `needed_within_days`, if any.
+	NeededBefore nulls.String `json:"needed_before"`
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// swagger:model
+type Sessions []Session
+
+// Session is an active user access token, i.e. a signed-in device or browser
+// swagger:model
+type Session struct {
+	// unique identifier for the Session
+	// swagger:strfmt uuid4
+	// example: 63d5b060-1460-4348-bdf0-ad03c105a8d5
+	ID uuid.UUID `json:"id"`
+
+	// client ID provided by the UI at login
+	ClientID string `json:"client_id"`
+
+	// organization the session was created for, if any
+	Organization *Organization `json:"organization,omitempty"`
+
+	// IP address of the most recent request made with this session
+	IPAddress string `json:"ip_address"`
+
+	// time the session was created
+	CreatedAt time.Time `json:"created_at"`
+
+	// time the session was last used, null if never used after login
+	LastUsedAt *time.Time `json:"last_used_at"`
+
+	// time the session will expire if not used again
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// true if this is the session used to make the current request
+	IsCurrent bool `json:"is_current"`
+}
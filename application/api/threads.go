@@ -28,9 +28,13 @@ type Thread struct {
 	// LastViewedAt is the time the auth user last viewed this thread. Messages with `updatedAt` after this time can be considered unread.
 	LastViewedAt *time.Time `json:"last_viewed_at"`
 
-	// Messages in this thread
+	// The most recent messages in this thread, oldest first. Older messages can be retrieved with
+	// `GET /threads/{thread_id}/messages`. Omitted (null) in the list of the user's threads.
 	Messages *Messages `json:"messages"`
 
+	// LatestMessage is the most recent message in this thread
+	LatestMessage *Message `json:"latest_message"`
+
 	// Users participating in the message thread. The request creator is automatically added to all of the requests's threads
 	Participants Users `json:"participants"`
 
@@ -44,6 +48,20 @@ type Thread struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// ThreadMessages is a page of a thread's messages
+//
+// swagger:model
+type ThreadMessages struct {
+	// Messages on this page, oldest first
+	Messages Messages `json:"messages"`
+
+	// ID of the oldest message on this page, to be given as the `before` parameter to get the previous page. Null if
+	// there are no older messages.
+	//
+	// swagger:strfmt uuid4
+	NextBefore *uuid.UUID `json:"next_before"`
+}
+
 // MarkMessagesAsReadInput is an object for setting the last_viewed_at time of a thread
 // swagger:model
 type MarkMessagesAsReadInput struct {
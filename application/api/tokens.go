@@ -0,0 +1,54 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// swagger:model
+type PersonalAccessTokens []PersonalAccessToken
+
+// PersonalAccessToken is a named, revocable credential for scripted access to the API, limited to a set of scopes
+// swagger:model
+type PersonalAccessToken struct {
+	// unique identifier for the token
+	// swagger:strfmt uuid4
+	// example: 63d5b060-1460-4348-bdf0-ad03c105a8d5
+	ID uuid.UUID `json:"id"`
+
+	// name given to the token by its owner
+	Name string `json:"name"`
+
+	// scopes granted to the token, e.g. "requests:read"
+	Scopes []string `json:"scopes"`
+
+	// the secret token to use as a Bearer token. Only provided in the response to the creation request.
+	Token string `json:"token,omitempty"`
+
+	// time the token was created
+	CreatedAt time.Time `json:"created_at"`
+
+	// time the token expires, null if it does not expire
+	ExpiresAt *time.Time `json:"expires_at"`
+
+	// time the token was last used, null if never used
+	LastUsedAt *time.Time `json:"last_used_at"`
+
+	// IP address of the most recent request made with the token
+	LastIP string `json:"last_ip"`
+}
+
+// Input object to create a PersonalAccessToken
+// swagger:model
+type PersonalAccessTokenInput struct {
+	// name of the token, must be unique for the user
+	Name string `json:"name"`
+
+	// scopes to grant to the token. Valid scopes are events:admin, events:read, messages:read, messages:write,
+	// requests:read, requests:write, watches:read, and watches:write
+	Scopes []string `json:"scopes"`
+
+	// optional expiration time
+	ExpiresAt *time.Time `json:"expires_at"`
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"time"
+
 	"github.com/gobuffalo/nulls"
 	"github.com/gofrs/uuid"
 )
@@ -30,6 +32,16 @@ type UserPrivate struct {
 
 	// Organizations that the User is affilated with. This can be empty or have a single entry. Future capability is TBD
 	Organizations []Organization `json:"organizations"`
+
+	// Whether the User is subscribed to the marketing email list
+	MarketingOptIn bool `json:"marketing_opt_in"`
+
+	// Short description of the User, shown on the public profile unless hidden by the `bio_visibility` preference
+	Bio nulls.String `json:"bio"`
+
+	// User's home location, shown at city level on the public profile if allowed by the `location_visibility`
+	// preference
+	Location *Location `json:"location"`
 }
 
 // swagger:model
@@ -61,4 +73,116 @@ type UsersInput struct {
 	// swagger:strfmt uuid4
 	// example: 63d5b060-1460-4348-bdf0-ad03c105a8d5
 	PhotoID *string `json:"photo_id"`
+
+	// Subscribe to, or unsubscribe from, the marketing email list. If omitted, the subscription is unchanged.
+	MarketingOptIn *bool `json:"marketing_opt_in"`
+
+	// Short description of the User, limited to 500 characters. If omitted or `null`, the bio is removed.
+	Bio *string `json:"bio"`
+
+	// User's home location. If omitted or `null`, the location is removed.
+	Location *Location `json:"location"`
+}
+
+// UserProfile is the public profile of a User, visible to members of the same or a trusted Organization
+// swagger:model
+type UserProfile struct {
+	// unique identifier for the User
+	// swagger:strfmt uuid4
+	// example: 63d5b060-1460-4348-bdf0-ad03c105a8d5
+	ID uuid.UUID `json:"id"`
+
+	// User's nickname
+	Nickname string `json:"nickname"`
+
+	// avatarURL is generated from an attached photo if present, an external URL if present, or a Gravatar URL
+	// swagger:strfmt url
+	AvatarURL nulls.String `json:"avatar_url"`
+
+	// time at which the User joined
+	MemberSince time.Time `json:"member_since"`
+
+	// Organizations that the User is affiliated with
+	Organizations []Organization `json:"organizations"`
+
+	// number of requests completed with the User as the provider
+	CompletedDeliveries int `json:"completed_deliveries"`
+
+	// number of the User's own requests that were completed
+	CompletedRequests int `json:"completed_requests"`
+
+	// User's home location at city level, or `null` if not set or hidden by the User
+	Location *ProfileLocation `json:"location"`
+
+	// Short description of the User, or `null` if not set or hidden by the User
+	Bio nulls.String `json:"bio"`
+}
+
+// ProfileLocation is a location at city level, as shown on a public profile
+// swagger:model
+type ProfileLocation struct {
+	// Equivalent to Google Place's locality
+	City string `json:"city"`
+
+	// Equivalent to Google Place's administrative area level 1
+	State string `json:"state"`
+
+	// Country (ISO 3166-1 Alpha-2 code), e.g. 'US'
+	Country string `json:"country"`
+}
+
+// UserPreferences are the settings of the authenticated User. An empty value means the default.
+// swagger:model
+type UserPreferences struct {
+	// language for notifications, one of `en`, `es`, `fr`, `ko` or `pt`
+	Language string `json:"language"`
+
+	// time zone, e.g. `America/New_York`
+	TimeZone string `json:"time_zone"`
+
+	// unit of weight, `kilograms` or `pounds`
+	WeightUnit string `json:"weight_unit"`
+
+	// whether the bio is shown on the public profile, `visible` (default) or `hidden`
+	BioVisibility string `json:"bio_visibility"`
+
+	// whether the home location is shown on the public profile, `visible` or `hidden` (default)
+	LocationVisibility string `json:"location_visibility"`
+}
+
+// UserExport is an archive of the data held about the authenticated User
+// swagger:model
+type UserExport struct {
+	// time at which the export was made
+	ExportedAt time.Time `json:"exported_at"`
+
+	// the User's profile
+	Profile UserPrivate `json:"profile"`
+
+	// User's first name, as provided by the authentication provider
+	FirstName string `json:"first_name"`
+
+	// User's last name, as provided by the authentication provider
+	LastName string `json:"last_name"`
+
+	// requests created by the User
+	Requests Requests `json:"requests"`
+
+	// requests on which the User has offered to be the provider
+	Offers RequestsAbridged `json:"offers"`
+
+	// message threads in which the User is a participant, with all of their messages
+	Threads Threads `json:"threads"`
+
+	// the User's watches
+	Watches Watches `json:"watches"`
+
+	// the User's request templates
+	Templates RequestTemplates `json:"templates"`
+
+	// events created by the User or in which the User is a participant
+	Events Meetings `json:"events"`
+
+	// files attached to the User's profile, requests and messages
+	Files []File `json:"files"`
 }
@@ -34,9 +34,30 @@ type Watch struct {
 
 	// Maximum size of a requested item
 	Size nulls.String `json:"size"`
+
+	// Minimum size of a requested item
+	MinSize nulls.String `json:"min_size"`
+
+	// Maximum distance in km of a request's destination or origin from that of the Watch. If `null`, the default
+	// proximity distance is used.
+	RadiusKm nulls.Int `json:"radius_km"`
+
+	// Earliest date (yyyy-mm-dd) of the `needed_before` date of a request. Requests with no `needed_before` date
+	// always match.
+	NeededBeforeStart nulls.String `json:"needed_before_start"`
+
+	// Latest date (yyyy-mm-dd) of the `needed_before` date of a request. Requests with no `needed_before` date
+	// always match.
+	NeededBeforeEnd nulls.String `json:"needed_before_end"`
+
+	// No notifications are sent for the Watch until this time
+	PausedUntil nulls.Time `json:"paused_until"`
+
+	// No notifications are sent for the Watch after this time
+	ExpiresAt nulls.Time `json:"expires_at"`
 }
 
-// Input object to create a new Watch for the user
+// Input object to create a new Watch for the user, or to replace all of the properties of an existing Watch
 // swagger:model
 type WatchInput struct {
 	// Short description, as named by the Watch creator
@@ -56,6 +77,25 @@ type WatchInput struct {
 
 	// Maximum size of a requested item
 	Size *RequestSize `json:"size"`
+
+	// Minimum size of a requested item
+	MinSize *RequestSize `json:"min_size"`
+
+	// Maximum distance in km of a request's destination or origin from that of the Watch. If `null`, the default
+	// proximity distance is used.
+	RadiusKm nulls.Int `json:"radius_km"`
+
+	// Earliest date (yyyy-mm-dd) of the `needed_before` date of a request
+	NeededBeforeStart nulls.String `json:"needed_before_start"`
+
+	// Latest date (yyyy-mm-dd) of the `needed_before` date of a request
+	NeededBeforeEnd nulls.String `json:"needed_before_end"`
+
+	// Pause notifications for the Watch until this time
+	PausedUntil nulls.Time `json:"paused_until"`
+
+	// Stop notifications for the Watch after this time
+	ExpiresAt nulls.Time `json:"expires_at"`
 }
 
 func (w WatchInput) IsEmpty() bool {
@@ -71,6 +111,14 @@ func (w WatchInput) IsEmpty() bool {
 		return false
 	}
 
+	if w.MinSize != nil && w.MinSize.String() != "" {
+		return false
+	}
+
+	if w.NeededBeforeStart.Valid || w.NeededBeforeEnd.Valid {
+		return false
+	}
+
 	if w.MeetingID.Valid {
 		return false
 	}
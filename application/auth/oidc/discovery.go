@@ -0,0 +1,127 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// discoveryCacheLifetime is how long a discovery document or key set is reused before it is fetched again
+const discoveryCacheLifetime = time.Hour
+
+const discoveryPath = "/.well-known/openid-configuration"
+
+// Discovery holds the fields used from an OpenID Provider's discovery document
+//
+// see https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+type Discovery struct {
+	Issuer                           string   `json:"issuer"`
+	AuthorizationEndpoint            string   `json:"authorization_endpoint"`
+	TokenEndpoint                    string   `json:"token_endpoint"`
+	UserInfoEndpoint                 string   `json:"userinfo_endpoint"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	EndSessionEndpoint               string   `json:"end_session_endpoint"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+	CodeChallengeMethodsSupported    []string `json:"code_challenge_methods_supported"`
+}
+
+type cachedDocument struct {
+	fetchedAt time.Time
+	value     interface{}
+}
+
+var (
+	cache     = map[string]cachedDocument{}
+	cacheLock sync.Mutex
+)
+
+func getCached(url string) (interface{}, bool) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+
+	c, ok := cache[url]
+	if !ok || time.Since(c.fetchedAt) > discoveryCacheLifetime {
+		return nil, false
+	}
+	return c.value, true
+}
+
+func setCached(url string, value interface{}) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+
+	cache[url] = cachedDocument{fetchedAt: time.Now(), value: value}
+}
+
+func clearCached(url string) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+
+	delete(cache, url)
+}
+
+// discover fetches (or retrieves from cache) the discovery document for the configured issuer
+func (p *Provider) discover(ctx context.Context) (Discovery, error) {
+	url := p.Config.DiscoveryURL
+	if url == "" {
+		url = strings.TrimSuffix(p.Config.Issuer, "/") + discoveryPath
+	}
+
+	if d, ok := getCached(url); ok {
+		return d.(Discovery), nil
+	}
+
+	var d Discovery
+	if err := p.getJSON(ctx, url, &d); err != nil {
+		return Discovery{}, fmt.Errorf("error fetching OIDC discovery document, %w", err)
+	}
+
+	if strings.TrimSuffix(d.Issuer, "/") != strings.TrimSuffix(p.Config.Issuer, "/") {
+		return Discovery{}, fmt.Errorf("OIDC discovery issuer '%s' does not match configured issuer '%s'",
+			d.Issuer, p.Config.Issuer)
+	}
+
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return Discovery{}, fmt.Errorf("OIDC discovery document at %s is missing a required endpoint", url)
+	}
+
+	setCached(url, d)
+	return d, nil
+}
+
+// getJSON makes a GET request to `url` and decodes the JSON response into `v`
+func (p *Provider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	return p.doJSON(req, v)
+}
+
+func (p *Provider) doJSON(req *http.Request, v interface{}) error {
+	res, err := p.Client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with status %d", req.URL.Host, res.StatusCode)
+	}
+
+	return json.Unmarshal(body, v)
+}
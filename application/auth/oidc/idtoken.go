@@ -0,0 +1,289 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// clockSkew is the tolerance allowed when checking the time claims of an ID token
+const clockSkew = time.Minute
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// signingAlgorithms are the supported ID token signature algorithms. "none" and HMAC algorithms are deliberately
+// not supported.
+var signingAlgorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// verifyIDToken checks the signature and standard claims of an ID token and returns its claims
+func (p *Provider) verifyIDToken(ctx context.Context, d Discovery, rawToken, nonce string) (map[string]interface{}, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("ID token is not a valid JWT")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid ID token header, %w", err)
+	}
+
+	hash, ok := signingAlgorithms[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("unsupported ID token signing algorithm '%s'", header.Alg)
+	}
+
+	key, err := p.getSigningKey(ctx, d, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token signature encoding, %w", err)
+	}
+
+	if err := verifySignature(header.Alg, hash, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid ID token payload, %w", err)
+	}
+
+	if err := p.validateClaims(d, claims, nonce, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// validateClaims checks the issuer, audience, time, and nonce claims of an ID token
+//
+// see https://openid.net/specs/openid-connect-core-1_0.html#IDTokenValidation
+func (p *Provider) validateClaims(d Discovery, claims map[string]interface{}, nonce string, now time.Time) error {
+	if iss, _ := claims["iss"].(string); iss != d.Issuer {
+		return fmt.Errorf("ID token issuer '%s' does not match '%s'", iss, d.Issuer)
+	}
+
+	audiences := claimStrings(claims["aud"])
+	if !contains(audiences, p.Config.ClientID) {
+		return errors.New("ID token audience does not include the client ID")
+	}
+	if len(audiences) > 1 {
+		if azp, _ := claims["azp"].(string); azp != p.Config.ClientID {
+			return errors.New("ID token authorized party does not match the client ID")
+		}
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("ID token has no expiration")
+	}
+	if now.Add(-clockSkew).After(time.Unix(int64(exp), 0)) {
+		return errors.New("ID token is expired")
+	}
+
+	if iat, ok := claims["iat"].(float64); ok && time.Unix(int64(iat), 0).After(now.Add(clockSkew)) {
+		return errors.New("ID token was issued in the future")
+	}
+
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return errors.New("ID token nonce does not match")
+	}
+
+	return nil
+}
+
+// getSigningKey finds the key identified by `kid` in the provider's key set. If the key is not found in a cached
+// key set, the key set is fetched again in case the provider has rotated its keys.
+func (p *Provider) getSigningKey(ctx context.Context, d Discovery, kid string) (crypto.PublicKey, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		keys, err := p.getKeySet(ctx, d.JWKSURI)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, k := range keys.Keys {
+			if k.Use != "" && k.Use != "sig" {
+				continue
+			}
+			if kid == "" && len(keys.Keys) > 1 {
+				break
+			}
+			if kid == "" || k.Kid == kid {
+				return k.publicKey()
+			}
+		}
+
+		clearCached(d.JWKSURI)
+	}
+
+	return nil, fmt.Errorf("no signing key found for ID token key ID '%s'", kid)
+}
+
+func (p *Provider) getKeySet(ctx context.Context, url string) (jsonWebKeySet, error) {
+	if ks, ok := getCached(url); ok {
+		return ks.(jsonWebKeySet), nil
+	}
+
+	var ks jsonWebKeySet
+	if err := p.getJSON(ctx, url, &ks); err != nil {
+		return jsonWebKeySet{}, fmt.Errorf("error fetching OIDC key set, %w", err)
+	}
+
+	setCached(url, ks)
+	return ks, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported elliptic curve '%s'", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type '%s'", k.Kty)
+}
+
+func verifySignature(alg string, hash crypto.Hash, key crypto.PublicKey, signed, signature []byte) error {
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch strings.ToUpper(alg[:2]) {
+	case "RS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("ID token signing key is not an RSA key")
+		}
+		if err := rsa.VerifyPKCS1v15(pub, hash, digest, signature); err != nil {
+			return errors.New("invalid ID token signature")
+		}
+		return nil
+
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("ID token signing key is not an EC key")
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid ID token signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("invalid ID token signature")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unsupported ID token signing algorithm '%s'", alg)
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key parameter encoding, %w", err)
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// claimStrings converts a claim that may be either a string or an array of strings into a slice
+func claimStrings(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, s := range v {
+			if str, ok := s.(string); ok {
+				list = append(list, str)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,371 @@
+// Package oidc implements the OpenID Connect authorization code flow, with PKCE, for authenticating users through
+// any standards-compliant OpenID Provider, such as Okta, Keycloak or Auth0.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/markbates/goth"
+	"golang.org/x/oauth2"
+
+	"github.com/silinternational/wecarry-api/auth"
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/log"
+)
+
+const ProviderName = "oidc"
+
+// maxResponseSize limits the size of responses read from the OpenID Provider
+const maxResponseSize = 1 << 20
+
+// requestTimeout limits the time spent on each request to the OpenID Provider
+const requestTimeout = 10 * time.Second
+
+// ClaimMap gives the names of the claims that hold each auth.User field. Claims are read from the ID token and,
+// if missing there, from the userinfo endpoint.
+type ClaimMap struct {
+	UserID    string `json:"UserID"`
+	Email     string `json:"Email"`
+	FirstName string `json:"FirstName"`
+	LastName  string `json:"LastName"`
+	Nickname  string `json:"Nickname"`
+	PhotoURL  string `json:"PhotoURL"`
+}
+
+// Config is the json-encoded configuration stored in `Organization.AuthConfig` or `OrganizationDomain.AuthConfig`
+type Config struct {
+	// Issuer is the OpenID Provider's issuer identifier, e.g. https://example.okta.com
+	Issuer string `json:"Issuer"`
+
+	// DiscoveryURL overrides the location of the discovery document, which is normally found under the issuer
+	DiscoveryURL string `json:"DiscoveryURL"`
+
+	ClientID     string `json:"ClientID"`
+	ClientSecret string `json:"ClientSecret"`
+
+	// Scopes to request in addition to "openid". Defaults to "email" and "profile".
+	Scopes []string `json:"Scopes"`
+
+	// Claims maps claim names to user fields. Any field left empty uses the standard claim name.
+	Claims ClaimMap `json:"Claims"`
+
+	// RequireVerifiedEmail rejects users whose `email_verified` claim is not true. Defaults to true, since users are
+	// linked to existing accounts by email address.
+	RequireVerifiedEmail bool `json:"RequireVerifiedEmail"`
+}
+
+var defaultClaims = ClaimMap{
+	UserID:    "sub",
+	Email:     "email",
+	FirstName: "given_name",
+	LastName:  "family_name",
+	Nickname:  "nickname",
+	PhotoURL:  "picture",
+}
+
+// Provider is the implementation of `auth.Provider` for any OpenID Provider
+type Provider struct {
+	Config      Config
+	CallbackURL string
+	HTTPClient  *http.Client
+}
+
+// New creates a new OIDC provider from the given json configuration. The discovery document is not fetched until
+// it is needed.
+func New(jsonConfig json.RawMessage) (*Provider, error) {
+	p := Provider{CallbackURL: domain.AuthCallbackURL}
+	p.Config.RequireVerifiedEmail = true
+
+	if err := json.Unmarshal(jsonConfig, &p.Config); err != nil {
+		return &Provider{}, errors.New("error unmarshaling oidc provider config json, " + err.Error())
+	}
+
+	if p.Config.Issuer == "" || p.Config.ClientID == "" || p.Config.ClientSecret == "" {
+		return &Provider{}, errors.New("missing required config value for OIDC Auth Provider")
+	}
+
+	if !strings.HasPrefix(p.Config.Issuer, "https://") && !strings.HasPrefix(p.Config.Issuer, "http://localhost") {
+		return &Provider{}, fmt.Errorf("OIDC issuer must use https, got '%s'", p.Config.Issuer)
+	}
+
+	if len(p.Config.Scopes) == 0 {
+		p.Config.Scopes = []string{"email", "profile"}
+	}
+
+	p.Config.Claims = p.Config.Claims.withDefaults()
+
+	return &p, nil
+}
+
+func (m ClaimMap) withDefaults() ClaimMap {
+	fill := func(s *string, def string) {
+		if *s == "" {
+			*s = def
+		}
+	}
+	fill(&m.UserID, defaultClaims.UserID)
+	fill(&m.Email, defaultClaims.Email)
+	fill(&m.FirstName, defaultClaims.FirstName)
+	fill(&m.LastName, defaultClaims.LastName)
+	fill(&m.Nickname, defaultClaims.Nickname)
+	fill(&m.PhotoURL, defaultClaims.PhotoURL)
+	return m
+}
+
+// Client returns an HTTP client to be used in all fetch operations.
+func (p *Provider) Client() *http.Client {
+	return goth.HTTPClientWithFallBack(p.HTTPClient)
+}
+
+func (p *Provider) oauth2Config(d Discovery) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.Config.ClientID,
+		ClientSecret: p.Config.ClientSecret,
+		RedirectURL:  p.CallbackURL,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  d.AuthorizationEndpoint,
+			TokenURL: d.TokenEndpoint,
+		},
+		Scopes: append([]string{"openid"}, p.Config.Scopes...),
+	}
+}
+
+// Logout calls auth.Logout
+func (p *Provider) Logout(c buffalo.Context) auth.Response {
+	resp := auth.Response{}
+	if err := auth.Logout(c.Response(), c.Request()); err != nil {
+		resp.Error = err
+	}
+	return resp
+}
+
+// AuthRequest builds the authorization URL, including the state, nonce and PKCE code challenge, and keeps the
+// values needed to complete the flow in the session
+func (p *Provider) AuthRequest(c buffalo.Context) (string, error) {
+	req := c.Request()
+
+	ctx, cancel := context.WithTimeout(req.Context(), requestTimeout)
+	defer cancel()
+
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	sess := Session{
+		CodeVerifier: randomString(),
+		Nonce:        randomString(),
+	}
+
+	sess.AuthURL = p.oauth2Config(d).AuthCodeURL(auth.SetState(req),
+		oauth2.SetAuthURLParam("nonce", sess.Nonce),
+		oauth2.SetAuthURLParam("code_challenge", codeChallenge(sess.CodeVerifier)),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+
+	if err := auth.StoreInSession(ProviderName, sess.Marshal(), req, c.Response()); err != nil {
+		return "", err
+	}
+
+	return sess.AuthURL, nil
+}
+
+// AuthCallback exchanges the authorization code for tokens, validates the ID token, and maps its claims to an
+// auth.User
+func (p *Provider) AuthCallback(c buffalo.Context) auth.Response {
+	res := c.Response()
+	req := c.Request()
+
+	resp := auth.Response{}
+
+	defer auth.Logout(res, req)
+
+	if msg := auth.CheckSessionStore(); msg != "" {
+		log.WithContext(c).Errorf("got message from OIDC's CheckSessionStore() in AuthCallback ... %s", msg)
+	}
+
+	params := req.URL.Query()
+	if e := params.Get("error"); e != "" {
+		resp.Error = fmt.Errorf("OIDC provider returned error '%s': %s", e, params.Get("error_description"))
+		return resp
+	}
+
+	value, err := auth.GetFromSession(ProviderName, req)
+	if err != nil {
+		resp.Error = err
+		return resp
+	}
+
+	var sess Session
+	if err := json.Unmarshal([]byte(value), &sess); err != nil {
+		resp.Error = err
+		return resp
+	}
+
+	if err := auth.ValidateState(req, &sess); err != nil {
+		resp.Error = err
+		return resp
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), requestTimeout)
+	defer cancel()
+
+	user, err := p.completeAuth(ctx, sess, params.Get("code"))
+	if err != nil {
+		resp.Error = err
+		return resp
+	}
+
+	resp.AuthUser = &user
+	return resp
+}
+
+// completeAuth exchanges the authorization code, verifies the resulting ID token, and returns the user it identifies
+func (p *Provider) completeAuth(ctx context.Context, sess Session, code string) (auth.User, error) {
+	if code == "" {
+		return auth.User{}, errors.New("no authorization code in OIDC callback")
+	}
+
+	d, err := p.discover(ctx)
+	if err != nil {
+		return auth.User{}, err
+	}
+
+	token, err := p.oauth2Config(d).Exchange(context.WithValue(ctx, oauth2.HTTPClient, p.Client()), code,
+		oauth2.SetAuthURLParam("code_verifier", sess.CodeVerifier))
+	if err != nil {
+		return auth.User{}, fmt.Errorf("error exchanging OIDC authorization code, %w", err)
+	}
+
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return auth.User{}, errors.New("no id_token in OIDC token response")
+	}
+
+	claims, err := p.verifyIDToken(ctx, d, rawIDToken, sess.Nonce)
+	if err != nil {
+		return auth.User{}, err
+	}
+
+	if p.missingClaims(claims) && d.UserInfoEndpoint != "" {
+		if err := p.mergeUserInfo(ctx, d, token.AccessToken, claims); err != nil {
+			return auth.User{}, err
+		}
+	}
+
+	return p.mapClaims(claims)
+}
+
+// missingClaims returns true if any of the mapped user claims are absent
+func (p *Provider) missingClaims(claims map[string]interface{}) bool {
+	m := p.Config.Claims
+	for _, name := range []string{m.Email, m.FirstName, m.LastName, m.Nickname, m.PhotoURL} {
+		if _, ok := claims[name]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeUserInfo adds claims from the userinfo endpoint that are not present in the ID token. As required by the
+// spec, the userinfo response is discarded if its subject differs from the ID token's.
+func (p *Provider) mergeUserInfo(ctx context.Context, d Discovery, accessToken string,
+	claims map[string]interface{}) error {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.UserInfoEndpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	var info map[string]interface{}
+	if err := p.doJSON(req, &info); err != nil {
+		return fmt.Errorf("error fetching OIDC userinfo, %w", err)
+	}
+
+	if info["sub"] != claims["sub"] {
+		return errors.New("OIDC userinfo subject does not match the ID token")
+	}
+
+	for k, v := range info {
+		if _, ok := claims[k]; !ok {
+			claims[k] = v
+		}
+	}
+	return nil
+}
+
+// mapClaims builds an auth.User from the claims, using the configured claim names
+func (p *Provider) mapClaims(claims map[string]interface{}) (auth.User, error) {
+	m := p.Config.Claims
+
+	user := auth.User{
+		UserID:    claimString(claims, m.UserID),
+		Email:     claimString(claims, m.Email),
+		FirstName: claimString(claims, m.FirstName),
+		LastName:  claimString(claims, m.LastName),
+		Nickname:  claimString(claims, m.Nickname),
+		PhotoURL:  claimString(claims, m.PhotoURL),
+	}
+
+	if user.UserID == "" {
+		return auth.User{}, fmt.Errorf("OIDC claim '%s' is missing", m.UserID)
+	}
+	if user.Email == "" {
+		return auth.User{}, fmt.Errorf("OIDC claim '%s' is missing", m.Email)
+	}
+	if p.Config.RequireVerifiedEmail {
+		if verified, _ := claims["email_verified"].(bool); !verified {
+			return auth.User{}, errors.New("OIDC email address is not verified")
+		}
+	}
+
+	return user, nil
+}
+
+// claimString returns the value of the named claim as a string. Nested claims can be named using a dotted path,
+// e.g. "profile.first_name".
+func claimString(claims map[string]interface{}, name string) string {
+	var value interface{} = claims
+	for _, part := range strings.Split(name, ".") {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = obj[part]
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	}
+	return ""
+}
+
+// randomString returns a url-safe string suitable for a nonce or PKCE code verifier
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("crypto/rand failed, " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// codeChallenge derives the S256 PKCE code challenge from a code verifier
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,359 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testClientID = "client-id"
+	testNonce    = "the-nonce"
+	testVerifier = "the-code-verifier"
+)
+
+// testIDP is a minimal OpenID Provider for testing
+type testIDP struct {
+	server   *httptest.Server
+	rsaKey   *rsa.PrivateKey
+	ecKey    *ecdsa.PrivateKey
+	idToken  string
+	userInfo map[string]interface{}
+	verifier string
+}
+
+func newTestIDP(t *testing.T) *testIDP {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	idp := &testIDP{rsaKey: rsaKey, ecKey: ecKey}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, Discovery{
+			Issuer:                idp.server.URL,
+			AuthorizationEndpoint: idp.server.URL + "/authorize",
+			TokenEndpoint:         idp.server.URL + "/token",
+			UserInfoEndpoint:      idp.server.URL + "/userinfo",
+			JWKSURI:               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, jsonWebKeySet{Keys: []jsonWebKey{
+			{
+				Kty: "RSA",
+				Kid: "rsa1",
+				Use: "sig",
+				N:   b64(rsaKey.N.Bytes()),
+				E:   b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			{
+				Kty: "EC",
+				Kid: "ec1",
+				Crv: "P-256",
+				X:   b64(ecKey.X.FillBytes(make([]byte, 32))),
+				Y:   b64(ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		idp.verifier = r.PostForm.Get("code_verifier")
+		writeJSON(w, map[string]interface{}{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idp.idToken,
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, idp.userInfo)
+	})
+
+	idp.server = httptest.NewTLSServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *testIDP) provider(t *testing.T, config string) *Provider {
+	if config == "" {
+		config = `{"Issuer":"` + idp.server.URL + `","ClientID":"` + testClientID + `","ClientSecret":"secret"}`
+	}
+	p, err := New([]byte(config))
+	require.NoError(t, err)
+	p.HTTPClient = idp.server.Client()
+	return p
+}
+
+func (idp *testIDP) claims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":            idp.server.URL,
+		"aud":            testClientID,
+		"sub":            "user-123",
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          testNonce,
+		"email":          "user@example.com",
+		"email_verified": true,
+		"given_name":     "Given",
+		"family_name":    "Family",
+		"nickname":       "Nick",
+		"picture":        "https://example.com/photo.jpg",
+	}
+}
+
+func (idp *testIDP) signRSA(t *testing.T, claims map[string]interface{}) string {
+	signed := encodeSegments(t, map[string]string{"alg": "RS256", "kid": "rsa1"}, claims)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, idp.rsaKey, crypto.SHA256, sum[:])
+	require.NoError(t, err)
+	return signed + "." + b64(sig)
+}
+
+func (idp *testIDP) signEC(t *testing.T, claims map[string]interface{}) string {
+	signed := encodeSegments(t, map[string]string{"alg": "ES256", "kid": "ec1"}, claims)
+	sum := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, idp.ecKey, sum[:])
+	require.NoError(t, err)
+	sig := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	return signed + "." + b64(sig)
+}
+
+func encodeSegments(t *testing.T, header map[string]string, claims map[string]interface{}) string {
+	h, err := json.Marshal(header)
+	require.NoError(t, err)
+	c, err := json.Marshal(claims)
+	require.NoError(t, err)
+	return b64(h) + "." + b64(c)
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{
+			name:    "bad json",
+			config:  `{`,
+			wantErr: true,
+		},
+		{
+			name:    "missing client secret",
+			config:  `{"Issuer":"https://idp.example.com","ClientID":"abc"}`,
+			wantErr: true,
+		},
+		{
+			name:    "insecure issuer",
+			config:  `{"Issuer":"http://idp.example.com","ClientID":"abc","ClientSecret":"xyz"}`,
+			wantErr: true,
+		},
+		{
+			name:   "good",
+			config: `{"Issuer":"https://idp.example.com","ClientID":"abc","ClientSecret":"xyz","Claims":{"FirstName":"first"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New([]byte(tt.config))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"email", "profile"}, p.Config.Scopes)
+			assert.Equal(t, "first", p.Config.Claims.FirstName)
+			assert.Equal(t, "family_name", p.Config.Claims.LastName)
+			assert.Equal(t, "sub", p.Config.Claims.UserID)
+			assert.True(t, p.Config.RequireVerifiedEmail, "verified email should be required by default")
+		})
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// unpadded base64url encoding of the SHA-256 hash of the verifier
+	assert.Equal(t, "RtwJGpcTlPZ822FGksV_5mDi87ZpxFmKW4Op4ApXsaM",
+		codeChallenge("dBjftJeZ4CVP-mJ92K7mvKG7a2F9bCHVcGpY6c9mUUk"))
+}
+
+func TestProvider_verifyIDToken(t *testing.T) {
+	idp := newTestIDP(t)
+	p := idp.provider(t, "")
+	ctx := context.Background()
+
+	d, err := p.discover(ctx)
+	require.NoError(t, err)
+
+	withClaim := func(name string, value interface{}) map[string]interface{} {
+		c := idp.claims()
+		if value == nil {
+			delete(c, name)
+		} else {
+			c[name] = value
+		}
+		return c
+	}
+
+	good := idp.signRSA(t, idp.claims())
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	forged := (&testIDP{rsaKey: otherKey}).signRSA(t, idp.claims())
+	unsigned := encodeSegments(t, map[string]string{"alg": "none"}, idp.claims()) + "."
+
+	tests := []struct {
+		name    string
+		token   string
+		nonce   string
+		wantErr string
+	}{
+		{name: "RS256", token: good, nonce: testNonce},
+		{name: "ES256", token: idp.signEC(t, idp.claims()), nonce: testNonce},
+		{
+			name: "multiple audiences with azp",
+			token: idp.signRSA(t, func() map[string]interface{} {
+				c := withClaim("aud", []string{testClientID, "other-client"})
+				c["azp"] = testClientID
+				return c
+			}()),
+			nonce: testNonce,
+		},
+		{name: "not a JWT", token: "abc.def", nonce: testNonce, wantErr: "not a valid JWT"},
+		{name: "alg none", token: unsigned, nonce: testNonce, wantErr: "unsupported"},
+		{name: "forged signature", token: forged, nonce: testNonce, wantErr: "invalid ID token signature"},
+		{name: "wrong nonce", token: good, nonce: "other", wantErr: "nonce"},
+		{
+			name:    "wrong issuer",
+			token:   idp.signRSA(t, withClaim("iss", "https://evil.example.com")),
+			nonce:   testNonce,
+			wantErr: "issuer",
+		},
+		{
+			name:    "wrong audience",
+			token:   idp.signRSA(t, withClaim("aud", "other-client")),
+			nonce:   testNonce,
+			wantErr: "audience",
+		},
+		{
+			name:    "multiple audiences without azp",
+			token:   idp.signRSA(t, withClaim("aud", []string{testClientID, "other-client"})),
+			nonce:   testNonce,
+			wantErr: "authorized party",
+		},
+		{
+			name:    "expired",
+			token:   idp.signRSA(t, withClaim("exp", time.Now().Add(-2*time.Minute).Unix())),
+			nonce:   testNonce,
+			wantErr: "expired",
+		},
+		{
+			name:    "no expiration",
+			token:   idp.signRSA(t, withClaim("exp", nil)),
+			nonce:   testNonce,
+			wantErr: "no expiration",
+		},
+		{
+			name:    "issued in the future",
+			token:   idp.signRSA(t, withClaim("iat", time.Now().Add(time.Hour).Unix())),
+			nonce:   testNonce,
+			wantErr: "future",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := p.verifyIDToken(ctx, d, tt.token, tt.nonce)
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "user-123", claims["sub"])
+		})
+	}
+}
+
+func TestProvider_completeAuth(t *testing.T) {
+	idp := newTestIDP(t)
+	ctx := context.Background()
+	sess := Session{CodeVerifier: testVerifier, Nonce: testNonce}
+
+	// all claims in the ID token
+	p := idp.provider(t, "")
+	idp.idToken = idp.signRSA(t, idp.claims())
+	user, err := p.completeAuth(ctx, sess, "the-code")
+	assert.NoError(t, err)
+	assert.Equal(t, testVerifier, idp.verifier, "code verifier was not sent to token endpoint")
+	assert.Equal(t, "user-123", user.UserID)
+	assert.Equal(t, "user@example.com", user.Email)
+	assert.Equal(t, "Given", user.FirstName)
+	assert.Equal(t, "Family", user.LastName)
+	assert.Equal(t, "Nick", user.Nickname)
+	assert.Equal(t, "https://example.com/photo.jpg", user.PhotoURL)
+
+	// custom claim names, with missing claims found in userinfo
+	p = idp.provider(t, `{"Issuer":"`+idp.server.URL+`","ClientID":"`+testClientID+`","ClientSecret":"secret",
+		"Claims":{"FirstName":"profile.first","Email":"mail"}}`)
+	claims := idp.claims()
+	delete(claims, "email")
+	idp.idToken = idp.signRSA(t, claims)
+	idp.userInfo = map[string]interface{}{
+		"sub":     "user-123",
+		"mail":    "info@example.com",
+		"profile": map[string]interface{}{"first": "Nested"},
+	}
+	user, err = p.completeAuth(ctx, sess, "the-code")
+	assert.NoError(t, err)
+	assert.Equal(t, "info@example.com", user.Email)
+	assert.Equal(t, "Nested", user.FirstName)
+
+	// userinfo for a different subject
+	idp.userInfo["sub"] = "someone-else"
+	_, err = p.completeAuth(ctx, sess, "the-code")
+	assert.Error(t, err)
+
+	// unverified email, which is rejected unless the config allows it
+	p = idp.provider(t, "")
+	claims = idp.claims()
+	delete(claims, "email_verified")
+	idp.idToken = idp.signRSA(t, claims)
+	_, err = p.completeAuth(ctx, sess, "the-code")
+	assert.Error(t, err)
+
+	claims["email_verified"] = false
+	idp.idToken = idp.signRSA(t, claims)
+	_, err = p.completeAuth(ctx, sess, "the-code")
+	assert.Error(t, err)
+
+	p = idp.provider(t, `{"Issuer":"`+idp.server.URL+`","ClientID":"`+testClientID+`","ClientSecret":"secret",
+		"RequireVerifiedEmail":false}`)
+	_, err = p.completeAuth(ctx, sess, "the-code")
+	assert.NoError(t, err)
+
+	// no code
+	_, err = p.completeAuth(ctx, sess, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,39 @@
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/markbates/goth"
+)
+
+// Session stores data during the auth process with an OpenID Provider.
+type Session struct {
+	AuthURL      string
+	CodeVerifier string
+	Nonce        string
+}
+
+// GetAuthURL will return the URL set by calling the `BeginAuth` function on the OIDC provider.
+func (s *Session) GetAuthURL() (string, error) {
+	if s.AuthURL == "" {
+		return "", errors.New(goth.NoAuthUrlErrorMessage)
+	}
+	return s.AuthURL, nil
+}
+
+// Authorize is not used by the OIDC provider, which exchanges the authorization code in AuthCallback so that the
+// ID token can be validated.
+func (s *Session) Authorize(provider goth.Provider, params goth.Params) (string, error) {
+	return "", errors.New("oidc sessions are authorized by the provider's AuthCallback")
+}
+
+// Marshal the session into a string
+func (s *Session) Marshal() string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func (s *Session) String() string {
+	return s.Marshal()
+}
@@ -0,0 +1,281 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/beevik/etree"
+	saml2 "github.com/russellhaering/gosaml2"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+const (
+	paramRelayState   = "RelayState"
+	paramSAMLRequest  = "SAMLRequest"
+	paramSAMLResponse = "SAMLResponse"
+	paramSigAlg       = "SigAlg"
+	paramSignature    = "Signature"
+)
+
+// LogoutRequestIDSessionKey is the session key of the ID of the LogoutRequest sent to the IdP by Logout
+const LogoutRequestIDSessionKey = "SAMLLogoutRequestID"
+
+// redirectSignatureHashes are the query string signature algorithms accepted from the IdP
+var redirectSignatureHashes = map[string]crypto.Hash{
+	dsig.RSASHA1SignatureMethod:   crypto.SHA1,
+	dsig.RSASHA256SignatureMethod: crypto.SHA256,
+	dsig.RSASHA384SignatureMethod: crypto.SHA384,
+	dsig.RSASHA512SignatureMethod: crypto.SHA512,
+}
+
+// SetDefaultSPSingleLogoutURL sets the URL of our single logout service, unless the config provides one
+func (p *Provider) SetDefaultSPSingleLogoutURL(sloURL string) {
+	if p.Config.SPSingleLogoutURL != "" {
+		return
+	}
+	p.Config.SPSingleLogoutURL = sloURL
+	p.SamlProvider.ServiceProviderSLOURL = sloURL
+}
+
+// IsSingleLogoutEnabled returns true if logging out should also end the user's session at the IdP
+func (p *Provider) IsSingleLogoutEnabled() bool {
+	return p.Config.EnableSingleLogout && p.Config.SingleLogoutURL != ""
+}
+
+// logoutRequestURL builds the IdP URL for a LogoutRequest, using the HTTP-Redirect binding, that ends the session
+// of the user identified by nameID. The ID of the LogoutRequest is returned for validating the IdP's LogoutResponse.
+func (p *Provider) logoutRequestURL(nameID string) (string, string, error) {
+	doc, err := p.SamlProvider.BuildLogoutRequestDocumentNoSig(nameID, "")
+	if err != nil {
+		return "", "", fmt.Errorf("error building saml logout request: %w", err)
+	}
+
+	rURL, err := p.redirectURL(paramSAMLRequest, doc, "")
+	return rURL, doc.Root().SelectAttrValue("ID", ""), err
+}
+
+// ValidateLogoutRequest verifies a LogoutRequest sent by the IdP using either the HTTP-Redirect or the HTTP-POST
+// binding. The request must be signed by the IdP.
+func (p *Provider) ValidateLogoutRequest(req *http.Request) (*saml2.LogoutRequest, error) {
+	var encodedRequest string
+	if req.Method == http.MethodGet {
+		if err := p.verifyRedirectSignature(req.URL.RawQuery, paramSAMLRequest); err != nil {
+			return nil, err
+		}
+		encodedRequest = req.URL.Query().Get(paramSAMLRequest)
+	} else {
+		encodedRequest = req.PostFormValue(paramSAMLRequest)
+	}
+
+	if encodedRequest == "" {
+		return nil, errors.New("saml logout request is missing")
+	}
+
+	logoutRequest, err := p.SamlProvider.ValidateEncodedLogoutRequestPOST(encodedRequest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid saml logout request: %w", err)
+	}
+
+	if req.Method != http.MethodGet && !logoutRequest.SignatureValidated {
+		return nil, errors.New("saml logout request is not signed")
+	}
+
+	if logoutRequest.NameID == nil || logoutRequest.NameID.Value == "" {
+		return nil, errors.New("saml logout request has no NameID")
+	}
+
+	return logoutRequest, nil
+}
+
+// ValidateLogoutResponse verifies a LogoutResponse sent by the IdP, using either the HTTP-Redirect or the HTTP-POST
+// binding, in response to the LogoutRequest identified by requestID. The response must be signed by the IdP and
+// report success.
+func (p *Provider) ValidateLogoutResponse(req *http.Request, requestID string) error {
+	if requestID == "" {
+		return errors.New("no saml logout request is awaiting a response")
+	}
+
+	var encodedResponse string
+	if req.Method == http.MethodGet {
+		if err := p.verifyRedirectSignature(req.URL.RawQuery, paramSAMLResponse); err != nil {
+			return err
+		}
+		encodedResponse = req.URL.Query().Get(paramSAMLResponse)
+	} else {
+		encodedResponse = req.PostFormValue(paramSAMLResponse)
+	}
+
+	if encodedResponse == "" {
+		return errors.New("saml logout response is missing")
+	}
+
+	// the issuer, destination and status are checked by the saml library
+	logoutResponse, err := p.SamlProvider.ValidateEncodedLogoutResponsePOST(encodedResponse)
+	if err != nil {
+		return fmt.Errorf("invalid saml logout response: %w", err)
+	}
+
+	if req.Method != http.MethodGet && !logoutResponse.SignatureValidated {
+		return errors.New("saml logout response is not signed")
+	}
+
+	if logoutResponse.InResponseTo != requestID {
+		return fmt.Errorf("saml logout response is for request '%s', expected '%s'",
+			logoutResponse.InResponseTo, requestID)
+	}
+
+	return nil
+}
+
+// LogoutResponseURL builds the IdP URL for a successful LogoutResponse to the given LogoutRequest, using the
+// HTTP-Redirect binding
+func (p *Provider) LogoutResponseURL(logoutRequest *saml2.LogoutRequest, relayState string) (string, error) {
+	doc, err := p.SamlProvider.BuildLogoutResponseDocumentNoSig(saml2.StatusCodeSuccess, logoutRequest.ID)
+	if err != nil {
+		return "", fmt.Errorf("error building saml logout response: %w", err)
+	}
+
+	return p.redirectURL(paramSAMLResponse, doc, relayState)
+}
+
+// redirectURL encodes a SAML message for the HTTP-Redirect binding to the IdP's single logout service. If an SP
+// key is configured, the query string is signed as described in section 3.4.4.1 of saml-bindings-2.0-os.
+func (p *Provider) redirectURL(param string, doc *etree.Document, relayState string) (string, error) {
+	if p.Config.SingleLogoutURL == "" {
+		return "", errors.New("the IdP single logout URL is not configured")
+	}
+
+	xml, err := doc.WriteToBytes()
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err = fw.Write(xml); err != nil {
+		return "", err
+	}
+	if err = fw.Close(); err != nil {
+		return "", err
+	}
+
+	query := param + "=" + url.QueryEscape(base64.StdEncoding.EncodeToString(buf.Bytes()))
+	if relayState != "" {
+		query += "&" + paramRelayState + "=" + url.QueryEscape(relayState)
+	}
+
+	if p.Config.SPPrivateKey != "" {
+		ctx, err := p.signingContext()
+		if err != nil {
+			return "", err
+		}
+		query += "&" + paramSigAlg + "=" + url.QueryEscape(ctx.GetSignatureMethodIdentifier())
+
+		signature, err := ctx.SignString(query)
+		if err != nil {
+			return "", fmt.Errorf("error signing saml redirect: %w", err)
+		}
+		query += "&" + paramSignature + "=" + url.QueryEscape(base64.StdEncoding.EncodeToString(signature))
+	}
+
+	separator := "?"
+	if strings.Contains(p.Config.SingleLogoutURL, "?") {
+		separator = "&"
+	}
+
+	return p.Config.SingleLogoutURL + separator + query, nil
+}
+
+// verifyRedirectSignature checks the signature of a SAML message received with the HTTP-Redirect binding against
+// the IdP certificate. The signature covers the query parameters exactly as they were encoded by the IdP.
+func (p *Provider) verifyRedirectSignature(rawQuery, param string) error {
+	rawValues := map[string]string{}
+	for _, pair := range strings.Split(rawQuery, "&") {
+		key, value, _ := strings.Cut(pair, "=")
+		rawValues[key] = value
+	}
+
+	if rawValues[paramSignature] == "" || rawValues[paramSigAlg] == "" {
+		return errors.New("saml redirect is not signed")
+	}
+
+	sigAlg, err := url.QueryUnescape(rawValues[paramSigAlg])
+	if err != nil {
+		return fmt.Errorf("invalid saml signature algorithm: %w", err)
+	}
+	hash, ok := redirectSignatureHashes[sigAlg]
+	if !ok {
+		return fmt.Errorf("unsupported saml signature algorithm: %s", sigAlg)
+	}
+
+	encodedSignature, err := url.QueryUnescape(rawValues[paramSignature])
+	if err != nil {
+		return fmt.Errorf("invalid saml signature: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return fmt.Errorf("invalid saml signature: %w", err)
+	}
+
+	signed := param + "=" + rawValues[param]
+	if relayState, ok := rawValues[paramRelayState]; ok {
+		signed += "&" + paramRelayState + "=" + relayState
+	}
+	signed += "&" + paramSigAlg + "=" + rawValues[paramSigAlg]
+
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	certs, err := p.SamlProvider.IDPCertificateStore.Certificates()
+	if err != nil {
+		return err
+	}
+	for _, cert := range certs {
+		publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			continue
+		}
+		if rsa.VerifyPKCS1v15(publicKey, hash, digest, signature) == nil {
+			return nil
+		}
+	}
+
+	return errors.New("saml redirect signature is not valid")
+}
+
+// signingContext creates a signing context for the SP key and certificate
+func (p *Provider) signingContext() (*dsig.SigningContext, error) {
+	key, err := getRsaPrivateKey(p.Config.SPPrivateKey, p.Config.SPPublicCert)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := pemToBase64(p.Config.SPPublicCert)
+	if err != nil {
+		return nil, err
+	}
+	certData, err := base64.StdEncoding.DecodeString(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := dsig.NewSigningContext(key, [][]byte{certData})
+	if err != nil {
+		return nil, err
+	}
+	ctx.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+
+	return ctx, nil
+}
@@ -0,0 +1,270 @@
+package saml
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	saml2 "github.com/russellhaering/gosaml2"
+)
+
+const (
+	testIDPEntityID = "https://idp.example.com"
+	testIDPSLOURL   = "https://idp.example.com/slo"
+	testSPEntityID  = "https://sp.example.com"
+	testSPSLOURL    = "https://sp.example.com/auth/saml/org1/slo"
+)
+
+func newTestProvider(t *testing.T, config Config) *Provider {
+	config.IDPPublicCert = ValidPublicCert
+	config.SPPublicCert = ValidPublicCert
+	config.SPPrivateKey = ValidPrivateKey
+	p := &Provider{Config: config}
+	if err := p.initSAMLServiceProvider(); err != nil {
+		t.Fatalf("error creating test provider: %s", err)
+	}
+	return p
+}
+
+// newTestIDP returns a provider that sends messages to the test SP as if it were the test IdP
+func newTestIDP(t *testing.T) *Provider {
+	return newTestProvider(t, Config{
+		SPEntityID:      testIDPEntityID,
+		SingleLogoutURL: testSPSLOURL,
+	})
+}
+
+func newTestSP(t *testing.T) *Provider {
+	p := newTestProvider(t, Config{
+		IDPEntityID:        testIDPEntityID,
+		SPEntityID:         testSPEntityID,
+		SingleLogoutURL:    testIDPSLOURL,
+		EnableSingleLogout: true,
+	})
+	p.SetDefaultSPSingleLogoutURL(testSPSLOURL)
+	return p
+}
+
+func TestProvider_SetDefaultSPSingleLogoutURL(t *testing.T) {
+	p := newTestProvider(t, Config{})
+	p.SetDefaultSPSingleLogoutURL("https://default")
+	if p.SamlProvider.ServiceProviderSLOURL != "https://default" {
+		t.Errorf("default SLO URL not used, got %s", p.SamlProvider.ServiceProviderSLOURL)
+	}
+
+	p = newTestProvider(t, Config{SPSingleLogoutURL: "https://configured"})
+	p.SetDefaultSPSingleLogoutURL("https://default")
+	if p.SamlProvider.ServiceProviderSLOURL != "https://configured" {
+		t.Errorf("configured SLO URL not used, got %s", p.SamlProvider.ServiceProviderSLOURL)
+	}
+}
+
+func TestProvider_logoutRequestURL(t *testing.T) {
+	sp := newTestSP(t)
+
+	rURL, requestID, err := sp.logoutRequestURL("user1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if requestID == "" {
+		t.Error("logout request ID was not returned")
+	}
+
+	if !strings.HasPrefix(rURL, testIDPSLOURL+"?SAMLRequest=") {
+		t.Errorf("logout request URL is not for the IdP SLO service: %s", rURL)
+	}
+
+	u, err := url.Parse(rURL)
+	if err != nil {
+		t.Fatalf("invalid logout request URL: %s", err)
+	}
+	if u.Query().Get(paramSignature) == "" {
+		t.Errorf("logout request URL is not signed: %s", rURL)
+	}
+
+	// the test SP and IdP share a certificate, so the SP can verify its own signature
+	if err := sp.verifyRedirectSignature(u.RawQuery, paramSAMLRequest); err != nil {
+		t.Errorf("logout request signature is not valid: %s", err)
+	}
+}
+
+func TestProvider_ValidateLogoutRequest(t *testing.T) {
+	sp := newTestSP(t)
+	idp := newTestIDP(t)
+
+	signedURL, _, err := idp.logoutRequestURL("user1")
+	if err != nil {
+		t.Fatalf("error building logout request: %s", err)
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{
+			name: "signed",
+			url:  signedURL,
+		},
+		{
+			name:    "unsigned",
+			url:     signedURL[:strings.Index(signedURL, "&"+paramSigAlg)],
+			wantErr: true,
+		},
+		{
+			name:    "bad signature",
+			url:     strings.Replace(signedURL, "&"+paramSignature+"=", "&"+paramSignature+"=AAAA", 1),
+			wantErr: true,
+		},
+		{
+			name:    "missing request",
+			url:     testSPSLOURL,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			logoutRequest, err := sp.ValidateLogoutRequest(req)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if logoutRequest.NameID.Value != "user1" {
+				t.Errorf("wrong NameID, got %s", logoutRequest.NameID.Value)
+			}
+
+			rURL, err := sp.LogoutResponseURL(logoutRequest, "state")
+			if err != nil {
+				t.Fatalf("error building logout response: %s", err)
+			}
+			if !strings.HasPrefix(rURL, testIDPSLOURL+"?SAMLResponse=") || !strings.Contains(rURL, "RelayState=state") {
+				t.Errorf("incorrect logout response URL: %s", rURL)
+			}
+		})
+	}
+}
+
+func TestProvider_ValidateLogoutRequest_unsignedPost(t *testing.T) {
+	sp := newTestSP(t)
+	idp := newTestIDP(t)
+
+	doc, err := idp.SamlProvider.BuildLogoutRequestDocumentNoSig("user1", "")
+	if err != nil {
+		t.Fatalf("error building logout request: %s", err)
+	}
+	body, err := idp.SamlProvider.BuildLogoutBodyPostFromDocument("", doc)
+	if err != nil {
+		t.Fatalf("error building logout request: %s", err)
+	}
+	start := strings.Index(string(body), `name="SAMLRequest" value="`) + len(`name="SAMLRequest" value="`)
+	encoded := string(body[start : start+strings.Index(string(body[start:]), `"`)])
+
+	form := url.Values{paramSAMLRequest: {encoded}}
+	req := httptest.NewRequest(http.MethodPost, testSPSLOURL, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if _, err := sp.ValidateLogoutRequest(req); err == nil {
+		t.Error("expected an error for an unsigned logout request")
+	}
+}
+
+func TestProvider_ValidateLogoutResponse(t *testing.T) {
+	sp := newTestSP(t)
+	idp := newTestIDP(t)
+
+	_, requestID, err := sp.logoutRequestURL("user1")
+	if err != nil {
+		t.Fatalf("error building logout request: %s", err)
+	}
+	signedURL, err := idp.LogoutResponseURL(&saml2.LogoutRequest{ID: requestID}, "")
+	if err != nil {
+		t.Fatalf("error building logout response: %s", err)
+	}
+
+	tests := []struct {
+		name      string
+		url       string
+		requestID string
+		wantErr   bool
+	}{
+		{
+			name:      "signed",
+			url:       signedURL,
+			requestID: requestID,
+		},
+		{
+			name:      "unsigned",
+			url:       signedURL[:strings.Index(signedURL, "&"+paramSigAlg)],
+			requestID: requestID,
+			wantErr:   true,
+		},
+		{
+			name:      "bad signature",
+			url:       strings.Replace(signedURL, "&"+paramSignature+"=", "&"+paramSignature+"=AAAA", 1),
+			requestID: requestID,
+			wantErr:   true,
+		},
+		{
+			name:      "other request",
+			url:       signedURL,
+			requestID: "_other",
+			wantErr:   true,
+		},
+		{
+			name:    "no request sent",
+			url:     signedURL,
+			wantErr: true,
+		},
+		{
+			name:      "missing response",
+			url:       testSPSLOURL,
+			requestID: requestID,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			err := sp.ValidateLogoutResponse(req, tt.requestID)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestProvider_ValidateLogoutResponse_otherIssuer(t *testing.T) {
+	sp := newTestSP(t)
+	other := newTestProvider(t, Config{
+		SPEntityID:      "https://other.example.com",
+		SingleLogoutURL: testSPSLOURL,
+	})
+
+	_, requestID, err := sp.logoutRequestURL("user1")
+	if err != nil {
+		t.Fatalf("error building logout request: %s", err)
+	}
+	rURL, err := other.LogoutResponseURL(&saml2.LogoutRequest{ID: requestID}, "")
+	if err != nil {
+		t.Fatalf("error building logout response: %s", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, rURL, nil)
+	if err := sp.ValidateLogoutResponse(req, requestID); err == nil {
+		t.Error("expected an error for a logout response from another issuer")
+	}
+}
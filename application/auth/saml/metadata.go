@@ -0,0 +1,91 @@
+package saml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/beevik/etree"
+	saml2 "github.com/russellhaering/gosaml2"
+	"github.com/russellhaering/gosaml2/types"
+	dsigtypes "github.com/russellhaering/goxmldsig/types"
+
+	"github.com/silinternational/wecarry-api/domain"
+)
+
+// metadataValidity is how long IdPs may cache our metadata
+const metadataValidity = 7 * 24 * time.Hour
+
+// Metadata returns the SP metadata for configuring the IdP, signed with the SP key
+func (p *Provider) Metadata() ([]byte, error) {
+	if p.Config.SPEntityID == "" {
+		return nil, errors.New("the SP entity ID is not configured")
+	}
+
+	cert, err := pemToBase64(p.Config.SPPublicCert)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SP certificate: %w", err)
+	}
+	keyInfo := dsigtypes.KeyInfo{
+		X509Data: dsigtypes.X509Data{
+			X509Certificates: []dsigtypes.X509Certificate{{Data: cert}},
+		},
+	}
+
+	descriptor := types.SPSSODescriptor{
+		AuthnRequestsSigned:        p.Config.SignRequest,
+		WantAssertionsSigned:       true,
+		ProtocolSupportEnumeration: saml2.SAMLProtocolNamespace,
+		KeyDescriptors: []types.KeyDescriptor{
+			{Use: "signing", KeyInfo: keyInfo},
+			{Use: "encryption", KeyInfo: keyInfo},
+		},
+		AssertionConsumerServices: []types.IndexedEndpoint{{
+			Binding:  saml2.BindingHttpPost,
+			Location: p.Config.AssertionConsumerServiceURL,
+			Index:    1,
+		}},
+	}
+	if p.Config.SPSingleLogoutURL != "" {
+		descriptor.SingleLogoutServices = []types.Endpoint{
+			{Binding: saml2.BindingHttpRedirect, Location: p.Config.SPSingleLogoutURL},
+			{Binding: saml2.BindingHttpPost, Location: p.Config.SPSingleLogoutURL},
+		}
+	}
+
+	metadata, err := xml.Marshal(types.EntityDescriptor{
+		ValidUntil:      time.Now().UTC().Add(metadataValidity).Truncate(time.Second),
+		EntityID:        p.Config.SPEntityID,
+		SPSSODescriptor: &descriptor,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(metadata); err != nil {
+		return nil, err
+	}
+	root := doc.Root()
+	root.CreateAttr("ID", "_"+domain.GetUUID().String())
+
+	ctx, err := p.signingContext()
+	if err != nil {
+		return nil, err
+	}
+	signature, err := ctx.ConstructSignature(root, true)
+	if err != nil {
+		return nil, fmt.Errorf("error signing saml metadata: %w", err)
+	}
+
+	// the metadata schema requires the signature to be the first child of the EntityDescriptor
+	root.InsertChildAt(0, signature)
+
+	signed, err := doc.WriteToBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), signed...), nil
+}
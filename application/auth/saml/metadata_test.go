@@ -0,0 +1,57 @@
+package saml
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+func TestProvider_Metadata(t *testing.T) {
+	sp := newTestSP(t)
+	sp.Config.AssertionConsumerServiceURL = "https://sp.example.com/auth/callback"
+
+	metadata, err := sp.Metadata()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, want := range []string{
+		`entityID="` + testSPEntityID + `"`,
+		`Location="https://sp.example.com/auth/callback"`,
+		`Location="` + testSPSLOURL + `"`,
+		`use="signing"`,
+	} {
+		if !strings.Contains(string(metadata), want) {
+			t.Errorf("metadata does not contain %s:\n%s", want, metadata)
+		}
+	}
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(metadata); err != nil {
+		t.Fatalf("metadata is not valid xml: %s", err)
+	}
+
+	certData, err := pemToBase64(ValidPublicCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := base64.StdEncoding.DecodeString(certData)
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: []*x509.Certificate{cert}})
+	if _, err := ctx.Validate(doc.Root()); err != nil {
+		t.Errorf("metadata signature is not valid: %s", err)
+	}
+
+	sp.Config.SPEntityID = ""
+	if _, err := sp.Metadata(); err == nil {
+		t.Error("expected an error without an SP entity ID")
+	}
+}
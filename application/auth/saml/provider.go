@@ -29,6 +29,8 @@ type Config struct {
 	SPEntityID                  string            `json:"SPEntityID"`
 	SingleSignOnURL             string            `json:"SingleSignOnURL"`
 	SingleLogoutURL             string            `json:"SingleLogoutURL"`
+	SPSingleLogoutURL           string            `json:"SPSingleLogoutURL"`
+	EnableSingleLogout          bool              `json:"EnableSingleLogout"`
 	AudienceURI                 string            `json:"AudienceURI"`
 	AssertionConsumerServiceURL string            `json:"AssertionConsumerServiceURL"`
 	IDPPublicCert               string            `json:"IDPPublicCert"`
@@ -78,8 +80,10 @@ func (p *Provider) initSAMLServiceProvider() error {
 
 	p.SamlProvider = &saml2.SAMLServiceProvider{
 		IdentityProviderSSOURL:         p.Config.SingleSignOnURL,
+		IdentityProviderSLOURL:         p.Config.SingleLogoutURL,
 		IdentityProviderIssuer:         p.Config.IDPEntityID,
 		AssertionConsumerServiceURL:    p.Config.AssertionConsumerServiceURL,
+		ServiceProviderSLOURL:          p.Config.SPSingleLogoutURL,
 		ServiceProviderIssuer:          p.Config.SPEntityID,
 		SignAuthnRequests:              p.Config.SignRequest,
 		SignAuthnRequestsAlgorithm:     "",
@@ -126,12 +130,22 @@ func (p *Provider) AuthCallback(c buffalo.Context) auth.Response {
 	return resp
 }
 
+// Logout ends the local session. If single logout is enabled, it also sends a LogoutRequest to the IdP for the
+// user whose NameID is in the context, and keeps the ID of the LogoutRequest in the session.
 func (p *Provider) Logout(c buffalo.Context) auth.Response {
 	resp := auth.Response{}
 	err := auth.Logout(c.Response(), c.Request())
 	if err != nil {
 		resp.Error = err
 	}
+
+	nameID, _ := c.Value(domain.ContextKeyAuthID).(string)
+	if p.IsSingleLogoutEnabled() && nameID != "" {
+		rURL, requestID, err := p.logoutRequestURL(nameID)
+		c.Session().Set(LogoutRequestIDSessionKey, requestID)
+		return auth.Response{RedirectURL: rURL, Error: err}
+	}
+
 	rURL := fmt.Sprintf("%s?ReturnTo=%s", p.Config.SingleLogoutURL, domain.MarketingSiteURL)
 	return auth.Response{RedirectURL: rURL}
 }
@@ -2,6 +2,7 @@ package aws
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -21,6 +22,7 @@ import (
 	"github.com/silinternational/wecarry-api/assets"
 	"github.com/silinternational/wecarry-api/domain"
 	"github.com/silinternational/wecarry-api/log"
+	"github.com/silinternational/wecarry-api/tracing"
 )
 
 type ObjectUrl struct {
@@ -70,7 +72,7 @@ func createS3Service(config awsConfig) (*s3.S3, error) {
 	return svc, err
 }
 
-func getObjectURL(config awsConfig, svc *s3.S3, key string) (ObjectUrl, error) {
+func getObjectURL(ctx context.Context, config awsConfig, svc *s3.S3, key string) (ObjectUrl, error) {
 	var objectUrl ObjectUrl
 
 	if !config.getPresignedUrl {
@@ -83,6 +85,7 @@ func getObjectURL(config awsConfig, svc *s3.S3, key string) (ObjectUrl, error) {
 		Bucket: aws.String(config.awsS3Bucket),
 		Key:    aws.String(key),
 	})
+	req.SetContext(ctx)
 
 	if newUrl, err := req.Presign(urlLifespan); err == nil {
 		objectUrl.Url = newUrl
@@ -96,7 +99,10 @@ func getObjectURL(config awsConfig, svc *s3.S3, key string) (ObjectUrl, error) {
 }
 
 // StoreFile saves content in an AWS S3 bucket or compatible storage, depending on environment configuration.
-func StoreFile(key, contentType string, content []byte) (ObjectUrl, error) {
+func StoreFile(ctx context.Context, key, contentType string, content []byte) (objectUrl ObjectUrl, err error) {
+	ctx, span := tracing.Start(ctx, "S3 PutObject")
+	defer func() { tracing.End(span, err) }()
+
 	config := getS3ConfigFromEnv()
 
 	svc, err := createS3Service(config)
@@ -108,7 +114,7 @@ func StoreFile(key, contentType string, content []byte) (ObjectUrl, error) {
 	if !config.getPresignedUrl {
 		acl = "public-read"
 	}
-	if _, err := svc.PutObject(&s3.PutObjectInput{
+	if _, err := svc.PutObjectWithContext(ctx, &s3.PutObjectInput{
 		Bucket:      aws.String(config.awsS3Bucket),
 		Key:         aws.String(key),
 		ContentType: aws.String(contentType),
@@ -118,17 +124,15 @@ func StoreFile(key, contentType string, content []byte) (ObjectUrl, error) {
 		return ObjectUrl{}, err
 	}
 
-	objectUrl, err := getObjectURL(config, svc, key)
-	if err != nil {
-		return ObjectUrl{}, err
-	}
-
-	return objectUrl, nil
+	return getObjectURL(ctx, config, svc, key)
 }
 
 // GetFileURL retrieves a URL from which a stored object can be loaded. The URL should not require external
 // credentials to access. It may reference a file with public_read access or it may be a pre-signed URL.
-func GetFileURL(key string) (ObjectUrl, error) {
+func GetFileURL(ctx context.Context, key string) (objectUrl ObjectUrl, err error) {
+	ctx, span := tracing.Start(ctx, "S3 GetObjectURL")
+	defer func() { tracing.End(span, err) }()
+
 	config := getS3ConfigFromEnv()
 
 	svc, err := createS3Service(config)
@@ -136,11 +140,14 @@ func GetFileURL(key string) (ObjectUrl, error) {
 		return ObjectUrl{}, err
 	}
 
-	return getObjectURL(config, svc, key)
+	return getObjectURL(ctx, config, svc, key)
 }
 
 // RemoveFile removes a file from the configured AWS S3 bucket.
-func RemoveFile(key string) error {
+func RemoveFile(ctx context.Context, key string) (err error) {
+	ctx, span := tracing.Start(ctx, "S3 DeleteObject")
+	defer func() { tracing.End(span, err) }()
+
 	config := getS3ConfigFromEnv()
 
 	svc, err := createS3Service(config)
@@ -148,7 +155,7 @@ func RemoveFile(key string) error {
 		return err
 	}
 
-	if _, err := svc.DeleteObject(&s3.DeleteObjectInput{
+	if _, err := svc.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(config.awsS3Bucket),
 		Key:    aws.String(key),
 	}); err != nil {
@@ -158,6 +165,19 @@ func RemoveFile(key string) error {
 	return nil
 }
 
+// CheckBucket verifies that the configured S3 bucket exists and is accessible with the configured credentials
+func CheckBucket(ctx context.Context) error {
+	config := getS3ConfigFromEnv()
+
+	svc, err := createS3Service(config)
+	if err != nil {
+		return err
+	}
+
+	_, err = svc.HeadBucketWithContext(ctx, &s3.HeadBucketInput{Bucket: aws.String(config.awsS3Bucket)})
+	return err
+}
+
 // CreateS3Bucket creates an S3 bucket with a name defined by an environment variable. If the bucket already
 // exists, it will not return an error.
 func CreateS3Bucket() error {
@@ -187,19 +207,22 @@ func CreateS3Bucket() error {
 	return nil
 }
 
-// SendEmail sends a message using SES
-func SendEmail(to, from, subject, body string) error {
+// SendEmail sends a message using SES. If replyTo is not empty, it is used as the Reply-To address.
+func SendEmail(ctx context.Context, to, from, replyTo, subject, body string) (err error) {
+	ctx, span := tracing.Start(ctx, "SES SendRawEmail")
+	defer func() { tracing.End(span, err) }()
+
 	svc, err := createSESService(getSESConfigFromEnv())
 	if err != nil {
 		return fmt.Errorf("SendEmail failed creating SES service, %s", err)
 	}
 
 	input := &ses.SendRawEmailInput{
-		RawMessage: &ses.RawMessage{Data: rawEmail(to, from, subject, body)},
+		RawMessage: &ses.RawMessage{Data: rawEmail(to, from, replyTo, subject, body)},
 		Source:     aws.String(from),
 	}
 
-	result, err := svc.SendRawEmail(input)
+	result, err := svc.SendRawEmailWithContext(ctx, input)
 	if err != nil {
 		return fmt.Errorf("SendEmail failed using SES, %s", err)
 	}
@@ -221,6 +244,7 @@ func SendEmail(to, from, subject, body string) error {
 //
 //	 	From: from@example.com
 //		To: to@example.com
+//		Reply-To: reply@example.com
 //		Subject: subject text
 //		Content-Type: multipart/alternative; boundary="boundary_alternative"
 //
@@ -241,7 +265,7 @@ func SendEmail(to, from, subject, body string) error {
 //		Content-ID: <logo>
 //		--boundary_related--
 //		--boundary_alternative--
-func rawEmail(to, from, subject, body string) []byte {
+func rawEmail(to, from, replyTo, subject, body string) []byte {
 	tbody, err := html2text.FromString(body)
 	if err != nil {
 		log.Errorf("error converting html email to plain text ... %s", err.Error())
@@ -252,6 +276,9 @@ func rawEmail(to, from, subject, body string) []byte {
 
 	b.WriteString("From: " + from + "\n")
 	b.WriteString("To: " + to + "\n")
+	if replyTo != "" {
+		b.WriteString("Reply-To: " + replyTo + "\n")
+	}
 	b.WriteString("Subject: " + subject + "\n")
 	b.WriteString("MIME-Version: 1.0\n")
 
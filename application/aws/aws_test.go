@@ -2,6 +2,7 @@ package aws
 
 import (
 	"bytes"
+	"context"
 	"os"
 	"testing"
 
@@ -26,8 +27,10 @@ func Test_TestSuite(t *testing.T) {
 func (ts *TestSuite) TestSendEmail() {
 	ts.T().Skip("only for use in local environment if configured with SES credentials")
 	err := SendEmail(
+		context.Background(),
 		"me@example.com",
 		domain.Env.EmailFromAddress,
+		"",
 		"test subject",
 		`<h4>body</h4><img src="cid:logo"><p>End of body</p>`)
 	ts.NoError(err)
@@ -42,10 +45,12 @@ func (ts *TestSuite) TestRawEmail() {
 	raw := rawEmail(
 		"to@example.com",
 		domain.Env.EmailFromAddress,
+		"reply@example.com",
 		"test subject",
 		`<h4>body</h4><img src="cid:logo"><p>End of body</p>`)
 
 	ts.Greater(len(raw), 1000)
+	ts.Contains(string(raw), "Reply-To: reply@example.com\n")
 
 	ts.Equal("", buf.String(), "Got an unexpected error log entry")
 }
@@ -1,24 +1,46 @@
+// Package cache holds the lists of open requests shown to users. The requests are cached by scope: one scope for
+// public requests, and one for the non-public requests visible to the members of each organization, which includes
+// the TRUSTED requests of the organizations it trusts.
+//
+// Each scope has a version number, and the requests of a scope are cached under a key that includes the version.
+// Cached entries are never modified. Instead, a change to a request increments the version of every scope that may
+// hold it, so that the next read rebuilds the entry from the database. Since the version is incremented only after
+// the change is committed, a rebuild that races with a change can only write to an outdated version.
 package cache
 
 import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"sort"
 	"time"
 
 	rediscache "github.com/go-redis/cache/v8"
 	"github.com/go-redis/redis/v8"
+	"github.com/gofrs/uuid"
 
 	"github.com/silinternational/wecarry-api/api"
 	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/metrics"
 	"github.com/silinternational/wecarry-api/models"
+	"github.com/silinternational/wecarry-api/tracing"
+)
+
+const (
+	cacheName      = "requests"
+	keyPrefix      = cacheName + ":"
+	publicScope    = "public"
+	orgScopePrefix = "org:"
+	entryTTL       = time.Hour
 )
 
 var (
-	RequestsCache           *rediscache.Cache
-	PrivateRequestKeyPrefix string = "requests-orgname-affiliated_"
-	PublicRequestKey        string = "requests-allusers"
+	RequestsCache *rediscache.Cache
+
+	// versions holds the version number of each scope. It is read directly from Redis, since a local copy could be
+	// out of date.
+	versions redis.Cmdable
 )
 
 func init() {
@@ -27,9 +49,12 @@ func init() {
 			domain.Env.RedisInstanceName: domain.Env.RedisInstanceHostPort,
 		},
 	})
+	ring.AddHook(tracing.RedisHook{})
+	versions = ring
 
 	RequestsCache = rediscache.New(&rediscache.Options{
-		Redis:      ring,
+		Redis: ring,
+		// Entries are immutable, so they can be held locally for as long as they are in use
 		LocalCache: rediscache.NewTinyLFU(1000, time.Minute),
 		// use json in lieu of msgpack for encoding/decoding
 		Marshal:   json.Marshal,
@@ -37,239 +62,183 @@ func init() {
 	})
 }
 
-// Wrapper for cache write
-func cacheWrite(key string, data interface{}) error {
-	return RequestsCache.Set(&rediscache.Item{
-		Ctx:   nil,
-		Key:   key,
-		Value: data,
-		TTL:   time.Hour,
-	})
+// Ping verifies that Redis is reachable
+func Ping(ctx context.Context) error {
+	return versions.Ping(ctx).Err()
 }
 
-// Wrapper for cache read
-func cacheRead(ctx context.Context, organization string, requestsMap interface{}) error {
-	return RequestsCache.Get(ctx, organization, requestsMap)
+// orgScope is the scope of the non-public requests visible to the members of the organization
+func orgScope(orgID uuid.UUID) string {
+	return orgScopePrefix + orgID.String()
 }
 
-// GetVisibleRequests gets all requests visible to user in specified organization from cache, fetching data from database if needed
-func GetVisibleRequests(ctx context.Context, orgs []models.Organization) ([]api.RequestAbridged, error) {
-	// get and de-duplicate private requests for all organizations a user belongs to
-	privateRequestsMap := make(map[string]api.RequestAbridged)
-	for _, org := range orgs {
-		_, privateRequestsMapPartial, err := getOrCreateCacheEntryPrivate(ctx, org)
-		if err != nil {
-			return nil, errors.New("error in cache get visible requests: " + err.Error())
-		}
-		// re-mapping requests by request ID is necessary to de-duplicate requests visible to
-		// trusted organizations (so that users belonging to multiple orgs don't see duplicated requests)
-		for requestID, request := range privateRequestsMapPartial {
-			privateRequestsMap[requestID] = request
-		}
-	}
+func versionKey(scope string) string {
+	return keyPrefix + scope + ":version"
+}
 
-	// get public requests
-	_, publicRequestsMap, err := getOrCreateCacheEntryPublic(ctx)
-	if err != nil {
-		return nil, errors.New("error in cache get visible requests: " + err.Error())
-	}
+func entryKey(scope string, version int64) string {
+	return fmt.Sprintf("%s%s:v%d", keyPrefix, scope, version)
+}
 
-	// compose them to get list of all requests visible to the current user
-	visibleRequestsList := []api.RequestAbridged{}
-	for _, privateRequest := range privateRequestsMap {
-		visibleRequestsList = append(visibleRequestsList, privateRequest)
-	}
-	for _, publicRequest := range publicRequestsMap {
-		visibleRequestsList = append(visibleRequestsList, publicRequest)
+// currentVersion returns the version number of the scope. A scope that was never invalidated is at version zero.
+func currentVersion(ctx context.Context, scope string) (int64, error) {
+	version, err := versions.Get(ctx, versionKey(scope)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
 	}
-
-	sort.Slice(visibleRequestsList, func(i, j int) bool {
-		return visibleRequestsList[i].CreatedAt.After(visibleRequestsList[j].CreatedAt)
-	})
-
-	return visibleRequestsList, nil
+	return version, err
 }
 
-// CacheRebuildOnNewRequest rebuilds cache after a request is created
-// We cache non-finished public requests publicly with cache key "requests-allusers"
-// We cache non-finished private caches privately with cache key "requests-orgname-affiliated_"
-// We remove a finished request from its respective cache
-func CacheRebuildOnNewRequest(ctx context.Context, request models.Request) error {
-	organization := request.Organization
-	// add to public cache only
-	if request.Visibility == models.RequestVisibilityAll {
-		newEntryCreated, requestsMap, err := getOrCreateCacheEntryPublic(ctx)
-		if err != nil {
-			return errors.New("error in cache rebuild (creation): " + err.Error())
-		}
-		if !newEntryCreated {
-			if err := updateRequestPublicCache(ctx, request, requestsMap); err != nil {
-				return errors.New("error in cache rebuild (creation): " + err.Error())
-			}
-		}
-	} else {
-		// add to private cache only
-		newEntryCreated, requestsMap, err := getOrCreateCacheEntryPrivate(ctx, organization)
-		if err != nil {
-			return errors.New("error in cache rebuild: " + err.Error())
-		}
-		if !newEntryCreated {
-			if err := updateRequestPrivateCache(ctx, request, requestsMap); err != nil {
-				return errors.New("error in cache rebuild: " + err.Error())
-			}
-		}
+// invalidate increments the version number of each of the scopes
+func invalidate(ctx context.Context, scopes ...string) error {
+	pipe := versions.Pipeline()
+	for _, scope := range scopes {
+		pipe.Incr(ctx, versionKey(scope))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("error invalidating cached requests, %w", err)
 	}
 	return nil
 }
 
-// CacheRebuildOnChangedRequest rebuilds cache after a request is updated
-// We cache non-finished public requests publicly with cache key "requests-allusers"
-// We cache non-finished private caches privately with cache key "requests-orgname-affiliated_"
-// We remove a finished request from its respective cache
-func CacheRebuildOnChangedRequest(ctx context.Context, request models.Request) error {
-	organization := request.Organization
-	// update private cache
-	newEntryCreated, requestsMap, err := getOrCreateCacheEntryPrivate(ctx, organization)
+// getScope gets the requests of the scope from the cache, keyed by request ID. If the current version is not
+// cached, it is loaded from the database by `find`.
+func getScope(ctx context.Context, scope string, find func(*models.Requests) error) (map[string]api.RequestAbridged, error) {
+	version, err := currentVersion(ctx, scope)
 	if err != nil {
-		return errors.New("error in getOrCreateCacheEntryPrivate: " + err.Error())
-	}
-	if !newEntryCreated {
-		if err := updateRequestPrivateCache(ctx, request, requestsMap); err != nil {
-			return errors.New("error in updateRequestPrivateCache: " + err.Error())
-		}
+		return nil, fmt.Errorf("error reading version of cached requests %s, %w", scope, err)
 	}
 
-	// update public cache
-	newEntryCreated, requestsMap, err = getOrCreateCacheEntryPublic(ctx)
+	hit := true
+	requestsMap := map[string]api.RequestAbridged{}
+	err = RequestsCache.Once(&rediscache.Item{
+		Ctx:   ctx,
+		Key:   entryKey(scope, version),
+		Value: &requestsMap,
+		TTL:   entryTTL,
+		Do: func(*rediscache.Item) (interface{}, error) {
+			hit = false
+			requests := models.Requests{}
+			if err := find(&requests); err != nil {
+				return nil, err
+			}
+			requestsList, err := models.ConvertRequestsAbridged(ctx, requests)
+			if err != nil {
+				return nil, err
+			}
+			m := make(map[string]api.RequestAbridged, len(requestsList))
+			for _, requestEntry := range requestsList {
+				m[requestEntry.ID.String()] = requestEntry
+			}
+			return m, nil
+		},
+	})
 	if err != nil {
-		return errors.New("error in getOrCreateCacheEntryPublic: " + err.Error())
-	}
-	if !newEntryCreated {
-		if err := updateRequestPublicCache(ctx, request, requestsMap); err != nil {
-			return errors.New("error in updateRequestPublicCache: " + err.Error())
-		}
+		return nil, fmt.Errorf("error getting cached requests %s, %w", scope, err)
 	}
-
-	return nil
+	metrics.ObserveCacheLookup(cacheName, hit)
+	return requestsMap, nil
 }
 
-// Gets cache entry for organization, or creates one if none exists
-// Returns true if new cache entry was created
-// TODO: explore refactoring getOrCreateCacheEntryPrivate and getOrCreateCacheEntryPublic to use a common helper function
-func getOrCreateCacheEntryPrivate(ctx context.Context, organization models.Organization) (bool, map[string]api.RequestAbridged, error) {
-	requestsMap := map[string]api.RequestAbridged{}
-	// if a cache value does not exist, we create it
-	if err := cacheRead(ctx, PrivateRequestKeyPrefix+organization.Name, requestsMap); err != nil {
-		tx := models.Tx(ctx)
-
-		// RequestFilterParams is currently empty because the UI is not using it
-		filter := models.RequestFilterParams{}
+// GetVisibleRequests gets all requests visible to members of the given organizations from cache, fetching data from
+// database if needed
+func GetVisibleRequests(ctx context.Context, orgs []models.Organization) ([]api.RequestAbridged, error) {
+	tx := models.Tx(ctx)
 
-		requests := models.Requests{}
-		if err := requests.FindByOrganization(tx, organization, filter); err != nil {
-			return false, nil, err
-		}
+	// RequestFilterParams is currently empty because the UI is not using it
+	filter := models.RequestFilterParams{}
 
-		requestsList, err := models.ConvertRequestsAbridged(ctx, requests)
+	// re-mapping requests by request ID is necessary to de-duplicate requests visible to
+	// trusted organizations (so that users belonging to multiple orgs don't see duplicated requests)
+	visibleRequestsMap := map[string]api.RequestAbridged{}
+	for _, org := range orgs {
+		org := org
+		requestsMap, err := getScope(ctx, orgScope(org.UUID), func(r *models.Requests) error {
+			return r.FindByOrganization(tx, org, filter)
+		})
 		if err != nil {
-			return false, nil, err
+			return nil, errors.New("error in cache get visible requests: " + err.Error())
 		}
-		requestsMap := make(map[string]api.RequestAbridged)
-		for _, requestEntry := range requestsList {
-			requestsMap[requestEntry.ID.String()] = requestEntry
+		for requestID, request := range requestsMap {
+			visibleRequestsMap[requestID] = request
 		}
-
-		return true, requestsMap, cacheWrite(PrivateRequestKeyPrefix+organization.Name, requestsMap)
 	}
 
-	return false, requestsMap, nil
-}
+	publicRequestsMap, err := getScope(ctx, publicScope, func(r *models.Requests) error {
+		return r.FindPublic(tx, filter)
+	})
+	if err != nil {
+		return nil, errors.New("error in cache get visible requests: " + err.Error())
+	}
+	for requestID, request := range publicRequestsMap {
+		visibleRequestsMap[requestID] = request
+	}
 
-// Gets cache entry for organization, or creates one if none exists.
-// Returns true if new cache entry was created
-func getOrCreateCacheEntryPublic(ctx context.Context) (bool, map[string]api.RequestAbridged, error) {
-	requestsMap := map[string]api.RequestAbridged{}
+	visibleRequestsList := make([]api.RequestAbridged, 0, len(visibleRequestsMap))
+	for _, request := range visibleRequestsMap {
+		visibleRequestsList = append(visibleRequestsList, request)
+	}
 
-	// if a cache value does not exist, we create it
-	if err := cacheRead(ctx, PublicRequestKey, requestsMap); err != nil {
-		tx := models.Tx(ctx)
+	sort.Slice(visibleRequestsList, func(i, j int) bool {
+		return visibleRequestsList[i].CreatedAt.After(visibleRequestsList[j].CreatedAt)
+	})
 
-		// RequestFilterParams is currently empty because the UI is not using it
-		filter := models.RequestFilterParams{}
+	return visibleRequestsList, nil
+}
 
-		requests := models.Requests{}
-		if err := requests.FindPublic(tx, filter); err != nil {
-			return false, nil, err
-		}
-		requestsList, err := models.ConvertRequestsAbridged(ctx, requests)
-		if err != nil {
-			return false, nil, err
-		}
-		requestsMap := make(map[string]api.RequestAbridged)
-		for _, requestEntry := range requestsList {
-			requestsMap[requestEntry.ID.String()] = requestEntry
-		}
+// InvalidateNewRequest invalidates the cached requests that should include a new request: the public requests if it
+// is public, or else the requests of its organization and of every organization that trusts it
+func InvalidateNewRequest(ctx context.Context, request models.Request) error {
+	if request.IsPublic() {
+		return invalidate(ctx, publicScope)
+	}
 
-		return true, requestsMap, cacheWrite(PublicRequestKey, requestsMap)
+	scopes, err := privateScopes(ctx, request)
+	if err != nil {
+		return err
 	}
-	return false, requestsMap, nil
+	return invalidate(ctx, scopes...)
 }
 
-func updateRequestPrivateCache(ctx context.Context, request models.Request, requestsMap map[string]api.RequestAbridged) error {
-	organization := request.Organization
-	_, cachedPrivately := requestsMap[request.UUID.String()]
-	// was cached privately, but request has been finished. Just need to remove from private cache.
-	if cachedPrivately && request.IsFinished() {
-		delete(requestsMap, request.UUID.String())
-		return cacheWrite(PrivateRequestKeyPrefix+organization.Name, requestsMap)
-	}
-	// was cached privately, but request visibility has changed to public.
-	// Need to remove from private and add to public cache.
-	if cachedPrivately && request.IsPublic() {
-		delete(requestsMap, request.UUID.String())
-		if err := cacheWrite(PrivateRequestKeyPrefix+organization.Name, requestsMap); err != nil {
-			return err
-		}
+// InvalidateChangedRequest invalidates the cached requests that may include a changed request. Its previous
+// visibility is not known, so the public requests, the requests of its organization, and those of every organization
+// that trusts its organization are all invalidated.
+func InvalidateChangedRequest(ctx context.Context, request models.Request) error {
+	scopes, err := privateScopes(ctx, request)
+	if err != nil {
+		return err
 	}
+	return invalidate(ctx, append(scopes, publicScope)...)
+}
 
-	// should be cached privately. Need to update or create an entry in the private cache.
-	if request.IsPrivate() {
-		requestAbridged, err := models.ConvertRequestAbridged(ctx, request)
-		if err != nil {
-			return err
-		}
-		requestsMap[request.UUID.String()] = requestAbridged
-		if err := cacheWrite(PrivateRequestKeyPrefix+organization.Name, requestsMap); err != nil {
-			return err
-		}
+// InvalidateOrganizations invalidates the cached requests of the organizations, e.g. after a change of trust between
+// them
+func InvalidateOrganizations(ctx context.Context, orgs ...models.Organization) error {
+	scopes := make([]string, len(orgs))
+	for i := range orgs {
+		scopes[i] = orgScope(orgs[i].UUID)
 	}
-	return nil
+	return invalidate(ctx, scopes...)
 }
 
-func updateRequestPublicCache(ctx context.Context, request models.Request, requestsMap map[string]api.RequestAbridged) error {
-	_, cachedPublicly := requestsMap[request.UUID.String()]
-	// was cached publicly, but request has been finished. Just need to remove from public cache.
-	if cachedPublicly && request.IsFinished() {
-		delete(requestsMap, request.UUID.String())
-		return cacheWrite(PublicRequestKey, requestsMap)
+// privateScopes returns the scopes that may include the request if it is not public: that of its organization and
+// those of the organizations that trust its organization
+func privateScopes(ctx context.Context, request models.Request) ([]string, error) {
+	tx := models.Tx(ctx)
+
+	var org models.Organization
+	if err := tx.Find(&org, request.OrganizationID); err != nil {
+		return nil, fmt.Errorf("error finding organization of request %s, %w", request.UUID, err)
 	}
-	// was cached publicly, but request visibility has changed to private.
-	// Need to remove from public cache.
-	if cachedPublicly && request.IsPrivate() {
-		delete(requestsMap, request.UUID.String())
-		if err := cacheWrite(PublicRequestKey, requestsMap); err != nil {
-			return err
-		}
+
+	trusting, err := org.TrustingOrganizations(tx)
+	if err != nil {
+		return nil, fmt.Errorf("error finding organizations that trust %s, %w", org.UUID, err)
 	}
 
-	// should be cached publicly, need to update or create an entry in the public cache.
-	if request.IsPublic() {
-		requestAbridged, err := models.ConvertRequestAbridged(ctx, request)
-		if err != nil {
-			return err
-		}
-		requestsMap[request.UUID.String()] = requestAbridged
-		return cacheWrite(PublicRequestKey, requestsMap)
+	scopes := []string{orgScope(org.UUID)}
+	for _, o := range trusting {
+		scopes = append(scopes, orgScope(o.UUID))
 	}
-	return nil
+	return scopes, nil
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net/http"
@@ -14,6 +15,7 @@ import (
 	"github.com/silinternational/wecarry-api/actions"
 	"github.com/silinternational/wecarry-api/domain"
 	"github.com/silinternational/wecarry-api/log"
+	"github.com/silinternational/wecarry-api/tracing"
 )
 
 // main is the starting point for your Buffalo application.
@@ -29,6 +31,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		log.Errorf(err.Error())
+		os.Exit(1)
+	}
 	app := actions.App()
 
 	fmt.Printf("Go version: %s\n", runtime.Version())
@@ -36,7 +43,11 @@ func main() {
 	fmt.Printf("Buffalo build info: %s\n", buffalo.Build())
 	fmt.Printf("Commit hash: %s\n", domain.Commit)
 
-	if err := app.Serve(srv); err != nil {
+	err = app.Serve(srv)
+	if err := shutdownTracing(context.Background()); err != nil {
+		log.Errorf("error flushing traces, %s", err)
+	}
+	if err != nil {
 		if err.Error() != "context canceled" {
 			panic(err)
 		}
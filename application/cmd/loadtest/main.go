@@ -0,0 +1,114 @@
+// Command loadtest replays a scenario of API calls by many users against a running API, and reports the latency
+// percentiles and error rate of each kind of call. The users' access tokens are those of generated data (see
+// `buffalo task db:generate`) or are read from a file.
+//
+//	go run ./cmd/loadtest -scenario mixed -concurrency 20 -duration 2m
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/silinternational/wecarry-api/loadtest"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var (
+		baseURL     = flag.String("url", "http://localhost:3000", "base URL of the API")
+		scenario    = flag.String("scenario", "mixed", "built-in scenario ("+strings.Join(loadtest.Scenarios(), ", ")+") or path of a scenario file")
+		tokenFormat = flag.String("token", "seed1_user%d", "format of the access tokens of the generated users, with %d for the user number")
+		users       = flag.Int("users", 50, "number of generated users whose tokens are used")
+		tokenFile   = flag.String("token-file", "", "file of access tokens, one per line, used instead of generated users")
+		concurrency = flag.Int("concurrency", 10, "number of virtual users")
+		duration    = flag.Duration("duration", time.Minute, "length of the test")
+		requests    = flag.Int("requests", 0, "stop after this many requests, if not zero")
+		thinkTime   = flag.Duration("think", 0, "pause of each virtual user between requests")
+		seed        = flag.Int64("seed", 1, "seed of the random choice of steps and values")
+		asJSON      = flag.Bool("json", false, "write the report as JSON")
+		maxErrors   = flag.Float64("max-error-rate", -1, "exit with an error if the error rate of any route exceeds this fraction")
+	)
+	flag.Parse()
+
+	s, err := loadtest.LoadScenario(*scenario)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := readTokens(*tokenFile, *tokenFormat, *users)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report, err := loadtest.Run(ctx, s, loadtest.Config{
+		BaseURL:     *baseURL,
+		Tokens:      tokens,
+		Concurrency: *concurrency,
+		Duration:    *duration,
+		Requests:    *requests,
+		ThinkTime:   *thinkTime,
+		Seed:        *seed,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *maxErrors >= 0 {
+		for _, rt := range report.Routes {
+			if rt.ErrorRate > *maxErrors {
+				return fmt.Errorf("error rate of '%s' is %.3f, more than %.3f", rt.Name, rt.ErrorRate, *maxErrors)
+			}
+		}
+	}
+	return nil
+}
+
+// readTokens reads the access tokens from a file, if given, or else makes the tokens of the generated users
+func readTokens(file, format string, users int) ([]string, error) {
+	if file == "" {
+		tokens := make([]string, users)
+		for i := range tokens {
+			tokens[i] = fmt.Sprintf(format, i)
+		}
+		return tokens, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var tokens []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if t := strings.TrimSpace(scanner.Text()); t != "" {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens, scanner.Err()
+}
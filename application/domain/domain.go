@@ -34,8 +34,14 @@ const (
 	DateFormat                  = "2006-01-02"
 	MaxFileSize                 = 1024 * 1024 * 10       // 10 Megabytes
 	AccessTokenLifetimeSeconds  = 60*60*24*13 + 60*60*12 // 13 days, 12 hours
+	AccessTokenTouchInterval    = 1 * time.Minute
 	DateTimeFormat              = "2006-01-02 15:04:05"
 	NewMessageNotificationDelay = 1 * time.Minute
+	MessageEditWindow           = 15 * time.Minute
+	ThreadMessagesPageSize      = 50
+	ThreadMessagesMaxPageSize   = 200
+	MaxReportCommentLength      = 4096
+	MaxBioLength                = 500
 	DefaultProximityDistanceKm  = 100
 	DurationDay                 = time.Duration(time.Hour * 24)
 	DurationWeek                = time.Duration(DurationDay * 7)
@@ -48,6 +54,8 @@ const (
 // Event Kinds
 const (
 	EventApiUserCreated                    = "api:user:created"
+	EventApiUserDeleted                    = "api:user:deleted"
+	EventApiUserMarketingUpdated           = "api:user:marketing:updated"
 	EventApiAuthUserLoggedIn               = "api:auth:user:loggedin"
 	EventApiMessageCreated                 = "api:message:created"
 	EventApiRequestStatusUpdated           = "api:request:status:updated"
@@ -57,13 +65,19 @@ const (
 	EventApiPotentialProviderRejected      = "api:potentialprovider:rejected"
 	EventApiPotentialProviderSelfDestroyed = "api:potentialprovider:selfdestroyed"
 	EventApiMeetingInviteCreated           = "api:meetinginvite:created"
+	EventApiOrganizationTrustChanged       = "api:organization:trust:changed"
 )
 
 // Event and Job argument names
 const (
 	ArgId        = "id"
+	ArgAttempt   = "attempt"
+	ArgEmail     = "email"
 	ArgEventData = "eventData"
 	ArgMessageID = "message_id"
+
+	// ArgTraceContext holds the trace context of the request that raised an event or submitted a job
+	ArgTraceContext = "trace_context"
 )
 
 // Notification Message Template Names -- the values correspond to the template file names
@@ -89,9 +103,12 @@ const (
 	MessageTemplateRequestReceived                 = "request_received"
 	MessageTemplateRequestNotReceivedAfterAll      = "request_not_received_after_all"
 	MessageTemplateRequestPastNeededBefore         = "request_past_needed_before"
+	MessageTemplateRequestNeededBeforeReminder     = "request_needed_before_reminder"
+	MessageTemplateRequestExpired                  = "request_expired"
 	MessageTemplatePotentialProviderCreated        = "request_potentialprovider_created"
 	MessageTemplatePotentialProviderRejected       = "request_potentialprovider_rejected"
 	MessageTemplatePotentialProviderSelfDestroyed  = "request_potentialprovider_self_destroyed"
+	MessageTemplatePotentialProviderWithdrawn      = "request_potentialprovider_withdrawn"
 )
 
 // User preferences
@@ -108,6 +125,13 @@ const (
 	UserPreferenceKeyWeightUnit    = "weight_unit"
 	UserPreferenceWeightUnitPounds = "pounds"
 	UserPreferenceWeightUnitKGs    = "kilograms"
+
+	// UserPreferenceKeyBioVisibility and UserPreferenceKeyLocationVisibility control whether the bio and home
+	// location are shown on the user's public profile
+	UserPreferenceKeyBioVisibility      = "bio_visibility"
+	UserPreferenceKeyLocationVisibility = "location_visibility"
+	UserPreferenceVisibilityVisible     = "visible"
+	UserPreferenceVisibilityHidden      = "hidden"
 )
 
 // UI URL Paths
@@ -119,9 +143,13 @@ const (
 
 // Context keys
 const (
-	ContextKeyCurrentUser = "current_user"
-	ContextKeyExtras      = "extras"
-	ContextKeyTx          = "tx"
+	ContextKeyAuthID                     = "auth_id"
+	ContextKeyCurrentAccessToken         = "current_access_token"
+	ContextKeyCurrentPersonalAccessToken = "current_personal_access_token"
+	ContextKeyCurrentUser                = "current_user"
+	ContextKeyExtras                     = "extras"
+	ContextKeySCIMOrganization           = "scim_organization"
+	ContextKeyTx                         = "tx"
 )
 
 // Error extras (fields)
@@ -146,6 +174,7 @@ var AllowedFileUploadTypes = []string{
 
 var Env struct {
 	AccessTokenLifetimeSeconds int
+	AccessTokenMaxLifeSeconds  int
 	ServiceIntegrationToken    string
 	ApiBaseURL                 string
 	AppName                    string
@@ -159,6 +188,8 @@ var Env struct {
 	DisableTLS                 bool
 	EmailService               string
 	EmailFromAddress           string
+	EmailInboundSecret         string
+	EmailReplyDomain           string
 	FacebookKey                string
 	FacebookSecret             string
 	GoEnv                      string
@@ -171,6 +202,7 @@ var Env struct {
 	LogLevel                   string
 	MaxFileDelete              int
 	MaxLocationDelete          int
+	MaxSessionsPerUser         int
 	MailChimpAPIBaseURL        string
 	MailChimpAPIKey            string
 	MailChimpListID            string
@@ -178,8 +210,17 @@ var Env struct {
 	MicrosoftKey               string
 	MicrosoftSecret            string
 	MobileService              string
+	OtelExporterEndpoint       string
+	OutboxPollMilliseconds     int
 	PlaygroundPort             string
+	RateLimitEnabled           bool
+	RateLimitIP                string
+	RateLimitMeetingInvites    string
+	RateLimitRoutes            string
+	RateLimitUser              string
 	RedisInstanceName          string
+	RequestExpiryGraceDays     int
+	RequestReminderDays        int
 	RedisInstanceHostPort      string
 	SendGridAPIKey             string
 	ServerPort                 int
@@ -212,6 +253,7 @@ func init() {
 // readEnv loads environment data into `Env`
 func readEnv() {
 	Env.AccessTokenLifetimeSeconds = envToInt("ACCESS_TOKEN_LIFETIME_SECONDS", AccessTokenLifetimeSeconds)
+	Env.AccessTokenMaxLifeSeconds = envToInt("ACCESS_TOKEN_MAX_LIFE_SECONDS", 0)
 	Env.ApiBaseURL = envy.Get("HOST", "")
 	Env.AppName = envy.Get("APP_NAME", "WeCarry")
 	Env.AuthCallbackURL = envy.Get("AUTH_CALLBACK_URL", "")
@@ -224,6 +266,8 @@ func readEnv() {
 	Env.DisableTLS, _ = strconv.ParseBool(envy.Get("DISABLE_TLS", "false"))
 	Env.EmailService = envy.Get("EMAIL_SERVICE", "sendgrid")
 	Env.EmailFromAddress = envy.Get("EMAIL_FROM_ADDRESS", "no_reply@example.com")
+	Env.EmailInboundSecret = envy.Get("EMAIL_INBOUND_SECRET", "")
+	Env.EmailReplyDomain = envy.Get("EMAIL_REPLY_DOMAIN", "")
 	Env.FacebookKey = envy.Get("FACEBOOK_KEY", "")
 	Env.FacebookSecret = envy.Get("FACEBOOK_SECRET", "")
 	Env.GoEnv = envy.Get("GO_ENV", "development")
@@ -236,6 +280,7 @@ func readEnv() {
 	Env.LogLevel = envy.Get("LOG_LEVEL", "warning")
 	Env.MaxFileDelete = envToInt("MAX_FILE_DELETE", 10)
 	Env.MaxLocationDelete = envToInt("MAX_LOCATION_DELETE", 10)
+	Env.MaxSessionsPerUser = envToInt("MAX_SESSIONS_PER_USER", 0)
 	Env.MailChimpAPIBaseURL = envy.Get("MAILCHIMP_API_BASE_URL", "https://us4.api.mailchimp.com/3.0")
 	Env.MailChimpAPIKey = envy.Get("MAILCHIMP_API_KEY", "")
 	Env.MailChimpListID = envy.Get("MAILCHIMP_LIST_ID", "")
@@ -243,8 +288,18 @@ func readEnv() {
 	Env.MicrosoftKey = envy.Get("MICROSOFT_KEY", "")
 	Env.MicrosoftSecret = envy.Get("MICROSOFT_SECRET", "")
 	Env.MobileService = envy.Get("MOBILE_SERVICE", "dummy")
+	Env.OtelExporterEndpoint = envy.Get("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	Env.OutboxPollMilliseconds = envToInt("OUTBOX_POLL_MILLISECONDS", 1000)
 	Env.PlaygroundPort = envy.Get("PORT", "3000")
+	Env.RateLimitEnabled, _ = strconv.ParseBool(envy.Get("RATE_LIMIT_ENABLED", "true"))
+	Env.RateLimitIP = envy.Get("RATE_LIMIT_IP", "300/m")
+	Env.RateLimitMeetingInvites = envy.Get("RATE_LIMIT_MEETING_INVITES", "200/d")
+	Env.RateLimitRoutes = envy.Get("RATE_LIMIT_ROUTES", "POST /auth/login=10/m,POST /auth/invite=10/m,"+
+		"POST /messages=30/m,POST /upload=30/m,POST /requests=20/h")
+	Env.RateLimitUser = envy.Get("RATE_LIMIT_USER", "600/m")
 	Env.RedisInstanceName = envy.Get("REDIS_INSTANCE_NAME", "redis")
+	Env.RequestExpiryGraceDays = envToInt("REQUEST_EXPIRY_GRACE_DAYS", 7)
+	Env.RequestReminderDays = envToInt("REQUEST_REMINDER_DAYS", 2)
 	Env.RedisInstanceHostPort = envy.Get("REDIS_INSTANCE_HOST_PORT", "redis:6379")
 	Env.SendGridAPIKey = envy.Get("SENDGRID_API_KEY", "")
 	Env.ServerPort, _ = strconv.Atoi(envy.Get("PORT", "3000"))
@@ -378,6 +433,15 @@ func IsLanguageAllowed(lang string) bool {
 	return false
 }
 
+func IsVisibilityAllowed(visibility string) bool {
+	switch visibility {
+	case UserPreferenceVisibilityVisible, UserPreferenceVisibilityHidden:
+		return true
+	}
+
+	return false
+}
+
 func IsWeightUnitAllowed(unit string) bool {
 	switch unit {
 	case UserPreferenceWeightUnitKGs, UserPreferenceWeightUnitPounds:
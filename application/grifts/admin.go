@@ -0,0 +1,520 @@
+package grifts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gobuffalo/grift/grift"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/job"
+	"github.com/silinternational/wecarry-api/models"
+)
+
+// errDryRun rolls back the transaction of a task run with the dry-run flag
+var errDryRun = errors.New("dry run")
+
+// dryRunFlag is given in the task arguments to report the changes a task would make, without making them
+const dryRunFlag = "dry-run"
+
+// adminArgs are the arguments of an admin task, given as key=value pairs, e.g.
+// `buffalo task admin:user:promote user=jane@example.org role=ADMIN dry-run`
+type adminArgs struct {
+	values map[string]string
+	dryRun bool
+}
+
+// parseAdminArgs reads key=value pairs and the dry-run flag. Keys may be prefixed with dashes, as with command flags.
+func parseAdminArgs(args []string) (adminArgs, error) {
+	a := adminArgs{values: map[string]string{}}
+	for _, arg := range args {
+		arg = strings.TrimLeft(arg, "-")
+		if arg == dryRunFlag {
+			a.dryRun = true
+			continue
+		}
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			return a, fmt.Errorf("invalid argument '%s', must be of the form key=value or %s", arg, dryRunFlag)
+		}
+		a.values[key] = strings.TrimSpace(value)
+	}
+	return a, nil
+}
+
+// require returns the value of each of the given keys, or an error naming the missing keys
+func (a adminArgs) require(keys ...string) ([]string, error) {
+	values := make([]string, len(keys))
+	var missing []string
+	for i, key := range keys {
+		values[i] = a.values[key]
+		if values[i] == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required argument(s): %s", strings.Join(missing, ", "))
+	}
+	return values, nil
+}
+
+// get returns the value of the key, or the default if the key is not given
+func (a adminArgs) get(key, def string) string {
+	if v, ok := a.values[key]; ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// jobArgs converts the arguments, other than the job name, to job arguments. Whole numbers are converted to int,
+// since the job handlers expect IDs to be of that type.
+func (a adminArgs) jobArgs() map[string]interface{} {
+	args := map[string]interface{}{}
+	for k, v := range a.values {
+		if k == "name" {
+			continue
+		}
+		if n, err := strconv.Atoi(v); err == nil {
+			args[k] = n
+		} else {
+			args[k] = v
+		}
+	}
+	return args
+}
+
+// runAdminTask parses the arguments and runs the task in a transaction. The transaction is rolled back if the task
+// fails or if the dry-run flag is given.
+func runAdminTask(c *grift.Context, task func(tx *pop.Connection, a adminArgs) error) error {
+	a, err := parseAdminArgs(c.Args)
+	if err != nil {
+		return err
+	}
+
+	err = models.DB.Transaction(func(tx *pop.Connection) error {
+		if err := task(tx, a); err != nil {
+			return err
+		}
+		if a.dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		fmt.Println("dry run, no changes were made")
+		return nil
+	}
+	return err
+}
+
+// findUser finds a user by UUID or email address
+func findUser(tx *pop.Connection, ref string) (models.User, error) {
+	var user models.User
+	var err error
+	if _, uuidErr := uuid.FromString(ref); uuidErr == nil {
+		err = user.FindByUUID(tx, ref)
+	} else {
+		err = user.FindByEmail(tx, ref)
+	}
+	if err != nil {
+		return user, fmt.Errorf("user '%s' not found, %w", ref, err)
+	}
+	return user, nil
+}
+
+// findOrganization finds an organization by UUID or by one of its domains
+func findOrganization(tx *pop.Connection, ref string) (models.Organization, error) {
+	var org models.Organization
+	var err error
+	if _, uuidErr := uuid.FromString(ref); uuidErr == nil {
+		err = org.FindByUUID(tx, ref)
+	} else {
+		err = org.FindByDomain(tx, ref)
+	}
+	if err != nil {
+		return org, fmt.Errorf("organization '%s' not found, %w", ref, err)
+	}
+	return org, nil
+}
+
+var _ = grift.Namespace("admin", func() {
+	grift.Desc("org:create", "Creates an organization. "+
+		"Args: name= auth_type= [auth_config=] [url=] [domain=] [domain_auth_type=] [domain_auth_config=] [dry-run]")
+	_ = grift.Add("org:create", func(c *grift.Context) error {
+		return runAdminTask(c, func(tx *pop.Connection, a adminArgs) error {
+			v, err := a.require("name", "auth_type")
+			if err != nil {
+				return err
+			}
+
+			org := models.Organization{
+				Name:       v[0],
+				AuthType:   models.AuthType(strings.ToUpper(v[1])),
+				AuthConfig: a.get("auth_config", "{}"),
+				UUID:       domain.GetUUID(),
+			}
+			if url := a.get("url", ""); url != "" {
+				org.Url = nulls.NewString(url)
+			}
+			if !org.AuthType.IsValid() || org.AuthType == models.AuthTypeDefault {
+				return fmt.Errorf("invalid auth_type '%s'", v[1])
+			}
+			if err := org.Save(tx); err != nil {
+				return fmt.Errorf("error creating organization, %w", err)
+			}
+			fmt.Printf("created organization '%s' (%s)\n", org.Name, org.UUID)
+
+			domainName := a.get("domain", "")
+			if domainName == "" {
+				return nil
+			}
+			authType := models.AuthType(strings.ToUpper(a.get("domain_auth_type", models.AuthTypeDefault.String())))
+			if !authType.IsValid() {
+				return fmt.Errorf("invalid domain_auth_type '%s'", authType)
+			}
+			if err := org.AddDomain(tx, domainName, authType, a.get("domain_auth_config", "")); err != nil {
+				return fmt.Errorf("error adding domain to organization, %w", err)
+			}
+			fmt.Printf("added domain '%s' with auth type %s\n", domainName, authType)
+			return nil
+		})
+	})
+
+	grift.Desc("user:add-org", "Adds a user to an organization, or changes the user's role in it. "+
+		"Args: user=<email|uuid> org=<uuid|domain> [role=user|admin] [auth_id=] [auth_email=] [dry-run]")
+	_ = grift.Add("user:add-org", func(c *grift.Context) error {
+		return runAdminTask(c, func(tx *pop.Connection, a adminArgs) error {
+			v, err := a.require("user", "org")
+			if err != nil {
+				return err
+			}
+			user, err := findUser(tx, v[0])
+			if err != nil {
+				return err
+			}
+			org, err := findOrganization(tx, v[1])
+			if err != nil {
+				return err
+			}
+
+			role := strings.ToLower(a.get("role", models.UserOrganizationRoleUser))
+			if role != models.UserOrganizationRoleUser && role != models.UserOrganizationRoleAdmin {
+				return fmt.Errorf("invalid role '%s'", role)
+			}
+
+			userOrg, err := user.FindUserOrganization(tx, org)
+			if domain.IsOtherThanNoRows(err) {
+				return err
+			}
+			if userOrg.ID != 0 {
+				userOrg.Role = role
+				if err := userOrg.Update(tx); err != nil {
+					return fmt.Errorf("error updating role, %w", err)
+				}
+				fmt.Printf("changed role of %s in '%s' to %s\n", user.Email, org.Name, role)
+				return nil
+			}
+
+			userOrg = models.UserOrganization{
+				OrganizationID: org.ID,
+				UserID:         user.ID,
+				Role:           role,
+				AuthID:         a.get("auth_id", user.Email),
+				AuthEmail:      a.get("auth_email", user.Email),
+			}
+			if err := userOrg.Create(tx); err != nil {
+				return fmt.Errorf("error adding user to organization, %w", err)
+			}
+			fmt.Printf("added %s to '%s' as %s\n", user.Email, org.Name, role)
+			return nil
+		})
+	})
+
+	grift.Desc("user:remove-org", "Removes a user from an organization and revokes the user's sessions in it. "+
+		"Args: user=<email|uuid> org=<uuid|domain> [dry-run]")
+	_ = grift.Add("user:remove-org", func(c *grift.Context) error {
+		return runAdminTask(c, func(tx *pop.Connection, a adminArgs) error {
+			v, err := a.require("user", "org")
+			if err != nil {
+				return err
+			}
+			user, err := findUser(tx, v[0])
+			if err != nil {
+				return err
+			}
+			org, err := findOrganization(tx, v[1])
+			if err != nil {
+				return err
+			}
+
+			userOrg, err := user.FindUserOrganization(tx, org)
+			if err != nil {
+				return fmt.Errorf("%s is not a member of '%s', %w", user.Email, org.Name, err)
+			}
+
+			var tokens models.UserAccessTokens
+			if err := tokens.DeleteByUserOrganization(tx, userOrg); err != nil {
+				return fmt.Errorf("error revoking sessions, %w", err)
+			}
+			if err := tx.Destroy(&userOrg); err != nil {
+				return fmt.Errorf("error removing user from organization, %w", err)
+			}
+			fmt.Printf("removed %s from '%s'\n", user.Email, org.Name)
+			return nil
+		})
+	})
+
+	grift.Desc("user:promote", "Sets a user's admin role. "+
+		"Args: user=<email|uuid> role=SUPERADMIN|SALESADMIN|ADMIN|USER [dry-run]")
+	_ = grift.Add("user:promote", func(c *grift.Context) error {
+		return runAdminTask(c, func(tx *pop.Connection, a adminArgs) error {
+			v, err := a.require("user", "role")
+			if err != nil {
+				return err
+			}
+			user, err := findUser(tx, v[0])
+			if err != nil {
+				return err
+			}
+
+			role := models.UserAdminRole(strings.ToUpper(v[1]))
+			if !role.IsValid() {
+				return fmt.Errorf("invalid role '%s'", v[1])
+			}
+
+			previous := user.AdminRole
+			user.AdminRole = role
+			if err := tx.UpdateColumns(&user, "admin_role", "updated_at"); err != nil {
+				return fmt.Errorf("error updating admin role, %w", err)
+			}
+			fmt.Printf("changed admin role of %s from %s to %s\n", user.Email, previous, role)
+			return nil
+		})
+	})
+
+	grift.Desc("trust:create", "Creates a trust between two organizations. "+
+		"Args: org=<uuid|domain> trusted=<uuid|domain> [dry-run]")
+	_ = grift.Add("trust:create", func(c *grift.Context) error {
+		return runAdminTask(c, func(tx *pop.Connection, a adminArgs) error {
+			primary, secondary, err := findTrustOrganizations(tx, a)
+			if err != nil {
+				return err
+			}
+			if err := primary.CreateTrust(tx, secondary.UUID.String()); err != nil {
+				return err
+			}
+			fmt.Printf("created trust between '%s' and '%s'\n", primary.Name, secondary.Name)
+			return nil
+		})
+	})
+
+	grift.Desc("trust:remove", "Removes the trust between two organizations. "+
+		"Args: org=<uuid|domain> trusted=<uuid|domain> [dry-run]")
+	_ = grift.Add("trust:remove", func(c *grift.Context) error {
+		return runAdminTask(c, func(tx *pop.Connection, a adminArgs) error {
+			primary, secondary, err := findTrustOrganizations(tx, a)
+			if err != nil {
+				return err
+			}
+			if err := primary.RemoveTrust(tx, secondary.UUID.String()); err != nil {
+				return err
+			}
+			fmt.Printf("removed trust between '%s' and '%s'\n", primary.Name, secondary.Name)
+			return nil
+		})
+	})
+
+	grift.Desc("user:revoke-tokens", "Revokes all of a user's sessions and personal access tokens. "+
+		"Args: user=<email|uuid> [dry-run]")
+	_ = grift.Add("user:revoke-tokens", func(c *grift.Context) error {
+		return runAdminTask(c, func(tx *pop.Connection, a adminArgs) error {
+			v, err := a.require("user")
+			if err != nil {
+				return err
+			}
+			user, err := findUser(tx, v[0])
+			if err != nil {
+				return err
+			}
+
+			var sessions models.UserAccessTokens
+			if err := sessions.FindByUser(tx, user); err != nil {
+				return err
+			}
+			var pats models.PersonalAccessTokens
+			if err := pats.FindByUser(tx, user); err != nil {
+				return err
+			}
+
+			if err := sessions.DeleteByUser(tx, user, 0); err != nil {
+				return fmt.Errorf("error revoking sessions, %w", err)
+			}
+			if err := pats.DeleteByUser(tx, user); err != nil {
+				return fmt.Errorf("error revoking personal access tokens, %w", err)
+			}
+			fmt.Printf("revoked %d session(s) and %d personal access token(s) of %s\n",
+				len(sessions), len(pats), user.Email)
+			return nil
+		})
+	})
+
+	grift.Desc("invite:resend", "Sends a meeting invite again. "+
+		"Args: meeting=<uuid> email= [dry-run]")
+	_ = grift.Add("invite:resend", func(c *grift.Context) error {
+		return runAdminTask(c, func(tx *pop.Connection, a adminArgs) error {
+			v, err := a.require("meeting", "email")
+			if err != nil {
+				return err
+			}
+
+			var meeting models.Meeting
+			if err := meeting.FindByUUID(tx, v[0]); err != nil {
+				return fmt.Errorf("meeting '%s' not found, %w", v[0], err)
+			}
+			var invite models.MeetingInvite
+			if err := invite.FindByMeetingIDAndEmail(tx, meeting.ID, v[1]); err != nil {
+				return fmt.Errorf("no invite to '%s' for %s, %w", meeting.Name, v[1], err)
+			}
+
+			if err := invite.Resend(tx); err != nil {
+				return err
+			}
+			fmt.Printf("queued invite to '%s' for %s\n", meeting.Name, invite.Email)
+			return nil
+		})
+	})
+
+	grift.Desc("job:run", "Runs a job synchronously. "+
+		"Args: name=<"+strings.Join(job.Handlers(), "|")+"> [id=] [message_id=] [dry-run]")
+	_ = grift.Add("job:run", func(c *grift.Context) error {
+		a, err := parseAdminArgs(c.Args)
+		if err != nil {
+			return err
+		}
+		v, err := a.require("name")
+		if err != nil {
+			return err
+		}
+
+		args := a.jobArgs()
+		if a.dryRun {
+			fmt.Printf("would run job %s with args %v\n", v[0], args)
+			fmt.Println("dry run, no changes were made")
+			return nil
+		}
+
+		if err := job.Run(context.Background(), v[0], args); err != nil {
+			return fmt.Errorf("job %s failed, %w", v[0], err)
+		}
+		fmt.Printf("job %s completed\n", v[0])
+		return nil
+	})
+
+	grift.Desc("request:show", "Prints a request's full state and status history. Args: request=<uuid>")
+	_ = grift.Add("request:show", func(c *grift.Context) error {
+		a, err := parseAdminArgs(c.Args)
+		if err != nil {
+			return err
+		}
+		v, err := a.require("request")
+		if err != nil {
+			return err
+		}
+		return printRequest(models.DB, v[0])
+	})
+})
+
+// findTrustOrganizations finds the two organizations of a trust task
+func findTrustOrganizations(tx *pop.Connection, a adminArgs) (models.Organization, models.Organization, error) {
+	v, err := a.require("org", "trusted")
+	if err != nil {
+		return models.Organization{}, models.Organization{}, err
+	}
+	primary, err := findOrganization(tx, v[0])
+	if err != nil {
+		return primary, models.Organization{}, err
+	}
+	secondary, err := findOrganization(tx, v[1])
+	return primary, secondary, err
+}
+
+// printRequest prints the request, its related records, and its status history
+func printRequest(tx *pop.Connection, id string) error {
+	var request models.Request
+	if err := request.FindByUUID(tx, id); err != nil {
+		return fmt.Errorf("request '%s' not found, %w", id, err)
+	}
+	if err := tx.Load(&request, "CreatedBy", "Organization", "Provider", "Destination", "Origin", "Meeting"); err != nil {
+		return fmt.Errorf("error loading request details, %w", err)
+	}
+
+	fields := map[string]interface{}{
+		"uuid":         request.UUID,
+		"title":        request.Title,
+		"status":       request.Status,
+		"visibility":   request.Visibility,
+		"organization": request.Organization.Name,
+		"created_by":   request.CreatedBy.Email,
+		"created_at":   request.CreatedAt,
+		"updated_at":   request.UpdatedAt,
+		"destination":  request.Destination.Description,
+	}
+	if request.NeededBefore.Valid {
+		fields["needed_before"] = request.NeededBefore.Time
+	}
+	if request.ProviderID.Valid {
+		fields["provider"] = request.Provider.Email
+	}
+	if request.OriginID.Valid {
+		fields["origin"] = request.Origin.Description
+	}
+	if request.MeetingID.Valid {
+		fields["meeting"] = request.Meeting.Name
+	}
+	printFields(fields)
+
+	var providers models.PotentialProviders
+	if err := tx.Eager("User").Where("request_id = ?", request.ID).All(&providers); err != nil {
+		return fmt.Errorf("error loading potential providers, %w", err)
+	}
+	fmt.Printf("\npotential providers (%d):\n", len(providers))
+	for _, p := range providers {
+		fmt.Printf("  %s  %s\n", p.CreatedAt.Format(timeFormat), p.User.Email)
+	}
+
+	var histories models.RequestHistories
+	if err := histories.FindByRequest(tx, request); err != nil {
+		return err
+	}
+	fmt.Printf("\nhistory (%d):\n", len(histories))
+	for _, h := range histories {
+		provider := "-"
+		if h.ProviderID.Valid {
+			provider = strconv.Itoa(h.ProviderID.Int)
+		}
+		fmt.Printf("  %s  %-10s receiver=%s provider_id=%s\n",
+			h.CreatedAt.Format(timeFormat), h.Status, h.Receiver.Email, provider)
+	}
+	return nil
+}
+
+const timeFormat = "2006-01-02 15:04:05 MST"
+
+// printFields prints the fields in alphabetical order
+func printFields(fields map[string]interface{}) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Printf("%-14s %v\n", k+":", fields[k])
+	}
+}
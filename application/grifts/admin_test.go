@@ -0,0 +1,49 @@
+package grifts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// TestSuite establishes a test suite for grift tests
+type TestSuite struct {
+	suite.Suite
+}
+
+// Test_TestSuite runs the test suite
+func Test_TestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+func (ts *TestSuite) TestParseAdminArgs() {
+	a, err := parseAdminArgs([]string{"user=jane@example.org", "--role=ADMIN", "--dry-run", "note=a=b"})
+	ts.NoError(err)
+	ts.True(a.dryRun)
+	ts.Equal("jane@example.org", a.values["user"])
+	ts.Equal("ADMIN", a.values["role"])
+	ts.Equal("a=b", a.values["note"], "only the first = separates the key")
+
+	v, err := a.require("user", "role")
+	ts.NoError(err)
+	ts.Equal([]string{"jane@example.org", "ADMIN"}, v)
+
+	_, err = a.require("user", "org", "name")
+	ts.EqualError(err, "missing required argument(s): org, name")
+
+	ts.Equal("user", a.get("missing", "user"))
+	ts.Equal("ADMIN", a.get("role", "USER"))
+
+	_, err = parseAdminArgs([]string{"jane@example.org"})
+	ts.Error(err, "expected an error for an argument without a key")
+
+	a, err = parseAdminArgs(nil)
+	ts.NoError(err)
+	ts.False(a.dryRun)
+}
+
+func (ts *TestSuite) TestJobArgs() {
+	a, err := parseAdminArgs([]string{"name=marketing_sync", "id=42", "email=x@example.org"})
+	ts.NoError(err)
+	ts.Equal(map[string]interface{}{"id": 42, "email": "x@example.org"}, a.jobArgs())
+}
@@ -2,14 +2,17 @@ package grifts
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/gobuffalo/grift/grift"
 	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
 
 	"github.com/silinternational/wecarry-api/domain"
 	"github.com/silinternational/wecarry-api/models"
+	"github.com/silinternational/wecarry-api/seed"
 )
 
 var _ = grift.Namespace("db", func() {
@@ -612,4 +615,84 @@ var _ = grift.Namespace("db", func() {
 
 		return nil
 	})
+
+	grift.Desc("generate", "Generates a realistic data set for load tests and demonstrations. "+
+		"Args: [seed=1] [orgs=3] [users=50] [requests=200] [meetings=4] [files=true] [events=false] [dry-run]")
+	_ = grift.Add("generate", func(c *grift.Context) error {
+		if domain.Env.GoEnv != "development" && domain.Env.GoEnv != "test" {
+			return fmt.Errorf("generated data has well-known access tokens, and is not allowed in %s", domain.Env.GoEnv)
+		}
+
+		return runAdminTask(c, func(tx *pop.Connection, a adminArgs) error {
+			cfg, err := generateConfig(a)
+			if err != nil {
+				return err
+			}
+
+			result, err := seed.Generate(tx, cfg)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("generated seed %d: %d organizations, %d trusts, %d users, %d meetings with %d participants, "+
+				"%d watches, %d threads with %d messages, %d potential providers, %d locations, %d files\n",
+				cfg.Seed, result.Organizations, result.Trusts, result.Users, result.Meetings, result.Participants,
+				result.Watches, result.Threads, result.Messages, result.PotentialProviders, result.Locations,
+				result.Files)
+			for _, status := range []models.RequestStatus{
+				models.RequestStatusOpen, models.RequestStatusAccepted, models.RequestStatusDelivered,
+				models.RequestStatusReceived, models.RequestStatusCompleted, models.RequestStatusRemoved,
+				models.RequestStatusExpired,
+			} {
+				fmt.Printf("  %d %s requests\n", result.Requests[status], status)
+			}
+			fmt.Printf("user 0 is %s, with bearer token %s\n", seed.UserEmail(cfg.Seed, 0), seed.AccessToken(cfg.Seed, 0))
+			return nil
+		})
+	})
 })
+
+// generateConfig reads the db:generate arguments over the default configuration
+func generateConfig(a adminArgs) (seed.Config, error) {
+	cfg := seed.DefaultConfig()
+
+	ints := map[string]*int{
+		"orgs":     &cfg.Organizations,
+		"users":    &cfg.Users,
+		"requests": &cfg.Requests,
+		"meetings": &cfg.Meetings,
+	}
+	for key, field := range ints {
+		if v, ok := a.values[key]; ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid %s '%s'", key, v)
+			}
+			*field = n
+		}
+	}
+
+	if v, ok := a.values["seed"]; ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid seed '%s'", v)
+		}
+		cfg.Seed = n
+	}
+
+	bools := map[string]*bool{
+		"files":  &cfg.Files,
+		"events": &cfg.Events,
+	}
+	for key, field := range bools {
+		if v, ok := a.values[key]; ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid %s '%s'", key, v)
+			}
+			*field = b
+		}
+	}
+
+	return cfg, cfg.Validate()
+}
@@ -1,83 +1,203 @@
 package job
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gobuffalo/buffalo/worker"
+	"github.com/gobuffalo/pop/v6"
 
 	"github.com/silinternational/wecarry-api/domain"
 	"github.com/silinternational/wecarry-api/log"
+	"github.com/silinternational/wecarry-api/marketing"
+	"github.com/silinternational/wecarry-api/metrics"
 	"github.com/silinternational/wecarry-api/models"
 	"github.com/silinternational/wecarry-api/notifications"
+	"github.com/silinternational/wecarry-api/tracing"
 )
 
 const (
-	NewThreadMessage = "new_thread_message"
-	OutdatedRequests = "outdated_requests"
-	FileCleanup      = "file_cleanup"
-	LocationCleanup  = "location_cleanup"
-	TokenCleanup     = "token_cleanup"
+	NewThreadMessage   = "new_thread_message"
+	OutdatedRequests   = "outdated_requests"
+	FileCleanup        = "file_cleanup"
+	LocationCleanup    = "location_cleanup"
+	TokenCleanup       = "token_cleanup"
+	OutboxDrain        = "outbox_drain"
+	OutboxCleanup      = "outbox_cleanup"
+	MarketingSync      = "marketing_sync"
+	MarketingReconcile = "marketing_reconcile"
+	HealthCheck        = "health_check"
+	RecurringRequests  = "recurring_requests"
 )
 
+const (
+	// marketingSyncMaxAttempts is the number of times a marketing list sync is tried before it is abandoned
+	marketingSyncMaxAttempts = 6
+
+	// marketingSyncRetryDelay is the delay before the first retry of a failed marketing list sync. It doubles with
+	// each attempt.
+	marketingSyncRetryDelay = time.Minute
+)
+
+// outboxRetention is how long delivered outbox events are kept before removal by the cleanup job
+const outboxRetention = 7 * 24 * time.Hour
+
 var w *worker.Worker
 
+// errNoWorker is returned when a job is submitted in a process without a worker, e.g. a task that runs a handler
+var errNoWorker = errors.New("the worker is not initialized")
+
+// pings holds the channel of each pending health check job, by ID
+var pings sync.Map
+
 var handlers = map[string]func(worker.Args) error{
-	NewThreadMessage: newThreadMessageHandler,
-	OutdatedRequests: outdatedRequestsHandler,
-	FileCleanup:      fileCleanupHandler,
-	LocationCleanup:  locationCleanupHandler,
-	TokenCleanup:     tokenCleanupHandler,
+	NewThreadMessage:   newThreadMessageHandler,
+	OutdatedRequests:   outdatedRequestsHandler,
+	FileCleanup:        fileCleanupHandler,
+	LocationCleanup:    locationCleanupHandler,
+	TokenCleanup:       tokenCleanupHandler,
+	OutboxCleanup:      outboxCleanupHandler,
+	MarketingSync:      marketingSyncHandler,
+	MarketingReconcile: marketingReconcileHandler,
+	RecurringRequests:  recurringRequestsHandler,
 }
 
 func Init(appWorker *worker.Worker) {
 	w = appWorker
 	for key, handler := range handlers {
-		if err := (*w).Register(key, handler); err != nil {
+		if err := (*w).Register(key, instrument(key, handler)); err != nil {
 			log.Errorf("error registering '%s' handler, %s", key, err)
 		}
 	}
+
+	// not instrumented, since it runs on every readiness probe
+	if err := (*w).Register(HealthCheck, healthCheckHandler); err != nil {
+		log.Errorf("error registering '%s' handler, %s", HealthCheck, err)
+	}
+}
+
+// Register adds a handler to the Worker. It is for handlers defined in packages that cannot be imported by this one.
+func Register(key string, handler func(worker.Args) error) error {
+	if w == nil {
+		return errNoWorker
+	}
+	return (*w).Register(key, instrument(key, handler))
+}
+
+// instrument wraps a Worker handler to record its queue depth, duration and outcome. A job submitted by
+// SubmitContext as part of a trace also records a span in that trace. The span's trace context replaces the
+// submitter's in the arguments given to the handler.
+func instrument(key string, handler func(worker.Args) error) func(worker.Args) error {
+	return func(args worker.Args) error {
+		metrics.JobStarted(key)
+		start := time.Now()
+
+		var err error
+		if parent := jobContext(args); tracing.IsTraced(parent) {
+			ctx, span := tracing.Start(parent, "job "+key)
+			err = handler(withTraceContext(ctx, args))
+			tracing.End(span, err)
+		} else {
+			err = handler(args)
+		}
+
+		metrics.ObserveJob(key, time.Since(start), err)
+		return err
+	}
+}
+
+// jobContext returns the context that holds the trace of the job
+func jobContext(args worker.Args) context.Context {
+	return tracing.Extract(args[domain.ArgTraceContext])
 }
 
-// outdatedRequestsHandler is the Worker handler for new notifications
-// regarding open requests that have a needby date in the past
+// outdatedRequestsHandler is the Worker handler for open requests near or past their needed_before date. Requests
+// past the expiry grace period are expired, which withdraws their offers. The creators of other requests past their
+// date are asked to update them, and the creators of requests needed soon are reminded.
 func outdatedRequestsHandler(args worker.Args) error {
+	ctx := jobContext(args)
+
+	var lastErr error
+	for _, f := range []func(context.Context) error{expireRequests, sendOutdatedRequestNotices, sendRequestReminders} {
+		if err := f(ctx); err != nil {
+			lastErr = fmt.Errorf("error in %s worker, %w", OutdatedRequests, err)
+		}
+	}
+	return lastErr
+}
+
+// expireRequests expires the open requests that are past their needed_before date by more than the grace period,
+// and notifies their creators and the users who had offered to fulfill them
+func expireRequests(ctx context.Context) error {
+	graceDays := time.Duration(domain.Env.RequestExpiryGraceDays)
+	var requests models.Requests
+	if err := requests.FindOpenToExpire(models.DB, time.Now().UTC().Add(-graceDays*domain.DurationDay)); err != nil {
+		return fmt.Errorf("error finding requests to expire, %s", err)
+	}
+
+	var lastErr error
+	expired := 0
+	for i := range requests {
+		var tx *pop.Connection
+		var withdrawn models.Users
+		err := models.DB.Transaction(func(t *pop.Connection) error {
+			tx = t
+			var err error
+			withdrawn, err = requests[i].Expire(t)
+			return err
+		})
+		models.FinishOutboxTransaction(tx, err == nil)
+		if err != nil {
+			log.Errorf("error expiring request, %s", err)
+			lastErr = err
+			continue
+		}
+		expired++
+
+		if err := models.DB.Load(&requests[i], "CreatedBy"); err != nil {
+			lastErr = fmt.Errorf("error loading CreatedBy User of request: %s", err)
+			continue
+		}
+		msg := requestCreatorMessage(requests[i], domain.MessageTemplateRequestExpired, "Email.Subject.Request.Expired")
+		if err := notifications.SendContext(ctx, msg); err != nil {
+			log.Errorf("error sending 'Expired Request' notification, %s", err)
+			lastErr = err
+		}
+		for _, provider := range withdrawn {
+			if err := sendOfferWithdrawnNotification(ctx, provider, requests[i]); err != nil {
+				log.Errorf("error sending 'Offer Withdrawn' notification, %s", err)
+				lastErr = err
+			}
+		}
+	}
+
+	log.Infof("Expired %d of %d outdated requests", expired, len(requests))
+	return lastErr
+}
+
+// sendOutdatedRequestNotices asks the creators of open requests that are past their needed_before date to update or
+// close them
+func sendOutdatedRequestNotices(ctx context.Context) error {
 	var requests models.Requests
 	db := models.DB
 	if err := requests.FindOpenPastNeededBefore(db, "NeededBefore", "UUID"); err != nil {
-		return fmt.Errorf("error finding outdated requests for %s worker: %s", OutdatedRequests, err)
+		return fmt.Errorf("error finding outdated requests, %s", err)
 	}
 
 	var lastErr error
-	template := domain.MessageTemplateRequestPastNeededBefore
-	for i, r := range requests {
+	for i := range requests {
 		if err := db.Load(&requests[i], "CreatedBy"); err != nil {
 			return fmt.Errorf("error loading CreatedBy User of request: %s", err)
 		}
 
-		requestTitle := domain.Truncate(r.Title, "...", 16)
-		msg := notifications.Message{
-			Template: template,
-			Data: map[string]interface{}{
-				"appName":        domain.Env.AppName,
-				"uiURL":          domain.Env.UIURL,
-				"requestURL":     domain.GetRequestUIURL(r.UUID.String()),
-				"requestEditURL": domain.GetRequestEditUIURL(r.UUID.String()),
-				"requestTitle":   requestTitle,
-				"supportEmail":   domain.Env.SupportEmail,
-			},
-			FromEmail: domain.EmailFromAddress(nil),
-		}
-
-		creator := requests[i].CreatedBy
-		msg.ToName = creator.GetRealName()
-		msg.ToEmail = creator.Email
-		msg.Subject = domain.GetTranslatedSubject(r.CreatedBy.GetLanguagePreference(db),
-			"Email.Subject.Request.Outdated",
-			map[string]string{"requestTitle": requestTitle})
-
-		if err := notifications.Send(msg); err != nil {
+		msg := requestCreatorMessage(requests[i], domain.MessageTemplateRequestPastNeededBefore,
+			"Email.Subject.Request.Outdated")
+		if err := notifications.SendContext(ctx, msg); err != nil {
 			log.Errorf("error sending 'Outdated Request' notification, %s", err)
 			lastErr = err
 			continue
@@ -87,6 +207,124 @@ func outdatedRequestsHandler(args worker.Args) error {
 	return lastErr
 }
 
+// sendRequestReminders reminds the creators of open requests that are needed within the configured number of days.
+// Each request gets one reminder, unless its needed_before date is changed.
+func sendRequestReminders(ctx context.Context) error {
+	if domain.Env.RequestReminderDays <= 0 {
+		return nil
+	}
+
+	reminderDays := time.Duration(domain.Env.RequestReminderDays)
+	var requests models.Requests
+	db := models.DB
+	if err := requests.FindOpenNeedingReminder(db, time.Now().UTC().Add(reminderDays*domain.DurationDay)); err != nil {
+		return fmt.Errorf("error finding requests needing a reminder, %s", err)
+	}
+
+	var lastErr error
+	for i := range requests {
+		if err := db.Load(&requests[i], "CreatedBy"); err != nil {
+			return fmt.Errorf("error loading CreatedBy User of request: %s", err)
+		}
+
+		msg := requestCreatorMessage(requests[i], domain.MessageTemplateRequestNeededBeforeReminder,
+			"Email.Subject.Request.NeededBeforeReminder")
+		if err := notifications.SendContext(ctx, msg); err != nil {
+			log.Errorf("error sending 'Request Reminder' notification, %s", err)
+			lastErr = err
+			continue
+		}
+
+		if err := requests[i].MarkReminded(db); err != nil {
+			log.Errorf("error recording request reminder, %s", err)
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// requestCreatorMessage prepares a notification about a request to its creator, who must be loaded
+func requestCreatorMessage(r models.Request, template, subject string) notifications.Message {
+	requestTitle := domain.Truncate(r.Title, "...", 16)
+	msg := notifications.Message{
+		Template: template,
+		Data: map[string]interface{}{
+			"appName":        domain.Env.AppName,
+			"uiURL":          domain.Env.UIURL,
+			"requestURL":     domain.GetRequestUIURL(r.UUID.String()),
+			"requestEditURL": domain.GetRequestEditUIURL(r.UUID.String()),
+			"requestTitle":   requestTitle,
+			"neededBefore":   r.NeededBefore.Time.Format(domain.DateFormat),
+			"supportEmail":   domain.Env.SupportEmail,
+		},
+		FromEmail: domain.EmailFromAddress(nil),
+	}
+
+	creator := r.CreatedBy
+	msg.ToName = creator.GetRealName()
+	msg.ToEmail = creator.Email
+	msg.Subject = domain.GetTranslatedSubject(creator.GetLanguagePreference(models.DB), subject,
+		map[string]string{"requestTitle": requestTitle})
+	return msg
+}
+
+// sendOfferWithdrawnNotification tells a user that their offer to fulfill a request was withdrawn because the request
+// expired. The request creator must be loaded.
+func sendOfferWithdrawnNotification(ctx context.Context, provider models.User, r models.Request) error {
+	requestTitle := domain.Truncate(r.Title, "...", 16)
+	msg := notifications.Message{
+		Template:  domain.MessageTemplatePotentialProviderWithdrawn,
+		ToName:    provider.GetRealName(),
+		ToEmail:   provider.Email,
+		FromEmail: domain.EmailFromAddress(nil),
+		Subject: domain.GetTranslatedSubject(provider.GetLanguagePreference(models.DB),
+			"Email.Subject.Request.OfferWithdrawn", map[string]string{"requestTitle": requestTitle}),
+		Data: map[string]interface{}{
+			"appName":          domain.Env.AppName,
+			"uiURL":            domain.Env.UIURL,
+			"requestURL":       domain.GetRequestUIURL(r.UUID.String()),
+			"requestTitle":     requestTitle,
+			"receiverNickname": r.CreatedBy.Nickname,
+		},
+	}
+	return notifications.SendContext(ctx, msg)
+}
+
+// recurringRequestsHandler makes the requests of the recurring request templates that are due. Each template is
+// handled in its own transaction, so that one failure does not hold back the others.
+func recurringRequestsHandler(args worker.Args) error {
+	var templates models.RequestTemplates
+	if err := templates.FindDue(models.DB, time.Now()); err != nil {
+		return fmt.Errorf("error finding due request templates for %s worker: %s", RecurringRequests, err)
+	}
+
+	var lastErr error
+	made := 0
+	for i := range templates {
+		var tx *pop.Connection
+		var request models.Request
+		err := models.DB.Transaction(func(t *pop.Connection) error {
+			tx = t
+			var err error
+			request, err = templates[i].Recur(t)
+			return err
+		})
+		models.FinishOutboxTransaction(tx, err == nil)
+		if err != nil {
+			log.Errorf("error making recurring request, %s", err)
+			lastErr = err
+			continue
+		}
+		if request.ID != 0 {
+			made++
+		}
+	}
+
+	log.Infof("Made %d recurring requests from %d due templates", made, len(templates))
+	return lastErr
+}
+
 // newThreadMessageHandler is the Worker handler for new notifications of new Thread Messages
 func newThreadMessageHandler(args worker.Args) error {
 	id, ok := args[domain.ArgMessageID].(int)
@@ -99,22 +337,36 @@ func newThreadMessageHandler(args worker.Args) error {
 		return fmt.Errorf("bad ID (%d) received by new thread message handler, %s", id, err)
 	}
 
+	// The sender deleted the message before anyone was notified
+	if m.IsDeleted() {
+		return nil
+	}
+
 	if err := m.Thread.Load(models.DB, "Participants", "Request"); err != nil {
 		return errors.New("failed to load Participants and Request in new thread message handler")
 	}
 
+	// Attached files are not included in the email, but the recipient is told to look for them in the thread
+	var messageFiles models.MessageFiles
+	attachmentCount, err := models.DB.Where("message_id = ?", m.ID).Count(&messageFiles)
+	if err != nil {
+		return fmt.Errorf("failed to count the files attached to message %d, %s", m.ID, err)
+	}
+
 	template := domain.MessageTemplateNewThreadMessage
 	requestTitle := domain.Truncate(m.Thread.Request.Title, "...", 16)
 	msg := notifications.Message{
 		Template: template,
 		Data: map[string]interface{}{
-			"appName":        domain.Env.AppName,
-			"uiURL":          domain.Env.UIURL,
-			"requestURL":     domain.GetRequestUIURL(m.Thread.Request.UUID.String()),
-			"requestTitle":   requestTitle,
-			"messageContent": m.Content,
-			"sentByNickname": m.SentBy.Nickname,
-			"threadURL":      domain.GetThreadUIURL(m.Thread.UUID.String()),
+			"appName":         domain.Env.AppName,
+			"uiURL":           domain.Env.UIURL,
+			"requestURL":      domain.GetRequestUIURL(m.Thread.Request.UUID.String()),
+			"requestTitle":    requestTitle,
+			"messageContent":  m.Content,
+			"attachmentCount": attachmentCount,
+			"sentByNickname":  m.SentBy.Nickname,
+			"threadURL":       domain.GetThreadUIURL(m.Thread.UUID.String()),
+			"canReply":        false,
 		},
 		FromEmail: domain.EmailFromAddress(&m.SentBy.Nickname),
 	}
@@ -136,13 +388,21 @@ func newThreadMessageHandler(args worker.Args) error {
 			continue
 		}
 
+		// Each participant gets their own reply-to address, which identifies them when they reply by email
+		replyTo, err := tp.GetReplyToAddress(models.DB)
+		if err != nil {
+			log.Errorf("newThreadMessageHandler error, %s", err)
+		}
+		msg.ReplyToEmail = replyTo
+		msg.Data["canReply"] = replyTo != ""
+
 		msg.ToName = p.GetRealName()
 		msg.ToEmail = p.Email
 		msg.Subject = domain.GetTranslatedSubject(p.GetLanguagePreference(models.DB),
 			"Email.Subject.Message.Created",
 			map[string]string{"sentByNickname": m.SentBy.Nickname, "requestTitle": requestTitle})
 
-		if err := notifications.Send(msg); err != nil {
+		if err := notifications.SendContext(jobContext(args), msg); err != nil {
 			log.Errorf("error sending 'New Thread Message' notification, %s", err)
 			lastErr = err
 			continue
@@ -187,22 +447,190 @@ func tokenCleanupHandler(args worker.Args) error {
 	return nil
 }
 
+// outboxCleanupHandler removes delivered outbox events
+func outboxCleanupHandler(args worker.Args) error {
+	o := models.OutboxEvents{}
+	deleted, err := o.DeleteProcessed(models.DB, time.Now().Add(-outboxRetention))
+	if err != nil {
+		return fmt.Errorf("error cleaning delivered outbox events: %v", err)
+	}
+
+	log.Infof("Deleted %v delivered outbox events during cleanup", deleted)
+	return nil
+}
+
+// marketingSyncHandler syncs a user's marketing list membership. A failed sync is retried with an increasing delay,
+// until it is abandoned after marketingSyncMaxAttempts.
+func marketingSyncHandler(args worker.Args) error {
+	id, ok := args[domain.ArgId].(int)
+	if !ok || id <= 0 {
+		return fmt.Errorf("no user ID provided to %s worker, args = %+v", MarketingSync, args)
+	}
+	attempt, _ := args[domain.ArgAttempt].(int)
+
+	c, ok := marketing.ClientFromEnv()
+	if !ok {
+		log.Infof("missing MailChimp env vars. need to sync user %d to the marketing list", id)
+		return nil
+	}
+
+	var user models.User
+	if err := user.FindByID(models.DB, id); err != nil {
+		return fmt.Errorf("bad ID (%d) received by %s worker, %s", id, MarketingSync, err)
+	}
+
+	err := marketing.SyncUser(models.DB, c, user)
+	if err == nil {
+		return nil
+	}
+
+	attempt++
+	if attempt >= marketingSyncMaxAttempts {
+		return fmt.Errorf("abandoning marketing list sync of user %d after %d attempts, %w", id, attempt, err)
+	}
+
+	log.Errorf("marketing list sync of user %d failed, will retry, %s", id, err)
+	retryArgs := map[string]interface{}{
+		domain.ArgId:           id,
+		domain.ArgAttempt:      attempt,
+		domain.ArgTraceContext: args[domain.ArgTraceContext],
+	}
+	if err := SubmitDelayed(MarketingSync, marketingSyncRetryDelay<<(attempt-1), retryArgs); err != nil {
+		return fmt.Errorf("error scheduling retry of marketing list sync of user %d, %w", id, err)
+	}
+	return nil
+}
+
+// marketingReconcileHandler brings the marketing list in line with the users
+func marketingReconcileHandler(args worker.Args) error {
+	c, ok := marketing.ClientFromEnv()
+	if !ok {
+		log.Info("missing MailChimp env vars. cannot reconcile the marketing list")
+		return nil
+	}
+
+	result, err := marketing.Reconcile(models.DB, c)
+	log.Infof("marketing list reconciliation synced %d, unsubscribed %d, archived %d, opted out %d, failed %d",
+		result.Synced, result.Unsubscribed, result.Archived, result.OptedOut, result.Failed)
+	if err != nil {
+		return fmt.Errorf("marketing list reconciliation failed, %w", err)
+	}
+	return nil
+}
+
 // SubmitDelayed enqueues a new Worker job for the given handler. Arguments can be provided in `args`.
 func SubmitDelayed(handler string, delay time.Duration, args map[string]interface{}) error {
+	if w == nil {
+		return errNoWorker
+	}
+
 	job := worker.Job{
 		Queue:   "default",
 		Args:    args,
 		Handler: handler,
 	}
-	return (*w).PerformIn(job, delay)
+	if err := (*w).PerformIn(job, delay); err != nil {
+		return err
+	}
+	metrics.JobSubmitted(handler)
+	return nil
+}
+
+// SubmitContext enqueues a new Worker job for the given handler, as part of the trace in the context, if any.
+// Arguments can be provided in `args`.
+func SubmitContext(ctx context.Context, handler string, args map[string]interface{}) error {
+	return Submit(handler, withTraceContext(ctx, args))
+}
+
+// SubmitDelayedContext enqueues a new Worker job for the given handler, as part of the trace in the context, if any.
+// Arguments can be provided in `args`.
+func SubmitDelayedContext(ctx context.Context, handler string, delay time.Duration, args map[string]interface{}) error {
+	return SubmitDelayed(handler, delay, withTraceContext(ctx, args))
+}
+
+// withTraceContext returns a copy of the job arguments that includes the trace context of the context, if any
+func withTraceContext(ctx context.Context, args map[string]interface{}) map[string]interface{} {
+	a := map[string]interface{}{}
+	for k, v := range args {
+		a[k] = v
+	}
+	if tracing.IsTraced(ctx) {
+		a[domain.ArgTraceContext] = tracing.Inject(ctx)
+	}
+	return a
+}
+
+// Run runs the named handler synchronously in the current process, rather than submitting a job to the Worker. It is
+// for running jobs from the command line.
+func Run(ctx context.Context, handler string, args map[string]interface{}) error {
+	h, ok := handlers[handler]
+	if !ok {
+		return fmt.Errorf("unknown job handler '%s', must be one of: %s", handler, strings.Join(Handlers(), ", "))
+	}
+	return h(withTraceContext(ctx, args))
+}
+
+// Handlers returns the names of the handlers that can be run by Run, in alphabetical order
+func Handlers() []string {
+	names := make([]string, 0, len(handlers))
+	for name := range handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Ping verifies that the Worker is running jobs, by submitting a job that does nothing and waiting for it to run
+func Ping(ctx context.Context) error {
+	if w == nil {
+		return errNoWorker
+	}
+
+	id := domain.GetUUID().String()
+	done := make(chan struct{})
+	pings.Store(id, done)
+	defer pings.Delete(id)
+
+	job := worker.Job{
+		Queue:   "default",
+		Args:    map[string]interface{}{domain.ArgId: id},
+		Handler: HealthCheck,
+	}
+	if err := (*w).Perform(job); err != nil {
+		return err
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("health check job did not run, %w", ctx.Err())
+	}
+}
+
+// healthCheckHandler is the Worker handler for the jobs submitted by Ping
+func healthCheckHandler(args worker.Args) error {
+	id, _ := args[domain.ArgId].(string)
+	if done, ok := pings.Load(id); ok {
+		close(done.(chan struct{}))
+	}
+	return nil
 }
 
 // Submit enqueues a new Worker job for the given handler. Arguments can be provided in `args`.
 func Submit(handler string, args map[string]interface{}) error {
+	if w == nil {
+		return errNoWorker
+	}
+
 	job := worker.Job{
 		Queue:   "default",
 		Args:    args,
 		Handler: handler,
 	}
-	return (*w).Perform(job)
+	if err := (*w).Perform(job); err != nil {
+		return err
+	}
+	metrics.JobSubmitted(handler)
+	return nil
 }
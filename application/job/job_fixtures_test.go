@@ -18,6 +18,10 @@ type RequestFixtures struct {
 	models.Users
 	models.Requests
 }
+type RequestTemplateFixtures struct {
+	models.Users
+	models.RequestTemplates
+}
 
 func createFixture(js *JobSuite, f interface{}) {
 	err := js.DB.Create(f)
@@ -45,6 +49,60 @@ func CreateFixtures_TestOutdatedRequestHandler(js *JobSuite) RequestFixtures {
 	}
 }
 
+// CreateFixtures_TestOutdatedRequestsExpiry creates three open requests: one past the expiry grace period with two
+// offers, one needed tomorrow, and one needed in four weeks
+func CreateFixtures_TestOutdatedRequestsExpiry(js *JobSuite) RequestFixtures {
+	users := test.CreateUserFixtures(js.DB, 3).Users
+	requests := test.CreateRequestFixtures(js.DB, 3, false, users[0].ID)
+
+	neededBefore := []time.Time{
+		time.Now().Add(-10 * domain.DurationDay),
+		time.Now().Add(domain.DurationDay),
+	}
+	for i := range neededBefore {
+		requests[i].NeededBefore = nulls.NewTime(neededBefore[i])
+		js.NoError(js.DB.Update(&requests[i]), "error modifying request for test")
+	}
+
+	for _, u := range users[1:] {
+		createFixture(js, &models.PotentialProvider{RequestID: requests[0].ID, UserID: u.ID})
+	}
+
+	return RequestFixtures{
+		Users:    users,
+		Requests: requests,
+	}
+}
+
+func CreateFixtures_TestRecurringRequestsHandler(js *JobSuite) RequestTemplateFixtures {
+	uf := test.CreateUserFixtures(js.DB, 1)
+	locations := test.CreateLocationFixtures(js.DB, 2)
+
+	nextDates := []time.Time{
+		time.Now().Add(-1 * domain.DurationDay),
+		time.Now().Add(domain.DurationWeek),
+	}
+	templates := make(models.RequestTemplates, len(nextDates))
+	for i := range templates {
+		templates[i] = models.RequestTemplate{
+			OwnerID:          uf.Users[0].ID,
+			OrganizationID:   uf.Organization.ID,
+			Title:            "coffee",
+			Size:             models.RequestSizeSmall,
+			DestinationID:    locations[i].ID,
+			NeededWithinDays: nulls.NewInt(7),
+			RepeatEveryDays:  nulls.NewInt(30),
+			NextRequestOn:    nulls.NewTime(nextDates[i]),
+		}
+		js.NoError(templates[i].Create(js.DB), "error creating request template fixture")
+	}
+
+	return RequestTemplateFixtures{
+		Users:            uf.Users,
+		RequestTemplates: templates,
+	}
+}
+
 func CreateFixtures_TestNewThreadMessageHandler(js *JobSuite) MessageFixtures {
 	uf := test.CreateUserFixtures(js.DB, 7)
 	org := uf.Organization
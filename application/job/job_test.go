@@ -1,13 +1,18 @@
 package job
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"text/template"
 	"time"
 
+	"github.com/gobuffalo/buffalo/worker"
 	"github.com/gobuffalo/suite/v4"
 
 	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/internal/test"
 	"github.com/silinternational/wecarry-api/models"
 	"github.com/silinternational/wecarry-api/notifications"
 )
@@ -42,6 +47,61 @@ func (js *JobSuite) TestOutdatedRequestsHandler() {
 	js.Contains(body, `mailto:`+supportEmail)
 }
 
+func (js *JobSuite) TestOutdatedRequestsHandler_Expiry() {
+	domain.Env.RequestExpiryGraceDays = 7
+	domain.Env.RequestReminderDays = 2
+
+	f := CreateFixtures_TestOutdatedRequestsExpiry(js)
+	notifications.TestEmailService.DeleteSentMessages()
+
+	js.NoError(outdatedRequestsHandler(nil))
+
+	js.ElementsMatch(
+		[]string{f.Users[0].Email, f.Users[1].Email, f.Users[2].Email, f.Users[0].Email},
+		notifications.TestEmailService.GetAllToAddresses(),
+		"the creator should be told of the expiry and the reminder, and the offerers of the withdrawal",
+	)
+
+	var expired models.Request
+	js.NoError(js.DB.Find(&expired, f.Requests[0].ID))
+	js.Equal(models.RequestStatusExpired, expired.Status)
+
+	var providers models.PotentialProviders
+	n, err := js.DB.Where("request_id = ?", expired.ID).Count(&providers)
+	js.NoError(err)
+	js.Equal(0, n, "the offers should be withdrawn")
+
+	var reminded models.Request
+	js.NoError(js.DB.Find(&reminded, f.Requests[1].ID))
+	js.Equal(models.RequestStatusOpen, reminded.Status)
+	js.True(reminded.RemindedAt.Valid, "the reminder should be recorded")
+
+	notifications.TestEmailService.DeleteSentMessages()
+	js.NoError(outdatedRequestsHandler(nil))
+	js.Equal(0, notifications.TestEmailService.GetNumberOfMessagesSent(), "nothing should be sent twice")
+}
+
+func (js *JobSuite) TestRecurringRequestsHandler() {
+	f := CreateFixtures_TestRecurringRequestsHandler(js)
+
+	js.NoError(recurringRequestsHandler(nil))
+
+	var requests models.Requests
+	js.NoError(js.DB.Where("created_by_id = ?", f.Users[0].ID).All(&requests))
+	js.Equal(1, len(requests), "only the due template should make a request")
+	js.Equal(models.RequestStatusOpen, requests[0].Status)
+	js.Equal(f.RequestTemplates[0].Title, requests[0].Title)
+
+	var template models.RequestTemplate
+	js.NoError(js.DB.Find(&template, f.RequestTemplates[0].ID))
+	js.Equal(requests[0].ID, template.LastRequestID.Int)
+	js.True(template.NextRequestOn.Time.After(time.Now()), "the next request should be scheduled")
+
+	js.NoError(recurringRequestsHandler(nil))
+	js.NoError(js.DB.Where("created_by_id = ?", f.Users[0].ID).All(&requests))
+	js.Equal(1, len(requests), "no request should be made before the next date")
+}
+
 func (js *JobSuite) TestNewThreadMessageHandler() {
 	f := CreateFixtures_TestNewThreadMessageHandler(js)
 
@@ -108,3 +168,71 @@ func (js *JobSuite) TestNewThreadMessageHandler() {
 		})
 	}
 }
+
+func (js *JobSuite) TestPing() {
+	saved := w
+	defer func() { w = saved }()
+
+	w = nil
+	js.Error(Ping(context.Background()), "expected an error before the worker is initialized")
+
+	var simple worker.Worker = worker.NewSimple()
+	Init(&simple)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	js.Error(Ping(ctx), "expected an error before the worker is started")
+
+	js.NoError(simple.Start(context.Background()))
+	defer func() { _ = simple.Stop() }()
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	js.NoError(Ping(ctx))
+}
+
+func (js *JobSuite) TestRun() {
+	js.Contains(Handlers(), TokenCleanup)
+	js.NotContains(Handlers(), HealthCheck)
+
+	err := Run(context.Background(), "bogus", nil)
+	js.Error(err, "expected an error for an unknown handler")
+	js.Contains(err.Error(), FileCleanup, "error should list the known handlers")
+
+	js.NoError(Run(context.Background(), TokenCleanup, nil))
+
+	err = Run(context.Background(), NewThreadMessage, map[string]interface{}{})
+	js.Error(err, "expected an error for missing arguments")
+}
+
+func (js *JobSuite) TestRun_NoWorker() {
+	saved := w
+	defer func() { w = saved }()
+	w = nil
+
+	js.ErrorIs(Submit(TokenCleanup, nil), errNoWorker)
+	js.ErrorIs(SubmitDelayed(TokenCleanup, time.Minute, nil), errNoWorker)
+
+	// a failed marketing list sync schedules a retry
+	srv := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	env := domain.Env
+	defer func() { domain.Env = env }()
+	domain.Env.MailChimpAPIBaseURL = srv.URL
+	domain.Env.MailChimpAPIKey = "key"
+	domain.Env.MailChimpListID = "list"
+	domain.Env.MailChimpUsername = "user"
+
+	user := test.CreateUserFixtures(js.DB, 1).Users[0]
+	user.MarketingOptIn = true
+	js.NoError(js.DB.UpdateColumns(&user, "marketing_opt_in"))
+
+	var err error
+	js.NotPanics(func() {
+		err = Run(context.Background(), MarketingSync, map[string]interface{}{domain.ArgId: user.ID})
+	})
+	js.ErrorIs(err, errNoWorker, "the retry should fail without a worker")
+}
@@ -3,20 +3,24 @@ package listeners
 import (
 	"context"
 	"errors"
-	"strings"
+	"fmt"
 	"time"
 
 	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/worker"
 	"github.com/gobuffalo/events"
 	"github.com/gobuffalo/pop/v6"
 
+	"github.com/silinternational/wecarry-api/aws"
 	"github.com/silinternational/wecarry-api/cache"
 	"github.com/silinternational/wecarry-api/domain"
 	"github.com/silinternational/wecarry-api/job"
 	"github.com/silinternational/wecarry-api/log"
 	"github.com/silinternational/wecarry-api/marketing"
+	"github.com/silinternational/wecarry-api/metrics"
 	"github.com/silinternational/wecarry-api/models"
 	"github.com/silinternational/wecarry-api/notifications"
+	"github.com/silinternational/wecarry-api/tracing"
 )
 
 type listenerContext struct {
@@ -46,332 +50,490 @@ func (*listenerContext) Err() error {
 	return nil
 }
 
-var eventTypes = map[string]func(event events.Event){
-	domain.EventApiUserCreated:                    userCreatedHandler,
-	domain.EventApiMessageCreated:                 sendNewThreadMessageNotification,
-	domain.EventApiRequestStatusUpdated:           sendRequestStatusUpdatedNotification,
-	domain.EventApiRequestCreated:                 requestCreatedHandler,
-	domain.EventApiRequestUpdated:                 cacheRequestUpdatedListener,
-	domain.EventApiPotentialProviderCreated:       potentialProviderCreated,
-	domain.EventApiPotentialProviderSelfDestroyed: potentialProviderSelfDestroyed,
-	domain.EventApiPotentialProviderRejected:      potentialProviderRejected,
-	domain.EventApiMeetingInviteCreated:           meetingInviteCreated,
+// eventHandler is one step of the delivery of an event. Steps are named so that a redelivery skips the ones that
+// already succeeded.
+type eventHandler struct {
+	name    string
+	handler func(event events.Event) error
 }
 
-func userCreatedHandler(event events.Event) {
-	userCreatedLogger(event)
-	userCreatedSendWelcomeMessage(event)
-	userCreatedAddToMarketingList(event)
+var eventTypes = map[string][]eventHandler{
+	domain.EventApiUserCreated: {
+		{name: "logger", handler: userCreatedLogger},
+		{name: "welcome_message", handler: userCreatedSendWelcomeMessage},
+		{name: "marketing_list", handler: userCreatedAddToMarketingList},
+	},
+	domain.EventApiUserMarketingUpdated: {
+		{name: "marketing_list", handler: userMarketingUpdated},
+	},
+	domain.EventApiUserDeleted: {
+		{name: "marketing_list", handler: userDeletedRemoveFromMarketingList},
+		{name: "storage", handler: userDeletedRemoveFiles},
+	},
+	domain.EventApiMessageCreated: {
+		{name: "notification", handler: sendNewThreadMessageNotification},
+	},
+	domain.EventApiRequestStatusUpdated: {
+		{name: "notification", handler: sendRequestStatusUpdatedNotification},
+	},
+	domain.EventApiRequestCreated: {
+		{name: "notifications", handler: sendRequestCreatedNotifications},
+		{name: "cache", handler: cacheRequestCreatedListener},
+	},
+	domain.EventApiRequestUpdated: {
+		{name: "cache", handler: cacheRequestUpdatedListener},
+	},
+	domain.EventApiPotentialProviderCreated: {
+		{name: "notification", handler: potentialProviderCreated},
+	},
+	domain.EventApiPotentialProviderSelfDestroyed: {
+		{name: "notification", handler: potentialProviderSelfDestroyed},
+	},
+	domain.EventApiPotentialProviderRejected: {
+		{name: "notification", handler: potentialProviderRejected},
+	},
+	domain.EventApiMeetingInviteCreated: {
+		{name: "invite", handler: meetingInviteCreated},
+	},
+	domain.EventApiOrganizationTrustChanged: {
+		{name: "cache", handler: cacheOrganizationTrustListener},
+	},
 }
 
-func requestCreatedHandler(event events.Event) {
-	sendRequestCreatedNotifications(event)
-	cacheRequestCreatedListener(event)
+// argPoll marks the outbox drain jobs that are part of the regular poll of the outbox
+const argPoll = "poll"
+
+// RegisterListener registers the Worker handler that delivers the events in the outbox, and starts polling the
+// outbox for events that are due for a retry. The Worker must already be initialized.
+func RegisterListener() {
+	if err := job.Register(job.OutboxDrain, drainOutbox); err != nil {
+		panic("failed to register outbox handler " + err.Error())
+	}
+
+	models.SetOutboxDispatcher(dispatchOutbox)
+	scheduleOutboxPoll()
+}
+
+// dispatchOutbox starts delivery of newly committed events
+func dispatchOutbox() {
+	if err := job.Submit(job.OutboxDrain, nil); err != nil {
+		log.Errorf("error starting outbox drain job, %s", err)
+	}
+}
+
+func scheduleOutboxPoll() {
+	delay := time.Duration(domain.Env.OutboxPollMilliseconds) * time.Millisecond
+	if err := job.SubmitDelayed(job.OutboxDrain, delay, map[string]interface{}{argPoll: true}); err != nil {
+		log.Errorf("error scheduling outbox poll, %s", err)
+	}
 }
 
-func listener(e events.Event) {
+// drainOutbox is the Worker handler that delivers every event in the outbox that is due. If it was started by the
+// poll, it schedules the next poll when done.
+func drainOutbox(args worker.Args) error {
+	if poll, _ := args[argPoll].(bool); poll {
+		defer scheduleOutboxPoll()
+	}
+
+	for {
+		delivered, err := deliverNextOutboxEvent()
+		if err != nil {
+			return fmt.Errorf("error draining outbox, %w", err)
+		}
+		if !delivered {
+			return nil
+		}
+	}
+}
+
+// deliverNextOutboxEvent delivers the next due event in the outbox, if any. It returns false if there was none.
+func deliverNextOutboxEvent() (bool, error) {
+	found := false
+	err := models.DB.Transaction(func(tx *pop.Connection) error {
+		var o models.OutboxEvent
+		if err := o.FindNextDue(tx, domain.Env.ListenerMaxRetries); err != nil {
+			if domain.IsOtherThanNoRows(err) {
+				return err
+			}
+			return nil
+		}
+		found = true
+
+		if err := deliver(tx, &o); err != nil {
+			return o.MarkFailed(tx, err, getDelayDuration(1))
+		}
+		return o.MarkProcessed(tx)
+	})
+	return found, err
+}
+
+// deliver calls each handler of an outbox event that has not yet processed it
+func deliver(tx *pop.Connection, o *models.OutboxEvent) (err error) {
 	defer func() {
-		if err := recover(); err != nil {
-			log.Errorf("panic in event %s: %s", e.Kind, err)
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic in event %s: %s", o.Kind, r)
 		}
 	}()
 
-	handler, ok := eventTypes[e.Kind]
+	e, err := o.Event()
+	if err != nil {
+		return err
+	}
+
+	handlers, ok := eventTypes[e.Kind]
 	if !ok {
-		if strings.HasPrefix(e.Kind, "app") {
-			panic("event '" + e.Kind + "' has no handler")
-		}
-		return
+		return fmt.Errorf("event '%s' has no handler", e.Kind)
 	}
 
-	time.Sleep(time.Second * 5) // a rough guess at the longest time it takes for the database transaction to close
+	for _, h := range handlers {
+		if o.IsHandlerCompleted(h.name) {
+			continue
+		}
+		err := handle(h, e)
+		metrics.ObserveEvent(e.Kind, h.name, err)
+		if err != nil {
+			return fmt.Errorf("%s handler of event %s failed, %w", h.name, e.Kind, err)
+		}
+		if err := o.CompleteHandler(tx, h.name); err != nil {
+			return err
+		}
+	}
 
-	handler(e)
+	return nil
 }
 
-// RegisterListener registers the event listener
-func RegisterListener() {
-	if _, err := events.Listen(listener); err != nil {
-		panic("failed to register event listener " + err.Error())
+// handle calls the handler of an event. If the event is part of a trace, the handler records a span in that trace,
+// and the span's trace context replaces the event's in the payload given to the handler.
+func handle(h eventHandler, e events.Event) error {
+	parent := eventContext(e)
+	if !tracing.IsTraced(parent) {
+		return h.handler(e)
+	}
+
+	ctx, span := tracing.Start(parent, "event "+e.Kind+" "+h.name)
+	p := events.Payload{}
+	for k, v := range e.Payload {
+		p[k] = v
 	}
+	p[domain.ArgTraceContext] = tracing.Inject(ctx)
+	e.Payload = p
+
+	err := h.handler(e)
+	tracing.End(span, err)
+	return err
+}
+
+// eventContext returns the context that holds the trace of the delivery of an event
+func eventContext(e events.Event) context.Context {
+	return tracing.Extract(e.Payload[domain.ArgTraceContext])
 }
 
-func userCreatedLogger(e events.Event) {
+func userCreatedLogger(e events.Event) error {
 	if e.Kind != domain.EventApiUserCreated {
-		return
+		return nil
 	}
 
 	log.Infof("User Created: %s", e.Message)
+	return nil
 }
 
-func userCreatedSendWelcomeMessage(e events.Event) {
+func userCreatedSendWelcomeMessage(e events.Event) error {
 	if e.Kind != domain.EventApiUserCreated {
-		return
+		return nil
 	}
 
-	user, ok := e.Payload["user"].(*models.User)
-	if !ok {
-		log.Errorf("Failed to get User from event payload for sending welcome message. Event message: %s", e.Message)
-		return
+	user, err := getUser(e.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to get User from event payload for sending welcome message, %w", err)
 	}
 
-	if err := sendNewUserWelcome(*user); err != nil {
-		log.Errorf("Failed to send new user welcome to %s. Error: %s",
-			user.UUID.String(), err)
+	if err := sendNewUserWelcome(eventContext(e), user); err != nil {
+		return fmt.Errorf("failed to send new user welcome to %s, %w", user.UUID.String(), err)
 	}
+	return nil
 }
 
-func userCreatedAddToMarketingList(e events.Event) {
+func userCreatedAddToMarketingList(e events.Event) error {
 	if e.Kind != domain.EventApiUserCreated {
-		return
+		return nil
 	}
 
-	user, ok := e.Payload["user"].(*models.User)
-	if !ok {
-		log.Errorf("Failed to get User from event payload for adding to marketing list. Event message: %s", e.Message)
-		return
+	return submitMarketingSync(e)
+}
+
+func userMarketingUpdated(e events.Event) error {
+	if e.Kind != domain.EventApiUserMarketingUpdated {
+		return nil
 	}
 
-	// ensure env vars are present
-	if domain.Env.MailChimpAPIKey == "" {
-		log.Infof("missing env var for MAILCHIMP_API_KEY. need to add %s to list", user.Email)
-		return
+	return submitMarketingSync(e)
+}
+
+// submitMarketingSync starts a job to sync the marketing list membership of the user in the event payload. The job
+// retries on its own, so a MailChimp outage does not hold up the outbox.
+func submitMarketingSync(e events.Event) error {
+	id, err := getID(e.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to get user ID from event payload for marketing list sync, %w", err)
 	}
-	if domain.Env.MailChimpListID == "" {
-		log.Infof("missing env var for MAILCHIMP_LIST_ID. need to add %s to list", user.Email)
-		return
+
+	if err := job.SubmitContext(eventContext(e), job.MarketingSync, map[string]interface{}{domain.ArgId: id}); err != nil {
+		return fmt.Errorf("error starting marketing list sync of user %d, %w", id, err)
 	}
-	if domain.Env.MailChimpUsername == "" {
-		log.Infof("missing env var for MAILCHIMP_USERNAME. need to add %s to list", user.Email)
-		return
+	return nil
+}
+
+func userDeletedRemoveFromMarketingList(e events.Event) error {
+	if e.Kind != domain.EventApiUserDeleted {
+		return nil
 	}
 
-	err := marketing.AddUserToList(*user, domain.Env.MailChimpAPIBaseURL, domain.Env.MailChimpListID,
+	email, ok := e.Payload[domain.ArgEmail].(string)
+	if !ok || email == "" {
+		return errors.New("email not in event payload for removing from marketing list")
+	}
+
+	if domain.Env.MailChimpAPIKey == "" || domain.Env.MailChimpListID == "" || domain.Env.MailChimpUsername == "" {
+		log.Infof("missing MailChimp env vars. need to remove %s from list", email)
+		return nil
+	}
+
+	err := marketing.RemoveUserFromList(email, domain.Env.MailChimpAPIBaseURL, domain.Env.MailChimpListID,
 		domain.Env.MailChimpUsername, domain.Env.MailChimpAPIKey)
 	if err != nil {
-		log.Errorf("error calling marketing.AddUserToList when trying to add %s: %s",
-			user.Email, err.Error())
+		return fmt.Errorf("error calling marketing.RemoveUserFromList when trying to remove %s: %w", email, err)
 	}
+	return nil
 }
 
-func sendNewThreadMessageNotification(e events.Event) {
+// userDeletedRemoveFiles removes the files of a deleted user from storage. It runs only after the deletion is
+// committed, so the files are kept if the deletion is rolled back.
+func userDeletedRemoveFiles(e events.Event) error {
+	if e.Kind != domain.EventApiUserDeleted {
+		return nil
+	}
+
+	eventData, ok := e.Payload[domain.ArgEventData].(models.UserDeletedEventData)
+	if !ok {
+		return errors.New("userDeletedRemoveFiles: unable to read event data from event payload")
+	}
+
+	ctx := eventContext(e)
+	for _, id := range eventData.FileUUIDs {
+		if err := aws.RemoveFile(ctx, id); err != nil {
+			return fmt.Errorf("error removing file %s of deleted user from storage, %w", id, err)
+		}
+	}
+	return nil
+}
+
+func sendNewThreadMessageNotification(e events.Event) error {
 	if e.Kind != domain.EventApiMessageCreated {
-		return
+		return nil
 	}
 
 	log.Infof("%s Thread Message Created ... %s", domain.GetCurrentTime(), e.Message)
 
 	id, ok := e.Payload[domain.ArgMessageID].(int)
 	if !ok {
-		log.Errorf("sendNewThreadMessageNotification: unable to read message ID from event payload")
-		return
+		return errors.New("sendNewThreadMessageNotification: unable to read message ID from event payload")
 	}
 
-	if err := job.SubmitDelayed(job.NewThreadMessage, domain.NewMessageNotificationDelay,
+	if err := job.SubmitDelayedContext(eventContext(e), job.NewThreadMessage, domain.NewMessageNotificationDelay,
 		map[string]interface{}{domain.ArgMessageID: id}); err != nil {
-		log.Errorf("error starting 'New Message' job, %s", err)
+		return fmt.Errorf("error starting 'New Message' job, %w", err)
 	}
+	return nil
 }
 
-func sendRequestStatusUpdatedNotification(e events.Event) {
+func sendRequestStatusUpdatedNotification(e events.Event) error {
 	if e.Kind != domain.EventApiRequestStatusUpdated {
-		return
+		return nil
 	}
 
 	pEData, ok := e.Payload[domain.ArgEventData].(models.RequestStatusEventData)
 	if !ok {
-		log.Errorf("unable to parse Request Status Updated event payload")
-		return
+		return errors.New("unable to parse Request Status Updated event payload")
 	}
 
 	pid := pEData.RequestID
 
 	request := models.Request{}
 	if err := request.FindByID(models.DB, pid); err != nil {
-		log.Errorf("unable to find request from event with id %v ... %s", pid, err)
+		return fmt.Errorf("unable to find request from event with id %v ... %w", pid, err)
 	}
 
-	requestStatusUpdatedNotifications(request, pEData)
+	requestStatusUpdatedNotifications(eventContext(e), request, pEData)
+	return nil
 }
 
-func sendRequestCreatedNotifications(e events.Event) {
+func sendRequestCreatedNotifications(e events.Event) error {
 	if e.Kind != domain.EventApiRequestCreated {
-		return
+		return nil
 	}
 
 	eventData, ok := e.Payload[domain.ArgEventData].(models.RequestCreatedEventData)
 	if !ok {
-		log.Errorf("Request Created event payload incorrect type: %T", e.Payload[domain.ArgEventData])
-		return
+		return fmt.Errorf("Request Created event payload incorrect type: %T", e.Payload[domain.ArgEventData])
 	}
 
 	var request models.Request
 	if err := request.FindByID(models.DB, eventData.RequestID); err != nil {
-		log.Errorf("unable to find request %d from request-created event, %s", eventData.RequestID, err)
-		return
+		return fmt.Errorf("unable to find request %d from request-created event, %w", eventData.RequestID, err)
 	}
 
-	users, err := request.GetAudience(models.DB)
+	users, err := request.GetNotificationAudience(models.DB)
 	if err != nil {
-		log.Errorf("unable to get request audience in event listener: %s", err.Error())
-		return
+		return fmt.Errorf("unable to get request audience in event listener: %w", err)
 	}
 
-	sendNewRequestNotifications(request, users)
+	sendNewRequestNotifications(eventContext(e), request, users)
+	return nil
 }
 
-func cacheRequestCreatedListener(e events.Event) {
+func cacheRequestCreatedListener(e events.Event) error {
 	if e.Kind != domain.EventApiRequestCreated {
-		return
+		return nil
 	}
 
 	eventData, ok := e.Payload[domain.ArgEventData].(models.RequestCreatedEventData)
 	if !ok {
-		log.Errorf("Request Created event payload incorrect type: %T", e.Payload[domain.ArgEventData])
-		return
+		return fmt.Errorf("Request Created event payload incorrect type: %T", e.Payload[domain.ArgEventData])
 	}
 
 	var request models.Request
 	if err := request.FindByID(models.DB, eventData.RequestID); err != nil {
-		log.Errorf("unable to find request %d from request-created event, %s", eventData.RequestID, err)
+		return fmt.Errorf("unable to find request %d from request-created event, %w", eventData.RequestID, err)
 	}
 
 	err := models.DB.Transaction(func(tx *pop.Connection) error {
 		ctx := newListenerContext()
 		ctx.Set(domain.ContextKeyTx, tx)
-		return cache.CacheRebuildOnNewRequest(ctx, request)
+		return cache.InvalidateNewRequest(ctx, request)
 	})
 	if err != nil {
-		log.Errorf("error in cache rebuild on new request: " + err.Error())
+		return fmt.Errorf("error in cache invalidation on new request: %w", err)
 	}
+	return nil
 }
 
-func cacheRequestUpdatedListener(e events.Event) {
+func cacheRequestUpdatedListener(e events.Event) error {
 	if e.Kind != domain.EventApiRequestUpdated {
-		return
+		return nil
 	}
 
 	eventData, ok := e.Payload[domain.ArgEventData].(models.RequestUpdatedEventData)
 	if !ok {
-		log.Errorf("Request Updated event payload incorrect type: %T", e.Payload[domain.ArgEventData])
-		return
+		return fmt.Errorf("Request Updated event payload incorrect type: %T", e.Payload[domain.ArgEventData])
 	}
 
 	var request models.Request
 	if err := request.FindByID(models.DB, eventData.RequestID); err != nil {
-		log.Errorf("unable to find request %d from request-created event, %s", eventData.RequestID, err)
+		return fmt.Errorf("unable to find request %d from request-updated event, %w", eventData.RequestID, err)
 	}
 
 	err := models.DB.Transaction(func(tx *pop.Connection) error {
 		ctx := newListenerContext()
 		ctx.Set(domain.ContextKeyTx, tx)
-		return cache.CacheRebuildOnChangedRequest(ctx, request)
+		return cache.InvalidateChangedRequest(ctx, request)
 	})
 	if err != nil {
-		log.Errorf("error in cache rebuild on changed request: " + err.Error())
+		return fmt.Errorf("error in cache invalidation on changed request: %w", err)
 	}
+	return nil
 }
 
-func potentialProviderCreated(e events.Event) {
-	if e.Kind != domain.EventApiPotentialProviderCreated {
-		return
+func cacheOrganizationTrustListener(e events.Event) error {
+	if e.Kind != domain.EventApiOrganizationTrustChanged {
+		return nil
 	}
 
-	eventData, ok := e.Payload[domain.ArgEventData].(models.PotentialProviderEventData)
-	if !ok {
-		log.Errorf("PotentialProvider event payload incorrect type: %T", e.Payload[domain.ArgEventData])
-		return
-	}
-
-	var potentialProvider models.User
-	if err := potentialProvider.FindByID(models.DB, eventData.UserID); err != nil {
-		log.Errorf("unable to find PotentialProvider User %d, %s", eventData.UserID, err)
+	id, err := getID(e.Payload)
+	if err != nil {
+		return err
 	}
 
-	var request models.Request
-	if err := request.FindByID(models.DB, eventData.RequestID); err != nil {
-		log.Errorf("unable to find request %d from PotentialProvider event, %s", eventData.RequestID, err)
+	var org models.Organization
+	if err := models.DB.Find(&org, id); err != nil {
+		return fmt.Errorf("unable to find organization %d from trust-changed event, %w", id, err)
 	}
 
-	creator, err := request.Creator(models.DB)
-	if err != nil {
-		log.Errorf("unable to find request %d creator from PotentialProvider event, %s",
-			eventData.RequestID, err)
-	}
-
-	err = sendPotentialProviderCreatedNotification(potentialProvider.Nickname, creator, request)
-	if err != nil {
-		log.Errorf(err.Error())
+	if err := cache.InvalidateOrganizations(newListenerContext(), org); err != nil {
+		return fmt.Errorf("error in cache invalidation on organization trust change: %w", err)
 	}
+	return nil
 }
 
-func potentialProviderSelfDestroyed(e events.Event) {
-	if e.Kind != domain.EventApiPotentialProviderSelfDestroyed {
-		return
-	}
+// getPotentialProviderEventUsers finds the potential provider, request, and requester of a PotentialProvider event
+func getPotentialProviderEventUsers(e events.Event) (models.User, models.Request, models.User, error) {
+	var potentialProvider, creator models.User
+	var request models.Request
 
 	eventData, ok := e.Payload[domain.ArgEventData].(models.PotentialProviderEventData)
 	if !ok {
-		log.Errorf("PotentialProvider event payload incorrect type: %T", e.Payload[domain.ArgEventData])
-		return
+		return potentialProvider, request, creator,
+			fmt.Errorf("PotentialProvider event payload incorrect type: %T", e.Payload[domain.ArgEventData])
 	}
 
-	var potentialProvider models.User
 	if err := potentialProvider.FindByID(models.DB, eventData.UserID); err != nil {
-		log.Errorf("unable to find PotentialProvider User %d, %s", eventData.UserID, err)
+		return potentialProvider, request, creator,
+			fmt.Errorf("unable to find PotentialProvider User %d, %w", eventData.UserID, err)
 	}
 
-	var request models.Request
 	if err := request.FindByID(models.DB, eventData.RequestID); err != nil {
-		log.Errorf("unable to find request %d from PotentialProvider event, %s", eventData.RequestID, err)
+		return potentialProvider, request, creator,
+			fmt.Errorf("unable to find request %d from PotentialProvider event, %w", eventData.RequestID, err)
 	}
 
 	creator, err := request.Creator(models.DB)
 	if err != nil {
-		log.Errorf("unable to find request %d creator from PotentialProvider event, %s",
-			eventData.RequestID, err)
+		return potentialProvider, request, creator,
+			fmt.Errorf("unable to find request %d creator from PotentialProvider event, %w", eventData.RequestID, err)
 	}
 
-	err = sendPotentialProviderSelfDestroyedNotification(potentialProvider.Nickname, creator, request)
-	if err != nil {
-		log.Errorf(err.Error())
-	}
+	return potentialProvider, request, creator, nil
 }
 
-func potentialProviderRejected(e events.Event) {
-	if e.Kind != domain.EventApiPotentialProviderRejected {
-		return
+func potentialProviderCreated(e events.Event) error {
+	if e.Kind != domain.EventApiPotentialProviderCreated {
+		return nil
 	}
 
-	eventData, ok := e.Payload[domain.ArgEventData].(models.PotentialProviderEventData)
-	if !ok {
-		log.Errorf("PotentialProvider event payload incorrect type: %T", e.Payload[domain.ArgEventData])
-		return
+	potentialProvider, request, creator, err := getPotentialProviderEventUsers(e)
+	if err != nil {
+		return err
 	}
 
-	var potentialProvider models.User
-	if err := potentialProvider.FindByID(models.DB, eventData.UserID); err != nil {
-		log.Errorf("unable to find PotentialProvider User %d, %s", eventData.UserID, err)
-	}
+	return sendPotentialProviderCreatedNotification(eventContext(e), potentialProvider.Nickname, creator, request)
+}
 
-	var request models.Request
-	if err := request.FindByID(models.DB, eventData.RequestID); err != nil {
-		log.Errorf("unable to find request %d from PotentialProvider event, %s", eventData.RequestID, err)
+func potentialProviderSelfDestroyed(e events.Event) error {
+	if e.Kind != domain.EventApiPotentialProviderSelfDestroyed {
+		return nil
 	}
 
-	creator, err := request.Creator(models.DB)
+	potentialProvider, request, creator, err := getPotentialProviderEventUsers(e)
 	if err != nil {
-		log.Errorf("unable to find request %d creator from PotentialProvider event, %s",
-			eventData.RequestID, err)
+		return err
 	}
 
-	err = sendPotentialProviderRejectedNotification(potentialProvider, creator.Nickname, request)
+	return sendPotentialProviderSelfDestroyedNotification(eventContext(e), potentialProvider.Nickname, creator, request)
+}
+
+func potentialProviderRejected(e events.Event) error {
+	if e.Kind != domain.EventApiPotentialProviderRejected {
+		return nil
+	}
+
+	potentialProvider, request, creator, err := getPotentialProviderEventUsers(e)
 	if err != nil {
-		log.Errorf(err.Error())
+		return err
 	}
+
+	return sendPotentialProviderRejectedNotification(eventContext(e), potentialProvider, creator.Nickname, request)
 }
 
-func sendNewUserWelcome(user models.User) error {
+func sendNewUserWelcome(ctx context.Context, user models.User) error {
 	if user.Email == "" {
 		return errors.New("'To' email address is required")
 	}
@@ -393,47 +555,32 @@ func sendNewUserWelcome(user models.User) error {
 			"firstName":    user.FirstName,
 		},
 	}
-	return notifications.Send(msg)
+	return notifications.SendContext(ctx, msg)
 }
 
-func meetingInviteCreated(e events.Event) {
+func meetingInviteCreated(e events.Event) error {
 	if e.Kind != domain.EventApiMeetingInviteCreated {
-		return
+		return nil
 	}
 
 	id, err := getID(e.Payload)
 	if err != nil {
-		log.Errorf("meeting invite ID not found in payload, %s", err)
-		return
+		return fmt.Errorf("meeting invite ID not found in payload, %w", err)
 	}
 
-	foundMeetingInvite := false
 	var invite models.MeetingInvite
-	var findErr error
-
-	i := 1
-	for ; i <= domain.Env.ListenerMaxRetries; i++ {
-		findErr = invite.FindByID(models.DB, id, "Meeting", "Inviter")
-		if findErr == nil {
-			foundMeetingInvite = true
-			break
-		}
-		time.Sleep(getDelayDuration(i * i))
+	if err := invite.FindByID(models.DB, id, "Meeting", "Inviter"); err != nil {
+		return fmt.Errorf("failed to find MeetingInvite in meetingInviteCreated, %w", err)
 	}
-	log.Errorf("listener meetingInviteCreated required %d retries with delay %d", i-1, domain.Env.ListenerDelayMilliseconds)
 
-	if !foundMeetingInvite {
-		log.Errorf("failed to find MeetingInvite in meetingInviteCreated, %s", findErr)
-		return
-	}
-
-	if err = sendMeetingInvite(invite); err != nil {
-		log.Errorf("unable to send invite %d in meetingInviteCreated event, %s", invite.ID, err)
+	if err = sendMeetingInvite(eventContext(e), invite); err != nil {
+		return fmt.Errorf("unable to send invite %d in meetingInviteCreated event, %w", invite.ID, err)
 	}
+	return nil
 }
 
 // sendMeetingInvite sends an email to the invitee. The MeetingInvite must have its Meeting and Inviter hydrated.
-func sendMeetingInvite(invite models.MeetingInvite) error {
+func sendMeetingInvite(ctx context.Context, invite models.MeetingInvite) error {
 	if invite.Email == "" {
 		return errors.New("'To' email address is required")
 	}
@@ -456,10 +603,10 @@ func sendMeetingInvite(invite models.MeetingInvite) error {
 			"inviteURL":    invite.InviteURL(),
 		},
 	}
-	return notifications.Send(msg)
+	return notifications.SendContext(ctx, msg)
 }
 
-// getDelayDuration is a helper function to calculate delay in milliseconds before processing event
+// getDelayDuration is a helper function to calculate the delay before retrying delivery of an event
 func getDelayDuration(multiplier int) time.Duration {
 	return time.Duration(domain.Env.ListenerDelayMilliseconds) * time.Millisecond * time.Duration(multiplier)
 }
@@ -478,6 +625,18 @@ func getID(p events.Payload) (int, error) {
 	return id, nil
 }
 
+// getUser finds the User identified by the ID in an event payload
+func getUser(p events.Payload) (models.User, error) {
+	var user models.User
+	id, err := getID(p)
+	if err != nil {
+		return user, err
+	}
+
+	err = user.FindByID(models.DB, id)
+	return user, err
+}
+
 func newListenerContext() *listenerContext {
 	ctx := listenerContext{
 		params: map[interface{}]interface{}{},
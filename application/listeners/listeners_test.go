@@ -3,10 +3,13 @@ package listeners
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/gobuffalo/buffalo/worker"
 	"github.com/gobuffalo/envy"
 	"github.com/gobuffalo/events"
 	"github.com/gobuffalo/pop/v6"
@@ -16,6 +19,7 @@ import (
 	"github.com/silinternational/wecarry-api/cache"
 	"github.com/silinternational/wecarry-api/domain"
 	"github.com/silinternational/wecarry-api/internal/test"
+	"github.com/silinternational/wecarry-api/job"
 	"github.com/silinternational/wecarry-api/log"
 	"github.com/silinternational/wecarry-api/models"
 	"github.com/silinternational/wecarry-api/notifications"
@@ -57,16 +61,15 @@ func (ms *ModelSuite) TestRegisterListeners() {
 		log.SetOutput(os.Stderr)
 	}()
 
+	var w worker.Worker = worker.NewSimple()
+	job.Init(&w)
+
 	RegisterListener()
 	got := buf.String()
 
 	ms.Equal("", got, "Got an unexpected error log entry")
 
-	newLs, err := events.List()
-	ms.NoError(err, "Got an unexpected error listing the listeners")
-
-	gotCount := len(newLs)
-	ms.Equal(1, gotCount, "Wrong number of listeners registered")
+	ms.Error(w.Register(job.OutboxDrain, drainOutbox), "outbox handler was not registered")
 }
 
 func (ms *ModelSuite) TestUserCreated() {
@@ -77,19 +80,12 @@ func (ms *ModelSuite) TestUserCreated() {
 		log.SetOutput(os.Stdout)
 	}()
 
-	user := models.User{
-		Email:     "test@test.com",
-		FirstName: "test",
-		LastName:  "user",
-		Nickname:  "testy",
-		AdminRole: models.UserAdminRoleUser,
-		UUID:      domain.GetUUID(),
-	}
+	user := test.CreateUserFixtures(ms.DB, 1).Users[0]
 
 	e := events.Event{
 		Kind:    domain.EventApiUserCreated,
 		Message: "Nickname: " + user.Nickname + "  UUID: " + user.UUID.String(),
-		Payload: events.Payload{"user": &user},
+		Payload: events.Payload{domain.ArgId: user.ID},
 	}
 
 	notifications.TestEmailService.DeleteSentMessages()
@@ -106,6 +102,16 @@ func (ms *ModelSuite) TestUserCreated() {
 	ms.Equal(1, emailCount, "wrong email count")
 }
 
+func (ms *ModelSuite) TestUserDeletedRemoveFiles() {
+	file := test.CreateFileFixture(ms.DB)
+
+	e := events.Event{Kind: domain.EventApiUserDeleted, Payload: events.Payload{domain.ArgId: 1}}
+	ms.Error(userDeletedRemoveFiles(e), "expected an error for a payload without event data")
+
+	e.Payload[domain.ArgEventData] = models.UserDeletedEventData{FileUUIDs: []string{file.UUID.String()}}
+	ms.NoError(userDeletedRemoveFiles(e))
+}
+
 func (ms *ModelSuite) TestSendNewMessageNotification() {
 	var buf bytes.Buffer
 	log.SetOutput(&buf)
@@ -224,3 +230,75 @@ func (ms *ModelSuite) Test_cacheRequestCreatedListener() {
 	_, err := cache.GetVisibleRequests(context.Background(), models.Organizations{f.Requests[0].Organization})
 	ms.NoError(err)
 }
+
+func (ms *ModelSuite) Test_cacheRequestUpdatedListener() {
+	user := test.CreateUserFixtures(ms.DB, 1).Users[0]
+	request := test.CreateRequestFixtures(ms.DB, 1, false, user.ID)[0]
+	ms.NoError(ms.DB.Load(&request, "Organization"))
+
+	ctx := newListenerContext()
+	ctx.Set(domain.ContextKeyTx, ms.DB)
+	orgs := models.Organizations{request.Organization}
+
+	visible, err := cache.GetVisibleRequests(ctx, orgs)
+	ms.NoError(err)
+	ms.Equal(1, len(visible), "request should be visible before the change")
+
+	// bypass the model hooks so that the listener is the only cache invalidation
+	ms.NoError(ms.DB.RawQuery("UPDATE requests SET status = ? WHERE id = ?",
+		models.RequestStatusCompleted, request.ID).Exec())
+
+	ms.NoError(cacheRequestUpdatedListener(events.Event{
+		Kind:    domain.EventApiRequestUpdated,
+		Message: "Request updated",
+		Payload: events.Payload{domain.ArgEventData: models.RequestUpdatedEventData{RequestID: request.ID}},
+	}))
+
+	visible, err = cache.GetVisibleRequests(ctx, orgs)
+	ms.NoError(err)
+	ms.Equal(0, len(visible), "completed request should no longer be visible")
+}
+
+func (ms *ModelSuite) TestDrainOutbox() {
+	user := test.CreateUserFixtures(ms.DB, 1).Users[0]
+
+	now := time.Now()
+	userCreated := models.OutboxEvent{
+		IdempotencyKey: "user-created",
+		Kind:           domain.EventApiUserCreated,
+		Payload:        fmt.Sprintf(`{"%s":%d}`, domain.ArgId, user.ID),
+		AvailableAt:    now,
+	}
+	ms.NoError(ms.DB.Create(&userCreated))
+	unknown := models.OutboxEvent{
+		IdempotencyKey: "unknown",
+		Kind:           "api:unknown",
+		Payload:        "{}",
+		AvailableAt:    now,
+	}
+	ms.NoError(ms.DB.Create(&unknown))
+
+	notifications.TestEmailService.DeleteSentMessages()
+
+	ms.NoError(drainOutbox(nil))
+
+	ms.Equal(1, notifications.TestEmailService.GetNumberOfMessagesSent(), "wrong email count")
+
+	ms.NoError(ms.DB.Reload(&userCreated))
+	ms.True(userCreated.ProcessedAt.Valid, "event was not marked as processed")
+	for _, h := range eventTypes[domain.EventApiUserCreated] {
+		ms.True(userCreated.IsHandlerCompleted(h.name), "handler %s was not completed", h.name)
+	}
+
+	ms.NoError(ms.DB.Reload(&unknown))
+	ms.False(unknown.ProcessedAt.Valid, "event with no handler was marked as processed")
+	ms.Equal(1, unknown.Attempts)
+	ms.Contains(unknown.LastError, "has no handler")
+	ms.True(unknown.AvailableAt.After(now), "failed event was not postponed")
+
+	// a second drain does not redeliver anything
+	ms.NoError(drainOutbox(nil))
+	ms.Equal(1, notifications.TestEmailService.GetNumberOfMessagesSent(), "event was redelivered")
+	ms.NoError(ms.DB.Reload(&unknown))
+	ms.Equal(1, unknown.Attempts, "failed event was retried before its delay")
+}
@@ -1,6 +1,7 @@
 package listeners
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -124,7 +125,7 @@ func sendNotificationRequestToProvider(params senderParams) {
 	msg.Subject = domain.GetTranslatedSubject(requestUsers.Provider.Language, params.subject,
 		map[string]string{requestTitleKey: request.Title})
 
-	if err := notifications.Send(msg); err != nil {
+	if err := notifications.SendContext(params.ctx, msg); err != nil {
 		log.Errorf("error sending '%s' notification, %s", template, err)
 	}
 }
@@ -144,7 +145,7 @@ func sendNotificationRequestToReceiver(params senderParams) {
 	msg.Subject = domain.GetTranslatedSubject(requestUsers.Receiver.Language, params.subject,
 		map[string]string{requestTitleKey: request.Title})
 
-	if err := notifications.Send(msg); err != nil {
+	if err := notifications.SendContext(params.ctx, msg); err != nil {
 		log.Errorf("error sending '%s' notification, %s", template, err)
 	}
 }
@@ -167,6 +168,20 @@ func sendNotificationRequestFromAcceptedToOpen(params senderParams) {
 		return
 	}
 
+	// the provider deleted their account, so it is the requester who needs to know
+	if oldProvider.IsDeleted() {
+		requester, err := request.GetCreator(models.DB)
+		if err != nil {
+			log.Errorf("error preparing '%s' notification for requester, %s", template, err)
+			return
+		}
+		if err := sendPotentialProviderSelfDestroyedNotification(params.ctx, oldProvider.Nickname, *requester,
+			request); err != nil {
+			log.Errorf("error sending '%s' notification, %s", domain.MessageTemplatePotentialProviderSelfDestroyed, err)
+		}
+		return
+	}
+
 	msg := getMessageForProvider(requestUsers, request, template)
 
 	msg.ToName = oldProvider.GetRealName()
@@ -174,7 +189,7 @@ func sendNotificationRequestFromAcceptedToOpen(params senderParams) {
 	msg.Subject = domain.GetTranslatedSubject(oldProvider.GetLanguagePreference(models.DB), params.subject,
 		map[string]string{requestTitleKey: request.Title})
 
-	if err := notifications.Send(msg); err != nil {
+	if err := notifications.SendContext(params.ctx, msg); err != nil {
 		log.Errorf("error sending '%s' notification, %s", template, err)
 	}
 }
@@ -187,7 +202,7 @@ func sendNotificationRequestFromAcceptedToRemoved(params senderParams) {
 	sendNotificationRequestToProvider(params)
 }
 
-func sendRejectionToPotentialProvider(potentialProvider models.User, request models.Request) {
+func sendRejectionToPotentialProvider(ctx context.Context, potentialProvider models.User, request models.Request) {
 	template := domain.MessageTemplatePotentialProviderRejected
 	ppNickname := potentialProvider.Nickname
 	ppEmail := potentialProvider.Email
@@ -224,7 +239,7 @@ func sendRejectionToPotentialProvider(potentialProvider models.User, request mod
 			map[string]string{requestTitleKey: request.Title}),
 	}
 
-	if err := notifications.Send(msg); err != nil {
+	if err := notifications.SendContext(ctx, msg); err != nil {
 		log.Errorf("error sending '%s' notification to rejected potentialProvider, %s", template, err)
 	}
 }
@@ -242,7 +257,7 @@ func sendNotificationRequestFromOpenToAccepted(params senderParams) {
 
 	for _, u := range users {
 		if u.ID != request.ProviderID.Int {
-			sendRejectionToPotentialProvider(u, request)
+			sendRejectionToPotentialProvider(params.ctx, u, request)
 		}
 	}
 }
@@ -259,7 +274,12 @@ func sendNotificationEmpty(params senderParams) {
 	log.Errorf("Notification not implemented yet for %s", params.template)
 }
 
+// sendNotificationNone is for transitions that need no notification, or whose notifications are sent by the job that
+// made the change
+func sendNotificationNone(params senderParams) {}
+
 type senderParams struct {
+	ctx        context.Context
 	template   string
 	subject    string
 	request    models.Request
@@ -343,6 +363,18 @@ var statusSenders = map[string]sender{
 		sender:   sendNotificationRequestFromOpenToAccepted,
 	},
 
+	join(models.RequestStatusOpen, models.RequestStatusExpired): {
+		sender: sendNotificationNone,
+	},
+
+	join(models.RequestStatusExpired, models.RequestStatusOpen): {
+		sender: sendNotificationNone,
+	},
+
+	join(models.RequestStatusExpired, models.RequestStatusRemoved): {
+		sender: sendNotificationNone,
+	},
+
 	join(models.RequestStatusReceived, models.RequestStatusCompleted): {
 		template: domain.MessageTemplateRequestFromReceivedToCompleted,
 		subject:  "",
@@ -350,7 +382,7 @@ var statusSenders = map[string]sender{
 	},
 }
 
-func requestStatusUpdatedNotifications(request models.Request, eData models.RequestStatusEventData) {
+func requestStatusUpdatedNotifications(ctx context.Context, request models.Request, eData models.RequestStatusEventData) {
 	fromStatusTo := join(eData.OldStatus, eData.NewStatus)
 	sender, ok := statusSenders[fromStatusTo]
 
@@ -360,6 +392,7 @@ func requestStatusUpdatedNotifications(request models.Request, eData models.Requ
 	}
 
 	params := senderParams{
+		ctx:        ctx,
 		template:   notifications.GetEmailTemplate(sender.template),
 		subject:    sender.subject,
 		request:    request,
@@ -369,20 +402,16 @@ func requestStatusUpdatedNotifications(request models.Request, eData models.Requ
 	sender.sender(params)
 }
 
-func sendNewRequestNotifications(request models.Request, users models.Users) {
+func sendNewRequestNotifications(ctx context.Context, request models.Request, users models.Users) {
 	for i, user := range users {
-		if !user.WantsRequestNotification(models.DB, request) {
-			continue
-		}
-
-		if err := sendNewRequestNotification(user, request); err != nil {
+		if err := sendNewRequestNotification(ctx, user, request); err != nil {
 			log.Errorf("error sending request created notification (%d of %d), %s",
 				i, len(users), err)
 		}
 	}
 }
 
-func sendNewRequestNotification(user models.User, request models.Request) error {
+func sendNewRequestNotification(ctx context.Context, user models.User, request models.Request) error {
 	if user.Email == "" {
 		return errors.New("'To' email address is required")
 	}
@@ -418,27 +447,27 @@ func sendNewRequestNotification(user models.User, request models.Request) error
 			"requestDestination": requestDestination,
 		},
 	}
-	return notifications.Send(msg)
+	return notifications.SendContext(ctx, msg)
 }
 
-func sendPotentialProviderCreatedNotification(providerNickname string, requester models.User, request models.Request) error {
+func sendPotentialProviderCreatedNotification(ctx context.Context, providerNickname string, requester models.User, request models.Request) error {
 	template := domain.MessageTemplatePotentialProviderCreated
 	msg := getPotentialProviderMessageForReceiver(requester, providerNickname, template, request)
 	msg.Subject = domain.GetTranslatedSubject(requester.GetLanguagePreference(models.DB),
 		"Email.Subject.Request.NewOffer", map[string]string{})
 
-	return notifications.Send(msg)
+	return notifications.SendContext(ctx, msg)
 }
 
-func sendPotentialProviderSelfDestroyedNotification(providerNickname string, requester models.User, request models.Request) error {
+func sendPotentialProviderSelfDestroyedNotification(ctx context.Context, providerNickname string, requester models.User, request models.Request) error {
 	template := domain.MessageTemplatePotentialProviderSelfDestroyed
 	msg := getPotentialProviderMessageForReceiver(requester, providerNickname, template, request)
 	msg.Subject = domain.GetTranslatedSubject(requester.GetLanguagePreference(models.DB),
 		"Email.Subject.Request.OfferRetracted", map[string]string{})
-	return notifications.Send(msg)
+	return notifications.SendContext(ctx, msg)
 }
 
-func sendPotentialProviderRejectedNotification(provider models.User, requester string, request models.Request) error {
+func sendPotentialProviderRejectedNotification(ctx context.Context, provider models.User, requester string, request models.Request) error {
 	msg := notifications.Message{
 		Subject: domain.GetTranslatedSubject(provider.GetLanguagePreference(models.DB),
 			"Email.Subject.Request.OfferRejected", map[string]string{}),
@@ -454,5 +483,5 @@ func sendPotentialProviderRejectedNotification(provider models.User, requester s
 			"receiverNickname": requester,
 		},
 	}
-	return notifications.Send(msg)
+	return notifications.SendContext(ctx, msg)
 }
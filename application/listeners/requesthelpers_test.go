@@ -2,6 +2,7 @@ package listeners
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -93,7 +94,7 @@ func (ms *ModelSuite) TestRequestStatusUpdatedNotifications() {
 	}()
 
 	// No logging message expected
-	requestStatusUpdatedNotifications(requests[0], requestStatusEData)
+	requestStatusUpdatedNotifications(context.Background(), requests[0], requestStatusEData)
 
 	got := buf.String()
 	ms.Equal("", got, "Got an unexpected error log entry")
@@ -102,7 +103,7 @@ func (ms *ModelSuite) TestRequestStatusUpdatedNotifications() {
 
 	// Logging message expected about bad transition
 	requestStatusEData.NewStatus = models.RequestStatusDelivered
-	requestStatusUpdatedNotifications(requests[0], requestStatusEData)
+	requestStatusUpdatedNotifications(context.Background(), requests[0], requestStatusEData)
 
 	// got = buf.String()
 	// want := "unexpected request status transition 'OPEN-DELIVERED'"
@@ -324,7 +325,7 @@ func (ms *ModelSuite) TestSendNewRequestNotification() {
 		ms.T().Run(nextT.name, func(t *testing.T) {
 			notifications.TestEmailService.DeleteSentMessages()
 
-			err := sendNewRequestNotification(nextT.user, nextT.request)
+			err := sendNewRequestNotification(context.Background(), nextT.user, nextT.request)
 			if nextT.wantErr != "" {
 				ms.Error(err)
 				ms.Contains(err.Error(), nextT.wantErr)
@@ -387,7 +388,7 @@ func (ms *ModelSuite) TestSendNewRequestNotifications() {
 		t.Run(test.name, func(t *testing.T) {
 			notifications.TestEmailService.DeleteSentMessages()
 
-			sendNewRequestNotifications(test.request, test.users)
+			sendNewRequestNotifications(context.Background(), test.request, test.users)
 
 			emailCount := notifications.TestEmailService.GetNumberOfMessagesSent()
 			ms.Equal(test.wantEmailCount, emailCount, "wrong email count")
@@ -415,7 +416,7 @@ func (ms *ModelSuite) TestSendPotentialProviderCreatedNotification() {
 
 	notifications.TestEmailService.DeleteSentMessages()
 
-	err := sendPotentialProviderCreatedNotification(provider, requester, request)
+	err := sendPotentialProviderCreatedNotification(context.Background(), provider, requester, request)
 	ms.NoError(err)
 
 	emailCount := notifications.TestEmailService.GetNumberOfMessagesSent()
@@ -442,7 +443,7 @@ func (ms *ModelSuite) TestSendPotentialProviderSelfDestroyedNotification() {
 
 	notifications.TestEmailService.DeleteSentMessages()
 
-	err := sendPotentialProviderSelfDestroyedNotification(provider, requester, request)
+	err := sendPotentialProviderSelfDestroyedNotification(context.Background(), provider, requester, request)
 	ms.NoError(err)
 
 	emailCount := notifications.TestEmailService.GetNumberOfMessagesSent()
@@ -469,7 +470,7 @@ func (ms *ModelSuite) TestSendPotentialProviderRejectedNotification() {
 
 	notifications.TestEmailService.DeleteSentMessages()
 
-	err := sendPotentialProviderRejectedNotification(provider, requester, request)
+	err := sendPotentialProviderRejectedNotification(context.Background(), provider, requester, request)
 	ms.NoError(err)
 
 	emailCount := notifications.TestEmailService.GetNumberOfMessagesSent()
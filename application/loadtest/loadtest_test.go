@@ -0,0 +1,253 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// TestSuite establishes a test suite for the load tester
+type TestSuite struct {
+	suite.Suite
+}
+
+// Test_TestSuite runs the test suite
+func Test_TestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+func (ts *TestSuite) TestScenarios() {
+	names := Scenarios()
+	ts.Contains(names, "mixed")
+
+	for _, name := range names {
+		s, err := LoadScenario(name)
+		ts.NoError(err, name)
+		ts.NotEmpty(s.Steps, name)
+	}
+
+	_, err := LoadScenario("no-such-scenario")
+	ts.Error(err)
+}
+
+func (ts *TestSuite) TestParseScenario() {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "good",
+			content: `{"method": "get", "path": "/requests/{{request}}", "weight": 1}`,
+		},
+		{
+			name:    "unknown field",
+			content: `{"method": "GET", "path": "/requests/", "weight": 1, "wieght": 2}`,
+			wantErr: true,
+		},
+		{
+			name:    "no weight",
+			content: `{"method": "GET", "path": "/requests/"}`,
+			wantErr: true,
+		},
+		{
+			name:    "only setup",
+			content: `{"method": "GET", "path": "/users/me", "setup": true}`,
+			wantErr: true,
+		},
+		{
+			name:    "relative path",
+			content: `{"method": "GET", "path": "requests/", "weight": 1}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		ts.Run(tt.name, func() {
+			s, err := ParseScenario(tt.name, strings.NewReader(tt.content))
+			if tt.wantErr {
+				ts.Error(err)
+				return
+			}
+			ts.NoError(err)
+			ts.Equal("GET", s.Steps[0].Method)
+			ts.Equal("GET /requests/{{request}}", s.Steps[0].Name)
+			ts.Equal([]string{"request"}, s.Steps[0].refs)
+		})
+	}
+}
+
+func (ts *TestSuite) TestExtract() {
+	var data interface{}
+	ts.NoError(json.Unmarshal([]byte(`{"id": "u1", "organizations": [{"id": "o1"}, {"id": "o2"}]}`), &data))
+
+	ts.Equal([]interface{}{"u1"}, extract(data, "id"))
+	ts.Equal([]interface{}{"o1", "o2"}, extract(data, "organizations[].id"))
+	ts.Len(extract(data, "organizations[]"), 2)
+	ts.Empty(extract(data, "missing"))
+	ts.Empty(extract(data, "id[]"))
+
+	ts.NoError(json.Unmarshal([]byte(`[{"id": "r1"}, {"id": "r2"}]`), &data))
+	ts.Equal([]interface{}{"r1", "r2"}, extract(data, "[].id"))
+}
+
+func (ts *TestSuite) TestExpand() {
+	thread := map[string]interface{}{"id": "t1", "request": map[string]interface{}{"id": "r/1"}}
+	picked := map[string]poolValue{
+		"thread": {value: thread},
+		"text":   {value: `say "hi"`},
+	}
+
+	ts.Equal("/threads/t1/requests/r%2F1/7",
+		expand("/threads/{{thread.id}}/requests/{{thread.request.id}}/{{n}}", picked, "7", url.PathEscape))
+	ts.Equal(`{"content": "say \"hi\""}`, expand(`{"content": "{{text}}"}`, picked, "1", jsonEscape))
+	ts.Equal(`{"r": {"id":"r/1"}}`, expand(`{"r": {{thread.request}}}`, picked, "1", jsonEscape))
+
+	date := expand("{{needed_before}}", nil, "1", jsonEscape)
+	neededBefore, err := time.Parse("2006-01-02", date)
+	ts.NoError(err)
+	ts.True(neededBefore.After(time.Now().AddDate(0, 0, 7)))
+}
+
+func (ts *TestSuite) TestPools() {
+	u := &virtualUser{pools: map[string][]poolValue{}}
+	step := Step{refs: []string{"request"}}
+	ts.False(u.runnable(step))
+
+	for i := 0; i < poolSize+10; i++ {
+		u.add("request", float64(i))
+	}
+	u.add("request", float64(poolSize+9))
+	ts.Len(u.pools["request"], poolSize, "pool should be capped, without duplicates")
+	ts.Equal("10", u.pools["request"][0].key, "oldest values should be dropped")
+	ts.True(u.runnable(step))
+
+	u.add("mine", float64(50))
+	u.remove("50")
+	ts.Len(u.pools["request"], poolSize-1)
+	ts.Empty(u.pools["mine"])
+}
+
+func (ts *TestSuite) TestPercentile() {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	ts.Equal(time.Duration(0), percentile(nil, 50))
+	ts.Equal(50*time.Millisecond, percentile(latencies, 50))
+	ts.Equal(99*time.Millisecond, percentile(latencies, 99))
+	ts.Equal(100*time.Millisecond, percentile(latencies, 100))
+	ts.Equal(time.Millisecond, percentile(latencies[:1], 95))
+}
+
+func (ts *TestSuite) TestRun() {
+	var mu sync.Mutex
+	created := map[string]bool{}
+	var bodies []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token0" && r.Header.Get("Authorization") != "Bearer token1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/users/me":
+			_, _ = w.Write([]byte(`{"organizations": [{"id": "org1"}]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/requests/":
+			var body bytes.Buffer
+			_, _ = body.ReadFrom(r.Body)
+			bodies = append(bodies, body.String())
+			id := fmt.Sprintf("req%d", len(bodies))
+			created[id] = true
+			_, _ = w.Write([]byte(`{"id": "` + id + `"}`))
+		case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/status"):
+			id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/requests/"), "/status")
+			if !created[id] {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			delete(created, id)
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/requests/":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	s, err := LoadScenario("create")
+	ts.NoError(err)
+
+	report, err := Run(context.Background(), s, Config{
+		BaseURL:     server.URL,
+		Tokens:      []string{"token0", "token1"},
+		Concurrency: 2,
+		Duration:    10 * time.Second,
+		Requests:    200,
+	})
+	ts.NoError(err)
+	ts.Equal(0, report.StuckUsers)
+
+	routes := map[string]RouteReport{}
+	for _, rt := range report.Routes {
+		routes[rt.Name] = rt
+	}
+
+	ts.Equal(2, routes["me"].Requests)
+	ts.Equal(0, routes["me"].Errors)
+	ts.Greater(routes["create request"].Requests, 0)
+	ts.Equal(0, routes["create request"].Errors)
+	ts.Greater(routes["remove request"].Requests, 0)
+	ts.Equal(0, routes["remove request"].Errors, "removed requests should be consumed from the pool")
+	ts.Equal(routes["list requests"].Requests, routes["list requests"].Errors)
+	ts.Equal(1.0, routes["list requests"].ErrorRate)
+	ts.Contains(routes["list requests"].FirstError, "500")
+	ts.Equal(200, report.Requests-routes["me"].Requests)
+
+	ts.Contains(bodies[0], `"org_id": "org1"`)
+	ts.NotContains(bodies[0], "{{")
+
+	var text bytes.Buffer
+	ts.NoError(report.WriteText(&text))
+	ts.Contains(text.String(), "create request")
+	ts.Contains(text.String(), "500:")
+
+	var decoded Report
+	var j bytes.Buffer
+	ts.NoError(report.WriteJSON(&j))
+	ts.NoError(json.Unmarshal(j.Bytes(), &decoded))
+	ts.Equal(report.Requests, decoded.Requests)
+}
+
+func (ts *TestSuite) TestConfigValidate() {
+	good := Config{BaseURL: "http://localhost:3000", Tokens: []string{"t"}, Concurrency: 1, Duration: time.Second}
+	ts.NoError(good.Validate())
+
+	bad := good
+	bad.Tokens = nil
+	ts.Error(bad.Validate())
+
+	bad = good
+	bad.Concurrency = 0
+	ts.Error(bad.Validate())
+
+	bad = good
+	bad.Duration = 0
+	ts.Error(bad.Validate())
+	bad.Requests = 10
+	ts.NoError(bad.Validate())
+}
@@ -0,0 +1,213 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Report is the result of a load test
+type Report struct {
+	Scenario string  `json:"scenario"`
+	Seconds  float64 `json:"seconds"`
+	Requests int     `json:"requests"`
+	Errors   int     `json:"errors"`
+
+	// RequestsPerSecond is the throughput of all routes
+	RequestsPerSecond float64 `json:"requests_per_second"`
+
+	// StuckUsers is the number of virtual users that stopped because none of their steps could be run
+	StuckUsers int `json:"stuck_users"`
+
+	Routes []RouteReport `json:"routes"`
+}
+
+// RouteReport is the result of one step of a scenario. Latencies are in milliseconds.
+type RouteReport struct {
+	Name      string      `json:"name"`
+	Requests  int         `json:"requests"`
+	Errors    int         `json:"errors"`
+	ErrorRate float64     `json:"error_rate"`
+	Skipped   int         `json:"skipped,omitempty"`
+	Statuses  map[int]int `json:"statuses"`
+	P50       float64     `json:"p50_ms"`
+	P90       float64     `json:"p90_ms"`
+	P95       float64     `json:"p95_ms"`
+	P99       float64     `json:"p99_ms"`
+	Max       float64     `json:"max_ms"`
+
+	// FirstError describes the first unexpected response, to help find its cause
+	FirstError string `json:"first_error,omitempty"`
+}
+
+// recorder collects the results of the calls of all virtual users
+type recorder struct {
+	sync.Mutex
+	routes     map[string]*route
+	stuckUsers int
+}
+
+type route struct {
+	latencies  []time.Duration
+	errors     int
+	skipped    int
+	statuses   map[int]int
+	firstError string
+}
+
+func newRecorder() *recorder {
+	return &recorder{routes: map[string]*route{}}
+}
+
+func (r *recorder) route(name string) *route {
+	rt, ok := r.routes[name]
+	if !ok {
+		rt = &route{statuses: map[int]int{}}
+		r.routes[name] = rt
+	}
+	return rt
+}
+
+// record adds the result of a call. A non-empty problem marks the call as an error.
+func (r *recorder) record(name string, status int, latency time.Duration, problem string) {
+	r.Lock()
+	defer r.Unlock()
+
+	rt := r.route(name)
+	rt.latencies = append(rt.latencies, latency)
+	rt.statuses[status]++
+	if problem != "" {
+		rt.errors++
+		if rt.firstError == "" {
+			rt.firstError = problem
+		}
+	}
+}
+
+// skip counts a setup step that could not be run
+func (r *recorder) skip(name string) {
+	r.Lock()
+	defer r.Unlock()
+	r.route(name).skipped++
+}
+
+// stuck counts a virtual user that has no step it can run
+func (r *recorder) stuck() {
+	r.Lock()
+	defer r.Unlock()
+	r.stuckUsers++
+}
+
+func (r *recorder) report(scenario string, elapsed time.Duration) Report {
+	r.Lock()
+	defer r.Unlock()
+
+	report := Report{
+		Scenario:   scenario,
+		Seconds:    elapsed.Seconds(),
+		StuckUsers: r.stuckUsers,
+	}
+	for name, rt := range r.routes {
+		sort.Slice(rt.latencies, func(i, j int) bool { return rt.latencies[i] < rt.latencies[j] })
+		rr := RouteReport{
+			Name:       name,
+			Requests:   len(rt.latencies),
+			Errors:     rt.errors,
+			Skipped:    rt.skipped,
+			Statuses:   rt.statuses,
+			P50:        milliseconds(percentile(rt.latencies, 50)),
+			P90:        milliseconds(percentile(rt.latencies, 90)),
+			P95:        milliseconds(percentile(rt.latencies, 95)),
+			P99:        milliseconds(percentile(rt.latencies, 99)),
+			Max:        milliseconds(percentile(rt.latencies, 100)),
+			FirstError: rt.firstError,
+		}
+		if rr.Requests > 0 {
+			rr.ErrorRate = float64(rr.Errors) / float64(rr.Requests)
+		}
+		report.Requests += rr.Requests
+		report.Errors += rr.Errors
+		report.Routes = append(report.Routes, rr)
+	}
+	sort.Slice(report.Routes, func(i, j int) bool { return report.Routes[i].Name < report.Routes[j].Name })
+	if report.Seconds > 0 {
+		report.RequestsPerSecond = float64(report.Requests) / report.Seconds
+	}
+	return report
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*10) / 10
+}
+
+// WriteJSON writes the report as indented JSON
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteText writes the report as a table with one row per route, followed by the first error of each route
+func (r Report) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "scenario %s: %d requests in %.1fs (%.1f/s), %d errors\n",
+		r.Scenario, r.Requests, r.Seconds, r.RequestsPerSecond, r.Errors)
+	if r.StuckUsers > 0 {
+		fmt.Fprintf(w, "%d virtual users stopped because none of their steps could be run\n", r.StuckUsers)
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "route\trequests\terrors\terror %\tp50 ms\tp90 ms\tp95 ms\tp99 ms\tmax ms\tstatuses\t")
+	for _, rt := range r.Routes {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t%s\t\n", rt.Name, rt.Requests, rt.Errors,
+			rt.ErrorRate*100, rt.P50, rt.P90, rt.P95, rt.P99, rt.Max, formatStatuses(rt.Statuses))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, rt := range r.Routes {
+		if rt.Skipped > 0 {
+			fmt.Fprintf(w, "\n%s: skipped %d times because a value it needs was not captured", rt.Name, rt.Skipped)
+		}
+		if rt.FirstError != "" {
+			fmt.Fprintf(w, "\n%s: first error: %s", rt.Name, rt.FirstError)
+		}
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// formatStatuses lists the counts of response statuses, e.g. "200:95 400:5". Status 0 means there was no response.
+func formatStatuses(statuses map[int]int) string {
+	codes := make([]int, 0, len(statuses))
+	for code := range statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	parts := make([]string, len(codes))
+	for i, code := range codes {
+		parts[i] = strconv.Itoa(code) + ":" + strconv.Itoa(statuses[code])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,389 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// poolSize is the most values kept in each pool of a virtual user
+const poolSize = 100
+
+// Config is the configuration of a load test
+type Config struct {
+	// BaseURL is the address of the API, e.g. http://localhost:3000
+	BaseURL string
+
+	// Tokens are the access tokens of the users. Each virtual user uses one of them, in turn.
+	Tokens []string
+
+	// Concurrency is the number of virtual users
+	Concurrency int
+
+	// Duration limits the length of the test
+	Duration time.Duration
+
+	// Requests limits the number of calls of all virtual users, not counting setup steps. Zero means no limit.
+	Requests int
+
+	// ThinkTime is the pause of each virtual user between calls
+	ThinkTime time.Duration
+
+	// Seed makes the choice of steps and values repeatable
+	Seed int64
+
+	// Client is the HTTP client. It defaults to a client with a 30 second timeout.
+	Client *http.Client
+}
+
+// Validate checks that the configuration can be used to run a test
+func (c Config) Validate() error {
+	if _, err := url.ParseRequestURI(c.BaseURL); err != nil {
+		return fmt.Errorf("invalid base URL '%s', %w", c.BaseURL, err)
+	}
+	if len(c.Tokens) == 0 {
+		return errors.New("at least one access token is required")
+	}
+	if c.Concurrency < 1 {
+		return errors.New("concurrency must be at least 1")
+	}
+	if c.Duration <= 0 && c.Requests <= 0 {
+		return errors.New("a duration or a number of requests is required")
+	}
+	return nil
+}
+
+// Run runs the scenario until the duration or the number of requests is reached, or the context is canceled
+func Run(ctx context.Context, s Scenario, cfg Config) (Report, error) {
+	if err := cfg.Validate(); err != nil {
+		return Report{}, err
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	r := runner{
+		scenario: s,
+		cfg:      cfg,
+		baseURL:  strings.TrimSuffix(cfg.BaseURL, "/"),
+		recorder: newRecorder(),
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r.user(ctx, i)
+		}(i)
+	}
+	wg.Wait()
+
+	return r.recorder.report(s.Name, time.Since(start)), nil
+}
+
+type runner struct {
+	scenario Scenario
+	cfg      Config
+	baseURL  string
+	recorder *recorder
+	sent     int64
+	counter  int64
+}
+
+// virtualUser is the state of one virtual user
+type virtualUser struct {
+	token string
+	rng   *rand.Rand
+	pools map[string][]poolValue
+}
+
+// poolValue is a captured value, with its JSON encoding to tell values apart
+type poolValue struct {
+	key   string
+	value interface{}
+}
+
+// user runs the steps of one virtual user
+func (r *runner) user(ctx context.Context, i int) {
+	u := &virtualUser{
+		token: r.cfg.Tokens[i%len(r.cfg.Tokens)],
+		rng:   rand.New(rand.NewSource(r.cfg.Seed + int64(i))),
+		pools: map[string][]poolValue{},
+	}
+
+	for _, step := range r.scenario.Setup {
+		if ctx.Err() != nil {
+			return
+		}
+		if !u.runnable(step) {
+			r.recorder.skip(step.Name)
+			continue
+		}
+		r.run(ctx, u, step)
+	}
+
+	for ctx.Err() == nil {
+		step, ok := u.pick(r.scenario.Steps)
+		if !ok {
+			r.recorder.stuck()
+			return
+		}
+		if r.cfg.Requests > 0 && atomic.AddInt64(&r.sent, 1) > int64(r.cfg.Requests) {
+			return
+		}
+		r.run(ctx, u, step)
+
+		if r.cfg.ThinkTime > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(r.cfg.ThinkTime):
+			}
+		}
+	}
+}
+
+// run makes the call of a step, records the result, and captures values from the response
+func (r *runner) run(ctx context.Context, u *virtualUser, step Step) {
+	picked := u.choose(step)
+	n := strconv.FormatInt(atomic.AddInt64(&r.counter, 1), 10)
+
+	path := expand(step.Path, picked, n, url.PathEscape)
+	var body io.Reader
+	if len(step.Body) > 0 {
+		body = strings.NewReader(expand(string(step.Body), picked, n, jsonEscape))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, step.Method, r.baseURL+path, body)
+	if err != nil {
+		r.recorder.record(step.Name, 0, 0, err.Error())
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+u.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	start := time.Now()
+	resp, err := r.cfg.Client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.recorder.record(step.Name, 0, time.Since(start), err.Error())
+		}
+		return
+	}
+	content, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	elapsed := time.Since(start)
+	if err != nil {
+		r.recorder.record(step.Name, resp.StatusCode, elapsed, err.Error())
+		return
+	}
+
+	if !step.expects(resp.StatusCode) {
+		r.recorder.record(step.Name, resp.StatusCode, elapsed, fmt.Sprintf("%s %s: %d %s",
+			step.Method, path, resp.StatusCode, truncate(string(content), 200)))
+		return
+	}
+	r.recorder.record(step.Name, resp.StatusCode, elapsed, "")
+
+	if step.Consume {
+		for _, v := range picked {
+			u.remove(v.key)
+		}
+	}
+	if len(step.Capture) > 0 {
+		var data interface{}
+		if err := json.Unmarshal(content, &data); err != nil {
+			return
+		}
+		for name, path := range step.Capture {
+			for _, v := range extract(data, path) {
+				u.add(name, v)
+			}
+		}
+	}
+}
+
+// expects returns true if the status is expected for the step
+func (s Step) expects(status int) bool {
+	if len(s.Expect) == 0 {
+		return status >= 200 && status < 300
+	}
+	for _, e := range s.Expect {
+		if e == status {
+			return true
+		}
+	}
+	return false
+}
+
+// runnable returns true if the pools of all the values the step refers to have a value
+func (u *virtualUser) runnable(step Step) bool {
+	for _, ref := range step.refs {
+		if len(u.pools[ref]) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// pick chooses a runnable step at random in proportion to the weights
+func (u *virtualUser) pick(steps []Step) (Step, bool) {
+	total := 0
+	for _, step := range steps {
+		if u.runnable(step) {
+			total += step.Weight
+		}
+	}
+	if total == 0 {
+		return Step{}, false
+	}
+
+	n := u.rng.Intn(total)
+	for _, step := range steps {
+		if !u.runnable(step) {
+			continue
+		}
+		if n < step.Weight {
+			return step, true
+		}
+		n -= step.Weight
+	}
+	return Step{}, false
+}
+
+// choose picks a random value of each pool the step refers to
+func (u *virtualUser) choose(step Step) map[string]poolValue {
+	picked := make(map[string]poolValue, len(step.refs))
+	for _, ref := range step.refs {
+		pool := u.pools[ref]
+		picked[ref] = pool[u.rng.Intn(len(pool))]
+	}
+	return picked
+}
+
+// add adds a value to a pool, unless it is already there. The oldest value is dropped from a full pool.
+func (u *virtualUser) add(name string, value interface{}) {
+	key, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	pool := u.pools[name]
+	for _, v := range pool {
+		if v.key == string(key) {
+			return
+		}
+	}
+	if len(pool) >= poolSize {
+		pool = pool[1:]
+	}
+	u.pools[name] = append(pool, poolValue{key: string(key), value: value})
+}
+
+// remove removes a value from all the pools
+func (u *virtualUser) remove(key string) {
+	for name, pool := range u.pools {
+		kept := pool[:0]
+		for _, v := range pool {
+			if v.key != key {
+				kept = append(kept, v)
+			}
+		}
+		u.pools[name] = kept
+	}
+}
+
+// expand replaces the placeholders in a template with the chosen values. Values that are not strings are written as
+// JSON.
+func expand(template string, picked map[string]poolValue, n string, escape func(string) string) string {
+	return placeholder.ReplaceAllStringFunc(template, func(m string) string {
+		parts := placeholder.FindStringSubmatch(m)
+		switch parts[1] {
+		case "n":
+			return n
+		case "now":
+			return time.Now().UTC().Format(time.RFC3339)
+		case "needed_before":
+			return time.Now().AddDate(0, 0, 28).Format("2006-01-02")
+		}
+
+		value := picked[parts[1]].value
+		if parts[2] != "" {
+			for _, field := range strings.Split(parts[2][1:], ".") {
+				obj, _ := value.(map[string]interface{})
+				value = obj[field]
+			}
+		}
+		if s, ok := value.(string); ok {
+			return escape(s)
+		}
+		j, _ := json.Marshal(value)
+		return string(j)
+	})
+}
+
+// extract selects values from decoded JSON by a dot-separated path, in which a field name ending in [] selects each
+// element of an array
+func extract(data interface{}, path string) []interface{} {
+	values := []interface{}{data}
+	if path == "" {
+		return values
+	}
+	for _, segment := range strings.Split(path, ".") {
+		each := strings.HasSuffix(segment, "[]")
+		field := strings.TrimSuffix(segment, "[]")
+
+		var next []interface{}
+		for _, v := range values {
+			if field != "" {
+				obj, ok := v.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				v = obj[field]
+			}
+			if !each {
+				if v != nil {
+					next = append(next, v)
+				}
+				continue
+			}
+			if list, ok := v.([]interface{}); ok {
+				next = append(next, list...)
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// jsonEscape escapes a string to be placed inside a quoted JSON string
+func jsonEscape(s string) string {
+	j, _ := json.Marshal(s)
+	return string(j[1 : len(j)-1])
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
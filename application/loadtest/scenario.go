@@ -0,0 +1,158 @@
+// Package loadtest replays a mix of API calls by many users against a running API, and reports the latency and error
+// rate of each kind of call.
+//
+// A scenario is a JSON Lines file with one step per line. Each virtual user runs the setup steps once, and then runs
+// the other steps at random in proportion to their weights. A step can capture values from its response into named
+// pools, and later steps refer to a random value of a pool with {{name}}, or to a field of it with {{name.field}}.
+// A step that refers to an empty pool is not run until the pool has a value. The built-in placeholders are {{n}}, a
+// number that is different for each call, {{now}}, the current time, and {{needed_before}}, a date four weeks ahead.
+package loadtest
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+//go:embed scenarios/*.jsonl
+var scenarios embed.FS
+
+// Step is one kind of API call in a scenario
+type Step struct {
+	// Name identifies the step in the report. It defaults to the method and path.
+	Name string `json:"name"`
+
+	Method string `json:"method"`
+	Path   string `json:"path"`
+
+	// Body is the JSON request body, if any
+	Body json.RawMessage `json:"body,omitempty"`
+
+	// Weight is the relative frequency of the step. Setup steps do not have a weight.
+	Weight int `json:"weight"`
+
+	// Setup steps are run once by each virtual user, in order, before the other steps
+	Setup bool `json:"setup,omitempty"`
+
+	// Capture adds values of the response to pools, by pool name. The value is selected by a dot-separated path,
+	// in which a field name ending in [] selects each element of an array, e.g. "organizations[]" or "[].id".
+	Capture map[string]string `json:"capture,omitempty"`
+
+	// Consume removes the values used by the step from their pools, e.g. for a request that is removed by the step
+	Consume bool `json:"consume,omitempty"`
+
+	// Expect lists the expected response statuses. By default, any 2xx status is expected.
+	Expect []int `json:"expect,omitempty"`
+
+	refs []string
+}
+
+// Scenario is a named set of steps
+type Scenario struct {
+	Name  string
+	Setup []Step
+	Steps []Step
+}
+
+var placeholder = regexp.MustCompile(`{{\s*([a-z_]+)(\.[a-z_.]+)?\s*}}`)
+
+// builtins are the placeholders that do not refer to a pool
+var builtins = map[string]bool{
+	"n":             true,
+	"needed_before": true,
+	"now":           true,
+}
+
+// Scenarios returns the names of the scenarios that are built into the command
+func Scenarios() []string {
+	entries, _ := scenarios.ReadDir("scenarios")
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = strings.TrimSuffix(e.Name(), ".jsonl")
+	}
+	return names
+}
+
+// LoadScenario reads a built-in scenario by name, or a scenario file by path
+func LoadScenario(nameOrPath string) (Scenario, error) {
+	if content, err := scenarios.ReadFile("scenarios/" + nameOrPath + ".jsonl"); err == nil {
+		return ParseScenario(nameOrPath, bytes.NewReader(content))
+	}
+
+	f, err := os.Open(nameOrPath)
+	if err != nil {
+		return Scenario{}, fmt.Errorf("scenario '%s' is not one of %s, and cannot be read, %w",
+			nameOrPath, strings.Join(Scenarios(), ", "), err)
+	}
+	defer f.Close()
+	return ParseScenario(nameOrPath, f)
+}
+
+// ParseScenario reads the steps of a scenario, one JSON object per line. Blank lines are ignored.
+func ParseScenario(name string, r io.Reader) (Scenario, error) {
+	s := Scenario{Name: name}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var step Step
+		dec := json.NewDecoder(strings.NewReader(text))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&step); err != nil {
+			return s, fmt.Errorf("%s line %d: %w", name, line, err)
+		}
+		if err := step.init(); err != nil {
+			return s, fmt.Errorf("%s line %d: %w", name, line, err)
+		}
+
+		if step.Setup {
+			s.Setup = append(s.Setup, step)
+			continue
+		}
+		s.Steps = append(s.Steps, step)
+	}
+	if err := scanner.Err(); err != nil {
+		return s, err
+	}
+	if len(s.Steps) == 0 {
+		return s, fmt.Errorf("%s has no steps with a weight", name)
+	}
+	return s, nil
+}
+
+// init validates the step and finds the pools it refers to
+func (s *Step) init() error {
+	s.Method = strings.ToUpper(s.Method)
+	if s.Method == "" || !strings.HasPrefix(s.Path, "/") {
+		return fmt.Errorf("step '%s' must have a method and a path starting with /", s.Name)
+	}
+	if s.Name == "" {
+		s.Name = s.Method + " " + s.Path
+	}
+	if s.Weight < 0 || (s.Weight == 0 && !s.Setup) {
+		return fmt.Errorf("step '%s' must have a positive weight", s.Name)
+	}
+	if len(s.Body) > 0 && !json.Valid(s.Body) {
+		return fmt.Errorf("step '%s' has an invalid body", s.Name)
+	}
+
+	seen := map[string]bool{}
+	for _, m := range placeholder.FindAllStringSubmatch(s.Path+string(s.Body), -1) {
+		if !builtins[m[1]] && !seen[m[1]] {
+			seen[m[1]] = true
+			s.refs = append(s.refs, m[1])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package marketing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/silinternational/wecarry-api/domain"
+)
+
+const (
+	MailChimpStatusArchived = "archived"
+
+	// TagStatusActive and TagStatusInactive add a tag to, or remove a tag from, a list member
+	TagStatusActive   = "active"
+	TagStatusInactive = "inactive"
+
+	// membersPageSize is the number of list members requested at once, the maximum allowed by MailChimp
+	membersPageSize = 1000
+)
+
+// Client calls the MailChimp API for one list
+type Client struct {
+	BaseURL  string
+	ListID   string
+	Username string
+	APIKey   string
+}
+
+// MailChimpMember is a member of a list, as returned by the MailChimp API
+type MailChimpMember struct {
+	EmailAddress string               `json:"email_address"`
+	Status       string               `json:"status"`
+	MergeFields  MailChimpMergeFields `json:"merge_fields"`
+	Tags         []MailChimpTag       `json:"tags"`
+
+	// LastChanged is when the member's information, including its status, was last changed
+	LastChanged time.Time `json:"last_changed"`
+}
+
+// MailChimpMergeFields are the fields of a list member that hold the member's name
+type MailChimpMergeFields struct {
+	FirstName string `json:"FNAME"`
+	LastName  string `json:"LNAME"`
+}
+
+// MailChimpTag is a tag of a list member
+type MailChimpTag struct {
+	Name   string `json:"name"`
+	Status string `json:"status,omitempty"`
+}
+
+type mailChimpMembers struct {
+	Members    []MailChimpMember `json:"members"`
+	TotalItems int               `json:"total_items"`
+}
+
+// ClientFromEnv returns a Client configured by environment variables. The second return value is false if any of
+// the required variables is missing.
+func ClientFromEnv() (Client, bool) {
+	c := Client{
+		BaseURL:  domain.Env.MailChimpAPIBaseURL,
+		ListID:   domain.Env.MailChimpListID,
+		Username: domain.Env.MailChimpUsername,
+		APIKey:   domain.Env.MailChimpAPIKey,
+	}
+	return c, c.BaseURL != "" && c.ListID != "" && c.Username != "" && c.APIKey != ""
+}
+
+// Upsert subscribes the email address to the list, or updates the existing member's name. The status of an existing
+// member is left alone, so a member who unsubscribed through MailChimp is not subscribed again, unless `resubscribe`
+// is true because the user opted in again since.
+func (c Client) Upsert(email, firstName, lastName string, resubscribe bool) (MailChimpMember, error) {
+	body := map[string]interface{}{
+		"email_address": email,
+		"status_if_new": MailChimpStatusSubscribed,
+		"merge_fields":  MailChimpMergeFields{FirstName: firstName, LastName: lastName},
+	}
+	if resubscribe {
+		body["status"] = MailChimpStatusSubscribed
+	}
+
+	var member MailChimpMember
+	resp, err := c.call(http.MethodPut, c.memberURL(email), body)
+	if err != nil {
+		return member, err
+	}
+	if err := json.Unmarshal([]byte(resp), &member); err != nil {
+		return member, fmt.Errorf("error decoding MailChimp list member, %w", err)
+	}
+	return member, nil
+}
+
+// ChangeEmail changes the email address of a list member. A member that is not on the list is not an error.
+func (c Client) ChangeEmail(oldEmail, newEmail string) error {
+	resp, err := c.call(http.MethodPatch, c.memberURL(oldEmail), map[string]string{"email_address": newEmail})
+	if isNotFound(resp) {
+		return nil
+	}
+	return err
+}
+
+// Unsubscribe unsubscribes the email address from the list. An address that is not on the list is not an error.
+func (c Client) Unsubscribe(email string) error {
+	resp, err := c.call(http.MethodPatch, c.memberURL(email), map[string]string{"status": MailChimpStatusUnsubscribed})
+	if isNotFound(resp) {
+		return nil
+	}
+	return err
+}
+
+// Archive removes the email address from the list. An address that is not on the list is not an error.
+func (c Client) Archive(email string) error {
+	resp, err := c.call(http.MethodDelete, c.memberURL(email), nil)
+	if isNotFound(resp) {
+		return nil
+	}
+	return err
+}
+
+// UpdateTags adds the `active` tags to, and removes the `inactive` tags from, the list member
+func (c Client) UpdateTags(email string, active, inactive []string) error {
+	tags := make([]MailChimpTag, 0, len(active)+len(inactive))
+	for _, t := range active {
+		tags = append(tags, MailChimpTag{Name: t, Status: TagStatusActive})
+	}
+	for _, t := range inactive {
+		tags = append(tags, MailChimpTag{Name: t, Status: TagStatusInactive})
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	_, err := c.call(http.MethodPost, c.memberURL(email)+"/tags", map[string]interface{}{"tags": tags})
+	return err
+}
+
+// ListMembers returns all of the members of the list
+func (c Client) ListMembers() ([]MailChimpMember, error) {
+	var members []MailChimpMember
+	for {
+		params := url.Values{}
+		params.Set("count", fmt.Sprint(membersPageSize))
+		params.Set("offset", fmt.Sprint(len(members)))
+		params.Set("fields",
+			"members.email_address,members.status,members.merge_fields,members.tags,members.last_changed,total_items")
+
+		resp, err := c.call(http.MethodGet, fmt.Sprintf("%s/lists/%s/members?%s", c.BaseURL, c.ListID,
+			params.Encode()), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var page mailChimpMembers
+		if err := json.Unmarshal([]byte(resp), &page); err != nil {
+			return nil, fmt.Errorf("error decoding MailChimp list members, %w", err)
+		}
+		members = append(members, page.Members...)
+
+		if len(page.Members) == 0 || len(members) >= page.TotalItems {
+			return members, nil
+		}
+	}
+}
+
+func (c Client) memberURL(email string) string {
+	return fmt.Sprintf("%s/lists/%s/members/%s", c.BaseURL, c.ListID, subscriberHash(email))
+}
+
+// call makes a MailChimp API request with the given body encoded as JSON. The response body is returned even if
+// there is an error.
+func (c Client) call(method, apiURL string, body interface{}) (string, error) {
+	var reqBody []byte
+	if body != nil {
+		var err error
+		if reqBody, err = json.Marshal(body); err != nil {
+			return "", err
+		}
+	}
+
+	resp, err := callApi(ApiRequest{
+		Method:      method,
+		URL:         apiURL,
+		Body:        string(reqBody),
+		ContentType: "application/json",
+		Username:    c.Username,
+		Password:    c.APIKey,
+	})
+	if err != nil {
+		return resp, fmt.Errorf("error calling MailChimp API: %s. Response: %s", err.Error(), resp)
+	}
+	return resp, nil
+}
+
+// isNotFound returns true if the response is a MailChimp "not found" error
+func isNotFound(resp string) bool {
+	var mcErr MailChimpError
+	return json.Unmarshal([]byte(resp), &mcErr) == nil && mcErr.Status == http.StatusNotFound
+}
@@ -1,6 +1,7 @@
 package marketing
 
 import (
+	"crypto/md5"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -12,8 +13,9 @@ import (
 )
 
 const (
-	ApiTimeout                = 10 * time.Second
-	MailChimpStatusSubscribed = "subscribed"
+	ApiTimeout                  = 10 * time.Second
+	MailChimpStatusSubscribed   = "subscribed"
+	MailChimpStatusUnsubscribed = "unsubscribed"
 )
 
 type ApiRequest struct {
@@ -74,6 +76,18 @@ func AddUserToList(u models.User, apiBaseURL, listId, username, password string)
 	return nil
 }
 
+// RemoveUserFromList unsubscribes the given email address from the list. An address that is not on the list is not
+// an error.
+func RemoveUserFromList(email, apiBaseURL, listId, username, password string) error {
+	c := Client{BaseURL: apiBaseURL, ListID: listId, Username: username, APIKey: password}
+	return c.Unsubscribe(email)
+}
+
+// subscriberHash is the MailChimp identifier of a list member: the MD5 hash of the lowercase email address
+func subscriberHash(email string) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(strings.ToLower(email))))
+}
+
 func callApi(apiRequest ApiRequest) (string, error) {
 	reqBody := strings.NewReader(apiRequest.Body)
 	req, err := http.NewRequest(apiRequest.Method, apiRequest.URL, reqBody)
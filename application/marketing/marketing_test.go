@@ -173,3 +173,58 @@ func (ts *TestSuite) TestAddUserToList() {
 		})
 	}
 }
+
+func (ts *TestSuite) TestRemoveUserFromList() {
+	t := ts.T()
+
+	const listID = "list1"
+	member := subscriberHash("Member@Example.com")
+
+	var gotBody string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/lists/"+listID+"/members/", func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPatch {
+			res.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if strings.TrimPrefix(req.URL.Path, "/lists/"+listID+"/members/") != member {
+			res.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(res, `{"title":"Resource Not Found","status":404}`)
+			return
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		gotBody = string(body)
+		_, _ = fmt.Fprint(res, `{}`)
+	})
+	mux.HandleFunc("/", func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusUnauthorized)
+		_, _ = fmt.Fprint(res, `{"title":"API Key Invalid","status":401}`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		email   string
+		listID  string
+		wantErr bool
+	}{
+		{name: "member", email: "member@example.com", listID: listID},
+		{name: "not a member", email: "other@example.com", listID: listID},
+		{name: "error", email: "member@example.com", listID: "list2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotBody = ""
+			err := RemoveUserFromList(tt.email, srv.URL, tt.listID, "user", "pass")
+			if tt.wantErr {
+				ts.Error(err)
+				return
+			}
+			ts.NoError(err)
+			if tt.name == "member" {
+				ts.JSONEq(`{"status":"unsubscribed"}`, gotBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package marketing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/log"
+	"github.com/silinternational/wecarry-api/models"
+)
+
+// OrgTagPrefix marks the tags that name a member's organizations. List members with such a tag are managed by the
+// app; any other members, such as subscribers from the marketing site, are left alone by the reconciliation.
+const OrgTagPrefix = "org:"
+
+// reconcileBatchSize is the number of users loaded at once by the reconciliation
+const reconcileBatchSize = 500
+
+// Subscriber is the state of a user's list membership as it should be
+type Subscriber struct {
+	UserID int
+
+	// Email is the user's current email address
+	Email string
+
+	// PreviousEmail is the address under which the user was last subscribed, if any
+	PreviousEmail string
+
+	FirstName string
+	LastName  string
+	OptIn     bool
+
+	// OptInChangedAt is when the user last changed the opt-in, or zero if never
+	OptInChangedAt time.Time
+
+	// Resubscribe is true if the user opted in after the last sync, so that the member is subscribed even if it had
+	// unsubscribed before
+	Resubscribe bool
+
+	// Tags are the tags naming the user's organizations
+	Tags []string
+}
+
+// ReconcileResult counts the changes made to the list by a reconciliation
+type ReconcileResult struct {
+	Synced       int
+	Unsubscribed int
+	Archived     int
+	OptedOut     int
+	Failed       int
+}
+
+// reconcilePlan lists the changes needed to bring the list in line with the users
+type reconcilePlan struct {
+	sync        []Subscriber
+	unsubscribe []string
+	archive     []string
+
+	// optOut are the subscribers who unsubscribed through the list itself
+	optOut []Subscriber
+}
+
+// NewSubscriber gets the state of the user's list membership as it should be
+func NewSubscriber(tx *pop.Connection, user models.User) (Subscriber, error) {
+	orgs, err := user.GetOrganizations(tx)
+	if err != nil {
+		return Subscriber{}, fmt.Errorf("error getting organizations of user %s, %w", user.UUID, err)
+	}
+
+	tags := make([]string, len(orgs))
+	for i := range orgs {
+		tags[i] = OrgTagPrefix + orgs[i].Name
+	}
+	sort.Strings(tags)
+
+	optedInSinceSync := user.OptInChangedAt.Valid &&
+		(!user.MarketingSyncedAt.Valid || user.OptInChangedAt.Time.After(user.MarketingSyncedAt.Time))
+
+	return Subscriber{
+		UserID:         user.ID,
+		Email:          user.Email,
+		PreviousEmail:  user.MarketingEmail.String,
+		FirstName:      user.FirstName,
+		LastName:       user.LastName,
+		OptIn:          user.MarketingOptIn,
+		OptInChangedAt: user.OptInChangedAt.Time,
+		Resubscribe:    user.MarketingOptIn && optedInSinceSync,
+		Tags:           tags,
+	}, nil
+}
+
+// Sync brings the list member in line with the Subscriber: subscribed with the current email address, name and
+// organization tags, or unsubscribed
+func (c Client) Sync(s Subscriber) error {
+	if !s.OptIn {
+		if s.PreviousEmail != "" && !strings.EqualFold(s.PreviousEmail, s.Email) {
+			if err := c.Unsubscribe(s.PreviousEmail); err != nil {
+				return err
+			}
+		}
+		return c.Unsubscribe(s.Email)
+	}
+
+	if s.PreviousEmail != "" && !strings.EqualFold(s.PreviousEmail, s.Email) {
+		if err := c.ChangeEmail(s.PreviousEmail, s.Email); err != nil {
+			return err
+		}
+	}
+
+	member, err := c.Upsert(s.Email, s.FirstName, s.LastName, s.Resubscribe)
+	if err != nil {
+		return err
+	}
+
+	var inactive []string
+	for _, t := range orgTags(member) {
+		if !domain.IsStringInSlice(t, s.Tags) {
+			inactive = append(inactive, t)
+		}
+	}
+	return c.UpdateTags(s.Email, s.Tags, inactive)
+}
+
+// SyncUser brings the user's list membership in line with the user's profile and organizations, and records the
+// sync on the user
+func SyncUser(tx *pop.Connection, c Client, user models.User) error {
+	if user.IsDeleted() {
+		return nil
+	}
+
+	s, err := NewSubscriber(tx, user)
+	if err != nil {
+		return err
+	}
+	return syncSubscriber(tx, c, user, s)
+}
+
+// syncSubscriber brings the user's list membership in line with the Subscriber, and records the sync on the user
+func syncSubscriber(tx *pop.Connection, c Client, user models.User, s Subscriber) error {
+	if err := c.Sync(s); err != nil {
+		return fmt.Errorf("error syncing user %s to the marketing list, %w", user.UUID, err)
+	}
+
+	email := nulls.String{}
+	if s.OptIn {
+		email = nulls.NewString(s.Email)
+	}
+	return user.SetMarketingSynced(tx, email)
+}
+
+// Reconcile compares all of the users with the members of the list, and makes the changes needed to bring the list
+// in line with the users. Members that are managed by the app but no longer belong to any user are archived. Users
+// who unsubscribed through the list itself are opted out, unless they opted in again in the app since.
+func Reconcile(tx *pop.Connection, c Client) (ReconcileResult, error) {
+	var result ReconcileResult
+
+	members, err := c.ListMembers()
+	if err != nil {
+		return result, err
+	}
+
+	users := map[int]models.User{}
+	var subscribers []Subscriber
+	for afterID := 0; ; {
+		var batch models.Users
+		if err := batch.FindForMarketing(tx, afterID, reconcileBatchSize); err != nil {
+			return result, fmt.Errorf("error finding users for marketing reconciliation, %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, u := range batch {
+			s, err := NewSubscriber(tx, u)
+			if err != nil {
+				return result, err
+			}
+			users[u.ID] = u
+			subscribers = append(subscribers, s)
+		}
+		afterID = batch[len(batch)-1].ID
+	}
+
+	plan := planReconcile(subscribers, members)
+
+	for _, s := range plan.sync {
+		if err := syncSubscriber(tx, c, users[s.UserID], s); err != nil {
+			log.Error(err)
+			result.Failed++
+			continue
+		}
+		result.Synced++
+	}
+	for _, email := range plan.unsubscribe {
+		if err := c.Unsubscribe(email); err != nil {
+			log.Errorf("error unsubscribing %s from the marketing list, %s", email, err)
+			result.Failed++
+			continue
+		}
+		result.Unsubscribed++
+	}
+	for _, email := range plan.archive {
+		if err := c.Archive(email); err != nil {
+			log.Errorf("error archiving %s on the marketing list, %s", email, err)
+			result.Failed++
+			continue
+		}
+		result.Archived++
+	}
+	for _, s := range plan.optOut {
+		u := users[s.UserID]
+		if err := u.SetMarketingOptOut(tx); err != nil {
+			log.Error(err)
+			result.Failed++
+			continue
+		}
+		result.OptedOut++
+	}
+
+	if result.Failed > 0 {
+		return result, fmt.Errorf("%d marketing list changes failed", result.Failed)
+	}
+	return result, nil
+}
+
+// planReconcile finds the changes needed to bring the list members in line with the subscribers
+func planReconcile(subscribers []Subscriber, members []MailChimpMember) reconcilePlan {
+	var plan reconcilePlan
+
+	byEmail := map[string]MailChimpMember{}
+	for _, m := range members {
+		byEmail[strings.ToLower(m.EmailAddress)] = m
+	}
+
+	seen := map[string]bool{}
+	for _, s := range subscribers {
+		email := strings.ToLower(s.Email)
+		previous := strings.ToLower(s.PreviousEmail)
+		seen[email] = true
+
+		m, ok := byEmail[email]
+		if !ok && previous != "" {
+			m, ok = byEmail[previous]
+			seen[previous] = true
+		}
+
+		switch {
+		case s.OptIn && ok && m.Status == MailChimpStatusUnsubscribed && m.LastChanged.After(s.OptInChangedAt):
+			plan.optOut = append(plan.optOut, s)
+		case s.OptIn && ok && m.Status == MailChimpStatusUnsubscribed:
+			s.Resubscribe = true
+			plan.sync = append(plan.sync, s)
+		case s.OptIn && (!ok || !s.matches(m)):
+			plan.sync = append(plan.sync, s)
+		case !s.OptIn && ok && m.Status == MailChimpStatusSubscribed:
+			plan.unsubscribe = append(plan.unsubscribe, m.EmailAddress)
+		}
+	}
+
+	for _, m := range members {
+		if seen[strings.ToLower(m.EmailAddress)] || m.Status == MailChimpStatusArchived || len(orgTags(m)) == 0 {
+			continue
+		}
+		plan.archive = append(plan.archive, m.EmailAddress)
+	}
+
+	return plan
+}
+
+// matches returns true if the list member is subscribed with the Subscriber's email address, name and tags
+func (s Subscriber) matches(m MailChimpMember) bool {
+	if m.Status != MailChimpStatusSubscribed || !strings.EqualFold(m.EmailAddress, s.Email) ||
+		m.MergeFields.FirstName != s.FirstName || m.MergeFields.LastName != s.LastName {
+		return false
+	}
+
+	tags := orgTags(m)
+	if len(tags) != len(s.Tags) {
+		return false
+	}
+	for _, t := range tags {
+		if !domain.IsStringInSlice(t, s.Tags) {
+			return false
+		}
+	}
+	return true
+}
+
+// orgTags returns the names of the member's tags that name an organization
+func orgTags(m MailChimpMember) []string {
+	var tags []string
+	for _, t := range m.Tags {
+		if strings.HasPrefix(t.Name, OrgTagPrefix) {
+			tags = append(tags, t.Name)
+		}
+	}
+	return tags
+}
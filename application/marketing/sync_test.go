@@ -0,0 +1,212 @@
+package marketing
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeList is a stand-in for the MailChimp API that records the calls made to it
+type fakeList struct {
+	listID  string
+	members map[string]string // subscriber hash to member JSON
+	calls   []string
+}
+
+func (f *fakeList) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	path := strings.TrimPrefix(req.URL.Path, "/lists/"+f.listID+"/members/")
+	body, _ := ioutil.ReadAll(req.Body)
+	f.calls = append(f.calls, strings.TrimSpace(req.Method+" "+path+" "+string(body)))
+
+	hash := strings.TrimSuffix(path, "/tags")
+	member, ok := f.members[hash]
+	switch {
+	case req.Method == http.MethodPut:
+		_, _ = fmt.Fprint(res, member)
+	case !ok:
+		res.WriteHeader(http.StatusNotFound)
+		_, _ = fmt.Fprint(res, `{"title":"Resource Not Found","status":404}`)
+	default:
+		_, _ = fmt.Fprint(res, member)
+	}
+}
+
+func (ts *TestSuite) TestClient_Sync() {
+	t := ts.T()
+
+	const listID = "list1"
+	oldHash := subscriberHash("old@example.com")
+	newHash := subscriberHash("new@example.com")
+
+	tests := []struct {
+		name       string
+		subscriber Subscriber
+		members    map[string]string
+		wantCalls  []string
+	}{
+		{
+			name: "new subscriber",
+			subscriber: Subscriber{
+				Email: "new@example.com", FirstName: "New", LastName: "User", OptIn: true,
+				Tags: []string{"org:Org1"},
+			},
+			members: map[string]string{newHash: `{"email_address":"new@example.com","status":"subscribed"}`},
+			wantCalls: []string{
+				`PUT ` + newHash + ` {"email_address":"new@example.com","merge_fields":{"FNAME":"New","LNAME":"User"},` +
+					`"status_if_new":"subscribed"}`,
+				`POST ` + newHash + `/tags {"tags":[{"name":"org:Org1","status":"active"}]}`,
+			},
+		},
+		{
+			name: "changed email and organizations",
+			subscriber: Subscriber{
+				Email: "new@example.com", PreviousEmail: "old@example.com", FirstName: "New", LastName: "User",
+				OptIn: true, Tags: []string{"org:Org2"},
+			},
+			members: map[string]string{
+				oldHash: `{"email_address":"old@example.com","status":"subscribed"}`,
+				newHash: `{"email_address":"new@example.com","status":"subscribed",` +
+					`"tags":[{"name":"org:Org1"},{"name":"Newsletter"}]}`,
+			},
+			wantCalls: []string{
+				`PATCH ` + oldHash + ` {"email_address":"new@example.com"}`,
+				`PUT ` + newHash + ` {"email_address":"new@example.com","merge_fields":{"FNAME":"New","LNAME":"User"},` +
+					`"status_if_new":"subscribed"}`,
+				`POST ` + newHash + `/tags {"tags":[{"name":"org:Org2","status":"active"},` +
+					`{"name":"org:Org1","status":"inactive"}]}`,
+			},
+		},
+		{
+			name: "opted in again",
+			subscriber: Subscriber{
+				Email: "new@example.com", PreviousEmail: "new@example.com", FirstName: "New", LastName: "User",
+				OptIn: true, Resubscribe: true,
+			},
+			members: map[string]string{newHash: `{"email_address":"new@example.com","status":"subscribed"}`},
+			wantCalls: []string{
+				`PUT ` + newHash + ` {"email_address":"new@example.com","merge_fields":{"FNAME":"New","LNAME":"User"},` +
+					`"status":"subscribed","status_if_new":"subscribed"}`,
+			},
+		},
+		{
+			name:       "opted out",
+			subscriber: Subscriber{Email: "new@example.com", PreviousEmail: "new@example.com"},
+			members:    map[string]string{newHash: `{"email_address":"new@example.com","status":"subscribed"}`},
+			wantCalls:  []string{`PATCH ` + newHash + ` {"status":"unsubscribed"}`},
+		},
+		{
+			name:       "opted out, never subscribed",
+			subscriber: Subscriber{Email: "new@example.com"},
+			members:    map[string]string{},
+			wantCalls:  []string{`PATCH ` + newHash + ` {"status":"unsubscribed"}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := &fakeList{listID: listID, members: tt.members}
+			srv := httptest.NewServer(list)
+			defer srv.Close()
+
+			c := Client{BaseURL: srv.URL, ListID: listID, Username: "user", APIKey: "pass"}
+			ts.NoError(c.Sync(tt.subscriber))
+			ts.Equal(tt.wantCalls, list.calls)
+		})
+	}
+}
+
+func (ts *TestSuite) TestPlanReconcile() {
+	synced := Subscriber{
+		UserID: 1, Email: "synced@example.com", PreviousEmail: "synced@example.com", FirstName: "A", LastName: "B",
+		OptIn: true, Tags: []string{"org:Org1"},
+	}
+	renamed := Subscriber{
+		UserID: 2, Email: "renamed@example.com", PreviousEmail: "renamed@example.com", FirstName: "New",
+		LastName: "B", OptIn: true, Tags: []string{"org:Org1"},
+	}
+	moved := Subscriber{
+		UserID: 3, Email: "moved@example.com", PreviousEmail: "old@example.com", FirstName: "A", LastName: "B",
+		OptIn: true, Tags: []string{"org:Org1"},
+	}
+	missing := Subscriber{UserID: 4, Email: "missing@example.com", OptIn: true}
+	optedOut := Subscriber{UserID: 5, Email: "optedout@example.com", PreviousEmail: "optedout@example.com"}
+	retagged := Subscriber{
+		UserID: 6, Email: "retagged@example.com", FirstName: "A", LastName: "B", OptIn: true,
+		Tags: []string{"org:Org2"},
+	}
+	unsubscribed := Subscriber{
+		UserID: 7, Email: "unsubscribed@example.com", FirstName: "A", LastName: "B", OptIn: true,
+		OptInChangedAt: time.Now().Add(-time.Hour), Tags: []string{"org:Org1"},
+	}
+
+	member := func(email, status string, tags ...string) MailChimpMember {
+		m := MailChimpMember{
+			EmailAddress: email,
+			Status:       status,
+			MergeFields:  MailChimpMergeFields{FirstName: "A", LastName: "B"},
+			LastChanged:  time.Now(),
+		}
+		for _, tag := range tags {
+			m.Tags = append(m.Tags, MailChimpTag{Name: tag})
+		}
+		return m
+	}
+
+	members := []MailChimpMember{
+		member("Synced@example.com", MailChimpStatusSubscribed, "org:Org1", "Newsletter"),
+		member("renamed@example.com", MailChimpStatusSubscribed, "org:Org1"),
+		member("old@example.com", MailChimpStatusSubscribed, "org:Org1"),
+		member("optedout@example.com", MailChimpStatusSubscribed, "org:Org1"),
+		member("retagged@example.com", MailChimpStatusSubscribed, "org:Org1"),
+		member("unsubscribed@example.com", MailChimpStatusUnsubscribed, "org:Org1"),
+		member("gone@example.com", MailChimpStatusSubscribed, "org:Org1"),
+		member("gone-archived@example.com", MailChimpStatusArchived, "org:Org1"),
+		member("newsletter@example.com", MailChimpStatusSubscribed, "Newsletter"),
+	}
+
+	plan := planReconcile([]Subscriber{synced, renamed, moved, missing, optedOut, retagged, unsubscribed}, members)
+
+	ts.Equal([]Subscriber{renamed, moved, missing, retagged}, plan.sync, "incorrect subscribers to sync")
+	ts.Equal([]string{"optedout@example.com"}, plan.unsubscribe, "incorrect members to unsubscribe")
+	ts.Equal([]string{"gone@example.com"}, plan.archive, "incorrect members to archive")
+	ts.Equal([]Subscriber{unsubscribed}, plan.optOut, "incorrect subscribers to opt out")
+}
+
+func (ts *TestSuite) TestReconcile_OptOutThenOptIn() {
+	const listID = "list1"
+	hash := subscriberHash("user@example.com")
+	optedOutAt := time.Now().Add(-time.Hour)
+	unsubscribed := MailChimpMember{
+		EmailAddress: "user@example.com",
+		Status:       MailChimpStatusUnsubscribed,
+		LastChanged:  optedOutAt,
+	}
+
+	// the user opted out in the app, then opted in again before the reconciliation
+	s := Subscriber{
+		UserID: 1, Email: "user@example.com", PreviousEmail: "user@example.com", OptIn: true,
+		OptInChangedAt: optedOutAt.Add(time.Minute),
+	}
+	plan := planReconcile([]Subscriber{s}, []MailChimpMember{unsubscribed})
+	ts.Empty(plan.optOut, "a user who opted in after unsubscribing should not be opted out")
+	ts.Equal(1, len(plan.sync), "a user who opted in after unsubscribing should be synced")
+	ts.True(plan.sync[0].Resubscribe, "a user who opted in after unsubscribing should be subscribed again")
+
+	list := &fakeList{listID: listID, members: map[string]string{
+		hash: `{"email_address":"user@example.com","status":"subscribed"}`,
+	}}
+	srv := httptest.NewServer(list)
+	defer srv.Close()
+	c := Client{BaseURL: srv.URL, ListID: listID, Username: "user", APIKey: "pass"}
+	ts.NoError(c.Sync(plan.sync[0]))
+	ts.Contains(list.calls[0], `"status":"subscribed"`, "member should be subscribed again")
+
+	// the user unsubscribed through the list after opting in
+	unsubscribed.LastChanged = s.OptInChangedAt.Add(time.Minute)
+	plan = planReconcile([]Subscriber{s}, []MailChimpMember{unsubscribed})
+	ts.Equal([]Subscriber{s}, plan.optOut, "a user who unsubscribed after opting in should be opted out")
+	ts.Empty(plan.sync)
+}
@@ -0,0 +1,139 @@
+// Package metrics holds the Prometheus metrics of the API, which are served by the /metrics endpoint
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "wecarry"
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Registry holds all of the metrics of the API. It is used in lieu of the Prometheus default registry so that only
+// the metrics registered here are exposed.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests by route and response status",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	jobQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_queue_depth",
+		Help:      "Number of background jobs submitted but not yet started, including delayed jobs",
+	}, []string{"job"})
+
+	jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Duration of background job handlers by job and outcome",
+		Buckets:   []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300},
+	}, []string{"job", "outcome"})
+
+	notificationsSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_sent_total",
+		Help:      "Number of email notifications sent by email service and outcome",
+	}, []string{"service", "outcome"})
+
+	eventsHandled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_handled_total",
+		Help:      "Number of domain events handled by event kind, listener and outcome",
+	}, []string{"kind", "listener", "outcome"})
+
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Number of cache lookups by cache and result (hit or miss)",
+	}, []string{"cache", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		jobQueueDepth,
+		jobDuration,
+		notificationsSent,
+		eventsHandled,
+		cacheLookups,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Register adds a collector to the Registry, e.g. for metrics that are computed when they are collected
+func Register(c prometheus.Collector) error {
+	return Registry.Register(c)
+}
+
+// ObserveHTTPRequest records the duration of an HTTP request. The route is the route pattern rather than the path,
+// to keep the number of label values small.
+func ObserveHTTPRequest(method, route string, status int, d time.Duration) {
+	httpRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(d.Seconds())
+}
+
+// JobSubmitted records the submission of a background job
+func JobSubmitted(job string) {
+	jobQueueDepth.WithLabelValues(job).Inc()
+}
+
+// JobStarted records the start of a background job, which removes it from the queue
+func JobStarted(job string) {
+	jobQueueDepth.WithLabelValues(job).Dec()
+}
+
+// ObserveJob records the duration and outcome of a background job handler
+func ObserveJob(job string, d time.Duration, err error) {
+	jobDuration.WithLabelValues(job, outcome(err)).Observe(d.Seconds())
+}
+
+// ObserveNotification records the outcome of sending a notification by the given email service
+func ObserveNotification(service string, err error) {
+	notificationsSent.WithLabelValues(service, outcome(err)).Inc()
+}
+
+// ObserveEvent records the outcome of the handling of a domain event by a listener
+func ObserveEvent(kind, listener string, err error) {
+	eventsHandled.WithLabelValues(kind, listener, outcome(err)).Inc()
+}
+
+// ObserveCacheLookup records a cache hit or miss
+func ObserveCacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+// RegisterDBStats adds the statistics of a database connection pool to the Registry
+func RegisterDBStats(stats func() sql.DBStats) error {
+	return Register(newDBStatsCollector(stats))
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return OutcomeFailure
+	}
+	return OutcomeSuccess
+}
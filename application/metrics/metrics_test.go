@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+// TestSuite establishes a test suite for metrics tests
+type TestSuite struct {
+	suite.Suite
+}
+
+// Test_TestSuite runs the test suite
+func Test_TestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+func (ts *TestSuite) TestObserveJob() {
+	JobSubmitted("test_job")
+	JobSubmitted("test_job")
+	ts.Equal(float64(2), testutil.ToFloat64(jobQueueDepth.WithLabelValues("test_job")))
+
+	JobStarted("test_job")
+	ObserveJob("test_job", time.Second, nil)
+	JobStarted("test_job")
+	ObserveJob("test_job", time.Second, errors.New("failed"))
+
+	ts.Equal(float64(0), testutil.ToFloat64(jobQueueDepth.WithLabelValues("test_job")))
+	ts.Equal(1, testutil.CollectAndCount(jobDuration.WithLabelValues("test_job", OutcomeSuccess).(prometheus.Histogram)))
+	ts.Equal(1, testutil.CollectAndCount(jobDuration.WithLabelValues("test_job", OutcomeFailure).(prometheus.Histogram)))
+}
+
+func (ts *TestSuite) TestObserveCounters() {
+	ObserveNotification("dummy", nil)
+	ObserveNotification("dummy", errors.New("failed"))
+	ObserveNotification("dummy", nil)
+	ts.Equal(float64(2), testutil.ToFloat64(notificationsSent.WithLabelValues("dummy", OutcomeSuccess)))
+	ts.Equal(float64(1), testutil.ToFloat64(notificationsSent.WithLabelValues("dummy", OutcomeFailure)))
+
+	ObserveEvent("api:test", "cache", errors.New("failed"))
+	ts.Equal(float64(1), testutil.ToFloat64(eventsHandled.WithLabelValues("api:test", "cache", OutcomeFailure)))
+
+	ObserveCacheLookup("test", true)
+	ObserveCacheLookup("test", false)
+	ObserveCacheLookup("test", true)
+	ts.Equal(float64(2), testutil.ToFloat64(cacheLookups.WithLabelValues("test", "hit")))
+	ts.Equal(float64(1), testutil.ToFloat64(cacheLookups.WithLabelValues("test", "miss")))
+}
+
+func (ts *TestSuite) TestHandler() {
+	ObserveHTTPRequest(http.MethodGet, "/requests/{request_id}/", http.StatusNotFound, time.Millisecond)
+
+	c := newDBStatsCollector(func() sql.DBStats { return sql.DBStats{OpenConnections: 3, InUse: 1, Idle: 2} })
+	ts.NoError(Registry.Register(c))
+	defer Registry.Unregister(c)
+
+	res := httptest.NewRecorder()
+	Handler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	ts.Equal(http.StatusOK, res.Code)
+	body := res.Body.String()
+	for _, want := range []string{
+		`wecarry_http_request_duration_seconds_count{method="GET",route="/requests/{request_id}/",status="404"} 1`,
+		`wecarry_db_open_connections 3`,
+		`wecarry_db_in_use_connections 1`,
+		`go_goroutines`,
+	} {
+		ts.True(strings.Contains(body, want), "metrics do not include %q", want)
+	}
+}
@@ -0,0 +1,10 @@
+// Package migrations holds the database migrations, which are embedded so that the API can verify at runtime that
+// they have all been applied.
+package migrations
+
+import "embed"
+
+// FS holds the migration files
+//
+//go:embed *.fizz
+var FS embed.FS
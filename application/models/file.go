@@ -3,6 +3,7 @@ package models
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
 	"image/gif"
@@ -26,6 +27,9 @@ import (
 	"github.com/silinternational/wecarry-api/log"
 )
 
+// errFileAlreadyLinked is returned when attempting to link a file to more than one record
+var errFileAlreadyLinked = errors.New("cannot link file, it is already linked")
+
 type FileUploadError struct {
 	HttpStatus int
 	ErrorCode  api.ErrorKey
@@ -109,7 +113,7 @@ func (f *File) Store(tx *pop.Connection) *FileUploadError {
 
 	f.UUID = domain.GetUUID()
 
-	url, err := aws.StoreFile(f.UUID.String(), contentType, f.Content)
+	url, err := aws.StoreFile(tx.Context(), f.UUID.String(), contentType, f.Content)
 	if err != nil {
 		e := FileUploadError{
 			HttpStatus: http.StatusInternalServerError,
@@ -194,7 +198,7 @@ func (f *File) RefreshURL(tx *pop.Connection) error {
 		return nil
 	}
 
-	newURL, err := aws.GetFileURL(f.UUID.String())
+	newURL, err := aws.GetFileURL(tx.Context(), f.UUID.String())
 	if err != nil {
 		return err
 	}
@@ -243,7 +247,7 @@ func (f *Files) DeleteUnlinked(tx *pop.Connection) error {
 	nRemovedFromDB := 0
 	nRemovedFromS3 := 0
 	for _, file := range files {
-		if err := aws.RemoveFile(file.UUID.String()); err != nil {
+		if err := aws.RemoveFile(tx.Context(), file.UUID.String()); err != nil {
 			log.Errorf("error removing from S3, id='%s', %s", file.UUID.String(), err)
 			continue
 		}
@@ -272,7 +276,7 @@ func (f *File) SetLinked(tx *pop.Connection) error {
 		return fmt.Errorf("failed to load file for setting linked flag, %w", err)
 	}
 	if f.Linked {
-		return fmt.Errorf("cannot link file, it is already linked")
+		return errFileAlreadyLinked
 	}
 	f.Linked = true
 	return tx.UpdateColumns(f, "linked", "updated_at")
@@ -300,3 +304,12 @@ func convertFile(file File) api.File {
 		ContentType:   file.ContentType,
 	}
 }
+
+// ConvertFiles converts a list of models.File to a list of api.File
+func ConvertFiles(files Files) []api.File {
+	output := make([]api.File, len(files))
+	for i := range files {
+		output[i] = convertFile(files[i])
+	}
+	return output
+}
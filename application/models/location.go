@@ -108,8 +108,13 @@ func (l *Location) DistanceKm(loc2 Location) float64 {
 
 // IsNear answers the question "Are these two locations near each other?"
 func (l *Location) IsNear(loc2 Location) bool {
+	return l.IsWithinKm(loc2, domain.DefaultProximityDistanceKm)
+}
+
+// IsWithinKm answers the question "Are these two locations less than `km` kilometers apart?"
+func (l *Location) IsWithinKm(loc2 Location, km int) bool {
 	d := l.DistanceKm(loc2)
-	return !math.IsNaN(d) && d < domain.DefaultProximityDistanceKm
+	return !math.IsNaN(d) && d < float64(km)
 }
 
 // FindByIDs finds all Locations associated with the given IDs and loads them from the database
@@ -171,6 +176,17 @@ func (l *Locations) DeleteUnused() error {
 		}
 	}
 
+	var templates RequestTemplates
+	if err := DB.All(&templates); err != nil {
+		return fmt.Errorf("could not load request templates in Locations.DeleteUnused, %s", err)
+	}
+	for _, m := range templates {
+		usedLocations = append(usedLocations, m.DestinationID)
+		if m.OriginID.Valid {
+			usedLocations = append(usedLocations, m.OriginID.Int)
+		}
+	}
+
 	var locations Locations
 	if err := DB.Where("id NOT IN (?)", usedLocations).All(&locations); err != nil {
 		return fmt.Errorf("could not load locations in Locations.DeleteUnused, %s", err)
@@ -229,14 +245,14 @@ ORDER BY kcu.table_schema,
          kcu.table_name;`).All(&keys); err != nil {
 		return false, err
 	}
-	if len(keys) != 6 {
-		// expected 6 foreign keys: [{meetings location_id} {requests destination_id} {requests origin_id} {users location_id} {watches destination_id} {watches origin_id}]
+	if len(keys) != 8 {
+		// expected 8 foreign keys: [{meetings location_id} {request_templates destination_id} {request_templates origin_id} {requests destination_id} {requests origin_id} {users location_id} {watches destination_id} {watches origin_id}]
 		return true, nil
 	}
 	return false, nil
 }
 
-func convertLocation(location Location) api.Location {
+func ConvertLocation(location Location) api.Location {
 	return api.Location{
 		Description: location.Description,
 		Country:     location.Country,
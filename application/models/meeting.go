@@ -469,7 +469,7 @@ func ConvertMeeting(ctx context.Context, meeting Meeting, user User, options ...
 	output.CreatedBy = createdBy
 
 	output.ImageFile = convertMeetingImageFile(meeting)
-	output.Location = convertLocation(meeting.Location)
+	output.Location = ConvertLocation(meeting.Location)
 	output.HasJoined = true
 
 	var userP MeetingParticipant
@@ -608,7 +608,7 @@ func loadMeetingLocation(ctx context.Context, meeting Meeting) (api.Location, er
 		return api.Location{}, err
 	}
 
-	return convertLocation(location), nil
+	return ConvertLocation(location), nil
 }
 
 func loadMeetingParticipants(ctx context.Context, meeting Meeting, user User) (api.MeetingParticipants, error) {
@@ -652,7 +652,7 @@ func (m *Meeting) CreateInvites(ctx context.Context, emails string) error {
 	}
 
 	badEmails := make([]string, 0)
-	for _, email := range splitEmailList(emails) {
+	for _, email := range SplitEmailList(emails) {
 		inv.Email = email
 		if err := inv.Create(tx); err != nil {
 			badEmails = append(badEmails, email)
@@ -665,7 +665,8 @@ func (m *Meeting) CreateInvites(ctx context.Context, emails string) error {
 	return nil
 }
 
-func splitEmailList(emails string) []string {
+// SplitEmailList splits a list of email addresses separated by commas or newlines
+func SplitEmailList(emails string) []string {
 	if emails == "" {
 		return []string{}
 	}
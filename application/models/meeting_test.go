@@ -935,7 +935,7 @@ func (ms *ModelSuite) TestMeeting_CreateInvites() {
 	}
 }
 
-func (ms *ModelSuite) Test_splitEmailList() {
+func (ms *ModelSuite) Test_SplitEmailList() {
 	tests := []struct {
 		name   string
 		emails string
@@ -974,7 +974,7 @@ func (ms *ModelSuite) Test_splitEmailList() {
 	}
 	for _, tt := range tests {
 		ms.T().Run(tt.name, func(t *testing.T) {
-			ms.Equal(tt.want, splitEmailList(tt.emails))
+			ms.Equal(tt.want, SplitEmailList(tt.emails))
 		})
 	}
 }
@@ -3,6 +3,7 @@ package models
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -50,22 +51,37 @@ func (m *MeetingInvite) Create(tx *pop.Connection) error {
 	invite.Secret = domain.GetUUID()
 
 	err := create(tx, &invite)
-	if err != nil && strings.Contains(err.Error(), `duplicate key value violates unique constraint`) {
-		err = nil
+	if err != nil {
+		if strings.Contains(err.Error(), `duplicate key value violates unique constraint`) {
+			*m = invite
+			return nil
+		}
+		return err
 	}
-	if err == nil {
-		*m = invite
+	*m = invite
 
-		e := events.Event{
-			Kind:    domain.EventApiMeetingInviteCreated,
-			Message: "Meeting Invite created",
-			Payload: events.Payload{domain.ArgId: m.ID},
-		}
+	e := events.Event{
+		Kind:    domain.EventApiMeetingInviteCreated,
+		Message: "Meeting Invite created",
+		Payload: events.Payload{domain.ArgId: m.ID},
+	}
+
+	return emitEvent(tx, strconv.Itoa(m.ID), e)
+}
+
+// Resend records another "created" event for the MeetingInvite, so that the invitation email is sent again
+func (m *MeetingInvite) Resend(tx *pop.Connection) error {
+	if m.ID == 0 {
+		return errors.New("meeting invite must have an id in MeetingInvite.Resend")
+	}
 
-		emitEvent(e)
+	e := events.Event{
+		Kind:    domain.EventApiMeetingInviteCreated,
+		Message: "Meeting Invite resent",
+		Payload: events.Payload{domain.ArgId: m.ID},
 	}
 
-	return err
+	return emitEvent(tx, "", e)
 }
 
 // AvatarURL returns a generated gravatar URL for the inivitee
@@ -218,3 +218,19 @@ func (ms *ModelSuite) TestMeetingInvite_FindBySecret() {
 		})
 	}
 }
+
+func (ms *ModelSuite) TestMeetingInvite_Resend() {
+	meeting := createMeetingFixtures(ms.DB, 1).Meetings[0]
+	inviter := createUserFixtures(ms.DB, 1).Users[0]
+	invite := MeetingInvite{MeetingID: meeting.ID, InviterID: inviter.ID, Email: "resend@example.com"}
+	ms.NoError(invite.Create(ms.DB))
+
+	ms.Error((&MeetingInvite{}).Resend(ms.DB), "expected an error for an invite without an ID")
+
+	ms.NoError(invite.Resend(ms.DB))
+	ms.NoError(invite.Resend(ms.DB))
+
+	n, err := ms.DB.Where("kind = ?", domain.EventApiMeetingInviteCreated).Count(&OutboxEvent{})
+	ms.NoError(err)
+	ms.Equal(3, n, "each resend should record a new event")
+}
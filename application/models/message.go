@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/gobuffalo/events"
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gobuffalo/validate/v3"
 	"github.com/gobuffalo/validate/v3/validators"
@@ -19,15 +21,18 @@ import (
 )
 
 type Message struct {
-	ID        int       `json:"-" db:"id"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
-	UUID      uuid.UUID `json:"uuid" db:"uuid"`
-	ThreadID  int       `json:"thread_id" db:"thread_id"`
-	SentByID  int       `json:"sent_by_id" db:"sent_by_id"`
-	Content   string    `json:"content" db:"content"`
-	Thread    Thread    `belongs_to:"threads"`
-	SentBy    User      `belongs_to:"users"`
+	ID        int          `json:"-" db:"id"`
+	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at" db:"updated_at"`
+	UUID      uuid.UUID    `json:"uuid" db:"uuid"`
+	ThreadID  int          `json:"thread_id" db:"thread_id"`
+	SentByID  int          `json:"sent_by_id" db:"sent_by_id"`
+	Content   string       `json:"content" db:"content"`
+	EditedAt  nulls.Time   `json:"edited_at" db:"edited_at"`
+	DeletedAt nulls.Time   `json:"deleted_at" db:"deleted_at"`
+	Thread    Thread       `belongs_to:"threads"`
+	SentBy    User         `belongs_to:"users"`
+	Files     MessageFiles `json:"-" has_many:"message_files"`
 }
 
 // MessageCreatedEventData holds data needed by the Message Created event listener
@@ -120,9 +125,7 @@ func (m *Message) AfterCreate(tx *pop.Connection) error {
 		Payload: events.Payload{domain.ArgMessageID: m.ID},
 	}
 
-	emitEvent(e)
-
-	return nil
+	return emitEvent(tx, strconv.Itoa(m.ID), e)
 }
 
 // GetSender finds and returns the User that is the Sender of this Message
@@ -178,6 +181,176 @@ func (m *Message) CreateFromInput(tx *pop.Connection, user User, input api.Messa
 		return &appError
 	}
 
+	for _, fileID := range input.FileIDs {
+		if _, err := m.AttachFile(tx, fileID.String()); err != nil {
+			appError := api.NewAppError(err, api.ErrorMessageFileIDNotFound, api.CategoryUser)
+			if domain.IsOtherThanNoRows(err) && !errors.Is(err, errFileAlreadyLinked) {
+				appError.Category = api.CategoryInternal
+			}
+			return appError
+		}
+	}
+
+	return nil
+}
+
+// CreateFromEmailReply creates a message on behalf of the thread participant whose reply-to address is the given
+// address, on that participant's thread. Replies from suspended or deleted users are rejected.
+func (m *Message) CreateFromEmailReply(tx *pop.Connection, replyToAddress, content string) error {
+	var tp ThreadParticipant
+	if err := tp.FindByReplyToAddress(tx, replyToAddress); err != nil {
+		appError := api.NewAppError(err, api.ErrorMessageReplyAddressNotFound, api.CategoryNotFound)
+		if domain.IsOtherThanNoRows(err) && !errors.Is(err, errNotReplyToAddress) {
+			appError.Category = api.CategoryInternal
+		}
+		return appError
+	}
+
+	var user User
+	if err := user.FindByID(tx, tp.UserID); err != nil {
+		return api.NewAppError(err, api.ErrorMessageReplyAddressNotFound, api.CategoryInternal)
+	}
+	if user.IsDeleted() {
+		return api.NewAppError(fmt.Errorf("user %s is deleted", user.UUID), api.ErrorMessageReplyAddressNotFound,
+			api.CategoryNotFound)
+	}
+	if user.IsSuspended() {
+		return api.NewAppError(fmt.Errorf("user %s is suspended", user.UUID), api.ErrorUserSuspended,
+			api.CategoryForbidden)
+	}
+
+	var thread Thread
+	if err := tx.Find(&thread, tp.ThreadID); err != nil {
+		return api.NewAppError(err, api.ErrorThreadNotFound, api.CategoryInternal)
+	}
+	if err := thread.LoadRequest(tx); err != nil {
+		return api.NewAppError(err, api.ErrorThreadNotFound, api.CategoryInternal)
+	}
+
+	threadID := thread.UUID.String()
+	input := api.MessageInput{
+		Content:   content,
+		RequestID: thread.Request.UUID.String(),
+		ThreadID:  &threadID,
+	}
+	return m.CreateFromInput(tx, user, input)
+}
+
+// IsDeleted returns true if the message was deleted by its sender
+func (m *Message) IsDeleted() bool {
+	return m.DeletedAt.Valid
+}
+
+// IsEditable returns true if the message has not been deleted and was sent less than `domain.MessageEditWindow` before
+// the given time
+func (m *Message) IsEditable(t time.Time) bool {
+	return !m.IsDeleted() && t.Before(m.CreatedAt.Add(domain.MessageEditWindow))
+}
+
+// findForSender loads the message with the given UUID, if the user is its sender
+func (m *Message) findForSender(tx *pop.Connection, user User, id string) *api.AppError {
+	if err := m.findByUUID(tx, id); err != nil {
+		appError := api.NewAppError(err, api.ErrorMessageNotFound, api.CategoryNotFound)
+		if domain.IsOtherThanNoRows(err) {
+			appError.Category = api.CategoryInternal
+		}
+		return appError
+	}
+
+	if m.SentByID != user.ID {
+		err := fmt.Errorf("user %s is not the sender of message %s", user.UUID, id)
+		return api.NewAppError(err, api.ErrorNotAuthorized, api.CategoryForbidden)
+	}
+
+	return nil
+}
+
+// UpdateFromInput changes the content of the message with the given UUID, if the user is its sender and the message
+// is still editable.
+func (m *Message) UpdateFromInput(tx *pop.Connection, user User, id string, input api.MessageUpdateInput) error {
+	if aErr := m.findForSender(tx, user, id); aErr != nil {
+		return aErr
+	}
+
+	if !m.IsEditable(time.Now()) {
+		err := fmt.Errorf("message %s is deleted or too old to edit", id)
+		return api.NewAppError(err, api.ErrorMessageNotEditable, api.CategoryUser)
+	}
+
+	if input.Content == m.Content {
+		return nil
+	}
+
+	m.Content = input.Content
+	m.EditedAt = nulls.NewTime(time.Now())
+	if err := update(tx, m); err != nil {
+		err = errors.New("failed to update message, " + err.Error())
+		return api.NewAppError(err, api.ErrorMessageUpdateFailure, api.CategoryUser)
+	}
+
+	return nil
+}
+
+// DeleteForSender marks the message with the given UUID as deleted, if the user is its sender, and removes its
+// attached files. The message remains in its thread, without its content.
+func (m *Message) DeleteForSender(tx *pop.Connection, user User, id string) error {
+	if aErr := m.findForSender(tx, user, id); aErr != nil {
+		return aErr
+	}
+
+	if m.IsDeleted() {
+		return nil
+	}
+
+	if err := m.removeFiles(tx); err != nil {
+		return api.NewAppError(err, api.ErrorMessageDeleteFailure, api.CategoryInternal)
+	}
+
+	m.DeletedAt = nulls.NewTime(time.Now())
+	if err := tx.UpdateColumns(m, "deleted_at", "updated_at"); err != nil {
+		err = errors.New("failed to delete message, " + err.Error())
+		return api.NewAppError(err, api.ErrorMessageDeleteFailure, api.CategoryInternal)
+	}
+
+	return nil
+}
+
+// AttachFile adds a previously-stored File to this Message
+func (m *Message) AttachFile(tx *pop.Connection, fileID string) (File, error) {
+	var f File
+	if err := f.FindByUUID(tx, fileID); err != nil {
+		return f, err
+	}
+
+	if err := f.SetLinked(tx); err != nil {
+		return f, fmt.Errorf("error attaching file %s to message, %w", fileID, err)
+	}
+
+	messageFile := MessageFile{MessageID: m.ID, FileID: f.ID}
+	if err := messageFile.Create(tx); err != nil {
+		return f, err
+	}
+
+	return f, nil
+}
+
+// removeFiles detaches all files from this Message, leaving them to be removed by the file cleanup job
+func (m *Message) removeFiles(tx *pop.Connection) error {
+	var mf MessageFiles
+	if err := tx.Where("message_id = ?", m.ID).All(&mf); err != nil {
+		return fmt.Errorf("error getting files for message id %d, %s", m.ID, err)
+	}
+
+	for i := range mf {
+		f := File{ID: mf[i].FileID}
+		if err := f.ClearLinked(tx); err != nil {
+			return fmt.Errorf("error unlinking file %d of message %d, %s", f.ID, m.ID, err)
+		}
+	}
+
+	if err := tx.Destroy(&mf); err != nil {
+		return fmt.Errorf("error removing files of message %d, %s", m.ID, err)
+	}
 	return nil
 }
 
@@ -236,6 +409,22 @@ func findAndValidateThread(tx *pop.Connection, user User, input api.MessageInput
 		return &appError
 	}
 
+	if err := thread.LoadParticipants(tx); err != nil {
+		return api.NewAppError(err, api.ErrorQueryFailure, api.CategoryInternal)
+	}
+	participantIDs := make([]int, 0, len(thread.Participants))
+	for _, p := range thread.Participants {
+		if p.ID != user.ID {
+			participantIDs = append(participantIDs, p.ID)
+		}
+	}
+	if blocked, err := isBlockedByAny(tx, user.ID, participantIDs); err != nil {
+		return api.NewAppError(err, api.ErrorQueryFailure, api.CategoryInternal)
+	} else if blocked {
+		err := errors.New("user is blocked by a participant of the thread")
+		return api.NewAppError(err, api.ErrorMessageSenderBlocked, api.CategoryForbidden)
+	}
+
 	return nil
 }
 
@@ -302,7 +491,12 @@ func ConvertMessagesToAPIType(ctx context.Context, messages Messages) (api.Messa
 		return nil, err
 	}
 
-	// Hydrate the thread's messages with their sentBy users
+	files, err := messages.getFiles(Tx(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	// Hydrate the thread's messages with their sentBy users and files
 	for i := range output {
 		sentByOutput, err := ConvertUser(ctx, messages[i].SentBy)
 		if err != nil {
@@ -312,7 +506,46 @@ func ConvertMessagesToAPIType(ctx context.Context, messages Messages) (api.Messa
 
 		output[i].ID = messages[i].UUID
 		output[i].SentBy = &sentByOutput
+		output[i].IsEdited = messages[i].EditedAt.Valid
+		output[i].IsDeleted = messages[i].IsDeleted()
+		output[i].Files = []api.File{}
+
+		if messages[i].IsDeleted() {
+			output[i].Content = ""
+			continue
+		}
+
+		for _, f := range files[messages[i].ID] {
+			output[i].Files = append(output[i].Files, convertFile(f))
+		}
 	}
 
 	return output, nil
 }
+
+// getFiles retrieves the files attached to the messages, keyed by message ID
+func (m Messages) getFiles(tx *pop.Connection) (map[int][]File, error) {
+	files := map[int][]File{}
+	if len(m) == 0 {
+		return files, nil
+	}
+
+	ids := make([]int, len(m))
+	for i := range m {
+		ids[i] = m[i].ID
+	}
+
+	var mf MessageFiles
+	if err := tx.Eager("File").Where("message_id IN (?)", ids).Order("id").All(&mf); err != nil {
+		return nil, fmt.Errorf("error getting files for messages, %s", err)
+	}
+
+	for i := range mf {
+		if err := mf[i].File.RefreshURL(tx); err != nil {
+			return nil, err
+		}
+		files[mf[i].MessageID] = append(files[mf[i].MessageID], mf[i].File)
+	}
+
+	return files, nil
+}
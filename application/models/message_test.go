@@ -4,7 +4,6 @@ import (
 	"testing"
 	"time"
 
-	"github.com/gobuffalo/events"
 	"github.com/gobuffalo/validate/v3"
 
 	"github.com/silinternational/wecarry-api/api"
@@ -356,16 +355,7 @@ func (ms *ModelSuite) TestMessage_AfterCreate() {
 		Content:  "This message should update LastViewedAt",
 	}
 
-	var eventDetected bool
-	deleteFn, err := events.Listen(func(e events.Event) {
-		if e.Kind == domain.EventApiMessageCreated {
-			eventDetected = true
-		}
-	})
-	ms.NoError(err, "error registering event listener")
-	defer deleteFn()
-
-	err = DB.Create(&newMessage)
+	err := DB.Create(&newMessage)
 	ms.NoError(err)
 
 	const tSecond = time.Second
@@ -384,5 +374,194 @@ func (ms *ModelSuite) TestMessage_AfterCreate() {
 	ms.WithinDuration(time.Now(), f.Threads[1].UpdatedAt, tSecond,
 		"thread.updated_at was not set to the current time")
 
-	ms.True(eventDetected, "EventApiMessageCreated event was not emitted")
+	var outboxEvent OutboxEvent
+	err = DB.Where("kind = ?", domain.EventApiMessageCreated).First(&outboxEvent)
+	ms.NoError(err, "EventApiMessageCreated event was not recorded in the outbox")
+	e, err := outboxEvent.Event()
+	ms.NoError(err)
+	ms.Equal(newMessage.ID, e.Payload[domain.ArgMessageID])
+}
+
+func (ms *ModelSuite) TestMessage_UpdateFromInput() {
+	t := ms.T()
+
+	f := Fixtures_Message_GetSender(ms, t)
+	sender := f.Users[0]
+	messages := Messages{
+		f.Messages[0],
+		{UUID: domain.GetUUID(), ThreadID: f.Threads[0].ID, SentByID: sender.ID, Content: "old message"},
+		{UUID: domain.GetUUID(), ThreadID: f.Threads[0].ID, SentByID: sender.ID, Content: "deleted message"},
+	}
+	createFixture(ms, &messages[1])
+	createFixture(ms, &messages[2])
+
+	old := time.Now().Add(-domain.MessageEditWindow - time.Minute)
+	ms.NoError(ms.DB.RawQuery("UPDATE messages SET created_at = ? WHERE id = ?", old, messages[1].ID).Exec())
+	ms.NoError(ms.DB.RawQuery("UPDATE messages SET deleted_at = ? WHERE id = ?", time.Now(), messages[2].ID).Exec())
+
+	tests := []struct {
+		name    string
+		user    User
+		id      string
+		content string
+		wantErr api.ErrorKey
+	}{
+		{
+			name:    "not the sender",
+			user:    f.Users[1],
+			id:      messages[0].UUID.String(),
+			content: "not mine",
+			wantErr: api.ErrorNotAuthorized,
+		},
+		{
+			name:    "not found",
+			user:    sender,
+			id:      domain.GetUUID().String(),
+			content: "no message",
+			wantErr: api.ErrorMessageNotFound,
+		},
+		{
+			name:    "too old",
+			user:    sender,
+			id:      messages[1].UUID.String(),
+			content: "too late",
+			wantErr: api.ErrorMessageNotEditable,
+		},
+		{
+			name:    "deleted",
+			user:    sender,
+			id:      messages[2].UUID.String(),
+			content: "gone",
+			wantErr: api.ErrorMessageNotEditable,
+		},
+		{
+			name:    "good",
+			user:    sender,
+			id:      messages[0].UUID.String(),
+			content: "I can bring chocolate if you bring PB",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var message Message
+			err := message.UpdateFromInput(ms.DB, tt.user, tt.id, api.MessageUpdateInput{Content: tt.content})
+			if tt.wantErr != "" {
+				appErr, ok := err.(*api.AppError)
+				ms.True(ok, "error is not an AppError")
+				ms.Equal(tt.wantErr, appErr.Key, "wrong error type")
+				return
+			}
+			ms.NoError(err)
+
+			var got Message
+			ms.NoError(got.findByUUID(ms.DB, tt.id))
+			ms.Equal(tt.content, got.Content, "content was not updated")
+			ms.True(got.EditedAt.Valid, "EditedAt was not set")
+		})
+	}
+}
+
+func (ms *ModelSuite) TestMessage_DeleteForSender() {
+	t := ms.T()
+
+	f := Fixtures_Message_GetSender(ms, t)
+	message := f.Messages[0]
+	file, err := message.AttachFile(ms.DB, createFileFixture(ms.DB).UUID.String())
+	ms.NoError(err)
+
+	var m Message
+	err = m.DeleteForSender(ms.DB, f.Users[1], message.UUID.String())
+	appErr, ok := err.(*api.AppError)
+	ms.True(ok, "error is not an AppError")
+	ms.Equal(api.ErrorNotAuthorized, appErr.Key, "wrong error type")
+
+	m = Message{}
+	ms.NoError(m.DeleteForSender(ms.DB, f.Users[0], message.UUID.String()))
+	ms.True(m.IsDeleted(), "message was not marked as deleted")
+
+	n, err := ms.DB.Where("message_id = ?", message.ID).Count(MessageFile{})
+	ms.NoError(err)
+	ms.Equal(0, n, "message files were not removed")
+
+	ms.NoError(ms.DB.Reload(&file))
+	ms.False(file.Linked, "file was not unlinked")
+
+	m = Message{}
+	ms.NoError(m.DeleteForSender(ms.DB, f.Users[0], message.UUID.String()), "second delete should succeed")
+}
+
+func (ms *ModelSuite) TestConvertMessagesToAPIType() {
+	t := ms.T()
+
+	f := Fixtures_Message_GetSender(ms, t)
+	messages := Messages{
+		f.Messages[0],
+		{UUID: domain.GetUUID(), ThreadID: f.Threads[0].ID, SentByID: f.Users[0].ID, Content: "deleted message"},
+	}
+	createFixture(ms, &messages[1])
+
+	file, err := messages[0].AttachFile(ms.DB, createFileFixture(ms.DB).UUID.String())
+	ms.NoError(err)
+	_, err = messages[1].AttachFile(ms.DB, createFileFixture(ms.DB).UUID.String())
+	ms.NoError(err)
+
+	var deleted Message
+	ms.NoError(deleted.DeleteForSender(ms.DB, f.Users[0], messages[1].UUID.String()))
+	messages[1] = deleted
+
+	got, err := ConvertMessagesToAPIType(CtxWithUser(f.Users[0]), messages)
+	ms.NoError(err)
+	ms.Equal(2, len(got))
+
+	ms.Equal(messages[0].Content, got[0].Content)
+	ms.False(got[0].IsDeleted)
+	ms.Equal(1, len(got[0].Files), "wrong number of files")
+	ms.Equal(file.UUID, got[0].Files[0].ID)
+
+	ms.Equal("", got[1].Content, "content of deleted message should be hidden")
+	ms.True(got[1].IsDeleted)
+	ms.Equal(0, len(got[1].Files), "files of deleted message should be hidden")
+}
+
+func (ms *ModelSuite) TestMessage_CreateFromEmailReply() {
+	t := ms.T()
+
+	f := Fixtures_Message_GetSender(ms, t)
+	tp := ThreadParticipant{ThreadID: f.Threads[0].ID, UserID: f.Users[0].ID}
+	createFixture(ms, &tp)
+
+	replyDomain := domain.Env.EmailReplyDomain
+	defer func() { domain.Env.EmailReplyDomain = replyDomain }()
+	domain.Env.EmailReplyDomain = "reply.example.com"
+
+	replyTo, err := tp.GetReplyToAddress(ms.DB)
+	ms.NoError(err)
+
+	var message Message
+	err = message.CreateFromEmailReply(ms.DB, "reply+0123456789abcdef0123456789abcdef@reply.example.com", "Hi")
+	appErr, ok := err.(*api.AppError)
+	ms.True(ok, "error is not an AppError")
+	ms.Equal(api.ErrorMessageReplyAddressNotFound, appErr.Key, "wrong error type")
+	ms.Equal(api.CategoryNotFound, appErr.Category, "wrong error category")
+
+	message = Message{}
+	ms.NoError(message.CreateFromEmailReply(ms.DB, replyTo, "Replied by email"))
+	ms.Equal(f.Threads[0].ID, message.ThreadID, "message is on the wrong thread")
+	ms.Equal(f.Users[0].ID, message.SentByID, "message has the wrong sender")
+	ms.Equal("Replied by email", message.Content)
+
+	ms.NoError(f.Users[0].Suspend(ms.DB))
+	message = Message{}
+	err = message.CreateFromEmailReply(ms.DB, replyTo, "Replied while suspended")
+	appErr, ok = err.(*api.AppError)
+	ms.True(ok, "error is not an AppError")
+	ms.Equal(api.ErrorUserSuspended, appErr.Key, "suspended user should not be able to reply")
+
+	f.Users[0].Email = "deleted@" + deletedUserEmailDomain
+	ms.NoError(ms.DB.UpdateColumns(&f.Users[0], "email"))
+	message = Message{}
+	err = message.CreateFromEmailReply(ms.DB, replyTo, "Replied after deletion")
+	appErr, ok = err.(*api.AppError)
+	ms.True(ok, "error is not an AppError")
+	ms.Equal(api.ErrorMessageReplyAddressNotFound, appErr.Key, "deleted user should not be able to reply")
 }
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+)
+
+// MessageFile links a File attached to a Message
+type MessageFile struct {
+	ID        int       `json:"id" db:"id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	MessageID int       `json:"message_id" db:"message_id"`
+	FileID    int       `json:"file_id" db:"file_id"`
+	File      File      `belongs_to:"files"`
+}
+
+// String can be helpful for serializing the model
+func (m MessageFile) String() string {
+	jm, _ := json.Marshal(m)
+	return string(jm)
+}
+
+// MessageFiles is merely for convenience and brevity
+type MessageFiles []MessageFile
+
+// String can be helpful for serializing the model
+func (m MessageFiles) String() string {
+	jm, _ := json.Marshal(m)
+	return string(jm)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (m *MessageFile) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+func (m *MessageFile) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+func (m *MessageFile) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// Create stores the MessageFile data as a new record in the database.
+func (m *MessageFile) Create(tx *pop.Connection) error {
+	return create(tx, m)
+}
@@ -4,12 +4,13 @@ import (
 	"context"
 	"crypto/md5" // #nosec G501 weak cryptography used for gravatar URL only
 	"crypto/rand"
+	"database/sql"
 	"encoding/base64"
 	"fmt"
+	"io/fs"
 	"reflect"
 	"strings"
 
-	"github.com/gobuffalo/events"
 	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gobuffalo/validate/v3"
@@ -19,6 +20,8 @@ import (
 
 	"github.com/silinternational/wecarry-api/domain"
 	"github.com/silinternational/wecarry-api/log"
+	"github.com/silinternational/wecarry-api/migrations"
+	"github.com/silinternational/wecarry-api/tracing"
 )
 
 // Count can be used to receive the results of a SQL COUNT
@@ -39,6 +42,12 @@ var allowedUserPreferenceKeys map[string]string
 func init() {
 	var err error
 	env := domain.Env.GoEnv
+	if c, ok := pop.Connections[env]; ok {
+		// record a span for each query that is part of a trace
+		details := c.Dialect.Details()
+		details.UseInstrumentedDriver = true
+		details.InstrumentedDriverOptions = tracing.SQLOptions()
+	}
 	DB, err = pop.Connect(env)
 	if err != nil {
 		panic(fmt.Sprintf("error connecting to database ... %v", err))
@@ -84,6 +93,19 @@ func CurrentUser(ctx context.Context) User {
 	return user
 }
 
+// CurrentAccessToken retrieves the access token used to authenticate the current request
+func CurrentAccessToken(ctx context.Context) UserAccessToken {
+	token, _ := ctx.Value(domain.ContextKeyCurrentAccessToken).(UserAccessToken)
+	return token
+}
+
+// CurrentPersonalAccessToken retrieves the personal access token used to authenticate the current request, if any.
+// The returned token has a zero ID if the request was authenticated another way.
+func CurrentPersonalAccessToken(ctx context.Context) PersonalAccessToken {
+	token, _ := ctx.Value(domain.ContextKeyCurrentPersonalAccessToken).(PersonalAccessToken)
+	return token
+}
+
 // flattenPopErrors - pop validation errors are complex structures, this flattens them to a simple string
 func flattenPopErrors(popErrs *validate.Errors) string {
 	var msg string
@@ -118,13 +140,6 @@ func (v *NullsStringIsURL) IsValid(errors *validate.Errors) {
 	newV.IsValid(errors)
 }
 
-// This can include an event payload, which is a map[string]interface{}
-func emitEvent(e events.Event) {
-	if err := events.Emit(e); err != nil {
-		log.Errorf("error emitting event %s ... %v", e.Kind, err)
-	}
-}
-
 func create(tx *pop.Connection, m interface{}) error {
 	uuidField := fieldByName(m, "UUID")
 	if uuidField.IsValid() && uuidField.Interface().(uuid.UUID).Version() == 0 {
@@ -186,6 +201,48 @@ func IsDBConnected() bool {
 	return true
 }
 
+// CheckMigrations verifies that every migration embedded in the binary has been applied to the database
+func CheckMigrations(tx *pop.Connection) error {
+	files, err := fs.ReadDir(migrations.FS, ".")
+	if err != nil {
+		return fmt.Errorf("error reading migrations, %w", err)
+	}
+
+	var applied []struct {
+		Version string `db:"version"`
+	}
+	if err := tx.RawQuery("SELECT version FROM " + tx.MigrationTableName()).All(&applied); err != nil {
+		return fmt.Errorf("error reading applied migrations, %w", err)
+	}
+	isApplied := map[string]bool{}
+	for _, a := range applied {
+		isApplied[a.Version] = true
+	}
+
+	var pending []string
+	for _, f := range files {
+		m, err := pop.ParseMigrationFilename(f.Name())
+		if err != nil || m == nil || m.Direction != "up" {
+			continue
+		}
+		if !isApplied[m.Version] {
+			pending = append(pending, m.Version)
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d migration(s) not applied: %s", len(pending), strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// DBStats returns the statistics of the database connection pool
+func DBStats() sql.DBStats {
+	if db, ok := DB.Store.(interface{ Stats() sql.DBStats }); ok {
+		return db.Stats()
+	}
+	return sql.DBStats{}
+}
+
 func gravatarURL(email string) string {
 	// ref: https://en.gravatar.com/site/implement/images/
 	hash := md5.Sum([]byte(strings.ToLower(strings.TrimSpace(email)))) // #nosec G401 weak cryptography acceptable here
@@ -293,6 +350,10 @@ func DestroyAll() {
 	// delete all Locations
 	var locations Locations
 	destroyTable(&locations)
+
+	// delete all OutboxEvents
+	var outboxEvents OutboxEvents
+	destroyTable(&outboxEvents)
 }
 
 func destroyTable(i interface{}) {
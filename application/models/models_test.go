@@ -209,3 +209,21 @@ func (ms *ModelSuite) Test_removeFile() {
 		})
 	}
 }
+
+func (ms *ModelSuite) TestCheckMigrations() {
+	ms.NoError(CheckMigrations(ms.DB))
+
+	var latest struct {
+		Version string `db:"version"`
+	}
+	table := ms.DB.MigrationTableName()
+	ms.NoError(ms.DB.RawQuery("SELECT MAX(version) AS version FROM " + table).First(&latest))
+	ms.NoError(ms.DB.RawQuery("DELETE FROM "+table+" WHERE version = ?", latest.Version).Exec())
+	defer func() {
+		ms.NoError(ms.DB.RawQuery("INSERT INTO "+table+" (version) VALUES (?)", latest.Version).Exec())
+	}()
+
+	err := CheckMigrations(ms.DB)
+	ms.Error(err)
+	ms.Contains(err.Error(), latest.Version)
+}
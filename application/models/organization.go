@@ -1,6 +1,7 @@
 package models
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -17,6 +18,7 @@ import (
 	"github.com/silinternational/wecarry-api/auth"
 	"github.com/silinternational/wecarry-api/auth/azureadv2"
 	"github.com/silinternational/wecarry-api/auth/google"
+	"github.com/silinternational/wecarry-api/auth/oidc"
 	"github.com/silinternational/wecarry-api/auth/saml"
 	"github.com/silinternational/wecarry-api/domain"
 )
@@ -30,13 +32,15 @@ const (
 	AuthTypeDefault AuthType = "DEFAULT"
 	// AuthTypeGoogle : Google OAUTH 2.0
 	AuthTypeGoogle AuthType = "GOOGLE"
+	// AuthTypeOIDC : Generic OpenID Connect
+	AuthTypeOIDC AuthType = "OIDC"
 	// AuthTypeSaml : SAML 2.0
 	AuthTypeSaml AuthType = "SAML"
 )
 
 func (e AuthType) IsValid() bool {
 	switch e {
-	case AuthTypeAzureAD, AuthTypeDefault, AuthTypeGoogle, AuthTypeSaml:
+	case AuthTypeAzureAD, AuthTypeDefault, AuthTypeGoogle, AuthTypeOIDC, AuthTypeSaml:
 		return true
 	}
 	return false
@@ -57,6 +61,33 @@ type Organization struct {
 	UUID       uuid.UUID    `json:"uuid" db:"uuid"`
 	FileID     nulls.Int    `json:"file_id" db:"file_id"`
 	Users      Users        `many_to_many:"user_organizations" order_by:"nickname"`
+
+	SessionIdleTimeoutSeconds nulls.Int `json:"session_idle_timeout_seconds" db:"session_idle_timeout_seconds"`
+	SessionMaxLifetimeSeconds nulls.Int `json:"session_max_lifetime_seconds" db:"session_max_lifetime_seconds"`
+	MaxSessionsPerUser        nulls.Int `json:"max_sessions_per_user" db:"max_sessions_per_user"`
+
+	SCIMTokenHash string `json:"-" db:"scim_token_hash"`
+}
+
+// SessionPolicy holds the limits applied to user access tokens
+type SessionPolicy struct {
+	// IdleTimeout is how long a token remains valid after its last use
+	IdleTimeout time.Duration
+
+	// MaxLifetime is how long a token remains valid after creation, regardless of use. Zero means no limit.
+	MaxLifetime time.Duration
+
+	// MaxSessions is how many tokens a user may hold at once. Zero means no limit.
+	MaxSessions int
+}
+
+// defaultSessionPolicy returns the session policy configured in the environment
+func defaultSessionPolicy() SessionPolicy {
+	return SessionPolicy{
+		IdleTimeout: time.Second * time.Duration(domain.Env.AccessTokenLifetimeSeconds),
+		MaxLifetime: time.Second * time.Duration(domain.Env.AccessTokenMaxLifeSeconds),
+		MaxSessions: domain.Env.MaxSessionsPerUser,
+	}
 }
 
 // String is used to serialize error extras
@@ -86,6 +117,53 @@ func (o *Organization) ValidateUpdate(tx *pop.Connection) (*validate.Errors, err
 	return validate.NewErrors(), nil
 }
 
+// SessionPolicy returns the Organization's session policy. Any limit not set on the Organization is taken from
+// the environment defaults.
+func (o *Organization) SessionPolicy() SessionPolicy {
+	p := defaultSessionPolicy()
+	if o.SessionIdleTimeoutSeconds.Valid && o.SessionIdleTimeoutSeconds.Int > 0 {
+		p.IdleTimeout = time.Second * time.Duration(o.SessionIdleTimeoutSeconds.Int)
+	}
+	if o.SessionMaxLifetimeSeconds.Valid {
+		p.MaxLifetime = time.Second * time.Duration(o.SessionMaxLifetimeSeconds.Int)
+	}
+	if o.MaxSessionsPerUser.Valid {
+		p.MaxSessions = o.MaxSessionsPerUser.Int
+	}
+	return p
+}
+
+// CreateSCIMToken generates and stores a new bearer token for the Organization's SCIM endpoint, replacing any
+// previous token. The returned token is not stored and cannot be retrieved later.
+func (o *Organization) CreateSCIMToken(tx *pop.Connection) (string, error) {
+	random, err := getRandomToken()
+	if err != nil {
+		return "", err
+	}
+	token := SCIMTokenPrefix + random
+
+	o.SCIMTokenHash = HashClientIdAccessToken(token)
+	if err := o.Save(tx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RemoveSCIMToken disables the Organization's SCIM endpoint
+func (o *Organization) RemoveSCIMToken(tx *pop.Connection) error {
+	o.SCIMTokenHash = ""
+	return o.Save(tx)
+}
+
+// IsValidSCIMToken returns true if the given bearer token is the Organization's SCIM token
+func (o *Organization) IsValidSCIMToken(token string) bool {
+	if o.SCIMTokenHash == "" || token == "" {
+		return false
+	}
+	hash := HashClientIdAccessToken(token)
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(o.SCIMTokenHash)) == 1
+}
+
 // GetAuthProvider returns the auth provider associated with the domain of `authEmail`, if assigned, otherwise from the Organization's auth provider.
 func (o *Organization) GetAuthProvider(tx *pop.Connection, authEmail string) (auth.Provider, error) {
 	// Use type and config from organization by default
@@ -114,14 +192,59 @@ func (o *Organization) GetAuthProvider(tx *pop.Connection, authEmail string) (au
 			},
 			[]byte(authConfig),
 		)
+	case AuthTypeOIDC:
+		return oidc.New([]byte(authConfig))
 	case AuthTypeSaml:
-		return saml.New([]byte(authConfig))
+		return o.newSAMLProvider(authConfig)
 
 	}
 
 	return &auth.EmptyProvider{}, fmt.Errorf("unsupported auth provider type: %s", o.AuthType)
 }
 
+// GetSAMLProviders returns a SAML provider for the Organization's auth config and for each of its domains that
+// overrides it with a SAML auth config
+func (o *Organization) GetSAMLProviders(tx *pop.Connection) ([]*saml.Provider, error) {
+	var configs []string
+	if AuthType(strings.ToUpper(o.AuthType.String())) == AuthTypeSaml {
+		configs = append(configs, o.AuthConfig)
+	}
+
+	domains, err := o.Domains(tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range domains {
+		if d.AuthType == AuthTypeSaml {
+			configs = append(configs, d.AuthConfig)
+		}
+	}
+
+	providers := make([]*saml.Provider, len(configs))
+	for i, config := range configs {
+		if providers[i], err = o.newSAMLProvider(config); err != nil {
+			return nil, err
+		}
+	}
+
+	return providers, nil
+}
+
+// SAMLSingleLogoutURL returns the URL of the Organization's SAML single logout service
+func (o *Organization) SAMLSingleLogoutURL() string {
+	return fmt.Sprintf("%s/auth/saml/%s/slo", domain.Env.ApiBaseURL, o.UUID.String())
+}
+
+func (o *Organization) newSAMLProvider(authConfig string) (*saml.Provider, error) {
+	p, err := saml.New([]byte(authConfig))
+	if err != nil {
+		return p, err
+	}
+
+	p.SetDefaultSPSingleLogoutURL(o.SAMLSingleLogoutURL())
+	return p, nil
+}
+
 func (o *Organization) FindByUUID(tx *pop.Connection, uuid string) error {
 	if uuid == "" {
 		return errors.New("error: org uuid must not be blank")
@@ -299,6 +422,13 @@ func (o *Organization) TrustedOrganizations(tx *pop.Connection) (Organizations,
 	return trustedOrgs, nil
 }
 
+// TrustingOrganizations gets the Organizations that trust this one, and so can see its TRUSTED requests
+func (o *Organization) TrustingOrganizations(tx *pop.Connection) (Organizations, error) {
+	orgs := Organizations{}
+	err := tx.Where("id IN (SELECT primary_id FROM organization_trusts WHERE secondary_id = ?)", o.ID).All(&orgs)
+	return orgs, err
+}
+
 // AttachLogo assigns a previously-stored File to this Organization as its logo. Parameter `fileID` is the UUID
 // of the file to attach.
 func (o *Organization) AttachLogo(tx *pop.Connection, fileID string) (File, error) {
@@ -2,10 +2,12 @@ package models
 
 import (
 	"testing"
+	"time"
 
 	"github.com/gobuffalo/nulls"
 	"github.com/silinternational/wecarry-api/auth/azureadv2"
 	"github.com/silinternational/wecarry-api/auth/google"
+	"github.com/silinternational/wecarry-api/auth/oidc"
 	"github.com/silinternational/wecarry-api/domain"
 )
 
@@ -583,6 +585,35 @@ func (ms *ModelSuite) TestOrganization_TrustedOrganizations() {
 	}
 }
 
+func (ms *ModelSuite) TestOrganization_TrustingOrganizations() {
+	t := ms.T()
+
+	orgs := createOrganizationFixtures(ms.DB, 3)
+	trust := OrganizationTrust{PrimaryID: orgs[0].ID, SecondaryID: orgs[1].ID}
+	ms.NoError(trust.Create(ms.DB))
+
+	tests := []struct {
+		name    string
+		primary Organization
+		want    []int
+	}{
+		{name: "trusted", primary: orgs[1], want: []int{orgs[0].ID}},
+		{name: "trusting only", primary: orgs[0], want: []int{}},
+		{name: "no trusts", primary: orgs[2], want: []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.primary.TrustingOrganizations(ms.DB)
+			ms.NoError(err, "unexpected error from UUT")
+			ids := make([]int, len(got))
+			for i := range got {
+				ids[i] = got[i].ID
+			}
+			ms.ElementsMatch(tt.want, ids, "received incorrect trusting orgs")
+		})
+	}
+}
+
 func (ms *ModelSuite) TestOrganization_GetAuthProvider() {
 	adTenantID := "TestADTenant"
 	adSecret := "TestADKey"
@@ -626,6 +657,15 @@ func (ms *ModelSuite) TestOrganization_GetAuthProvider() {
 	err = orgDomain2.Save(ms.DB)
 	ms.NoError(err, "unable to create orgDomain2 fixture")
 
+	orgDomain3 := OrganizationDomain{
+		OrganizationID: org.ID,
+		Domain:         "domain3.com",
+		AuthType:       AuthTypeOIDC,
+		AuthConfig:     `{"Issuer":"https://idp.example.com","ClientID":"abc","ClientSecret":"xyz"}`,
+	}
+	err = orgDomain3.Save(ms.DB)
+	ms.NoError(err, "unable to create orgDomain3 fixture")
+
 	var o Organization
 	err = o.FindByUUID(ms.DB, uid.String())
 	ms.NoError(err, "unable to find organization fixture")
@@ -639,6 +679,11 @@ func (ms *ModelSuite) TestOrganization_GetAuthProvider() {
 	provider, err = o.GetAuthProvider(ms.DB, "test@domain2.com")
 	ms.NoError(err, "unable to get authprovider for test@domain2.com")
 	ms.IsType(&google.Provider{}, provider, "auth provider not expected google type")
+
+	// should get type oidc:
+	provider, err = o.GetAuthProvider(ms.DB, "test@domain3.com")
+	ms.NoError(err, "unable to get authprovider for test@domain3.com")
+	ms.IsType(&oidc.Provider{}, provider, "auth provider not expected oidc type")
 }
 
 func (ms *ModelSuite) TestOrganizations_FindByIDs() {
@@ -676,3 +721,42 @@ func (ms *ModelSuite) TestOrganizations_FindByIDs() {
 		})
 	}
 }
+
+func (ms *ModelSuite) TestOrganization_SessionPolicy() {
+	tests := []struct {
+		name string
+		org  Organization
+		want SessionPolicy
+	}{
+		{
+			name: "defaults",
+			org:  Organization{},
+			want: defaultSessionPolicy(),
+		},
+		{
+			name: "idle timeout",
+			org:  Organization{SessionIdleTimeoutSeconds: nulls.NewInt(60)},
+			want: SessionPolicy{IdleTimeout: time.Minute, MaxSessions: domain.Env.MaxSessionsPerUser,
+				MaxLifetime: time.Second * time.Duration(domain.Env.AccessTokenMaxLifeSeconds)},
+		},
+		{
+			name: "invalid idle timeout",
+			org:  Organization{SessionIdleTimeoutSeconds: nulls.NewInt(0)},
+			want: defaultSessionPolicy(),
+		},
+		{
+			name: "all limits",
+			org: Organization{
+				SessionIdleTimeoutSeconds: nulls.NewInt(3600),
+				SessionMaxLifetimeSeconds: nulls.NewInt(86400),
+				MaxSessionsPerUser:        nulls.NewInt(2),
+			},
+			want: SessionPolicy{IdleTimeout: time.Hour, MaxLifetime: 24 * time.Hour, MaxSessions: 2},
+		},
+	}
+	for _, tt := range tests {
+		ms.T().Run(tt.name, func(t *testing.T) {
+			ms.Equal(tt.want, tt.org.SessionPolicy())
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/gobuffalo/events"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gobuffalo/validate/v3"
 	"github.com/gobuffalo/validate/v3/validators"
@@ -44,6 +45,28 @@ func (o *OrganizationTrust) ValidateUpdate(tx *pop.Connection) (*validate.Errors
 	return validate.NewErrors(), nil
 }
 
+// AfterCreate is called by Pop after successful creation of the record. The primary organization can now see the
+// TRUSTED requests of the secondary organization.
+func (o *OrganizationTrust) AfterCreate(tx *pop.Connection) error {
+	return o.emitChanged(tx)
+}
+
+// AfterDestroy is called by Pop after successful removal of the record. The primary organization can no longer see
+// the TRUSTED requests of the secondary organization.
+func (o *OrganizationTrust) AfterDestroy(tx *pop.Connection) error {
+	return o.emitChanged(tx)
+}
+
+// emitChanged emits an event for the change of the requests visible to the primary organization
+func (o *OrganizationTrust) emitChanged(tx *pop.Connection) error {
+	e := events.Event{
+		Kind:    domain.EventApiOrganizationTrustChanged,
+		Message: fmt.Sprintf("Organization trust changed: %d - %d", o.PrimaryID, o.SecondaryID),
+		Payload: events.Payload{domain.ArgId: o.PrimaryID},
+	}
+	return emitEvent(tx, "", e)
+}
+
 // CreateSymmetric creates two records to make a two-way trust connection
 func (o *OrganizationTrust) CreateSymmetric(tx *pop.Connection) error {
 	if err := o.Create(tx); err != nil {
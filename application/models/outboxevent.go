@@ -0,0 +1,253 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/events"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/log"
+	"github.com/silinternational/wecarry-api/tracing"
+)
+
+// OutboxEvent is a domain event recorded in the same transaction as the change that raised it. It is delivered to
+// the event listeners only after that transaction commits, and redelivered until every listener has succeeded.
+type OutboxEvent struct {
+	ID                int        `json:"-" db:"id"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+	IdempotencyKey    string     `json:"idempotency_key" db:"idempotency_key"`
+	Kind              string     `json:"kind" db:"kind"`
+	Message           string     `json:"message" db:"message"`
+	Payload           string     `json:"payload" db:"payload"`
+	CompletedHandlers string     `json:"completed_handlers" db:"completed_handlers"`
+	Attempts          int        `json:"attempts" db:"attempts"`
+	LastError         string     `json:"last_error" db:"last_error"`
+	AvailableAt       time.Time  `json:"available_at" db:"available_at"`
+	ProcessedAt       nulls.Time `json:"processed_at" db:"processed_at"`
+}
+
+// OutboxEvents is merely for convenience and brevity
+type OutboxEvents []OutboxEvent
+
+// String can be helpful for serializing the model
+func (o OutboxEvent) String() string {
+	jo, _ := json.Marshal(o)
+	return string(jo)
+}
+
+// outboxEventData decodes the `domain.ArgEventData` payload item of each event kind into the type the listeners expect
+var outboxEventData = map[string]func(json.RawMessage) (interface{}, error){
+	domain.EventApiRequestStatusUpdated: func(raw json.RawMessage) (interface{}, error) {
+		var d RequestStatusEventData
+		err := json.Unmarshal(raw, &d)
+		return d, err
+	},
+	domain.EventApiRequestCreated: func(raw json.RawMessage) (interface{}, error) {
+		var d RequestCreatedEventData
+		err := json.Unmarshal(raw, &d)
+		return d, err
+	},
+	domain.EventApiRequestUpdated: func(raw json.RawMessage) (interface{}, error) {
+		var d RequestUpdatedEventData
+		err := json.Unmarshal(raw, &d)
+		return d, err
+	},
+	domain.EventApiUserDeleted: func(raw json.RawMessage) (interface{}, error) {
+		var d UserDeletedEventData
+		err := json.Unmarshal(raw, &d)
+		return d, err
+	},
+	domain.EventApiPotentialProviderCreated:       decodePotentialProviderEventData,
+	domain.EventApiPotentialProviderRejected:      decodePotentialProviderEventData,
+	domain.EventApiPotentialProviderSelfDestroyed: decodePotentialProviderEventData,
+}
+
+func decodePotentialProviderEventData(raw json.RawMessage) (interface{}, error) {
+	var d PotentialProviderEventData
+	err := json.Unmarshal(raw, &d)
+	return d, err
+}
+
+var (
+	// outboxTransactions holds the open transactions that have written to the outbox
+	outboxTransactions sync.Map
+
+	// outboxDispatcher starts delivery of committed outbox events
+	outboxDispatcher func()
+)
+
+// SetOutboxDispatcher sets the function called when committed events are waiting in the outbox
+func SetOutboxDispatcher(dispatcher func()) {
+	outboxDispatcher = dispatcher
+}
+
+// FinishOutboxTransaction must be called when a transaction ends. If it committed after writing to the outbox, the
+// new events are dispatched right away rather than on the next poll of the outbox.
+func FinishOutboxTransaction(tx *pop.Connection, committed bool) {
+	if tx == nil || tx.TX == nil {
+		return
+	}
+	if _, ok := outboxTransactions.LoadAndDelete(tx.TX); ok && committed {
+		dispatchOutbox()
+	}
+}
+
+func dispatchOutbox() {
+	if outboxDispatcher != nil {
+		outboxDispatcher()
+	}
+}
+
+// emitEvent records an event in the outbox using the given transaction. The key identifies the occurrence of the
+// event, so recording it again has no effect. If the key is empty, every call records a new event. The trace context
+// of the transaction, if any, is added to the payload so that the delivery of the event is part of the same trace.
+func emitEvent(tx *pop.Connection, key string, e events.Event) error {
+	if key == "" {
+		key = domain.GetUUID().String()
+	}
+
+	p := events.Payload{}
+	for k, v := range e.Payload {
+		p[k] = v
+	}
+	if ctx := tx.Context(); tracing.IsTraced(ctx) {
+		p[domain.ArgTraceContext] = tracing.Inject(ctx)
+	}
+
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("error encoding payload of event %s, %w", e.Kind, err)
+	}
+
+	now := time.Now()
+	err = tx.RawQuery(`INSERT INTO outbox_events
+		(created_at, updated_at, idempotency_key, kind, message, payload, available_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (idempotency_key) DO NOTHING`,
+		now, now, e.Kind+":"+key, e.Kind, e.Message, string(payload), now).Exec()
+	if err != nil {
+		return fmt.Errorf("error recording event %s, %w", e.Kind, err)
+	}
+
+	if tx.TX == nil {
+		dispatchOutbox()
+	} else {
+		outboxTransactions.Store(tx.TX, true)
+	}
+	return nil
+}
+
+// FindNextDue finds and locks the oldest event that is ready for delivery and has been attempted fewer than
+// `maxAttempts` times. Events locked by another transaction are skipped.
+func (o *OutboxEvent) FindNextDue(tx *pop.Connection, maxAttempts int) error {
+	return tx.RawQuery(`SELECT * FROM outbox_events
+		WHERE processed_at IS NULL AND available_at <= ? AND attempts < ?
+		ORDER BY available_at, id LIMIT 1 FOR UPDATE SKIP LOCKED`, time.Now(), maxAttempts).First(o)
+}
+
+// Event rebuilds the event as it was emitted, with the payload items in the types the listeners expect
+func (o *OutboxEvent) Event() (events.Event, error) {
+	e := events.Event{Kind: o.Kind, Message: o.Message, Payload: events.Payload{}}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(o.Payload), &raw); err != nil {
+		return e, fmt.Errorf("error decoding payload of outbox event %d, %w", o.ID, err)
+	}
+
+	for name, value := range raw {
+		var item interface{}
+		var err error
+		switch name {
+		case domain.ArgId, domain.ArgMessageID:
+			var id int
+			err = json.Unmarshal(value, &id)
+			item = id
+		case domain.ArgTraceContext:
+			var carrier map[string]string
+			err = json.Unmarshal(value, &carrier)
+			item = carrier
+		case domain.ArgEventData:
+			decode, ok := outboxEventData[o.Kind]
+			if !ok {
+				return e, fmt.Errorf("no event data type for outbox event kind %s", o.Kind)
+			}
+			item, err = decode(value)
+		default:
+			err = json.Unmarshal(value, &item)
+		}
+		if err != nil {
+			return e, fmt.Errorf("error decoding %s in payload of outbox event %d, %w", name, o.ID, err)
+		}
+		e.Payload[name] = item
+	}
+
+	return e, nil
+}
+
+// IsHandlerCompleted returns true if the named handler has already processed the event
+func (o *OutboxEvent) IsHandlerCompleted(handler string) bool {
+	for _, h := range strings.Fields(o.CompletedHandlers) {
+		if h == handler {
+			return true
+		}
+	}
+	return false
+}
+
+// CompleteHandler records that the named handler has processed the event, so it is skipped on redelivery
+func (o *OutboxEvent) CompleteHandler(tx *pop.Connection, handler string) error {
+	if o.IsHandlerCompleted(handler) {
+		return nil
+	}
+	o.CompletedHandlers = strings.TrimSpace(o.CompletedHandlers + " " + handler)
+	return tx.UpdateColumns(o, "completed_handlers", "updated_at")
+}
+
+// MarkProcessed records the successful delivery of the event to all of its handlers
+func (o *OutboxEvent) MarkProcessed(tx *pop.Connection) error {
+	o.Attempts++
+	o.LastError = ""
+	o.ProcessedAt = nulls.NewTime(time.Now())
+	return tx.UpdateColumns(o, "attempts", "last_error", "processed_at", "updated_at")
+}
+
+// MarkFailed records a failed delivery attempt and postpones the next one by `retryDelay` times the square of the
+// number of attempts
+func (o *OutboxEvent) MarkFailed(tx *pop.Connection, deliveryErr error, retryDelay time.Duration) error {
+	if deliveryErr == nil {
+		return errors.New("no error given for failed outbox event")
+	}
+
+	o.Attempts++
+	o.LastError = domain.Truncate(deliveryErr.Error(), "...", 1024)
+	o.AvailableAt = time.Now().Add(retryDelay * time.Duration(o.Attempts*o.Attempts))
+	if err := tx.UpdateColumns(o, "attempts", "last_error", "available_at", "updated_at"); err != nil {
+		return err
+	}
+
+	log.Errorf("delivery of outbox event %d (%s) failed on attempt %d, %s", o.ID, o.Kind, o.Attempts, deliveryErr)
+	return nil
+}
+
+// DeleteProcessed removes events that were delivered before the given time, and returns the number removed
+func (o *OutboxEvents) DeleteProcessed(tx *pop.Connection, before time.Time) (int, error) {
+	var c Count
+	err := tx.RawQuery("SELECT COUNT(*) FROM outbox_events WHERE processed_at < ?", before).First(&c)
+	if err != nil || c.N == 0 {
+		return 0, err
+	}
+
+	err = tx.RawQuery("DELETE FROM outbox_events WHERE processed_at < ?", before).Exec()
+	if err != nil {
+		return 0, err
+	}
+
+	return c.N, nil
+}
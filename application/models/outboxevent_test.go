@@ -0,0 +1,203 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/events"
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+
+	"github.com/silinternational/wecarry-api/domain"
+)
+
+func (ms *ModelSuite) TestEmitEvent() {
+	dispatched := 0
+	SetOutboxDispatcher(func() { dispatched++ })
+	defer SetOutboxDispatcher(nil)
+
+	e := events.Event{
+		Kind:    domain.EventApiRequestCreated,
+		Message: "Request created",
+		Payload: events.Payload{domain.ArgEventData: RequestCreatedEventData{RequestID: 7}},
+	}
+
+	// outside of a transaction, the event is dispatched immediately
+	ms.NoError(emitEvent(ms.DB, "7", e))
+	ms.Equal(1, dispatched)
+
+	// the same key records the event only once
+	ms.NoError(emitEvent(ms.DB, "7", e))
+	n, err := ms.DB.Where("kind = ?", domain.EventApiRequestCreated).Count(&OutboxEvent{})
+	ms.NoError(err)
+	ms.Equal(1, n, "duplicate event was recorded")
+
+	// in a transaction, the event is dispatched after commit
+	var committedTx *pop.Connection
+	ms.NoError(ms.DB.Transaction(func(tx *pop.Connection) error {
+		committedTx = tx
+		return emitEvent(tx, "8", e)
+	}))
+	ms.Equal(2, dispatched, "event should not be dispatched before the transaction ends")
+	FinishOutboxTransaction(committedTx, true)
+	ms.Equal(3, dispatched, "committed event was not dispatched")
+	FinishOutboxTransaction(committedTx, true)
+	ms.Equal(3, dispatched, "event was dispatched twice")
+
+	// a rolled-back event is neither recorded nor dispatched
+	var rolledBackTx *pop.Connection
+	ms.Error(ms.DB.Transaction(func(tx *pop.Connection) error {
+		rolledBackTx = tx
+		ms.NoError(emitEvent(tx, "9", e))
+		return errors.New("roll back")
+	}))
+	FinishOutboxTransaction(rolledBackTx, false)
+	ms.Equal(3, dispatched, "rolled-back event was dispatched")
+	n, err = ms.DB.Where("kind = ?", domain.EventApiRequestCreated).Count(&OutboxEvent{})
+	ms.NoError(err)
+	ms.Equal(2, n, "rolled-back event was recorded")
+}
+
+func (ms *ModelSuite) TestOutboxEvent_Event() {
+	tests := []struct {
+		name    string
+		event   OutboxEvent
+		want    events.Payload
+		wantErr bool
+	}{
+		{
+			name:  "id",
+			event: OutboxEvent{Kind: domain.EventApiUserCreated, Payload: `{"id":3}`},
+			want:  events.Payload{domain.ArgId: 3},
+		},
+		{
+			name:  "message id",
+			event: OutboxEvent{Kind: domain.EventApiMessageCreated, Payload: `{"message_id":4}`},
+			want:  events.Payload{domain.ArgMessageID: 4},
+		},
+		{
+			name: "status event data",
+			event: OutboxEvent{
+				Kind:    domain.EventApiRequestStatusUpdated,
+				Payload: `{"eventData":{"OldStatus":"OPEN","NewStatus":"ACCEPTED","OldProviderID":0,"RequestID":5}}`,
+			},
+			want: events.Payload{domain.ArgEventData: RequestStatusEventData{
+				OldStatus: RequestStatusOpen,
+				NewStatus: RequestStatusAccepted,
+				RequestID: 5,
+			}},
+		},
+		{
+			name: "potential provider event data",
+			event: OutboxEvent{
+				Kind:    domain.EventApiPotentialProviderRejected,
+				Payload: `{"eventData":{"UserID":1,"RequestID":2}}`,
+			},
+			want: events.Payload{domain.ArgEventData: PotentialProviderEventData{UserID: 1, RequestID: 2}},
+		},
+		{
+			name: "user deleted event data",
+			event: OutboxEvent{
+				Kind:    domain.EventApiUserDeleted,
+				Payload: `{"id":6,"email":"a@example.com","eventData":{"FileUUIDs":["f1","f2"]}}`,
+			},
+			want: events.Payload{
+				domain.ArgId:        6,
+				domain.ArgEmail:     "a@example.com",
+				domain.ArgEventData: UserDeletedEventData{FileUUIDs: []string{"f1", "f2"}},
+			},
+		},
+		{
+			name:    "unknown event data",
+			event:   OutboxEvent{Kind: "api:unknown", Payload: `{"eventData":{}}`},
+			wantErr: true,
+		},
+		{
+			name:    "bad payload",
+			event:   OutboxEvent{Kind: domain.EventApiUserCreated, Payload: `[]`},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		ms.T().Run(tt.name, func(t *testing.T) {
+			got, err := tt.event.Event()
+			if tt.wantErr {
+				ms.Error(err)
+				return
+			}
+			ms.NoError(err)
+			ms.Equal(tt.event.Kind, got.Kind)
+			ms.Equal(tt.want, got.Payload)
+		})
+	}
+}
+
+func (ms *ModelSuite) TestOutboxEvent_Delivery() {
+	e := events.Event{Kind: domain.EventApiUserCreated, Payload: events.Payload{domain.ArgId: 1}}
+	ms.NoError(emitEvent(ms.DB, "1", e))
+
+	var o OutboxEvent
+	ms.NoError(o.FindNextDue(ms.DB, 2))
+	ms.Equal(domain.EventApiUserCreated, o.Kind)
+
+	// a handler completes, but the delivery fails
+	ms.NoError(o.CompleteHandler(ms.DB, "logger"))
+	ms.NoError(o.MarkFailed(ms.DB, errors.New("mail server down"), time.Hour))
+
+	var notDue OutboxEvent
+	ms.Error(notDue.FindNextDue(ms.DB, 2), "failed event should not be due before its retry delay")
+
+	var reloaded OutboxEvent
+	ms.NoError(ms.DB.Find(&reloaded, o.ID))
+	ms.Equal(1, reloaded.Attempts)
+	ms.Equal("mail server down", reloaded.LastError)
+	ms.True(reloaded.IsHandlerCompleted("logger"))
+	ms.False(reloaded.IsHandlerCompleted("welcome_message"))
+	ms.WithinDuration(time.Now().Add(time.Hour), reloaded.AvailableAt, time.Minute)
+
+	// retry
+	ms.NoError(ms.DB.RawQuery("UPDATE outbox_events SET available_at = ?", time.Now().Add(-time.Second)).Exec())
+	var retry OutboxEvent
+	ms.NoError(retry.FindNextDue(ms.DB, 2))
+	ms.NoError(retry.MarkProcessed(ms.DB))
+
+	var done OutboxEvent
+	ms.Error(done.FindNextDue(ms.DB, 2), "processed event should not be due")
+	ms.NoError(ms.DB.Find(&reloaded, o.ID))
+	ms.Equal(2, reloaded.Attempts)
+	ms.True(reloaded.ProcessedAt.Valid)
+	ms.Equal("", reloaded.LastError)
+
+	// attempts exhausted
+	ms.NoError(emitEvent(ms.DB, "2", e))
+	ms.NoError(ms.DB.RawQuery("UPDATE outbox_events SET attempts = 2 WHERE processed_at IS NULL").Exec())
+	var exhausted OutboxEvent
+	ms.Error(exhausted.FindNextDue(ms.DB, 2), "event with no attempts left should not be due")
+}
+
+func (ms *ModelSuite) TestOutboxEvents_DeleteProcessed() {
+	now := time.Now()
+	fixtures := []OutboxEvent{
+		{IdempotencyKey: "old", ProcessedAt: nulls.NewTime(now.Add(-48 * time.Hour))},
+		{IdempotencyKey: "new", ProcessedAt: nulls.NewTime(now)},
+		{IdempotencyKey: "pending"},
+	}
+	for i := range fixtures {
+		fixtures[i].Kind = domain.EventApiUserCreated
+		fixtures[i].Payload = "{}"
+		fixtures[i].AvailableAt = now
+		createFixture(ms, &fixtures[i])
+	}
+
+	var o OutboxEvents
+	deleted, err := o.DeleteProcessed(ms.DB, now.Add(-24*time.Hour))
+	ms.NoError(err)
+	ms.Equal(1, deleted)
+
+	var remaining OutboxEvents
+	ms.NoError(ms.DB.Order("id").All(&remaining))
+	ms.Equal(2, len(remaining))
+	ms.Equal("new", remaining[0].IdempotencyKey)
+	ms.Equal("pending", remaining[1].IdempotencyKey)
+}
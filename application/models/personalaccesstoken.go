@@ -0,0 +1,283 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/domain"
+)
+
+// PersonalAccessTokenPrefix is prepended to every personal access token to distinguish it from a session token
+const PersonalAccessTokenPrefix = "wcpat_"
+
+type TokenScope string
+
+const (
+	TokenScopeEventsAdmin   TokenScope = "events:admin"
+	TokenScopeEventsRead    TokenScope = "events:read"
+	TokenScopeMessagesRead  TokenScope = "messages:read"
+	TokenScopeMessagesWrite TokenScope = "messages:write"
+	TokenScopeRequestsRead  TokenScope = "requests:read"
+	TokenScopeRequestsWrite TokenScope = "requests:write"
+	TokenScopeWatchesRead   TokenScope = "watches:read"
+	TokenScopeWatchesWrite  TokenScope = "watches:write"
+)
+
+func (s TokenScope) IsValid() bool {
+	switch s {
+	case TokenScopeEventsAdmin, TokenScopeEventsRead, TokenScopeMessagesRead, TokenScopeMessagesWrite,
+		TokenScopeRequestsRead, TokenScopeRequestsWrite, TokenScopeWatchesRead, TokenScopeWatchesWrite:
+		return true
+	}
+	return false
+}
+
+func (s TokenScope) String() string {
+	return string(s)
+}
+
+// PersonalAccessToken is a named, long-lived credential created by a User for scripted access to the API. Unlike a
+// UserAccessToken, it is limited to the scopes chosen at creation.
+type PersonalAccessToken struct {
+	ID         int        `json:"-" db:"id"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+	UUID       uuid.UUID  `json:"uuid" db:"uuid"`
+	UserID     int        `json:"user_id" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	Scopes     string     `json:"scopes" db:"scopes"`
+	ExpiresAt  nulls.Time `json:"expires_at" db:"expires_at"`
+	LastUsedAt nulls.Time `json:"last_used_at" db:"last_used_at"`
+	LastIP     string     `json:"last_ip" db:"last_ip"`
+	User       User       `belongs_to:"users"`
+}
+
+// String can be helpful for serializing the model
+func (p PersonalAccessToken) String() string {
+	jp, _ := json.Marshal(p)
+	return string(jp)
+}
+
+// PersonalAccessTokens is merely for convenience and brevity
+type PersonalAccessTokens []PersonalAccessToken
+
+// String can be helpful for serializing the model
+func (p PersonalAccessTokens) String() string {
+	jp, _ := json.Marshal(p)
+	return string(jp)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (p *PersonalAccessToken) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: p.UUID, Name: "UUID"},
+		&validators.IntIsPresent{Field: p.UserID, Name: "UserID"},
+		&validators.StringIsPresent{Field: p.Name, Name: "Name"},
+		&validators.StringLengthInRange{Field: p.Name, Name: "Name", Max: 255},
+		&validators.StringIsPresent{Field: p.TokenHash, Name: "TokenHash"},
+		&scopesValidator{Field: p.Scopes, Name: "Scopes"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+func (p *PersonalAccessToken) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+func (p *PersonalAccessToken) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+type scopesValidator struct {
+	Name    string
+	Field   string
+	Message string
+}
+
+// IsValid ensures that at least one scope is given and that all of the scopes are known
+func (v *scopesValidator) IsValid(errors *validate.Errors) {
+	scopes := strings.Fields(v.Field)
+	if len(scopes) == 0 {
+		errors.Add(validators.GenerateKey(v.Name), "at least one scope is required")
+		return
+	}
+
+	for _, s := range scopes {
+		if !TokenScope(s).IsValid() {
+			errors.Add(validators.GenerateKey(v.Name), fmt.Sprintf("invalid scope '%s'", s))
+		}
+	}
+}
+
+// Create stores the PersonalAccessToken data as a new record in the database.
+func (p *PersonalAccessToken) Create(tx *pop.Connection) error {
+	return create(tx, p)
+}
+
+// Update writes the PersonalAccessToken data to an existing database record.
+func (p *PersonalAccessToken) Update(tx *pop.Connection) error {
+	return update(tx, p)
+}
+
+// FindByBearerToken loads the PersonalAccessToken matching the given bearer token
+func (p *PersonalAccessToken) FindByBearerToken(tx *pop.Connection, bearerToken string) error {
+	if err := tx.Where("token_hash = ?", HashClientIdAccessToken(bearerToken)).First(p); err != nil {
+		return fmt.Errorf("failed to find personal access token, %w", err)
+	}
+	return nil
+}
+
+// FindByUUID loads the PersonalAccessToken identified by the given UUID
+func (p *PersonalAccessToken) FindByUUID(tx *pop.Connection, id string) error {
+	return tx.Where("uuid = ?", id).First(p)
+}
+
+// FindByUser returns all of the PersonalAccessTokens of the given User
+func (p *PersonalAccessTokens) FindByUser(tx *pop.Connection, user User) error {
+	if user.ID <= 0 {
+		return errors.New("invalid user ID in PersonalAccessTokens.FindByUser")
+	}
+
+	return tx.Where("user_id = ?", user.ID).Order("name").All(p)
+}
+
+// DeleteByUser removes all of the PersonalAccessTokens of the given User
+func (p *PersonalAccessTokens) DeleteByUser(tx *pop.Connection, user User) error {
+	if user.ID <= 0 {
+		return errors.New("invalid user ID in PersonalAccessTokens.DeleteByUser")
+	}
+
+	return tx.RawQuery("DELETE FROM personal_access_tokens WHERE user_id = ?", user.ID).Exec()
+}
+
+// DeleteForOwner removes the PersonalAccessToken identified by `id` if it belongs to `user`. Returns the UUID of the
+// deleted token.
+func (p *PersonalAccessToken) DeleteForOwner(tx *pop.Connection, id string, user User) (string, *api.AppError) {
+	if err := p.FindByUUID(tx, id); err != nil || p.UserID != user.ID {
+		if err == nil {
+			err = errors.New("personal access token belongs to a different user")
+		}
+		return "", api.NewAppError(err, api.ErrorPersonalAccessTokenNotFound, api.CategoryNotFound)
+	}
+
+	if err := tx.Destroy(p); err != nil {
+		return "", api.NewAppError(err, api.ErrorPersonalAccessTokenDeleteFailure, api.CategoryDatabase)
+	}
+
+	return p.UUID.String(), nil
+}
+
+// IsExpired returns true if the token has an expiration time and it is in the past
+func (p *PersonalAccessToken) IsExpired() bool {
+	return p.ExpiresAt.Valid && p.ExpiresAt.Time.Before(time.Now())
+}
+
+// GetScopes returns the list of scopes granted to the token
+func (p *PersonalAccessToken) GetScopes() []TokenScope {
+	fields := strings.Fields(p.Scopes)
+	scopes := make([]TokenScope, len(fields))
+	for i := range fields {
+		scopes[i] = TokenScope(fields[i])
+	}
+	return scopes
+}
+
+// SetScopes replaces the token's scopes. Duplicates are removed.
+func (p *PersonalAccessToken) SetScopes(scopes []TokenScope) {
+	seen := map[TokenScope]bool{}
+	list := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		list = append(list, s.String())
+	}
+	p.Scopes = strings.Join(list, " ")
+}
+
+// HasScope returns true if the token was granted the given scope
+func (p *PersonalAccessToken) HasScope(scope TokenScope) bool {
+	for _, s := range p.GetScopes() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Touch records the use of the token from the given IP address. To limit database writes, this only happens if the
+// token has not been used recently or the IP address has changed.
+func (p *PersonalAccessToken) Touch(tx *pop.Connection, ip string) error {
+	if p.LastUsedAt.Valid && time.Since(p.LastUsedAt.Time) < domain.AccessTokenTouchInterval && p.LastIP == ip {
+		return nil
+	}
+
+	p.LastUsedAt = nulls.NewTime(time.Now())
+	p.LastIP = ip
+	if err := p.Update(tx); err != nil {
+		return fmt.Errorf("error recording use of personal access token, %w", err)
+	}
+	return nil
+}
+
+// GetUser returns the User who owns the token
+func (p *PersonalAccessToken) GetUser(tx *pop.Connection) (User, error) {
+	if err := tx.Load(p, "User"); err != nil {
+		return User{}, err
+	}
+	if p.User.ID <= 0 {
+		return User{}, errors.New("no user associated with personal access token")
+	}
+	return p.User, nil
+}
+
+// ConvertPersonalAccessTokens converts a list of PersonalAccessTokens to api.PersonalAccessTokens
+func ConvertPersonalAccessTokens(tokens PersonalAccessTokens) api.PersonalAccessTokens {
+	output := make(api.PersonalAccessTokens, len(tokens))
+	for i := range tokens {
+		output[i] = ConvertPersonalAccessToken(tokens[i])
+	}
+	return output
+}
+
+// ConvertPersonalAccessToken converts a PersonalAccessToken to api.PersonalAccessToken. The secret token value
+// is not stored and is therefore never included.
+func ConvertPersonalAccessToken(token PersonalAccessToken) api.PersonalAccessToken {
+	scopes := token.GetScopes()
+	output := api.PersonalAccessToken{
+		ID:        token.UUID,
+		Name:      token.Name,
+		Scopes:    make([]string, len(scopes)),
+		CreatedAt: token.CreatedAt,
+		LastIP:    token.LastIP,
+	}
+
+	for i := range scopes {
+		output.Scopes[i] = scopes[i].String()
+	}
+
+	if token.ExpiresAt.Valid {
+		t := token.ExpiresAt.Time
+		output.ExpiresAt = &t
+	}
+
+	if token.LastUsedAt.Valid {
+		t := token.LastUsedAt.Time
+		output.LastUsedAt = &t
+	}
+
+	return output
+}
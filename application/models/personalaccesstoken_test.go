@@ -0,0 +1,137 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+
+	"github.com/silinternational/wecarry-api/domain"
+)
+
+func (ms *ModelSuite) TestPersonalAccessToken_Validate() {
+	t := ms.T()
+	tests := []struct {
+		name     string
+		token    PersonalAccessToken
+		wantErr  bool
+		errField string
+	}{
+		{
+			name: "minimum",
+			token: PersonalAccessToken{UUID: domain.GetUUID(), UserID: 1, Name: "n", TokenHash: "h",
+				Scopes: "requests:read"},
+			wantErr: false,
+		},
+		{
+			name:     "missing name",
+			token:    PersonalAccessToken{UUID: domain.GetUUID(), UserID: 1, TokenHash: "h", Scopes: "requests:read"},
+			wantErr:  true,
+			errField: "name",
+		},
+		{
+			name:     "missing scopes",
+			token:    PersonalAccessToken{UUID: domain.GetUUID(), UserID: 1, Name: "n", TokenHash: "h"},
+			wantErr:  true,
+			errField: "scopes",
+		},
+		{
+			name: "invalid scope",
+			token: PersonalAccessToken{UUID: domain.GetUUID(), UserID: 1, Name: "n", TokenHash: "h",
+				Scopes: "requests:read users:admin"},
+			wantErr:  true,
+			errField: "scopes",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vErr, _ := test.token.Validate(DB)
+			if test.wantErr {
+				if vErr.Count() == 0 {
+					t.Errorf("Expected an error, but did not get one")
+				} else if len(vErr.Get(test.errField)) == 0 {
+					t.Errorf("Expected an error on field %v, but got none (errors: %v)", test.errField, vErr.Errors)
+				}
+			} else if (test.wantErr == false) && (vErr.HasAny()) {
+				t.Errorf("Unexpected error: %v", vErr)
+			}
+		})
+	}
+}
+
+func (ms *ModelSuite) TestPersonalAccessToken_Scopes() {
+	var pat PersonalAccessToken
+	pat.SetScopes([]TokenScope{TokenScopeRequestsRead, TokenScopeEventsAdmin, TokenScopeRequestsRead})
+
+	ms.Equal("requests:read events:admin", pat.Scopes)
+	ms.Equal([]TokenScope{TokenScopeRequestsRead, TokenScopeEventsAdmin}, pat.GetScopes())
+	ms.True(pat.HasScope(TokenScopeEventsAdmin))
+	ms.False(pat.HasScope(TokenScopeRequestsWrite))
+}
+
+func (ms *ModelSuite) TestUser_CreatePersonalAccessToken() {
+	uf := createUserFixtures(ms.DB, 2)
+	user := uf.Users[0]
+
+	pat, token, err := user.CreatePersonalAccessToken(ms.DB, "reports",
+		[]TokenScope{TokenScopeRequestsRead}, nulls.Time{})
+	ms.NoError(err)
+	ms.Contains(token, PersonalAccessTokenPrefix)
+	ms.NotEqual(token, pat.TokenHash, "token must not be stored in plain text")
+
+	var found PersonalAccessToken
+	ms.NoError(found.FindByBearerToken(ms.DB, token))
+	ms.Equal(pat.ID, found.ID)
+	ms.False(found.IsExpired())
+
+	owner, err := found.GetUser(ms.DB)
+	ms.NoError(err)
+	ms.Equal(user.ID, owner.ID)
+
+	_, _, err = user.CreatePersonalAccessToken(ms.DB, "reports", []TokenScope{TokenScopeRequestsRead}, nulls.Time{})
+	ms.Error(err, "expected an error for a duplicate name")
+
+	_, _, err = user.CreatePersonalAccessToken(ms.DB, "old", []TokenScope{TokenScopeRequestsRead},
+		nulls.NewTime(time.Now().Add(-time.Hour)))
+	ms.Error(err, "expected an error for an expiration in the past")
+
+	ms.Error(found.FindByBearerToken(ms.DB, PersonalAccessTokenPrefix+"bogus"))
+}
+
+func (ms *ModelSuite) TestPersonalAccessToken_DeleteForOwner() {
+	uf := createUserFixtures(ms.DB, 2)
+	owner := uf.Users[0]
+
+	pat, _, err := owner.CreatePersonalAccessToken(ms.DB, "t", []TokenScope{TokenScopeRequestsRead}, nulls.Time{})
+	ms.NoError(err)
+
+	var p PersonalAccessToken
+	_, appErr := p.DeleteForOwner(ms.DB, pat.UUID.String(), uf.Users[1])
+	ms.NotNil(appErr, "expected an error deleting another user's token")
+
+	id, appErr := p.DeleteForOwner(ms.DB, pat.UUID.String(), owner)
+	ms.Nil(appErr)
+	ms.Equal(pat.UUID.String(), id)
+
+	var tokens PersonalAccessTokens
+	ms.NoError(tokens.FindByUser(ms.DB, owner))
+	ms.Equal(0, len(tokens))
+}
+
+func (ms *ModelSuite) TestPersonalAccessTokens_DeleteByUser() {
+	uf := createUserFixtures(ms.DB, 2)
+
+	for _, u := range uf.Users {
+		_, _, err := u.CreatePersonalAccessToken(ms.DB, "t", []TokenScope{TokenScopeRequestsRead}, nulls.Time{})
+		ms.NoError(err)
+	}
+
+	var tokens PersonalAccessTokens
+	ms.Error(tokens.DeleteByUser(ms.DB, User{}), "expected an error for a user without an ID")
+	ms.NoError(tokens.DeleteByUser(ms.DB, uf.Users[0]))
+
+	ms.NoError(tokens.FindByUser(ms.DB, uf.Users[0]))
+	ms.Equal(0, len(tokens), "user's tokens were not deleted")
+	ms.NoError(tokens.FindByUser(ms.DB, uf.Users[1]))
+	ms.Equal(1, len(tokens), "other user's token was deleted")
+}
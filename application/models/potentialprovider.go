@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/gobuffalo/events"
@@ -108,9 +109,7 @@ func (p *PotentialProvider) Create(tx *pop.Connection) error {
 		Payload: events.Payload{domain.ArgEventData: eventData},
 	}
 
-	emitEvent(e)
-
-	return nil
+	return emitEvent(tx, strconv.Itoa(p.ID), e)
 }
 
 // Update writes the PotentialProvider data to an existing database record.
@@ -0,0 +1,513 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/domain"
+)
+
+type ReportSubjectType string
+
+const (
+	ReportSubjectRequest ReportSubjectType = "REQUEST"
+	ReportSubjectMessage ReportSubjectType = "MESSAGE"
+	ReportSubjectUser    ReportSubjectType = "USER"
+)
+
+func (e ReportSubjectType) IsValid() bool {
+	switch e {
+	case ReportSubjectRequest, ReportSubjectMessage, ReportSubjectUser:
+		return true
+	}
+	return false
+}
+
+func (e ReportSubjectType) String() string {
+	return string(e)
+}
+
+type ReportReason string
+
+const (
+	ReportReasonSpam          ReportReason = "SPAM"
+	ReportReasonAbuse         ReportReason = "ABUSE"
+	ReportReasonFraud         ReportReason = "FRAUD"
+	ReportReasonInappropriate ReportReason = "INAPPROPRIATE"
+	ReportReasonOther         ReportReason = "OTHER"
+)
+
+func (e ReportReason) IsValid() bool {
+	switch e {
+	case ReportReasonSpam, ReportReasonAbuse, ReportReasonFraud, ReportReasonInappropriate, ReportReasonOther:
+		return true
+	}
+	return false
+}
+
+func (e ReportReason) String() string {
+	return string(e)
+}
+
+type ReportStatus string
+
+const (
+	ReportStatusOpen      ReportStatus = "OPEN"
+	ReportStatusDismissed ReportStatus = "DISMISSED"
+	ReportStatusActioned  ReportStatus = "ACTIONED"
+)
+
+func (e ReportStatus) IsValid() bool {
+	switch e {
+	case ReportStatusOpen, ReportStatusDismissed, ReportStatusActioned:
+		return true
+	}
+	return false
+}
+
+func (e ReportStatus) String() string {
+	return string(e)
+}
+
+type ReportAction string
+
+const (
+	ReportActionHideRequest ReportAction = "hide_request"
+	ReportActionSuspendUser ReportAction = "suspend_user"
+	ReportActionDismiss     ReportAction = "dismiss"
+)
+
+func (e ReportAction) IsValid() bool {
+	switch e {
+	case ReportActionHideRequest, ReportActionSuspendUser, ReportActionDismiss:
+		return true
+	}
+	return false
+}
+
+func (e ReportAction) String() string {
+	return string(e)
+}
+
+// Report is a report by a User of a request, message or user that may be abusive, fraudulent or otherwise
+// inappropriate. Open reports are reviewed by moderators, who resolve them by taking a ReportAction.
+type Report struct {
+	ID             int               `json:"-" db:"id"`
+	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at" db:"updated_at"`
+	UUID           uuid.UUID         `json:"uuid" db:"uuid"`
+	ReporterID     int               `json:"reporter_id" db:"reporter_id"`
+	SubjectType    ReportSubjectType `json:"subject_type" db:"subject_type"`
+	ReportedUserID int               `json:"reported_user_id" db:"reported_user_id"`
+	RequestID      nulls.Int         `json:"request_id" db:"request_id"`
+	MessageID      nulls.Int         `json:"message_id" db:"message_id"`
+	OrganizationID nulls.Int         `json:"organization_id" db:"organization_id"`
+	Reason         ReportReason      `json:"reason" db:"reason"`
+	Comment        nulls.String      `json:"comment" db:"comment"`
+	Status         ReportStatus      `json:"status" db:"status"`
+	Action         nulls.String      `json:"action" db:"action"`
+	ResolvedByID   nulls.Int         `json:"resolved_by_id" db:"resolved_by_id"`
+	ResolvedAt     nulls.Time        `json:"resolved_at" db:"resolved_at"`
+}
+
+// String can be helpful for serializing the model
+func (r Report) String() string {
+	jr, _ := json.Marshal(r)
+	return string(jr)
+}
+
+// Reports is merely for convenience and brevity
+type Reports []Report
+
+// String can be helpful for serializing the model
+func (r Reports) String() string {
+	jr, _ := json.Marshal(r)
+	return string(jr)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (r *Report) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Field: r.UUID, Name: "UUID"},
+		&validators.IntIsPresent{Field: r.ReporterID, Name: "ReporterID"},
+		&validators.IntIsPresent{Field: r.ReportedUserID, Name: "ReportedUserID"},
+		&validators.IntsAreNotEqual{ValueOne: r.ReporterID, ValueTwo: r.ReportedUserID, Name: "ReportedEqualsReporter"},
+		&validators.StringInclusion{Field: r.SubjectType.String(), Name: "SubjectType",
+			List: []string{ReportSubjectRequest.String(), ReportSubjectMessage.String(), ReportSubjectUser.String()}},
+		&validators.StringInclusion{Field: r.Reason.String(), Name: "Reason",
+			List: []string{ReportReasonSpam.String(), ReportReasonAbuse.String(), ReportReasonFraud.String(),
+				ReportReasonInappropriate.String(), ReportReasonOther.String()}},
+		&validators.StringInclusion{Field: r.Status.String(), Name: "Status",
+			List: []string{ReportStatusOpen.String(), ReportStatusDismissed.String(), ReportStatusActioned.String()}},
+		&validators.StringLengthInRange{Field: r.Comment.String, Name: "Comment", Max: domain.MaxReportCommentLength},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+func (r *Report) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+func (r *Report) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// Create stores the Report data as a new record in the database.
+func (r *Report) Create(tx *pop.Connection) error {
+	return create(tx, r)
+}
+
+// Update writes the Report data to an existing database record.
+func (r *Report) Update(tx *pop.Connection) error {
+	return update(tx, r)
+}
+
+// FindByUUID loads the Report identified by the given UUID
+func (r *Report) FindByUUID(tx *pop.Connection, id string) error {
+	if err := tx.Where("uuid = ?", id).First(r); err != nil {
+		return fmt.Errorf("error finding report by uuid, %w", err)
+	}
+	return nil
+}
+
+// CreateForRequest reports the request identified by `id`, which must be visible to the reporter
+func (r *Report) CreateForRequest(tx *pop.Connection, reporter User, id string, input api.ReportInput) error {
+	var request Request
+	if err := request.FindByUUIDForCurrentUser(tx, id, reporter); err != nil {
+		return api.NewAppError(err, api.ErrorReportSubjectNotFound, api.CategoryNotFound)
+	}
+
+	r.SubjectType = ReportSubjectRequest
+	r.ReportedUserID = request.CreatedByID
+	r.RequestID = nulls.NewInt(request.ID)
+	r.OrganizationID = nulls.NewInt(request.OrganizationID)
+	return r.createFromInput(tx, reporter, input)
+}
+
+// CreateForMessage reports the message identified by `id`, which must be in one of the reporter's threads
+func (r *Report) CreateForMessage(tx *pop.Connection, reporter User, id string, input api.ReportInput) error {
+	var message Message
+	if err := message.FindByUserAndUUID(tx, reporter, id); err != nil {
+		return api.NewAppError(err, api.ErrorReportSubjectNotFound, api.CategoryNotFound)
+	}
+
+	thread, err := message.GetThread(tx)
+	if err != nil {
+		return api.NewAppError(err, api.ErrorReportCreateFailure, api.CategoryInternal)
+	}
+	if err := thread.LoadRequest(tx); err != nil {
+		return api.NewAppError(err, api.ErrorReportCreateFailure, api.CategoryInternal)
+	}
+
+	r.SubjectType = ReportSubjectMessage
+	r.ReportedUserID = message.SentByID
+	r.MessageID = nulls.NewInt(message.ID)
+	r.RequestID = nulls.NewInt(thread.RequestID)
+	r.OrganizationID = nulls.NewInt(thread.Request.OrganizationID)
+	return r.createFromInput(tx, reporter, input)
+}
+
+// CreateForUser reports the user identified by `id`
+func (r *Report) CreateForUser(tx *pop.Connection, reporter User, id string, input api.ReportInput) error {
+	var user User
+	if err := user.FindByUUID(tx, id); err != nil {
+		return api.NewAppError(err, api.ErrorReportSubjectNotFound, api.CategoryNotFound)
+	}
+
+	r.SubjectType = ReportSubjectUser
+	r.ReportedUserID = user.ID
+	return r.createFromInput(tx, reporter, input)
+}
+
+// createFromInput completes and stores a new report of the subject already set on the report. If the reporter
+// already has an open report of the same subject, that report is loaded instead.
+func (r *Report) createFromInput(tx *pop.Connection, reporter User, input api.ReportInput) error {
+	reason := ReportReason(input.Reason)
+	if !reason.IsValid() {
+		err := fmt.Errorf("invalid report reason '%s'", input.Reason)
+		return api.NewAppError(err, api.ErrorReportInputInvalid, api.CategoryUser)
+	}
+	if r.ReportedUserID == reporter.ID {
+		err := errors.New("users cannot report themselves or their own requests and messages")
+		return api.NewAppError(err, api.ErrorReportInputInvalid, api.CategoryUser)
+	}
+	if input.Comment != nil && len(*input.Comment) > domain.MaxReportCommentLength {
+		err := fmt.Errorf("report comment is longer than %d characters", domain.MaxReportCommentLength)
+		return api.NewAppError(err, api.ErrorReportInputInvalid, api.CategoryUser)
+	}
+
+	q := tx.Where("reporter_id = ? AND subject_type = ? AND reported_user_id = ? AND status = ?",
+		reporter.ID, r.SubjectType, r.ReportedUserID, ReportStatusOpen)
+	if r.RequestID.Valid {
+		q = q.Where("request_id = ?", r.RequestID.Int)
+	}
+	if r.MessageID.Valid {
+		q = q.Where("message_id = ?", r.MessageID.Int)
+	}
+	var existing Report
+	if err := q.First(&existing); err == nil {
+		*r = existing
+		return nil
+	} else if domain.IsOtherThanNoRows(err) {
+		return api.NewAppError(err, api.ErrorReportCreateFailure, api.CategoryInternal)
+	}
+
+	r.ReporterID = reporter.ID
+	r.Reason = reason
+	r.Status = ReportStatusOpen
+	if input.Comment != nil && *input.Comment != "" {
+		r.Comment = nulls.NewString(*input.Comment)
+	}
+
+	if err := r.Create(tx); err != nil {
+		return api.NewAppError(err, api.ErrorReportCreateFailure, api.CategoryInternal)
+	}
+	return nil
+}
+
+// FindForModerator returns the reports with the given status that the moderator is allowed to review, oldest first.
+// Super admins can review all reports. Organization admins can review the reports of requests and messages of their
+// organizations, and the reports of members of their organizations.
+func (r *Reports) FindForModerator(tx *pop.Connection, moderator User, status ReportStatus) error {
+	q := tx.Where("status = ?", status)
+
+	if !moderator.isSuperAdmin() {
+		adminOrgs := "SELECT organization_id FROM user_organizations WHERE user_id = ? AND role = ?"
+		// pop joins the clauses with AND but no parentheses, so the OR must be wrapped
+		q = q.Where("(organization_id IN ("+adminOrgs+") OR reported_user_id IN ("+
+			"SELECT user_id FROM user_organizations WHERE organization_id IN ("+adminOrgs+")))",
+			moderator.ID, UserOrganizationRoleAdmin, moderator.ID, UserOrganizationRoleAdmin)
+	}
+
+	if err := q.Order("created_at asc").All(r); err != nil {
+		return fmt.Errorf("error finding reports for moderator %s, %w", moderator.UUID, err)
+	}
+	return nil
+}
+
+// FindByUUIDForModerator loads the Report identified by the given UUID if the moderator is allowed to review it
+func (r *Report) FindByUUIDForModerator(tx *pop.Connection, id string, moderator User) *api.AppError {
+	if err := r.FindByUUID(tx, id); err != nil {
+		appError := api.NewAppError(err, api.ErrorReportNotFound, api.CategoryNotFound)
+		if domain.IsOtherThanNoRows(err) {
+			appError.Category = api.CategoryInternal
+		}
+		return appError
+	}
+
+	if !r.canModerate(tx, moderator) {
+		err := fmt.Errorf("user %s may not moderate report %s", moderator.UUID, r.UUID)
+		return api.NewAppError(err, api.ErrorNotAuthorized, api.CategoryForbidden)
+	}
+	return nil
+}
+
+// canModerate returns true if the given user may review the report. The rules are those of Reports.FindForModerator.
+func (r *Report) canModerate(tx *pop.Connection, moderator User) bool {
+	return moderator.isSuperAdmin() || r.canModerateRequest(tx, moderator) || r.canModerateUser(tx, moderator)
+}
+
+// canModerateRequest returns true if the given user is an admin of the organization of the reported request or message
+func (r *Report) canModerateRequest(tx *pop.Connection, moderator User) bool {
+	if moderator.isSuperAdmin() {
+		return true
+	}
+	if !r.OrganizationID.Valid {
+		return false
+	}
+
+	orgIDs, err := moderator.getAdminOrgIDs(tx)
+	if err != nil {
+		return false
+	}
+	for _, id := range orgIDs {
+		if id == r.OrganizationID.Int {
+			return true
+		}
+	}
+	return false
+}
+
+// canModerateUser returns true if the given user is an admin of an organization of the reported user
+func (r *Report) canModerateUser(tx *pop.Connection, moderator User) bool {
+	if moderator.isSuperAdmin() {
+		return true
+	}
+
+	orgIDs, err := moderator.getAdminOrgIDs(tx)
+	if err != nil || len(orgIDs) == 0 {
+		return false
+	}
+
+	exists, err := tx.Where("user_id = ?", r.ReportedUserID).
+		Where("organization_id IN (?)", convertSliceFromIntToInterface(orgIDs)...).
+		Exists(&UserOrganization{})
+	return err == nil && exists
+}
+
+// Resolve takes the given moderation action and marks the report as resolved by the moderator. A `hide_request`
+// action removes the reported request, or the request of the reported message, and requires the moderator to be an
+// admin of its organization. A `suspend_user` action suspends the reported user, and requires the moderator to be an
+// admin of one of the user's organizations. Super admins may take any action.
+func (r *Report) Resolve(tx *pop.Connection, moderator User, action ReportAction) *api.AppError {
+	if !action.IsValid() {
+		err := fmt.Errorf("invalid report action '%s'", action)
+		return api.NewAppError(err, api.ErrorReportActionInvalid, api.CategoryUser)
+	}
+	if r.Status != ReportStatusOpen {
+		err := fmt.Errorf("report %s is already %s", r.UUID, r.Status)
+		return api.NewAppError(err, api.ErrorReportAlreadyResolved, api.CategoryUser)
+	}
+
+	r.Status = ReportStatusActioned
+	switch action {
+	case ReportActionHideRequest:
+		if appErr := r.hideRequest(tx, moderator); appErr != nil {
+			return appErr
+		}
+	case ReportActionSuspendUser:
+		if appErr := r.suspendUser(tx, moderator); appErr != nil {
+			return appErr
+		}
+	case ReportActionDismiss:
+		r.Status = ReportStatusDismissed
+	}
+
+	r.Action = nulls.NewString(action.String())
+	r.ResolvedByID = nulls.NewInt(moderator.ID)
+	r.ResolvedAt = nulls.NewTime(time.Now())
+	if err := r.Update(tx); err != nil {
+		return api.NewAppError(err, api.ErrorReportActionFailure, api.CategoryInternal)
+	}
+	return nil
+}
+
+func (r *Report) hideRequest(tx *pop.Connection, moderator User) *api.AppError {
+	if !r.RequestID.Valid {
+		err := fmt.Errorf("report %s is not of a request or message", r.UUID)
+		return api.NewAppError(err, api.ErrorReportActionInvalid, api.CategoryUser)
+	}
+	if !r.canModerateRequest(tx, moderator) {
+		err := fmt.Errorf("user %s may not hide the request of report %s", moderator.UUID, r.UUID)
+		return api.NewAppError(err, api.ErrorNotAuthorized, api.CategoryForbidden)
+	}
+
+	var request Request
+	if err := request.FindByID(tx, r.RequestID.Int); err != nil {
+		return api.NewAppError(err, api.ErrorReportActionFailure, api.CategoryInternal)
+	}
+	if request.Status == RequestStatusRemoved {
+		return nil
+	}
+
+	if ok, err := isTransitionValid(request.Status, RequestStatusRemoved); err != nil || !ok {
+		if err == nil {
+			err = fmt.Errorf("request %s with status %s cannot be removed", request.UUID, request.Status)
+		}
+		return api.NewAppError(err, api.ErrorReportActionInvalid, api.CategoryUser)
+	}
+
+	request.Status = RequestStatusRemoved
+	if err := request.Update(tx); err != nil {
+		return api.NewAppError(err, api.ErrorReportActionFailure, api.CategoryInternal)
+	}
+	return nil
+}
+
+func (r *Report) suspendUser(tx *pop.Connection, moderator User) *api.AppError {
+	if !r.canModerateUser(tx, moderator) {
+		err := fmt.Errorf("user %s may not suspend the reported user of report %s", moderator.UUID, r.UUID)
+		return api.NewAppError(err, api.ErrorNotAuthorized, api.CategoryForbidden)
+	}
+
+	var user User
+	if err := user.FindByID(tx, r.ReportedUserID); err != nil {
+		return api.NewAppError(err, api.ErrorReportActionFailure, api.CategoryInternal)
+	}
+	if err := user.Suspend(tx); err != nil {
+		return api.NewAppError(err, api.ErrorReportActionFailure, api.CategoryInternal)
+	}
+	return nil
+}
+
+// ConvertReports converts a list of Reports to api.Reports
+func ConvertReports(ctx context.Context, reports Reports) (api.Reports, error) {
+	output := make(api.Reports, len(reports))
+	for i := range reports {
+		var err error
+		if output[i], err = ConvertReport(ctx, reports[i]); err != nil {
+			return nil, err
+		}
+	}
+	return output, nil
+}
+
+// ConvertReport converts a Report to api.Report
+func ConvertReport(ctx context.Context, report Report) (api.Report, error) {
+	tx := Tx(ctx)
+
+	output := api.Report{
+		ID:          report.UUID,
+		SubjectType: report.SubjectType.String(),
+		Reason:      report.Reason.String(),
+		Comment:     report.Comment,
+		Status:      report.Status.String(),
+		Action:      report.Action,
+		ResolvedAt:  report.ResolvedAt,
+		CreatedAt:   report.CreatedAt,
+	}
+
+	var err error
+	if output.Reporter, err = convertUserByID(ctx, report.ReporterID); err != nil {
+		return api.Report{}, err
+	}
+	if output.ReportedUser, err = convertUserByID(ctx, report.ReportedUserID); err != nil {
+		return api.Report{}, err
+	}
+	if report.ResolvedByID.Valid {
+		resolvedBy, err := convertUserByID(ctx, report.ResolvedByID.Int)
+		if err != nil {
+			return api.Report{}, err
+		}
+		output.ResolvedBy = &resolvedBy
+	}
+
+	if report.RequestID.Valid {
+		var request Request
+		if err := request.FindByID(tx, report.RequestID.Int); err != nil {
+			return api.Report{}, err
+		}
+		output.RequestID = &request.UUID
+		output.RequestTitle = nulls.NewString(request.Title)
+	}
+
+	if report.MessageID.Valid {
+		var message Message
+		if err := tx.Find(&message, report.MessageID.Int); err != nil {
+			return api.Report{}, fmt.Errorf("error finding reported message, %w", err)
+		}
+		output.MessageID = &message.UUID
+		output.MessageContent = nulls.NewString(message.Content)
+	}
+
+	return output, nil
+}
+
+func convertUserByID(ctx context.Context, id int) (api.User, error) {
+	var user User
+	if err := user.FindByID(Tx(ctx), id); err != nil {
+		return api.User{}, err
+	}
+	return ConvertUser(ctx, user)
+}
@@ -0,0 +1,340 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gofrs/uuid"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/domain"
+)
+
+type reportFixtures struct {
+	Users
+	Requests
+	Messages
+}
+
+// createReportFixtures creates 4 users in the same organization, of which users[3] is an admin of the organization,
+// a request by users[0], and a message from users[1] to users[0] on that request
+func createReportFixtures(ms *ModelSuite) reportFixtures {
+	uf := createUserFixtures(ms.DB, 4)
+	users := uf.Users
+
+	uf.UserOrganizations[3].Role = UserOrganizationRoleAdmin
+	ms.NoError(ms.DB.UpdateColumns(&uf.UserOrganizations[3], "role"))
+
+	requests := createRequestFixtures(ms.DB, 1, false, users[0].ID)
+
+	var message Message
+	ms.NoError(message.CreateFromInput(ms.DB, users[1], api.MessageInput{
+		Content:   "spam spam spam",
+		RequestID: requests[0].UUID.String(),
+	}))
+
+	return reportFixtures{Users: users, Requests: requests, Messages: Messages{message}}
+}
+
+func (ms *ModelSuite) TestReport_Create() {
+	t := ms.T()
+	f := createReportFixtures(ms)
+	longComment := strings.Repeat("x", domain.MaxReportCommentLength+1)
+
+	tests := []struct {
+		name         string
+		reporter     User
+		subject      ReportSubjectType
+		id           string
+		input        api.ReportInput
+		wantUserID   int
+		wantErr      api.ErrorKey
+		wantCategory api.ErrorCategory
+	}{
+		{
+			name:       "request",
+			reporter:   f.Users[2],
+			subject:    ReportSubjectRequest,
+			id:         f.Requests[0].UUID.String(),
+			input:      api.ReportInput{Reason: ReportReasonFraud.String()},
+			wantUserID: f.Users[0].ID,
+		},
+		{
+			name:       "message",
+			reporter:   f.Users[0],
+			subject:    ReportSubjectMessage,
+			id:         f.Messages[0].UUID.String(),
+			input:      api.ReportInput{Reason: ReportReasonSpam.String()},
+			wantUserID: f.Users[1].ID,
+		},
+		{
+			name:       "user",
+			reporter:   f.Users[2],
+			subject:    ReportSubjectUser,
+			id:         f.Users[1].UUID.String(),
+			input:      api.ReportInput{Reason: ReportReasonAbuse.String()},
+			wantUserID: f.Users[1].ID,
+		},
+		{
+			name:         "message not in the reporter's threads",
+			reporter:     f.Users[2],
+			subject:      ReportSubjectMessage,
+			id:           f.Messages[0].UUID.String(),
+			input:        api.ReportInput{Reason: ReportReasonSpam.String()},
+			wantErr:      api.ErrorReportSubjectNotFound,
+			wantCategory: api.CategoryNotFound,
+		},
+		{
+			name:         "request not found",
+			reporter:     f.Users[2],
+			subject:      ReportSubjectRequest,
+			id:           domain.GetUUID().String(),
+			input:        api.ReportInput{Reason: ReportReasonSpam.String()},
+			wantErr:      api.ErrorReportSubjectNotFound,
+			wantCategory: api.CategoryNotFound,
+		},
+		{
+			name:         "own request",
+			reporter:     f.Users[0],
+			subject:      ReportSubjectRequest,
+			id:           f.Requests[0].UUID.String(),
+			input:        api.ReportInput{Reason: ReportReasonSpam.String()},
+			wantErr:      api.ErrorReportInputInvalid,
+			wantCategory: api.CategoryUser,
+		},
+		{
+			name:         "invalid reason",
+			reporter:     f.Users[2],
+			subject:      ReportSubjectUser,
+			id:           f.Users[1].UUID.String(),
+			input:        api.ReportInput{Reason: "BORING"},
+			wantErr:      api.ErrorReportInputInvalid,
+			wantCategory: api.CategoryUser,
+		},
+		{
+			name:         "comment too long",
+			reporter:     f.Users[2],
+			subject:      ReportSubjectUser,
+			id:           f.Users[1].UUID.String(),
+			input:        api.ReportInput{Reason: ReportReasonOther.String(), Comment: &longComment},
+			wantErr:      api.ErrorReportInputInvalid,
+			wantCategory: api.CategoryUser,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var report Report
+			var err error
+			switch tt.subject {
+			case ReportSubjectRequest:
+				err = report.CreateForRequest(ms.DB, tt.reporter, tt.id, tt.input)
+			case ReportSubjectMessage:
+				err = report.CreateForMessage(ms.DB, tt.reporter, tt.id, tt.input)
+			case ReportSubjectUser:
+				err = report.CreateForUser(ms.DB, tt.reporter, tt.id, tt.input)
+			}
+
+			if tt.wantErr != "" {
+				ms.Error(err)
+				appErr, ok := err.(*api.AppError)
+				ms.True(ok, "error is not an AppError: %s", err)
+				ms.Equal(tt.wantErr, appErr.Key, "incorrect error key")
+				ms.Equal(tt.wantCategory, appErr.Category, "incorrect error category")
+				return
+			}
+			ms.NoError(err)
+
+			ms.Equal(tt.subject, report.SubjectType)
+			ms.Equal(tt.wantUserID, report.ReportedUserID, "incorrect reported user")
+			ms.Equal(tt.reporter.ID, report.ReporterID)
+			ms.Equal(ReportStatusOpen, report.Status)
+
+			var again Report
+			switch tt.subject {
+			case ReportSubjectRequest:
+				err = again.CreateForRequest(ms.DB, tt.reporter, tt.id, tt.input)
+			case ReportSubjectMessage:
+				err = again.CreateForMessage(ms.DB, tt.reporter, tt.id, tt.input)
+			case ReportSubjectUser:
+				err = again.CreateForUser(ms.DB, tt.reporter, tt.id, tt.input)
+			}
+			ms.NoError(err)
+			ms.Equal(report.UUID, again.UUID, "a second report of the same item should not be created")
+		})
+	}
+}
+
+func (ms *ModelSuite) TestReports_FindForModerator() {
+	f := createReportFixtures(ms)
+
+	var requestReport, userReport Report
+	ms.NoError(requestReport.CreateForRequest(ms.DB, f.Users[2], f.Requests[0].UUID.String(),
+		api.ReportInput{Reason: ReportReasonFraud.String()}))
+	ms.NoError(userReport.CreateForUser(ms.DB, f.Users[2], f.Users[1].UUID.String(),
+		api.ReportInput{Reason: ReportReasonAbuse.String()}))
+
+	// a report of a user of another organization, who cannot be moderated by the organization's admin
+	org := Organization{AuthConfig: "{}"}
+	createFixture(ms, &org)
+	outsider := createUserFixtures(ms.DB, 1).Users[0]
+	ms.NoError(ms.DB.RawQuery("UPDATE user_organizations SET organization_id = ? WHERE user_id = ?",
+		org.ID, outsider.ID).Exec())
+	var outsiderReport Report
+	ms.NoError(outsiderReport.CreateForUser(ms.DB, f.Users[2], outsider.UUID.String(),
+		api.ReportInput{Reason: ReportReasonSpam.String()}))
+
+	// a dismissed report of a member, which is not in anyone's open queue
+	var dismissedReport Report
+	ms.NoError(dismissedReport.CreateForUser(ms.DB, f.Users[0], f.Users[1].UUID.String(),
+		api.ReportInput{Reason: ReportReasonSpam.String()}))
+	ms.NoError(ms.DB.RawQuery("UPDATE reports SET status = ? WHERE id = ?",
+		ReportStatusDismissed, dismissedReport.ID).Exec())
+
+	superAdmin := f.Users[1]
+	superAdmin.AdminRole = UserAdminRoleSuperAdmin
+
+	tests := []struct {
+		name      string
+		moderator User
+		want      []uuid.UUID
+	}{
+		{
+			name:      "org admin",
+			moderator: f.Users[3],
+			want:      []uuid.UUID{requestReport.UUID, userReport.UUID},
+		},
+		{
+			name:      "super admin",
+			moderator: superAdmin,
+			want:      []uuid.UUID{requestReport.UUID, userReport.UUID, outsiderReport.UUID},
+		},
+		{
+			name:      "user",
+			moderator: f.Users[2],
+			want:      []uuid.UUID{},
+		},
+	}
+	for _, tt := range tests {
+		ms.T().Run(tt.name, func(t *testing.T) {
+			var reports Reports
+			ms.NoError(reports.FindForModerator(ms.DB, tt.moderator, ReportStatusOpen))
+
+			got := make([]uuid.UUID, len(reports))
+			for i := range reports {
+				got[i] = reports[i].UUID
+			}
+			ms.Equal(tt.want, got)
+
+			for _, r := range reports {
+				var report Report
+				ms.Nil(report.FindByUUIDForModerator(ms.DB, r.UUID.String(), tt.moderator))
+			}
+		})
+	}
+
+	var report Report
+	appErr := report.FindByUUIDForModerator(ms.DB, outsiderReport.UUID.String(), f.Users[3])
+	ms.NotNil(appErr, "org admin should not be able to moderate a report of another organization's user")
+	ms.Equal(api.ErrorNotAuthorized, appErr.Key)
+}
+
+func (ms *ModelSuite) TestReport_Resolve() {
+	t := ms.T()
+	f := createReportFixtures(ms)
+	admin := f.Users[3]
+
+	_, _, err := f.Users[1].CreatePersonalAccessToken(ms.DB, "t", []TokenScope{TokenScopeRequestsRead}, nulls.Time{})
+	ms.NoError(err)
+
+	newReport := func(subject ReportSubjectType) Report {
+		var r Report
+		input := api.ReportInput{Reason: ReportReasonOther.String()}
+		switch subject {
+		case ReportSubjectRequest:
+			ms.NoError(r.CreateForRequest(ms.DB, f.Users[2], f.Requests[0].UUID.String(), input))
+		case ReportSubjectMessage:
+			ms.NoError(r.CreateForMessage(ms.DB, f.Users[0], f.Messages[0].UUID.String(), input))
+		case ReportSubjectUser:
+			ms.NoError(r.CreateForUser(ms.DB, f.Users[2], f.Users[1].UUID.String(), input))
+		}
+		return r
+	}
+
+	tests := []struct {
+		name       string
+		subject    ReportSubjectType
+		action     ReportAction
+		wantStatus ReportStatus
+		wantErr    api.ErrorKey
+	}{
+		{
+			name:    "invalid action",
+			subject: ReportSubjectUser,
+			action:  "delete_user",
+			wantErr: api.ErrorReportActionInvalid,
+		},
+		{
+			name:    "hide request of a user report",
+			subject: ReportSubjectUser,
+			action:  ReportActionHideRequest,
+			wantErr: api.ErrorReportActionInvalid,
+		},
+		{
+			name:       "dismiss",
+			subject:    ReportSubjectUser,
+			action:     ReportActionDismiss,
+			wantStatus: ReportStatusDismissed,
+		},
+		{
+			name:       "suspend user",
+			subject:    ReportSubjectMessage,
+			action:     ReportActionSuspendUser,
+			wantStatus: ReportStatusActioned,
+		},
+		{
+			name:       "hide request",
+			subject:    ReportSubjectRequest,
+			action:     ReportActionHideRequest,
+			wantStatus: ReportStatusActioned,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := newReport(tt.subject)
+
+			appErr := report.Resolve(ms.DB, admin, tt.action)
+			if tt.wantErr != "" {
+				ms.NotNil(appErr)
+				ms.Equal(tt.wantErr, appErr.Key, "incorrect error key")
+				return
+			}
+			ms.Nil(appErr)
+
+			var stored Report
+			ms.NoError(stored.FindByUUID(ms.DB, report.UUID.String()))
+			ms.Equal(tt.wantStatus, stored.Status)
+			ms.Equal(tt.action.String(), stored.Action.String)
+			ms.Equal(admin.ID, stored.ResolvedByID.Int)
+			ms.True(stored.ResolvedAt.Valid)
+
+			appErr = stored.Resolve(ms.DB, admin, tt.action)
+			ms.NotNil(appErr, "a resolved report should not be resolved again")
+			ms.Equal(api.ErrorReportAlreadyResolved, appErr.Key)
+		})
+	}
+
+	var request Request
+	ms.NoError(request.FindByID(ms.DB, f.Requests[0].ID))
+	ms.Equal(RequestStatusRemoved, request.Status, "request was not hidden")
+
+	var user User
+	ms.NoError(user.FindByID(ms.DB, f.Users[1].ID))
+	ms.True(user.IsSuspended(), "user was not suspended")
+	n, err := ms.DB.Where("user_id = ?", user.ID).Count(&UserAccessToken{})
+	ms.NoError(err)
+	ms.Equal(0, n, "sessions of the suspended user were not ended")
+	n, err = ms.DB.Where("user_id = ?", user.ID).Count(&PersonalAccessToken{})
+	ms.NoError(err)
+	ms.Equal(0, n, "personal access tokens of the suspended user were not revoked")
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -30,6 +31,7 @@ const (
 	RequestStatusReceived  RequestStatus = "RECEIVED"
 	RequestStatusCompleted RequestStatus = "COMPLETED"
 	RequestStatusRemoved   RequestStatus = "REMOVED"
+	RequestStatusExpired   RequestStatus = "EXPIRED"
 
 	RequestActionReopen       = "reopen"
 	RequestActionOffer        = "offer"
@@ -39,12 +41,14 @@ const (
 	RequestActionReceive      = "receive"
 	// RequestActionComplete     = "complete"  //  For now Receiving a Request makes it Completed
 	RequestActionRemove = "remove"
+	RequestActionRenew  = "renew"
 )
 
 type StatusTransitionTarget struct {
 	Status           RequestStatus
 	IsBackStep       bool
 	isProviderAction bool
+	isSystemAction   bool
 }
 
 type RequestVisibility string
@@ -72,6 +76,7 @@ func allStatusTransitions() map[RequestStatus][]StatusTransitionTarget {
 		RequestStatusOpen: {
 			{Status: RequestStatusAccepted},
 			{Status: RequestStatusRemoved},
+			{Status: RequestStatusExpired, isSystemAction: true}, // by the outdated requests job
 		},
 		RequestStatusAccepted: {
 			{Status: RequestStatusOpen, IsBackStep: true}, // to correct a false acceptance
@@ -95,6 +100,10 @@ func allStatusTransitions() map[RequestStatus][]StatusTransitionTarget {
 			//	{Status: RequestStatusReceived, IsBackStep: true, isProviderAction: true}, // to correct a false completion
 		},
 		RequestStatusRemoved: {},
+		RequestStatusExpired: {
+			{Status: RequestStatusOpen}, // only by renewing, which sets a new needed_before date
+			{Status: RequestStatusRemoved},
+		},
 	}
 }
 
@@ -154,7 +163,7 @@ func statusActions() map[RequestStatus]string {
 func (e RequestStatus) IsValid() bool {
 	switch e {
 	case RequestStatusOpen, RequestStatusAccepted, RequestStatusDelivered, RequestStatusReceived,
-		RequestStatusCompleted, RequestStatusRemoved:
+		RequestStatusCompleted, RequestStatusRemoved, RequestStatusExpired:
 		return true
 	}
 	return false
@@ -185,6 +194,7 @@ type Request struct {
 	OriginID       nulls.Int         `json:"origin_id" db:"origin_id"`
 	MeetingID      nulls.Int         `json:"meeting_id" db:"meeting_id"`
 	Visibility     RequestVisibility `json:"visibility" db:"visibility"`
+	RemindedAt     nulls.Time        `json:"reminded_at" db:"reminded_at"`
 
 	CreatedBy    User         `json:"-" belongs_to:"users"`
 	Organization Organization `json:"-" belongs_to:"organizations"`
@@ -235,6 +245,51 @@ func (r *Request) Update(tx *pop.Connection) error {
 	return update(tx, r)
 }
 
+// Duplicate creates a new OPEN request with the details of this one, for the same creator. The destination and
+// origin are copied, and the meeting is kept if it has not ended. Photos and files are not copied, since a file can
+// only be attached to one record. The new request is needed before the given date, if any.
+func (r *Request) Duplicate(tx *pop.Connection, neededBefore nulls.Time) (Request, error) {
+	request := Request{
+		CreatedByID:    r.CreatedByID,
+		OrganizationID: r.OrganizationID,
+		Status:         RequestStatusOpen,
+		Title:          r.Title,
+		Description:    r.Description,
+		Size:           r.Size,
+		URL:            r.URL,
+		Kilograms:      r.Kilograms,
+		Visibility:     r.Visibility,
+		NeededBefore:   neededBefore,
+	}
+
+	var err error
+	if request.DestinationID, err = copyLocation(tx, r.DestinationID); err != nil {
+		return request, err
+	}
+	if r.OriginID.Valid {
+		originID, err := copyLocation(tx, r.OriginID.Int)
+		if err != nil {
+			return request, err
+		}
+		request.OriginID = nulls.NewInt(originID)
+	}
+
+	if r.MeetingID.Valid {
+		var meeting Meeting
+		if err := tx.Find(&meeting, r.MeetingID.Int); err != nil {
+			return request, fmt.Errorf("error finding meeting of request %s, %w", r.UUID, err)
+		}
+		if !meeting.EndDate.Before(today()) {
+			request.MeetingID = r.MeetingID
+		}
+	}
+
+	if err := request.Create(tx); err != nil {
+		return request, err
+	}
+	return request, nil
+}
+
 func (r *Request) NewWithUser(currentUser User) error {
 	r.CreatedByID = currentUser.ID
 	r.Status = RequestStatusOpen
@@ -430,7 +485,9 @@ func (r *Request) manageStatusTransition(tx *pop.Connection) error {
 		Payload: events.Payload{domain.ArgEventData: eventData},
 	}
 
-	emitEvent(e)
+	if err := emitEvent(tx, "", e); err != nil {
+		return err
+	}
 
 	// If completed, hydrate CompletedOn. If not completed, nullify CompletedOn
 	// Don't use r.UpdateColumns, due to this being called by the AfterUpdate function
@@ -460,7 +517,7 @@ func (r *Request) manageStatusTransition(tx *pop.Connection) error {
 		}
 	}
 
-	if r.Status == RequestStatusCompleted {
+	if r.Status == RequestStatusCompleted || r.Status == RequestStatusExpired {
 		p := PotentialProviders{}
 		if err = tx.Select("id").Where("request_id = ?", r.ID).All(&p); domain.IsOtherThanNoRows(err) {
 			return errors.New("unable to find Request's Potential Providers in order to remove them: " + err.Error())
@@ -473,22 +530,20 @@ func (r *Request) manageStatusTransition(tx *pop.Connection) error {
 	return nil
 }
 
-// AfterUpdate ensures there is no provider on an Open Request
+// AfterUpdate ensures there is no provider on an Open Request, and emits an event for the change
 func (r *Request) AfterUpdate(tx *pop.Connection) error {
 	if err := r.manageStatusTransition(tx); err != nil {
 		return err
 	}
 
-	if r.Status != RequestStatusOpen {
-		return nil
-	}
+	if r.Status == RequestStatusOpen {
+		r.ProviderID = nulls.Int{}
 
-	r.ProviderID = nulls.Int{}
-
-	// Don't try to use tx.Update inside AfterUpdate, since that gets into an eternal loop
-	if err := tx.RawQuery(
-		fmt.Sprintf(`UPDATE requests set provider_id = NULL where ID = %v`, r.ID)).Exec(); err != nil {
-		log.Errorf("error removing provider id from request: %s", err.Error())
+		// Don't try to use tx.Update inside AfterUpdate, since that gets into an eternal loop
+		if err := tx.RawQuery(
+			fmt.Sprintf(`UPDATE requests set provider_id = NULL where ID = %v`, r.ID)).Exec(); err != nil {
+			log.Errorf("error removing provider id from request: %s", err.Error())
+		}
 	}
 
 	e := events.Event{
@@ -499,9 +554,7 @@ func (r *Request) AfterUpdate(tx *pop.Connection) error {
 		}},
 	}
 
-	emitEvent(e)
-
-	return nil
+	return emitEvent(tx, "", e)
 }
 
 // AfterCreate is called by Pop after successful creation of the record
@@ -523,8 +576,26 @@ func (r *Request) AfterCreate(tx *pop.Connection) error {
 		}},
 	}
 
-	emitEvent(e)
-	return nil
+	return emitEvent(tx, strconv.Itoa(r.ID), e)
+}
+
+// CountRequestsByStatus counts the requests that are not yet completed or removed, by status
+func CountRequestsByStatus(tx *pop.Connection) (map[RequestStatus]int, error) {
+	var rows []struct {
+		Status RequestStatus `db:"status"`
+		Count  int           `db:"count"`
+	}
+	err := tx.RawQuery("SELECT status, COUNT(*) AS count FROM requests WHERE status NOT IN (?, ?) GROUP BY status",
+		RequestStatusCompleted, RequestStatusRemoved).All(&rows)
+	if err != nil {
+		return nil, fmt.Errorf("error counting requests by status, %w", err)
+	}
+
+	counts := map[RequestStatus]int{}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
 }
 
 func (r *Requests) FindOpenPastNeededBefore(tx *pop.Connection, eagerFields ...string) error {
@@ -536,6 +607,73 @@ func (r *Requests) FindOpenPastNeededBefore(tx *pop.Connection, eagerFields ...s
 	return nil
 }
 
+// FindOpenNeedingReminder finds the open requests that are needed before the given time, but not yet past their
+// needed_before date, and whose creators have not been reminded
+func (r *Requests) FindOpenNeedingReminder(tx *pop.Connection, before time.Time, eagerFields ...string) error {
+	now := time.Now().UTC()
+	queryStr := "status = ? AND reminded_at IS NULL AND needed_before >= ? AND needed_before < ?"
+	if err := tx.Where(queryStr, RequestStatusOpen, now, before).Eager(eagerFields...).All(r); err != nil {
+		return fmt.Errorf("error finding open requests that need a reminder: %s", err.Error())
+	}
+	return nil
+}
+
+// FindOpenToExpire finds the open requests that were needed before the given time
+func (r *Requests) FindOpenToExpire(tx *pop.Connection, before time.Time, eagerFields ...string) error {
+	queryStr := "status = ? AND needed_before IS NOT NULL AND needed_before < ?"
+	if err := tx.Where(queryStr, RequestStatusOpen, before).Eager(eagerFields...).All(r); err != nil {
+		return fmt.Errorf("error finding open requests to expire: %s", err.Error())
+	}
+	return nil
+}
+
+// MarkReminded records that the creator of the request has been reminded of its needed_before date. It is not a
+// change visible to users, so no event is emitted.
+func (r *Request) MarkReminded(tx *pop.Connection) error {
+	now := time.Now().UTC()
+	if err := tx.RawQuery("UPDATE requests SET reminded_at = ? WHERE id = ?", now, r.ID).Exec(); err != nil {
+		return fmt.Errorf("error marking request %d as reminded, %w", r.ID, err)
+	}
+	r.RemindedAt = nulls.NewTime(now)
+	return nil
+}
+
+// Expire moves an open request to EXPIRED status, which withdraws all offers to carry it. The users who had offered
+// are returned so they can be notified.
+func (r *Request) Expire(tx *pop.Connection) (Users, error) {
+	if r.Status != RequestStatusOpen {
+		return nil, fmt.Errorf("cannot expire request %s, its status is %s", r.UUID, r.Status)
+	}
+
+	var providers PotentialProviders
+	users, err := providers.FindUsersByRequestID(tx, *r, User{})
+	if err != nil {
+		return nil, err
+	}
+
+	r.Status = RequestStatusExpired
+	if err := r.Update(tx); err != nil {
+		return nil, fmt.Errorf("error expiring request %s, %w", r.UUID, err)
+	}
+	return users, nil
+}
+
+// Renew moves an expired request back to OPEN status with a new needed_before date, which must be after today
+func (r *Request) Renew(tx *pop.Connection, neededBefore time.Time) error {
+	if r.Status != RequestStatusExpired {
+		return fmt.Errorf("cannot renew request %s, its status is %s", r.UUID, r.Status)
+	}
+	if !neededBefore.After(today()) {
+		return fmt.Errorf("cannot renew request %s, the new date %s is not after today", r.UUID,
+			neededBefore.Format(domain.DateFormat))
+	}
+
+	r.Status = RequestStatusOpen
+	r.NeededBefore = nulls.NewTime(neededBefore)
+	r.RemindedAt = nulls.Time{}
+	return r.Update(tx)
+}
+
 func (r *Request) FindByID(tx *pop.Connection, id int, eagerFields ...string) error {
 	if id <= 0 {
 		return errors.New("error finding request: id must a positive number")
@@ -601,6 +739,9 @@ func (r *Request) GetStatusTransitions(currentUser User) ([]StatusTransitionTarg
 	finalOptions := []StatusTransitionTarget{}
 
 	for _, o := range statusOptions {
+		if o.isSystemAction {
+			continue
+		}
 		// User is the Creator - sees all but Provider's actions
 		if currentUser.ID == r.CreatedByID && !o.isProviderAction {
 			finalOptions = append(finalOptions, o)
@@ -830,10 +971,11 @@ func (r *Requests) FindByUser(tx *pop.Connection, user User, filter RequestFilte
 			)
 		) AND visibility = ?
 	)
-	AND status not in (?, ?)`
+	AND status not in (?, ?, ?)
+	AND created_by_id NOT IN (` + blockedBySQL + `)`
 	args := []interface{}{
 		user.ID, RequestVisibilityAll, RequestVisibilityTrusted, RequestStatusRemoved,
-		RequestStatusCompleted,
+		RequestStatusCompleted, RequestStatusExpired, user.ID,
 	}
 
 	return r.findBySelectClause(tx, filter, selectClause, args, fmt.Sprintf("user %s", user.UUID.String()))
@@ -852,12 +994,12 @@ func (r *Requests) FindByOrganization(tx *pop.Connection, organization Organizat
 			)
 		) AND visibility = ?
 	)
-	AND status not in (?, ?)
+	AND status not in (?, ?, ?)
 	`
 
 	args := []interface{}{
 		organization.ID, RequestVisibilitySame, RequestVisibilityTrusted, organization.ID,
-		RequestVisibilityTrusted, RequestStatusRemoved, RequestStatusCompleted,
+		RequestVisibilityTrusted, RequestStatusRemoved, RequestStatusCompleted, RequestStatusExpired,
 	}
 
 	return r.findBySelectClause(tx, filter, selectClause, args, fmt.Sprintf("organization %s", organization.UUID.String()))
@@ -866,11 +1008,11 @@ func (r *Requests) FindByOrganization(tx *pop.Connection, organization Organizat
 // FindPublic finds all public requests visible to all WeCarry users
 func (r *Requests) FindPublic(tx *pop.Connection, filter RequestFilterParams) error {
 	selectClause := `
-	SELECT * FROM requests WHERE visibility = ? AND status not in (?, ?)
+	SELECT * FROM requests WHERE visibility = ? AND status not in (?, ?, ?)
 	`
 
 	args := []interface{}{
-		RequestVisibilityAll, RequestStatusRemoved, RequestStatusCompleted,
+		RequestVisibilityAll, RequestStatusRemoved, RequestStatusCompleted, RequestStatusExpired,
 	}
 
 	return r.findBySelectClause(tx, filter, selectClause, args, "all WeCarry userss")
@@ -998,6 +1140,11 @@ func (r *Request) isRequestEditable() bool {
 }
 
 func (r *Request) canCreatorChangeStatus(newStatus RequestStatus) bool {
+	// Only the outdated requests job expires a request, and only renewing reopens it
+	if newStatus == RequestStatusExpired || r.Status == RequestStatusExpired && newStatus == RequestStatusOpen {
+		return false
+	}
+
 	// Creator can't move off of Delivered except to Completed
 	if r.Status == RequestStatusDelivered {
 		return newStatus == RequestStatusCompleted
@@ -1037,23 +1184,124 @@ func (r *Request) canUserChangeStatus(user User, newStatus RequestStatus) bool {
 	return false
 }
 
-// GetAudience returns a list of all of the users which have visibility to this request. As of this writing, it is
-// simply the users in the organization associated with this request.
+// requestAudienceSQL selects the request identified by the first query argument as `r`, with its creator's nickname,
+// and the IDs of the users who can see it as `audience`. The visibility rules are those of Requests.FindByUser plus
+// Meeting.Requests. Users blocked by the request creator are not in the audience.
+const requestAudienceSQL = `
+	WITH r AS (
+		SELECT * FROM requests WHERE id = ?
+	),
+	members AS (
+		SELECT uo.user_id FROM user_organizations uo JOIN r ON uo.organization_id = r.organization_id
+		UNION
+		SELECT uo.user_id FROM user_organizations uo
+			JOIN organization_trusts ot ON ot.primary_id = uo.organization_id
+			JOIN r ON ot.secondary_id = r.organization_id
+			WHERE r.visibility = '` + string(RequestVisibilityTrusted) + `'
+		UNION
+		SELECT uo.user_id FROM user_organizations uo CROSS JOIN r
+			WHERE r.visibility = '` + string(RequestVisibilityAll) + `'
+		UNION
+		SELECT mp.user_id FROM meeting_participants mp JOIN r ON mp.meeting_id = r.meeting_id
+	),
+	audience AS (
+		SELECT user_id FROM members WHERE user_id NOT IN (
+			SELECT ub.blocked_id FROM user_blocks ub JOIN r ON ub.blocker_id = r.created_by_id
+		)
+	)`
+
+// GetAudience returns a list of all of the users who can see this request: the members of its organization, the
+// members of organizations that trust its organization if its visibility is TRUSTED, the members of any organization
+// if it is public, and the participants of its meeting.
 func (r *Request) GetAudience(tx *pop.Connection) (Users, error) {
 	if r.ID <= 0 {
 		return nil, errors.New("invalid request ID in GetAudience")
 	}
-	org, err := r.GetOrganization(tx)
-	if err != nil {
-		return nil, err
+
+	users := Users{}
+	q := tx.RawQuery(requestAudienceSQL+` SELECT * FROM users WHERE id IN (SELECT user_id FROM audience) ORDER BY id`,
+		r.ID)
+	if err := q.All(&users); err != nil {
+		return nil, fmt.Errorf("unable to get request audience, %s", err)
 	}
-	users, err := org.GetUsers(tx)
-	if err != nil {
-		return nil, fmt.Errorf("unable to get request organization user list, %s", err.Error())
+	return users, nil
+}
+
+// GetNotificationAudience returns the users in the audience of this request who want to be notified of it, because
+// its origin is near their location or it matches one of their active watches. The request creator is not included.
+func (r *Request) GetNotificationAudience(tx *pop.Connection) (Users, error) {
+	if r.ID <= 0 {
+		return nil, errors.New("invalid request ID in GetNotificationAudience")
+	}
+
+	users := Users{}
+	now := time.Now()
+	q := tx.RawQuery(requestNotificationAudienceSQL+" ORDER BY u.id", r.ID, now, now)
+	if err := q.All(&users); err != nil {
+		return nil, fmt.Errorf("unable to get request notification audience, %s", err)
 	}
 	return users, nil
 }
 
+// requestNotificationAudienceSQL selects the users in the audience of a request who want to be notified of it. The
+// parameters are the request ID and, twice, the current time.
+var requestNotificationAudienceSQL = requestAudienceSQL + `
+	SELECT u.* FROM users u
+	JOIN audience a ON a.user_id = u.id
+	CROSS JOIN r
+	WHERE u.id <> r.created_by_id AND (
+		EXISTS (
+			SELECT 1 FROM locations ul JOIN locations ro ON ro.id = r.origin_id
+			WHERE ul.id = u.location_id AND ` + sqlDistanceKm("ul", "ro") + ` < ` + sqlProximityKm + `
+		)
+		OR EXISTS (
+			SELECT 1 FROM watches w
+			WHERE w.owner_id = u.id
+			AND (w.paused_until IS NULL OR w.paused_until <= ?)
+			AND (w.expires_at IS NULL OR w.expires_at > ?)
+			AND ` + watchMatchesRequestSQL + `
+		)
+	)`
+
+// watchMatchesRequestSQL is the SQL equivalent of the criteria of Watch.matchesRequest, for the watch `w` and the
+// request `r`. It does not check whether the watch is active.
+var watchMatchesRequestSQL = `
+	(w.size IS NULL OR ` + sqlSizeRank("w.size") + ` <= ` + sqlSizeRank("r.size") + `)
+	AND (w.min_size IS NULL OR ` + sqlSizeRank("r.size") + ` <= ` + sqlSizeRank("w.min_size") + `)
+	AND (r.needed_before IS NULL OR (
+		(w.needed_before_start IS NULL OR r.needed_before >= w.needed_before_start)
+		AND (w.needed_before_end IS NULL OR r.needed_before <= w.needed_before_end)
+	))
+	AND (w.search_text IS NULL
+		OR strpos(r.title, w.search_text) > 0
+		OR strpos(COALESCE(r.description, ''), w.search_text) > 0
+		OR strpos((SELECT nickname FROM users WHERE users.id = r.created_by_id), w.search_text) > 0)
+	AND (w.meeting_id IS NULL OR w.meeting_id = r.meeting_id)
+	AND (w.destination_id IS NULL OR EXISTS (
+		SELECT 1 FROM locations wd JOIN locations rd ON rd.id = r.destination_id
+		WHERE wd.id = w.destination_id AND ` + sqlDistanceKm("wd", "rd") + ` < COALESCE(w.radius_km, ` + sqlProximityKm + `)
+	))
+	AND (w.origin_id IS NULL OR EXISTS (
+		SELECT 1 FROM locations wo JOIN locations ro ON ro.id = r.origin_id
+		WHERE wo.id = w.origin_id AND ` + sqlDistanceKm("wo", "ro") + ` < COALESCE(w.radius_km, ` + sqlProximityKm + `)
+	))`
+
+// sqlProximityKm is the distance below which two locations are near each other, as used by Location.IsNear
+var sqlProximityKm = strconv.Itoa(domain.DefaultProximityDistanceKm)
+
+// sqlDistanceKm is the SQL equivalent of Location.DistanceKm for the locations with table aliases `a` and `b`
+func sqlDistanceKm(a, b string) string {
+	return fmt.Sprintf(`12742 * asin(sqrt(GREATEST(0, LEAST(1, 0.5 - cos(radians(%[2]s.latitude - %[1]s.latitude))/2
+		+ cos(radians(%[1]s.latitude)) * cos(radians(%[2]s.latitude))
+		* (1 - cos(radians(%[2]s.longitude - %[1]s.longitude)))/2))))`, a, b)
+}
+
+// sqlSizeRank is the SQL equivalent of the ordering used by RequestSize.isLargerOrSame
+func sqlSizeRank(column string) string {
+	return fmt.Sprintf(`CASE %s WHEN '%s' THEN 5 WHEN '%s' THEN 4 WHEN '%s' THEN 3 WHEN '%s' THEN 2 WHEN '%s' THEN 1
+		ELSE 0 END`, column, RequestSizeTiny, RequestSizeSmall, RequestSizeMedium, RequestSizeLarge, RequestSizeXlarge)
+}
+
 // GetMeeting reads the meeting record, if it exists, and returns a pointer to the object.
 func (r *Request) GetMeeting(tx *pop.Connection) (*Meeting, error) {
 	if !r.MeetingID.Valid {
@@ -1111,7 +1359,11 @@ func (r *Request) GetCurrentActions(tx *pop.Connection, user User) ([]string, er
 
 	actions := []string{}
 	for _, t := range transitions {
-		if action := allActions[t.Status]; action != "" {
+		action := allActions[t.Status]
+		if r.Status == RequestStatusExpired && t.Status == RequestStatusOpen {
+			action = RequestActionRenew
+		}
+		if action != "" {
 			actions = append(actions, action)
 		}
 	}
@@ -1206,7 +1458,7 @@ func ConvertRequest(ctx context.Context, request Request) (api.Request, error) {
 	}
 	output.CreatedBy = createdBy
 
-	output.Destination = convertLocation(request.Destination)
+	output.Destination = ConvertLocation(request.Destination)
 
 	output.Origin = convertRequestOrigin(request)
 
@@ -1266,7 +1518,7 @@ func ConvertRequestAbridged(ctx context.Context, request Request) (api.RequestAb
 	}
 	output.CreatedBy = &createdBy
 
-	output.Destination = convertLocation(request.Destination)
+	output.Destination = ConvertLocation(request.Destination)
 
 	output.Origin = convertRequestOrigin(request)
 
@@ -1297,7 +1549,7 @@ func convertRequestOrigin(request Request) *api.Location {
 		return nil
 	}
 
-	outputOrigin := convertLocation(request.Origin)
+	outputOrigin := ConvertLocation(request.Origin)
 	return &outputOrigin
 }
 
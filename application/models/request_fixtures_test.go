@@ -348,18 +348,53 @@ func CreateFixtures_Request_IsEditable(ms *ModelSuite) RequestFixtures {
 	}
 }
 
+// createFixturesForRequestGetAudience creates four organizations, with the third trusting the first, and five users.
+// The first two users are in the first organization, the third user in the third organization, and the other two in
+// the fourth organization. The last user participates in a meeting.
+// Request 0: first organization, SAME visibility
+// Request 1: second organization, SAME visibility
+// Request 2: first organization, TRUSTED visibility
+// Request 3: first organization, ALL visibility
+// Request 4: second organization, SAME visibility, at the meeting
 func createFixturesForRequestGetAudience(ms *ModelSuite) RequestFixtures {
-	orgs := make(Organizations, 2)
+	orgs := make(Organizations, 4)
 	for i := range orgs {
 		orgs[i] = Organization{UUID: domain.GetUUID(), AuthConfig: "{}"}
 		createFixture(ms, &orgs[i])
 	}
 
-	users := createUserFixtures(ms.DB, 2).Users
+	trust := OrganizationTrust{PrimaryID: orgs[2].ID, SecondaryID: orgs[0].ID}
+	createFixture(ms, &trust)
 
-	requests := createRequestFixtures(ms.DB, 2, false, users[0].ID)
+	uf := createUserFixtures(ms.DB, 5)
+	users := uf.Users
+	for i, orgIndex := range []int{0, 0, 2, 3, 3} {
+		uf.UserOrganizations[i].OrganizationID = orgs[orgIndex].ID
+		ms.NoError(ms.DB.Update(&uf.UserOrganizations[i]))
+	}
+
+	meeting := Meeting{
+		UUID:        domain.GetUUID(),
+		Name:        "a meeting",
+		CreatedByID: users[0].ID,
+		LocationID:  uf.Locations[0].ID,
+	}
+	createFixture(ms, &meeting)
+	participant := MeetingParticipant{MeetingID: meeting.ID, UserID: users[4].ID}
+	createFixture(ms, &participant)
+
+	requests := createRequestFixtures(ms.DB, 5, false, users[0].ID)
+	for i := range requests {
+		requests[i].OrganizationID = orgs[0].ID
+	}
 	requests[1].OrganizationID = orgs[1].ID
-	ms.NoError(ms.DB.Save(&requests[1]))
+	requests[2].Visibility = RequestVisibilityTrusted
+	requests[3].Visibility = RequestVisibilityAll
+	requests[4].OrganizationID = orgs[1].ID
+	requests[4].MeetingID = nulls.NewInt(meeting.ID)
+	for i := range requests {
+		ms.NoError(ms.DB.Save(&requests[i]))
+	}
 
 	return RequestFixtures{
 		Users:    users,
@@ -367,6 +402,82 @@ func createFixturesForRequestGetAudience(ms *ModelSuite) RequestFixtures {
 	}
 }
 
+// createFixturesForRequestGetNotificationAudience creates a TRUSTED request in the first of three organizations, the
+// second of which trusts the first. The users are:
+// 0: the request creator, in the first organization, near the request origin
+// 1: in the first organization, near the request origin
+// 2: in the first organization, far from the request, without a watch
+// 3: in the second organization, with a watch for the request destination
+// 4: in the third organization, with a watch for the request destination
+// 5: in the second organization, with a watch for the request destination and text that does not match
+// 6: in the second organization, with a watch for the request's size and text in the creator's nickname
+// 7: in the second organization, with a paused watch for the request destination
+// 8: in the second organization, with an expired watch for the request destination
+func createFixturesForRequestGetNotificationAudience(ms *ModelSuite) RequestFixtures {
+	orgs := make(Organizations, 3)
+	for i := range orgs {
+		orgs[i] = Organization{UUID: domain.GetUUID(), AuthConfig: "{}"}
+		createFixture(ms, &orgs[i])
+	}
+
+	trust := OrganizationTrust{PrimaryID: orgs[1].ID, SecondaryID: orgs[0].ID}
+	createFixture(ms, &trust)
+
+	uf := createUserFixtures(ms.DB, 9)
+	users := uf.Users
+	for i, orgIndex := range []int{0, 0, 0, 1, 2, 1, 1, 1, 1} {
+		uf.UserOrganizations[i].OrganizationID = orgs[orgIndex].ID
+		ms.NoError(ms.DB.Update(&uf.UserOrganizations[i]))
+	}
+
+	request := createRequestFixtures(ms.DB, 1, false, users[0].ID)[0]
+	request.OrganizationID = orgs[0].ID
+	request.Visibility = RequestVisibilityTrusted
+	ms.NoError(ms.DB.Save(&request))
+
+	origin, err := request.GetOrigin(ms.DB)
+	ms.NoError(err)
+	destination, err := request.GetDestination(ms.DB)
+	ms.NoError(err)
+
+	far := Location{Description: "far away", Country: "FJ", Latitude: -origin.Latitude, Longitude: origin.Longitude + 180}
+	if far.Longitude > 180 {
+		far.Longitude -= 360
+	}
+	for i := range users {
+		location := far
+		if i < 2 {
+			location = *origin
+		}
+		ms.NoError(users[i].SetLocation(ms.DB, location))
+	}
+
+	size := RequestSizeMedium
+	watches := Watches{
+		{OwnerID: users[3].ID},
+		{OwnerID: users[4].ID},
+		{OwnerID: users[5].ID, SearchText: nulls.NewString("no match")},
+		{OwnerID: users[6].ID, SearchText: nulls.NewString(users[0].Nickname[:8]), Size: &size},
+		{OwnerID: users[7].ID, PausedUntil: nulls.NewTime(time.Now().Add(domain.DurationDay))},
+		{OwnerID: users[8].ID, ExpiresAt: nulls.NewTime(time.Now().Add(-domain.DurationDay))},
+	}
+	for i := range watches {
+		watches[i].UUID = domain.GetUUID()
+		if i != 3 {
+			watchDestination := *destination
+			watchDestination.ID = 0
+			createFixture(ms, &watchDestination)
+			watches[i].DestinationID = nulls.NewInt(watchDestination.ID)
+		}
+		createFixture(ms, &watches[i])
+	}
+
+	return RequestFixtures{
+		Users:    users,
+		Requests: Requests{request},
+	}
+}
+
 // CreateFixtures_Request_AddUserAsPotentialProvider generates
 //   five PotentialProvider records for testing.
 // If necessary, five User and four Request fixtures will also be created.
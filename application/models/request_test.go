@@ -979,6 +979,86 @@ func (ms *ModelSuite) TestRequest_FindOpenPastNeededBefore() {
 	ms.Equal(outdatedReq.Title, requests[0].Title, "incorrect request found")
 }
 
+func (ms *ModelSuite) TestRequests_FindOpenToExpire() {
+	users := createUserFixtures(ms.DB, 1).Users
+	reqFix := createRequestFixtures(ms.DB, 3, false, users[0].ID)
+
+	dates := []time.Time{
+		time.Now().Add(-10 * domain.DurationDay),
+		time.Now().Add(-1 * domain.DurationDay),
+		time.Now().Add(-10 * domain.DurationDay),
+	}
+	for i := range reqFix {
+		// avoid validation error for value in the past
+		ms.NoError(ms.DB.RawQuery("UPDATE requests SET needed_before = ? WHERE id = ?", dates[i], reqFix[i].ID).Exec())
+	}
+	ms.NoError(ms.DB.RawQuery("UPDATE requests SET status = ? WHERE id = ?", RequestStatusAccepted, reqFix[2].ID).Exec())
+
+	var requests Requests
+	ms.NoError(requests.FindOpenToExpire(ms.DB, time.Now().Add(-7*domain.DurationDay)))
+	ms.Len(requests, 1, "incorrect number of requests found")
+	ms.Equal(reqFix[0].ID, requests[0].ID, "incorrect request found")
+}
+
+func (ms *ModelSuite) TestRequests_FindOpenNeedingReminder() {
+	users := createUserFixtures(ms.DB, 1).Users
+	reqFix := createRequestFixtures(ms.DB, 4, false, users[0].ID)
+
+	dates := []time.Time{
+		time.Now().Add(domain.DurationDay),
+		time.Now().Add(domain.DurationDay),
+		time.Now().Add(domain.DurationWeek),
+		time.Now().Add(-1 * domain.DurationDay),
+	}
+	for i := range reqFix {
+		ms.NoError(ms.DB.RawQuery("UPDATE requests SET needed_before = ? WHERE id = ?", dates[i], reqFix[i].ID).Exec())
+	}
+	ms.NoError(reqFix[1].MarkReminded(ms.DB))
+
+	var requests Requests
+	ms.NoError(requests.FindOpenNeedingReminder(ms.DB, time.Now().Add(2*domain.DurationDay)))
+	ms.Len(requests, 1, "incorrect number of requests found")
+	ms.Equal(reqFix[0].ID, requests[0].ID, "incorrect request found")
+}
+
+func (ms *ModelSuite) TestRequest_ExpireAndRenew() {
+	f := createUserFixtures(ms.DB, 3)
+	requests := createRequestFixtures(ms.DB, 2, false, f.Users[0].ID)
+	createPotentialProviderFixtures(ms.DB, 0, 2)
+
+	request := requests[0]
+	withdrawn, err := request.Expire(ms.DB)
+	ms.NoError(err)
+	ms.Equal(RequestStatusExpired, request.Status)
+	ms.Len(withdrawn, 2, "the users who offered should be returned")
+
+	var providers PotentialProviders
+	n, err := ms.DB.Where("request_id = ?", request.ID).Count(&providers)
+	ms.NoError(err)
+	ms.Equal(0, n, "the offers should be withdrawn")
+
+	var visible Requests
+	ms.NoError(visible.FindByUser(ms.DB, f.Users[1], RequestFilterParams{}))
+	ms.Len(visible, 1, "an expired request should not be listed")
+	ms.Equal(requests[1].ID, visible[0].ID)
+
+	_, err = request.Expire(ms.DB)
+	ms.Error(err, "only an open request can expire")
+
+	ms.Error(request.Renew(ms.DB, today()), "the new date must be after today")
+
+	neededBefore := today().AddDate(0, 0, 14)
+	ms.NoError(request.Renew(ms.DB, neededBefore))
+
+	var dbRequest Request
+	ms.NoError(ms.DB.Find(&dbRequest, request.ID))
+	ms.Equal(RequestStatusOpen, dbRequest.Status)
+	ms.Equal(neededBefore, dbRequest.NeededBefore.Time.UTC())
+	ms.False(dbRequest.RemindedAt.Valid)
+
+	ms.Error(request.Renew(ms.DB, neededBefore), "only an expired request can be renewed")
+}
+
 func (ms *ModelSuite) TestRequest_FindByID() {
 	t := ms.T()
 
@@ -1165,6 +1245,15 @@ func (ms *ModelSuite) TestRequest_GetStatusTransitions() {
 				{Status: RequestStatusDelivered, IsBackStep: true},
 			},
 		},
+		{
+			name:    "Expired Request - Creator",
+			request: Request{ID: 1, CreatedByID: 11, Status: RequestStatusExpired},
+			user:    User{ID: 11},
+			want: []StatusTransitionTarget{
+				{Status: RequestStatusOpen},
+				{Status: RequestStatusRemoved},
+			},
+		},
 		{
 			name:    "Accepted Request - Provider",
 			request: Request{ID: 1, ProviderID: nulls.NewInt(12), Status: RequestStatusAccepted},
@@ -1261,6 +1350,9 @@ func (ms *ModelSuite) TestRequest_GetCurrentActions() {
 	acceptedRequest.Status = RequestStatusAccepted // This doesn't change the request in the slice
 	acceptedRequest.ProviderID = nulls.NewInt(users[1].ID)
 
+	expiredRequest := requests[1]
+	expiredRequest.Status = RequestStatusExpired
+
 	// The rest of the scenarios are already tested elsewhere
 	tests := []struct {
 		name    string
@@ -1298,6 +1390,18 @@ func (ms *ModelSuite) TestRequest_GetCurrentActions() {
 			user:    users[1],
 			want:    []string{RequestActionDeliver},
 		},
+		{
+			name:    "Expired Request - Creator",
+			request: expiredRequest,
+			user:    users[0],
+			want:    []string{RequestActionRenew, RequestActionRemove},
+		},
+		{
+			name:    "Expired Request - not Creator",
+			request: expiredRequest,
+			user:    users[1],
+			want:    []string{},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1602,6 +1706,34 @@ func (ms *ModelSuite) TestRequest_Origin() {
 	ms.False(domain.IsOtherThanNoRows(err), "unexpected error type finding old origin, "+err.Error())
 }
 
+func (ms *ModelSuite) TestRequest_Duplicate() {
+	user := createUserFixtures(ms.DB, 1).Users[0]
+	requests := createRequestFixtures(ms.DB, 2, true, user.ID)
+	meetings := createMeetingFixtures(ms.DB, 1, user.ID).Meetings
+
+	original := requests[0]
+	original.Status = RequestStatusCompleted
+	original.MeetingID = nulls.NewInt(meetings[0].ID)
+	ms.NoError(ms.DB.Update(&original))
+
+	neededBefore := nulls.NewTime(time.Now().Add(2 * domain.DurationWeek).UTC().Truncate(domain.DurationDay))
+	request, err := original.Duplicate(ms.DB, neededBefore)
+	ms.NoError(err)
+	ms.NotEqual(original.ID, request.ID)
+	ms.Equal(RequestStatusOpen, request.Status)
+	ms.Equal(original.CreatedByID, request.CreatedByID)
+	ms.Equal(original.Title, request.Title)
+	ms.Equal(original.URL, request.URL)
+	ms.Equal(original.MeetingID, request.MeetingID)
+	ms.Equal(neededBefore.Time, request.NeededBefore.Time.UTC())
+	ms.False(request.FileID.Valid, "the photo should not be copied")
+	ms.NotEqual(original.DestinationID, request.DestinationID, "the destination should be a copy")
+	ms.NotEqual(original.OriginID.Int, request.OriginID.Int, "the origin should be a copy")
+
+	_, err = requests[1].Duplicate(ms.DB, nulls.NewTime(time.Now()))
+	ms.Error(err, "a request needed today should be rejected by Request validation")
+}
+
 func (ms *ModelSuite) TestRequest_NewWithUser() {
 	t := ms.T()
 	user := createUserFixtures(ms.DB, 1).Users[0]
@@ -1926,6 +2058,27 @@ func (ms *ModelSuite) TestRequest_canUserChangeStatus() {
 			newStatus: RequestStatusAccepted,
 			want:      true,
 		},
+		{
+			name:      "Open to Expired by Creator",
+			request:   Request{CreatedByID: 1, Status: RequestStatusOpen},
+			user:      User{ID: 1},
+			newStatus: RequestStatusExpired,
+			want:      false,
+		},
+		{
+			name:      "Expired to Open by Creator",
+			request:   Request{CreatedByID: 1, Status: RequestStatusExpired},
+			user:      User{ID: 1},
+			newStatus: RequestStatusOpen,
+			want:      false,
+		},
+		{
+			name:      "Expired to Removed by Creator",
+			request:   Request{CreatedByID: 1, Status: RequestStatusExpired},
+			user:      User{ID: 1},
+			newStatus: RequestStatusRemoved,
+			want:      true,
+		},
 		{
 			name:      "Accepted",
 			request:   Request{CreatedByID: 1},
@@ -2021,6 +2174,21 @@ func (ms *ModelSuite) TestRequest_GetAudience() {
 			request: f.Requests[1],
 			want:    []int{},
 		},
+		{
+			name:    "trusted",
+			request: f.Requests[2],
+			want:    []int{f.Users[0].ID, f.Users[1].ID, f.Users[2].ID},
+		},
+		{
+			name:    "public",
+			request: f.Requests[3],
+			want:    []int{f.Users[0].ID, f.Users[1].ID, f.Users[2].ID, f.Users[3].ID, f.Users[4].ID},
+		},
+		{
+			name:    "meeting participant",
+			request: f.Requests[4],
+			want:    []int{f.Users[4].ID},
+		},
 		{
 			name:    "invalid request",
 			request: Request{},
@@ -2050,6 +2218,27 @@ func (ms *ModelSuite) TestRequest_GetAudience() {
 	}
 }
 
+func (ms *ModelSuite) TestRequest_GetNotificationAudience() {
+	f := createFixturesForRequestGetNotificationAudience(ms)
+
+	got, err := f.Requests[0].GetNotificationAudience(ms.DB)
+	ms.NoError(err)
+
+	ids := make([]int, len(got))
+	for i := range got {
+		ids[i] = got[i].ID
+	}
+	ms.Equal([]int{f.Users[1].ID, f.Users[3].ID, f.Users[6].ID}, ids)
+
+	for i, user := range f.Users {
+		want := i == 1 || i == 3 || i == 6
+		ms.Equal(want, user.WantsRequestNotification(ms.DB, f.Requests[0]), "user %d", i)
+	}
+
+	_, err = (&Request{}).GetNotificationAudience(ms.DB)
+	ms.Error(err)
+}
+
 func (ms *ModelSuite) TestRequest_Meeting() {
 	t := ms.T()
 	requests := createRequestFixtures(ms.DB, 2, false)
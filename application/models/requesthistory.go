@@ -124,6 +124,14 @@ func (rH *RequestHistory) getLastForRequest(tx *pop.Connection, request Request)
 	return nil
 }
 
+// FindByRequest finds all of the RequestHistory records of the given Request, oldest first
+func (p *RequestHistories) FindByRequest(tx *pop.Connection, request Request) error {
+	if err := tx.Eager("Receiver").Where("request_id = ?", request.ID).Order("id asc").All(p); err != nil {
+		return fmt.Errorf("error getting Request History for request %v ... %v", request.ID, err)
+	}
+	return nil
+}
+
 // Create stores the RequestHistory data as a new record in the database.
 func (rH *RequestHistory) Create(tx *pop.Connection) error {
 	return create(tx, rH)
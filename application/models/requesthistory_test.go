@@ -191,3 +191,19 @@ func (ms *ModelSuite) TestRequestHistory_createForRequest() {
 		})
 	}
 }
+
+func (ms *ModelSuite) TestRequestHistories_FindByRequest() {
+	f := createFixturesForTestRequestHistory_createForRequest(ms)
+	request := f.Requests[0]
+
+	request.Status = RequestStatusAccepted
+	request.ProviderID = nulls.NewInt(f.Users[1].ID)
+	ms.NoError(request.Update(ms.DB))
+
+	var histories RequestHistories
+	ms.NoError(histories.FindByRequest(ms.DB, request))
+	ms.Equal(2, len(histories), "incorrect number of histories")
+	ms.Equal(RequestStatusOpen, histories[0].Status, "histories are out of order")
+	ms.Equal(RequestStatusAccepted, histories[1].Status, "histories are out of order")
+	ms.Equal(request.CreatedByID, histories[1].Receiver.ID, "Receiver is not hydrated")
+}
@@ -0,0 +1,341 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/domain"
+)
+
+// minNeededWithinDays is the fewest days after its creation that a request made from a template can be needed by,
+// since the needed_before date of a new request must be after tomorrow
+const minNeededWithinDays = 2
+
+// RequestTemplate is a User's saved set of request details, from which new requests can be made. A template with a
+// recurrence interval makes a new request on each `NextRequestOn` date.
+type RequestTemplate struct {
+	ID             int               `json:"-" db:"id"`
+	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at" db:"updated_at"`
+	UUID           uuid.UUID         `json:"uuid" db:"uuid"`
+	OwnerID        int               `json:"owner_id" db:"owner_id"`
+	OrganizationID int               `json:"organization_id" db:"organization_id"`
+	Name           string            `json:"name" db:"name"`
+	Title          string            `json:"title" db:"title"`
+	Description    nulls.String      `json:"description" db:"description"`
+	Size           RequestSize       `json:"size" db:"size"`
+	Kilograms      nulls.Float64     `json:"kilograms" db:"kilograms"`
+	Visibility     RequestVisibility `json:"visibility" db:"visibility"`
+	DestinationID  int               `json:"destination_id" db:"destination_id"`
+	OriginID       nulls.Int         `json:"origin_id" db:"origin_id"`
+
+	// NeededWithinDays sets the needed_before date of each new request to this many days after it is made
+	NeededWithinDays nulls.Int `json:"needed_within_days" db:"needed_within_days"`
+
+	// RepeatEveryDays is the recurrence interval. If null, requests are only made from the template on demand.
+	RepeatEveryDays nulls.Int `json:"repeat_every_days" db:"repeat_every_days"`
+
+	// NextRequestOn is the date of the next recurring request
+	NextRequestOn nulls.Time `json:"next_request_on" db:"next_request_on"`
+
+	// LastRequestID is the most recent request made by the recurrence
+	LastRequestID nulls.Int `json:"last_request_id" db:"last_request_id"`
+
+	Owner        User         `json:"-" belongs_to:"users"`
+	Organization Organization `json:"-" belongs_to:"organizations"`
+	Destination  Location     `json:"-" belongs_to:"locations"`
+	Origin       Location     `json:"-" belongs_to:"locations"`
+	LastRequest  Request      `json:"-" belongs_to:"requests"`
+}
+
+// RequestTemplates is merely for convenience and brevity
+type RequestTemplates []RequestTemplate
+
+// String can be helpful for serializing the model
+func (t RequestTemplate) String() string {
+	jt, _ := json.Marshal(t)
+	return string(jt)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (t *RequestTemplate) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	v := []validate.Validator{
+		&validators.UUIDIsPresent{Field: t.UUID, Name: "UUID"},
+		&validators.IntIsPresent{Field: t.OwnerID, Name: "OwnerID"},
+		&validators.IntIsPresent{Field: t.OrganizationID, Name: "OrganizationID"},
+		&validators.IntIsPresent{Field: t.DestinationID, Name: "DestinationID"},
+		&validators.StringIsPresent{Field: t.Name, Name: "Name"},
+		&validators.StringIsPresent{Field: t.Title, Name: "Title"},
+		&validators.StringIsPresent{Field: t.Size.String(), Name: "Size"},
+		&validators.FuncValidator{
+			Field:   t.Visibility.String(),
+			Name:    "Visibility",
+			Message: "%s is not a valid request visibility",
+			Fn:      func() bool { return t.Visibility.IsValid() },
+		},
+	}
+
+	if t.NeededWithinDays.Valid {
+		v = append(v, &validators.IntIsGreaterThan{
+			Field:    t.NeededWithinDays.Int,
+			Name:     "NeededWithinDays",
+			Compared: minNeededWithinDays - 1,
+			Message:  fmt.Sprintf("Template neededWithinDays must be at least %d", minNeededWithinDays),
+		})
+	}
+
+	if t.RepeatEveryDays.Valid {
+		v = append(v,
+			&validators.IntIsGreaterThan{
+				Field:    t.RepeatEveryDays.Int,
+				Name:     "RepeatEveryDays",
+				Compared: 0,
+				Message:  "Template repeatEveryDays must be greater than zero",
+			},
+			&validators.TimeIsPresent{Field: t.NextRequestOn.Time, Name: "NextRequestOn"},
+		)
+	}
+
+	return validate.Validate(v...), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+func (t *RequestTemplate) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+func (t *RequestTemplate) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// Create stores the RequestTemplate data as a new record in the database.
+func (t *RequestTemplate) Create(tx *pop.Connection) error {
+	if t.Visibility == "" {
+		t.Visibility = RequestVisibilitySame
+	}
+	if t.Name == "" {
+		t.Name = t.Title
+	}
+	return create(tx, t)
+}
+
+// Update writes the RequestTemplate data to an existing database record.
+func (t *RequestTemplate) Update(tx *pop.Connection) error {
+	return update(tx, t)
+}
+
+// Destroy wraps the Pop function of the same name
+func (t *RequestTemplate) Destroy(tx *pop.Connection) error {
+	return tx.Destroy(t)
+}
+
+// FindByUUID loads from DB the RequestTemplate record identified by the given UUID
+func (t *RequestTemplate) FindByUUID(tx *pop.Connection, id string) error {
+	if id == "" {
+		return errors.New("error: request template uuid must not be blank")
+	}
+
+	if err := tx.Where("uuid = ?", id).First(t); err != nil {
+		return fmt.Errorf("error finding request template by uuid: %w", err)
+	}
+
+	return nil
+}
+
+// FindByUUIDForOwner loads from DB the RequestTemplate record identified by the given UUID, if the user is the owner
+// of the template.
+func (t *RequestTemplate) FindByUUIDForOwner(tx *pop.Connection, id string, user User) *api.AppError {
+	if err := t.FindByUUID(tx, id); err != nil {
+		appError := api.NewAppError(err, api.ErrorRequestTemplateNotFound, api.CategoryNotFound)
+		if domain.IsOtherThanNoRows(err) {
+			appError.Category = api.CategoryInternal
+		}
+		return appError
+	}
+
+	if t.OwnerID != user.ID {
+		err := errors.New("user may not access a request template they don't own")
+		return api.NewAppError(err, api.ErrorNotAuthorized, api.CategoryForbidden)
+	}
+
+	return nil
+}
+
+// FindByOwner returns all request templates owned by the given user, most recently updated first
+func (t *RequestTemplates) FindByOwner(tx *pop.Connection, user User) error {
+	err := tx.Eager("Destination", "Organization").
+		Where("owner_id = ?", user.ID).
+		Order("updated_at desc").
+		All(t)
+	if err != nil {
+		return fmt.Errorf("error getting request templates for user id %v, %w", user.ID, err)
+	}
+
+	return nil
+}
+
+// FindDue finds the recurring templates whose next request is due on or before the given date
+func (t *RequestTemplates) FindDue(tx *pop.Connection, date time.Time) error {
+	err := tx.Where("repeat_every_days IS NOT NULL AND next_request_on <= ?", date.Format(domain.DateFormat)).
+		Order("next_request_on, id").
+		All(t)
+	if err != nil {
+		return fmt.Errorf("error finding due request templates, %w", err)
+	}
+
+	return nil
+}
+
+// SetLocations stores the destination and origin of the template, updating its existing location records or creating
+// new ones. An origin that is nil is removed from the template.
+func (t *RequestTemplate) SetLocations(tx *pop.Connection, destination Location, origin *Location) error {
+	if t.DestinationID != 0 {
+		destination.ID = t.DestinationID
+		if err := destination.Update(tx); err != nil {
+			return err
+		}
+	} else {
+		if err := destination.Create(tx); err != nil {
+			return err
+		}
+		t.DestinationID = destination.ID
+	}
+
+	if origin == nil {
+		t.OriginID = nulls.Int{}
+		return nil
+	}
+
+	if t.OriginID.Valid {
+		origin.ID = t.OriginID.Int
+		return origin.Update(tx)
+	}
+	if err := origin.Create(tx); err != nil {
+		return err
+	}
+	t.OriginID = nulls.NewInt(origin.ID)
+	return nil
+}
+
+// NewRequest creates a new OPEN request from the template. If `neededBefore` is not given, and the template has a
+// NeededWithinDays value, the request is needed that many days from today. The request is subject to the same
+// validation as one created through the API.
+func (t *RequestTemplate) NewRequest(tx *pop.Connection, neededBefore nulls.Time) (Request, error) {
+	if !neededBefore.Valid && t.NeededWithinDays.Valid {
+		neededBefore = nulls.NewTime(today().AddDate(0, 0, t.NeededWithinDays.Int))
+	}
+
+	request := Request{
+		CreatedByID:    t.OwnerID,
+		OrganizationID: t.OrganizationID,
+		Status:         RequestStatusOpen,
+		Title:          t.Title,
+		Description:    t.Description,
+		Size:           t.Size,
+		Kilograms:      t.Kilograms,
+		Visibility:     t.Visibility,
+		NeededBefore:   neededBefore,
+	}
+
+	var err error
+	if request.DestinationID, err = copyLocation(tx, t.DestinationID); err != nil {
+		return request, err
+	}
+	if t.OriginID.Valid {
+		originID, err := copyLocation(tx, t.OriginID.Int)
+		if err != nil {
+			return request, err
+		}
+		request.OriginID = nulls.NewInt(originID)
+	}
+
+	if err := request.Create(tx); err != nil {
+		return request, err
+	}
+	return request, nil
+}
+
+// Recur makes the template's recurring request, unless the previous one is still open or the owner is suspended, and
+// schedules the next one. If the dates of several intervals have passed, only one request is made. If the owner has
+// left the template's organization, the template stops recurring. The returned request is empty if none was made.
+func (t *RequestTemplate) Recur(tx *pop.Connection) (Request, error) {
+	if !t.RepeatEveryDays.Valid || !t.NextRequestOn.Valid {
+		return Request{}, fmt.Errorf("request template %s does not recur", t.UUID)
+	}
+
+	isMember, err := tx.Where("user_id = ? AND organization_id = ?", t.OwnerID, t.OrganizationID).
+		Exists(&UserOrganization{})
+	if err != nil {
+		return Request{}, fmt.Errorf("error checking organization of request template %s, %w", t.UUID, err)
+	}
+	if !isMember {
+		t.RepeatEveryDays = nulls.Int{}
+		t.NextRequestOn = nulls.Time{}
+		if err := t.Update(tx); err != nil {
+			return Request{}, fmt.Errorf("error stopping recurrence of request template %s, %w", t.UUID, err)
+		}
+		return Request{}, nil
+	}
+
+	var owner User
+	if err := owner.FindByID(tx, t.OwnerID); err != nil {
+		return Request{}, fmt.Errorf("error finding owner of request template %s, %w", t.UUID, err)
+	}
+
+	// a suspended owner's requests are skipped, but the template keeps its schedule in case the owner is reinstated
+	skip := owner.IsSuspended()
+	if !skip && t.LastRequestID.Valid {
+		skip, err = tx.Where("id = ? AND status = ?", t.LastRequestID.Int, RequestStatusOpen).
+			Exists(&Request{})
+		if err != nil {
+			return Request{}, fmt.Errorf("error checking last request of template %s, %w", t.UUID, err)
+		}
+	}
+
+	var request Request
+	if !skip {
+		if request, err = t.NewRequest(tx, nulls.Time{}); err != nil {
+			return Request{}, fmt.Errorf("error creating request from template %s, %w", t.UUID, err)
+		}
+		t.LastRequestID = nulls.NewInt(request.ID)
+	}
+
+	next := t.NextRequestOn.Time
+	for !next.After(today()) {
+		next = next.AddDate(0, 0, t.RepeatEveryDays.Int)
+	}
+	t.NextRequestOn = nulls.NewTime(next)
+
+	if err := t.Update(tx); err != nil {
+		return Request{}, fmt.Errorf("error scheduling next request of template %s, %w", t.UUID, err)
+	}
+	return request, nil
+}
+
+// copyLocation creates a copy of a location record, so that it can be changed independently of the original
+func copyLocation(tx *pop.Connection, id int) (int, error) {
+	var location Location
+	if err := tx.Find(&location, id); err != nil {
+		return 0, fmt.Errorf("error finding location %d to copy, %w", id, err)
+	}
+	location.ID = 0
+	if err := location.Create(tx); err != nil {
+		return 0, fmt.Errorf("error copying location %d, %w", id, err)
+	}
+	return location.ID, nil
+}
+
+// today returns the start of the current day in UTC
+func today() time.Time {
+	return time.Now().UTC().Truncate(domain.DurationDay)
+}
@@ -0,0 +1,220 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+
+	"github.com/silinternational/wecarry-api/domain"
+)
+
+// createRequestTemplateFixture creates a template owned by the given user, in the user's first organization, with a
+// destination and an origin
+func createRequestTemplateFixture(ms *ModelSuite, user User) RequestTemplate {
+	locations := createLocationFixtures(ms.DB, 2)
+
+	template := RequestTemplate{
+		OwnerID:          user.ID,
+		OrganizationID:   user.Organizations[0].ID,
+		Title:            "printer toner",
+		Description:      nulls.NewString("black, for the office printer"),
+		Size:             RequestSizeSmall,
+		Kilograms:        nulls.NewFloat64(0.5),
+		DestinationID:    locations[0].ID,
+		OriginID:         nulls.NewInt(locations[1].ID),
+		NeededWithinDays: nulls.NewInt(14),
+	}
+	ms.NoError(template.Create(ms.DB))
+	return template
+}
+
+func (ms *ModelSuite) TestRequestTemplate_Validate() {
+	t := ms.T()
+	valid := func() RequestTemplate {
+		return RequestTemplate{
+			UUID:           domain.GetUUID(),
+			OwnerID:        1,
+			OrganizationID: 1,
+			DestinationID:  1,
+			Name:           "toner",
+			Title:          "printer toner",
+			Size:           RequestSizeSmall,
+			Visibility:     RequestVisibilitySame,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(*RequestTemplate)
+		errField string
+	}{
+		{
+			name:   "minimum",
+			modify: func(*RequestTemplate) {},
+		},
+		{
+			name:     "missing title",
+			modify:   func(rt *RequestTemplate) { rt.Title = "" },
+			errField: "title",
+		},
+		{
+			name:     "missing destination",
+			modify:   func(rt *RequestTemplate) { rt.DestinationID = 0 },
+			errField: "destination_id",
+		},
+		{
+			name:     "bad visibility",
+			modify:   func(rt *RequestTemplate) { rt.Visibility = "ALL_OF_THEM" },
+			errField: "visibility",
+		},
+		{
+			name:     "needed too soon",
+			modify:   func(rt *RequestTemplate) { rt.NeededWithinDays = nulls.NewInt(1) },
+			errField: "needed_within_days",
+		},
+		{
+			name: "recurring",
+			modify: func(rt *RequestTemplate) {
+				rt.RepeatEveryDays = nulls.NewInt(30)
+				rt.NextRequestOn = nulls.NewTime(time.Now())
+			},
+		},
+		{
+			name: "zero interval",
+			modify: func(rt *RequestTemplate) {
+				rt.RepeatEveryDays = nulls.NewInt(0)
+				rt.NextRequestOn = nulls.NewTime(time.Now())
+			},
+			errField: "repeat_every_days",
+		},
+		{
+			name:     "recurring without next date",
+			modify:   func(rt *RequestTemplate) { rt.RepeatEveryDays = nulls.NewInt(30) },
+			errField: "next_request_on",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			template := valid()
+			test.modify(&template)
+
+			vErr, err := template.Validate(ms.DB)
+			ms.NoError(err)
+			if test.errField == "" {
+				ms.False(vErr.HasAny(), "unexpected error, %s", vErr)
+				return
+			}
+			ms.True(vErr.HasAny(), "expected an error but did not get one")
+			ms.True(len(vErr.Get(test.errField)) > 0,
+				"Expected an error on field %v, but got none (errors: %v)",
+				test.errField, vErr.Errors)
+		})
+	}
+}
+
+func (ms *ModelSuite) TestRequestTemplate_NewRequest() {
+	user := createUserFixtures(ms.DB, 1).Users[0]
+	template := createRequestTemplateFixture(ms, user)
+
+	request, err := template.NewRequest(ms.DB, nulls.Time{})
+	ms.NoError(err)
+	ms.Equal(RequestStatusOpen, request.Status)
+	ms.Equal(user.ID, request.CreatedByID)
+	ms.Equal(template.Title, request.Title)
+	ms.Equal(template.Description, request.Description)
+	ms.Equal(template.Size, request.Size)
+	ms.Equal(RequestVisibilitySame, request.Visibility)
+	ms.Equal(today().AddDate(0, 0, 14), request.NeededBefore.Time.UTC())
+
+	ms.NotEqual(template.DestinationID, request.DestinationID, "the destination should be a copy")
+	ms.NotEqual(template.OriginID.Int, request.OriginID.Int, "the origin should be a copy")
+	var origin, templateOrigin Location
+	ms.NoError(ms.DB.Find(&origin, request.OriginID.Int))
+	ms.NoError(ms.DB.Find(&templateOrigin, template.OriginID.Int))
+	ms.Equal(templateOrigin.Description, origin.Description)
+
+	_, err = template.NewRequest(ms.DB, nulls.NewTime(time.Now()))
+	ms.Error(err, "a request needed today should be rejected by Request validation")
+}
+
+func (ms *ModelSuite) TestRequestTemplate_Recur() {
+	uf := createUserFixtures(ms.DB, 3)
+	template := createRequestTemplateFixture(ms, uf.Users[0])
+	template.RepeatEveryDays = nulls.NewInt(7)
+	template.NextRequestOn = nulls.NewTime(today().AddDate(0, 0, -15))
+	ms.NoError(template.Update(ms.DB))
+
+	request, err := template.Recur(ms.DB)
+	ms.NoError(err)
+	ms.NotZero(request.ID)
+	ms.Equal(request.ID, template.LastRequestID.Int)
+	ms.Equal(today().AddDate(0, 0, 6), template.NextRequestOn.Time.UTC(), "missed intervals should be skipped")
+
+	again, err := template.Recur(ms.DB)
+	ms.NoError(err)
+	ms.Zero(again.ID, "no request should be made while the last one is open")
+	ms.Equal(request.ID, template.LastRequestID.Int)
+
+	request.Status = RequestStatusRemoved
+	ms.NoError(ms.DB.Update(&request))
+	next, err := template.Recur(ms.DB)
+	ms.NoError(err)
+	ms.NotZero(next.ID)
+	ms.NotEqual(request.ID, next.ID)
+
+	other := createRequestTemplateFixture(ms, uf.Users[1])
+	other.RepeatEveryDays = nulls.NewInt(7)
+	other.NextRequestOn = nulls.NewTime(today())
+	ms.NoError(other.Update(ms.DB))
+	ms.NoError(ms.DB.Destroy(&uf.UserOrganizations[1]))
+	left, err := other.Recur(ms.DB)
+	ms.NoError(err)
+	ms.Zero(left.ID, "no request should be made for a user who left the organization")
+	ms.False(other.RepeatEveryDays.Valid, "template of a user who left the organization should stop recurring")
+	var due RequestTemplates
+	ms.NoError(due.FindDue(ms.DB, time.Now()))
+	for _, d := range due {
+		ms.NotEqual(other.ID, d.ID, "template of a user who left the organization should no longer be due")
+	}
+
+	suspended := createRequestTemplateFixture(ms, uf.Users[2])
+	suspended.RepeatEveryDays = nulls.NewInt(7)
+	suspended.NextRequestOn = nulls.NewTime(today())
+	ms.NoError(suspended.Update(ms.DB))
+	ms.NoError(uf.Users[2].Suspend(ms.DB))
+	skipped, err := suspended.Recur(ms.DB)
+	ms.NoError(err)
+	ms.Zero(skipped.ID, "no request should be made for a suspended user")
+	ms.Equal(today().AddDate(0, 0, 7), suspended.NextRequestOn.Time.UTC(), "next request should be scheduled")
+
+	onDemand := createRequestTemplateFixture(ms, uf.Users[0])
+	_, err = onDemand.Recur(ms.DB)
+	ms.Error(err)
+}
+
+func (ms *ModelSuite) TestRequestTemplates_FindDue() {
+	user := createUserFixtures(ms.DB, 1).Users[0]
+
+	dates := []nulls.Time{
+		nulls.NewTime(today().AddDate(0, 0, -1)),
+		nulls.NewTime(today()),
+		nulls.NewTime(today().AddDate(0, 0, 1)),
+		{},
+	}
+	templates := make(RequestTemplates, len(dates))
+	for i := range templates {
+		templates[i] = createRequestTemplateFixture(ms, user)
+		if dates[i].Valid {
+			templates[i].RepeatEveryDays = nulls.NewInt(7)
+			templates[i].NextRequestOn = dates[i]
+			ms.NoError(templates[i].Update(ms.DB))
+		}
+	}
+
+	var due RequestTemplates
+	ms.NoError(due.FindDue(ms.DB, time.Now()))
+	ms.Equal(2, len(due))
+	ms.Equal(templates[0].ID, due[0].ID)
+	ms.Equal(templates[1].ID, due[1].ID)
+}
@@ -0,0 +1,522 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/auth"
+	"github.com/silinternational/wecarry-api/domain"
+)
+
+// SCIMTokenPrefix is prepended to every SCIM bearer token to distinguish it from other tokens
+const SCIMTokenPrefix = "wcscim_"
+
+// SCIMGroupAdmins is the ID of the only SCIM Group, whose members are the admins of the Organization
+const SCIMGroupAdmins = "admins"
+
+// scimFilterColumns maps the SCIM attributes that can be used in a filter to their database columns
+var scimFilterColumns = map[string]string{
+	"username":     "user_name",
+	"externalid":   "external_id",
+	"emails":       "email",
+	"emails.value": "email",
+}
+
+// SCIMUser records that a User was provisioned in an Organization by the Organization's identity provider through
+// the SCIM API. While the SCIMUser is active, the User is a member of the Organization. The names given by the
+// identity provider are kept here rather than on the User, since the User may belong to other Organizations.
+type SCIMUser struct {
+	ID             int          `json:"id" db:"id"`
+	CreatedAt      time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at" db:"updated_at"`
+	OrganizationID int          `json:"organization_id" db:"organization_id"`
+	UserID         int          `json:"user_id" db:"user_id"`
+	ExternalID     string       `json:"external_id" db:"external_id"`
+	UserName       string       `json:"user_name" db:"user_name"`
+	Email          string       `json:"email" db:"email"`
+	FirstName      string       `json:"first_name" db:"first_name"`
+	LastName       string       `json:"last_name" db:"last_name"`
+	Active         bool         `json:"active" db:"active"`
+	User           User         `belongs_to:"users"`
+	Organization   Organization `belongs_to:"organizations"`
+}
+
+// SCIMUserAttributes are the attributes of a SCIMUser that are managed by the identity provider
+type SCIMUserAttributes struct {
+	ExternalID string
+	UserName   string
+	Email      string
+	FirstName  string
+	LastName   string
+	Nickname   string
+	Active     bool
+}
+
+// TableName overrides the default table name
+func (s SCIMUser) TableName() string {
+	return "scim_users"
+}
+
+// String can be helpful for serializing the model
+func (s SCIMUser) String() string {
+	js, _ := json.Marshal(s)
+	return string(js)
+}
+
+// SCIMUsers is merely for convenience and brevity
+type SCIMUsers []SCIMUser
+
+// TableName overrides the default table name
+func (s SCIMUsers) TableName() string {
+	return "scim_users"
+}
+
+// String can be helpful for serializing the model
+func (s SCIMUsers) String() string {
+	js, _ := json.Marshal(s)
+	return string(js)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (s *SCIMUser) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.IntIsPresent{Field: s.OrganizationID, Name: "OrganizationID"},
+		&validators.IntIsPresent{Field: s.UserID, Name: "UserID"},
+		&validators.StringIsPresent{Field: s.UserName, Name: "UserName"},
+		&validators.EmailIsPresent{Field: s.Email, Name: "Email"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+func (s *SCIMUser) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+func (s *SCIMUser) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// Create stores the SCIMUser data as a new record in the database.
+func (s *SCIMUser) Create(tx *pop.Connection) error {
+	return create(tx, s)
+}
+
+// Update writes the SCIMUser data to an existing database record.
+func (s *SCIMUser) Update(tx *pop.Connection) error {
+	return update(tx, s)
+}
+
+// FindByOrganizationAndUserUUID loads the SCIMUser of the Organization for the User identified by `userUUID`
+func (s *SCIMUser) FindByOrganizationAndUserUUID(tx *pop.Connection, org Organization, userUUID string) error {
+	err := tx.Eager("User").
+		Where("organization_id = ?", org.ID).
+		Where("user_id = (SELECT id FROM users WHERE uuid = ?)", userUUID).
+		First(s)
+	if err != nil {
+		return api.NewAppError(err, api.ErrorSCIMUserNotFound, api.CategoryNotFound)
+	}
+	return nil
+}
+
+// FindByOrganization loads a page of the Organization's SCIMUsers, optionally filtered by an attribute equal to a
+// value, and returns the total number of SCIMUsers that match the filter. `startIndex` is 1-based as in SCIM.
+func (s *SCIMUsers) FindByOrganization(tx *pop.Connection, org Organization, filterAttr, filterValue string,
+	startIndex, count int) (int, error) {
+
+	where := "organization_id = ?"
+	args := []interface{}{org.ID}
+
+	if filterAttr != "" {
+		column, ok := scimFilterColumns[strings.ToLower(filterAttr)]
+		if !ok {
+			err := fmt.Errorf("filtering by attribute '%s' is not supported", filterAttr)
+			return 0, api.NewAppError(err, api.ErrorSCIMInvalidFilter, api.CategoryUser)
+		}
+		where += " AND LOWER(" + column + ") = LOWER(?)"
+		args = append(args, filterValue)
+	}
+
+	var c Count
+	if err := tx.RawQuery("SELECT COUNT(*) FROM scim_users WHERE "+where, args...).First(&c); err != nil {
+		return 0, err
+	}
+
+	*s = SCIMUsers{}
+	if count <= 0 {
+		return c.N, nil
+	}
+	if startIndex < 1 {
+		startIndex = 1
+	}
+
+	q := "SELECT * FROM scim_users WHERE " + where + " ORDER BY id LIMIT ? OFFSET ?"
+	if err := tx.RawQuery(q, append(args, count, startIndex-1)...).All(s); err != nil {
+		return 0, err
+	}
+
+	for i := range *s {
+		if err := tx.Load(&(*s)[i], "User"); err != nil {
+			return 0, err
+		}
+	}
+	return c.N, nil
+}
+
+// FindAdmins loads the active SCIMUsers of the Organization that are admins of the Organization
+func (s *SCIMUsers) FindAdmins(tx *pop.Connection, org Organization) error {
+	err := tx.Eager("User").
+		Where("organization_id = ? AND active = true", org.ID).
+		Where(`user_id IN (SELECT user_id FROM user_organizations WHERE organization_id = ? AND role = ?)`,
+			org.ID, UserOrganizationRoleAdmin).
+		Order("id").
+		All(s)
+	if err != nil {
+		return fmt.Errorf("error finding SCIM admins of organization %d, %w", org.ID, err)
+	}
+	return nil
+}
+
+// Provision creates a SCIMUser for the given attributes in the Organization. An existing User with the same email
+// address is used if the User is already a member of the Organization or the email address is on one of the
+// Organization's domains. Otherwise, an existing User is a conflict, and if there is none a new User is created. If
+// the SCIMUser is active, the User is made a member of the Organization.
+func (s *SCIMUser) Provision(tx *pop.Connection, org Organization, attrs SCIMUserAttributes) error {
+	if err := validateSCIMUserAttributes(attrs); err != nil {
+		return err
+	}
+
+	var existing SCIMUsers
+	n, err := existing.FindByOrganization(tx, org, "userName", attrs.UserName, 1, 0)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		err := fmt.Errorf("userName '%s' is already provisioned in organization %d", attrs.UserName, org.ID)
+		return api.NewAppError(err, api.ErrorSCIMUserConflict, api.CategoryUser)
+	}
+
+	var user User
+	if err := tx.Where("LOWER(email) = LOWER(?)", attrs.Email).First(&user); domain.IsOtherThanNoRows(err) {
+		return err
+	}
+
+	if user.ID == 0 {
+		authUser := auth.User{
+			FirstName: attrs.FirstName,
+			LastName:  attrs.LastName,
+			Email:     attrs.Email,
+			Nickname:  attrs.Nickname,
+		}
+		if err := user.hydrateFromAuthUser(tx, &authUser, ""); err != nil {
+			return api.NewAppError(err, api.ErrorSCIMProvisionFailure, api.CategoryInternal)
+		}
+	} else if err := tx.Where("organization_id = ? AND user_id = ?", org.ID, user.ID).First(&SCIMUser{}); err == nil {
+		err := fmt.Errorf("user with email '%s' is already provisioned in organization %d", attrs.Email, org.ID)
+		return api.NewAppError(err, api.ErrorSCIMUserConflict, api.CategoryUser)
+	} else if ok, err := canLinkSCIMUser(tx, org, user); err != nil {
+		return err
+	} else if !ok {
+		err := fmt.Errorf("user with email '%s' is not a member of organization %d", attrs.Email, org.ID)
+		return api.NewAppError(err, api.ErrorSCIMUserConflict, api.CategoryUser)
+	}
+
+	s.OrganizationID = org.ID
+	s.Organization = org
+	s.UserID = user.ID
+	s.User = user
+	s.ExternalID = attrs.ExternalID
+	s.UserName = attrs.UserName
+	s.Email = attrs.Email
+	s.FirstName = attrs.FirstName
+	s.LastName = attrs.LastName
+	s.Active = attrs.Active
+	if err := s.Create(tx); err != nil {
+		return api.NewAppError(err, api.ErrorSCIMProvisionFailure, api.CategoryInternal)
+	}
+
+	if s.Active {
+		return s.ensureMembership(tx)
+	}
+	return nil
+}
+
+// Apply replaces the attributes of the SCIMUser. A change of the active flag adds or removes the User's membership
+// in the Organization.
+func (s *SCIMUser) Apply(tx *pop.Connection, attrs SCIMUserAttributes) error {
+	if err := validateSCIMUserAttributes(attrs); err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(attrs.UserName, s.UserName) {
+		var existing SCIMUsers
+		n, err := existing.FindByOrganization(tx, Organization{ID: s.OrganizationID}, "userName", attrs.UserName, 1, 0)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			err := fmt.Errorf("userName '%s' is already provisioned in organization %d", attrs.UserName,
+				s.OrganizationID)
+			return api.NewAppError(err, api.ErrorSCIMUserConflict, api.CategoryUser)
+		}
+	}
+
+	if err := tx.Find(&s.User, s.UserID); err != nil {
+		return err
+	}
+
+	wasActive := s.Active
+	s.ExternalID = attrs.ExternalID
+	s.UserName = attrs.UserName
+	s.Email = attrs.Email
+	s.FirstName = attrs.FirstName
+	s.LastName = attrs.LastName
+	s.Active = attrs.Active
+	if err := s.Update(tx); err != nil {
+		return api.NewAppError(err, api.ErrorSCIMProvisionFailure, api.CategoryInternal)
+	}
+
+	switch {
+	case s.Active:
+		return s.ensureMembership(tx)
+	case wasActive:
+		return s.removeMembership(tx)
+	}
+	return nil
+}
+
+// Attributes returns the current attributes of the SCIMUser, for use as the starting point of a partial update
+func (s *SCIMUser) Attributes() SCIMUserAttributes {
+	return SCIMUserAttributes{
+		ExternalID: s.ExternalID,
+		UserName:   s.UserName,
+		Email:      s.Email,
+		FirstName:  s.FirstName,
+		LastName:   s.LastName,
+		Nickname:   s.User.Nickname,
+		Active:     s.Active,
+	}
+}
+
+// Deprovision removes the User from the Organization, ending their sessions and revoking their personal access
+// tokens, and deletes the SCIMUser. The User
+// record itself is kept, since it may belong to other Organizations and is referenced by the User's requests.
+func (s *SCIMUser) Deprovision(tx *pop.Connection) error {
+	if err := s.removeMembership(tx); err != nil {
+		return err
+	}
+	if err := tx.Destroy(s); err != nil {
+		return fmt.Errorf("error deleting SCIM user %d, %w", s.ID, err)
+	}
+	return nil
+}
+
+// IsAdmin returns true if the User is an admin of the Organization
+func (s *SCIMUser) IsAdmin(tx *pop.Connection) bool {
+	var userOrg UserOrganization
+	err := tx.Where("organization_id = ? AND user_id = ?", s.OrganizationID, s.UserID).First(&userOrg)
+	return err == nil && userOrg.Role == UserOrganizationRoleAdmin
+}
+
+// SetAdmin changes the User's role in the Organization. The SCIMUser must be active.
+func (s *SCIMUser) SetAdmin(tx *pop.Connection, isAdmin bool) error {
+	if !s.Active {
+		err := fmt.Errorf("SCIM user %d is not active and cannot be an admin", s.ID)
+		return api.NewAppError(err, api.ErrorSCIMInvalidValue, api.CategoryUser)
+	}
+
+	var userOrg UserOrganization
+	if err := tx.Where("organization_id = ? AND user_id = ?", s.OrganizationID, s.UserID).First(&userOrg); err != nil {
+		return fmt.Errorf("error finding membership of SCIM user %d, %w", s.ID, err)
+	}
+
+	role := UserOrganizationRoleUser
+	if isAdmin {
+		role = UserOrganizationRoleAdmin
+	}
+	if userOrg.Role == role {
+		return nil
+	}
+
+	userOrg.Role = role
+	return userOrg.Update(tx)
+}
+
+// canLinkSCIMUser returns true if an existing User may be provisioned in the Organization, which is only the case if
+// the User is already a member of the Organization or has an email address on one of the Organization's domains.
+// Otherwise, any Organization's identity provider could take over the account of a member of another Organization.
+func canLinkSCIMUser(tx *pop.Connection, org Organization, user User) (bool, error) {
+	isMember, err := tx.Where("organization_id = ? AND user_id = ?", org.ID, user.ID).Exists(&UserOrganization{})
+	if err != nil || isMember {
+		return isMember, err
+	}
+
+	at := strings.LastIndex(user.Email, "@")
+	if at < 0 {
+		return false, nil
+	}
+	emailDomain := strings.ToLower(user.Email[at+1:])
+	return tx.Where("organization_id = ? AND LOWER(domain) = ?", org.ID, emailDomain).Exists(&OrganizationDomain{})
+}
+
+// ensureMembership creates or updates the UserOrganization of the SCIMUser. The identity provider's externalId, or
+// the userName if none is given, must match the user ID given by the Organization's auth provider at login.
+func (s *SCIMUser) ensureMembership(tx *pop.Connection) error {
+	authID := s.ExternalID
+	if authID == "" {
+		authID = s.UserName
+	}
+
+	var userOrg UserOrganization
+	err := tx.Where("organization_id = ? AND user_id = ?", s.OrganizationID, s.UserID).First(&userOrg)
+	if domain.IsOtherThanNoRows(err) {
+		return err
+	}
+
+	if userOrg.ID == 0 {
+		userOrg = UserOrganization{
+			OrganizationID: s.OrganizationID,
+			UserID:         s.UserID,
+			Role:           UserOrganizationRoleUser,
+			AuthID:         authID,
+			AuthEmail:      s.Email,
+			LastLogin:      time.Now(),
+		}
+		if err := userOrg.Create(tx); err != nil {
+			return api.NewAppError(err, api.ErrorSCIMProvisionFailure, api.CategoryInternal)
+		}
+		return nil
+	}
+
+	if userOrg.AuthID == authID && userOrg.AuthEmail == s.Email {
+		return nil
+	}
+	userOrg.AuthID = authID
+	userOrg.AuthEmail = s.Email
+	if err := userOrg.Update(tx); err != nil {
+		return api.NewAppError(err, api.ErrorSCIMProvisionFailure, api.CategoryInternal)
+	}
+	return nil
+}
+
+// removeMembership revokes the access tokens the User obtained through the Organization and all of the User's personal
+// access tokens, and removes the User from the Organization
+func (s *SCIMUser) removeMembership(tx *pop.Connection) error {
+	var userOrg UserOrganization
+	err := tx.Where("organization_id = ? AND user_id = ?", s.OrganizationID, s.UserID).First(&userOrg)
+	if domain.IsOtherThanNoRows(err) {
+		return err
+	}
+	if userOrg.ID == 0 {
+		return nil
+	}
+
+	var tokens UserAccessTokens
+	if err := tokens.DeleteByUserOrganization(tx, userOrg); err != nil {
+		return fmt.Errorf("error revoking access tokens of SCIM user %d, %w", s.ID, err)
+	}
+
+	// personal access tokens are not tied to an Organization, so none of them can be trusted to stay within the
+	// User's remaining memberships
+	var pats PersonalAccessTokens
+	if err := pats.DeleteByUser(tx, User{ID: s.UserID}); err != nil {
+		return fmt.Errorf("error revoking personal access tokens of SCIM user %d, %w", s.ID, err)
+	}
+
+	if err := tx.Destroy(&userOrg); err != nil {
+		return fmt.Errorf("error removing membership of SCIM user %d, %w", s.ID, err)
+	}
+	return nil
+}
+
+func validateSCIMUserAttributes(attrs SCIMUserAttributes) error {
+	var missing []string
+	if attrs.UserName == "" {
+		missing = append(missing, "userName")
+	}
+	if attrs.Email == "" {
+		missing = append(missing, "emails")
+	}
+	if len(missing) > 0 {
+		err := errors.New("missing required attributes: " + strings.Join(missing, ", "))
+		return api.NewAppError(err, api.ErrorSCIMInvalidValue, api.CategoryUser)
+	}
+
+	errs := validate.Validate(&validators.EmailIsPresent{Field: attrs.Email, Name: "Email"})
+	if errs.HasAny() {
+		err := fmt.Errorf("invalid email address '%s'", attrs.Email)
+		return api.NewAppError(err, api.ErrorSCIMInvalidValue, api.CategoryUser)
+	}
+	return nil
+}
+
+// GetRealName returns the name given by the identity provider
+func (s *SCIMUser) GetRealName() string {
+	return strings.TrimSpace(s.FirstName + " " + s.LastName)
+}
+
+// ConvertSCIMUser converts a SCIMUser, which must have its User loaded, to api.SCIMUser
+func ConvertSCIMUser(tx *pop.Connection, s SCIMUser, baseURL string) api.SCIMUser {
+	active := s.Active
+	created := s.CreatedAt
+	modified := s.UpdatedAt
+
+	output := api.SCIMUser{
+		Schemas:    []string{api.SCIMSchemaUser},
+		ID:         s.User.UUID.String(),
+		ExternalID: s.ExternalID,
+		UserName:   s.UserName,
+		Name: api.SCIMName{
+			Formatted:  s.GetRealName(),
+			GivenName:  s.FirstName,
+			FamilyName: s.LastName,
+		},
+		DisplayName: s.GetRealName(),
+		NickName:    s.User.Nickname,
+		Emails:      []api.SCIMEmail{{Value: s.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta: &api.SCIMMeta{
+			ResourceType: "User",
+			Created:      &created,
+			LastModified: &modified,
+			Location:     baseURL + "/Users/" + s.User.UUID.String(),
+		},
+	}
+
+	if s.Active && s.IsAdmin(tx) {
+		output.Groups = []api.SCIMGroupRef{{Value: SCIMGroupAdmins, Display: "Administrators"}}
+	}
+
+	return output
+}
+
+// ConvertSCIMAdminGroup returns the SCIM Group of the admins of the Organization
+func ConvertSCIMAdminGroup(admins SCIMUsers, baseURL string) api.SCIMGroup {
+	output := api.SCIMGroup{
+		Schemas:     []string{api.SCIMSchemaGroup},
+		ID:          SCIMGroupAdmins,
+		DisplayName: "Administrators",
+		Members:     make([]api.SCIMMember, len(admins)),
+		Meta: &api.SCIMMeta{
+			ResourceType: "Group",
+			Location:     baseURL + "/Groups/" + SCIMGroupAdmins,
+		},
+	}
+
+	for i := range admins {
+		output.Members[i] = api.SCIMMember{
+			Value:   admins[i].User.UUID.String(),
+			Display: admins[i].UserName,
+		}
+	}
+
+	return output
+}
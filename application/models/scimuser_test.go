@@ -0,0 +1,247 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+
+	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/domain"
+)
+
+func (ms *ModelSuite) TestOrganization_SCIMToken() {
+	org := createOrganizationFixtures(ms.DB, 1)[0]
+
+	ms.False(org.IsValidSCIMToken(""), "empty token should not be valid")
+
+	token, err := org.CreateSCIMToken(ms.DB)
+	ms.NoError(err)
+	ms.Contains(token, SCIMTokenPrefix)
+
+	var reloaded Organization
+	ms.NoError(reloaded.FindByUUID(ms.DB, org.UUID.String()))
+	ms.True(reloaded.IsValidSCIMToken(token), "new token should be valid")
+	ms.False(reloaded.IsValidSCIMToken(token+"x"), "wrong token should not be valid")
+
+	ms.NoError(reloaded.RemoveSCIMToken(ms.DB))
+	ms.False(reloaded.IsValidSCIMToken(token), "removed token should not be valid")
+}
+
+func (ms *ModelSuite) TestSCIMUser_Provision() {
+	org := createOrganizationFixtures(ms.DB, 2)[1]
+	users := createUserFixtures(ms.DB, 3).Users
+
+	// users[0] is only a member of another organization
+	member := users[1]
+	mustCreate(ms.DB, &UserOrganization{
+		OrganizationID: org.ID,
+		UserID:         member.ID,
+		Role:           UserOrganizationRoleUser,
+		AuthID:         member.Email,
+		AuthEmail:      member.Email,
+	})
+	onDomain := users[2]
+	onDomain.Email = "on_domain@scim.example.org"
+	ms.NoError(ms.DB.Update(&onDomain))
+	ms.NoError(org.AddDomain(ms.DB, "SCIM.example.org", AuthTypeSaml, ""))
+
+	tests := []struct {
+		name     string
+		attrs    SCIMUserAttributes
+		wantKey  api.ErrorKey
+		wantUser *User
+	}{
+		{
+			name: "new user",
+			attrs: SCIMUserAttributes{
+				ExternalID: "ext1",
+				UserName:   "new@example.com",
+				Email:      "new@example.com",
+				FirstName:  "New",
+				LastName:   "User",
+				Active:     true,
+			},
+		},
+		{
+			name: "user of another organization",
+			attrs: SCIMUserAttributes{
+				UserName:  "other",
+				Email:     users[0].Email,
+				FirstName: "Taken",
+				LastName:  "Over",
+				Active:    true,
+			},
+			wantKey: api.ErrorSCIMUserConflict,
+		},
+		{
+			name: "existing member",
+			attrs: SCIMUserAttributes{
+				UserName:  "existing",
+				Email:     member.Email,
+				FirstName: "Existing",
+				LastName:  "User",
+				Active:    true,
+			},
+			wantUser: &member,
+		},
+		{
+			name:     "existing user on a domain of the organization",
+			attrs:    SCIMUserAttributes{UserName: "on_domain", Email: onDomain.Email, Active: true},
+			wantUser: &onDomain,
+		},
+		{
+			name:    "duplicate userName",
+			attrs:   SCIMUserAttributes{UserName: "NEW@example.com", Email: "other@example.com", Active: true},
+			wantKey: api.ErrorSCIMUserConflict,
+		},
+		{
+			name:    "duplicate email",
+			attrs:   SCIMUserAttributes{UserName: "another", Email: member.Email, Active: true},
+			wantKey: api.ErrorSCIMUserConflict,
+		},
+		{
+			name:    "missing email",
+			attrs:   SCIMUserAttributes{UserName: "noemail", Active: true},
+			wantKey: api.ErrorSCIMInvalidValue,
+		},
+	}
+	for _, tt := range tests {
+		ms.T().Run(tt.name, func(t *testing.T) {
+			var s SCIMUser
+			err := s.Provision(ms.DB, org, tt.attrs)
+			if tt.wantKey != "" {
+				ms.Error(err)
+				appErr, ok := err.(*api.AppError)
+				ms.True(ok, "error is not an AppError")
+				ms.Equal(tt.wantKey, appErr.Key)
+				return
+			}
+			ms.NoError(err)
+
+			ms.Equal(tt.attrs.FirstName, s.FirstName)
+			if tt.wantUser == nil {
+				ms.Equal(tt.attrs.FirstName, s.User.FirstName)
+				ms.NotEqual("", s.User.Nickname, "new user should have a nickname")
+			} else {
+				ms.Equal(tt.wantUser.ID, s.UserID)
+				var user User
+				ms.NoError(ms.DB.Find(&user, s.UserID))
+				ms.Equal(tt.wantUser.FirstName, user.FirstName, "an existing user should not be renamed")
+			}
+
+			userOrg, err := s.User.FindUserOrganization(ms.DB, org)
+			ms.NoError(err, "user should be a member of the organization")
+			ms.Equal(UserOrganizationRoleUser, userOrg.Role)
+			ms.Equal(tt.attrs.Email, userOrg.AuthEmail)
+
+			wantAuthID := tt.attrs.ExternalID
+			if wantAuthID == "" {
+				wantAuthID = tt.attrs.UserName
+			}
+			ms.Equal(wantAuthID, userOrg.AuthID)
+		})
+	}
+}
+
+func (ms *ModelSuite) TestSCIMUser_ApplyAndDeprovision() {
+	org := createOrganizationFixtures(ms.DB, 2)[1]
+
+	var s SCIMUser
+	attrs := SCIMUserAttributes{UserName: "user1", Email: "user1@example.com", FirstName: "A", LastName: "B", Active: true}
+	ms.NoError(s.Provision(ms.DB, org, attrs))
+
+	userOrg, err := s.User.FindUserOrganization(ms.DB, org)
+	ms.NoError(err)
+	token := UserAccessToken{
+		UUID:               domain.GetUUID(),
+		UserID:             s.UserID,
+		UserOrganizationID: nulls.NewInt(userOrg.ID),
+		AccessToken:        HashClientIdAccessToken("scim-user-token"),
+		ExpiresAt:          time.Now().Add(time.Hour),
+	}
+	ms.NoError(ms.DB.Create(&token))
+	newPAT := func() {
+		_, _, err := s.User.CreatePersonalAccessToken(ms.DB, "t", []TokenScope{TokenScopeRequestsRead}, nulls.Time{})
+		ms.NoError(err)
+	}
+	newPAT()
+
+	// rename
+	attrs.FirstName = "Renamed"
+	ms.NoError(s.Apply(ms.DB, attrs))
+	ms.Equal("Renamed", s.Attributes().FirstName)
+	var user User
+	ms.NoError(ms.DB.Find(&user, s.UserID))
+	ms.Equal("A", user.FirstName, "the identity provider should not rename the user")
+
+	// deactivate
+	attrs.Active = false
+	ms.NoError(s.Apply(ms.DB, attrs))
+	_, err = user.FindUserOrganization(ms.DB, org)
+	ms.Error(err, "deactivated user should not be a member of the organization")
+	n, err := ms.DB.Where("user_id = ?", s.UserID).Count(&UserAccessToken{})
+	ms.NoError(err)
+	ms.Equal(0, n, "deactivated user's access tokens should be revoked")
+	n, err = ms.DB.Where("user_id = ?", s.UserID).Count(&PersonalAccessToken{})
+	ms.NoError(err)
+	ms.Equal(0, n, "deactivated user's personal access tokens should be revoked")
+
+	// reactivate
+	attrs.Active = true
+	ms.NoError(s.Apply(ms.DB, attrs))
+	_, err = user.FindUserOrganization(ms.DB, org)
+	ms.NoError(err, "reactivated user should be a member of the organization")
+
+	// admin
+	ms.NoError(s.SetAdmin(ms.DB, true))
+	ms.True(s.IsAdmin(ms.DB))
+	var admins SCIMUsers
+	ms.NoError(admins.FindAdmins(ms.DB, org))
+	ms.Equal(1, len(admins))
+
+	// deprovision
+	newPAT()
+	ms.NoError(s.Deprovision(ms.DB))
+	_, err = user.FindUserOrganization(ms.DB, org)
+	ms.Error(err, "deprovisioned user should not be a member of the organization")
+	n, err = ms.DB.Where("user_id = ?", s.UserID).Count(&PersonalAccessToken{})
+	ms.NoError(err)
+	ms.Equal(0, n, "deprovisioned user's personal access tokens should be revoked")
+	var found SCIMUser
+	ms.Error(found.FindByOrganizationAndUserUUID(ms.DB, org, user.UUID.String()))
+	ms.NoError(ms.DB.Find(&User{}, s.UserID), "user record should be kept")
+}
+
+func (ms *ModelSuite) TestSCIMUsers_FindByOrganization() {
+	orgs := createOrganizationFixtures(ms.DB, 2)
+
+	for i, name := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		var s SCIMUser
+		attrs := SCIMUserAttributes{UserName: name, Email: name, ExternalID: "ext" + name, Active: i != 1}
+		ms.NoError(s.Provision(ms.DB, orgs[0], attrs))
+	}
+	ms.NoError(orgs[1].AddDomain(ms.DB, "example.com", AuthTypeSaml, ""))
+	var other SCIMUser
+	ms.NoError(other.Provision(ms.DB, orgs[1], SCIMUserAttributes{UserName: "a@example.com", Email: "a@example.com"}))
+
+	var users SCIMUsers
+	total, err := users.FindByOrganization(ms.DB, orgs[0], "", "", 2, 1)
+	ms.NoError(err)
+	ms.Equal(3, total)
+	ms.Equal(1, len(users))
+	ms.Equal("b@example.com", users[0].UserName)
+	ms.Equal("b@example.com", users[0].User.Email, "User should be loaded")
+
+	total, err = users.FindByOrganization(ms.DB, orgs[0], "userName", "C@EXAMPLE.COM", 1, 10)
+	ms.NoError(err)
+	ms.Equal(1, total)
+	ms.Equal("c@example.com", users[0].UserName)
+
+	total, err = users.FindByOrganization(ms.DB, orgs[0], "externalId", "exta@example.com", 1, 10)
+	ms.NoError(err)
+	ms.Equal(1, total)
+
+	_, err = users.FindByOrganization(ms.DB, orgs[0], "nickName", "x", 1, 10)
+	ms.Error(err)
+}
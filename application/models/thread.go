@@ -26,10 +26,39 @@ type Thread struct {
 	Participants Users     `many_to_many:"thread_participants" order_by:"id asc"`
 	Messages     Messages  `json:"messages" db:"-"`
 
+	LatestMessage      *Message   `json:"-" db:"-"`
 	LastViewedAt       *time.Time `json:"last_viewed_at" db:"-"`
 	UnreadMessageCount int        `json:"unread_message_count" db:"-"`
 }
 
+// threadReadStatus is the read status of a thread for one of its participants
+type threadReadStatus struct {
+	ThreadID     int       `db:"thread_id"`
+	ThreadUUID   uuid.UUID `db:"thread_uuid"`
+	LastViewedAt time.Time `db:"last_viewed_at"`
+	UnreadCount  int       `db:"unread_count"`
+}
+
+// threadReadStatusSQL selects the read status of each of a user's threads in a single query. A message is unread if
+// it was sent by another user after the user last viewed the thread and has not been deleted. Param: user ID.
+const threadReadStatusSQL = `
+SELECT tp.thread_id, t.uuid AS thread_uuid, tp.last_viewed_at, COUNT(m.id) AS unread_count
+FROM thread_participants tp
+JOIN threads t ON t.id = tp.thread_id
+LEFT JOIN messages m ON m.thread_id = tp.thread_id AND m.sent_by_id <> tp.user_id
+	AND m.created_at > tp.last_viewed_at AND m.deleted_at IS NULL
+WHERE tp.user_id = ?
+GROUP BY tp.thread_id, t.uuid, tp.last_viewed_at`
+
+// getThreadReadStatuses returns the read status of each of the user's threads
+func getThreadReadStatuses(tx *pop.Connection, userID int) ([]threadReadStatus, error) {
+	var statuses []threadReadStatus
+	if err := tx.RawQuery(threadReadStatusSQL, userID).All(&statuses); err != nil {
+		return nil, fmt.Errorf("error getting read status of threads for user %d, %s", userID, err)
+	}
+	return statuses, nil
+}
+
 // String can be helpful for serializing the model
 func (t Thread) String() string {
 	jt, _ := json.Marshal(t)
@@ -63,6 +92,24 @@ func (t *Thread) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
 	return validate.NewErrors(), nil
 }
 
+// FindByUUIDForParticipant loads the thread with the given UUID, if the user is one of its participants
+func (t *Thread) FindByUUIDForParticipant(tx *pop.Connection, id string, user User) *api.AppError {
+	if err := t.FindByUUID(tx, id); err != nil {
+		appError := api.NewAppError(err, api.ErrorThreadNotFound, api.CategoryNotFound)
+		if domain.IsOtherThanNoRows(err) {
+			appError.Category = api.CategoryInternal
+		}
+		return appError
+	}
+
+	if !t.IsVisible(tx, user.ID) {
+		err := fmt.Errorf("user %s is not a participant of thread %s", user.UUID, id)
+		return api.NewAppError(err, api.ErrorNotAuthorized, api.CategoryForbidden)
+	}
+
+	return nil
+}
+
 func (t *Thread) FindByUUID(tx *pop.Connection, uuid string) error {
 	if uuid == "" {
 		return errors.New("error: thread uuid must not be blank")
@@ -117,6 +164,42 @@ func (t *Thread) LoadMessages(tx *pop.Connection, eagerFields ...string) error {
 	return nil
 }
 
+// LoadMessagesPage loads up to `limit` of the thread's messages, with their senders. If `before` is the UUID of one of
+// the thread's messages, the messages sent before it are loaded, otherwise the most recent messages are loaded. The
+// messages are in the order they were sent. Returns true if there are older messages.
+func (t *Thread) LoadMessagesPage(tx *pop.Connection, before string, limit int) (bool, error) {
+	q := tx.EagerPreload("SentBy").Where("thread_id = ?", t.ID)
+
+	if before != "" {
+		var cursor Message
+		if err := tx.Where("uuid = ? AND thread_id = ?", before, t.ID).First(&cursor); err != nil {
+			appError := api.NewAppError(err, api.ErrorThreadMessagesBeforeInvalid, api.CategoryUser)
+			if domain.IsOtherThanNoRows(err) {
+				appError.Category = api.CategoryInternal
+			}
+			return false, appError
+		}
+		q = q.Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+
+	messages := Messages{}
+	if err := q.Order("created_at desc, id desc").Limit(limit + 1).All(&messages); err != nil {
+		return false, fmt.Errorf("error getting messages for thread id %v ... %v", t.ID, err)
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	t.Messages = messages
+	return hasMore, nil
+}
+
 func (t *Thread) LoadParticipants(tx *pop.Connection) error {
 	if err := tx.Load(t, "Participants"); err != nil {
 		return fmt.Errorf("error loading threads participants %v ... %v", t.ID, err)
@@ -207,12 +290,17 @@ func (t *Thread) UpdateLastViewedAt(tx *pop.Connection, userID int, time time.Ti
 	return tp.UpdateLastViewedAt(tx, time)
 }
 
+// LoadForAPI loads the data needed to render the thread, including its most recent messages
 func (t *Thread) LoadForAPI(tx *pop.Connection, user User) error {
-	err := t.LoadMessages(tx, "SentBy")
+	_, err := t.LoadMessagesPage(tx, "", domain.ThreadMessagesPageSize)
 	if err != nil {
 		return errors.New("error loading thread messages: " + err.Error())
 	}
 
+	if n := len(t.Messages); n > 0 {
+		t.LatestMessage = &t.Messages[n-1]
+	}
+
 	err = t.LoadParticipants(tx)
 	if err != nil {
 		return errors.New("error loading participants: " + err.Error())
@@ -252,22 +340,14 @@ func (t *Thread) Load(tx *pop.Connection, fields ...string) error {
 // GetUnreadMessageCount returns the number of messages on this thread that the current
 //  user has not created and for which the CreatedAt value is after the lastViewedAt value
 func (t *Thread) GetUnreadMessageCount(tx *pop.Connection, userID int, lastViewedAt time.Time) (int, error) {
-	count := 0
 	if userID <= 0 {
-		return count, fmt.Errorf("error in GetUnreadMessageCount, invalid id %v", userID)
-	}
-
-	if len(t.Messages) == 0 {
-		err := t.LoadMessages(tx)
-		if err != nil {
-			return count, err
-		}
+		return 0, fmt.Errorf("error in GetUnreadMessageCount, invalid id %v", userID)
 	}
 
-	for _, m := range t.Messages {
-		if m.SentByID != userID && m.CreatedAt.After(lastViewedAt) {
-			count++
-		}
+	count, err := tx.Where("thread_id = ? AND sent_by_id <> ? AND created_at > ? AND deleted_at IS NULL",
+		t.ID, userID, lastViewedAt).Count(Message{})
+	if err != nil {
+		return 0, fmt.Errorf("error counting unread messages for thread id %v ... %v", t.ID, err)
 	}
 
 	return count, nil
@@ -298,6 +378,74 @@ func (t *Thread) IsVisible(tx *pop.Connection, userID int) bool {
 	return false
 }
 
+// loadForAPIList loads the data needed to render the threads in a list. Rather than all of its messages, each thread
+// gets its latest message.
+func (t Threads) loadForAPIList(tx *pop.Connection, user User) error {
+	if len(t) == 0 {
+		return nil
+	}
+
+	statuses, err := getThreadReadStatuses(tx, user.ID)
+	if err != nil {
+		return err
+	}
+	statusByThread := map[int]threadReadStatus{}
+	for _, s := range statuses {
+		statusByThread[s.ThreadID] = s
+	}
+
+	latest, err := t.getLatestMessages(tx)
+	if err != nil {
+		return err
+	}
+
+	for i := range t {
+		if err := t[i].LoadParticipants(tx); err != nil {
+			return errors.New("error loading participants: " + err.Error())
+		}
+
+		if err := t[i].LoadRequest(tx, "CreatedBy"); err != nil {
+			return errors.New("error loading thread request: " + err.Error())
+		}
+
+		if status, ok := statusByThread[t[i].ID]; ok {
+			lastViewedAt := status.LastViewedAt
+			t[i].LastViewedAt = &lastViewedAt
+			t[i].UnreadMessageCount = status.UnreadCount
+		}
+
+		if m, ok := latest[t[i].ID]; ok {
+			message := m
+			t[i].LatestMessage = &message
+		}
+	}
+
+	return nil
+}
+
+// getLatestMessages retrieves the most recent message, with its sender, of each of the threads, keyed by thread ID
+func (t Threads) getLatestMessages(tx *pop.Connection) (map[int]Message, error) {
+	ids := make([]int, len(t))
+	for i := range t {
+		ids[i] = t[i].ID
+	}
+
+	var messages Messages
+	err := tx.EagerPreload("SentBy").
+		Where("id IN (SELECT DISTINCT ON (thread_id) id FROM messages WHERE thread_id IN (?) "+
+			"ORDER BY thread_id, created_at DESC, id DESC)", ids).
+		All(&messages)
+	if err != nil {
+		return nil, fmt.Errorf("error getting latest messages of threads, %s", err)
+	}
+
+	latest := map[int]Message{}
+	for _, m := range messages {
+		latest[m.ThreadID] = m
+	}
+	return latest, nil
+}
+
 // converts models.Threads to api.Threads
 func ConvertThreadsToAPIType(ctx context.Context, threads Threads) (api.Threads, error) {
 	var output api.Threads
@@ -308,11 +456,19 @@ func ConvertThreadsToAPIType(ctx context.Context, threads Threads) (api.Threads,
 
 	// Hydrate the thread's messages, participants
 	for i := range output {
-		messagesOutput, err := ConvertMessagesToAPIType(ctx, threads[i].Messages)
+		if threads[i].Messages != nil {
+			messagesOutput, err := ConvertMessagesToAPIType(ctx, threads[i].Messages)
+			if err != nil {
+				return nil, err
+			}
+			output[i].Messages = &messagesOutput
+		}
+
+		latestMessage, err := convertLatestMessage(ctx, threads[i].LatestMessage)
 		if err != nil {
 			return nil, err
 		}
-		output[i].Messages = &messagesOutput
+		output[i].LatestMessage = latestMessage
 
 		// Not converting Participants, since that happens automatically  above and
 		// because it doesn't have nested related objects
@@ -347,6 +503,12 @@ func ConvertThread(ctx context.Context, thread Thread) (api.Thread, error) {
 	}
 	output.Messages = &messagesOutput
 
+	latestMessage, err := convertLatestMessage(ctx, thread.LatestMessage)
+	if err != nil {
+		return api.Thread{}, err
+	}
+	output.LatestMessage = latestMessage
+
 	// Not converting Participants, since that happens automatically  above and
 	// because it doesn't have nested related objects
 	for i := range output.Participants {
@@ -367,3 +529,15 @@ func ConvertThread(ctx context.Context, thread Thread) (api.Thread, error) {
 	output.ID = thread.UUID
 	return output, nil
 }
+
+func convertLatestMessage(ctx context.Context, message *Message) (*api.Message, error) {
+	if message == nil {
+		return nil, nil
+	}
+
+	output, err := ConvertMessagesToAPIType(ctx, Messages{*message})
+	if err != nil {
+		return nil, err
+	}
+	return &output[0], nil
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/gobuffalo/validate/v3"
 	"github.com/gofrs/uuid"
+
+	"github.com/silinternational/wecarry-api/api"
 	"github.com/silinternational/wecarry-api/domain"
 )
 
@@ -190,6 +192,78 @@ func (ms *ModelSuite) TestThread_GetMessages() {
 	}
 }
 
+func (ms *ModelSuite) TestThread_LoadMessagesPage() {
+	t := ms.T()
+
+	users := createUserFixtures(ms.DB, 2).Users
+	requests := createRequestFixtures(ms.DB, 1, false, users[0].ID)
+	f := CreateThreadFixtures(ms, requests[0])
+
+	tests := []struct {
+		name        string
+		thread      Thread
+		before      string
+		limit       int
+		want        []uuid.UUID
+		wantHasMore bool
+		wantErr     api.ErrorKey
+	}{
+		{
+			name:   "all",
+			thread: f.Threads[1],
+			limit:  10,
+			want:   []uuid.UUID{f.Messages[1].UUID, f.Messages[2].UUID},
+		},
+		{
+			name:        "latest",
+			thread:      f.Threads[1],
+			limit:       1,
+			want:        []uuid.UUID{f.Messages[2].UUID},
+			wantHasMore: true,
+		},
+		{
+			name:   "before",
+			thread: f.Threads[1],
+			before: f.Messages[2].UUID.String(),
+			limit:  1,
+			want:   []uuid.UUID{f.Messages[1].UUID},
+		},
+		{
+			name:   "no messages",
+			thread: f.Threads[2],
+			limit:  10,
+			want:   []uuid.UUID{},
+		},
+		{
+			name:    "before is in another thread",
+			thread:  f.Threads[1],
+			before:  f.Messages[0].UUID.String(),
+			limit:   10,
+			wantErr: api.ErrorThreadMessagesBeforeInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasMore, err := tt.thread.LoadMessagesPage(ms.DB, tt.before, tt.limit)
+			if tt.wantErr != "" {
+				appErr, ok := err.(*api.AppError)
+				ms.True(ok, "error is not an AppError")
+				ms.Equal(tt.wantErr, appErr.Key, "wrong error type")
+				return
+			}
+			ms.NoError(err)
+
+			ids := make([]uuid.UUID, len(tt.thread.Messages))
+			for i, m := range tt.thread.Messages {
+				ids[i] = m.UUID
+				ms.Equal(m.SentByID, m.SentBy.ID, "sender was not loaded")
+			}
+			ms.Equal(tt.want, ids, "wrong messages")
+			ms.Equal(tt.wantHasMore, hasMore, "wrong hasMore")
+		})
+	}
+}
+
 func (ms *ModelSuite) TestThread_LoadParticipants() {
 	t := ms.T()
 
@@ -1,24 +1,42 @@
 package models
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gobuffalo/validate/v3"
 	"github.com/gobuffalo/validate/v3/validators"
+
+	"github.com/silinternational/wecarry-api/domain"
 )
 
+// replyAddressPrefix begins the local part of each reply-to address, and is followed by the participant's reply token
+const replyAddressPrefix = "reply+"
+
+// replyTokenBytes is the number of random bytes in a reply token, which is hex encoded since some mail servers do not
+// preserve the case of an address's local part
+const replyTokenBytes = 16
+
+// errNotReplyToAddress is returned when looking up a thread participant by an address that is not a reply-to address
+var errNotReplyToAddress = errors.New("not a reply-to address")
+
 type ThreadParticipant struct {
-	ID             int       `json:"id" db:"id"`
-	CreatedAt      time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
-	ThreadID       int       `json:"thread_id" db:"thread_id"`
-	UserID         int       `json:"user_id" db:"user_id"`
-	LastViewedAt   time.Time `json:"last_viewed_at" db:"last_viewed_at"`
-	LastNotifiedAt time.Time `json:"last_notified_at" db:"last_notified_at"`
-	Thread         Thread    `belongs_to:"threads"`
+	ID             int          `json:"id" db:"id"`
+	CreatedAt      time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at" db:"updated_at"`
+	ThreadID       int          `json:"thread_id" db:"thread_id"`
+	UserID         int          `json:"user_id" db:"user_id"`
+	LastViewedAt   time.Time    `json:"last_viewed_at" db:"last_viewed_at"`
+	LastNotifiedAt time.Time    `json:"last_notified_at" db:"last_notified_at"`
+	ReplyToken     nulls.String `json:"-" db:"reply_token"`
+	Thread         Thread       `belongs_to:"threads"`
 }
 
 // String can be helpful for serializing the model
@@ -85,6 +103,48 @@ func (t *ThreadParticipant) UpdateLastNotifiedAt(tx *pop.Connection, newTime tim
 	return nil
 }
 
+// GetReplyToAddress returns the address to which the participant can reply by email to the thread's messages, creating
+// the participant's reply token if needed. If replies by email are not enabled, an empty string is returned.
+func (t *ThreadParticipant) GetReplyToAddress(tx *pop.Connection) (string, error) {
+	if domain.Env.EmailReplyDomain == "" {
+		return "", nil
+	}
+
+	if !t.ReplyToken.Valid {
+		b := make([]byte, replyTokenBytes)
+		if _, err := rand.Read(b); err != nil {
+			return "", fmt.Errorf("failed to create reply token, %s", err)
+		}
+		t.ReplyToken = nulls.NewString(hex.EncodeToString(b))
+		if err := tx.UpdateColumns(t, "reply_token", "updated_at"); err != nil {
+			return "", fmt.Errorf("failed to save thread_participant.reply_token, %s", err)
+		}
+	}
+
+	return replyAddressPrefix + t.ReplyToken.String + "@" + domain.Env.EmailReplyDomain, nil
+}
+
+// FindByReplyToAddress reads the record whose reply-to address is the given address
+func (t *ThreadParticipant) FindByReplyToAddress(tx *pop.Connection, address string) error {
+	address = strings.ToLower(strings.TrimSpace(address))
+	at := strings.LastIndex(address, "@")
+	if domain.Env.EmailReplyDomain == "" || at < 0 ||
+		address[at+1:] != strings.ToLower(domain.Env.EmailReplyDomain) ||
+		!strings.HasPrefix(address[:at], replyAddressPrefix) {
+		return fmt.Errorf("'%s' is %w", address, errNotReplyToAddress)
+	}
+
+	token := address[len(replyAddressPrefix):at]
+	if len(token) != replyTokenBytes*2 {
+		return fmt.Errorf("'%s' is %w, invalid token length", address, errNotReplyToAddress)
+	}
+
+	if err := tx.Where("reply_token = ?", token).First(t); err != nil {
+		return fmt.Errorf("failed to find thread_participant for reply-to address %s, %s", address, err)
+	}
+	return nil
+}
+
 // Create stores the ThreadParticipant data as a new record in the database.
 func (t *ThreadParticipant) Create(tx *pop.Connection) error {
 	return create(tx, t)
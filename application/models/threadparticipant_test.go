@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -297,3 +298,50 @@ func (ms *ModelSuite) TestThreadParticipant_UpdateLastNotifiedAt() {
 		})
 	}
 }
+
+func (ms *ModelSuite) TestThreadParticipant_GetReplyToAddress() {
+	f := CreateFixtures_ThreadParticipant_FindByThreadIDAndUserID(ms)
+	tp := f.ThreadParticipants[0]
+
+	replyDomain := domain.Env.EmailReplyDomain
+	defer func() { domain.Env.EmailReplyDomain = replyDomain }()
+
+	domain.Env.EmailReplyDomain = ""
+	address, err := tp.GetReplyToAddress(ms.DB)
+	ms.NoError(err)
+	ms.Equal("", address, "replies should not be enabled without a reply domain")
+
+	domain.Env.EmailReplyDomain = "reply.example.com"
+	address, err = tp.GetReplyToAddress(ms.DB)
+	ms.NoError(err)
+	ms.Regexp(`^reply\+[0-9a-f]{32}@reply\.example\.com$`, address)
+
+	again, err := tp.GetReplyToAddress(ms.DB)
+	ms.NoError(err)
+	ms.Equal(address, again, "reply-to address should not change")
+
+	tests := []struct {
+		name    string
+		address string
+		wantErr bool
+	}{
+		{name: "good", address: address},
+		{name: "case-insensitive", address: strings.ToUpper(address)},
+		{name: "wrong domain", address: strings.Replace(address, "reply.example.com", "example.com", 1), wantErr: true},
+		{name: "no prefix", address: strings.Replace(address, "reply+", "", 1), wantErr: true},
+		{name: "unknown token", address: "reply+0123456789abcdef0123456789abcdef@reply.example.com", wantErr: true},
+		{name: "short token", address: "reply+0123@reply.example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		ms.T().Run(tt.name, func(t *testing.T) {
+			var got ThreadParticipant
+			err := got.FindByReplyToAddress(ms.DB, tt.address)
+			if tt.wantErr {
+				ms.Error(err)
+				return
+			}
+			ms.NoError(err)
+			ms.Equal(tp.ID, got.ID, "found the wrong thread_participant")
+		})
+	}
+}
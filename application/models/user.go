@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -58,6 +59,12 @@ type User struct {
 	FileID             nulls.Int         `json:"file_id" db:"file_id"`
 	AuthPhotoURL       nulls.String      `json:"auth_photo_url" db:"auth_photo_url"`
 	LocationID         nulls.Int         `json:"location_id" db:"location_id"`
+	SuspendedAt        nulls.Time        `json:"suspended_at" db:"suspended_at"`
+	MarketingOptIn     bool              `json:"marketing_opt_in" db:"marketing_opt_in"`
+	MarketingEmail     nulls.String      `json:"-" db:"marketing_email"`
+	MarketingSyncedAt  nulls.Time        `json:"-" db:"marketing_synced_at"`
+	OptInChangedAt     nulls.Time        `json:"-" db:"marketing_opt_in_changed_at"`
+	Bio                nulls.String      `json:"bio" db:"bio"`
 	Organizations      Organizations     `many_to_many:"user_organizations" order_by:"name asc" json:"-"`
 	UserOrganizations  UserOrganizations `has_many:"user_organizations" json:"-"`
 	UserPreferences    UserPreferences   `has_many:"user_preferences" json:"-"`
@@ -90,6 +97,7 @@ func (u *User) Validate(tx *pop.Connection) (*validate.Errors, error) {
 		&validators.UUIDIsPresent{Field: u.UUID, Name: "UUID"},
 		&NullsStringIsURL{Field: u.AuthPhotoURL, Name: "AuthPhotoURL"},
 		&domain.StringIsVisible{Field: u.Nickname, Name: "Nickname"},
+		&validators.StringLengthInRange{Field: u.Bio.String, Name: "Bio", Max: domain.MaxBioLength},
 	), nil
 }
 
@@ -103,6 +111,24 @@ func (u *User) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
 	return validate.NewErrors(), nil
 }
 
+// BeforeUpdate is called by Pop before updating the record. A change of any of the fields held by the marketing list
+// triggers a sync of the user's list membership. The time of a change of the opt-in is recorded.
+func (u *User) BeforeUpdate(tx *pop.Connection) error {
+	var stored User
+	if err := tx.Select("email", "first_name", "last_name", "marketing_opt_in").Find(&stored, u.ID); err != nil {
+		return fmt.Errorf("error reading user %s before update, %w", u.UUID, err)
+	}
+
+	if stored.MarketingOptIn != u.MarketingOptIn {
+		u.OptInChangedAt = nulls.NewTime(time.Now())
+	}
+	if stored.Email == u.Email && stored.FirstName == u.FirstName && stored.LastName == u.LastName &&
+		stored.MarketingOptIn == u.MarketingOptIn {
+		return nil
+	}
+	return u.requestMarketingSync(tx)
+}
+
 // All retrieves all Users from the database.
 func (u *Users) All(tx *pop.Connection) error {
 	return tx.Order("nickname asc").All(u)
@@ -116,29 +142,66 @@ func (u *User) CreateAccessToken(tx *pop.Connection, org Organization, clientID
 
 	token, _ := getRandomToken()
 	hash := HashClientIdAccessToken(clientID + token)
-	expireAt := createAccessTokenExpiry()
 
 	userAccessToken := &UserAccessToken{
 		UserID:      u.ID,
 		AccessToken: hash,
-		ExpiresAt:   expireAt,
+		ClientID:    clientID,
 	}
 
+	policy := defaultSessionPolicy()
 	if org.ID > 0 {
 		userOrg, err := u.FindUserOrganization(tx, org)
 		if err != nil {
 			return "", 0, err
 		}
 		userAccessToken.UserOrganizationID = nulls.NewInt(userOrg.ID)
+		policy = org.SessionPolicy()
 	}
 
+	expireAt := createAccessTokenExpiry(policy, time.Now())
+	userAccessToken.ExpiresAt = expireAt
+
 	if err := userAccessToken.Create(tx); err != nil {
 		return "", 0, err
 	}
 
+	if err := userAccessToken.deleteExcessSessions(tx, policy); err != nil {
+		return "", 0, err
+	}
+
 	return token, expireAt.UTC().Unix(), nil
 }
 
+// CreatePersonalAccessToken creates and stores a new PersonalAccessToken with the given name, scopes and optional
+// expiration. The returned secret token is not stored and cannot be retrieved later.
+func (u *User) CreatePersonalAccessToken(tx *pop.Connection, name string, scopes []TokenScope,
+	expiresAt nulls.Time) (PersonalAccessToken, string, error) {
+	if expiresAt.Valid && expiresAt.Time.Before(time.Now()) {
+		return PersonalAccessToken{}, "", errors.New("personal access token expiration must be in the future")
+	}
+
+	random, err := getRandomToken()
+	if err != nil {
+		return PersonalAccessToken{}, "", err
+	}
+	token := PersonalAccessTokenPrefix + random
+
+	pat := PersonalAccessToken{
+		UserID:    u.ID,
+		Name:      name,
+		TokenHash: HashClientIdAccessToken(token),
+		ExpiresAt: expiresAt,
+	}
+	pat.SetScopes(scopes)
+
+	if err := pat.Create(tx); err != nil {
+		return PersonalAccessToken{}, "", err
+	}
+
+	return pat, token, nil
+}
+
 // CreateOrglessAccessToken - Create and store new UserAccessToken with no associated UserOrg
 func (u *User) CreateOrglessAccessToken(tx *pop.Connection, clientID string) (string, int64, error) {
 	return u.CreateAccessToken(tx, Organization{}, clientID)
@@ -177,6 +240,7 @@ func (u *User) hydrateFromAuthUser(tx *pop.Connection, authUser *auth.User, auth
 
 	// if new user they will need a unique Nickname
 	if newUser {
+		u.MarketingOptIn = true
 		u.Nickname = authUser.Nickname
 		if err := u.uniquifyNickname(tx, getShuffledPrefixes()); err != nil {
 			return err
@@ -190,9 +254,11 @@ func (u *User) hydrateFromAuthUser(tx *pop.Connection, authUser *auth.User, auth
 		e := events.Event{
 			Kind:    domain.EventApiUserCreated,
 			Message: "Nickname: " + u.Nickname + "  UUID: " + u.UUID.String(),
-			Payload: events.Payload{"user": u},
+			Payload: events.Payload{domain.ArgId: u.ID},
+		}
+		if err := emitEvent(tx, strconv.Itoa(u.ID), e); err != nil {
+			return err
 		}
-		emitEvent(e)
 	}
 	return nil
 }
@@ -344,6 +410,14 @@ func (u *User) CanViewRequest(tx *pop.Connection, request Request) bool {
 		return true
 	}
 
+	// users blocked by the request creator cannot view it
+	if blocked, err := isBlockedByAny(tx, u.ID, []int{request.CreatedByID}); err != nil || blocked {
+		if err != nil {
+			log.Errorf("error checking whether user %s can view request %s, %s", u.UUID, request.UUID, err)
+		}
+		return false
+	}
+
 	if request.Visibility == RequestVisibilityAll {
 		return true
 	}
@@ -378,6 +452,39 @@ func (u *User) CanViewRequest(tx *pop.Connection, request Request) bool {
 	return false
 }
 
+// CanViewProfile returns true if the user is allowed to view the public profile of the given user: a member of the
+// same organization or of a trusted organization, who is not blocked by the given user
+func (u *User) CanViewProfile(tx *pop.Connection, profile User) bool {
+	if u.ID == profile.ID || u.AdminRole == UserAdminRoleSuperAdmin {
+		return true
+	}
+
+	if profile.IsDeleted() {
+		return false
+	}
+
+	if blocked, err := isBlockedByAny(tx, u.ID, []int{profile.ID}); err != nil || blocked {
+		if err != nil {
+			log.Errorf("error checking whether user %s can view profile of %s, %s", u.UUID, profile.UUID, err)
+		}
+		return false
+	}
+
+	var c Count
+	err := tx.RawQuery(`
+		SELECT COUNT(*) FROM user_organizations viewer
+			JOIN user_organizations member ON member.user_id = ?
+			WHERE viewer.user_id = ? AND (viewer.organization_id = member.organization_id OR EXISTS (
+				SELECT 1 FROM organization_trusts ot
+					WHERE ot.primary_id = member.organization_id AND ot.secondary_id = viewer.organization_id
+			))`, profile.ID, u.ID).First(&c)
+	if err != nil {
+		log.Errorf("error checking whether user %s can view profile of %s, %s", u.UUID, profile.UUID, err)
+		return false
+	}
+	return c.N > 0
+}
+
 // FindByUUID find a User with the given UUID and loads it from the database.
 func (u *User) FindByUUID(tx *pop.Connection, uuid string) error {
 	if uuid == "" {
@@ -597,25 +704,15 @@ type UnreadThread struct {
 // UnreadMessageCount returns an entry for each thread that has other users' messages
 // that have not yet been read by this this user.
 func (u *User) UnreadMessageCount(tx *pop.Connection) ([]UnreadThread, error) {
-	emptyUnreads := []UnreadThread{}
-
-	threadPs := ThreadParticipants{}
-	if err := tx.Eager("Thread").Where("user_id = ?", u.ID).All(&threadPs); err != nil {
-		return emptyUnreads, err
+	statuses, err := getThreadReadStatuses(tx, u.ID)
+	if err != nil {
+		return []UnreadThread{}, err
 	}
 
 	unreads := []UnreadThread{}
-
-	for _, tp := range threadPs {
-		msgCount, err := tp.Thread.GetUnreadMessageCount(tx, u.ID, tp.LastViewedAt)
-		if err != nil {
-			log.Errorf("error getting count of unread messages for thread %s ... %v",
-				tp.Thread.UUID, err)
-			continue
-		}
-
-		if msgCount > 0 {
-			unreads = append(unreads, UnreadThread{ThreadUUID: tp.Thread.UUID, Count: msgCount})
+	for _, s := range statuses {
+		if s.UnreadCount > 0 {
+			unreads = append(unreads, UnreadThread{ThreadUUID: s.ThreadUUID, Count: s.UnreadCount})
 		}
 	}
 
@@ -636,7 +733,8 @@ func (u *User) GetThreads(tx *pop.Connection) (Threads, error) {
 	return t, nil
 }
 
-// GetThreadsForConversations finds all threads that the user is participating in.
+// GetThreadsForConversations finds all threads that the user is participating in, each with its latest message
+// and unread message count.
 func (u *User) GetThreadsForConversations(tx *pop.Connection) (Threads, error) {
 	t := Threads{}
 	query := tx.Q().
@@ -647,11 +745,8 @@ func (u *User) GetThreadsForConversations(tx *pop.Connection) (Threads, error) {
 		return nil, err
 	}
 
-	for i := range t {
-		err := t[i].LoadForAPI(tx, *u)
-		if err != nil {
-			return nil, err
-		}
+	if err := t.loadForAPIList(tx, *u); err != nil {
+		return nil, err
 	}
 
 	return t, nil
@@ -659,51 +754,16 @@ func (u *User) GetThreadsForConversations(tx *pop.Connection) (Threads, error) {
 
 // WantsRequestNotification answers the question "Does the user want notifications for this request?"
 func (u *User) WantsRequestNotification(tx *pop.Connection, request Request) bool {
-	if request.CreatedByID == u.ID {
-		return false
-	}
-
-	if u.isNearRequest(tx, request) {
-		return true
-	}
-
-	return u.hasMatchingWatch(tx, request)
-}
-
-func (u *User) isNearRequest(tx *pop.Connection, request Request) bool {
-	if err := tx.Load(u, "Location"); err != nil {
-		log.Errorf("load of user location failed, %s", err)
-		return false
-	}
-
-	requestOrigin, err := request.GetOrigin(tx)
+	var c Count
+	now := time.Now()
+	err := tx.RawQuery("SELECT COUNT(*) FROM ("+requestNotificationAudienceSQL+" AND u.id = ?) AS n",
+		request.ID, now, now, u.ID).First(&c)
 	if err != nil {
-		log.Errorf("failed to get request origin, %s", err)
-		return false
-	}
-	if requestOrigin == nil {
-		return false
-	}
-
-	if u.Location.IsNear(*requestOrigin) {
-		return true
-	}
-	return false
-}
-
-func (u *User) hasMatchingWatch(tx *pop.Connection, request Request) bool {
-	watches := Watches{}
-	if err := watches.FindByUser(tx, *u); err != nil {
-		log.Errorf("failed to get watch list, %s", err)
+		log.Errorf("failed to check for request notification, %s", err)
 		return false
 	}
-	for _, watch := range watches {
-		if watch.matchesRequest(tx, request) {
-			return true
-		}
-	}
 
-	return false
+	return c.N > 0
 }
 
 // GetPreferences returns a StandardPreferences struct
@@ -831,6 +891,435 @@ func (u *User) RemovePreferences(tx *pop.Connection) error {
 	return p.removeAll(tx, u.ID)
 }
 
+// Block blocks the given user, who will no longer be able to see this user's requests, offer on them, or send
+// messages to this user. Blocking a user who is already blocked is not an error.
+func (u *User) Block(tx *pop.Connection, blocked User) error {
+	if blocked.ID == u.ID {
+		return api.NewAppError(errors.New("users cannot block themselves"), api.ErrorUserBlockSelf, api.CategoryUser)
+	}
+
+	var block UserBlock
+	err := block.FindByUserIDs(tx, u.ID, blocked.ID)
+	if err == nil {
+		return nil
+	}
+	if domain.IsOtherThanNoRows(err) {
+		return api.NewAppError(err, api.ErrorUserBlockFailure, api.CategoryInternal)
+	}
+
+	block = UserBlock{BlockerID: u.ID, BlockedID: blocked.ID}
+	if err := block.Create(tx); err != nil {
+		return api.NewAppError(err, api.ErrorUserBlockFailure, api.CategoryInternal)
+	}
+	return nil
+}
+
+// Unblock removes the block of the given user, if there is one
+func (u *User) Unblock(tx *pop.Connection, blocked User) error {
+	err := tx.RawQuery("DELETE FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?", u.ID, blocked.ID).Exec()
+	if err != nil {
+		return api.NewAppError(err, api.ErrorUserBlockFailure, api.CategoryInternal)
+	}
+	return nil
+}
+
+// GetBlockedUsers returns the users blocked by this user, most recently blocked first
+func (u *User) GetBlockedUsers(tx *pop.Connection) (Users, error) {
+	var blocks UserBlocks
+	if err := blocks.FindByBlocker(tx, u.ID); err != nil {
+		return nil, err
+	}
+
+	users := make(Users, len(blocks))
+	for i := range blocks {
+		users[i] = blocks[i].Blocked
+	}
+	return users, nil
+}
+
+// GetBlockerUUIDs returns the UUIDs of the users who have blocked this user
+func (u *User) GetBlockerUUIDs(tx *pop.Connection) ([]uuid.UUID, error) {
+	var blockers Users
+	if err := tx.Select("uuid").Where("id IN ("+blockedBySQL+")", u.ID).All(&blockers); err != nil {
+		return nil, fmt.Errorf("error finding the users who blocked user %s, %w", u.UUID, err)
+	}
+
+	ids := make([]uuid.UUID, len(blockers))
+	for i := range blockers {
+		ids[i] = blockers[i].UUID
+	}
+	return ids, nil
+}
+
+// IsSuspended returns true if the user was suspended by a moderator
+func (u *User) IsSuspended() bool {
+	return u.SuspendedAt.Valid
+}
+
+// Suspend prevents the user from using the API, ends all of the user's sessions and revokes the user's personal
+// access tokens
+func (u *User) Suspend(tx *pop.Connection) error {
+	if u.IsSuspended() {
+		return nil
+	}
+
+	u.SuspendedAt = nulls.NewTime(time.Now())
+	if err := tx.UpdateColumns(u, "suspended_at", "updated_at"); err != nil {
+		return fmt.Errorf("error suspending user %s, %w", u.UUID, err)
+	}
+
+	var tokens UserAccessTokens
+	if err := tokens.DeleteByUser(tx, *u, 0); err != nil {
+		return fmt.Errorf("error ending sessions of suspended user %s, %w", u.UUID, err)
+	}
+
+	var pats PersonalAccessTokens
+	if err := pats.DeleteByUser(tx, *u); err != nil {
+		return fmt.Errorf("error revoking personal access tokens of suspended user %s, %w", u.UUID, err)
+	}
+	return nil
+}
+
+// getAdminOrgIDs returns the IDs of the organizations of which the user is an admin
+func (u *User) getAdminOrgIDs(tx *pop.Connection) ([]int, error) {
+	var userOrgs UserOrganizations
+	if err := tx.Where("user_id = ? AND role = ?", u.ID, UserOrganizationRoleAdmin).All(&userOrgs); err != nil {
+		return nil, fmt.Errorf("error finding admin organizations of user %s, %w", u.UUID, err)
+	}
+
+	ids := make([]int, len(userOrgs))
+	for i := range userOrgs {
+		ids[i] = userOrgs[i].OrganizationID
+	}
+	return ids, nil
+}
+
+// IsModerator returns true if the user is allowed to review reports: super admins and organization admins
+func (u *User) IsModerator(tx *pop.Connection) bool {
+	if u.isSuperAdmin() {
+		return true
+	}
+
+	ids, err := u.getAdminOrgIDs(tx)
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return len(ids) > 0
+}
+
+// SetMarketingSynced records that the user's list membership was synced. The email is the address under which the
+// user is subscribed, or null if the user is not subscribed.
+func (u *User) SetMarketingSynced(tx *pop.Connection, email nulls.String) error {
+	u.MarketingEmail = email
+	u.MarketingSyncedAt = nulls.NewTime(time.Now())
+	if err := tx.UpdateColumns(u, "marketing_email", "marketing_synced_at"); err != nil {
+		return fmt.Errorf("error recording marketing sync of user %s, %w", u.UUID, err)
+	}
+	return nil
+}
+
+// SetMarketingOptOut records that the user unsubscribed through the marketing list itself. Unlike a change of the
+// opt-in on the profile, this does not trigger a sync, since the list is already up to date.
+func (u *User) SetMarketingOptOut(tx *pop.Connection) error {
+	u.MarketingOptIn = false
+	u.MarketingEmail = nulls.String{}
+	u.MarketingSyncedAt = nulls.NewTime(time.Now())
+	u.OptInChangedAt = u.MarketingSyncedAt
+	err := tx.RawQuery("UPDATE users SET marketing_opt_in = FALSE, marketing_email = NULL, marketing_synced_at = ?, "+
+		"marketing_opt_in_changed_at = ? WHERE id = ?", u.MarketingSyncedAt, u.OptInChangedAt, u.ID).Exec()
+	if err != nil {
+		return fmt.Errorf("error recording marketing opt-out of user %s, %w", u.UUID, err)
+	}
+	return nil
+}
+
+// IsDeleted returns true if the user's account was deleted
+func (u *User) IsDeleted() bool {
+	return strings.HasSuffix(u.Email, "@"+deletedUserEmailDomain)
+}
+
+// requestMarketingSync emits an event to sync the user's marketing list membership
+func (u *User) requestMarketingSync(tx *pop.Connection) error {
+	e := events.Event{
+		Kind:    domain.EventApiUserMarketingUpdated,
+		Message: "ID: " + strconv.Itoa(u.ID),
+		Payload: events.Payload{domain.ArgId: u.ID},
+	}
+	return emitEvent(tx, "", e)
+}
+
+// CompletedRequestCounts returns the number of requests completed with the user as the provider, and the number of
+// the user's own requests that were completed
+func (u *User) CompletedRequestCounts(tx *pop.Connection) (deliveries, requests int, err error) {
+	deliveries, err = tx.Where("provider_id = ?", u.ID).Where("status = ?", RequestStatusCompleted).
+		Count(&Request{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("error counting deliveries of user %s, %w", u.UUID, err)
+	}
+
+	requests, err = tx.Where("created_by_id = ?", u.ID).Where("status = ?", RequestStatusCompleted).
+		Count(&Request{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("error counting completed requests of user %s, %w", u.UUID, err)
+	}
+	return deliveries, requests, nil
+}
+
+// FindForMarketing finds up to `limit` users with an ID greater than `afterID`, in order of ID, excluding deleted
+// users
+func (u *Users) FindForMarketing(tx *pop.Connection, afterID, limit int) error {
+	return tx.Where("id > ?", afterID).
+		Where("email NOT LIKE ?", "%@"+deletedUserEmailDomain).
+		Order("id asc").Limit(limit).All(u)
+}
+
+// CountActiveUsers counts the users, excluding deleted users, that have used the API since the given time
+func CountActiveUsers(tx *pop.Connection, since time.Time) (int, error) {
+	n, err := tx.Where("email NOT LIKE ?", "%@"+deletedUserEmailDomain).
+		Where("id IN (SELECT user_id FROM user_access_tokens WHERE COALESCE(last_used_at, created_at) >= ?)", since).
+		Count(&Users{})
+	if err != nil {
+		return 0, fmt.Errorf("error counting active users, %w", err)
+	}
+	return n, nil
+}
+
+// deletedUserEmailDomain is the domain of the placeholder email address of a deleted user
+const deletedUserEmailDomain = "deleted.invalid"
+
+// UserData holds the records of a User's data, as provided in a data export
+type UserData struct {
+	Requests  Requests
+	Offers    Requests
+	Threads   Threads
+	Watches   Watches
+	Templates RequestTemplates
+	Meetings  Meetings
+	Files     Files
+}
+
+// userFilesSQL selects the IDs of the files attached to a user's profile, requests and messages. The user ID is
+// needed for each of the four placeholders.
+const userFilesSQL = `SELECT file_id FROM users WHERE id = ? AND file_id IS NOT NULL
+	UNION SELECT file_id FROM requests WHERE created_by_id = ? AND file_id IS NOT NULL
+	UNION SELECT rf.file_id FROM request_files rf JOIN requests r ON r.id = rf.request_id WHERE r.created_by_id = ?
+	UNION SELECT mf.file_id FROM message_files mf JOIN messages m ON m.id = mf.message_id WHERE m.sent_by_id = ?`
+
+// GetData finds all of the records of the user's data: requests, offers, threads with all of their messages,
+// watches, request templates, events (meetings), and files
+func (u *User) GetData(tx *pop.Connection) (UserData, error) {
+	var data UserData
+
+	if err := tx.Where("created_by_id = ?", u.ID).Order("created_at desc").All(&data.Requests); err != nil {
+		return data, fmt.Errorf("error finding requests of user %s, %w", u.UUID, err)
+	}
+
+	if err := tx.Where("id IN (SELECT request_id FROM potential_providers WHERE user_id = ?)", u.ID).
+		Order("created_at desc").All(&data.Offers); err != nil {
+		return data, fmt.Errorf("error finding offers of user %s, %w", u.UUID, err)
+	}
+
+	threads, err := u.GetThreads(tx)
+	if err != nil {
+		return data, fmt.Errorf("error finding threads of user %s, %w", u.UUID, err)
+	}
+	for i := range threads {
+		if err := threads[i].LoadMessages(tx, "SentBy"); err != nil {
+			return data, err
+		}
+		if err := threads[i].LoadParticipants(tx); err != nil {
+			return data, err
+		}
+		if err := threads[i].LoadRequest(tx); err != nil {
+			return data, err
+		}
+	}
+	data.Threads = threads
+
+	if err := data.Watches.FindByUser(tx, *u); err != nil {
+		return data, fmt.Errorf("error finding watches of user %s, %w", u.UUID, err)
+	}
+
+	if err := data.Templates.FindByOwner(tx, *u); err != nil {
+		return data, fmt.Errorf("error finding request templates of user %s, %w", u.UUID, err)
+	}
+
+	if err := tx.Where("created_by_id = ? OR id IN (SELECT meeting_id FROM meeting_participants WHERE user_id = ?)",
+		u.ID, u.ID).Order("start_date desc").All(&data.Meetings); err != nil {
+		return data, fmt.Errorf("error finding events of user %s, %w", u.UUID, err)
+	}
+
+	if err := tx.Where("id IN ("+userFilesSQL+")", u.ID, u.ID, u.ID, u.ID).All(&data.Files); err != nil {
+		return data, fmt.Errorf("error finding files of user %s, %w", u.UUID, err)
+	}
+	for i := range data.Files {
+		if err := data.Files[i].RefreshURL(tx); err != nil {
+			return data, err
+		}
+	}
+
+	return data, nil
+}
+
+// releaseRequests removes the user's open, accepted and expired requests, and reopens the accepted requests the user
+// was to provide. Each request is updated as a model, so that its history is kept and the other side is notified.
+func (u *User) releaseRequests(tx *pop.Connection) error {
+	var requests Requests
+	err := tx.Where("(created_by_id = ? AND status IN (?, ?, ?)) OR (provider_id = ? AND status = ?)",
+		u.ID, RequestStatusOpen, RequestStatusAccepted, RequestStatusExpired, u.ID, RequestStatusAccepted).
+		All(&requests)
+	if err != nil {
+		return fmt.Errorf("error finding requests of user %s, %w", u.UUID, err)
+	}
+
+	for i := range requests {
+		if requests[i].CreatedByID == u.ID {
+			requests[i].Status = RequestStatusRemoved
+		} else {
+			requests[i].Status = RequestStatusOpen
+		}
+		if err := requests[i].Update(tx); err != nil {
+			return fmt.Errorf("error releasing request %s of user %s, %w", requests[i].UUID, u.UUID, err)
+		}
+	}
+	return nil
+}
+
+// UserDeletedEventData holds data needed by the Deleted User event listeners
+type UserDeletedEventData struct {
+	// FileUUIDs are the files of the user, which are removed from storage once the deletion is committed
+	FileUUIDs []string
+}
+
+// Anonymize deletes the user's account. The user record is kept so that the requests, messages and events of other
+// users remain intact, but all personal data is removed from it. The user's open, accepted and expired requests are
+// removed, accepted requests the user was to provide are reopened, the user's messages are deleted, the user is
+// removed from events and offers, and all of the user's files are removed. The files are removed from storage by a
+// listener of the Deleted User event, so they are kept if the transaction is rolled back. The user's sessions, tokens,
+// watches, request templates, preferences, blocks, organization memberships and SCIM identities are deleted, so the
+// account can no longer be used.
+func (u *User) Anonymize(tx *pop.Connection) error {
+	email := u.Email
+	now := time.Now()
+
+	var files Files
+	if err := tx.Where("id IN ("+userFilesSQL+")", u.ID, u.ID, u.ID, u.ID).All(&files); err != nil {
+		return fmt.Errorf("error finding files of user %s, %w", u.UUID, err)
+	}
+
+	if err := u.releaseRequests(tx); err != nil {
+		return err
+	}
+
+	statements := []struct {
+		sql  string
+		args []interface{}
+	}{
+		{
+			sql:  "UPDATE requests SET file_id = NULL, updated_at = ? WHERE created_by_id = ? AND file_id IS NOT NULL",
+			args: []interface{}{now, u.ID},
+		},
+		{
+			sql:  "DELETE FROM request_files WHERE request_id IN (SELECT id FROM requests WHERE created_by_id = ?)",
+			args: []interface{}{u.ID},
+		},
+		{
+			sql:  "DELETE FROM message_files WHERE message_id IN (SELECT id FROM messages WHERE sent_by_id = ?)",
+			args: []interface{}{u.ID},
+		},
+		{
+			sql:  "UPDATE messages SET content = '', deleted_at = COALESCE(deleted_at, ?), updated_at = ? WHERE sent_by_id = ?",
+			args: []interface{}{now, now, u.ID},
+		},
+		{
+			sql:  "DELETE FROM meeting_participants WHERE user_id = ?",
+			args: []interface{}{u.ID},
+		},
+		{
+			sql:  "DELETE FROM meeting_invites WHERE email = ? OR user_id = ?",
+			args: []interface{}{email, u.UUID},
+		},
+		{
+			sql:  "DELETE FROM potential_providers WHERE user_id = ?",
+			args: []interface{}{u.ID},
+		},
+		{
+			sql:  "DELETE FROM watches WHERE owner_id = ?",
+			args: []interface{}{u.ID},
+		},
+		{
+			sql:  "DELETE FROM request_templates WHERE owner_id = ?",
+			args: []interface{}{u.ID},
+		},
+		{
+			sql:  "DELETE FROM user_blocks WHERE blocker_id = ? OR blocked_id = ?",
+			args: []interface{}{u.ID, u.ID},
+		},
+		{
+			sql:  "DELETE FROM user_access_tokens WHERE user_id = ?",
+			args: []interface{}{u.ID},
+		},
+		{
+			sql:  "DELETE FROM personal_access_tokens WHERE user_id = ?",
+			args: []interface{}{u.ID},
+		},
+		{
+			sql:  "DELETE FROM user_organizations WHERE user_id = ?",
+			args: []interface{}{u.ID},
+		},
+		{
+			sql:  "DELETE FROM scim_users WHERE user_id = ?",
+			args: []interface{}{u.ID},
+		},
+	}
+	for _, s := range statements {
+		if err := tx.RawQuery(s.sql, s.args...).Exec(); err != nil {
+			return fmt.Errorf("error anonymizing user %s, %w", u.UUID, err)
+		}
+	}
+
+	if err := u.RemovePreferences(tx); err != nil {
+		return fmt.Errorf("error removing preferences of user %s, %w", u.UUID, err)
+	}
+	if err := u.RemoveLocation(tx); err != nil {
+		return fmt.Errorf("error removing location of user %s, %w", u.UUID, err)
+	}
+
+	u.Email = u.UUID.String() + "@" + deletedUserEmailDomain
+	u.FirstName = "Deleted"
+	u.LastName = "User"
+	u.Nickname = "Deleted User " + u.UUID.String()[:8]
+	u.AdminRole = UserAdminRoleUser
+	u.SocialAuthProvider = nulls.String{}
+	u.AuthPhotoURL = nulls.String{}
+	u.FileID = nulls.Int{}
+	u.LocationID = nulls.Int{}
+	u.MarketingOptIn = false
+	u.MarketingEmail = nulls.String{}
+	u.Bio = nulls.String{}
+	if err := tx.UpdateColumns(u, "email", "first_name", "last_name", "nickname", "admin_role",
+		"social_auth_provider", "auth_photo_url", "file_id", "location_id", "marketing_opt_in", "marketing_email",
+		"bio", "updated_at"); err != nil {
+		return fmt.Errorf("error anonymizing user %s, %w", u.UUID, err)
+	}
+
+	eventData := UserDeletedEventData{FileUUIDs: make([]string, len(files))}
+	for i := range files {
+		if err := tx.Destroy(&files[i]); err != nil {
+			return fmt.Errorf("error removing file %s of user %s, %w", files[i].UUID, u.UUID, err)
+		}
+		eventData.FileUUIDs[i] = files[i].UUID.String()
+	}
+
+	e := events.Event{
+		Kind:    domain.EventApiUserDeleted,
+		Message: "UUID: " + u.UUID.String(),
+		Payload: events.Payload{domain.ArgId: u.ID, domain.ArgEmail: email, domain.ArgEventData: eventData},
+	}
+	return emitEvent(tx, u.UUID.String(), e)
+}
+
 func ConvertUserPrivate(ctx context.Context, user User) (api.UserPrivate, error) {
 	tx := Tx(ctx)
 
@@ -859,6 +1348,72 @@ func ConvertUserPrivate(ctx context.Context, user User) (api.UserPrivate, error)
 		return api.UserPrivate{}, err
 	}
 	output.Organizations = ConvertOrganizations(organizations)
+
+	output.Location = nil
+	location, err := user.GetLocation(tx)
+	if err != nil {
+		return api.UserPrivate{}, err
+	}
+	if location != nil {
+		l := ConvertLocation(*location)
+		output.Location = &l
+	}
+	return output, nil
+}
+
+// ConvertUserProfile converts a User to the public profile shown to other users. The bio and home location are
+// included only if allowed by the user's preferences.
+func ConvertUserProfile(ctx context.Context, user User) (api.UserProfile, error) {
+	tx := Tx(ctx)
+
+	output := api.UserProfile{
+		ID:          user.UUID,
+		Nickname:    user.Nickname,
+		MemberSince: user.CreatedAt,
+	}
+
+	photoURL, err := user.GetPhotoURL(tx)
+	if err != nil {
+		return api.UserProfile{}, err
+	}
+	if photoURL != nil {
+		output.AvatarURL = nulls.NewString(*photoURL)
+	}
+
+	organizations, err := user.GetOrganizations(tx)
+	if err != nil {
+		return api.UserProfile{}, err
+	}
+	output.Organizations = ConvertOrganizations(organizations)
+
+	output.CompletedDeliveries, output.CompletedRequests, err = user.CompletedRequestCounts(tx)
+	if err != nil {
+		return api.UserProfile{}, err
+	}
+
+	prefs, err := user.GetPreferences(tx)
+	if err != nil {
+		return api.UserProfile{}, err
+	}
+
+	if prefs.isBioVisible() {
+		output.Bio = user.Bio
+	}
+
+	if prefs.isLocationVisible() {
+		location, err := user.GetLocation(tx)
+		if err != nil {
+			return api.UserProfile{}, err
+		}
+		if location != nil {
+			output.Location = &api.ProfileLocation{
+				City:    location.City,
+				State:   location.State,
+				Country: location.Country,
+			}
+		}
+	}
+
 	return output, nil
 }
 
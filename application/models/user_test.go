@@ -7,11 +7,13 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
 
+	"github.com/silinternational/wecarry-api/api"
 	"github.com/silinternational/wecarry-api/auth"
 	"github.com/silinternational/wecarry-api/domain"
 )
@@ -322,7 +324,7 @@ func (ms *ModelSuite) TestUser_CreateAccessToken() {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			expectedExpiry := createAccessTokenExpiry().Unix()
+			expectedExpiry := createAccessTokenExpiry(defaultSessionPolicy(), time.Now()).Unix()
 			token, expiry, err := test.args.user.CreateAccessToken(ms.DB, uf.Organization, test.args.clientID)
 			if test.wantErr {
 				if err == nil {
@@ -388,7 +390,7 @@ func (ms *ModelSuite) TestUser_CreateOrglessAccessToken() {
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			expectedExpiry := createAccessTokenExpiry().Unix()
+			expectedExpiry := createAccessTokenExpiry(defaultSessionPolicy(), time.Now()).Unix()
 			token, expiry, err := tc.user.CreateOrglessAccessToken(ms.DB, tc.clientID)
 			if tc.wantErr {
 				if err == nil {
@@ -1327,6 +1329,37 @@ func (ms *ModelSuite) TestUser_GetThreads() {
 	}
 }
 
+func (ms *ModelSuite) TestUser_GetThreadsForConversations() {
+	users := createUserFixtures(ms.DB, 1).Users
+	requests := createRequestFixtures(ms.DB, 1, false, users[0].ID)
+	f := CreateThreadFixtures(ms, requests[0])
+
+	got, err := users[0].GetThreadsForConversations(ms.DB)
+	ms.NoError(err)
+	ms.Equal(2, len(got), "incorrect number of threads")
+
+	want := map[uuid.UUID]struct {
+		latest       uuid.UUID
+		unread       int
+		participants int
+	}{
+		f.Threads[0].UUID: {latest: f.Messages[0].UUID, unread: 0, participants: 1},
+		f.Threads[1].UUID: {latest: f.Messages[2].UUID, unread: 1, participants: 2},
+	}
+	for _, thread := range got {
+		w, ok := want[thread.UUID]
+		ms.True(ok, "unexpected thread %s", thread.UUID)
+		ms.Nil(thread.Messages, "thread messages should not be loaded")
+		ms.NotNil(thread.LatestMessage, "latest message was not loaded")
+		ms.Equal(w.latest, thread.LatestMessage.UUID, "incorrect latest message")
+		ms.Equal(thread.LatestMessage.SentByID, thread.LatestMessage.SentBy.ID, "sender was not loaded")
+		ms.Equal(w.unread, thread.UnreadMessageCount, "incorrect unread message count")
+		ms.NotNil(thread.LastViewedAt, "last viewed time was not loaded")
+		ms.Equal(requests[0].ID, thread.Request.ID, "request was not loaded")
+		ms.Equal(w.participants, len(thread.Participants), "participants were not loaded")
+	}
+}
+
 func (ms *ModelSuite) TestUser_WantsRequestNotification() {
 	t := ms.T()
 	f := CreateFixturesForUserWantsRequestNotification(ms)
@@ -1708,3 +1741,287 @@ func (ms *ModelSuite) TestUsers_FindByIDs() {
 		})
 	}
 }
+
+type userDataFixtures struct {
+	Users
+	Requests
+	Messages
+}
+
+// createFixturesForUserData creates 2 users. users[0] has 2 requests, a watch, and an offer and a message on the
+// request of users[1], who has blocked users[0].
+func createFixturesForUserData(ms *ModelSuite) userDataFixtures {
+	users := createUserFixtures(ms.DB, 2).Users
+
+	requests := createRequestFixtures(ms.DB, 2, false, users[0].ID)
+	requests = append(requests, createRequestFixtures(ms.DB, 1, false, users[1].ID)...)
+
+	var request Request
+	ms.NoError(request.AddUserAsPotentialProvider(ms.DB, requests[2].UUID.String(), users[0]))
+
+	var message Message
+	ms.NoError(message.CreateFromInput(ms.DB, users[0], api.MessageInput{
+		Content:   "I can bring it",
+		RequestID: requests[2].UUID.String(),
+	}))
+
+	watch := Watch{OwnerID: users[0].ID, Name: "everything", UUID: domain.GetUUID()}
+	createFixture(ms, &watch)
+
+	ms.NoError(users[1].Block(ms.DB, users[0]))
+
+	return userDataFixtures{Users: users, Requests: requests, Messages: Messages{message}}
+}
+
+func (ms *ModelSuite) TestUser_GetData() {
+	f := createFixturesForUserData(ms)
+
+	data, err := f.Users[0].GetData(ms.DB)
+	ms.NoError(err)
+
+	ms.Equal(2, len(data.Requests), "incorrect number of requests")
+	ms.Equal(1, len(data.Offers), "incorrect number of offers")
+	ms.Equal(f.Requests[2].ID, data.Offers[0].ID, "incorrect offer")
+	ms.Equal(1, len(data.Threads), "incorrect number of threads")
+	ms.Equal(1, len(data.Threads[0].Messages), "thread messages were not loaded")
+	ms.Equal(f.Requests[2].ID, data.Threads[0].Request.ID, "thread request was not loaded")
+	ms.Equal(1, len(data.Watches), "incorrect number of watches")
+	ms.Equal(0, len(data.Meetings), "incorrect number of events")
+	ms.Equal(0, len(data.Files), "incorrect number of files")
+
+	data, err = f.Users[1].GetData(ms.DB)
+	ms.NoError(err)
+	ms.Equal(1, len(data.Requests), "other user's data should not be included")
+	ms.Equal(0, len(data.Watches), "other user's data should not be included")
+}
+
+func (ms *ModelSuite) TestUser_Anonymize() {
+	f := createFixturesForUserData(ms)
+	user := f.Users[0]
+	email := user.Email
+
+	scimUser := SCIMUser{
+		OrganizationID: user.Organizations[0].ID,
+		UserID:         user.ID,
+		UserName:       email,
+		Email:          email,
+		Active:         true,
+	}
+	mustCreate(ms.DB, &scimUser)
+
+	photo := createFileFixture(ms.DB)
+	_, err := user.AttachPhoto(ms.DB, photo.UUID.String())
+	ms.NoError(err)
+
+	ms.NoError(user.Anonymize(ms.DB))
+
+	var stored User
+	ms.NoError(stored.FindByID(ms.DB, user.ID))
+	ms.NotEqual(email, stored.Email, "email was not removed")
+	ms.Equal("Deleted", stored.FirstName)
+	ms.Equal("User", stored.LastName)
+	ms.Contains(stored.Nickname, "Deleted User")
+	ms.False(stored.AuthPhotoURL.Valid, "photo URL was not removed")
+
+	for _, r := range f.Requests[:2] {
+		var request Request
+		ms.NoError(request.FindByID(ms.DB, r.ID))
+		ms.Equal(RequestStatusRemoved, request.Status, "open request was not removed")
+	}
+	var othersRequest Request
+	ms.NoError(othersRequest.FindByID(ms.DB, f.Requests[2].ID))
+	ms.Equal(RequestStatusOpen, othersRequest.Status, "other user's request should not change")
+
+	var message Message
+	ms.NoError(ms.DB.Find(&message, f.Messages[0].ID))
+	ms.True(message.IsDeleted(), "message was not deleted")
+	ms.Equal("", message.Content, "message content was not removed")
+
+	for _, r := range []struct {
+		where string
+		model interface{}
+	}{
+		{where: "user_id = ?", model: &PotentialProvider{}},
+		{where: "owner_id = ?", model: &Watch{}},
+		{where: "user_id = ?", model: &UserAccessToken{}},
+		{where: "user_id = ?", model: &UserOrganization{}},
+		{where: "user_id = ?", model: &SCIMUser{}},
+	} {
+		n, err := ms.DB.Where(r.where, user.ID).Count(r.model)
+		ms.NoError(err)
+		ms.Equal(0, n, "records of %T were not deleted", r.model)
+	}
+
+	n, err := ms.DB.Where("blocked_id = ?", user.ID).Count(&UserBlock{})
+	ms.NoError(err)
+	ms.Equal(0, n, "blocks were not deleted")
+
+	var event OutboxEvent
+	ms.NoError(ms.DB.Where("kind = ?", domain.EventApiUserDeleted).First(&event))
+	ms.Contains(event.Payload, email, "event should have the email address for unsubscribing")
+	ms.Contains(event.Payload, photo.UUID.String(), "event should have the files for removal from storage")
+	ms.Error(ms.DB.Find(&File{}, photo.ID), "file record was not removed")
+}
+
+func (ms *ModelSuite) TestUser_Anonymize_AcceptedRequests() {
+	f := createFixturesForUserData(ms)
+	user := f.Users[0]
+
+	created := f.Requests[0]
+	created.Status = RequestStatusAccepted
+	created.ProviderID = nulls.NewInt(f.Users[1].ID)
+	ms.NoError(created.Update(ms.DB))
+
+	provided := f.Requests[2]
+	provided.Status = RequestStatusAccepted
+	provided.ProviderID = nulls.NewInt(user.ID)
+	ms.NoError(provided.Update(ms.DB))
+
+	ms.NoError(user.Anonymize(ms.DB))
+
+	tests := []struct {
+		name       string
+		request    Request
+		wantStatus RequestStatus
+	}{
+		{name: "created by the user", request: created, wantStatus: RequestStatusRemoved},
+		{name: "provided by the user", request: provided, wantStatus: RequestStatusOpen},
+	}
+	for _, tt := range tests {
+		ms.T().Run(tt.name, func(t *testing.T) {
+			var request Request
+			ms.NoError(request.FindByID(ms.DB, tt.request.ID))
+			ms.Equal(tt.wantStatus, request.Status)
+			ms.False(request.ProviderID.Valid && tt.wantStatus == RequestStatusOpen, "provider was not removed")
+
+			var history RequestHistory
+			ms.NoError(history.getLastForRequest(ms.DB, request))
+			ms.Equal(tt.wantStatus, history.Status, "request history was not updated")
+
+			var outbox OutboxEvents
+			ms.NoError(ms.DB.Where("kind = ?", domain.EventApiRequestStatusUpdated).All(&outbox))
+			found := false
+			for _, o := range outbox {
+				e, err := o.Event()
+				ms.NoError(err)
+				data, ok := e.Payload[domain.ArgEventData].(RequestStatusEventData)
+				if ok && data.RequestID == request.ID && data.OldStatus == RequestStatusAccepted &&
+					data.NewStatus == tt.wantStatus {
+					found = true
+				}
+			}
+			ms.True(found, "no status event was emitted")
+		})
+	}
+}
+
+func (ms *ModelSuite) TestUser_BeforeUpdate() {
+	t := ms.T()
+
+	f := createUserFixtures(ms.DB, 1)
+	user := f.Users[0]
+
+	countSyncs := func() int {
+		n, err := ms.DB.Where("kind = ?", domain.EventApiUserMarketingUpdated).Count(&OutboxEvent{})
+		ms.NoError(err)
+		return n
+	}
+
+	tests := []struct {
+		name     string
+		update   func(u *User)
+		wantSync bool
+	}{
+		{name: "nickname", update: func(u *User) { u.Nickname = "new nickname" }, wantSync: false},
+		{name: "first name", update: func(u *User) { u.FirstName = "New" }, wantSync: true},
+		{name: "email", update: func(u *User) { u.Email = "new_" + u.Email }, wantSync: true},
+		{name: "opt-in", update: func(u *User) { u.MarketingOptIn = !u.MarketingOptIn }, wantSync: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := countSyncs()
+			tt.update(&user)
+			ms.NoError(user.Save(ms.DB))
+
+			want := before
+			if tt.wantSync {
+				want++
+			}
+			ms.Equal(want, countSyncs(), "incorrect number of marketing sync events")
+		})
+	}
+
+	var stored User
+	ms.NoError(stored.FindByID(ms.DB, user.ID))
+	ms.True(stored.OptInChangedAt.Valid, "the time of the opt-in change was not recorded")
+
+	before := countSyncs()
+	ms.NoError(user.SetMarketingSynced(ms.DB, nulls.NewString(user.Email)))
+	ms.Equal(before, countSyncs(), "recording a sync should not trigger another sync")
+
+	ms.NoError(user.SetMarketingOptOut(ms.DB))
+	ms.Equal(before, countSyncs(), "an opt-out through the list should not trigger a sync")
+	ms.NoError(stored.FindByID(ms.DB, user.ID))
+	ms.False(stored.MarketingOptIn, "user was not opted out")
+	ms.False(stored.MarketingEmail.Valid, "marketing email was not cleared")
+}
+
+func (ms *ModelSuite) TestUser_CanViewProfile() {
+	t := ms.T()
+
+	f := createUserFixtures(ms.DB, 5)
+	viewer := f.Users[0]
+
+	org2 := Organization{Name: "org2", AuthConfig: "{}"}
+	createFixture(ms, &org2)
+	org3 := Organization{Name: "org3", AuthConfig: "{}"}
+	createFixture(ms, &org3)
+	trust := OrganizationTrust{PrimaryID: f.Organization.ID, SecondaryID: org2.ID}
+	ms.NoError(trust.CreateSymmetric(ms.DB))
+
+	f.UserOrganizations[2].OrganizationID = org2.ID
+	f.UserOrganizations[3].OrganizationID = org3.ID
+	ms.NoError(ms.DB.Update(&f.UserOrganizations[2]))
+	ms.NoError(ms.DB.Update(&f.UserOrganizations[3]))
+
+	blocker := f.Users[4]
+	ms.NoError(blocker.Block(ms.DB, viewer))
+
+	tests := []struct {
+		name    string
+		profile User
+		want    bool
+	}{
+		{name: "self", profile: viewer, want: true},
+		{name: "same organization", profile: f.Users[1], want: true},
+		{name: "trusted organization", profile: f.Users[2], want: true},
+		{name: "other organization", profile: f.Users[3], want: false},
+		{name: "blocked by the user", profile: blocker, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms.Equal(tt.want, viewer.CanViewProfile(ms.DB, tt.profile))
+		})
+	}
+}
+
+func (ms *ModelSuite) TestUser_CompletedRequestCounts() {
+	f := createUserFixtures(ms.DB, 2)
+	requests := createRequestFixtures(ms.DB, 3, false, f.Users[0].ID)
+
+	for i := range requests[:2] {
+		requests[i].Status = RequestStatusCompleted
+		requests[i].ProviderID = nulls.NewInt(f.Users[1].ID)
+		ms.NoError(ms.DB.Update(&requests[i]))
+	}
+
+	deliveries, completed, err := f.Users[0].CompletedRequestCounts(ms.DB)
+	ms.NoError(err)
+	ms.Equal(0, deliveries, "incorrect deliveries of requester")
+	ms.Equal(2, completed, "incorrect completed requests of requester")
+
+	deliveries, completed, err = f.Users[1].CompletedRequestCounts(ms.DB)
+	ms.NoError(err)
+	ms.Equal(2, deliveries, "incorrect deliveries of provider")
+	ms.Equal(0, completed, "incorrect completed requests of provider")
+}
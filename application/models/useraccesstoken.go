@@ -9,7 +9,10 @@ import (
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gobuffalo/validate/v3"
 	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
+
+	"github.com/silinternational/wecarry-api/api"
 	"github.com/silinternational/wecarry-api/domain"
 )
 
@@ -17,10 +20,14 @@ type UserAccessToken struct {
 	ID                 int              `json:"id" db:"id"`
 	CreatedAt          time.Time        `json:"created_at" db:"created_at"`
 	UpdatedAt          time.Time        `json:"updated_at" db:"updated_at"`
+	UUID               uuid.UUID        `json:"uuid" db:"uuid"`
 	UserID             int              `json:"user_id" db:"user_id"`
 	UserOrganizationID nulls.Int        `json:"user_organization_id" db:"user_organization_id"`
 	AccessToken        string           `json:"access_token" db:"access_token"`
 	ExpiresAt          time.Time        `json:"expires_at" db:"expires_at"`
+	ClientID           string           `json:"client_id" db:"client_id"`
+	LastUsedAt         nulls.Time       `json:"last_used_at" db:"last_used_at"`
+	LastIP             string           `json:"last_ip" db:"last_ip"`
 	User               User             `belongs_to:"users"`
 	UserOrganization   UserOrganization `belongs_to:"user_organizations"`
 }
@@ -43,6 +50,7 @@ func (u UserAccessTokens) String() string {
 // Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
 func (u *UserAccessToken) Validate(tx *pop.Connection) (*validate.Errors, error) {
 	return validate.Validate(
+		&validators.UUIDIsPresent{Field: u.UUID, Name: "UUID"},
 		&validators.IntIsPresent{Field: u.UserID, Name: "UserID"},
 		&validators.StringIsPresent{Field: u.AccessToken, Name: "AccessToken"},
 		&validators.TimeIsPresent{Field: u.ExpiresAt, Name: "ExpiresAt"},
@@ -103,22 +111,61 @@ func (u *UserAccessToken) GetOrganization(tx *pop.Connection) (Organization, err
 	return uOrg.Organization, nil
 }
 
-func createAccessTokenExpiry() time.Time {
-	dtNow := time.Now()
-	futureTime := dtNow.Add(time.Second * time.Duration(domain.Env.AccessTokenLifetimeSeconds))
+// GetSessionPolicy returns the session policy of the token's organization, or the default policy if the token
+// is not associated with an organization
+func (u *UserAccessToken) GetSessionPolicy(tx *pop.Connection) SessionPolicy {
+	if !u.UserOrganizationID.Valid {
+		return defaultSessionPolicy()
+	}
 
-	return futureTime
+	org, err := u.GetOrganization(tx)
+	if err != nil {
+		return defaultSessionPolicy()
+	}
+	return org.SessionPolicy()
 }
 
-// Renew extends the token expiration to the configured token lifetime
+// createAccessTokenExpiry calculates a token expiration time for the given policy, limited by the policy's
+// maximum lifetime as measured from `createdAt`
+func createAccessTokenExpiry(policy SessionPolicy, createdAt time.Time) time.Time {
+	expiresAt := time.Now().Add(policy.IdleTimeout)
+
+	if policy.MaxLifetime > 0 {
+		maxExpiresAt := createdAt.Add(policy.MaxLifetime)
+		if maxExpiresAt.Before(expiresAt) {
+			expiresAt = maxExpiresAt
+		}
+	}
+
+	return expiresAt
+}
+
+// Renew extends the token expiration according to its session policy
 func (u *UserAccessToken) Renew(tx *pop.Connection) error {
-	u.ExpiresAt = createAccessTokenExpiry()
+	createdAt := u.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	u.ExpiresAt = createAccessTokenExpiry(u.GetSessionPolicy(tx), createdAt)
 	if err := u.Update(tx); err != nil {
 		return fmt.Errorf("error renewing access token, %s", err)
 	}
 	return nil
 }
 
+// Touch records the use of the token from the given IP address and renews it. To limit database writes, this
+// only happens if the token has not been used recently or the IP address has changed.
+func (u *UserAccessToken) Touch(tx *pop.Connection, ip string) error {
+	if u.LastUsedAt.Valid && time.Since(u.LastUsedAt.Time) < domain.AccessTokenTouchInterval && u.LastIP == ip {
+		return nil
+	}
+
+	u.LastUsedAt = nulls.NewTime(time.Now())
+	u.LastIP = ip
+	return u.Renew(tx)
+}
+
 // GetUser returns the User associated with this access token
 func (u *UserAccessToken) GetUser(tx *pop.Connection) (User, error) {
 	if err := tx.Load(u, "User"); err != nil {
@@ -133,7 +180,7 @@ func (u *UserAccessToken) GetUser(tx *pop.Connection) (User, error) {
 // DeleteIfExpired checks the token expiration and returns `true` if expired. Also deletes
 // the token from the database if it is expired.
 func (u *UserAccessToken) DeleteIfExpired(tx *pop.Connection) (bool, error) {
-	if u.ExpiresAt.Before(time.Now()) {
+	if u.isExpired(tx) {
 		err := tx.Destroy(u)
 		if err != nil {
 			return true, fmt.Errorf("unable to delete expired userAccessToken, id: %v", u.ID)
@@ -143,6 +190,87 @@ func (u *UserAccessToken) DeleteIfExpired(tx *pop.Connection) (bool, error) {
 	return false, nil
 }
 
+// isExpired returns true if the token is past its expiration time or past the maximum lifetime of its session
+// policy. The latter may happen if the policy was changed after the token was last renewed.
+func (u *UserAccessToken) isExpired(tx *pop.Connection) bool {
+	if u.ExpiresAt.Before(time.Now()) {
+		return true
+	}
+
+	if u.CreatedAt.IsZero() {
+		return false
+	}
+
+	maxLifetime := u.GetSessionPolicy(tx).MaxLifetime
+	return maxLifetime > 0 && u.CreatedAt.Add(maxLifetime).Before(time.Now())
+}
+
+// FindByUUID loads from DB the UserAccessToken record identified by the given UUID
+func (u *UserAccessToken) FindByUUID(tx *pop.Connection, id string) error {
+	return tx.Where("uuid = ?", id).First(u)
+}
+
+// FindByUser returns the unexpired UserAccessToken records of the given User, most recently used first
+func (u *UserAccessTokens) FindByUser(tx *pop.Connection, user User) error {
+	if user.ID <= 0 {
+		return errors.New("invalid user ID in UserAccessTokens.FindByUser")
+	}
+
+	return tx.Where("user_id = ? AND expires_at > ?", user.ID, time.Now()).
+		Order("COALESCE(last_used_at, created_at) DESC").
+		All(u)
+}
+
+// DeleteByUser removes all UserAccessToken records of the given User, except for the token with ID `exceptID`.
+// Use an `exceptID` of 0 to delete all of the User's tokens.
+func (u *UserAccessTokens) DeleteByUser(tx *pop.Connection, user User, exceptID int) error {
+	if user.ID <= 0 {
+		return errors.New("invalid user ID in UserAccessTokens.DeleteByUser")
+	}
+
+	return tx.RawQuery("DELETE FROM user_access_tokens WHERE user_id = ? AND id <> ?", user.ID, exceptID).Exec()
+}
+
+// DeleteByUserOrganization removes all UserAccessToken records created for the given UserOrganization
+func (u *UserAccessTokens) DeleteByUserOrganization(tx *pop.Connection, userOrg UserOrganization) error {
+	if userOrg.ID <= 0 {
+		return errors.New("invalid user organization ID in UserAccessTokens.DeleteByUserOrganization")
+	}
+
+	return tx.RawQuery("DELETE FROM user_access_tokens WHERE user_organization_id = ?", userOrg.ID).Exec()
+}
+
+// deleteExcessSessions removes the least recently used tokens of the token's user and organization, in order to
+// keep the number of tokens within the limit of the session policy
+func (u *UserAccessToken) deleteExcessSessions(tx *pop.Connection, policy SessionPolicy) error {
+	if policy.MaxSessions <= 0 {
+		return nil
+	}
+
+	q := tx.Where("user_id = ?", u.UserID)
+	if u.UserOrganizationID.Valid {
+		q = q.Where("user_organization_id = ?", u.UserOrganizationID.Int)
+	} else {
+		q = q.Where("user_organization_id IS NULL")
+	}
+
+	var tokens UserAccessTokens
+	if err := q.Order("COALESCE(last_used_at, created_at) DESC, id DESC").All(&tokens); err != nil {
+		return fmt.Errorf("error finding sessions for user %d, %w", u.UserID, err)
+	}
+
+	if len(tokens) <= policy.MaxSessions {
+		return nil
+	}
+
+	for i := policy.MaxSessions; i < len(tokens); i++ {
+		if err := tx.Destroy(&tokens[i]); err != nil {
+			return fmt.Errorf("error deleting excess sessions for user %d, %w", u.UserID, err)
+		}
+	}
+	return nil
+}
+
 // DeleteExpired removes all expired UserAccessToken records
 func (u *UserAccessTokens) DeleteExpired(tx *pop.Connection) (int, error) {
 	var c Count
@@ -168,3 +296,45 @@ func (u *UserAccessToken) Create(tx *pop.Connection) error {
 func (u *UserAccessToken) Update(tx *pop.Connection) error {
 	return update(tx, u)
 }
+
+// ConvertSessions converts a list of UserAccessTokens to api.Sessions. `current` is the token used to make the
+// current request.
+func ConvertSessions(tx *pop.Connection, tokens UserAccessTokens, current UserAccessToken) (api.Sessions, error) {
+	output := make(api.Sessions, len(tokens))
+	for i := range tokens {
+		s, err := ConvertSession(tx, tokens[i])
+		if err != nil {
+			return nil, err
+		}
+		s.IsCurrent = tokens[i].ID == current.ID
+		output[i] = s
+	}
+	return output, nil
+}
+
+// ConvertSession converts a UserAccessToken to api.Session
+func ConvertSession(tx *pop.Connection, token UserAccessToken) (api.Session, error) {
+	output := api.Session{
+		ID:        token.UUID,
+		ClientID:  token.ClientID,
+		IPAddress: token.LastIP,
+		CreatedAt: token.CreatedAt,
+		ExpiresAt: token.ExpiresAt,
+	}
+
+	if token.LastUsedAt.Valid {
+		t := token.LastUsedAt.Time
+		output.LastUsedAt = &t
+	}
+
+	if token.UserOrganizationID.Valid {
+		org, err := token.GetOrganization(tx)
+		if err != nil {
+			return api.Session{}, err
+		}
+		o := ConvertOrganization(org)
+		output.Organization = &o
+	}
+
+	return output, nil
+}
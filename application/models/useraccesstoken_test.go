@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"strconv"
 	"testing"
 	"time"
 
@@ -125,12 +126,14 @@ func CreateUserAccessTokenFixtures(ms *ModelSuite) ([]string, UserAccessTokens)
 	// Load access token test fixtures
 	tokens := UserAccessTokens{
 		{
+			UUID:               domain.GetUUID(),
 			UserID:             user.ID,
 			UserOrganizationID: nulls.NewInt(userOrgs[0].ID),
 			AccessToken:        HashClientIdAccessToken(rawTokens[0]),
 			ExpiresAt:          time.Unix(0, 0),
 		},
 		{
+			UUID:               domain.GetUUID(),
 			UserID:             user.ID,
 			UserOrganizationID: nulls.NewInt(userOrgs[0].ID),
 			AccessToken:        HashClientIdAccessToken(rawTokens[1]),
@@ -212,12 +215,14 @@ func CreateUserAccessTokenFixtures_GetOrgs(ms *ModelSuite, users Users, userOrgs
 	// Load access token test fixtures
 	tokens := UserAccessTokens{
 		{
+			UUID:               domain.GetUUID(),
 			UserID:             users[0].ID,
 			UserOrganizationID: nulls.NewInt(userOrgs[0].ID),
 			AccessToken:        HashClientIdAccessToken(rawTokens[0]),
 			ExpiresAt:          time.Unix(0, 0),
 		},
 		{
+			UUID:               domain.GetUUID(),
 			UserID:             users[1].ID,
 			UserOrganizationID: nulls.NewInt(userOrgs[1].ID),
 			AccessToken:        HashClientIdAccessToken(rawTokens[1]),
@@ -315,12 +320,14 @@ func CreateFixtures_DeleteIfExpired(ms *ModelSuite, t *testing.T) AccessTokenFix
 
 	tokens := UserAccessTokens{
 		{
+			UUID:               domain.GetUUID(),
 			UserID:             users[0].ID,
 			UserOrganizationID: nulls.NewInt(userOrgs[0].ID),
 			AccessToken:        HashClientIdAccessToken("abc123"),
 			ExpiresAt:          time.Unix(0, 0),
 		},
 		{
+			UUID:               domain.GetUUID(),
 			UserID:             users[1].ID,
 			UserOrganizationID: nulls.NewInt(userOrgs[1].ID),
 			AccessToken:        HashClientIdAccessToken("xyz789"),
@@ -371,12 +378,14 @@ func CreateFixtures_Renew(ms *ModelSuite, t *testing.T) AccessTokenFixtures {
 
 	tokens := UserAccessTokens{
 		{
+			UUID:               domain.GetUUID(),
 			UserID:             users[0].ID,
 			UserOrganizationID: nulls.NewInt(userOrgs[0].ID),
 			AccessToken:        HashClientIdAccessToken("abc123"),
 			ExpiresAt:          time.Unix(0, 0),
 		},
 		{
+			UUID:               domain.GetUUID(),
 			UserID:             users[1].ID,
 			UserOrganizationID: nulls.NewInt(userOrgs[1].ID),
 			AccessToken:        HashClientIdAccessToken("xyz789"),
@@ -422,12 +431,14 @@ func createFixtures_UserAccessTokensDeleteExpired(ms *ModelSuite, t *testing.T)
 
 	tokens := UserAccessTokens{
 		{
+			UUID:               domain.GetUUID(),
 			UserID:             users[0].ID,
 			UserOrganizationID: nulls.NewInt(userOrgs[0].ID),
 			AccessToken:        HashClientIdAccessToken("abc123"),
 			ExpiresAt:          time.Unix(0, 0),
 		},
 		{
+			UUID:               domain.GetUUID(),
 			UserID:             users[1].ID,
 			UserOrganizationID: nulls.NewInt(userOrgs[1].ID),
 			AccessToken:        HashClientIdAccessToken("xyz789"),
@@ -495,3 +506,127 @@ func (ms *ModelSuite) TestGetRandomToken() {
 
 	ms.Equal(want, got, "Wrong length of access token. Got ... %s", got1)
 }
+
+func (ms *ModelSuite) TestUserAccessToken_Touch() {
+	uf := createUserFixtures(ms.DB, 1)
+	token := uf.UserAccessTokens[0]
+	originalExpiry := token.ExpiresAt
+
+	ms.NoError(token.Touch(ms.DB, "10.0.0.1"))
+	ms.True(token.LastUsedAt.Valid, "LastUsedAt was not set")
+	ms.Equal("10.0.0.1", token.LastIP)
+	ms.True(token.ExpiresAt.After(originalExpiry), "token was not renewed")
+
+	var dbToken UserAccessToken
+	ms.NoError(ms.DB.Find(&dbToken, token.ID))
+	ms.Equal("10.0.0.1", dbToken.LastIP)
+
+	// a second use from the same address soon after should not write to the database
+	lastUsedAt := token.LastUsedAt.Time
+	ms.NoError(token.Touch(ms.DB, "10.0.0.1"))
+	ms.Equal(lastUsedAt, token.LastUsedAt.Time)
+
+	// but a different address should
+	ms.NoError(token.Touch(ms.DB, "10.0.0.2"))
+	ms.NoError(ms.DB.Find(&dbToken, token.ID))
+	ms.Equal("10.0.0.2", dbToken.LastIP)
+}
+
+func (ms *ModelSuite) TestUserAccessToken_RenewWithMaxLifetime() {
+	uf := createUserFixtures(ms.DB, 1)
+	org := uf.Organization
+	org.SessionMaxLifetimeSeconds = nulls.NewInt(3600)
+	ms.NoError(ms.DB.Update(&org))
+
+	token := uf.UserAccessTokens[0]
+	token.CreatedAt = time.Now().Add(-59 * time.Minute)
+	ms.NoError(ms.DB.RawQuery("UPDATE user_access_tokens SET created_at = ? WHERE id = ?",
+		token.CreatedAt, token.ID).Exec())
+
+	ms.NoError(token.Renew(ms.DB))
+	ms.WithinDuration(token.CreatedAt.Add(time.Hour), token.ExpiresAt, time.Second,
+		"expiry should be limited by the organization's maximum session lifetime")
+
+	token.CreatedAt = time.Now().Add(-2 * time.Hour)
+	token.ExpiresAt = time.Now().Add(time.Hour)
+	expired, err := token.DeleteIfExpired(ms.DB)
+	ms.NoError(err)
+	ms.True(expired, "token older than the maximum lifetime should be expired")
+}
+
+func (ms *ModelSuite) TestUserAccessTokens_FindByUser() {
+	uf := createUserFixtures(ms.DB, 2)
+	user := uf.Users[0]
+
+	tokens := UserAccessTokens{
+		{ExpiresAt: time.Now().Add(time.Hour), LastUsedAt: nulls.NewTime(time.Now().Add(-time.Hour))},
+		{ExpiresAt: time.Now().Add(-time.Hour)},
+		{ExpiresAt: time.Now().Add(time.Hour), LastUsedAt: nulls.NewTime(time.Now())},
+	}
+	for i := range tokens {
+		tokens[i].UserID = user.ID
+		tokens[i].AccessToken = HashClientIdAccessToken(domain.GetUUID().String())
+		mustCreate(ms.DB, &tokens[i])
+	}
+
+	var got UserAccessTokens
+	ms.NoError(got.FindByUser(ms.DB, user))
+
+	// the fixture token has never been used, so its creation time counts as its last use
+	want := []int{tokens[2].ID, uf.UserAccessTokens[0].ID, tokens[0].ID}
+	ids := make([]int, len(got))
+	for i := range got {
+		ids[i] = got[i].ID
+	}
+	ms.Equal(want, ids)
+
+	ms.Error(got.FindByUser(ms.DB, User{}))
+}
+
+func (ms *ModelSuite) TestUserAccessTokens_DeleteByUser() {
+	uf := createUserFixtures(ms.DB, 2)
+	user := uf.Users[0]
+
+	keep := uf.UserAccessTokens[0]
+	other := UserAccessToken{
+		UserID:      user.ID,
+		AccessToken: HashClientIdAccessToken("other"),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	mustCreate(ms.DB, &other)
+
+	var tokens UserAccessTokens
+	ms.NoError(tokens.DeleteByUser(ms.DB, user, keep.ID))
+
+	n, err := ms.DB.Where("user_id = ?", user.ID).Count(&UserAccessToken{})
+	ms.NoError(err)
+	ms.Equal(1, n, "wrong number of tokens remaining")
+
+	ms.NoError(tokens.DeleteByUser(ms.DB, user, 0))
+	n, err = ms.DB.Where("user_id = ?", user.ID).Count(&UserAccessToken{})
+	ms.NoError(err)
+	ms.Equal(0, n, "all tokens should have been deleted")
+
+	n, err = ms.DB.Where("user_id = ?", uf.Users[1].ID).Count(&UserAccessToken{})
+	ms.NoError(err)
+	ms.Equal(1, n, "other user's token should not have been deleted")
+}
+
+func (ms *ModelSuite) TestUser_CreateAccessToken_MaxSessions() {
+	uf := createUserFixtures(ms.DB, 1)
+	user := uf.Users[0]
+	org := uf.Organization
+	org.MaxSessionsPerUser = nulls.NewInt(2)
+	ms.NoError(ms.DB.Update(&org))
+
+	for i := 0; i < 3; i++ {
+		_, _, err := user.CreateAccessToken(ms.DB, org, "client"+strconv.Itoa(i))
+		ms.NoError(err)
+	}
+
+	var tokens UserAccessTokens
+	ms.NoError(ms.DB.Where("user_id = ?", user.ID).Order("id").All(&tokens))
+	ms.Equal(2, len(tokens), "wrong number of sessions")
+	ms.Equal("client1", tokens[0].ClientID)
+	ms.Equal("client2", tokens[1].ClientID)
+}
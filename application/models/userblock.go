@@ -0,0 +1,96 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+)
+
+// UserBlock records that a User (the blocker) has blocked another User. A blocked user cannot see the blocker's
+// requests, offer on them, or send messages to the blocker.
+type UserBlock struct {
+	ID        int       `json:"-" db:"id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	BlockerID int       `json:"blocker_id" db:"blocker_id"`
+	BlockedID int       `json:"blocked_id" db:"blocked_id"`
+	Blocked   User      `belongs_to:"users" fk_id:"BlockedID"`
+}
+
+// String can be helpful for serializing the model
+func (b UserBlock) String() string {
+	jb, _ := json.Marshal(b)
+	return string(jb)
+}
+
+// UserBlocks is merely for convenience and brevity
+type UserBlocks []UserBlock
+
+// String can be helpful for serializing the model
+func (b UserBlocks) String() string {
+	jb, _ := json.Marshal(b)
+	return string(jb)
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+func (b *UserBlock) Validate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.IntIsPresent{Field: b.BlockerID, Name: "BlockerID"},
+		&validators.IntIsPresent{Field: b.BlockedID, Name: "BlockedID"},
+		&validators.IntsAreNotEqual{ValueOne: b.BlockerID, ValueTwo: b.BlockedID, Name: "BlockedEqualsBlocker"},
+	), nil
+}
+
+// ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
+func (b *UserBlock) ValidateCreate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// ValidateUpdate gets run every time you call "pop.ValidateAndUpdate" method.
+func (b *UserBlock) ValidateUpdate(tx *pop.Connection) (*validate.Errors, error) {
+	return validate.NewErrors(), nil
+}
+
+// Create stores the UserBlock data as a new record in the database.
+func (b *UserBlock) Create(tx *pop.Connection) error {
+	return create(tx, b)
+}
+
+// FindByUserIDs loads the UserBlock of the user `blockedID` by the user `blockerID`
+func (b *UserBlock) FindByUserIDs(tx *pop.Connection, blockerID, blockedID int) error {
+	if err := tx.Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).First(b); err != nil {
+		return fmt.Errorf("error finding user block, %w", err)
+	}
+	return nil
+}
+
+// FindByBlocker returns all of the UserBlocks created by the given user, with the blocked users, most recent first
+func (b *UserBlocks) FindByBlocker(tx *pop.Connection, blockerID int) error {
+	if blockerID <= 0 {
+		return errors.New("invalid user ID in UserBlocks.FindByBlocker")
+	}
+	return tx.Eager("Blocked").Where("blocker_id = ?", blockerID).Order("created_at desc").All(b)
+}
+
+// isBlockedByAny returns true if any of the users `blockerIDs` has blocked the user `userID`
+func isBlockedByAny(tx *pop.Connection, userID int, blockerIDs []int) (bool, error) {
+	if len(blockerIDs) == 0 {
+		return false, nil
+	}
+
+	exists, err := tx.Where("blocked_id = ?", userID).
+		Where("blocker_id IN (?)", convertSliceFromIntToInterface(blockerIDs)...).
+		Exists(&UserBlock{})
+	if err != nil {
+		return false, fmt.Errorf("error checking for user blocks, %w", err)
+	}
+	return exists, nil
+}
+
+// blockedBySQL selects the IDs of the users who have blocked the user given as the query argument
+const blockedBySQL = `SELECT blocker_id FROM user_blocks WHERE blocked_id = ?`
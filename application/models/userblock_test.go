@@ -0,0 +1,97 @@
+package models
+
+import (
+	"github.com/silinternational/wecarry-api/api"
+)
+
+func (ms *ModelSuite) TestUser_Block() {
+	users := createUserFixtures(ms.DB, 3).Users
+
+	err := users[0].Block(ms.DB, users[0])
+	ms.Error(err, "a user should not be able to block themselves")
+	ms.Equal(api.ErrorUserBlockSelf, err.(*api.AppError).Key)
+
+	ms.NoError(users[0].Block(ms.DB, users[1]))
+	ms.NoError(users[0].Block(ms.DB, users[2]))
+	ms.NoError(users[0].Block(ms.DB, users[1]), "blocking a blocked user should not be an error")
+
+	n, err := ms.DB.Where("blocker_id = ?", users[0].ID).Count(&UserBlock{})
+	ms.NoError(err)
+	ms.Equal(2, n, "incorrect number of blocks")
+
+	blocked, err := users[0].GetBlockedUsers(ms.DB)
+	ms.NoError(err)
+	ms.Equal(2, len(blocked))
+
+	ms.NoError(users[0].Unblock(ms.DB, users[1]))
+	ms.NoError(users[0].Unblock(ms.DB, users[1]), "unblocking a user who isn't blocked should not be an error")
+
+	blocked, err = users[0].GetBlockedUsers(ms.DB)
+	ms.NoError(err)
+	ms.Equal(1, len(blocked))
+	ms.Equal(users[2].ID, blocked[0].ID)
+
+	isBlocked, err := isBlockedByAny(ms.DB, users[2].ID, []int{users[1].ID, users[0].ID})
+	ms.NoError(err)
+	ms.True(isBlocked)
+
+	isBlocked, err = isBlockedByAny(ms.DB, users[0].ID, []int{users[2].ID})
+	ms.NoError(err)
+	ms.False(isBlocked, "a block should only apply in one direction")
+}
+
+func (ms *ModelSuite) TestUser_Block_Requests() {
+	users := createUserFixtures(ms.DB, 3).Users
+	requests := createRequestFixtures(ms.DB, 2, false, users[0].ID)
+
+	ms.NoError(users[0].Block(ms.DB, users[1]))
+
+	ms.False(users[1].CanViewRequest(ms.DB, requests[0]), "blocked user should not see the request")
+	ms.True(users[2].CanViewRequest(ms.DB, requests[0]), "other users should still see the request")
+
+	var found Requests
+	ms.NoError(found.FindByUser(ms.DB, users[1], RequestFilterParams{}))
+	ms.Equal(0, len(found), "blocked user should not find the requests")
+
+	ms.NoError(found.FindByUser(ms.DB, users[2], RequestFilterParams{}))
+	ms.Equal(2, len(found), "other users should still find the requests")
+
+	var request Request
+	err := request.AddUserAsPotentialProvider(ms.DB, requests[0].UUID.String(), users[1])
+	ms.Error(err, "blocked user should not be able to offer on the request")
+	ms.Equal(api.CategoryNotFound, err.(*api.AppError).Category)
+}
+
+func (ms *ModelSuite) TestUser_Block_Messages() {
+	users := createUserFixtures(ms.DB, 2).Users
+	requests := createRequestFixtures(ms.DB, 1, false, users[0].ID)
+
+	var first Message
+	ms.NoError(first.CreateFromInput(ms.DB, users[1], api.MessageInput{
+		Content:   "I can bring it",
+		RequestID: requests[0].UUID.String(),
+	}))
+
+	// the provider blocks the request creator
+	ms.NoError(users[1].Block(ms.DB, users[0]))
+
+	thread, err := first.GetThread(ms.DB)
+	ms.NoError(err)
+	threadID := thread.UUID.String()
+
+	var reply Message
+	err = reply.CreateFromInput(ms.DB, users[0], api.MessageInput{
+		Content:   "Thanks!",
+		RequestID: requests[0].UUID.String(),
+		ThreadID:  &threadID,
+	})
+	ms.Error(err, "blocked user should not be able to send a message")
+	ms.Equal(api.ErrorMessageSenderBlocked, err.(*api.AppError).Key)
+
+	ms.NoError(users[1].Unblock(ms.DB, users[0]))
+	ms.NoError(reply.CreateFromInput(ms.DB, users[0], api.MessageInput{
+		Content:   "Thanks!",
+		RequestID: requests[0].UUID.String(),
+		ThreadID:  &threadID,
+	}))
+}
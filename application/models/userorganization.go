@@ -65,6 +65,20 @@ func (u *UserOrganization) ValidateUpdate(tx *pop.Connection) (*validate.Errors,
 	return validate.NewErrors(), nil
 }
 
+// AfterCreate is called by Pop after successful creation of the record. The user's organizations are tags on the
+// marketing list, so the user's list membership is synced.
+func (u *UserOrganization) AfterCreate(tx *pop.Connection) error {
+	user := User{ID: u.UserID}
+	return user.requestMarketingSync(tx)
+}
+
+// AfterDestroy is called by Pop after successful removal of the record. The user's organizations are tags on the
+// marketing list, so the user's list membership is synced.
+func (u *UserOrganization) AfterDestroy(tx *pop.Connection) error {
+	user := User{ID: u.UserID}
+	return user.requestMarketingSync(tx)
+}
+
 // FindByAuthEmail finds UserOrganizations for the given email address. However, if the
 // orgID param is greater than zero, it will find only the one with both that authEmail and orgID.
 func (u *UserOrganizations) FindByAuthEmail(tx *pop.Connection, authEmail string, orgID int) error {
@@ -89,7 +103,25 @@ func (u *UserOrganizations) FindByAuthEmail(tx *pop.Connection, authEmail string
 	return nil
 }
 
+// FindByAuthID finds the UserOrganizations of the given Organization that have the given auth provider user ID
+func (u *UserOrganizations) FindByAuthID(tx *pop.Connection, authID string, orgID int) error {
+	if authID == "" {
+		return fmt.Errorf("auth ID must not be blank")
+	}
+
+	if err := tx.Where("auth_id = ? AND organization_id = ?", authID, orgID).All(u); err != nil {
+		return fmt.Errorf("error finding user organization by auth id: %s", err.Error())
+	}
+
+	return nil
+}
+
 // Create stores the UserOrganization data as a new record in the database.
 func (u *UserOrganization) Create(tx *pop.Connection) error {
 	return create(tx, u)
 }
+
+// Update writes the UserOrganization data to an existing database record.
+func (u *UserOrganization) Update(tx *pop.Connection) error {
+	return update(tx, u)
+}
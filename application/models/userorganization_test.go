@@ -79,3 +79,32 @@ func (ms *ModelSuite) TestUserOrganization_FindByAuthEmail() {
 		})
 	}
 }
+
+func (ms *ModelSuite) TestUserOrganization_FindByAuthID() {
+	users, orgs := createUserOrganizationFixtures(ms)
+
+	tests := []struct {
+		name    string
+		authID  string
+		orgID   int
+		want    int
+		wantErr bool
+	}{
+		{name: "blank auth id", authID: "", orgID: orgs[1].ID, wantErr: true},
+		{name: "no results", authID: "nobody@example.com", orgID: orgs[1].ID, want: 0},
+		{name: "other organization", authID: users[1].Email, orgID: orgs[1].ID, want: 0},
+		{name: "match", authID: users[0].Email, orgID: orgs[1].ID, want: 1},
+	}
+	for _, tt := range tests {
+		ms.T().Run(tt.name, func(t *testing.T) {
+			var got UserOrganizations
+			err := got.FindByAuthID(ms.DB, tt.authID, tt.orgID)
+			if tt.wantErr {
+				ms.Error(err)
+				return
+			}
+			ms.NoError(err)
+			ms.Equal(tt.want, len(got))
+		})
+	}
+}
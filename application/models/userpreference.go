@@ -10,19 +10,58 @@ import (
 	"github.com/gobuffalo/validate/v3"
 	"github.com/gobuffalo/validate/v3/validators"
 	"github.com/gofrs/uuid"
+
+	"github.com/silinternational/wecarry-api/api"
 	"github.com/silinternational/wecarry-api/domain"
 )
 
 type StandardPreferences struct {
-	Language   string `json:"language"`
-	TimeZone   string `json:"time_zone"`
-	WeightUnit string `json:"weight_unit"`
+	Language           string `json:"language"`
+	TimeZone           string `json:"time_zone"`
+	WeightUnit         string `json:"weight_unit"`
+	BioVisibility      string `json:"bio_visibility"`
+	LocationVisibility string `json:"location_visibility"`
 }
 
 func (s *StandardPreferences) hydrateValues(values map[string]string) {
 	s.Language = values[domain.UserPreferenceKeyLanguage]
 	s.TimeZone = values[domain.UserPreferenceKeyTimeZone]
 	s.WeightUnit = values[domain.UserPreferenceKeyWeightUnit]
+	s.BioVisibility = values[domain.UserPreferenceKeyBioVisibility]
+	s.LocationVisibility = values[domain.UserPreferenceKeyLocationVisibility]
+}
+
+// isBioVisible returns true if the bio is to be shown on the public profile. It is shown unless hidden.
+func (s StandardPreferences) isBioVisible() bool {
+	return s.BioVisibility != domain.UserPreferenceVisibilityHidden
+}
+
+// isLocationVisible returns true if the home location is to be shown on the public profile. It is hidden unless
+// shown.
+func (s StandardPreferences) isLocationVisible() bool {
+	return s.LocationVisibility == domain.UserPreferenceVisibilityVisible
+}
+
+// ConvertUserPreferences converts StandardPreferences to api.UserPreferences
+func ConvertUserPreferences(prefs StandardPreferences) api.UserPreferences {
+	return api.UserPreferences{
+		Language:           prefs.Language,
+		TimeZone:           prefs.TimeZone,
+		WeightUnit:         prefs.WeightUnit,
+		BioVisibility:      prefs.BioVisibility,
+		LocationVisibility: prefs.LocationVisibility,
+	}
+}
+
+// ConvertUserPreferencesInput converts api.UserPreferences to StandardPreferences
+func ConvertUserPreferencesInput(input api.UserPreferences) StandardPreferences {
+	return StandardPreferences{
+		Language:           input.Language,
+		TimeZone:           input.TimeZone,
+		WeightUnit:         input.WeightUnit,
+		BioVisibility:      input.BioVisibility,
+		LocationVisibility: input.LocationVisibility,
+	}
 }
 
 type UserPreference struct {
@@ -107,6 +146,14 @@ func getPreferencesFieldsAndValidators(prefs StandardPreferences) map[string]fie
 		fieldValue: prefs.WeightUnit,
 		validator:  domain.IsWeightUnitAllowed,
 	}
+	fieldAndValidators[domain.UserPreferenceKeyBioVisibility] = fieldAndValidator{
+		fieldValue: prefs.BioVisibility,
+		validator:  domain.IsVisibilityAllowed,
+	}
+	fieldAndValidators[domain.UserPreferenceKeyLocationVisibility] = fieldAndValidator{
+		fieldValue: prefs.LocationVisibility,
+		validator:  domain.IsVisibilityAllowed,
+	}
 
 	return fieldAndValidators
 }
@@ -171,7 +218,8 @@ func updateUsersStandardPreferences(tx *pop.Connection, user User, prefs Standar
 		}
 
 		if !fV.validator(fV.fieldValue) {
-			return fmt.Errorf("unexpected UserPreference %s ... %s", fieldName, fV.fieldValue)
+			err := fmt.Errorf("unexpected UserPreference %s ... %s", fieldName, fV.fieldValue)
+			return api.NewAppError(err, api.ErrorUserPreferenceInvalid, api.CategoryUser)
 		}
 
 		err := p.updateForUserByKey(tx, user, fieldName, fV.fieldValue)
@@ -197,14 +197,19 @@ func (ms *ModelSuite) TestStandardPreferences_hydrateValues() {
 		domain.UserPreferenceKeyLanguage:   domain.UserPreferenceLanguageFrench,
 		domain.UserPreferenceKeyTimeZone:   "America/New_York",
 		domain.UserPreferenceKeyWeightUnit: domain.UserPreferenceWeightUnitKGs,
+
+		domain.UserPreferenceKeyBioVisibility:      domain.UserPreferenceVisibilityHidden,
+		domain.UserPreferenceKeyLocationVisibility: domain.UserPreferenceVisibilityVisible,
 	}
 	sps := StandardPreferences{}
 	sps.hydrateValues(values)
 
 	want := StandardPreferences{
-		Language:   values[domain.UserPreferenceKeyLanguage],
-		TimeZone:   values[domain.UserPreferenceKeyTimeZone],
-		WeightUnit: values[domain.UserPreferenceKeyWeightUnit],
+		Language:           values[domain.UserPreferenceKeyLanguage],
+		TimeZone:           values[domain.UserPreferenceKeyTimeZone],
+		WeightUnit:         values[domain.UserPreferenceKeyWeightUnit],
+		BioVisibility:      values[domain.UserPreferenceKeyBioVisibility],
+		LocationVisibility: values[domain.UserPreferenceKeyLocationVisibility],
 	}
 
 	ms.Equal(want, sps)
@@ -212,30 +217,38 @@ func (ms *ModelSuite) TestStandardPreferences_hydrateValues() {
 
 func (ms *ModelSuite) TestUserPreference_getPreferencesFieldsAndValidators() {
 	sps := StandardPreferences{
-		Language:   domain.UserPreferenceLanguageFrench,
-		TimeZone:   "America/New_York",
-		WeightUnit: domain.UserPreferenceWeightUnitKGs,
+		Language:           domain.UserPreferenceLanguageFrench,
+		TimeZone:           "America/New_York",
+		WeightUnit:         domain.UserPreferenceWeightUnitKGs,
+		BioVisibility:      domain.UserPreferenceVisibilityHidden,
+		LocationVisibility: domain.UserPreferenceVisibilityVisible,
 	}
 	fAndVs := getPreferencesFieldsAndValidators(sps)
 
-	wantValues := [3]string{sps.Language, sps.TimeZone, sps.WeightUnit}
-	gotValues := [3]string{
+	wantValues := [5]string{sps.Language, sps.TimeZone, sps.WeightUnit, sps.BioVisibility, sps.LocationVisibility}
+	gotValues := [5]string{
 		fAndVs[domain.UserPreferenceKeyLanguage].fieldValue,
 		fAndVs[domain.UserPreferenceKeyTimeZone].fieldValue,
 		fAndVs[domain.UserPreferenceKeyWeightUnit].fieldValue,
+		fAndVs[domain.UserPreferenceKeyBioVisibility].fieldValue,
+		fAndVs[domain.UserPreferenceKeyLocationVisibility].fieldValue,
 	}
 	ms.Equal(wantValues, gotValues, "incorrect field values")
 
-	wantValrs := [3]string{
+	wantValrs := [5]string{
 		runtime.FuncForPC(reflect.ValueOf(domain.IsLanguageAllowed).Pointer()).Name(),
 		runtime.FuncForPC(reflect.ValueOf(domain.IsTimeZoneAllowed).Pointer()).Name(),
 		runtime.FuncForPC(reflect.ValueOf(domain.IsWeightUnitAllowed).Pointer()).Name(),
+		runtime.FuncForPC(reflect.ValueOf(domain.IsVisibilityAllowed).Pointer()).Name(),
+		runtime.FuncForPC(reflect.ValueOf(domain.IsVisibilityAllowed).Pointer()).Name(),
 	}
 
-	gotValrs := [3]string{
+	gotValrs := [5]string{
 		runtime.FuncForPC(reflect.ValueOf(fAndVs[domain.UserPreferenceKeyLanguage].validator).Pointer()).Name(),
 		runtime.FuncForPC(reflect.ValueOf(fAndVs[domain.UserPreferenceKeyTimeZone].validator).Pointer()).Name(),
 		runtime.FuncForPC(reflect.ValueOf(fAndVs[domain.UserPreferenceKeyWeightUnit].validator).Pointer()).Name(),
+		runtime.FuncForPC(reflect.ValueOf(fAndVs[domain.UserPreferenceKeyBioVisibility].validator).Pointer()).Name(),
+		runtime.FuncForPC(reflect.ValueOf(fAndVs[domain.UserPreferenceKeyLocationVisibility].validator).Pointer()).Name(),
 	}
 
 	ms.Equal(wantValrs, gotValrs, "incorrect validators")
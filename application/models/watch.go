@@ -13,6 +13,7 @@ import (
 	"github.com/gofrs/uuid"
 
 	"github.com/silinternational/wecarry-api/api"
+	"github.com/silinternational/wecarry-api/domain"
 	"github.com/silinternational/wecarry-api/log"
 )
 
@@ -29,6 +30,13 @@ type Watch struct {
 	MeetingID     nulls.Int    `json:"meeting_id" db:"meeting_id"`
 	SearchText    nulls.String `json:"search_text" db:"search_text"`
 	Size          *RequestSize `json:"size" db:"size"`
+	MinSize       *RequestSize `json:"min_size" db:"min_size"`
+	RadiusKm      nulls.Int    `json:"radius_km" db:"radius_km"`
+	PausedUntil   nulls.Time   `json:"paused_until" db:"paused_until"`
+	ExpiresAt     nulls.Time   `json:"expires_at" db:"expires_at"`
+
+	NeededBeforeStart nulls.Time `json:"needed_before_start" db:"needed_before_start"`
+	NeededBeforeEnd   nulls.Time `json:"needed_before_end" db:"needed_before_end"`
 
 	Destination *Location `belongs_to:"locations"`
 	Origin      *Location `belongs_to:"locations"`
@@ -41,10 +49,51 @@ type Watches []Watch
 
 // Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
 func (w *Watch) Validate(tx *pop.Connection) (*validate.Errors, error) {
-	return validate.Validate(
+	v := []validate.Validator{
 		&validators.UUIDIsPresent{Field: w.UUID, Name: "UUID"},
 		&validators.IntIsPresent{Field: w.OwnerID, Name: "OwnerID"},
-	), nil
+		&watchSizeRangeValidator{Name: "MinSize", Watch: w},
+	}
+
+	if w.RadiusKm.Valid {
+		v = append(v, &validators.IntIsGreaterThan{
+			Field:    w.RadiusKm.Int,
+			Name:     "RadiusKm",
+			Compared: 0,
+			Message:  fmt.Sprintf("Watch radius must be greater than zero. Got %d", w.RadiusKm.Int),
+		})
+	}
+
+	if w.NeededBeforeStart.Valid && w.NeededBeforeEnd.Valid {
+		v = append(v, &validators.TimeAfterTime{
+			FirstName:  "NeededBeforeEnd",
+			FirstTime:  w.NeededBeforeEnd.Time,
+			SecondName: "NeededBeforeStart",
+			SecondTime: w.NeededBeforeStart.Time,
+			Message:    "Watch neededBeforeEnd must not be before neededBeforeStart",
+		})
+	}
+
+	return validate.Validate(v...), nil
+}
+
+type watchSizeRangeValidator struct {
+	Name    string
+	Watch   *Watch
+	Message string
+}
+
+// IsValid adds an error if the watch's minimum size is larger than its maximum size
+func (v *watchSizeRangeValidator) IsValid(errors *validate.Errors) {
+	if v.Watch.MinSize == nil || v.Watch.Size == nil {
+		return
+	}
+
+	if !v.Watch.Size.isLargerOrSame(*v.Watch.MinSize) {
+		v.Message = fmt.Sprintf("Watch minimum size %s is larger than its maximum size %s",
+			*v.Watch.MinSize, *v.Watch.Size)
+		errors.Add(validators.GenerateKey(v.Name), v.Message)
+	}
 }
 
 // ValidateCreate gets run every time you call "pop.ValidateAndCreate" method.
@@ -80,16 +129,16 @@ func (w *Watch) FindByUUID(tx *pop.Connection, id string) error {
 	return nil
 }
 
-// DeleteForOwner deletes the watch with the given UUID, if the user is the owner
-// of the watch.
-func (w *Watch) DeleteForOwner(tx *pop.Connection, id string, user User) (string, *api.AppError) {
+// FindByUUIDForOwner loads from DB the Watch record identified by the given UUID, if the user is the owner of the
+// watch.
+func (w *Watch) FindByUUIDForOwner(tx *pop.Connection, id string, user User) *api.AppError {
 	if id == "" {
 		appError := api.AppError{
 			Category: api.CategoryUser,
 			Key:      api.ErrorWatchMissingID,
 			Err:      errors.New("error: watch uuid must not be blank"),
 		}
-		return "", &appError
+		return &appError
 	}
 
 	if err := w.FindByUUID(tx, id); err != nil {
@@ -98,15 +147,25 @@ func (w *Watch) DeleteForOwner(tx *pop.Connection, id string, user User) (string
 			Key:      api.ErrorWatchNotFound,
 			Err:      err,
 		}
-		return "", &appError
+		return &appError
 	}
 	if w.OwnerID != user.ID {
 		appError := api.AppError{
 			Category: api.CategoryForbidden,
 			Key:      api.ErrorNotAuthorized,
-			Err:      errors.New("error: user may not delete a watch they don't own."),
+			Err:      errors.New("error: user may not access a watch they don't own."),
 		}
-		return "", &appError
+		return &appError
+	}
+
+	return nil
+}
+
+// DeleteForOwner deletes the watch with the given UUID, if the user is the owner
+// of the watch.
+func (w *Watch) DeleteForOwner(tx *pop.Connection, id string, user User) (string, *api.AppError) {
+	if appError := w.FindByUUIDForOwner(tx, id, user); appError != nil {
+		return "", appError
 	}
 
 	if err := w.Destroy(tx); err != nil {
@@ -137,6 +196,35 @@ func (w *Watches) FindByUser(tx *pop.Connection, user User, eagerFields ...strin
 	return nil
 }
 
+// FindMatchingRequests runs the watch as a saved search, returning the open requests that are visible to the watch
+// owner and match all of the watch criteria, newest first. Pausing or expiration of the watch is not considered.
+func (w *Watch) FindMatchingRequests(tx *pop.Connection) (Requests, error) {
+	q := tx.RawQuery(`
+	WITH o AS (
+		SELECT organization_id AS id FROM user_organizations WHERE user_id = ?
+	)
+	SELECT r.* FROM requests r, watches w
+	WHERE w.id = ? AND r.status = ? AND r.created_by_id <> w.owner_id AND (
+		r.organization_id IN (SELECT id FROM o)
+		OR
+		r.visibility = ?
+		OR
+		r.organization_id IN (
+			SELECT secondary_id FROM organization_trusts WHERE primary_id IN (SELECT id FROM o)
+		) AND r.visibility = ?
+	)
+	AND r.created_by_id NOT IN (SELECT blocker_id FROM user_blocks WHERE blocked_id = w.owner_id)
+	AND `+watchMatchesRequestSQL+`
+	ORDER BY r.created_at DESC`,
+		w.OwnerID, w.ID, RequestStatusOpen, RequestVisibilityAll, RequestVisibilityTrusted)
+
+	requests := Requests{}
+	if err := q.All(&requests); err != nil {
+		return nil, fmt.Errorf("error finding requests matching watch %s, %s", w.UUID, err)
+	}
+	return requests, nil
+}
+
 // GetOwner returns the owner of the watch.
 func (w *Watch) GetOwner(tx *pop.Connection) (*User, error) {
 	owner := User{}
@@ -223,14 +311,34 @@ func (w *Watch) LoadForAPI(tx *pop.Connection, user User) error {
 	return nil
 }
 
-// matchesRequest returns true if all non-null watch criteria match the request
+// IsActive returns true if the watch is neither paused nor expired at the given time
+func (w *Watch) IsActive(t time.Time) bool {
+	if w.PausedUntil.Valid && w.PausedUntil.Time.After(t) {
+		return false
+	}
+	return !w.ExpiresAt.Valid || w.ExpiresAt.Time.After(t)
+}
+
+// radiusKm returns the maximum distance of a matching destination or origin from that of the watch
+func (w *Watch) radiusKm() int {
+	if w.RadiusKm.Valid {
+		return w.RadiusKm.Int
+	}
+	return domain.DefaultProximityDistanceKm
+}
+
+// matchesRequest returns true if the watch is active and all non-null watch criteria match the request
 func (w *Watch) matchesRequest(tx *pop.Connection, request Request) bool {
 	if w == nil {
 		log.Errorf("nil receiver in Watch.matchesRequest")
 		return false
 	}
+	if !w.IsActive(time.Now()) {
+		return false
+	}
 	matchFunctions := []func(*Watch, *pop.Connection, Request) bool{
 		(*Watch).sizeMatches,
+		(*Watch).neededBeforeMatches,
 		(*Watch).textMatches,
 		(*Watch).meetingMatches,
 		(*Watch).destinationMatches,
@@ -244,7 +352,8 @@ func (w *Watch) matchesRequest(tx *pop.Connection, request Request) bool {
 	return true
 }
 
-// destinationMatches returns true if watch destination is not provided or passes the IsNear test
+// destinationMatches returns true if watch destination is not provided or is within the watch radius of the request
+// destination
 func (w *Watch) destinationMatches(tx *pop.Connection, request Request) bool {
 	if w == nil {
 		log.Errorf("nil receiver in Watch.destinationMatches")
@@ -267,10 +376,10 @@ func (w *Watch) destinationMatches(tx *pop.Connection, request Request) bool {
 		return true
 	}
 
-	return watchDestination.IsNear(*requestDestination)
+	return watchDestination.IsWithinKm(*requestDestination, w.radiusKm())
 }
 
-// originMatches returns true if watch origin is not provided or passes the IsNear test
+// originMatches returns true if watch origin is not provided or is within the watch radius of the request origin
 func (w *Watch) originMatches(tx *pop.Connection, request Request) bool {
 	if w == nil {
 		log.Errorf("nil receiver in Watch.originMatches")
@@ -295,7 +404,7 @@ func (w *Watch) originMatches(tx *pop.Connection, request Request) bool {
 	if watchOrigin == nil {
 		return true
 	}
-	return watchOrigin.IsNear(*requestOrigin)
+	return watchOrigin.IsWithinKm(*requestOrigin, w.radiusKm())
 }
 
 // meetingMatches returns true if watch meeting is not provided or is identical to the request meeting
@@ -336,14 +445,33 @@ func (w *Watch) textMatches(tx *pop.Connection, request Request) bool {
 	return false
 }
 
-// sizeMatches returns true if watch size is larger or the same as the request size
+// sizeMatches returns true if the request size is within the watch's minimum and maximum sizes, where given
 func (w *Watch) sizeMatches(tx *pop.Connection, request Request) bool {
 	if w == nil {
 		log.Errorf("nil receiver in Watch.sizeMatches")
 		return false
 	}
+	if w.MinSize != nil && !request.Size.isLargerOrSame(*w.MinSize) {
+		return false
+	}
 	if w.Size == nil {
 		return true
 	}
 	return w.Size.isLargerOrSame(request.Size)
 }
+
+// neededBeforeMatches returns true if the request has no needed-before date or the date is within the watch's
+// needed-before window
+func (w *Watch) neededBeforeMatches(tx *pop.Connection, request Request) bool {
+	if w == nil {
+		log.Errorf("nil receiver in Watch.neededBeforeMatches")
+		return false
+	}
+	if !request.NeededBefore.Valid {
+		return true
+	}
+	if w.NeededBeforeStart.Valid && request.NeededBefore.Time.Before(w.NeededBeforeStart.Time) {
+		return false
+	}
+	return !w.NeededBeforeEnd.Valid || !request.NeededBefore.Time.After(w.NeededBeforeEnd.Time)
+}
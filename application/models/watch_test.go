@@ -2,6 +2,7 @@ package models
 
 import (
 	"testing"
+	"time"
 
 	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v6"
@@ -13,6 +14,8 @@ import (
 
 func (ms *ModelSuite) TestWatch_Validate() {
 	t := ms.T()
+	small := RequestSizeSmall
+	large := RequestSizeLarge
 	tests := []struct {
 		name     string
 		watch    Watch
@@ -43,6 +46,51 @@ func (ms *ModelSuite) TestWatch_Validate() {
 			wantErr:  true,
 			errField: "owner_id",
 		},
+		{
+			name: "ranges",
+			watch: Watch{
+				UUID:              domain.GetUUID(),
+				OwnerID:           1,
+				Size:              &large,
+				MinSize:           &small,
+				RadiusKm:          nulls.NewInt(10),
+				NeededBeforeStart: nulls.NewTime(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)),
+				NeededBeforeEnd:   nulls.NewTime(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+			wantErr: false,
+		},
+		{
+			name: "min size larger than size",
+			watch: Watch{
+				UUID:    domain.GetUUID(),
+				OwnerID: 1,
+				Size:    &small,
+				MinSize: &large,
+			},
+			wantErr:  true,
+			errField: "min_size",
+		},
+		{
+			name: "zero radius",
+			watch: Watch{
+				UUID:     domain.GetUUID(),
+				OwnerID:  1,
+				RadiusKm: nulls.NewInt(0),
+			},
+			wantErr:  true,
+			errField: "radius_km",
+		},
+		{
+			name: "needed before end is before start",
+			watch: Watch{
+				UUID:              domain.GetUUID(),
+				OwnerID:           1,
+				NeededBeforeStart: nulls.NewTime(time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)),
+				NeededBeforeEnd:   nulls.NewTime(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+			wantErr:  true,
+			errField: "needed_before_end",
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -344,6 +392,13 @@ func (ms *ModelSuite) TestWatch_destinationMatches() {
 
 	ms.NoError(watches[1].SetDestination(ms.DB, locationX))
 
+	// about 55 km north of the request destination
+	nearby := *dest
+	nearby.ID = 0
+	nearby.Latitude += 0.5
+	ms.NoError(watches[3].SetDestination(ms.DB, nearby))
+	watches[3].RadiusKm = nulls.NewInt(10)
+
 	tests := []struct {
 		name    string
 		watch   *Watch
@@ -368,6 +423,12 @@ func (ms *ModelSuite) TestWatch_destinationMatches() {
 			request: requests[0],
 			want:    true,
 		},
+		{
+			name:    "outside of radius",
+			watch:   &watches[3],
+			request: requests[0],
+			want:    false,
+		},
 		{
 			name:    "watch is nil",
 			watch:   nil,
@@ -450,13 +511,17 @@ func (ms *ModelSuite) TestWatch_originMatches() {
 
 func (ms *ModelSuite) TestWatch_sizeMatches() {
 	requests := createRequestFixtures(ms.DB, 1, false)
-	watches := createWatchFixtures(ms.DB, createUserFixtures(ms.DB, 2).Users)
+	watches := createWatchFixtures(ms.DB, createUserFixtures(ms.DB, 3).Users)
 
 	// don't need to save these changes because sizeMatches doesn't access the database
 	requestSize := requests[0].Size // RequestSizeSmall
 	watches[0].Size = &requestSize
 	tiny := RequestSizeTiny
 	watches[1].Size = &tiny
+	medium := RequestSizeMedium
+	watches[3].MinSize = &medium
+	watches[4].MinSize = &tiny
+	watches[4].Size = &medium
 
 	tests := []struct {
 		name    string
@@ -482,6 +547,18 @@ func (ms *ModelSuite) TestWatch_sizeMatches() {
 			request: requests[0],
 			want:    true,
 		},
+		{
+			name:    "smaller than min size",
+			watch:   &watches[3],
+			request: requests[0],
+			want:    false,
+		},
+		{
+			name:    "within size range",
+			watch:   &watches[4],
+			request: requests[0],
+			want:    true,
+		},
 		{
 			name:    "watch is nil",
 			watch:   nil,
@@ -616,3 +693,117 @@ func (ms *ModelSuite) TestWatch_textMatches() {
 		})
 	}
 }
+
+func (ms *ModelSuite) TestWatch_neededBeforeMatches() {
+	day := func(d int) nulls.Time {
+		return nulls.NewTime(time.Date(2030, 1, d, 0, 0, 0, 0, time.UTC))
+	}
+
+	tests := []struct {
+		name    string
+		watch   *Watch
+		request Request
+		want    bool
+	}{
+		{
+			name:    "no window",
+			watch:   &Watch{},
+			request: Request{NeededBefore: day(10)},
+			want:    true,
+		},
+		{
+			name:    "request has no date",
+			watch:   &Watch{NeededBeforeStart: day(5), NeededBeforeEnd: day(15)},
+			request: Request{},
+			want:    true,
+		},
+		{
+			name:    "within window",
+			watch:   &Watch{NeededBeforeStart: day(10), NeededBeforeEnd: day(10)},
+			request: Request{NeededBefore: day(10)},
+			want:    true,
+		},
+		{
+			name:    "before window",
+			watch:   &Watch{NeededBeforeStart: day(11)},
+			request: Request{NeededBefore: day(10)},
+			want:    false,
+		},
+		{
+			name:    "after window",
+			watch:   &Watch{NeededBeforeEnd: day(9)},
+			request: Request{NeededBefore: day(10)},
+			want:    false,
+		},
+		{
+			name:  "watch is nil",
+			watch: nil,
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		ms.T().Run(tt.name, func(t *testing.T) {
+			ms.Equal(tt.want, tt.watch.neededBeforeMatches(ms.DB, tt.request))
+		})
+	}
+}
+
+func (ms *ModelSuite) TestWatch_IsActive() {
+	now := time.Now()
+	past := nulls.NewTime(now.Add(-time.Hour))
+	future := nulls.NewTime(now.Add(time.Hour))
+
+	tests := []struct {
+		name  string
+		watch Watch
+		want  bool
+	}{
+		{name: "not paused", watch: Watch{}, want: true},
+		{name: "paused", watch: Watch{PausedUntil: future}, want: false},
+		{name: "pause is over", watch: Watch{PausedUntil: past}, want: true},
+		{name: "expired", watch: Watch{ExpiresAt: past}, want: false},
+		{name: "not expired", watch: Watch{ExpiresAt: future}, want: true},
+	}
+	for _, tt := range tests {
+		ms.T().Run(tt.name, func(t *testing.T) {
+			ms.Equal(tt.want, tt.watch.IsActive(now))
+		})
+	}
+}
+
+func (ms *ModelSuite) TestWatch_FindMatchingRequests() {
+	users := createUserFixtures(ms.DB, 2).Users
+	watches := createWatchFixtures(ms.DB, users)
+	requests := createRequestFixtures(ms.DB, 4, false, users[1].ID)
+
+	// request 0 matches, request 1 is not open, request 2 is too small, request 3 does not match the text
+	requests[0].Title = "wanted: a book"
+	requests[1].Title = "wanted: a book"
+	requests[1].Status = RequestStatusCompleted
+	requests[2].Title = "wanted: a pin"
+	requests[2].Size = RequestSizeTiny
+	requests[3].Title = "something else"
+	for i := range requests {
+		ms.NoError(ms.DB.Update(&requests[i]))
+	}
+
+	// a paused watch can still be run as a saved search
+	small := RequestSizeSmall
+	watches[0].SearchText = nulls.NewString("wanted")
+	watches[0].MinSize = &small
+	watches[0].PausedUntil = nulls.NewTime(time.Now().Add(time.Hour))
+	ms.NoError(watches[0].Update(ms.DB))
+
+	watches[2].SearchText = nulls.NewString("wanted")
+	ms.NoError(watches[2].Update(ms.DB))
+
+	got, err := watches[0].FindMatchingRequests(ms.DB)
+	ms.NoError(err)
+	ms.Equal(1, len(got), "wrong number of matching requests")
+	ms.Equal(requests[0].UUID, got[0].UUID, "wrong matching request")
+
+	// the owner's own requests do not match
+	got, err = watches[2].FindMatchingRequests(ms.DB)
+	ms.NoError(err)
+	ms.Equal(0, len(got), "watch owner's requests should not match")
+}
@@ -14,7 +14,7 @@ type DummyEmailService struct {
 var TestEmailService DummyEmailService
 
 type dummyMessage struct {
-	subject, body, fromName, fromEmail, toName, toEmail string
+	subject, body, fromName, fromEmail, toName, toEmail, replyToEmail string
 }
 
 type DummyMessageInfo struct {
@@ -35,12 +35,13 @@ func (t *DummyEmailService) Send(msg Message) error {
 
 	t.sentMessages = append(t.sentMessages,
 		dummyMessage{
-			subject:   msg.Subject,
-			body:      bodyBuf.String(),
-			fromName:  msg.FromName,
-			fromEmail: msg.FromEmail,
-			toName:    msg.ToName,
-			toEmail:   msg.ToEmail,
+			subject:      msg.Subject,
+			body:         bodyBuf.String(),
+			fromName:     msg.FromName,
+			fromEmail:    msg.FromEmail,
+			toName:       msg.ToName,
+			toEmail:      msg.ToEmail,
+			replyToEmail: msg.ReplyToEmail,
 		})
 	return nil
 }
@@ -64,6 +65,15 @@ func (t *DummyEmailService) GetLastToEmail() string {
 	return t.sentMessages[len(t.sentMessages)-1].toEmail
 }
 
+// GetLastReplyToEmail returns the Reply-To address of the last message sent
+func (t *DummyEmailService) GetLastReplyToEmail() string {
+	if len(t.sentMessages) == 0 {
+		return ""
+	}
+
+	return t.sentMessages[len(t.sentMessages)-1].replyToEmail
+}
+
 func (t *DummyEmailService) GetToEmailByIndex(i int) string {
 	if len(t.sentMessages) <= i {
 		return ""
@@ -11,19 +11,22 @@ import (
 func TestSend(t *testing.T) {
 	nickname := "nickname"
 	msg := Message{
-		FromName:  "from name",
-		FromEmail: domain.EmailFromAddress(&nickname),
-		ToName:    "to name",
-		ToEmail:   "to@example.com",
-		Template:  domain.MessageTemplateNewThreadMessage,
+		FromName:     "from name",
+		FromEmail:    domain.EmailFromAddress(&nickname),
+		ToName:       "to name",
+		ToEmail:      "to@example.com",
+		Template:     domain.MessageTemplateNewThreadMessage,
+		ReplyToEmail: "reply+123@example.com",
 		Data: map[string]interface{}{
-			"uiURL":          "example.com",
-			"appName":        "Our App",
-			"requestURL":     "myrequest.example.com",
-			"requestTitle":   "My Request<script>doBadThings()</script>",
-			"messageContent": "I can bring it<script>doBadThings()</script>",
-			"sentByNickname": "Fred<script>doBadThings()</script>",
-			"threadURL":      "ourthread.example.com",
+			"uiURL":           "example.com",
+			"appName":         "Our App",
+			"requestURL":      "myrequest.example.com",
+			"requestTitle":    "My Request<script>doBadThings()</script>",
+			"messageContent":  "I can bring it<script>doBadThings()</script>",
+			"attachmentCount": 2,
+			"canReply":        true,
+			"sentByNickname":  "Fred<script>doBadThings()</script>",
+			"threadURL":       "ourthread.example.com",
 		},
 	}
 	var emailService EmailService
@@ -42,5 +45,38 @@ func TestSend(t *testing.T) {
 
 	body := testService.GetLastBody()
 	assert.Contains(t, body, template.HTMLEscapeString(msg.Data["messageContent"].(string)))
+	assert.Contains(t, body, "attached 2 file(s)")
+	assert.Contains(t, body, "by replying to this email")
+	assert.Equal(t, msg.ReplyToEmail, testService.GetLastReplyToEmail())
 	assert.NotContains(t, body, "<script>")
 }
+
+func TestCheckEmailConfig(t *testing.T) {
+	env := domain.Env
+	defer func() { domain.Env = env }()
+
+	tests := []struct {
+		name    string
+		service string
+		apiKey  string
+		wantErr string
+	}{
+		{name: "dummy", service: EmailServiceDummy},
+		{name: "sendgrid", service: EmailServiceSendGrid, apiKey: "key"},
+		{name: "sendgrid without key", service: EmailServiceSendGrid, wantErr: "SENDGRID_API_KEY"},
+		{name: "unknown", service: "pigeon", wantErr: "unknown EMAIL_SERVICE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain.Env.EmailService = tt.service
+			domain.Env.SendGridAPIKey = tt.apiKey
+
+			err := CheckEmailConfig()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
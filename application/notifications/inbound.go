@@ -0,0 +1,383 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"jaytaylor.com/html2text"
+)
+
+// maxInboundPartSize limits the size of each part of an inbound email that is read
+const maxInboundPartSize = 1024 * 1024
+
+// InboundEmail is an email received by the API, e.g. a reply to a notification
+type InboundEmail struct {
+	// Recipients are the addresses the email was sent to, without names
+	Recipients []string
+
+	// From is the address of the sender, without a name
+	From string
+
+	Subject string
+
+	// Text is the plain text body of the email. If the email only has an HTML body, it is converted to text.
+	Text string
+}
+
+// SNS message types
+const (
+	snsTypeNotification             = "Notification"
+	snsTypeSubscriptionConfirmation = "SubscriptionConfirmation"
+)
+
+// snsMessage is a message posted by Amazon SNS to a subscribed HTTP endpoint
+type snsMessage struct {
+	Type         string `json:"Type"`
+	Message      string `json:"Message"`
+	SubscribeURL string `json:"SubscribeURL"`
+}
+
+// sesReceivedNotification is the notification published by an Amazon SES receipt rule's SNS action
+type sesReceivedNotification struct {
+	NotificationType string `json:"notificationType"`
+	Mail             struct {
+		Destination []string `json:"destination"`
+	} `json:"mail"`
+	Receipt struct {
+		Action struct {
+			Encoding string `json:"encoding"`
+		} `json:"action"`
+	} `json:"receipt"`
+	Content string `json:"content"`
+}
+
+// ParseRawInboundEmail parses a raw MIME email
+func ParseRawInboundEmail(raw []byte) (InboundEmail, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return InboundEmail{}, fmt.Errorf("error reading inbound email, %s", err)
+	}
+
+	var email InboundEmail
+	for _, h := range []string{"To", "Cc", "Delivered-To"} {
+		email.Recipients = appendAddresses(email.Recipients, msg.Header.Get(h))
+	}
+
+	if from := parseAddresses(msg.Header.Get("From")); len(from) > 0 {
+		email.From = from[0]
+	}
+
+	dec := new(mime.WordDecoder)
+	if email.Subject, err = dec.DecodeHeader(msg.Header.Get("Subject")); err != nil {
+		email.Subject = msg.Header.Get("Subject")
+	}
+
+	text, html, err := readMIMEBody(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"),
+		msg.Body)
+	if err != nil {
+		return InboundEmail{}, err
+	}
+
+	email.Text, err = plainText(text, html)
+	return email, err
+}
+
+// ParseSendGridInboundEmail parses the form posted by the SendGrid Inbound Parse webhook. If the webhook is configured
+// to post the raw, full MIME message, it is parsed from the "email" field.
+func ParseSendGridInboundEmail(form url.Values) (InboundEmail, error) {
+	if raw := form.Get("email"); raw != "" {
+		email, err := ParseRawInboundEmail([]byte(raw))
+		if err != nil {
+			return email, err
+		}
+		email.Recipients = appendSendGridEnvelopeRecipients(email.Recipients, form.Get("envelope"))
+		return email, nil
+	}
+
+	var email InboundEmail
+	for _, f := range []string{"to", "cc"} {
+		email.Recipients = appendAddresses(email.Recipients, form.Get(f))
+	}
+	email.Recipients = appendSendGridEnvelopeRecipients(email.Recipients, form.Get("envelope"))
+
+	if from := parseAddresses(form.Get("from")); len(from) > 0 {
+		email.From = from[0]
+	}
+	email.Subject = form.Get("subject")
+
+	var err error
+	email.Text, err = plainText(form.Get("text"), form.Get("html"))
+	return email, err
+}
+
+// ParseSESInboundEmail parses an Amazon SNS message that contains an email received by Amazon SES. If the SNS message
+// is a subscription confirmation, no email is returned, but the URL for confirming the subscription is.
+func ParseSESInboundEmail(body []byte) (email InboundEmail, subscribeURL string, err error) {
+	var sns snsMessage
+	if err = json.Unmarshal(body, &sns); err != nil {
+		return email, "", fmt.Errorf("error decoding SNS message, %s", err)
+	}
+
+	switch sns.Type {
+	case snsTypeSubscriptionConfirmation:
+		return email, sns.SubscribeURL, nil
+	case snsTypeNotification:
+	default:
+		return email, "", fmt.Errorf("unexpected SNS message type '%s'", sns.Type)
+	}
+
+	var notification sesReceivedNotification
+	if err = json.Unmarshal([]byte(sns.Message), &notification); err != nil {
+		return email, "", fmt.Errorf("error decoding SES notification, %s", err)
+	}
+	if notification.Content == "" {
+		return email, "", errors.New("SES notification does not include the email content")
+	}
+
+	raw := []byte(notification.Content)
+	if strings.EqualFold(notification.Receipt.Action.Encoding, "BASE64") {
+		if raw, err = base64.StdEncoding.DecodeString(notification.Content); err != nil {
+			return email, "", fmt.Errorf("error decoding SES email content, %s", err)
+		}
+	}
+
+	if email, err = ParseRawInboundEmail(raw); err != nil {
+		return email, "", err
+	}
+	email.Recipients = appendUnique(email.Recipients, notification.Mail.Destination...)
+	return email, "", nil
+}
+
+// readMIMEBody finds the first plain text and HTML bodies in a (possibly multipart) MIME entity
+func readMIMEBody(contentType, encoding string, body io.Reader) (text, html string, err error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return text, html, fmt.Errorf("error reading inbound email part, %s", err)
+			}
+
+			if strings.HasPrefix(part.Header.Get("Content-Disposition"), "attachment") {
+				continue
+			}
+
+			t, h, err := readMIMEBody(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"),
+				part)
+			if err != nil {
+				return text, html, err
+			}
+			if text == "" {
+				text = t
+			}
+			if html == "" {
+				html = h
+			}
+		}
+		return text, html, nil
+	}
+
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return "", "", nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: body})
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(body, maxInboundPartSize))
+	if err != nil {
+		return "", "", fmt.Errorf("error reading inbound email body, %s", err)
+	}
+
+	if mediaType == "text/html" {
+		return "", string(content), nil
+	}
+	return string(content), "", nil
+}
+
+// newlineStripper removes line breaks, which the base64 decoder does not accept
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (n *newlineStripper) Read(p []byte) (int, error) {
+	count, err := n.r.Read(p)
+	j := 0
+	for _, b := range p[:count] {
+		if b != '\r' && b != '\n' {
+			p[j] = b
+			j++
+		}
+	}
+	return j, err
+}
+
+// plainText returns the text body, or the HTML body converted to text if there is no text body
+func plainText(text, html string) (string, error) {
+	if strings.TrimSpace(text) != "" || html == "" {
+		return text, nil
+	}
+
+	converted, err := html2text.FromString(html)
+	if err != nil {
+		return "", fmt.Errorf("error converting inbound html email to plain text, %s", err)
+	}
+	return converted, nil
+}
+
+// appendSendGridEnvelopeRecipients appends the recipients in a SendGrid "envelope" field, which include recipients
+// that are not in the email's headers, e.g. Bcc recipients
+func appendSendGridEnvelopeRecipients(recipients []string, envelope string) []string {
+	if envelope == "" {
+		return recipients
+	}
+
+	var e struct {
+		To []string `json:"to"`
+	}
+	if err := json.Unmarshal([]byte(envelope), &e); err != nil {
+		return recipients
+	}
+	return appendUnique(recipients, e.To...)
+}
+
+// appendAddresses appends the addresses in an address list header value
+func appendAddresses(addresses []string, header string) []string {
+	return appendUnique(addresses, parseAddresses(header)...)
+}
+
+// parseAddresses returns the addresses, without names, in an address list header value
+func parseAddresses(header string) []string {
+	if strings.TrimSpace(header) == "" {
+		return nil
+	}
+
+	list, err := mail.ParseAddressList(header)
+	if err != nil {
+		return nil
+	}
+
+	addresses := make([]string, len(list))
+	for i := range list {
+		addresses[i] = list[i].Address
+	}
+	return addresses
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, l := range list {
+			if strings.EqualFold(l, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+var (
+	// replyHeaderRegexes match the line that email clients add before the quoted text of the email being replied to
+	replyHeaderRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)^on\s.+\swrote:$`),
+		regexp.MustCompile(`(?i)^le\s.+\sa écrit\s?:$`),
+		regexp.MustCompile(`(?i)^el\s.+\sescribió:$`),
+		regexp.MustCompile(`(?i)^em\s.+\sescreveu:$`),
+		regexp.MustCompile(`(?i)^-+\s*original message\s*-+$`),
+		regexp.MustCompile(`^_{10,}$`),
+	}
+
+	// outlookHeaderRegex matches the headers of the email being replied to, which some clients add instead of a reply
+	// header line
+	outlookHeaderRegex = regexp.MustCompile(`(?i)^(from|de|von):\s`)
+	outlookNextRegex   = regexp.MustCompile(`(?i)^(sent|date|to|envoyé|enviado|gesendet):\s`)
+
+	// signatureRegexes match the first line of a signature
+	signatureRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`^--\s?$`),
+		regexp.MustCompile(`(?i)^sent from my\s`),
+		regexp.MustCompile(`(?i)^get outlook for\s`),
+		regexp.MustCompile(`(?i)^(enviado desde mi|envoyé de mon)\s`),
+	}
+)
+
+// ExtractReply returns the text that the sender of a reply wrote, without the quoted text of the email being replied
+// to and without the sender's signature
+func ExtractReply(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+
+	var reply []string
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+
+		if isReplyHeader(line) {
+			break
+		}
+		// Some clients wrap the reply header line
+		if i+1 < len(lines) && strings.HasPrefix(strings.ToLower(line), "on ") &&
+			isReplyHeader(line+" "+strings.TrimSpace(lines[i+1])) {
+			break
+		}
+		if outlookHeaderRegex.MatchString(line) && i+1 < len(lines) &&
+			outlookNextRegex.MatchString(strings.TrimSpace(lines[i+1])) {
+			break
+		}
+		if isSignature(line) {
+			break
+		}
+		if strings.HasPrefix(line, ">") {
+			continue
+		}
+
+		reply = append(reply, strings.TrimRight(lines[i], " \t"))
+	}
+
+	return strings.TrimSpace(strings.Join(reply, "\n"))
+}
+
+func isReplyHeader(line string) bool {
+	for _, r := range replyHeaderRegexes {
+		if r.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSignature(line string) bool {
+	for _, r := range signatureRegexes {
+		if r.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+package notifications
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRawEmail = "From: Fred Jones <fred@example.com>\r\n" +
+	"To: WeCarry <reply+0123456789abcdef0123456789abcdef@reply.example.com>\r\n" +
+	"Cc: other@example.com\r\n" +
+	"Subject: =?utf-8?q?Re:_caf=C3=A9?=\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"b1\"\r\n" +
+	"\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Yes, I can bring it =E2=80=94 see you Tuesday.\r\n" +
+	"\r\n" +
+	"On Mon, Oct 19, 2026 at 9:00 AM WeCarry <no_reply@example.com> wrote:\r\n" +
+	"> You have a new message\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Yes, I can bring it</p>\r\n" +
+	"--b1--\r\n"
+
+func TestParseRawInboundEmail(t *testing.T) {
+	email, err := ParseRawInboundEmail([]byte(testRawEmail))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"reply+0123456789abcdef0123456789abcdef@reply.example.com", "other@example.com"},
+		email.Recipients)
+	assert.Equal(t, "fred@example.com", email.From)
+	assert.Equal(t, "Re: café", email.Subject)
+	assert.Contains(t, email.Text, "Yes, I can bring it — see you Tuesday.")
+}
+
+func TestParseRawInboundEmail_HTMLOnly(t *testing.T) {
+	raw := "From: fred@example.com\r\n" +
+		"To: reply+abc@reply.example.com\r\n" +
+		"Content-Type: text/html; charset=utf-8\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		base64.StdEncoding.EncodeToString([]byte("<p>Sounds <b>good</b></p>")) + "\r\n"
+
+	email, err := ParseRawInboundEmail([]byte(raw))
+	require.NoError(t, err)
+	assert.Equal(t, "Sounds *good*", strings.TrimSpace(email.Text))
+}
+
+func TestParseSendGridInboundEmail(t *testing.T) {
+	t.Run("parsed", func(t *testing.T) {
+		form := url.Values{
+			"to":       {"WeCarry <reply+abc@reply.example.com>"},
+			"from":     {"Fred <fred@example.com>"},
+			"subject":  {"Re: chocolate"},
+			"text":     {"Sounds good"},
+			"envelope": {`{"to":["bcc+abc@reply.example.com"],"from":"fred@example.com"}`},
+		}
+
+		email, err := ParseSendGridInboundEmail(form)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"reply+abc@reply.example.com", "bcc+abc@reply.example.com"}, email.Recipients)
+		assert.Equal(t, "fred@example.com", email.From)
+		assert.Equal(t, "Re: chocolate", email.Subject)
+		assert.Equal(t, "Sounds good", email.Text)
+	})
+
+	t.Run("raw", func(t *testing.T) {
+		form := url.Values{"email": {testRawEmail}}
+
+		email, err := ParseSendGridInboundEmail(form)
+		require.NoError(t, err)
+		assert.Equal(t, "fred@example.com", email.From)
+		assert.Contains(t, email.Text, "Yes, I can bring it")
+	})
+}
+
+func TestParseSESInboundEmail(t *testing.T) {
+	sesNotification := func(encoding, content string) []byte {
+		var n sesReceivedNotification
+		n.NotificationType = "Received"
+		n.Mail.Destination = []string{"reply+0123456789abcdef0123456789abcdef@reply.example.com"}
+		n.Receipt.Action.Encoding = encoding
+		n.Content = content
+		message, _ := json.Marshal(n)
+		body, _ := json.Marshal(snsMessage{Type: snsTypeNotification, Message: string(message)})
+		return body
+	}
+
+	tests := []struct {
+		name    string
+		body    []byte
+		wantURL string
+		wantErr bool
+	}{
+		{
+			name: "utf8",
+			body: sesNotification("UTF8", testRawEmail),
+		},
+		{
+			name: "base64",
+			body: sesNotification("BASE64", base64.StdEncoding.EncodeToString([]byte(testRawEmail))),
+		},
+		{
+			name:    "subscription confirmation",
+			body:    []byte(`{"Type":"SubscriptionConfirmation","SubscribeURL":"https://sns.us-east-1.amazonaws.com/x"}`),
+			wantURL: "https://sns.us-east-1.amazonaws.com/x",
+		},
+		{
+			name:    "no content",
+			body:    sesNotification("UTF8", ""),
+			wantErr: true,
+		},
+		{
+			name:    "not json",
+			body:    []byte("From: fred@example.com"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, subscribeURL, err := ParseSESInboundEmail(tt.body)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantURL, subscribeURL)
+			if tt.wantURL != "" {
+				return
+			}
+			assert.Equal(t, "fred@example.com", email.From)
+			assert.Contains(t, email.Recipients, "reply+0123456789abcdef0123456789abcdef@reply.example.com")
+			assert.Contains(t, email.Text, "Yes, I can bring it")
+		})
+	}
+}
+
+func TestExtractReply(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "gmail",
+			text: "Sounds good!\r\n\r\nOn Mon, Oct 19, 2026 at 9:00 AM WeCarry <no_reply@example.com> wrote:\r\n" +
+				"> You have a new message from Fred\r\n> I can bring it",
+			want: "Sounds good!",
+		},
+		{
+			name: "wrapped reply header",
+			text: "Sounds good!\n\nOn Mon, Oct 19, 2026 at 9:00 AM WeCarry\n<no_reply@example.com> wrote:\n> Hi",
+			want: "Sounds good!",
+		},
+		{
+			name: "outlook",
+			text: "Thanks,\nsee you then\n\n________________________________\nFrom: WeCarry\nSent: Monday\n\nHi",
+			want: "Thanks,\nsee you then",
+		},
+		{
+			name: "outlook headers without separator",
+			text: "Thanks\n\nFrom: WeCarry <no_reply@example.com>\nDate: Monday\nSubject: New message",
+			want: "Thanks",
+		},
+		{
+			name: "original message",
+			text: "Thanks\n-----Original Message-----\nHi",
+			want: "Thanks",
+		},
+		{
+			name: "signature",
+			text: "I can bring it.\n\n-- \nFred Jones\nExample Org",
+			want: "I can bring it.",
+		},
+		{
+			name: "mobile signature",
+			text: "Yes\n\nSent from my iPhone",
+			want: "Yes",
+		},
+		{
+			name: "inline quotes",
+			text: "> Can you bring chocolate?\nYes\n> And PB?\nNo",
+			want: "Yes\nNo",
+		},
+		{
+			name: "from in text",
+			text: "From: the airport, I will take a taxi",
+			want: "From: the airport, I will take a taxi",
+		},
+		{
+			name: "only quoted text",
+			text: "On Mon, Oct 19, 2026, Fred wrote:\n> Hi",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ExtractReply(tt.text))
+		})
+	}
+}
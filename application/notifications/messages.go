@@ -1,5 +1,7 @@
 package notifications
 
+import "context"
+
 type Message struct {
 	Template  string
 	Data      map[string]interface{}
@@ -10,4 +12,18 @@ type Message struct {
 	ToEmail   string
 	ToPhone   string
 	Subject   string
+
+	// ReplyToEmail is the address for replies, if not the sender's address
+	ReplyToEmail string
+
+	// ctx holds the trace of the sending of the message
+	ctx context.Context
+}
+
+// traceContext returns the context that holds the trace of the sending of the message
+func (m Message) traceContext() context.Context {
+	if m.ctx == nil {
+		return context.Background()
+	}
+	return m.ctx
 }
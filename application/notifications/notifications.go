@@ -1,6 +1,10 @@
 package notifications
 
-import "github.com/silinternational/wecarry-api/log"
+import (
+	"context"
+
+	"github.com/silinternational/wecarry-api/log"
+)
 
 const mailTemplatePath = "mail/"
 
@@ -11,7 +15,14 @@ func init() {
 	notifiers = append(notifiers, &email)
 }
 
+// Send sends the message by each of the notifiers
 func Send(msg Message) error {
+	return SendContext(context.Background(), msg)
+}
+
+// SendContext sends the message by each of the notifiers, as part of the trace in the context, if any
+func SendContext(ctx context.Context, msg Message) error {
+	msg.ctx = ctx
 	for _, n := range notifiers {
 		if err := n.Send(msg); err != nil {
 			return err
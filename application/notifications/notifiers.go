@@ -1,7 +1,14 @@
 package notifications
 
 import (
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+
 	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/metrics"
+	"github.com/silinternational/wecarry-api/tracing"
 )
 
 const (
@@ -33,20 +40,53 @@ func (e *EmailNotifier) Send(msg Message) error {
 	case EmailServiceDummy:
 		emailService = &TestEmailService
 	default:
+		emailServiceType = EmailServiceDummy
 		emailService = &TestEmailService
 	}
 
+	ctx, span := tracing.Start(msg.traceContext(), "email send",
+		attribute.String("email.service", emailServiceType),
+		attribute.String("email.template", msg.Template),
+	)
+
 	emailMessage := Message{
-		FromName:  msg.FromName,
-		FromEmail: msg.FromEmail,
-		ToName:    msg.ToName,
-		ToEmail:   msg.ToEmail,
-		Template:  msg.Template,
-		Data:      msg.Data,
-		Subject:   msg.Subject,
+		FromName:     msg.FromName,
+		FromEmail:    msg.FromEmail,
+		ToName:       msg.ToName,
+		ToEmail:      msg.ToEmail,
+		Template:     msg.Template,
+		Data:         msg.Data,
+		Subject:      msg.Subject,
+		ReplyToEmail: msg.ReplyToEmail,
+		ctx:          ctx,
 	}
 
-	return emailService.Send(emailMessage)
+	err := emailService.Send(emailMessage)
+	metrics.ObserveNotification(emailServiceType, err)
+	tracing.End(span, err)
+	return err
+}
+
+// CheckEmailConfig verifies that the configured email service has the settings it needs to send email
+func CheckEmailConfig() error {
+	if domain.Env.EmailFromAddress == "" {
+		return errors.New("EMAIL_FROM_ADDRESS is not set")
+	}
+
+	switch domain.Env.EmailService {
+	case EmailServiceSendGrid:
+		if domain.Env.SendGridAPIKey == "" {
+			return errors.New("SENDGRID_API_KEY is not set")
+		}
+	case EmailServiceSES:
+		if domain.Env.AwsRegion == "" || domain.Env.AwsAccessKeyID == "" || domain.Env.AwsSecretAccessKey == "" {
+			return errors.New("AWS region or credentials are not set")
+		}
+	case EmailServiceDummy:
+	default:
+		return fmt.Errorf("unknown EMAIL_SERVICE '%s'", domain.Env.EmailService)
+	}
+	return nil
 }
 
 // MobileNotifier is an email notifier that conforms to the Notifier interface.
@@ -11,6 +11,7 @@ import (
 
 	"github.com/silinternational/wecarry-api/domain"
 	"github.com/silinternational/wecarry-api/log"
+	"github.com/silinternational/wecarry-api/tracing"
 )
 
 type SendGridService struct{}
@@ -45,8 +46,13 @@ func (e *SendGridService) Send(msg Message) error {
 	}
 
 	m := mail.NewSingleEmail(from, msg.Subject, to, tbody, body)
+	if msg.ReplyToEmail != "" {
+		m.SetReplyTo(mail.NewEmail("", msg.ReplyToEmail))
+	}
 	client := sendgrid.NewSendClient(apiKey)
-	response, err := client.Send(m)
+	ctx, span := tracing.Start(msg.traceContext(), "SendGrid Send")
+	response, err := client.SendWithContext(ctx, m)
+	tracing.End(span, err)
 	if err != nil {
 		return fmt.Errorf("error attempting to send message, %s", err)
 	}
@@ -26,7 +26,7 @@ func (s *SES) Send(msg Message) error {
 	to := addressWithName(msg.ToName, msg.ToEmail)
 	from := addressWithName(msg.FromName, msg.FromEmail)
 
-	return aws.SendEmail(to, from, msg.Subject, body)
+	return aws.SendEmail(msg.traceContext(), to, from, msg.ReplyToEmail, msg.Subject, body)
 }
 
 func addressWithName(name, address string) string {
@@ -0,0 +1,121 @@
+// Package ratelimit counts requests against limits in Redis, so that the limits hold across all instances of the API.
+// Requests are counted in fixed windows: a limit of 10/m allows 10 requests in each clock minute.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/tracing"
+)
+
+const keyPrefix = "ratelimit:"
+
+var client redis.Cmdable
+
+func init() {
+	ring := redis.NewRing(&redis.RingOptions{
+		Addrs: map[string]string{
+			domain.Env.RedisInstanceName: domain.Env.RedisInstanceHostPort,
+		},
+	})
+	ring.AddHook(tracing.RedisHook{})
+	client = ring
+}
+
+// Limit is a number of requests allowed in a window of time
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// String formats the limit as accepted by ParseLimit
+func (l Limit) String() string {
+	for unit, d := range units {
+		if l.Window == d {
+			return fmt.Sprintf("%d/%s", l.Requests, unit)
+		}
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Window)
+}
+
+var units = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+}
+
+// ParseLimit parses a limit such as "10/m". The window is one of s, m, h or d, or a Go duration such as "10m".
+func ParseLimit(s string) (Limit, error) {
+	n, w, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit '%s' is not of the form requests/window", s)
+	}
+
+	requests, err := strconv.Atoi(strings.TrimSpace(n))
+	if err != nil || requests < 1 {
+		return Limit{}, fmt.Errorf("rate limit '%s' does not have a positive number of requests", s)
+	}
+
+	w = strings.TrimSpace(w)
+	window, ok := units[w]
+	if !ok {
+		window, err = time.ParseDuration(w)
+		if err != nil || window < time.Second {
+			return Limit{}, fmt.Errorf("rate limit '%s' does not have a valid window", s)
+		}
+	}
+
+	return Limit{Requests: requests, Window: window}, nil
+}
+
+// Result is the state of a limit after a request was counted
+type Result struct {
+	Limit Limit
+
+	// Allowed is false if the request exceeded the limit
+	Allowed bool
+
+	// Remaining is the number of requests still allowed in the current window
+	Remaining int
+
+	// Reset is the time until the current window ends
+	Reset time.Duration
+}
+
+// Allow counts a request of the given cost against the limit, for the subject identified by the key. The cost is
+// the number of requests to count, e.g. the number of emails sent by one API call.
+func Allow(ctx context.Context, key string, limit Limit, cost int) (Result, error) {
+	now := time.Now()
+	windowStart := now.Truncate(limit.Window)
+	redisKey := fmt.Sprintf("%s%s:%d", keyPrefix, key, windowStart.Unix())
+
+	var incr *redis.IntCmd
+	_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.IncrBy(ctx, redisKey, int64(cost))
+		pipe.Expire(ctx, redisKey, limit.Window)
+		return nil
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("error counting request against rate limit %s, %w", key, err)
+	}
+
+	count := int(incr.Val())
+	result := Result{
+		Limit:     limit,
+		Allowed:   count <= limit.Requests,
+		Remaining: limit.Requests - count,
+		Reset:     windowStart.Add(limit.Window).Sub(now),
+	}
+	if result.Remaining < 0 {
+		result.Remaining = 0
+	}
+	return result, nil
+}
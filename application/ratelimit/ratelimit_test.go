@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// TestSuite establishes a test suite for rate limit tests
+type TestSuite struct {
+	suite.Suite
+}
+
+// Test_TestSuite runs the test suite
+func Test_TestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+func (ts *TestSuite) TestParseLimit() {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{in: "10/m", want: Limit{Requests: 10, Window: time.Minute}},
+		{in: " 300 / h ", want: Limit{Requests: 300, Window: time.Hour}},
+		{in: "5/d", want: Limit{Requests: 5, Window: 24 * time.Hour}},
+		{in: "20/15m", want: Limit{Requests: 20, Window: 15 * time.Minute}},
+		{in: "10", wantErr: true},
+		{in: "0/m", wantErr: true},
+		{in: "x/m", wantErr: true},
+		{in: "10/week", wantErr: true},
+		{in: "10/1ms", wantErr: true},
+	}
+	for _, tt := range tests {
+		ts.Run(tt.in, func() {
+			got, err := ParseLimit(tt.in)
+			if tt.wantErr {
+				ts.Error(err)
+				return
+			}
+			ts.NoError(err)
+			ts.Equal(tt.want, got)
+		})
+	}
+}
+
+func (ts *TestSuite) TestLimitString() {
+	ts.Equal("10/m", Limit{Requests: 10, Window: time.Minute}.String())
+	ts.Equal("20/15m0s", Limit{Requests: 20, Window: 15 * time.Minute}.String())
+}
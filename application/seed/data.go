@@ -0,0 +1,118 @@
+package seed
+
+import "github.com/silinternational/wecarry-api/models"
+
+// place is a real place used for generated locations
+type place struct {
+	description string
+	city        string
+	state       string
+	country     string
+	latitude    float64
+	longitude   float64
+}
+
+var places = []place{
+	{"Madrid, Spain", "Madrid", "MD", "ES", 40.4168, -3.7038},
+	{"Atlanta, GA, USA", "Atlanta", "GA", "US", 33.7490, -84.3880},
+	{"Orlando, FL, USA", "Orlando", "FL", "US", 28.5383, -81.3792},
+	{"Dallas, TX, USA", "Dallas", "TX", "US", 32.7767, -96.7970},
+	{"Charlotte, NC, USA", "Charlotte", "NC", "US", 35.2271, -80.8431},
+	{"Toronto, ON, Canada", "Toronto", "ON", "CA", 43.6532, -79.3832},
+	{"Mexico City, Mexico", "Mexico City", "", "MX", 19.4326, -99.1332},
+	{"Guatemala City, Guatemala", "Guatemala City", "", "GT", 14.6349, -90.5069},
+	{"Lima, Peru", "Lima", "", "PE", -12.0464, -77.0428},
+	{"Nairobi, Kenya", "Nairobi", "", "KE", -1.2921, 36.8219},
+	{"Addis Ababa, Ethiopia", "Addis Ababa", "", "ET", 8.9806, 38.7578},
+	{"Yaoundé, Cameroon", "Yaoundé", "", "CM", 3.8480, 11.5021},
+	{"Dakar, Senegal", "Dakar", "", "SN", 14.7167, -17.4677},
+	{"Paris, France", "Paris", "", "FR", 48.8566, 2.3522},
+	{"London, UK", "London", "", "GB", 51.5074, -0.1278},
+	{"Nuremberg, Germany", "Nuremberg", "BY", "DE", 49.4521, 11.0767},
+	{"Chiang Mai, Thailand", "Chiang Mai", "", "TH", 18.7883, 98.9853},
+	{"Manila, Philippines", "Manila", "", "PH", 14.5995, 120.9842},
+	{"Ukarumpa, Papua New Guinea", "Ukarumpa", "EHP", "PG", -6.3167, 145.8833},
+	{"Port Moresby, Papua New Guinea", "Port Moresby", "NCD", "PG", -9.4438, 147.1803},
+	{"Darwin, NT, Australia", "Darwin", "NT", "AU", -12.4634, 130.8456},
+	{"Port Lincoln, SA, Australia", "Port Lincoln", "SA", "AU", -34.7302, 135.8505},
+	{"Seoul, South Korea", "Seoul", "", "KR", 37.5665, 126.9780},
+	{"São Paulo, Brazil", "São Paulo", "SP", "BR", -23.5505, -46.6333},
+}
+
+// item is something that users ask to be carried
+type item struct {
+	title       string
+	description string
+	size        models.RequestSize
+	kilograms   float64
+}
+
+var items = []item{
+	{"Maple syrup", "A litre of real Canadian maple syrup, grade A amber", models.RequestSizeMedium, 1.4},
+	{"Peanut butter", "Two jars of crunchy peanut butter, any brand", models.RequestSizeSmall, 1.0},
+	{"Lip balm", "A pack of beeswax lip balm", models.RequestSizeTiny, 0.1},
+	{"Coffee beans", "Whole bean medium roast, 1kg bag", models.RequestSizeSmall, 1.0},
+	{"Printer toner", "Black toner cartridge for a laser printer, model on request", models.RequestSizeMedium, 1.2},
+	{"Blood pressure medication", "Three months of prescription refills, prescription available", models.RequestSizeTiny, 0.2},
+	{"Children's books", "A box of early reader books in English", models.RequestSizeLarge, 6.0},
+	{"Laptop charger", "USB-C 65W charger", models.RequestSizeSmall, 0.4},
+	{"Hiking boots", "Men's size 10, already paid for, shipped to my US address", models.RequestSizeMedium, 1.6},
+	{"Guitar strings", "Two sets of light gauge acoustic strings", models.RequestSizeTiny, 0.1},
+	{"Spices", "Cumin, smoked paprika and chili powder", models.RequestSizeSmall, 0.5},
+	{"Solar panel", "Folding 100W solar panel for the village clinic", models.RequestSizeXlarge, 9.0},
+	{"Water filter cartridges", "Replacement cartridges for a gravity filter", models.RequestSizeMedium, 2.0},
+	{"Dictionary", "Bilingual dictionary for the translation team", models.RequestSizeSmall, 1.1},
+	{"Vitamins", "Children's chewable multivitamins", models.RequestSizeTiny, 0.3},
+	{"Tea", "Loose leaf English breakfast tea", models.RequestSizeSmall, 0.5},
+	{"Camera lens", "Borrowed lens that needs to go back to its owner", models.RequestSizeSmall, 0.8},
+	{"Seeds", "Vegetable seeds for the school garden", models.RequestSizeTiny, 0.2},
+	{"External hard drive", "2TB drive with the project archive", models.RequestSizeTiny, 0.3},
+	{"Chocolate chips", "Semi-sweet, for a birthday cake", models.RequestSizeSmall, 0.7},
+}
+
+var firstNames = []string{
+	"Ana", "Ben", "Chloe", "David", "Esther", "Felipe", "Grace", "Hana", "Isaac", "Joy",
+	"Kwame", "Lena", "Mateo", "Naomi", "Omar", "Priya", "Quinn", "Ruth", "Samuel", "Tomoko",
+	"Uriel", "Vera", "Wanjiru", "Xavier", "Yuna", "Zeke",
+}
+
+var lastNames = []string{
+	"Adeyemi", "Bauer", "Castillo", "Dubois", "Eriksen", "Fernandes", "Gomez", "Hughes", "Ito", "Johnson",
+	"Kariuki", "Lee", "Martin", "Nguyen", "Okafor", "Park", "Quispe", "Rossi", "Smith", "Tanaka",
+}
+
+var organizationNames = []string{
+	"Northwind Relief", "Harbor Translation Partners", "Highland Health Mission", "Open Road Aviation",
+	"Riverbend Schools", "Lighthouse Literacy", "Summit Water Project", "Crossroads Media",
+	"Evergreen Community Services", "Bridgeway Fellowship",
+}
+
+var meetingNames = []string{
+	"Regional Conference", "Translators Workshop", "Leadership Retreat", "Annual General Meeting",
+	"Literacy Training", "Health Workers Summit", "IT Roundtable", "Family Camp",
+}
+
+var messages = []string{
+	"Hi! I'm flying out next week and have some room in my bag.",
+	"That would be wonderful, thank you so much!",
+	"Can you send me the link to the exact one you want?",
+	"Sure, here it is. It's fine if it's a different brand.",
+	"Where should I have it shipped before I leave?",
+	"I'll send you the address in a private message.",
+	"It arrived today, all packed and ready.",
+	"Where would be a good place to hand it over?",
+	"I'll be at the conference on Tuesday, we could meet there.",
+	"Got it, thanks again!",
+}
+
+var timeZones = []string{
+	"America/New_York", "America/Chicago", "America/Toronto", "America/Lima", "Europe/Madrid", "Europe/London",
+	"Africa/Nairobi", "Africa/Dakar", "Asia/Bangkok", "Asia/Manila", "Pacific/Port_Moresby", "Australia/Darwin",
+}
+
+// gif is a one-pixel image used for generated files
+var gif = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0xff, 0xff, 0xff,
+	0x00, 0x00, 0x00, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
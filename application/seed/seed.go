@@ -0,0 +1,630 @@
+// Package seed generates realistic data for load tests and demonstrations. The data is derived from a random seed,
+// so that generating with the same seed and configuration gives the same records, UUIDs and access tokens. Dates are
+// relative to the time of generation, and stored files get new UUIDs each time.
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+
+	"github.com/silinternational/wecarry-api/domain"
+	"github.com/silinternational/wecarry-api/models"
+)
+
+// Config holds the parameters of the generated data
+type Config struct {
+	// Seed determines all of the random choices of the generator
+	Seed int64
+
+	Organizations int
+	Users         int
+	Requests      int
+	Meetings      int
+
+	// Files adds images to organizations, meetings and some requests. Storing files requires the S3 bucket.
+	Files bool
+
+	// Events keeps the events raised while generating, which would otherwise be marked as delivered so that they
+	// do not cause a flood of notifications
+	Events bool
+}
+
+// DefaultConfig is a small data set suitable for a demonstration
+func DefaultConfig() Config {
+	return Config{
+		Seed:          1,
+		Organizations: 3,
+		Users:         50,
+		Requests:      200,
+		Meetings:      4,
+		Files:         true,
+	}
+}
+
+// Validate checks that the configuration can produce a consistent data set
+func (c Config) Validate() error {
+	switch {
+	case c.Organizations < 1:
+		return errors.New("at least one organization is required")
+	case c.Users < 2*c.Organizations:
+		return errors.New("at least two users per organization are required")
+	case c.Requests < 0 || c.Meetings < 0:
+		return errors.New("the number of requests and meetings must not be negative")
+	}
+	return nil
+}
+
+// Result counts the records generated
+type Result struct {
+	Organizations      int
+	Trusts             int
+	Users              int
+	Locations          int
+	Requests           map[models.RequestStatus]int
+	PotentialProviders int
+	Threads            int
+	Messages           int
+	Watches            int
+	Meetings           int
+	Participants       int
+	Files              int
+}
+
+// AccessToken returns the bearer token of the user with the given index, from 0, in the data generated with the
+// given seed. Each user has a session in their organization that lasts a year.
+func AccessToken(seed int64, user int) string {
+	return fmt.Sprintf("seed%d_user%d", seed, user)
+}
+
+// UserEmail returns the email address of the user with the given index, from 0, in the data generated with the given
+// seed
+func UserEmail(seed int64, user int) string {
+	first, last := personName(user)
+	return fmt.Sprintf("%s.%s.%d@seed%d.example.org", strings.ToLower(first), strings.ToLower(last), user, seed)
+}
+
+// generator holds the state of one run of Generate
+type generator struct {
+	tx     *pop.Connection
+	cfg    Config
+	rng    *rand.Rand
+	now    time.Time
+	result Result
+
+	orgs       models.Organizations
+	users      models.Users
+	homes      models.Locations
+	userOrg    []int   // index of each user's organization in orgs
+	members    [][]int // indexes of the users of each organization
+	meetingIDs []int
+}
+
+func newGenerator(cfg Config) *generator {
+	// #nosec G404 -- the data must be reproducible, not unpredictable
+	return &generator{
+		cfg: cfg,
+		rng: rand.New(rand.NewSource(cfg.Seed)),
+		now: time.Now(),
+		result: Result{
+			Requests: map[models.RequestStatus]int{},
+		},
+	}
+}
+
+// Generate adds the data set to the database. It fails if the data set of the same seed was already generated.
+func Generate(tx *pop.Connection, cfg Config) (Result, error) {
+	if err := cfg.Validate(); err != nil {
+		return Result{}, err
+	}
+
+	var existing models.User
+	if err := existing.FindByEmail(tx, UserEmail(cfg.Seed, 0)); err == nil {
+		return Result{}, fmt.Errorf("the data of seed %d was already generated", cfg.Seed)
+	}
+
+	var lastEvent models.OutboxEvent
+	if err := tx.Order("id desc").First(&lastEvent); domain.IsOtherThanNoRows(err) {
+		return Result{}, err
+	}
+
+	g := newGenerator(cfg)
+	g.tx = tx
+	steps := []func() error{
+		g.generateOrganizations,
+		g.generateTrusts,
+		g.generateUsers,
+		g.generateMeetings,
+		g.generateRequests,
+		g.generateWatches,
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return g.result, err
+		}
+	}
+
+	if !cfg.Events {
+		err := tx.RawQuery("UPDATE outbox_events SET processed_at = ? WHERE id > ? AND processed_at IS NULL",
+			time.Now(), lastEvent.ID).Exec()
+		if err != nil {
+			return g.result, fmt.Errorf("error suppressing events of generated data, %w", err)
+		}
+	}
+	return g.result, nil
+}
+
+// uuid returns a version 4 UUID made from the random source
+func (g *generator) uuid() uuid.UUID {
+	var u uuid.UUID
+	_, _ = g.rng.Read(u[:])
+	u.SetVersion(uuid.V4)
+	u.SetVariant(uuid.VariantRFC4122)
+	return u
+}
+
+// chance returns true with the given probability
+func (g *generator) chance(p float64) bool {
+	return g.rng.Float64() < p
+}
+
+// personName returns the name of the user with the given index. It does not use the random source, so that
+// UserEmail does not depend on the order of generation.
+func personName(i int) (string, string) {
+	return firstNames[i%len(firstNames)], lastNames[(i/len(firstNames)+i)%len(lastNames)]
+}
+
+// location creates a location near one of the places
+func (g *generator) location() (models.Location, error) {
+	p := places[g.rng.Intn(len(places))]
+	l := models.Location{
+		Description: p.description,
+		City:        p.city,
+		State:       p.state,
+		Country:     p.country,
+		Latitude:    p.latitude + (g.rng.Float64()-0.5)*0.1,
+		Longitude:   p.longitude + (g.rng.Float64()-0.5)*0.1,
+	}
+	if err := l.Create(g.tx); err != nil {
+		return l, fmt.Errorf("error creating location, %w", err)
+	}
+	g.result.Locations++
+	return l, nil
+}
+
+// file stores an image, if files are enabled. It returns the UUID of the file, or an empty string.
+func (g *generator) file(name string) (string, error) {
+	if !g.cfg.Files {
+		return "", nil
+	}
+	f := models.File{Name: name + ".gif", Content: gif}
+	if err := f.Store(g.tx); err != nil {
+		return "", fmt.Errorf("error storing file %s, %s", f.Name, err)
+	}
+	g.result.Files++
+	return f.UUID.String(), nil
+}
+
+func (g *generator) generateOrganizations() error {
+	g.orgs = make(models.Organizations, g.cfg.Organizations)
+	g.members = make([][]int, g.cfg.Organizations)
+	for i := range g.orgs {
+		name := organizationNames[i%len(organizationNames)]
+		if i >= len(organizationNames) {
+			name = fmt.Sprintf("%s %d", name, i/len(organizationNames)+1)
+		}
+		org := &g.orgs[i]
+		org.Name = name
+		org.UUID = g.uuid()
+		org.AuthType = models.AuthTypeSaml
+		org.AuthConfig = "{}"
+		org.Url = nulls.NewString("https://" + g.orgDomain(i))
+
+		if fileID, err := g.file(fmt.Sprintf("org%d", i)); err != nil {
+			return err
+		} else if fileID != "" {
+			if _, err := org.AttachLogo(g.tx, fileID); err != nil {
+				return fmt.Errorf("error attaching logo to organization %s, %w", name, err)
+			}
+		}
+
+		if err := org.Save(g.tx); err != nil {
+			return fmt.Errorf("error creating organization %s, %w", name, err)
+		}
+		if err := org.AddDomain(g.tx, g.orgDomain(i), models.AuthTypeDefault, ""); err != nil {
+			return fmt.Errorf("error adding domain to organization %s, %w", name, err)
+		}
+		g.result.Organizations++
+	}
+	return nil
+}
+
+func (g *generator) orgDomain(i int) string {
+	return fmt.Sprintf("org%d.seed%d.example.org", i+1, g.cfg.Seed)
+}
+
+// generateTrusts links each organization to the next, and adds some other trusts at random
+func (g *generator) generateTrusts() error {
+	n := len(g.orgs)
+	trusted := map[[2]int]bool{}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if j == i+1 || g.chance(0.2) {
+				trusted[[2]int{i, j}] = true
+			}
+		}
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if !trusted[[2]int{i, j}] {
+				continue
+			}
+			if err := g.orgs[i].CreateTrust(g.tx, g.orgs[j].UUID.String()); err != nil {
+				return err
+			}
+			g.result.Trusts++
+		}
+	}
+	return nil
+}
+
+func (g *generator) generateUsers() error {
+	g.users = make(models.Users, g.cfg.Users)
+	g.homes = make(models.Locations, g.cfg.Users)
+	g.userOrg = make([]int, g.cfg.Users)
+	languages := []string{
+		domain.UserPreferenceLanguageEnglish, domain.UserPreferenceLanguageEnglish, domain.UserPreferenceLanguageFrench,
+		domain.UserPreferenceLanguageSpanish, domain.UserPreferenceLanguageKorean, domain.UserPreferenceLanguagePortuguese,
+	}
+	visibilities := []string{domain.UserPreferenceVisibilityVisible, domain.UserPreferenceVisibilityHidden}
+
+	for i := range g.users {
+		first, last := personName(i)
+		loc, err := g.location()
+		if err != nil {
+			return err
+		}
+		g.homes[i] = loc
+
+		u := &g.users[i]
+		u.UUID = g.uuid()
+		u.Email = UserEmail(g.cfg.Seed, i)
+		u.FirstName = first
+		u.LastName = last
+		u.Nickname = fmt.Sprintf("%s%s%d", first, last[:1], i)
+		u.AdminRole = models.UserAdminRoleUser
+		u.LocationID = nulls.NewInt(loc.ID)
+		u.AuthPhotoURL = nulls.NewString(fmt.Sprintf("https://www.gravatar.com/avatar/%d?d=identicon", i))
+		if g.chance(0.5) {
+			u.Bio = nulls.NewString(fmt.Sprintf("%s, based in %s.", first, loc.Description))
+		}
+		if err := u.Save(g.tx); err != nil {
+			return fmt.Errorf("error creating user %s, %w", u.Email, err)
+		}
+
+		orgIndex := i % len(g.orgs)
+		g.userOrg[i] = orgIndex
+		g.members[orgIndex] = append(g.members[orgIndex], i)
+		role := models.UserOrganizationRoleUser
+		if i < len(g.orgs) {
+			role = models.UserOrganizationRoleAdmin
+		}
+		userOrg := models.UserOrganization{
+			OrganizationID: g.orgs[orgIndex].ID,
+			UserID:         u.ID,
+			Role:           role,
+			AuthID:         u.Email,
+			AuthEmail:      u.Email,
+		}
+		if err := userOrg.Create(g.tx); err != nil {
+			return fmt.Errorf("error adding user %s to organization, %w", u.Email, err)
+		}
+
+		token := models.UserAccessToken{
+			UserID:             u.ID,
+			UserOrganizationID: nulls.NewInt(userOrg.ID),
+			AccessToken:        models.HashClientIdAccessToken(AccessToken(g.cfg.Seed, i)),
+			ExpiresAt:          g.now.Add(365 * domain.DurationDay),
+		}
+		if err := g.tx.Create(&token); err != nil {
+			return fmt.Errorf("error creating access token of user %s, %w", u.Email, err)
+		}
+
+		prefs := models.StandardPreferences{
+			Language:           languages[g.rng.Intn(len(languages))],
+			TimeZone:           timeZones[g.rng.Intn(len(timeZones))],
+			WeightUnit:         domain.UserPreferenceWeightUnitKGs,
+			BioVisibility:      visibilities[g.rng.Intn(len(visibilities))],
+			LocationVisibility: visibilities[g.rng.Intn(len(visibilities))],
+		}
+		if loc.Country == "US" {
+			prefs.WeightUnit = domain.UserPreferenceWeightUnitPounds
+		}
+		if _, err := u.UpdateStandardPreferences(g.tx, prefs); err != nil {
+			return fmt.Errorf("error setting preferences of user %s, %w", u.Email, err)
+		}
+		g.result.Users++
+	}
+	return nil
+}
+
+// generateMeetings creates meetings spread over the past month and the next three months, each with an organizer and a
+// random group of participants
+func (g *generator) generateMeetings() error {
+	for i := 0; i < g.cfg.Meetings; i++ {
+		loc, err := g.location()
+		if err != nil {
+			return err
+		}
+		organizer := g.rng.Intn(len(g.users))
+		start := g.now.Add(time.Duration(g.rng.Intn(120)-30) * domain.DurationDay).Truncate(domain.DurationDay)
+
+		m := models.Meeting{
+			UUID:        g.uuid(),
+			Name:        fmt.Sprintf("%s %d", meetingNames[i%len(meetingNames)], start.Year()),
+			Description: nulls.NewString("Generated meeting in " + loc.Description),
+			StartDate:   start,
+			EndDate:     start.Add(time.Duration(1+g.rng.Intn(5)) * domain.DurationDay),
+			InviteCode:  nulls.NewUUID(g.uuid()),
+			CreatedByID: g.users[organizer].ID,
+			LocationID:  loc.ID,
+		}
+		if fileID, err := g.file(fmt.Sprintf("meeting%d", i)); err != nil {
+			return err
+		} else if fileID != "" {
+			if _, err := m.SetImageFile(g.tx, fileID); err != nil {
+				return fmt.Errorf("error attaching image to meeting %s, %w", m.Name, err)
+			}
+		}
+		if err := m.Create(g.tx); err != nil {
+			return fmt.Errorf("error creating meeting %s, %w", m.Name, err)
+		}
+		g.result.Meetings++
+		g.meetingIDs = append(g.meetingIDs, m.ID)
+
+		participants := map[int]bool{organizer: true}
+		for n := 3 + g.rng.Intn(8); n > 0; n-- {
+			participants[g.rng.Intn(len(g.users))] = true
+		}
+		for u := range g.users {
+			if !participants[u] {
+				continue
+			}
+			p := models.MeetingParticipant{MeetingID: m.ID, UserID: g.users[u].ID, IsOrganizer: u == organizer}
+			if err := g.tx.Create(&p); err != nil {
+				return fmt.Errorf("error adding participant to meeting %s, %w", m.Name, err)
+			}
+			g.result.Participants++
+		}
+	}
+	return nil
+}
+
+// requestPaths are the status updates that take a new request to each status
+var requestPaths = map[models.RequestStatus][]models.RequestStatus{
+	models.RequestStatusOpen:      {},
+	models.RequestStatusAccepted:  {models.RequestStatusAccepted},
+	models.RequestStatusDelivered: {models.RequestStatusAccepted, models.RequestStatusDelivered},
+	models.RequestStatusReceived:  {models.RequestStatusAccepted, models.RequestStatusReceived},
+	models.RequestStatusCompleted: {models.RequestStatusAccepted, models.RequestStatusDelivered, models.RequestStatusCompleted},
+	models.RequestStatusRemoved:   {models.RequestStatusRemoved},
+	models.RequestStatusExpired:   {models.RequestStatusExpired},
+}
+
+// requestStatuses are the final statuses of the requests. The first requests take each status in turn, so that all
+// are present. Open requests are the most common.
+var requestStatuses = []models.RequestStatus{
+	models.RequestStatusOpen, models.RequestStatusAccepted, models.RequestStatusDelivered,
+	models.RequestStatusReceived, models.RequestStatusCompleted, models.RequestStatusRemoved,
+	models.RequestStatusExpired, models.RequestStatusOpen, models.RequestStatusOpen, models.RequestStatusOpen,
+	models.RequestStatusCompleted,
+}
+
+var requestVisibilities = []models.RequestVisibility{
+	models.RequestVisibilitySame, models.RequestVisibilityTrusted, models.RequestVisibilityAll,
+}
+
+var requestSizes = []models.RequestSize{
+	models.RequestSizeTiny, models.RequestSizeSmall, models.RequestSizeMedium, models.RequestSizeLarge,
+	models.RequestSizeXlarge,
+}
+
+func (g *generator) generateRequests() error {
+	for i := 0; i < g.cfg.Requests; i++ {
+		creator := g.rng.Intn(len(g.users))
+		it := items[g.rng.Intn(len(items))]
+		dest, err := g.location()
+		if err != nil {
+			return err
+		}
+
+		r := models.Request{
+			UUID:           g.uuid(),
+			CreatedByID:    g.users[creator].ID,
+			OrganizationID: g.orgs[g.userOrg[creator]].ID,
+			Status:         models.RequestStatusOpen,
+			Title:          it.title,
+			Description:    nulls.NewString(it.description),
+			Size:           it.size,
+			Kilograms:      nulls.NewFloat64(it.kilograms),
+			DestinationID:  dest.ID,
+			Visibility:     requestVisibilities[i%len(requestVisibilities)],
+		}
+		if g.chance(0.7) {
+			weeks := 1 + g.rng.Intn(12)
+			r.NeededBefore = nulls.NewTime(g.now.Add(time.Duration(weeks) * domain.DurationWeek).Truncate(domain.DurationDay))
+		}
+		if g.chance(0.5) {
+			origin, err := g.location()
+			if err != nil {
+				return err
+			}
+			r.OriginID = nulls.NewInt(origin.ID)
+		}
+		if len(g.meetingIDs) > 0 && g.chance(0.2) {
+			r.MeetingID = nulls.NewInt(g.meetingIDs[g.rng.Intn(len(g.meetingIDs))])
+		}
+		if g.chance(0.3) {
+			if fileID, err := g.file(fmt.Sprintf("request%d", i)); err != nil {
+				return err
+			} else if fileID != "" {
+				if _, err := r.AttachPhoto(g.tx, fileID); err != nil {
+					return fmt.Errorf("error attaching photo to request %s, %w", r.Title, err)
+				}
+			}
+		}
+
+		if err := r.Create(g.tx); err != nil {
+			return fmt.Errorf("error creating request %s, %w", r.Title, err)
+		}
+
+		status := requestStatuses[i%len(requestStatuses)]
+		if i >= len(requestStatuses) {
+			status = requestStatuses[g.rng.Intn(len(requestStatuses))]
+		}
+		status, err = g.progressRequest(&r, creator, status)
+		if err != nil {
+			return err
+		}
+		g.result.Requests[status]++
+	}
+	return nil
+}
+
+// progressRequest adds potential providers and a conversation to the request, and takes it to the given status. If
+// no other user of the organization offered to carry the request, it stays open, unless it is to be removed or
+// expired. An expired request gets a needed-before date in the past. It returns the final status.
+func (g *generator) progressRequest(r *models.Request, creator int, status models.RequestStatus) (
+	models.RequestStatus, error,
+) {
+	candidates := g.members[g.userOrg[creator]]
+	var providers []int
+	if status != models.RequestStatusRemoved {
+		for n := g.rng.Intn(4); n >= 0; n-- {
+			p := candidates[g.rng.Intn(len(candidates))]
+			if p != creator && !containsInt(providers, p) {
+				providers = append(providers, p)
+			}
+		}
+	}
+	if len(providers) == 0 && status != models.RequestStatusRemoved && status != models.RequestStatusExpired {
+		status = models.RequestStatusOpen
+	}
+
+	for _, p := range providers {
+		pp := models.PotentialProvider{RequestID: r.ID, UserID: g.users[p].ID}
+		if err := pp.Create(g.tx); err != nil {
+			return status, fmt.Errorf("error adding potential provider to request %s, %w", r.Title, err)
+		}
+		g.result.PotentialProviders++
+	}
+
+	if len(providers) > 0 && g.chance(0.6) {
+		if err := g.conversation(*r, creator, providers[0]); err != nil {
+			return status, err
+		}
+	}
+
+	if status == models.RequestStatusExpired {
+		// set directly, since validation rejects a needed-before date in the past
+		weeks := 1 + g.rng.Intn(4)
+		r.NeededBefore = nulls.NewTime(g.now.Add(-time.Duration(weeks) * domain.DurationWeek).Truncate(domain.DurationDay))
+		if err := g.tx.UpdateColumns(r, "needed_before", "updated_at"); err != nil {
+			return status, fmt.Errorf("error backdating request %s, %w", r.Title, err)
+		}
+	}
+
+	for _, next := range requestPaths[status] {
+		if next == models.RequestStatusAccepted {
+			r.ProviderID = nulls.NewInt(g.users[providers[0]].ID)
+		}
+		r.Status = next
+		if err := r.Update(g.tx); err != nil {
+			return status, fmt.Errorf("error moving request %s to %s, %w", r.Title, next, err)
+		}
+	}
+	return status, nil
+}
+
+// conversation creates a thread between the creator and a provider, with a few messages. The participants are added
+// by the first message.
+func (g *generator) conversation(r models.Request, creator, provider int) error {
+	t := models.Thread{UUID: g.uuid(), RequestID: r.ID}
+	if err := t.Create(g.tx); err != nil {
+		return fmt.Errorf("error creating thread for request %s, %w", r.Title, err)
+	}
+	g.result.Threads++
+
+	for n := 0; n < 1+g.rng.Intn(len(messages)); n++ {
+		sender := provider
+		if n%2 == 1 {
+			sender = creator
+		}
+		m := models.Message{
+			UUID:     g.uuid(),
+			ThreadID: t.ID,
+			SentByID: g.users[sender].ID,
+			Content:  messages[n],
+		}
+		if err := g.tx.Create(&m); err != nil {
+			return fmt.Errorf("error creating message, %w", err)
+		}
+		g.result.Messages++
+	}
+	return nil
+}
+
+// generateWatches gives about a third of the users a watch for requests near their home, of a size they could carry, or
+// matching a search
+func (g *generator) generateWatches() error {
+	for i := range g.users {
+		if !g.chance(0.33) {
+			continue
+		}
+		u := g.users[i]
+		it := items[g.rng.Intn(len(items))]
+		size := requestSizes[g.rng.Intn(len(requestSizes))]
+
+		w := models.Watch{
+			UUID:    g.uuid(),
+			OwnerID: u.ID,
+			Name:    "Requests I could carry",
+			Size:    &size,
+		}
+		switch g.rng.Intn(3) {
+		case 0:
+			home := g.homes[i]
+			home.ID = 0
+			if err := home.Create(g.tx); err != nil {
+				return fmt.Errorf("error creating location of watch, %w", err)
+			}
+			g.result.Locations++
+			w.Name = "Near home"
+			w.DestinationID = nulls.NewInt(home.ID)
+			w.RadiusKm = nulls.NewInt(50 + g.rng.Intn(200))
+		case 1:
+			w.Name = it.title
+			w.SearchText = nulls.NewString(strings.ToLower(strings.Fields(it.title)[0]))
+		}
+		if err := w.Create(g.tx); err != nil {
+			return fmt.Errorf("error creating watch for user %s, %w", u.Email, err)
+		}
+		g.result.Watches++
+	}
+	return nil
+}
+
+func containsInt(s []int, v int) bool {
+	for _, i := range s {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
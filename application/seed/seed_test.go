@@ -0,0 +1,97 @@
+package seed
+
+import (
+	"testing"
+	"time"
+
+	buffalosuite "github.com/gobuffalo/suite/v4"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/silinternational/wecarry-api/models"
+)
+
+// TestSuite establishes a test suite for the parts of the generator that do not use the database
+type TestSuite struct {
+	suite.Suite
+}
+
+// Test_TestSuite runs the test suite
+func Test_TestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+// ModelSuite establishes a test suite for generating data in the test database
+type ModelSuite struct {
+	*buffalosuite.Model
+}
+
+// Test_ModelSuite runs the test suite
+func Test_ModelSuite(t *testing.T) {
+	suite.Run(t, &ModelSuite{Model: buffalosuite.NewModel()})
+}
+
+func (ts *TestSuite) TestConfigValidate() {
+	ts.NoError(DefaultConfig().Validate())
+	ts.Error(Config{Organizations: 0, Users: 10}.Validate())
+	ts.Error(Config{Organizations: 3, Users: 5}.Validate())
+	ts.Error(Config{Organizations: 1, Users: 2, Requests: -1}.Validate())
+}
+
+func (ts *TestSuite) TestDeterministic() {
+	a := newGenerator(Config{Seed: 7})
+	b := newGenerator(Config{Seed: 7})
+	c := newGenerator(Config{Seed: 8})
+
+	ts.Equal(a.uuid(), b.uuid())
+	ts.Equal(a.rng.Int63(), b.rng.Int63())
+	ts.NotEqual(a.uuid(), c.uuid())
+
+	ts.Equal(UserEmail(7, 3), UserEmail(7, 3))
+	ts.NotEqual(UserEmail(7, 3), UserEmail(8, 3))
+	ts.NotEqual(UserEmail(7, 3), UserEmail(7, 4))
+	ts.NotEqual(AccessToken(7, 3), AccessToken(7, 4))
+}
+
+func (ms *ModelSuite) TestGenerate() {
+	cfg := Config{Seed: 42, Organizations: 2, Users: 12, Requests: 30, Meetings: 2}
+
+	result, err := Generate(ms.DB, cfg)
+	ms.NoError(err)
+	ms.Equal(2, result.Organizations)
+	ms.Equal(1, result.Trusts)
+	ms.Equal(12, result.Users)
+	ms.Equal(2, result.Meetings)
+	ms.Equal(0, result.Files)
+
+	total := 0
+	for _, n := range result.Requests {
+		total += n
+	}
+	ms.Equal(30, total)
+	ms.Greater(result.Requests[models.RequestStatusOpen], 0)
+	ms.Greater(result.Requests[models.RequestStatusRemoved], 0)
+	ms.Greater(result.Requests[models.RequestStatusExpired], 0)
+
+	var expired models.Requests
+	ms.NoError(ms.DB.Where("status = ?", models.RequestStatusExpired).All(&expired))
+	for _, r := range expired {
+		ms.True(r.NeededBefore.Time.Before(time.Now()), "expired request should be needed in the past")
+	}
+
+	var user models.User
+	ms.NoError(user.FindByEmail(ms.DB, UserEmail(cfg.Seed, 1)))
+	var token models.UserAccessToken
+	ms.NoError(token.FindByBearerToken(ms.DB, AccessToken(cfg.Seed, 1)))
+	ms.Equal(user.ID, token.UserID)
+
+	prefs, err := user.GetPreferences(ms.DB)
+	ms.NoError(err)
+	ms.NotEqual("", prefs.TimeZone)
+
+	n, err := ms.DB.Where("processed_at IS NULL").Count(&models.OutboxEvent{})
+	ms.NoError(err)
+	ms.Equal(0, n, "events of the generated data should be suppressed")
+
+	_, err = Generate(ms.DB, cfg)
+	ms.Error(err, "expected an error generating the same seed again")
+}
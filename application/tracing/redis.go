@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RedisHook records a span for each Redis command or pipeline that is part of an existing trace
+type RedisHook struct{}
+
+type redisSpanKey struct{}
+
+// BeforeProcess implements redis.Hook
+func (RedisHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return startRedisSpan(ctx, "redis "+cmd.FullName(), cmd.Name())
+}
+
+// AfterProcess implements redis.Hook
+func (RedisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	endRedisSpan(ctx, cmd.Err())
+	return nil
+}
+
+// BeforeProcessPipeline implements redis.Hook
+func (RedisHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	names := make([]string, len(cmds))
+	for i := range cmds {
+		names[i] = cmds[i].Name()
+	}
+	return startRedisSpan(ctx, "redis pipeline", strings.Join(names, " "))
+}
+
+// AfterProcessPipeline implements redis.Hook
+func (RedisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmd.Err() != nil && cmd.Err() != redis.Nil {
+			err = cmd.Err()
+		}
+	}
+	endRedisSpan(ctx, err)
+	return nil
+}
+
+func startRedisSpan(ctx context.Context, name, statement string) (context.Context, error) {
+	if !IsTraced(ctx) {
+		return ctx, nil
+	}
+	ctx, span := Start(ctx, name, semconv.DBSystemRedis, attribute.String("db.operation", statement))
+	return context.WithValue(ctx, redisSpanKey{}, span), nil
+}
+
+func endRedisSpan(ctx context.Context, err error) {
+	span, ok := ctx.Value(redisSpanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	if err == redis.Nil {
+		err = nil
+	}
+	End(span, err)
+}
@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/luna-duclos/instrumentedsql"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SQLOptions are the options of the instrumented SQL driver that records a span for each query. Query arguments
+// are omitted, since they may hold personal data.
+func SQLOptions() []instrumentedsql.Opt {
+	return []instrumentedsql.Opt{
+		instrumentedsql.WithTracer(sqlTracer{}),
+		instrumentedsql.WithOmitArgs(),
+		instrumentedsql.WithOpsExcluded(instrumentedsql.OpSQLRowsNext, instrumentedsql.OpSQLResLastInsertID,
+			instrumentedsql.OpSQLResRowsAffected, instrumentedsql.OpSQLStmtClose),
+	}
+}
+
+// sqlTracer implements instrumentedsql.Tracer. Queries are only traced as part of an existing trace, so that
+// queries made outside of a request or job, such as the outbox poll, do not each start a trace of their own.
+type sqlTracer struct{}
+
+// sqlSpan implements instrumentedsql.Span
+type sqlSpan struct {
+	ctx  context.Context
+	span trace.Span
+}
+
+// GetSpan implements instrumentedsql.Tracer
+func (sqlTracer) GetSpan(ctx context.Context) instrumentedsql.Span {
+	return sqlSpan{ctx: ctx}
+}
+
+// NewChild implements instrumentedsql.Span
+func (s sqlSpan) NewChild(name string) instrumentedsql.Span {
+	if !IsTraced(s.ctx) {
+		return sqlSpan{ctx: s.ctx}
+	}
+	ctx, span := Start(s.ctx, name, semconv.DBSystemPostgreSQL)
+	return sqlSpan{ctx: ctx, span: span}
+}
+
+// SetLabel implements instrumentedsql.Span
+func (s sqlSpan) SetLabel(k, v string) {
+	if s.span == nil {
+		return
+	}
+	if k == "query" {
+		s.span.SetAttributes(semconv.DBStatement(v))
+		return
+	}
+	s.span.SetAttributes(attribute.String("db."+k, v))
+}
+
+// SetError implements instrumentedsql.Span
+func (s sqlSpan) SetError(err error) {
+	if s.span != nil && err != nil {
+		s.span.RecordError(err)
+	}
+}
+
+// Finish implements instrumentedsql.Span
+func (s sqlSpan) Finish() {
+	if s.span != nil {
+		s.span.End()
+	}
+}
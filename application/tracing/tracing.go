@@ -0,0 +1,119 @@
+// Package tracing provides OpenTelemetry tracing. Traces are exported by OTLP over HTTP when
+// OTEL_EXPORTER_OTLP_ENDPOINT is set; otherwise, spans are not recorded.
+//
+// The trace context of a request is carried across the asynchronous parts of the API, the outbox events and the
+// background jobs, as a map of W3C Trace Context headers under the domain.ArgTraceContext key of the event payload or
+// job arguments.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/silinternational/wecarry-api/domain"
+)
+
+const (
+	instrumentationName = "github.com/silinternational/wecarry-api"
+	serviceName         = "wecarry-api"
+)
+
+var propagator = propagation.TraceContext{}
+
+// Init installs the global tracer provider, if an OTLP endpoint is configured. The returned function flushes and
+// stops the exporter, and should be called before the program exits.
+func Init(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagator)
+
+	if domain.Env.OtelExporterEndpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	// the endpoint and any headers are read by the exporter from the standard OTEL_EXPORTER_OTLP_* variables
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating OTLP trace exporter, %w", err)
+	}
+
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(domain.Commit),
+		semconv.DeploymentEnvironment(domain.Env.GoEnv),
+	)
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span as a child of the span in the context, if any
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends the span, recording the error, if any
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject returns the trace context of the span in the context as a map of headers, for inclusion in an event
+// payload or job arguments
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	if ctx != nil {
+		propagator.Inject(ctx, carrier)
+	}
+	return carrier
+}
+
+// Extract returns a context holding the trace context given by Inject. The carrier may also be the result of
+// decoding the map from JSON. An empty context is returned if there is no trace context.
+func Extract(carrier interface{}) context.Context {
+	ctx := context.Background()
+
+	switch c := carrier.(type) {
+	case map[string]string:
+		return propagator.Extract(ctx, propagation.MapCarrier(c))
+	case map[string]interface{}:
+		m := propagation.MapCarrier{}
+		for k, v := range c {
+			if s, ok := v.(string); ok {
+				m[k] = s
+			}
+		}
+		return propagator.Extract(ctx, m)
+	}
+	return ctx
+}
+
+// IsTraced returns true if the context holds a valid span, e.g. from an HTTP request
+func IsTraced(ctx context.Context) bool {
+	return ctx != nil && trace.SpanContextFromContext(ctx).IsValid()
+}
+
+// ExtractHTTP returns a context holding the trace context of the caller of an HTTP request, if any
+func ExtractHTTP(ctx context.Context, header http.Header) context.Context {
+	return propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestSuite establishes a test suite for tracing tests
+type TestSuite struct {
+	suite.Suite
+	recorder *tracetest.SpanRecorder
+}
+
+// Test_TestSuite runs the test suite
+func Test_TestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+func (ts *TestSuite) SetupTest() {
+	ts.recorder = tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(ts.recorder)))
+}
+
+func (ts *TestSuite) TestInjectExtract() {
+	ts.False(IsTraced(context.Background()))
+	ts.False(IsTraced(Extract(nil)))
+	ts.Empty(Inject(context.Background()))
+
+	ctx, span := Start(context.Background(), "parent")
+	defer span.End()
+	ts.True(IsTraced(ctx))
+
+	carrier := Inject(ctx)
+	ts.Contains(carrier, "traceparent")
+
+	extracted := Extract(carrier)
+	ts.True(IsTraced(extracted))
+	ts.Equal(span.SpanContext().TraceID(), trace.SpanContextFromContext(extracted).TraceID())
+
+	// as it would be after a round trip through the Worker's JSON-encoded arguments
+	j, err := json.Marshal(carrier)
+	ts.NoError(err)
+	var decoded map[string]interface{}
+	ts.NoError(json.Unmarshal(j, &decoded))
+
+	extracted = Extract(decoded)
+	ts.True(IsTraced(extracted))
+	ts.Equal(span.SpanContext().SpanID(), trace.SpanContextFromContext(extracted).SpanID())
+}
+
+func (ts *TestSuite) TestStartEnd() {
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(Extract(Inject(ctx)), "child")
+	End(child, errors.New("failed"))
+	End(parent, nil)
+
+	spans := ts.recorder.Ended()
+	ts.Len(spans, 2)
+
+	ts.Equal("child", spans[0].Name())
+	ts.Equal(parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	ts.Equal(codes.Error, spans[0].Status().Code)
+	ts.Len(spans[0].Events(), 1, "error not recorded")
+
+	ts.Equal("parent", spans[1].Name())
+	ts.Equal(codes.Unset, spans[1].Status().Code)
+}